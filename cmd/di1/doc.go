@@ -69,6 +69,9 @@
 //
 //	go generate ./...
 //
+// "-spec -" reads the spec from stdin and "-out -" prints the generated file to
+// stdout, so other tools can drive di1 without temp files.
+//
 // Generated API (summary)
 //
 // The generated facade/builder typically includes:
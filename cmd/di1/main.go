@@ -6,8 +6,10 @@ import (
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"io"
 	"os"
 	"path"
@@ -28,6 +30,7 @@ import (
 // - Ensures fmt is imported (Build() returns errors)
 // - If the constructor needs config.Config, ensures an import usable as identifier `config` exists
 // - Writes output atomically (temp file + rename) to avoid partial writes
+// - With -dry-run (or -out -) prints the gofmt'ed output to stdout and writes nothing
 
 // Dep describes a single dependency to be injected into a service.
 // Each required dependency results in a generated Inject<Name> method and a build-time check.
@@ -40,6 +43,33 @@ type Dep struct {
 
 	// Type is the Go type of the dependency.
 	Type string `json:"type"`
+
+	// Description is rendered as the Inject<Name> doc comment.
+	Description string `json:"description"`
+
+	// Apply optionally overrides how the dependency is assigned (same shape as
+	// di2's optional apply). Without it the dependency is assigned to Field.
+	Apply *DepApply `json:"apply"`
+}
+
+// DepApply describes how a dependency is applied to the service.
+type DepApply struct {
+	// Kind is "field" (b.svc.<Name> = dep) or "setter" (b.svc.<Name>(dep)).
+	Kind string `json:"kind"`
+
+	// Name is the field or setter method name.
+	Name string `json:"name"`
+}
+
+// Assignment returns the statement that applies dep to b.svc.
+func (d Dep) Assignment() string {
+	if d.Apply != nil && d.Apply.Kind == "setter" {
+		return "b.svc." + d.Apply.Name + "(dep)"
+	}
+	if d.Apply != nil && d.Apply.Name != "" {
+		return "b.svc." + d.Apply.Name + " = dep"
+	}
+	return "b.svc." + d.Field + " = dep"
 }
 
 // Imports defines external packages required by the generated code.
@@ -66,6 +96,9 @@ type Spec struct {
 	Constructor string `json:"constructor"`
 	FacadeName  string `json:"facadeName"`
 
+	// Description is rendered as the facade type's doc comment.
+	Description string `json:"description"`
+
 	Imports  Imports `json:"imports"`
 	Required []Dep   `json:"required"`
 	Optional []Dep   `json:"optional"`
@@ -96,25 +129,26 @@ func run(args []string, stderr io.Writer) int {
 	flags := flag.NewFlagSet("di1", flag.ContinueOnError)
 	flags.SetOutput(stderr)
 
-	specPath := flags.String("spec", "", "path to service.inject.json")
-	outPath := flags.String("out", "", "output .gen.go file path")
+	specPath := flags.String("spec", "", "path to service.inject.json (\"-\" reads stdin)")
+	outPath := flags.String("out", "", "output .gen.go file path (\"-\" implies -dry-run)")
+	dryRun := flags.Bool("dry-run", false, "print the gofmt'ed output to stdout instead of writing -out")
 
 	if err := flags.Parse(args); err != nil {
 		return 2
 	}
 
 	if strings.TrimSpace(*specPath) == "" || strings.TrimSpace(*outPath) == "" {
-		_, _ = fmt.Fprintln(stderr, "usage: di1 -spec <file.inject.json> -out <file.gen.go>")
+		_, _ = fmt.Fprintln(stderr, "usage: di1 -spec <file.inject.json|-> -out <file.gen.go|-> [-dry-run]")
 		return 2
 	}
 
-	specBytes, err := os.ReadFile(*specPath)
+	specBytes, err := readSpecFile(*specPath)
 	must(err)
 
 	var spec Spec
 	must(json.Unmarshal(specBytes, &spec))
 
-	validateSpec(&spec)
+	validateSpecAt(*specPath, &spec)
 
 	if strings.TrimSpace(spec.FacadeName) == "" {
 		spec.FacadeName = spec.WrapperBase + spec.VersionSuffix
@@ -131,6 +165,7 @@ func run(args []string, stderr io.Writer) int {
 	}
 
 	constructorNeedsConfig := determineConstructorNeedsConfig(&spec, packageDir)
+	resolveEmbeddedDepFields(&spec, packageDir)
 
 	importsList, err := resolveImports(ownerGoFilePath, &spec, constructorNeedsConfig)
 	if err != nil {
@@ -149,6 +184,18 @@ func run(args []string, stderr io.Writer) int {
 	var out strings.Builder
 	must(genTemplate.Execute(&out, data))
 
+	if *dryRun || *outPath == "-" {
+		src := []byte(out.String())
+		if formatted, ferr := format.Source(src); ferr == nil {
+			src = formatted
+		} else {
+			_, _ = fmt.Fprintln(stderr, "di1: gofmt failed, printing raw output: "+ferr.Error())
+		}
+		_, err := dryRunOut.Write(src)
+		must(err)
+		return 0
+	}
+
 	must(writeFileAtomic(generatedFilePath, []byte(out.String()), 0o644))
 	return 0
 }
@@ -157,16 +204,62 @@ func main() {
 	os.Exit(run(os.Args[1:], os.Stderr))
 }
 
+// specIssue is one spec validation problem, located by a JSON pointer
+// (RFC 6901) into the spec, e.g. "/required/1/type".
+type specIssue struct {
+	Pointer string
+	Msg     string
+}
+
+// specError reports every problem found in a spec, so they can be fixed in one
+// pass instead of one panic at a time.
+type specError struct {
+	Path   string // spec file ("" when unknown)
+	Issues []specIssue
+}
+
+func (e *specError) Error() string {
+	var b strings.Builder
+	where := e.Path
+	switch where {
+	case "":
+		where = "spec"
+	case "-":
+		where = "stdin"
+	}
+	_, _ = fmt.Fprintf(&b, "%s: %d problem(s):", where, len(e.Issues))
+	for _, is := range e.Issues {
+		_, _ = fmt.Fprintf(&b, "\n  %s: %s", is.Pointer, is.Msg)
+	}
+	return b.String()
+}
+
 // validateSpec validates semantic correctness of the input specification.
+// It panics with a *specError listing all problems.
 func validateSpec(spec *Spec) {
-	var missingFields []string
+	validateSpecAt("", spec)
+}
+
+// validateSpecAt is validateSpec for the spec read from path, which prefixes
+// the error.
+func validateSpecAt(path string, spec *Spec) {
+	if issues := specIssues(spec); len(issues) > 0 {
+		panic(&specError{Path: path, Issues: issues})
+	}
+}
+
+// specIssues returns the problems of spec in document order.
+func specIssues(spec *Spec) []specIssue {
+	var issues []specIssue
+	add := func(pointer, format string, args ...any) {
+		issues = append(issues, specIssue{Pointer: pointer, Msg: fmt.Sprintf(format, args...)})
+	}
 
 	requireNonEmpty := func(fieldName, value string) {
 		if strings.TrimSpace(value) == "" {
-			missingFields = append(missingFields, fieldName)
+			add("/"+fieldName, "is required")
 		}
 	}
-
 	requireNonEmpty("package", spec.Package)
 	requireNonEmpty("wrapperBase", spec.WrapperBase)
 	requireNonEmpty("versionSuffix", spec.VersionSuffix)
@@ -174,37 +267,55 @@ func validateSpec(spec *Spec) {
 	requireNonEmpty("constructor", spec.Constructor)
 
 	if len(spec.Required) == 0 {
-		missingFields = append(missingFields, "required (must have at least 1)")
-	}
-
-	if len(missingFields) > 0 {
-		panic(fmt.Errorf("spec missing required fields: %v", missingFields))
+		add("/required", "must have at least 1 dep")
 	}
 
 	totalDeps := len(spec.Required) + len(spec.Optional)
-	seenNames := make(map[string]struct{}, totalDeps)
-	seenFields := make(map[string]struct{}, totalDeps)
+	seenNames := make(map[string]string, totalDeps)  // name -> pointer
+	seenFields := make(map[string]string, totalDeps) // field -> pointer
+
+	validateDep := func(at string, dep Dep) {
+		if strings.TrimSpace(dep.Name) == "" {
+			add(at+"/name", "is required")
+		} else if prev, ok := seenNames[dep.Name]; ok {
+			add(at+"/name", "duplicate dep name %s (also %s)", dep.Name, prev)
+		} else {
+			seenNames[dep.Name] = at + "/name"
+		}
 
-	validateDep := func(dep Dep) {
-		if dep.Name == "" || dep.Field == "" || dep.Type == "" {
-			panic(fmt.Errorf("each dep must have name/field/type; got: %+v", dep))
+		// a setter replaces the field, which may then be omitted
+		setter := dep.Apply != nil && dep.Apply.Kind == "setter"
+		if dep.Field == "" && !setter {
+			add(at+"/field", "is required (unless apply.kind is 'setter')")
+		} else if prev, ok := seenFields[dep.Field]; ok && dep.Field != "" {
+			add(at+"/field", "duplicate dep field %s (also %s)", dep.Field, prev)
+		} else if dep.Field != "" {
+			seenFields[dep.Field] = at + "/field"
 		}
-		if _, ok := seenNames[dep.Name]; ok {
-			panic(fmt.Errorf("duplicate dep name: %s", dep.Name))
+
+		if strings.TrimSpace(dep.Type) == "" {
+			add(at+"/type", "is required")
+		} else if _, err := parser.ParseExpr(dep.Type); err != nil {
+			add(at+"/type", "%q is not a valid Go type", dep.Type)
 		}
-		if _, ok := seenFields[dep.Field]; ok {
-			panic(fmt.Errorf("duplicate dep field: %s", dep.Field))
+
+		if dep.Apply != nil {
+			if dep.Apply.Kind != "field" && dep.Apply.Kind != "setter" {
+				add(at+"/apply/kind", "must be 'field' or 'setter' (got %q)", dep.Apply.Kind)
+			}
+			if strings.TrimSpace(dep.Apply.Name) == "" {
+				add(at+"/apply/name", "is required")
+			}
 		}
-		seenNames[dep.Name] = struct{}{}
-		seenFields[dep.Field] = struct{}{}
 	}
 
-	for _, dep := range spec.Required {
-		validateDep(dep)
+	for i, dep := range spec.Required {
+		validateDep(fmt.Sprintf("/required/%d", i), dep)
 	}
-	for _, dep := range spec.Optional {
-		validateDep(dep)
+	for i, dep := range spec.Optional {
+		validateDep(fmt.Sprintf("/optional/%d", i), dep)
 	}
+	return issues
 }
 
 // findOwnerGoGenerateFile finds the Go source file in packageDir that contains a go:generate
@@ -421,9 +532,102 @@ func determineConstructorNeedsConfig(spec *Spec, sourceDir string) bool {
 	return true
 }
 
+// resolveEmbeddedDepFields lets deps name an embedded field by its type.
+//
+// A field embedding an interface or struct (e.g. `log.Logger` or `*Base`) is
+// accessed by its type name (`Logger`, `Base`), so a dep whose Field is spelled
+// like the embedded type ("log.Logger") would otherwise generate
+// `b.svc.log.Logger = dep`, which does not compile. Deps with an explicit Apply
+// are left alone. Parsing is best-effort: unreadable sources change nothing.
+func resolveEmbeddedDepFields(spec *Spec, sourceDir string) {
+	embedded := embeddedFields(spec.ImplType, sourceDir)
+	if len(embedded) == 0 {
+		return
+	}
+	for i := range spec.Required {
+		dep := &spec.Required[i]
+		if name, ok := embedded[dep.Field]; ok && dep.Apply == nil {
+			dep.Field = name
+		}
+	}
+}
+
+// embeddedFields maps the type expressions of implType's embedded fields
+// (as written, e.g. "log.Logger", "*Base") to their field names.
+func embeddedFields(implType, sourceDir string) map[string]string {
+	files, err := listGoSourceFiles(sourceDir)
+	if err != nil {
+		return nil
+	}
+
+	fileSet := token.NewFileSet()
+	for _, filePath := range files {
+		parsedFile, _ := parser.ParseFile(fileSet, filePath, nil, 0)
+		if parsedFile == nil {
+			continue
+		}
+		for _, declaration := range parsedFile.Decls {
+			genDecl, ok := declaration.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range genDecl.Specs {
+				typeSpec := s.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if typeSpec.Name.Name != implType || !ok {
+					continue
+				}
+				out := map[string]string{}
+				for _, field := range structType.Fields.List {
+					if len(field.Names) > 0 {
+						continue
+					}
+					if name := embeddedFieldName(field.Type); name != "" {
+						out[types.ExprString(field.Type)] = name
+					}
+				}
+				return out
+			}
+		}
+	}
+	return nil
+}
+
+// embeddedFieldName returns the field name of an embedded field of type expr.
+func embeddedFieldName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedFieldName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedFieldName(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// docLines renders a spec description as Go comment lines ("// ..."), one per
+// line of text, without a trailing newline. Blank lines become "//".
+func docLines(desc string) string {
+	lines := strings.Split(strings.TrimSpace(desc), "\n")
+	for i, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		if l == "" {
+			lines[i] = "//"
+			continue
+		}
+		lines[i] = "// " + l
+	}
+	return strings.Join(lines, "\n")
+}
+
 // genTemplate is the Go source template used to generate the facade code.
 var genTemplate = template.Must(
-	template.New("di1").Parse(`// Code generated by di1; DO NOT EDIT.
+	template.New("di1").Funcs(template.FuncMap{"doc": docLines}).Parse(`// Code generated by di1; DO NOT EDIT.
 
 package {{.Spec.Package}}
 
@@ -434,6 +638,10 @@ import (
 )
 
 // {{.Spec.FacadeName}} is a public facade/builder.
+{{- if .Spec.Description}}
+//
+{{doc .Spec.Description}}
+{{- end}}
 type {{.Spec.FacadeName}} struct {
 	svc *{{.Spec.ImplType}}
 	{{- range .Spec.Required}}
@@ -456,9 +664,13 @@ func New{{.Spec.FacadeName}}() *{{.Spec.FacadeName}} {
 {{- end}}
 
 {{- range .Spec.Required}}
-
+{{if .Description}}
+// Inject{{.Name}} injects the required dependency {{.Name}}.
+//
+{{doc .Description}}
+{{- end}}
 func (b *{{$.Spec.FacadeName}}) Inject{{.Name}}(dep {{.Type}}) *{{$.Spec.FacadeName}} {
-	b.svc.{{.Field}} = dep
+	{{.Assignment}}
 	b.has{{.Name}} = true
 	return b
 }
@@ -503,8 +715,22 @@ var (
 	chmodFile      = os.Chmod
 	renameFile     = os.Rename
 	removeFile     = os.Remove
+
+	// dryRunOut receives the generated file in dry-run mode.
+	dryRunOut io.Writer = os.Stdout
+
+	// specIn supplies the spec for -spec -.
+	specIn io.Reader = os.Stdin
 )
 
+// readSpecFile reads the spec at path, or specIn for "-".
+func readSpecFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(specIn)
+	}
+	return os.ReadFile(path)
+}
+
 // writeFileAtomic writes a file atomically.
 //
 // It writes to a temporary file in the same directory and then renames it
@@ -4,6 +4,7 @@ package main
 import (
 	"bytes"
 	"errors"
+	"go/format"
 	"os"
 	"path/filepath"
 	"strings"
@@ -705,6 +706,182 @@ func TestTemplateSmoke(t *testing.T) {
 	assert.Contains(t, out, "InjectDB")
 }
 
+func TestTemplate_Descriptions(t *testing.T) {
+	t.Parallel()
+
+	data := templateData{
+		Spec: Spec{
+			Package:     "svc",
+			ImplType:    "Service",
+			Constructor: "NewService",
+			FacadeName:  "UserV1",
+			Description: "Manages users.\n\nSafe for concurrent use after Build.",
+			Required: []Dep{
+				{Name: "DB", Field: "db", Type: "*sql.DB", Description: "Primary store."},
+				{Name: "Cache", Field: "cache", Type: "Cache"},
+			},
+		},
+		ImportsList: []ImportSpec{{Path: "fmt"}},
+	}
+
+	var b strings.Builder
+	require.NoError(t, genTemplate.Execute(&b, data))
+	src, err := format.Source([]byte(b.String()))
+	require.NoError(t, err)
+	out := string(src)
+
+	assert.Contains(t, out, "// UserV1 is a public facade/builder.\n//\n// Manages users.\n//\n// Safe for concurrent use after Build.\ntype UserV1 struct")
+	assert.Contains(t, out, "// InjectDB injects the required dependency DB.\n//\n// Primary store.\nfunc (b *UserV1) InjectDB(")
+	assert.Contains(t, out, "}\n\nfunc (b *UserV1) InjectCache(")
+}
+
+func TestDocLines(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "// a", docLines("  a \n"))
+	assert.Equal(t, "// a\n//\n// b", docLines("a\n\t\nb"))
+}
+
+//
+// -----------------------------------------------------------------------------
+// Embedded dep fields / apply
+// -----------------------------------------------------------------------------
+
+func TestDepAssignment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		dep  Dep
+		want string
+	}{
+		{name: "field", dep: Dep{Field: "db"}, want: "b.svc.db = dep"},
+		{name: "apply_field", dep: Dep{Field: "db", Apply: &DepApply{Kind: "field", Name: "Store"}}, want: "b.svc.Store = dep"},
+		{name: "apply_setter", dep: Dep{Apply: &DepApply{Kind: "setter", Name: "SetDB"}}, want: "b.svc.SetDB(dep)"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, tc.dep.Assignment())
+		})
+	}
+}
+
+func TestValidateSpec_Apply(t *testing.T) {
+	t.Parallel()
+
+	spec := func(dep Dep) *Spec {
+		return &Spec{
+			Package: "svc", WrapperBase: "User", VersionSuffix: "V1",
+			ImplType: "Service", Constructor: "NewService",
+			Required: []Dep{dep},
+		}
+	}
+
+	require.NotPanics(t, func() {
+		validateSpec(spec(Dep{Name: "DB", Type: "*sql.DB", Apply: &DepApply{Kind: "setter", Name: "SetDB"}}))
+	})
+	mustPanicContains(t, "/required/0/field: is required (unless apply.kind is 'setter')", func() {
+		validateSpec(spec(Dep{Name: "DB", Type: "*sql.DB", Apply: &DepApply{Kind: "field", Name: "DB"}}))
+	})
+	mustPanicContains(t, `/required/0/apply/kind: must be 'field' or 'setter' (got "method")`, func() {
+		validateSpec(spec(Dep{Name: "DB", Field: "db", Type: "*sql.DB", Apply: &DepApply{Kind: "method", Name: "SetDB"}}))
+	})
+	mustPanicContains(t, "/required/0/apply/name: is required", func() {
+		validateSpec(spec(Dep{Name: "DB", Field: "db", Type: "*sql.DB", Apply: &DepApply{Kind: "setter"}}))
+	})
+}
+
+func TestValidateSpec_ReportsAllIssues(t *testing.T) {
+	t.Parallel()
+
+	spec := &Spec{
+		Package: "svc", WrapperBase: "User", VersionSuffix: "V1",
+		ImplType: "Service",
+		Required: []Dep{
+			{Name: "DB", Field: "db", Type: "*sql.DB"},
+			{Name: "Cache", Field: "db", Type: "map[string"},
+		},
+		Optional: []Dep{
+			{Name: "DB", Type: "Logger"},
+		},
+	}
+
+	var err *specError
+	func() {
+		defer func() {
+			r := recover()
+			require.NotNil(t, r, "expected panic")
+			var ok bool
+			err, ok = r.(*specError)
+			require.True(t, ok, "expected *specError, got %T", r)
+		}()
+		validateSpecAt("specs/user.inject.json", spec)
+	}()
+
+	assert.Equal(t, []specIssue{
+		{Pointer: "/constructor", Msg: "is required"},
+		{Pointer: "/required/1/field", Msg: "duplicate dep field db (also /required/0/field)"},
+		{Pointer: "/required/1/type", Msg: `"map[string" is not a valid Go type`},
+		{Pointer: "/optional/0/name", Msg: "duplicate dep name DB (also /required/0/name)"},
+		{Pointer: "/optional/0/field", Msg: "is required (unless apply.kind is 'setter')"},
+	}, err.Issues)
+	assert.True(t, strings.HasPrefix(err.Error(), "specs/user.inject.json: 5 problem(s):\n  /constructor: is required\n"), err.Error())
+}
+
+func TestResolveEmbeddedDepFields(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTempFile(t, dir, "svc.go", `package svc
+
+import "log/slog"
+
+type Logger interface{ Info(string) }
+
+type Base struct{}
+
+type Service struct {
+	Logger
+	*Base
+	*slog.Handler
+	db any
+}
+`, 0o644)
+
+	spec := Spec{
+		ImplType: "Service",
+		Required: []Dep{
+			{Name: "Log", Field: "Logger", Type: "Logger"},
+			{Name: "Base", Field: "*Base", Type: "*Base"},
+			{Name: "Handler", Field: "*slog.Handler", Type: "*slog.Handler"},
+			{Name: "DB", Field: "db", Type: "any"},
+			{Name: "Other", Field: "*Base", Type: "*Base", Apply: &DepApply{Kind: "setter", Name: "SetBase"}},
+		},
+	}
+	resolveEmbeddedDepFields(&spec, dir)
+
+	got := make([]string, 0, len(spec.Required))
+	for _, d := range spec.Required {
+		got = append(got, d.Assignment())
+	}
+	assert.Equal(t, []string{
+		"b.svc.Logger = dep",
+		"b.svc.Base = dep",
+		"b.svc.Handler = dep",
+		"b.svc.db = dep",
+		"b.svc.SetBase(dep)",
+	}, got)
+
+	// unreadable dir / unknown impl type: nothing changes
+	unchanged := Spec{ImplType: "Missing", Required: []Dep{{Name: "Base", Field: "*Base"}}}
+	resolveEmbeddedDepFields(&unchanged, dir)
+	resolveEmbeddedDepFields(&unchanged, filepath.Join(dir, "nope"))
+	assert.Equal(t, "*Base", unchanged.Required[0].Field)
+}
+
 //
 // -----------------------------------------------------------------------------
 // run(): relative out path cleaning
@@ -738,6 +915,70 @@ func TestRun_CleansRelativeOutPath(t *testing.T) {
 	assert.Contains(t, readFileString(t, cleanOut), "type UserV1 struct")
 }
 
+//
+// -----------------------------------------------------------------------------
+// run(): dry-run
+// -----------------------------------------------------------------------------
+
+func TestRun_DryRun_PrintsFormattedOutputWithoutWriting(t *testing.T) {
+	// NOT parallel: swaps dryRunOut
+
+	var stdout bytes.Buffer
+	oldOut := dryRunOut
+	dryRunOut = &stdout
+	t.Cleanup(func() { dryRunOut = oldOut })
+
+	tests := []struct {
+		name string
+		out  func(dir string) string
+		flag bool
+	}{
+		{name: "dry_run_flag", out: func(dir string) string { return filepath.Join(dir, "out.gen.go") }, flag: true},
+		{name: "out_dash", out: func(string) string { return "-" }},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			stdout.Reset()
+			dir := t.TempDir()
+			specPath := writeTempFile(t, dir, "service.inject.json", string(minimalSpecJSON()), 0o644)
+
+			args := []string{"-spec", specPath, "-out", tc.out(dir)}
+			if tc.flag {
+				args = append(args, "-dry-run")
+			}
+			var stderr bytes.Buffer
+			require.Equal(t, 0, run(args, &stderr))
+
+			out := stdout.String()
+			assert.Contains(t, out, "type UserV1 struct")
+			assert.Contains(t, out, `"fmt"`)
+			assert.NotContains(t, out, "\n\n\n", "output must be gofmt'ed")
+			assert.NoFileExists(t, filepath.Join(dir, "out.gen.go"))
+		})
+	}
+}
+
+func TestRun_SpecFromStdin(t *testing.T) {
+	// NOT parallel: swaps dryRunOut and specIn
+
+	var stdout bytes.Buffer
+	oldOut, oldIn := dryRunOut, specIn
+	dryRunOut = &stdout
+	t.Cleanup(func() { dryRunOut, specIn = oldOut, oldIn })
+
+	specIn = bytes.NewReader(minimalSpecJSON())
+	var stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-spec", "-", "-out", "-"}, &stderr))
+	assert.Contains(t, stdout.String(), "type UserV1 struct")
+
+	specIn = strings.NewReader(`{"package": "svc"}`)
+	mustPanicContains(t, "stdin: ", func() {
+		run([]string{"-spec", "-", "-out", "-"}, &stderr)
+	})
+}
+
 //
 // -----------------------------------------------------------------------------
 // run(): error branches
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Generated API compatibility report
+// -------------------------
+
+// apiDiffPrev makes -api-diff compare against the previous -out file.
+const apiDiffPrev = "prev"
+
+// apiDiffOut is where -api-diff prints its report.
+var apiDiffOut io.Writer = os.Stderr
+
+// apiChange is one difference between two generated APIs.
+type apiChange struct {
+	Kind string // "removed", "changed" or "added"
+	Name string // e.g. "func (*CoreV4) InjectAlpha", "field AppResult.Alpha"
+	Old  string
+	New  string
+}
+
+// Breaking reports whether callers of the old API may stop compiling.
+func (c apiChange) Breaking() bool { return c.Kind != "added" }
+
+func (c apiChange) String() string {
+	switch c.Kind {
+	case "removed":
+		return "- " + c.Name + c.Old
+	case "added":
+		return "+ " + c.Name + c.New
+	default:
+		return "~ " + c.Name + c.Old + " -> " + c.New
+	}
+}
+
+// checkAPIDiff compares the exported API of src with the baseline selected by
+// opts.APIDiff (a file, or the current outPath for "prev") and prints the
+// changes to apiDiffOut. With opts.APIBreaking == "error", breaking changes
+// stop generation before anything is written.
+//
+// A missing baseline is not an error: there is no API to break yet.
+func checkAPIDiff(outPath string, src []byte, opts genOptions) {
+	if opts.APIDiff == "" {
+		return
+	}
+	base := opts.APIDiff
+	if base == apiDiffPrev {
+		base = outPath
+	}
+	old, err := os.ReadFile(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	must(err)
+
+	changes, err := diffGeneratedAPI(old, src)
+	if err != nil {
+		die("api-diff " + base + ": " + err.Error())
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	breaking := 0
+	var sb strings.Builder
+	for _, c := range changes {
+		if c.Breaking() {
+			breaking++
+		}
+		sb.WriteString(c.String() + "\n")
+	}
+	fmt.Fprintf(apiDiffOut, "di2: api-diff %s (%d breaking):\n%s", outPath, breaking, sb.String())
+	if breaking > 0 && opts.APIBreaking == "error" {
+		die(fmt.Sprintf("api-diff %s: %d breaking changes to the generated API", outPath, breaking))
+	}
+}
+
+// diffGeneratedAPI returns the changes between the exported APIs of two Go
+// files, removed and changed entries first, each group sorted by name.
+func diffGeneratedAPI(oldSrc, newSrc []byte) ([]apiChange, error) {
+	oldAPI, err := exportedAPI(oldSrc)
+	if err != nil {
+		return nil, err
+	}
+	newAPI, err := exportedAPI(newSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []apiChange
+	for name, o := range oldAPI {
+		n, ok := newAPI[name]
+		switch {
+		case !ok:
+			changes = append(changes, apiChange{Kind: "removed", Name: name, Old: o})
+		case n != o:
+			changes = append(changes, apiChange{Kind: "changed", Name: name, Old: o, New: n})
+		}
+	}
+	for name, n := range newAPI {
+		if _, ok := oldAPI[name]; !ok {
+			changes = append(changes, apiChange{Kind: "added", Name: name, New: n})
+		}
+	}
+	rank := map[string]int{"removed": 0, "changed": 0, "added": 1}
+	sort.Slice(changes, func(i, j int) bool {
+		if rank[changes[i].Kind] != rank[changes[j].Kind] {
+			return rank[changes[i].Kind] < rank[changes[j].Kind]
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// exportedAPI maps every exported declaration of a Go file to its signature:
+// funcs and methods (parameter names dropped), types, struct fields, interface
+// methods, consts and vars.
+func exportedAPI(src []byte) (map[string]string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	render := func(n ast.Node) string {
+		var buf bytes.Buffer
+		_ = printer.Fprint(&buf, fset, n)
+		return buf.String()
+	}
+
+	api := map[string]string{}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			name := "func " + d.Name.Name
+			if d.Recv != nil {
+				recv := render(d.Recv.List[0].Type)
+				if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+					continue
+				}
+				name = "func (" + recv + ") " + d.Name.Name
+			}
+			api[name] = funcSignature(d.Type, render)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						addTypeAPI(api, s, render)
+					}
+				case *ast.ValueSpec:
+					for _, id := range s.Names {
+						if !id.IsExported() {
+							continue
+						}
+						typ := ""
+						if s.Type != nil {
+							typ = " " + render(s.Type)
+						}
+						api[d.Tok.String()+" "+id.Name] = typ
+					}
+				}
+			}
+		}
+	}
+	return api, nil
+}
+
+func addTypeAPI(api map[string]string, s *ast.TypeSpec, render func(ast.Node) string) {
+	name := s.Name.Name
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		api["type "+name] = " struct"
+		for _, f := range t.Fields.List {
+			for _, id := range fieldNames(f) {
+				if ast.IsExported(id) {
+					api["field "+name+"."+id] = " " + render(f.Type)
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		api["type "+name] = " interface"
+		for _, m := range t.Methods.List {
+			for _, id := range fieldNames(m) {
+				if ast.IsExported(id) {
+					if ft, ok := m.Type.(*ast.FuncType); ok {
+						api["method "+name+"."+id] = funcSignature(ft, render)
+					} else {
+						api["method "+name+"."+id] = " " + render(m.Type)
+					}
+				}
+			}
+		}
+	default:
+		api["type "+name] = " " + render(s.Type)
+	}
+}
+
+// fieldNames returns a field's names; an embedded field is named by its type.
+func fieldNames(f *ast.Field) []string {
+	if len(f.Names) == 0 {
+		t := f.Type
+		if st, ok := t.(*ast.StarExpr); ok {
+			t = st.X
+		}
+		if sel, ok := t.(*ast.SelectorExpr); ok {
+			return []string{sel.Sel.Name}
+		}
+		if id, ok := t.(*ast.Ident); ok {
+			return []string{id.Name}
+		}
+		return nil
+	}
+	names := make([]string, len(f.Names))
+	for i, id := range f.Names {
+		names[i] = id.Name
+	}
+	return names
+}
+
+// funcSignature renders "(T1, T2) R" without parameter names, so renaming a
+// parameter is not reported as a change.
+func funcSignature(ft *ast.FuncType, render func(ast.Node) string) string {
+	list := func(fl *ast.FieldList) []string {
+		if fl == nil {
+			return nil
+		}
+		var out []string
+		for _, f := range fl.List {
+			n := max(len(f.Names), 1)
+			for range n {
+				out = append(out, render(f.Type))
+			}
+		}
+		return out
+	}
+	sig := "(" + strings.Join(list(ft.Params), ", ") + ")"
+	switch res := list(ft.Results); len(res) {
+	case 0:
+	case 1:
+		sig += " " + res[0]
+	default:
+		sig += " (" + strings.Join(res, ", ") + ")"
+	}
+	return sig
+}
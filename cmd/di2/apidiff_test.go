@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// -api-diff
+// -------------------------
+
+func TestDiffGeneratedAPI(t *testing.T) {
+	t.Parallel()
+
+	oldSrc := `package p
+
+type CoreV4 struct{ svc *Core }
+type Result struct {
+	Alpha *Alpha
+	Beta  *Beta
+}
+type Lifecycle interface{ Start() error }
+
+const CoreV4OptionalTracerKey = "t"
+
+var CoreV4InjectPolicyOnOverwrite = "error"
+
+func NewCoreV4() *CoreV4                           { return nil }
+func (b *CoreV4) InjectAlpha(dep *Alpha) *CoreV4   { return b }
+func (b *CoreV4) InjectBeta(dep *Beta) *CoreV4     { return b }
+func (b *CoreV4) Process(ctx Ctx, id string) error { return nil }
+func (b *CoreV4) missingMask(need uint64) uint64   { return 0 }
+`
+	newSrc := `package p
+
+type CoreV4 struct{ svc *Core }
+type Result struct {
+	Alpha *Alpha
+	Gamma *Gamma
+}
+type Lifecycle interface{ Start() error; Stop() }
+
+const CoreV4OptionalTracerKey = "t"
+
+var CoreV4InjectPolicyOnOverwrite = "error"
+
+func NewCoreV4() *CoreV4                               { return nil }
+func (b *CoreV4) InjectAlpha(a *Alpha) *CoreV4         { return b }
+func (b *CoreV4) InjectGamma(dep *Gamma) *CoreV4       { return b }
+func (b *CoreV4) Process(ctx Ctx, id int) (int, error) { return 0, nil }
+func (b *CoreV4) missingMask() uint64                  { return 0 }
+`
+
+	changes, err := diffGeneratedAPI([]byte(oldSrc), []byte(newSrc))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"- field Result.Beta *Beta",
+		"- func (*CoreV4) InjectBeta(*Beta) *CoreV4",
+		"~ func (*CoreV4) Process(Ctx, string) error -> (Ctx, int) (int, error)",
+		"+ field Result.Gamma *Gamma",
+		"+ func (*CoreV4) InjectGamma(*Gamma) *CoreV4",
+		"+ method Lifecycle.Stop()",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("changes mismatch\n got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := diffGeneratedAPI([]byte("not go"), []byte(newSrc)); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestRun_APIDiff(t *testing.T) {
+	// NOT parallel: swaps apiDiffOut
+
+	var buf bytes.Buffer
+	old := apiDiffOut
+	apiDiffOut = &buf
+	t.Cleanup(func() { apiDiffOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	out := p.out("core.gen.go")
+	writeSpec := func(deps string) string {
+		return p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [`+deps+`]
+}`)
+	}
+	alpha := `{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }`
+	beta := `{ "name": "Beta", "field": "beta", "type": "*Beta", "nilable": true }`
+
+	// no baseline yet: nothing to report
+	spec := writeSpec(alpha + "," + beta)
+	if err := run([]string{"-spec", spec, "-out", out, "-api-diff", "prev"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no report without a baseline, got:\n%s", buf.String())
+	}
+	baseline := p.read("core.gen.go")
+
+	// unchanged API
+	if err := run([]string{"-spec", spec, "-out", out, "-api-diff", "prev", "-api-breaking", "error"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no report for an unchanged API, got:\n%s", buf.String())
+	}
+
+	// dropping Beta is breaking: error mode refuses to write
+	spec = writeSpec(alpha)
+	assertPanicContains(t, func() {
+		_ = run([]string{"-spec", spec, "-out", out, "-api-diff", "prev", "-api-breaking", "error"})
+	}, "2 breaking")
+	assertContainsInOrder(t, buf.String(),
+		"di2: api-diff "+out+" (2 breaking):",
+		"- func (*CoreV4) InjectBeta(*Beta) *CoreV4",
+	)
+	if p.read("core.gen.go") != baseline {
+		t.Fatalf("-api-breaking=error must not write the output")
+	}
+
+	// warn mode reports and writes; the baseline may be any file
+	buf.Reset()
+	saved := p.write("old.gen.go.txt", baseline)
+	if err := run([]string{"-spec", spec, "-out", out, "-api-diff", saved}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.Contains(buf.String(), "- func (*CoreV4) TryInjectBeta(*Beta) (*CoreV4, error)") {
+		t.Fatalf("expected TryInjectBeta removal in report, got:\n%s", buf.String())
+	}
+	if p.read("core.gen.go") == baseline {
+		t.Fatalf("-api-breaking=warn must write the output")
+	}
+
+	// additions are not breaking
+	buf.Reset()
+	spec = writeSpec(alpha + "," + beta)
+	if err := run([]string{"-spec", spec, "-out", out, "-api-diff", "prev", "-api-breaking", "error"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.Contains(buf.String(), "(0 breaking)") || !strings.Contains(buf.String(), "+ func (*CoreV4) InjectBeta(*Beta) *CoreV4") {
+		t.Fatalf("expected non-breaking additions in report, got:\n%s", buf.String())
+	}
+}
+
+func TestRun_APIDiffFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "bad_mode", args: []string{"-spec", "x.json", "-out", "x.gen.go", "-api-breaking", "fail"}, want: "-api-breaking must be warn or error"},
+		{name: "batch_path", args: []string{"-specs", "dir", "-out", "dir", "-api-diff", "old.gen.go"}, want: "-specs supports only -api-diff=prev"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := run(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
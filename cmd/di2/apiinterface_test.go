@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Facade API interface and mock (apiInterface, apiMock)
+// -------------------------
+
+func TestGenService_APIInterface(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "apiInterface": true, "healthCheck": "Ping",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  "methods": [
+    { "name": "Process", "description": "Process handles one request.\nIt is idempotent.",
+      "params": [{ "name": "ctx", "type": "context.Context" }, { "name": "req", "type": "Request" }],
+      "returns": [{ "type": "Response" }, { "type": "error" }] },
+    { "name": "Name", "returns": [{ "type": "string" }] },
+    { "name": "Close" },
+    { "name": "Limit", "chain": true, "params": [{ "name": "n", "type": "int" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"// CoreV4API is the business surface of CoreV4",
+		"type CoreV4API interface {",
+		"\tClose()\n",
+		"\tLimit(n int) *CoreV4\n",
+		"\tName() string\n",
+		"\t// Process handles one request.\n\t// It is idempotent.\n\tProcess(ctx context.Context, req Request) (Response, error)\n",
+		"\t// HealthCheck reports whether CoreV4 is healthy.\n\tHealthCheck(ctx context.Context) error\n}",
+		"var _ CoreV4API = (*CoreV4)(nil)",
+	)
+	iface := out[strings.Index(out, "type CoreV4API interface"):]
+	iface = iface[:strings.Index(iface, "\n}")]
+	for _, m := range []string{"Build", "Inject", "Reset", "UnsafeImpl", "Clone"} {
+		if strings.Contains(iface, m) {
+			t.Fatalf("the API interface must not contain wiring method %s:\n%s", m, iface)
+		}
+	}
+}
+
+func TestGenService_APIMock(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "apiInterface": true, "apiMock": true, "healthCheck": "Ping",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  "methods": [
+    { "name": "Process", "params": [{ "name": "ctx", "type": "context.Context" }, { "name": "req", "type": "Request" }],
+      "returns": [{ "type": "Response" }, { "type": "error" }] },
+    { "name": "Close" },
+    { "name": "Limit", "chain": true, "params": [{ "name": "n", "type": "int" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "sync")
+	assertContainsInOrder(t, out,
+		"type CoreV4API interface {",
+		"// CoreV4APIMock is a CoreV4API for tests",
+		"type CoreV4APIMock struct {",
+		"\tCloseFunc       func()\n",
+		"\tLimitFunc       func(n int) *CoreV4\n",
+		"\tProcessFunc     func(ctx context.Context, req Request) (Response, error)\n",
+		"\tHealthCheckFunc func(ctx context.Context) error\n",
+		"var _ CoreV4API = (*CoreV4APIMock)(nil)",
+		"func (m *CoreV4APIMock) Calls() []string {",
+		`panic("CoreV4APIMock: " + method + "Func is not set")`,
+		"func (m *CoreV4APIMock) Close() {\n\tm.record(\"Close\", m.CloseFunc != nil)\n\tm.CloseFunc()\n}",
+		"func (m *CoreV4APIMock) Limit(n int) *CoreV4 {\n\tm.record(\"Limit\", m.LimitFunc != nil)\n\treturn m.LimitFunc(n)\n}",
+		"func (m *CoreV4APIMock) Process(ctx context.Context, req Request) (Response, error) {",
+		"return m.ProcessFunc(ctx, req)",
+		"func (m *CoreV4APIMock) HealthCheck(ctx context.Context) error {",
+		"return m.HealthCheckFunc(ctx)",
+	)
+}
+
+func TestGenService_APIMockRequiresInterface(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "apiMock": true,
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`)
+	assertPanicContains(t, func() { genService(specPath, p.out("core.gen.go"), genOptions{}) }, "apiMock requires apiInterface")
+}
+
+func TestGenService_NoAPIInterface(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	genService(specPath, p.out("a.gen.go"), genOptions{})
+	if out := p.read("a.gen.go"); strings.Contains(out, "AlphaV4API") || strings.Contains(out, `"sync"`) {
+		t.Fatalf("apiInterface and apiMock are opt-in:\n%s", out)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Optional deps applied by a call (apply.kind "call")
+// -------------------------
+
+// Identifiers available to the args of an apply call: the resolved dep (after
+// the type assertion) and the facade's config.
+const (
+	applyCastedIdent = "casted"
+	applyConfigIdent = "cfg"
+)
+
+// validateApplyCall checks the args of an optional dep applied with kind
+// "call": Go expressions that use casted (at least once), cfg (only with
+// config enabled), predeclared identifiers, exported names of the service
+// package and package-qualified names. Other identifiers would be locals of
+// the generated code, which the args cannot see.
+func validateApplyCall(o OptionalDep, cfgEnabled bool) {
+	if len(o.Apply.Args) == 0 {
+		die("optional " + o.Name + " apply.args is required for kind 'call' (e.g. [\"casted\", \"cfg.TraceSampling\"])")
+	}
+	usesCasted := false
+	for _, arg := range o.Apply.Args {
+		refs, err := applyArgRefs(arg)
+		if err != nil {
+			die("optional " + o.Name + " apply.args " + err.Error())
+		}
+		for _, id := range refs {
+			switch {
+			case id.Name == applyCastedIdent:
+				usesCasted = true
+			case id.Name == applyConfigIdent && !cfgEnabled:
+				die("optional " + o.Name + " apply.args " + arg + ": cfg needs config.enabled")
+			case id.Name != applyConfigIdent && !ast.IsExported(id.Name):
+				die("optional " + o.Name + " apply.args " + arg + ": unknown identifier " + id.Name + " (available: casted, cfg, exported package-level and package-qualified names)")
+			}
+		}
+	}
+	if !usesCasted {
+		die("optional " + o.Name + " apply.args must pass the resolved dep (casted)")
+	}
+}
+
+// renderApplyCall renders the args of an apply call with casted replaced by
+// value (parenthesized inside a larger expression) and cfg by the facade's
+// config field.
+func renderApplyCall(o OptionalDep, value, cfgField string) string {
+	operand := value
+	if !token.IsIdentifier(value) {
+		operand = "(" + value + ")"
+	}
+	args := make([]string, 0, len(o.Apply.Args))
+	for _, arg := range o.Apply.Args {
+		src := strings.TrimSpace(arg)
+		if src == applyCastedIdent {
+			args = append(args, value)
+			continue
+		}
+		refs, err := applyArgRefs(src)
+		must(err)
+		sort.Slice(refs, func(i, j int) bool { return refs[i].Pos() > refs[j].Pos() })
+		for _, id := range refs {
+			if id.Name != applyCastedIdent && id.Name != applyConfigIdent {
+				continue
+			}
+			off := int(id.Pos()) - 1 // ParseExpr positions start at 1
+			repl := operand
+			if id.Name == applyConfigIdent {
+				repl = "b." + cfgField
+			}
+			src = src[:off] + repl + src[off+len(id.Name):]
+		}
+		args = append(args, src)
+	}
+	return strings.Join(args, ", ")
+}
+
+// qualifyApplyArgs qualifies the exported package-level names in the args of an
+// apply call with pkg, for a facade generated outside the service package.
+func qualifyApplyArgs(o *OptionalDep, pkg string) {
+	for i, arg := range o.Apply.Args {
+		src := strings.TrimSpace(arg)
+		refs, err := applyArgRefs(src)
+		must(err)
+		sort.Slice(refs, func(i, j int) bool { return refs[i].Pos() > refs[j].Pos() })
+		for _, id := range refs {
+			if ast.IsExported(id.Name) {
+				off := int(id.Pos()) - 1
+				src = src[:off] + pkg + "." + src[off:]
+			}
+		}
+		o.Apply.Args[i] = src
+	}
+}
+
+// applyArgRefs parses arg and returns the identifiers it references other than
+// predeclared ones, package qualifiers (the left side of a selector other than
+// casted or cfg), selected names, composite literal keys and parameter names.
+func applyArgRefs(arg string) ([]*ast.Ident, error) {
+	src := strings.TrimSpace(arg)
+	node, err := parser.ParseExpr(src)
+	if err != nil {
+		return nil, &cmdError{msg: src + ": " + err.Error()}
+	}
+	skip := map[*ast.Ident]bool{}
+	var refs []*ast.Ident
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			skip[x.Sel] = true
+			if id, ok := x.X.(*ast.Ident); ok && id.Name != applyCastedIdent && id.Name != applyConfigIdent {
+				skip[id] = true // package qualifier
+			}
+		case *ast.KeyValueExpr:
+			if id, ok := x.Key.(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.Field:
+			for _, name := range x.Names {
+				skip[name] = true
+			}
+		case *ast.Ident:
+			if !skip[x] && !predeclared[x.Name] && x.Name != "_" {
+				refs = append(refs, x)
+			}
+		}
+		return true
+	})
+	return refs, nil
+}
+
+// applyArgQualifiers returns the package qualifiers used by the apply.args of o.
+func applyArgQualifiers(o OptionalDep) []string {
+	var out []string
+	for _, arg := range o.Apply.Args {
+		node, err := parser.ParseExpr(strings.TrimSpace(arg))
+		if err != nil {
+			continue
+		}
+		ast.Inspect(node, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name != applyCastedIdent && id.Name != applyConfigIdent {
+					out = append(out, id.Name)
+				}
+			}
+			return true
+		})
+	}
+	return out
+}
+
+// setApplyCalls renders the args of the "call" optional deps of s.
+func setApplyCalls(s *ServiceSpec) {
+	for i := range s.Optional {
+		o := &s.Optional[i]
+		if o.Apply.Kind != "call" {
+			continue
+		}
+		o.Apply.Call = renderApplyCall(*o, "v", s.Config.FieldName)
+		if o.DefaultExpr != "" {
+			o.Apply.DefaultCall = renderApplyCall(*o, o.DefaultExpr, s.Config.FieldName)
+		}
+	}
+}
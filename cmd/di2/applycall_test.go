@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Optional deps applied by a call (apply.kind "call")
+// -------------------------
+
+func applyCallSpec(config, apply string) string {
+	return `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  ` + config + `
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  "optional": [
+    { "name": "Tracer", "type": "Tracer", "registryKey": "p.tracer", "defaultExpr": "NoopTracer{}",
+      "apply": ` + apply + ` }
+  ]
+}`
+}
+
+func TestGenService_ApplyCall(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", applyCallSpec(
+		`"config": { "enabled": true, "import": "example.com/proj/config" },`,
+		`{ "kind": "call", "name": "SetTracerWithSampling", "args": ["casted", "cfg.TraceSampling", "time.Second"] }`))
+	wrapped := p.write("wrapped.inject.json", applyCallSpec(``,
+		`{ "kind": "call", "name": "SetTracers", "args": ["[]Tracer{casted}"] }`))
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+	assertContainsInOrder(t, out,
+		"} else if ok {\n\t\t\tb.svc.SetTracerWithSampling(v, b.cfg.TraceSampling, time.Second)",
+		"} else {\n\t\t\tb.svc.SetTracerWithSampling(NoopTracer{}, b.cfg.TraceSampling, time.Second)",
+	)
+
+	genService(wrapped, p.out("wrapped.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("wrapped.gen.go"),
+		"b.svc.SetTracers([]Tracer{v})",
+		"b.svc.SetTracers([]Tracer{(NoopTracer{})})",
+	)
+}
+
+func TestGenService_ApplyCallExternal(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	specPath := writeExternalLayout(p, `"output": { "package": "wiring", "accessors": "../core_access.gen.go" },`)
+	raw := strings.Replace(p.read("orders/specs/core.inject.json"),
+		`"apply": { "kind": "setter", "name": "SetTracer" }`,
+		`"apply": { "kind": "call", "name": "SetTracerWithRate", "args": ["casted", "DefaultRate * 2"] }`, 1)
+	p.write("orders/specs/core.inject.json", raw)
+
+	genService(specPath, p.out("wiring/core_v4.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("wiring/core_v4.gen.go"),
+		"b.svc.SetTracerWithRate(v, orders.DefaultRate*2)",
+		"b.svc.SetTracerWithRate(orders.NoopTracer{}, orders.DefaultRate*2)",
+	)
+	if strings.Contains(p.read("orders/core_access.gen.go"), "DIRefTracer") {
+		t.Fatal("call-applied optional deps need no accessor")
+	}
+}
+
+func TestGenService_ApplyCallErrors(t *testing.T) {
+	t.Parallel()
+	const cfg = `"config": { "enabled": true, "import": "example.com/proj/config" },`
+
+	tests := []struct {
+		name   string
+		config string
+		apply  string
+		want   string
+	}{
+		{name: "no_args", config: cfg, apply: `{ "kind": "call", "name": "SetTracer" }`, want: "optional Tracer apply.args is required for kind 'call'"},
+		{name: "no_casted", config: cfg, apply: `{ "kind": "call", "name": "SetTracer", "args": ["cfg.Tracer"] }`, want: "optional Tracer apply.args must pass the resolved dep (casted)"},
+		{name: "unknown_ident", config: cfg, apply: `{ "kind": "call", "name": "SetTracer", "args": ["casted", "sampling"] }`, want: "optional Tracer apply.args sampling: unknown identifier sampling (available: casted, cfg, exported package-level and package-qualified names)"},
+		{name: "cfg_without_config", apply: `{ "kind": "call", "name": "SetTracer", "args": ["casted", "cfg.Rate"] }`, want: "optional Tracer apply.args cfg.Rate: cfg needs config.enabled"},
+		{name: "bad_expr", config: cfg, apply: `{ "kind": "call", "name": "SetTracer", "args": ["casted,"] }`, want: "optional Tracer apply.args casted,:"},
+		{name: "args_on_setter", config: cfg, apply: `{ "kind": "setter", "name": "SetTracer", "args": ["casted"] }`, want: "optional Tracer apply.args is only valid for kind 'call'"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			specPath := p.write("core.inject.json", applyCallSpec(tt.config, tt.apply))
+			assertPanicContains(t, func() { genService(specPath, p.out("core.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
+
+func TestGenService_ApplyCallNoInfer(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	specPath := p.write("core.inject.json", applyCallSpec(
+		`"imports": { "di": "example.com/proj/di" },`,
+		`{ "kind": "call", "name": "SetTracer", "args": ["casted", "time.Second"] }`))
+
+	assertPanicContains(t, func() {
+		genService(specPath, p.out("core.gen.go"), genOptions{NoInfer: true})
+	}, "-no-infer: optional Tracer apply.args uses package time; declare it in imports.packages")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// batchResult is the outcome of generating a single spec in batch mode.
+type batchResult struct {
+	Spec string
+	Out  string
+	Err  error
+
+	// dry holds the rendered file in dry-run mode.
+	dry bytes.Buffer
+}
+
+// findBatchSpecs returns the *.inject.json files directly inside dir, sorted by path.
+func findBatchSpecs(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.inject.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no *.inject.json specs in %s", dir)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// batchOutPath derives the output file for a spec in batch mode:
+// <outDir>/<name>_<lower(versionSuffix)>.gen.go, or <outDir>/<name>.gen.go
+// when the spec has no versionSuffix or its naming is "none"
+// (specs/core.inject.json -> core_v4.gen.go).
+func batchOutPath(specPath, outDir string) string {
+	name := strings.TrimSuffix(filepath.Base(specPath), ".inject.json")
+
+	var head struct {
+		VersionSuffix string `json:"versionSuffix"`
+		Naming        string `json:"naming"`
+	}
+	if raw, err := os.ReadFile(specPath); err == nil {
+		if rendered, err := expandSpecVars(raw); err == nil {
+			raw = rendered
+		}
+		_ = json.Unmarshal(raw, &head) // a broken spec fails later in genService
+	}
+	if head.Naming == "" {
+		head.Naming = defaultNaming
+	}
+	if s := strings.TrimSpace(head.VersionSuffix); s != "" && head.Naming != namingNone {
+		name += "_" + strings.ToLower(s)
+	}
+	return filepath.Join(outDir, name+".gen.go")
+}
+
+// genServiceBatch generates every spec in specDir into outDir using a pool of
+// workers. Specs are independent (each scans its own package imports), so they
+// are processed concurrently; results are reported in spec path order regardless
+// of completion order. All specs are attempted and failures are joined, along with
+// deps whose type differs between specs (see checkBatchDepTypes). In dry-run
+// mode each file is buffered and printed, in spec order, after the pool finishes.
+func genServiceBatch(specDir, outDir string, workers int, opts genOptions) ([]batchResult, error) {
+	specs, err := findBatchSpecs(specDir)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(specs))
+
+	results := make([]*batchResult, len(specs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range jobs {
+				results[i] = genBatchOne(specs[i], outDir, opts)
+			}
+		})
+	}
+	for i := range specs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	errs := []error{checkBatchDepTypes(specs)}
+	out := make([]batchResult, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+		if opts.DryRun != nil && r.Err == nil {
+			// printed in spec order once all workers are done
+			_, _ = fmt.Fprintf(opts.DryRun, "// ---- %s ----\n", filepath.ToSlash(r.Out))
+			_, _ = r.dry.WriteTo(opts.DryRun)
+		}
+		out = append(out, batchResult{Spec: r.Spec, Out: r.Out, Err: r.Err})
+	}
+	return out, errors.Join(errs...)
+}
+
+// genBatchOne runs genService for one spec, turning the generator's panics
+// (die/must) into an error so one bad spec does not abort the whole batch.
+func genBatchOne(specPath, outDir string, opts genOptions) (res *batchResult) {
+	res = &batchResult{Spec: specPath}
+	res.Out = batchOutPath(specPath, outDir)
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = fmt.Errorf("%s: %v", specPath, r)
+		}
+	}()
+	if opts.DryRun != nil {
+		opts.DryRun = &res.dry
+	}
+	genService(specPath, res.Out, opts)
+	return res
+}
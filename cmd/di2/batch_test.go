@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Batch mode (-specs)
+// -------------------------
+
+func writeBatchSpec(p *pkgHarness, name, base, suffix string) string {
+	return p.write(filepath.Join("specs", name+".inject.json"), `{
+  "package": "p", "wrapperBase": "`+base+`", "versionSuffix": "`+suffix+`",
+  "implType": "`+base+`", "constructor": "New`+base+`",
+  "required": [{ "name": "Dep", "field": "dep", "type": "*Dep", "nilable": true }]
+}`)
+}
+
+func TestFindBatchSpecs(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+
+	if _, err := findBatchSpecs(p.out("specs")); err == nil || !strings.Contains(err.Error(), "no *.inject.json specs") {
+		t.Fatalf("expected empty dir error, got %v", err)
+	}
+
+	writeBatchSpec(p, "zeta", "Zeta", "V4")
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+	p.write("specs/graph.json", `{}`)
+
+	got, err := findBatchSpecs(p.out("specs"))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	want := []string{p.out("specs/alpha.inject.json"), p.out("specs/zeta.inject.json")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+func TestBatchOutPath(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{name: "suffix_lowered", spec: writeBatchSpec(p, "core", "Core", "V4"), want: "core_v4.gen.go"},
+		{name: "no_suffix", spec: writeBatchSpec(p, "plain", "Plain", ""), want: "plain.gen.go"},
+		{name: "unreadable_spec", spec: p.out("specs/missing.inject.json"), want: "missing.gen.go"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := batchOutPath(tt.spec, "out"); got != filepath.Join("out", tt.want) {
+				t.Fatalf("got %q want %q", got, filepath.Join("out", tt.want))
+			}
+		})
+	}
+}
+
+func TestGenServiceBatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generates_all_in_spec_order", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		for _, n := range []string{"delta", "alpha", "charlie", "bravo"} {
+			writeBatchSpec(p, n, strings.ToUpper(n[:1])+n[1:], "V4")
+		}
+
+		results, err := genServiceBatch(p.out("specs"), p.dir, 3, genOptions{})
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		var outs []string
+		for _, r := range results {
+			outs = append(outs, filepath.Base(r.Out))
+		}
+		if got := strings.Join(outs, ","); got != "alpha_v4.gen.go,bravo_v4.gen.go,charlie_v4.gen.go,delta_v4.gen.go" {
+			t.Fatalf("unexpected result order: %s", got)
+		}
+		if !strings.Contains(p.read("charlie_v4.gen.go"), "type CharlieV4 struct") {
+			t.Fatalf("expected CharlieV4 facade")
+		}
+	})
+
+	t.Run("joins_errors_and_keeps_going", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		writeBatchSpec(p, "alpha", "Alpha", "V4")
+		p.write("specs/broken.inject.json", `{`)
+		p.write("specs/empty.inject.json", `{}`)
+		writeBatchSpec(p, "zulu", "Zulu", "V4")
+
+		results, err := genServiceBatch(p.out("specs"), p.dir, 0, genOptions{})
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		assertContainsInOrder(t, err.Error(), "broken.inject.json", "empty.inject.json")
+		if len(results) != 4 || results[0].Err != nil || results[3].Err != nil {
+			t.Fatalf("expected valid specs to succeed: %+v", results)
+		}
+		if !fileExists(p.out("zulu_v4.gen.go")) {
+			t.Fatalf("expected zulu output despite earlier failures")
+		}
+	})
+
+	t.Run("missing_dir", func(t *testing.T) {
+		t.Parallel()
+		if _, err := genServiceBatch(filepath.Join(t.TempDir(), "nope"), ".", 1, genOptions{}); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestRun_Batch(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+	outDir := p.out("gen")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "with_spec", args: []string{"-out", outDir, "-specs", p.out("specs"), "-spec", "x"}, wantErr: "use only one of -spec, -specs or -graph"},
+		{name: "negative_jobs", args: []string{"-out", outDir, "-specs", p.out("specs"), "-j", "-1"}, wantErr: "-j must be >= 0"},
+		{name: "ok", args: []string{"-out", outDir, "-specs", p.out("specs"), "-j", "2"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected err: %v", err)
+				}
+				if !fileExists(filepath.Join(outDir, "alpha_v4.gen.go")) {
+					t.Fatalf("expected batch output")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err=%v want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Exposed builders
+// -------------------------
+
+func TestGenGraph_ExposeBuilders(t *testing.T) {
+	t.Parallel()
+
+	graph := func(expose bool) string {
+		exp := "false"
+		if expose {
+			exp = "true"
+		}
+		return `{
+  "package": "p",
+  "exposeBuilders": ` + exp + `,
+  "roots": [{
+    "name": "App",
+    "services": [
+      { "var": "alpha", "facadeCtor": "NewAlphaV4", "facadeType": "*AlphaV4", "implType": "Alpha" },
+      { "var": "beta", "facadeCtor": "NewBetaV4", "facadeType": "*BetaV4", "implType": "Beta", "when": "betaOn" }
+    ]
+  }]
+}`
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		genGraph(p.write("graph.json", graph(true)), p.out("graph.gen.go"), genOptions{})
+		assertContainsInOrder(t, p.read("graph.gen.go"),
+			"type AppBuilders struct {",
+			"Alpha *AlphaV4",
+			"Beta  *BetaV4",
+			"type AppResult struct {",
+			"Builders AppBuilders",
+			"alphaB := NewAlphaV4()",
+			"res.Builders.Alpha = alphaB",
+			"if betaOn {",
+			"betaB = NewBetaV4()",
+			"res.Builders.Beta = betaB",
+		)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		genGraph(p.write("graph.json", graph(false)), p.out("graph.gen.go"), genOptions{})
+		if out := p.read("graph.gen.go"); strings.Contains(out, "AppBuilders") || strings.Contains(out, "res.Builders") {
+			t.Fatalf("did not expect builders without exposeBuilders:\n%s", out)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// -------------------------
+// Orphaned generated files (di2 clean, -prune)
+// -------------------------
+
+// cleanOut is where "di2 clean" and -prune report removed files.
+var cleanOut io.Writer = os.Stdout
+
+// runClean removes the orphaned di2 files under the given dir (default "."):
+// generated files whose spec or graph no longer exists. With -n it only lists
+// them.
+func runClean(args []string) error {
+	fset := flag.NewFlagSet("di2 clean", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	dryRun := fset.Bool("n", false, "list the files that would be removed without removing them")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 1 {
+		return fmt.Errorf("usage: di2 clean [-n] [dir]")
+	}
+	root := "."
+	if fset.NArg() == 1 {
+		root = fset.Arg(0)
+	}
+	if !dirExists(root) {
+		return fmt.Errorf("clean: %s is not a directory", root)
+	}
+
+	goFiles, genFiles := doctorFiles(root)
+	var directives []generateDirective
+	for _, f := range goFiles {
+		directives = append(directives, readGenerateDirectives(f)...)
+	}
+	var orphans []string
+	for _, f := range genFiles {
+		if isOrphanedGenFile(f, headerDirs(f, root, directives)) {
+			orphans = append(orphans, f)
+		}
+	}
+	return removeOrphans(orphans, *dryRun)
+}
+
+// pruneBatchOutput removes the orphaned service facades directly in outDir
+// after a -specs batch. Their header paths are relative to di2's working
+// directory, like the ones the batch just wrote.
+func pruneBatchOutput(outDir string) error {
+	genFiles, err := filepath.Glob(filepath.Join(outDir, "*.gen.go"))
+	if err != nil {
+		return err
+	}
+	var orphans []string
+	for _, f := range genFiles {
+		if isOrphanedGenFile(f, []string{"."}) {
+			orphans = append(orphans, f)
+		}
+	}
+	return removeOrphans(orphans, false)
+}
+
+// isOrphanedGenFile reports whether file is a di2 file whose spec or graph does
+// not resolve against dirs. Only files with a complete di2 header (path and
+// hash) qualify, so hand-written and foreign generated files are never removed;
+// neither are files generated from stdin.
+func isOrphanedGenFile(file string, dirs []string) bool {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	h, generated := readDI2Header(raw)
+	if !generated || h.Kind == "" || h.Path == "" || h.Path == "-" || h.Hash == "" {
+		return false
+	}
+	return resolveHeaderPath(h, dirs) == ""
+}
+
+func removeOrphans(files []string, dryRun bool) error {
+	var errs []error
+	for _, f := range files {
+		if dryRun {
+			_, _ = fmt.Fprintf(cleanOut, "would remove %s\n", filepath.ToSlash(f))
+			continue
+		}
+		if err := os.Remove(f); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(cleanOut, "removed %s\n", filepath.ToSlash(f))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// di2 clean / -prune
+// -------------------------
+
+func swapCleanOut(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := cleanOut
+	cleanOut = &buf
+	t.Cleanup(func() { cleanOut = old })
+	return &buf
+}
+
+func TestRunClean(t *testing.T) {
+	// NOT parallel: swaps cleanOut
+	buf := swapCleanOut(t)
+
+	p := newPkg(t)
+	writeDoctorProject(p)
+	p.write("svc/orphan_v4.gen.go", doctorGenFile("specs/gone.inject.json", "deadbeef"))
+	p.write("svc/stale_v4.gen.go", doctorGenFile("specs/core.inject.json", "deadbeef"))
+	p.write("svc/hand.gen.go", "package svc\n")
+	p.write("svc/nohash_v4.gen.go", "// Code generated by (di v2); DO NOT EDIT.\n// Spec: specs/gone.inject.json\n\npackage svc\n")
+
+	if err := runClean([]string{"-n", p.dir}); err != nil {
+		t.Fatalf("clean -n: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); !strings.HasPrefix(got, "would remove ") || !strings.HasSuffix(got, "svc/orphan_v4.gen.go") {
+		t.Fatalf("clean -n should list only the orphan, got %q", got)
+	}
+	if !fileExists(p.out("svc/orphan_v4.gen.go")) {
+		t.Fatal("clean -n must not remove files")
+	}
+
+	buf.Reset()
+	if err := runClean([]string{p.dir}); err != nil {
+		t.Fatalf("clean: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "removed ") {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+	if fileExists(p.out("svc/orphan_v4.gen.go")) {
+		t.Fatal("orphan should be removed")
+	}
+	for _, keep := range []string{"svc/core_v4.gen.go", "svc/stale_v4.gen.go", "svc/hand.gen.go", "svc/nohash_v4.gen.go", "svc/pb.gen.go"} {
+		if !fileExists(p.out(keep)) {
+			t.Fatalf("%s must be kept", keep)
+		}
+	}
+
+	if err := runClean([]string{"a", "b"}); err == nil {
+		t.Fatal("expected a usage error")
+	}
+	if err := runClean([]string{p.out("missing")}); err == nil {
+		t.Fatal("expected an error for a missing dir")
+	}
+}
+
+func TestRunClean_FacadeGeneratedFromModuleRoot(t *testing.T) {
+	// NOT parallel: swaps cleanOut
+	buf := swapCleanOut(t)
+
+	// di2 -spec svc/specs/core.inject.json -out svc/root_v4.gen.go, run from the module root
+	p := newPkg(t)
+	writeDoctorProject(p)
+	p.write("svc/root_v4.gen.go", doctorGenFile("svc/specs/core.inject.json", specSHA256([]byte(doctorSpec))))
+
+	for _, root := range []string{p.dir, p.out("svc")} {
+		if err := runClean([]string{"-n", root}); err != nil {
+			t.Fatalf("clean -n %s: %v", root, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("clean -n %s: a facade whose spec exists is not an orphan, got %q", root, buf.String())
+		}
+	}
+	if got := doctor(p.dir); len(got) != 0 {
+		t.Fatalf("doctor: expected no findings, got %+v", got)
+	}
+}
+
+func TestRun_BatchPrune(t *testing.T) {
+	// NOT parallel: swaps cleanOut
+	buf := swapCleanOut(t)
+
+	p := newPkg(t)
+	writeDISource(p)
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+	renamed := writeBatchSpec(p, "beta", "Beta", "V4")
+	p.write("hand.gen.go", "package p\n")
+	args := []string{"-out", p.dir, "-specs", p.out("specs"), "-prune"}
+
+	if err := run(args); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("nothing should be pruned yet, got %q", buf.String())
+	}
+
+	// rename beta -> gamma: beta_v4.gen.go is now a zombie
+	if err := os.Rename(renamed, p.out("specs/gamma.inject.json")); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := run(args); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if fileExists(p.out("beta_v4.gen.go")) {
+		t.Fatal("orphaned facade should be pruned")
+	}
+	for _, keep := range []string{"alpha_v4.gen.go", "gamma_v4.gen.go", "hand.gen.go"} {
+		if !fileExists(p.out(keep)) {
+			t.Fatalf("%s must be kept", keep)
+		}
+	}
+	if !strings.Contains(buf.String(), "removed ") {
+		t.Fatalf("prune should report removals, got %q", buf.String())
+	}
+
+	if err := run([]string{"-out", p.dir, "-spec", p.out("specs/alpha.inject.json"), "-prune"}); err == nil || err.Error() != "-prune needs -specs" {
+		t.Fatalf("expected -prune needs -specs, got %v", err)
+	}
+}
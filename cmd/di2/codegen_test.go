@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Codegen header / build tags
+// -------------------------
+
+func TestCodegenPreamble(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   CodegenSpec
+		want string
+	}{
+		{name: "empty", in: CodegenSpec{}, want: ""},
+		{name: "blank_header_ignored", in: CodegenSpec{Header: " \n\n"}, want: ""},
+		{
+			name: "header_lines_commented",
+			in:   CodegenSpec{Header: "Copyright 2026 Acme\n\nSPDX-License-Identifier: MIT\n// Owner: team-x\n"},
+			want: "// Copyright 2026 Acme\n//\n// SPDX-License-Identifier: MIT\n// Owner: team-x\n\n",
+		},
+		{name: "single_tag", in: CodegenSpec{BuildTags: []string{"!wireinject"}}, want: "//go:build !wireinject\n\n"},
+		{
+			name: "tags_anded_with_parens",
+			in:   CodegenSpec{BuildTags: []string{"linux || darwin", " !wireinject "}},
+			want: "//go:build (linux || darwin) && !wireinject\n\n",
+		},
+		{
+			name: "header_then_tags",
+			in:   CodegenSpec{Header: "License", BuildTags: []string{"prod"}},
+			want: "// License\n\n//go:build prod\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := codegenPreamble(tt.in, "spec"); got != tt.want {
+				t.Fatalf("got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+
+	assertPanicContains(t, func() {
+		codegenPreamble(CodegenSpec{BuildTags: []string{"ok", "a &&"}}, "graph spec")
+	}, `graph spec codegen.buildTags[1] "a &&" is not a valid build constraint`)
+}
+
+func TestGenService_CodegenPreamble(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "A", "field": "a", "type": "*A", "nilable": true }],
+  "codegen": { "header": "Copyright Acme\nSPDX-License-Identifier: Apache-2.0", "buildTags": ["!wireinject"] }
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	want := "// Copyright Acme\n// SPDX-License-Identifier: Apache-2.0\n\n//go:build !wireinject\n\n// Code generated by (di v2); DO NOT EDIT.\n"
+	if !strings.HasPrefix(out, want) {
+		t.Fatalf("unexpected file start:\n%s", out[:min(len(out), 300)])
+	}
+}
+
+func TestGenGraph_CodegenPreamble(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{"package": "p", "codegen": {"buildTags": ["a", "b"]},
+  "roots": [{"name": "App", "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }]}]}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	if out := p.read("graph.gen.go"); !strings.HasPrefix(out, "//go:build a && b\n\n// Code generated by (di v2); DO NOT EDIT.\n") {
+		t.Fatalf("unexpected file start:\n%s", out[:min(len(out), 200)])
+	}
+}
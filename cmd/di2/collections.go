@@ -0,0 +1,58 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"strings"
+)
+
+// -------------------------
+// Slice and map deps
+// -------------------------
+
+// setCollectionDeps classifies required deps typed as []E or map[K]V (plugin-style
+// fan-out deps). They get a per-item Inject<Name>, a bulk Inject<Plural>, and
+// count as wired once at least Min (default 1) items were injected.
+//
+// It runs after externalizeService, so element types carry the same package
+// qualifiers as Type.
+func setCollectionDeps(s *ServiceSpec) {
+	names := make(map[string]bool, len(s.Required))
+	for _, d := range s.Required {
+		names[d.Name] = true
+	}
+	for i := range s.Required {
+		d := &s.Required[i]
+		src := strings.TrimSpace(d.Type)
+		if expr, err := parser.ParseExpr(src); err == nil {
+			part := func(n ast.Node) string { return src[n.Pos()-1 : n.End()-1] }
+			switch t := expr.(type) {
+			case *ast.ArrayType:
+				if t.Len == nil {
+					d.Kind, d.ElemType = "slice", part(t.Elt)
+				}
+			case *ast.MapType:
+				d.Kind, d.KeyType, d.ElemType = "map", part(t.Key), part(t.Value)
+			}
+		}
+
+		if d.Kind == "" {
+			if d.Min != 0 || d.Plural != "" {
+				die("required dep " + d.Name + ": min/plural require a slice or map type (got " + d.Type + ")")
+			}
+			continue
+		}
+		if d.Min < 0 {
+			die("required dep " + d.Name + ": min must be >= 0")
+		}
+		if d.Min == 0 {
+			d.Min = 1
+		}
+		if d.Plural == "" {
+			d.Plural = d.Name + "s"
+		}
+		if d.Plural == d.Name || names[d.Plural] {
+			die("required dep " + d.Name + ": plural " + d.Plural + " collides with a dep name")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// -------------------------
+// Slice and map deps
+// -------------------------
+
+func TestGenService_CollectionDeps(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("checkout.inject.json", `{
+  "package": "p", "wrapperBase": "Checkout", "versionSuffix": "V4",
+  "implType": "Checkout", "constructor": "NewCheckout",
+  "required": [
+    { "name": "DB", "field": "db", "type": "*DB", "nilable": true },
+    { "name": "Provider", "field": "providers", "type": "[]PaymentProvider", "nilable": true, "min": 2 },
+    { "name": "Region", "field": "regions", "type": "map[string]PaymentProvider", "nilable": true, "plural": "RegionMap" }
+  ]
+}`)
+
+	genService(specPath, p.out("checkout.gen.go"), genOptions{})
+	out := p.read("checkout.gen.go")
+
+	assertContainsInOrder(t, out,
+		"func (b *CheckoutV4) TryInjectDB(dep *DB) (*CheckoutV4, error) {",
+		"func (b *CheckoutV4) TryInjectProvider(dep PaymentProvider) (*CheckoutV4, error) {",
+		"b.svc.providers = append(b.svc.providers, dep)",
+		"func (b *CheckoutV4) InjectProvider(dep PaymentProvider) *CheckoutV4 {",
+		"func (b *CheckoutV4) InjectProviders(deps ...PaymentProvider) *CheckoutV4 {",
+		"func (b *CheckoutV4) TryInjectRegion(key string, dep PaymentProvider) (*CheckoutV4, error) {",
+		"b.svc.regions = make(map[string]PaymentProvider)",
+		`di.CheckInject("CheckoutV4", CheckoutV4InjectPolicyOnOverwrite, fmt.Sprintf("Region[%v]", key), exists)`,
+		"m[key] = dep",
+		"func (b *CheckoutV4) InjectRegionMap(deps map[string]PaymentProvider) *CheckoutV4 {",
+		"b.svc.db == nil",
+		"len(b.svc.providers) < 2",
+		"len(b.svc.regions) < 1",
+	)
+}
+
+func TestSetCollectionDeps(t *testing.T) {
+	t.Parallel()
+
+	spec := ServiceSpec{Required: []RequiredDep{
+		{Name: "A", Type: "*A"},
+		{Name: "P", Type: "[]v4.Provider"},
+		{Name: "M", Type: "map[Key][]*Handler", Min: 3, Plural: "Ms"},
+		{Name: "Arr", Type: "[2]int"},
+	}}
+	setCollectionDeps(&spec)
+
+	want := []RequiredDep{
+		{Name: "A", Type: "*A"},
+		{Name: "P", Type: "[]v4.Provider", Kind: "slice", ElemType: "v4.Provider", Min: 1, Plural: "Ps"},
+		{Name: "M", Type: "map[Key][]*Handler", Kind: "map", KeyType: "Key", ElemType: "[]*Handler", Min: 3, Plural: "Ms"},
+		{Name: "Arr", Type: "[2]int"},
+	}
+	for i := range want {
+		if !reflect.DeepEqual(spec.Required[i], want[i]) {
+			t.Fatalf("dep %d: got %+v want %+v", i, spec.Required[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		name string
+		deps []RequiredDep
+		want string
+	}{
+		{"min on single dep", []RequiredDep{{Name: "A", Type: "*A", Min: 2}}, "required dep A: min/plural require a slice or map type (got *A)"},
+		{"plural on single dep", []RequiredDep{{Name: "A", Type: "*A", Plural: "As"}}, "min/plural require a slice or map type"},
+		{"negative min", []RequiredDep{{Name: "P", Type: "[]P", Min: -1}}, "required dep P: min must be >= 0"},
+		{"plural is name", []RequiredDep{{Name: "P", Type: "[]P", Plural: "P"}}, "required dep P: plural P collides with a dep name"},
+		{"plural is other dep", []RequiredDep{{Name: "P", Type: "[]P"}, {Name: "Ps", Type: "*P"}}, "plural Ps collides with a dep name"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := ServiceSpec{Required: tt.deps}
+			assertPanicContains(t, func() { setCollectionDeps(&s) }, tt.want)
+		})
+	}
+}
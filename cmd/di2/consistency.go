@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Cross-spec dep type consistency (-specs)
+// -------------------------
+
+// depTypeUse is one spec declaring a dep (by name or registry key) with a type.
+type depTypeUse struct {
+	spec string
+	typ  string
+}
+
+// checkBatchDepTypes fails when specs of one batch declare the same dep name,
+// or the same optional registry key, with different types (e.g. Logger as
+// *Logger in one spec and *LoggerV2 in another): drift like that surfaces
+// later as registry type-assertion failures. Specs that do not read are
+// skipped; generating them reports why.
+func checkBatchDepTypes(specs []string) error {
+	byName := map[string][]depTypeUse{}
+	byKey := map[string][]depTypeUse{}
+	for _, path := range specs {
+		spec, ok := tryReadServiceSpec(path)
+		if !ok {
+			continue
+		}
+		use := func(m map[string][]depTypeUse, id, typ string) {
+			if typ = strings.Join(strings.Fields(typ), ""); typ != "" {
+				m[id] = append(m[id], depTypeUse{spec: filepath.Base(path), typ: typ})
+			}
+		}
+		for _, d := range spec.Required {
+			use(byName, d.Name, d.Type)
+		}
+		for _, o := range spec.Optional {
+			use(byName, o.Name, o.Type)
+			use(byKey, o.RegistryKey, o.Type)
+		}
+	}
+	return errors.Join(depTypeDrift("dep", byName), depTypeDrift("registry key", byKey))
+}
+
+// depTypeDrift reports the ids of uses declared with more than one type,
+// sorted, listing each type with the specs declaring it.
+func depTypeDrift(what string, uses map[string][]depTypeUse) error {
+	ids := make([]string, 0, len(uses))
+	for id := range uses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var errs []error
+	for _, id := range ids {
+		var types []string
+		specs := map[string][]string{}
+		for _, u := range uses[id] {
+			if specs[u.typ] == nil {
+				types = append(types, u.typ)
+			}
+			specs[u.typ] = append(specs[u.typ], u.spec)
+		}
+		if len(types) < 2 {
+			continue
+		}
+		parts := make([]string, 0, len(types))
+		for _, t := range types {
+			parts = append(parts, fmt.Sprintf("%s (%s)", t, strings.Join(specs[t], ", ")))
+		}
+		errs = append(errs, fmt.Errorf("%s %s has inconsistent types across specs: %s", what, id, strings.Join(parts, " vs ")))
+	}
+	return errors.Join(errs...)
+}
+
+// tryReadServiceSpec is readServiceSpec reporting failure instead of dying.
+func tryReadServiceSpec(path string) (spec ServiceSpec, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	spec, _ = readServiceSpec(path)
+	return spec, true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Cross-spec dep type consistency (-specs)
+// -------------------------
+
+// writeDriftSpec writes specs/<name>.inject.json with the given required and
+// optional deps JSON.
+func writeDriftSpec(p *pkgHarness, name, base, required, optional string) {
+	p.write("specs/"+name+".inject.json", `{
+  "package": "p", "wrapperBase": "`+base+`", "versionSuffix": "V4", "implType": "`+base+`", "constructor": "New`+base+`",
+  "required": [`+required+`],
+  "optional": [`+optional+`]
+}`)
+}
+
+func TestGenServiceBatch_DepTypeDrift(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeDriftSpec(p, "alpha", "Alpha",
+		`{ "name": "Logger", "field": "logger", "type": "*Logger", "nilable": true }`,
+		`{ "name": "Tracer", "type": "Tracer", "registryKey": "p.tracer", "apply": { "kind": "field", "name": "tracer" } }`)
+	writeDriftSpec(p, "beta", "Beta",
+		`{ "name": "Logger", "field": "logger", "type": "*LoggerV2", "nilable": true }`,
+		`{ "name": "Tracing", "type": "*Tracer", "registryKey": "p.tracer", "apply": { "kind": "field", "name": "tracer" } }`)
+	writeDriftSpec(p, "core", "Core",
+		`{ "name": "Logger", "field": "logger", "type": "* Logger", "nilable": true }`, ``)
+
+	results, err := genServiceBatch(p.out("specs"), p.dir, 2, genOptions{})
+	if err == nil {
+		t.Fatal("expected a dep type drift error")
+	}
+	got := err.Error()
+	for _, want := range []string{
+		"dep Logger has inconsistent types across specs: *Logger (alpha.inject.json, core.inject.json) vs *LoggerV2 (beta.inject.json)",
+		"registry key p.tracer has inconsistent types across specs: Tracer (alpha.inject.json) vs *Tracer (beta.inject.json)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("error missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "dep Tracer") || strings.Contains(got, "dep Tracing") {
+		t.Fatalf("names declared once must not be reported:\n%s", got)
+	}
+	// drift does not stop generation of the specs themselves
+	for _, r := range results {
+		if r.Err != nil || !fileExists(r.Out) {
+			t.Fatalf("result %+v", r)
+		}
+	}
+}
+
+func TestCheckBatchDepTypes_Consistent(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	alpha := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	beta := writeBatchSpec(p, "beta", "Beta", "V4")
+	broken := p.write("specs/broken.inject.json", `{`)
+
+	if err := checkBatchDepTypes([]string{alpha, beta, broken}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
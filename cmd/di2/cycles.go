@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// -------------------------
+// Declared wiring cycles (cyclic, cycles)
+// -------------------------
+
+// validateSpecCycles checks that every cycles entry names a required dep, once.
+func validateSpecCycles(s *ServiceSpec) {
+	seen := map[string]bool{}
+	for _, name := range s.Cycles {
+		if !slices.ContainsFunc(s.Required, func(d RequiredDep) bool { return d.Name == name }) {
+			die(fmt.Sprintf("spec cycles: %q is not a required dep", name))
+		}
+		if seen[name] {
+			die(fmt.Sprintf("spec cycles: %q is listed twice", name))
+		}
+		seen[name] = true
+	}
+}
+
+// specCycleDeps returns the required deps s lists in cycles.
+func specCycleDeps(s ServiceSpec) []RequiredDep {
+	var out []RequiredDep
+	for _, d := range s.Required {
+		if slices.Contains(s.Cycles, d.Name) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// wiresDep reports whether a wiring call ("InjectDB", "TryInjectTopic",
+// "InjectTopics") wires the required dep d.
+func wiresDep(call string, d RequiredDep) bool {
+	name := strings.TrimPrefix(strings.TrimPrefix(call, "Try"), "Inject")
+	return name == d.Name || (d.Plural != "" && name == d.Plural) || name == d.Name+"s"
+}
+
+// cycleEdgeDeclared reports whether the wiring is a declared cycle edge: marked
+// "cycle" in the graph, or declared by the spec of its target (any dep of a
+// cyclic spec without cycles, or one of cycles).
+func cycleEdgeDeclared(to GraphService, w GraphWiring) bool {
+	if w.Cycle {
+		return true
+	}
+	if !to.Cyclic {
+		return false
+	}
+	if len(to.CycleDeps) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(to.CycleDeps, func(d RequiredDep) bool { return wiresDep(w.Call, d) })
+}
+
+// validateGraphCycles makes cycle wiring a checked contract between the graph
+// and its linked specs:
+//
+//   - every cycle of the root's wiring ("argFrom" -> "to") must contain a
+//     declared edge (see cycleEdgeDeclared); removing the declared edges must
+//     leave the wiring acyclic;
+//   - every wiring marked "cycle", or of a dep listed in cycles, must be part
+//     of a cycle.
+//
+// Wiring from shared services is not part of the root's cycles.
+func validateGraphCycles(root GraphRoot) {
+	byVar := make(map[string]GraphService, len(root.Services))
+	for _, s := range root.Services {
+		byVar[s.Var] = s
+	}
+	var edges []GraphWiring
+	for _, w := range root.Wiring {
+		if _, ok := byVar[w.To]; !ok {
+			continue
+		}
+		if _, ok := byVar[w.ArgFrom]; !ok {
+			continue
+		}
+		edges = append(edges, w)
+	}
+
+	var undeclared []GraphWiring
+	for _, w := range edges {
+		if !cycleEdgeDeclared(byVar[w.To], w) {
+			undeclared = append(undeclared, w)
+		}
+	}
+	if cycle := findWiringCycle(root.Services, undeclared); cycle != nil {
+		calls := make([]string, len(cycle))
+		for i, w := range cycle {
+			calls[i] = fmt.Sprintf("%s.%s(%s)", w.To, w.Call, w.ArgFrom)
+		}
+		die(fmt.Sprintf("graph root %s: undeclared wiring cycle %s; declare the dep closing it in its service's linked spec (\"cycles\") or mark the wiring \"cycle\": true",
+			root.Name, strings.Join(calls, " -> ")))
+	}
+
+	for _, w := range edges {
+		to := byVar[w.To]
+		explicit := w.Cycle || (len(to.CycleDeps) > 0 && cycleEdgeDeclared(to, w))
+		if explicit && !wiringReaches(edges, w.To, w.ArgFrom) {
+			die(fmt.Sprintf("graph root %s: %s.%s(%s) is declared a cycle edge but is not part of a wiring cycle",
+				root.Name, w.To, w.Call, w.ArgFrom))
+		}
+	}
+}
+
+// findWiringCycle returns the wiring of a cycle in edges (in wiring order:
+// each entry's "to" is the next one's "argFrom"), or nil. Services are visited
+// in spec order, so the reported cycle is deterministic.
+func findWiringCycle(services []GraphService, edges []GraphWiring) []GraphWiring {
+	out := map[string][]GraphWiring{}
+	for _, w := range edges {
+		out[w.ArgFrom] = append(out[w.ArgFrom], w)
+	}
+	const (
+		unvisited = iota
+		active
+		done
+	)
+	state := map[string]int{}
+	var stack []GraphWiring
+	var visit func(v string) []GraphWiring
+	visit = func(v string) []GraphWiring {
+		state[v] = active
+		for _, w := range out[v] {
+			switch state[w.To] {
+			case active:
+				stack = append(stack, w)
+				for i, s := range stack {
+					if s.ArgFrom == w.To {
+						return slices.Clone(stack[i:])
+					}
+				}
+			case unvisited:
+				stack = append(stack, w)
+				if c := visit(w.To); c != nil {
+					return c
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+		state[v] = done
+		return nil
+	}
+	for _, s := range services {
+		if state[s.Var] == unvisited {
+			if c := visit(s.Var); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// wiringReaches reports whether to is reachable from from along edges.
+func wiringReaches(edges []GraphWiring, from, to string) bool {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if v == to {
+			return true
+		}
+		for _, w := range edges {
+			if w.ArgFrom == v && !seen[w.To] {
+				seen[w.To] = true
+				queue = append(queue, w.To)
+			}
+		}
+	}
+	return false
+}
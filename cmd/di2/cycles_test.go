@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Declared wiring cycles (cyclic, cycles)
+// -------------------------
+
+// writeCycleSpec writes specs/<lower(name)>.inject.json requiring deps, with
+// extra top-level fields (e.g. `"cycles": ["Beta"],`).
+func writeCycleSpec(p *pkgHarness, name, extra string, deps ...string) {
+	var req []string
+	for _, d := range deps {
+		req = append(req, `{ "name": "`+d+`", "field": "`+strings.ToLower(d)+`", "type": "*`+d+`", "nilable": true }`)
+	}
+	p.write("specs/"+strings.ToLower(name)+".inject.json", `{
+  "package": "p", "wrapperBase": "`+name+`", "versionSuffix": "V4", "implType": "`+name+`", "constructor": "New`+name+`",
+  `+extra+`
+  "required": [`+strings.Join(req, ", ")+`]
+}`)
+}
+
+const cycleGraph = `{"package": "p", "roots": [{"name": "App",
+  "services": [
+    {"var": "alpha", "spec": "alpha.inject.json"},
+    {"var": "beta", "spec": "beta.inject.json"},
+    {"var": "core", "spec": "core.inject.json"}
+  ],
+  "wiring": [
+    {"to": "alpha", "call": "InjectBeta", "argFrom": "beta"},
+    {"to": "beta", "call": "InjectAlpha", "argFrom": "alpha"},
+    {"to": "core", "call": "InjectAlpha", "argFrom": "alpha"}
+  ]
+}]}`
+
+func TestGenGraph_DeclaredCycles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		alpha, beta string // extra spec fields
+		want        string // panic substring; empty means generation succeeds
+	}{
+		{
+			name: "undeclared",
+			want: `graph root App: undeclared wiring cycle beta.InjectAlpha(alpha) -> alpha.InjectBeta(beta); declare the dep closing it`,
+		},
+		{name: "one_edge_declared", alpha: `"cycles": ["Beta"],`},
+		{name: "legacy_cyclic", beta: `"cyclic": true,`},
+		{
+			name:  "both_declared",
+			alpha: `"cycles": ["Beta"],`,
+			beta:  `"cycles": ["Alpha"],`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			writeCycleSpec(p, "Alpha", tt.alpha, "Beta")
+			writeCycleSpec(p, "Beta", tt.beta, "Alpha")
+			writeCycleSpec(p, "Core", "", "Alpha")
+			graph := p.write("specs/graph.json", cycleGraph)
+
+			if tt.want == "" {
+				genGraph(graph, p.out("graph.gen.go"), genOptions{})
+				return
+			}
+			assertPanicContains(t, func() { genGraph(graph, p.out("graph.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+
+	// core declares a cycle through Alpha, but core -> alpha closes none
+	p := newPkg(t)
+	writeDISource(p)
+	writeCycleSpec(p, "Alpha", `"cycles": ["Beta"],`, "Beta")
+	writeCycleSpec(p, "Beta", "", "Alpha")
+	writeCycleSpec(p, "Core", `"cycles": ["Alpha"],`, "Alpha")
+	graph := p.write("specs/graph.json", cycleGraph)
+	assertPanicContains(t, func() {
+		genGraph(graph, p.out("graph.gen.go"), genOptions{})
+	}, "graph root App: core.InjectAlpha(alpha) is declared a cycle edge but is not part of a wiring cycle")
+}
+
+func TestValidateGraphCycles_WiringDeclarations(t *testing.T) {
+	t.Parallel()
+	services := []GraphService{{Var: "a"}, {Var: "b"}, {Var: "db"}}
+
+	// a self-loop is a cycle too
+	assertPanicContains(t, func() {
+		validateGraphCycles(GraphRoot{Name: "App", Services: services, Wiring: []GraphWiring{{To: "a", Call: "InjectSelf", ArgFrom: "a"}}})
+	}, "undeclared wiring cycle a.InjectSelf(a)")
+
+	// graphs without linked specs mark the closing wiring
+	validateGraphCycles(GraphRoot{Name: "App", Services: services, Wiring: []GraphWiring{
+		{To: "a", Call: "InjectB", ArgFrom: "b", Cycle: true},
+		{To: "b", Call: "InjectA", ArgFrom: "a"},
+		{To: "a", Call: "InjectShared", ArgFrom: "shared"}, // not a service of the root
+	}})
+
+	assertPanicContains(t, func() {
+		validateGraphCycles(GraphRoot{Name: "App", Services: services, Wiring: []GraphWiring{{To: "a", Call: "InjectDB", ArgFrom: "db", Cycle: true}}})
+	}, "a.InjectDB(db) is declared a cycle edge but is not part of a wiring cycle")
+}
+
+func TestValidateSpecCycles(t *testing.T) {
+	t.Parallel()
+	s := &ServiceSpec{Required: []RequiredDep{{Name: "Alpha"}, {Name: "Topic", Kind: "slice", Plural: "Topics"}}}
+
+	s.Cycles = []string{"Alpha", "Topic"}
+	validateSpecCycles(s)
+	if got := specCycleDeps(*s); len(got) != 2 {
+		t.Fatalf("cycle deps = %v", got)
+	}
+	if !wiresDep("InjectTopics", s.Required[1]) || !wiresDep("TryInjectTopic", s.Required[1]) || wiresDep("InjectAlpha", s.Required[1]) {
+		t.Fatal("wiresDep must match Inject<Name>, TryInject<Name> and Inject<Plural>")
+	}
+
+	s.Cycles = []string{"Beta"}
+	assertPanicContains(t, func() { validateSpecCycles(s) }, `spec cycles: "Beta" is not a required dep`)
+	s.Cycles = []string{"Alpha", "Alpha"}
+	assertPanicContains(t, func() { validateSpecCycles(s) }, `spec cycles: "Alpha" is listed twice`)
+}
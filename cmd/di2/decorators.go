@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// -------------------------
+// Method decorators
+// -------------------------
+
+// setMethodDecorators computes each method's decorator chain: di.DepthGuard for
+// the methods of recursionGuard (outermost), the service-level decorators, the
+// method's own, then di.Retry and di.Timeout from
+// its retry and timeoutMs (innermost, so every attempt gets the full timeout).
+// Only methods whose last return is error are decorated (or recover panics,
+// with recoverPanics), because both report through that error; per-method
+// decorators, retry, timeoutMs or recoverPanics on any other method are a spec
+// error. It also records the method's context parameter, which the chain passes
+// through.
+func setMethodDecorators(s *ServiceSpec) {
+	for _, d := range s.Decorators {
+		if strings.TrimSpace(d) == "" {
+			die("spec decorators must be non-empty Go expressions")
+		}
+	}
+	guarded := recursionGuardMethods(s)
+	s.Recovers = false
+	for i := range s.Methods {
+		m := &s.Methods[i]
+		m.Decorate, m.Recover, m.CtxParam = nil, false, ""
+		for _, d := range m.Decorators {
+			if strings.TrimSpace(d) == "" {
+				die("method " + m.Name + " decorators must be non-empty Go expressions")
+			}
+		}
+		ctxParam := ""
+		if len(m.Params) > 0 && m.Params[0].Type == "context.Context" {
+			ctxParam = m.Params[0].Name
+		}
+
+		own := append([]string(nil), m.Decorators...)
+		if r := m.Retry; r != nil {
+			if r.Attempts < 1 || r.BackoffMs < 0 {
+				die("method " + m.Name + " retry needs attempts >= 1 and backoffMs >= 0")
+			}
+			own = append(own, fmt.Sprintf("di.Retry(%d, %s)", r.Attempts, millis(r.BackoffMs)))
+		}
+		switch {
+		case m.TimeoutMs < 0:
+			die("method " + m.Name + " timeoutMs must be >= 0")
+		case m.TimeoutMs > 0 && ctxParam == "":
+			die("method " + m.Name + " timeoutMs requires a context.Context first parameter")
+		case m.TimeoutMs > 0:
+			own = append(own, fmt.Sprintf("di.Timeout(%s)", millis(m.TimeoutMs)))
+		}
+
+		if !returnsError(m.Returns) {
+			if len(own) > 0 || m.RecoverPanics {
+				die("method " + m.Name + " decorators, retry, timeoutMs and recoverPanics require error as the last return")
+			}
+			continue
+		}
+		m.Decorate = append(append([]string(nil), s.Decorators...), own...)
+		if guarded[m.Name] {
+			m.Decorate = append([]string{fmt.Sprintf("di.DepthGuard(%d)", s.RecursionGuard.MaxDepth)}, m.Decorate...)
+		}
+		m.Recover = s.RecoverPanics || m.RecoverPanics
+		if len(m.Decorate) > 0 || m.Recover {
+			m.CtxParam = ctxParam
+		}
+		s.Recovers = s.Recovers || m.Recover
+	}
+}
+
+// recursionGuardMethods validates recursionGuard and returns the names of the
+// methods it guards. Only cyclic services recurse through their deps, and only
+// methods passing a context on can carry the depth.
+func recursionGuardMethods(s *ServiceSpec) map[string]bool {
+	g := s.RecursionGuard
+	if g == nil {
+		return nil
+	}
+	switch {
+	case !s.Cyclic && len(s.Cycles) == 0:
+		die("spec recursionGuard needs a cyclic spec (cyclic or cycles)")
+	case g.MaxDepth < 1:
+		die("spec recursionGuard.maxDepth must be >= 1")
+	case len(g.Methods) == 0:
+		die("spec recursionGuard.methods must be non-empty")
+	}
+	guarded := make(map[string]bool, len(g.Methods))
+	for _, name := range g.Methods {
+		i := slices.IndexFunc(s.Methods, func(m MethodSpec) bool { return m.Name == name })
+		switch {
+		case i < 0:
+			die(fmt.Sprintf("spec recursionGuard.methods: %q is not a method", name))
+		case guarded[name]:
+			die(fmt.Sprintf("spec recursionGuard.methods: %q is listed twice", name))
+		}
+		m := s.Methods[i]
+		if len(m.Params) == 0 || m.Params[0].Type != "context.Context" || !returnsError(m.Returns) {
+			die(fmt.Sprintf("spec recursionGuard.methods: %s needs a context.Context first parameter and error as the last return", name))
+		}
+		guarded[name] = true
+	}
+	return guarded
+}
+
+func returnsError(returns []MethodReturn) bool {
+	return len(returns) > 0 && returns[len(returns)-1].Type == "error"
+}
+
+// millis renders ms as a time.Duration expression.
+func millis(ms int) string {
+	if ms == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d*time.Millisecond", ms)
+}
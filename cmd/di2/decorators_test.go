@@ -0,0 +1,285 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Method decorators
+// -------------------------
+
+func TestGenService_Decorators(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  "decorators": ["Trace(\"core\")", "di.Chain()"],
+  "methods": [
+    { "name": "Process", "decorators": ["Limit(2 * time.Second)"],
+      "params": [{ "name": "c", "type": "context.Context" }, { "name": "req", "type": "Request" }],
+      "returns": [{ "type": "Response" }, { "type": "error" }] },
+    { "name": "Ping", "returns": [{ "type": "error" }] },
+    { "name": "Name", "returns": [{ "type": "string" }] },
+    { "name": "Reset", "chain": true }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "time")
+	assertContainsInOrder(t, out,
+		"// decorateCoreV4Ping is the decorator chain of CoreV4.Ping.",
+		"var decorateCoreV4Ping = di.Chain(\n\tTrace(\"core\"),\n\tdi.Chain(),\n)",
+		"func (b *CoreV4) Ping() error {",
+		`err = decorateCoreV4Ping(context.Background(), di.MethodCall{Facade: "CoreV4", Method: "Ping"}, func(_ context.Context) error {`,
+		"return svc.Ping()",
+		"return err\n}",
+
+		"var decorateCoreV4Process = di.Chain(\n\tTrace(\"core\"),\n\tdi.Chain(),\n\tLimit(2*time.Second),\n)",
+		"func (b *CoreV4) Process(",
+		"var out0 Response",
+		`err = decorateCoreV4Process(c, di.MethodCall{Facade: "CoreV4", Method: "Process"}, func(c context.Context) error {`,
+		"var err error",
+		"out0, err = svc.Process(\n\t\t\tc,\n\t\t\treq,\n\t\t)",
+		"return err\n\t})",
+		"return out0, err\n}",
+	)
+	for _, m := range []string{"decorateCoreV4Name", "decorateCoreV4Reset"} {
+		if strings.Contains(out, m) {
+			t.Fatalf("methods without an error return must not be decorated (%s):\n%s", m, out)
+		}
+	}
+}
+
+func TestGenService_RetryTimeout(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  "decorators": ["Trace"],
+  "methods": [
+    { "name": "Fetch", "timeoutMs": 250, "retry": { "attempts": 3, "backoffMs": 50 },
+      "params": [{ "name": "ctx", "type": "context.Context" }], "returns": [{ "type": "error" }] },
+    { "name": "Ping", "retry": { "attempts": 2 }, "returns": [{ "type": "error" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "time")
+	assertContainsInOrder(t, out,
+		"var decorateCoreV4Fetch = di.Chain(\n\tTrace,\n\tdi.Retry(3, 50*time.Millisecond),\n\tdi.Timeout(250*time.Millisecond),\n)",
+		`err = decorateCoreV4Fetch(ctx, di.MethodCall{Facade: "CoreV4", Method: "Fetch"}, func(ctx context.Context) error {`,
+		"var decorateCoreV4Ping = di.Chain(\n\tTrace,\n\tdi.Retry(2, 0),\n)",
+		`err = decorateCoreV4Ping(context.Background(), `,
+	)
+}
+
+func TestGenService_RecoverPanics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "service_level",
+			spec: `"recoverPanics": true, "decorators": ["Trace"],
+  "methods": [
+    { "name": "Ping", "returns": [{ "type": "error" }] },
+    { "name": "Name", "returns": [{ "type": "string" }] }
+  ]`,
+			want: []string{
+				"\tpanicHandler di.PanicHandler\n}",
+				"var CoreV4PanicHandler di.PanicHandler",
+				"func (b *CoreV4) WithPanicHandler(h di.PanicHandler) *CoreV4 {",
+				"func (b *CoreV4) panicHandlerOrDefault() di.PanicHandler {",
+				"return CoreV4PanicHandler",
+				"panicHandler: b.panicHandler,",
+				"func (b *CoreV4) Name() string {",
+				"return svc.Name()",
+				"func (b *CoreV4) Ping() error {",
+				`err = di.CallRecovered(context.Background(), di.MethodCall{Facade: "CoreV4", Method: "Ping"}, "`,
+				`", b.panicHandlerOrDefault(), decorateCoreV4Ping, func(_ context.Context) error {`,
+			},
+		},
+		{
+			name: "method_level_without_decorators",
+			spec: `"methods": [
+    { "name": "Fetch", "recoverPanics": true, "params": [{ "name": "c", "type": "context.Context" }], "returns": [{ "type": "int" }, { "type": "error" }] },
+    { "name": "Ping", "returns": [{ "type": "error" }] }
+  ]`,
+			want: []string{
+				"func (b *CoreV4) Fetch(",
+				"var out0 int",
+				`err = di.CallRecovered(c, di.MethodCall{Facade: "CoreV4", Method: "Fetch"}, "`,
+				`", b.panicHandlerOrDefault(), nil, func(c context.Context) error {`,
+				"return out0, err",
+				"func (b *CoreV4) Ping() error {",
+				"return svc.Ping()",
+			},
+			notWant: []string{"decorateCoreV4"},
+		},
+		{
+			name:    "off",
+			spec:    `"methods": [{ "name": "Ping", "returns": [{ "type": "error" }] }]`,
+			notWant: []string{"panicHandler", "CallRecovered"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  `+tt.spec+`
+}`)
+			genService(specPath, p.out("core.gen.go"), genOptions{})
+			out := p.read("core.gen.go")
+			assertContainsInOrder(t, out, tt.want...)
+			for _, nw := range tt.notWant {
+				if strings.Contains(out, nw) {
+					t.Fatalf("did not expect %q in:\n%s", nw, out)
+				}
+			}
+		})
+	}
+}
+
+func TestGenService_RecursionGuard(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("alpha.inject.json", `{
+  "package": "p", "wrapperBase": "Alpha", "versionSuffix": "V4", "implType": "Alpha", "constructor": "NewAlpha",
+  "cycles": ["Beta"],
+  "recursionGuard": { "maxDepth": 8, "methods": ["DoAlpha"] },
+  "required": [{ "name": "Beta", "field": "beta", "type": "*Beta", "nilable": true }],
+  "decorators": ["Trace(\"alpha\")"],
+  "methods": [
+    { "name": "DoAlpha", "params": [{ "name": "ctx", "type": "context.Context" }], "returns": [{ "type": "error" }] },
+    { "name": "Ping", "params": [{ "name": "ctx", "type": "context.Context" }], "returns": [{ "type": "error" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("alpha.gen.go"), genOptions{})
+	out := p.read("alpha.gen.go")
+	assertContainsInOrder(t, out,
+		"var decorateAlphaV4DoAlpha = di.Chain(\n\tdi.DepthGuard(8),\n\tTrace(\"alpha\"),\n)",
+		`err = decorateAlphaV4DoAlpha(ctx, di.MethodCall{Facade: "AlphaV4", Method: "DoAlpha"}, func(ctx context.Context) error {`,
+		"var decorateAlphaV4Ping = di.Chain(\n\tTrace(\"alpha\"),\n)",
+	)
+}
+
+func TestGenService_RecursionGuardErrors(t *testing.T) {
+	t.Parallel()
+	const methods = `"methods": [
+    { "name": "DoAlpha", "params": [{ "name": "ctx", "type": "context.Context" }], "returns": [{ "type": "error" }] },
+    { "name": "Name", "returns": [{ "type": "string" }] }
+  ]`
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{name: "not_cyclic", spec: `"recursionGuard": { "maxDepth": 8, "methods": ["DoAlpha"] },`, want: "spec recursionGuard needs a cyclic spec (cyclic or cycles)"},
+		{name: "no_depth", spec: `"cyclic": true, "recursionGuard": { "methods": ["DoAlpha"] },`, want: "spec recursionGuard.maxDepth must be >= 1"},
+		{name: "no_methods", spec: `"cyclic": true, "recursionGuard": { "maxDepth": 8 },`, want: "spec recursionGuard.methods must be non-empty"},
+		{name: "unknown_method", spec: `"cyclic": true, "recursionGuard": { "maxDepth": 8, "methods": ["DoBeta"] },`, want: `spec recursionGuard.methods: "DoBeta" is not a method`},
+		{name: "twice", spec: `"cyclic": true, "recursionGuard": { "maxDepth": 8, "methods": ["DoAlpha", "DoAlpha"] },`, want: `spec recursionGuard.methods: "DoAlpha" is listed twice`},
+		{name: "no_ctx", spec: `"cyclic": true, "recursionGuard": { "maxDepth": 8, "methods": ["Name"] },`, want: "spec recursionGuard.methods: Name needs a context.Context first parameter and error as the last return"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			specPath := p.write("alpha.inject.json", `{
+  "package": "p", "wrapperBase": "Alpha", "versionSuffix": "V4", "implType": "Alpha", "constructor": "NewAlpha",
+  `+tt.spec+`
+  "required": [{ "name": "Beta", "field": "beta", "type": "*Beta", "nilable": true }],
+  `+methods+`
+}`)
+			assertPanicContains(t, func() { genService(specPath, p.out("alpha.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
+
+func TestGenService_DecoratorErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{
+			name: "no_error_return",
+			spec: `"methods": [{ "name": "Name", "returns": [{ "type": "string" }], "decorators": ["Trace"] }]`,
+			want: "method Name decorators, retry, timeoutMs and recoverPanics require error as the last return",
+		},
+		{
+			name: "empty_method_decorator",
+			spec: `"methods": [{ "name": "Ping", "returns": [{ "type": "error" }], "decorators": [" "] }]`,
+			want: "method Ping decorators must be non-empty Go expressions",
+		},
+		{
+			name: "timeout_without_ctx",
+			spec: `"methods": [{ "name": "Ping", "returns": [{ "type": "error" }], "timeoutMs": 10 }]`,
+			want: "method Ping timeoutMs requires a context.Context first parameter",
+		},
+		{
+			name: "negative_timeout",
+			spec: `"methods": [{ "name": "Ping", "returns": [{ "type": "error" }], "timeoutMs": -1 }]`,
+			want: "method Ping timeoutMs must be >= 0",
+		},
+		{
+			name: "retry_no_attempts",
+			spec: `"methods": [{ "name": "Ping", "returns": [{ "type": "error" }], "retry": { "backoffMs": 5 } }]`,
+			want: "method Ping retry needs attempts >= 1 and backoffMs >= 0",
+		},
+		{
+			name: "retry_without_error",
+			spec: `"methods": [{ "name": "Name", "returns": [{ "type": "string" }], "retry": { "attempts": 2 } }]`,
+			want: "method Name decorators, retry, timeoutMs and recoverPanics require error as the last return",
+		},
+		{
+			name: "recover_without_error",
+			spec: `"methods": [{ "name": "Name", "returns": [{ "type": "string" }], "recoverPanics": true }]`,
+			want: "method Name decorators, retry, timeoutMs and recoverPanics require error as the last return",
+		},
+		{
+			name: "empty_service_decorator",
+			spec: `"decorators": [""]`,
+			want: "spec decorators must be non-empty Go expressions",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }],
+  `+tt.spec+`
+}`)
+			assertPanicContains(t, func() { genService(specPath, p.out("core.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
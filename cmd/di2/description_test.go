@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Spec descriptions as doc comments
+// -------------------------
+
+func TestGenService_Descriptions(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "description": "Core processes orders.\n\nBuild it once per process.",
+  "required": [
+    { "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true, "description": "Alpha scores risk." },
+    { "name": "Beta", "field": "beta", "type": "*Beta", "nilable": true }
+  ],
+  "optional": [
+    { "name": "Tracer", "type": "Tracer", "registryKey": "t", "apply": { "kind": "field", "name": "tracer" },
+      "description": "Tracer spans every call." }
+  ],
+  "methods": [
+    { "name": "Process", "description": "Process handles one order.", "returns": [{ "type": "error" }] },
+    { "name": "Close", "returns": [{ "type": "error" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"\t// Tracer spans every call.\n\tCoreV4OptionalTracerKey = \"t\"",
+		"// CoreV4 is the generated facade for Core.\n//\n// Core processes orders.\n//\n// Build it once per process.\ntype CoreV4 struct {",
+		"// Unlike InjectAlpha, it returns an error instead of panicking.\n//\n// Alpha scores risk.\nfunc (b *CoreV4) TryInjectAlpha(",
+		"// Prefer TryInjectAlpha for safer wiring in tests.\n//\n// Alpha scores risk.\nfunc (b *CoreV4) InjectAlpha(",
+		"// Unlike InjectBeta, it returns an error instead of panicking.\nfunc (b *CoreV4) TryInjectBeta(",
+		"}\n\nfunc (b *CoreV4) Close() error {",
+		"// Process handles one order.\nfunc (b *CoreV4) Process() error {",
+	)
+}
+
+func TestGenService_NoDescriptions(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	if out := p.read("core.gen.go"); strings.Contains(out, "is the generated facade") {
+		t.Fatalf("did not expect a facade doc comment without description:\n%s", out)
+	}
+}
+
+func TestDocLines(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"a":            "// a",
+		"  a \n":       "// a",
+		"a\n\t\nb\r\n": "// a\n//\n// b",
+	} {
+		if got := docLines(in); got != want {
+			t.Fatalf("docLines(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -55,7 +55,18 @@
 //   - InjectX(...) for required deps
 //   - Build()/MustBuild() validates required deps
 //   - BuildWith(reg di.Registry) applies optional deps from the registry, then validates
-//   - UnsafeImpl() returns the underlying pointer for wiring only (composition root)
+//   - BuildWithCtx(ctx, reg) is BuildWith with registry lookups under ctx (di.RegistryCtx);
+//     only specs with optional deps get it
+//   - UnsafeImpl() returns the underlying pointer for wiring only (composition root);
+//     after it, Reset() leaves the builder stale (Build fails with di.ErrStaleImpl)
+//     and ResetUnsafe() returns the new pointer with the consumers to re-wire;
+//     Explain() lists the consumers ("unsafeImpl": {"track": true} adds call
+//     sites) and "unsafeImpl": {"disable": true} omits UnsafeImpl for non-cyclic specs
+//   - "identityHelpers": true adds SameImpl(other) and ImplPtr(), which let tests
+//     check instance identity (e.g. that graph wiring shared one instance) without
+//     UnsafeImpl; compare ImplPtr with di.ImplPtr of the dep a consumer received
+//   - "introspection": true adds WiringInfo(), WiringManifest() and
+//     <Facade>SpecInfo() for debug endpoints and audits
 //   - Optional safe method wrappers that enforce per-method "requires" deps
 //
 // B) Graph composition root (from graph.json)
@@ -76,7 +87,8 @@
 //	}
 //
 // Generated builders use registry keys (e.g. "v4.tracer") to resolve optional deps,
-// apply them (setter or field assignment), and can fall back to a default expression
+// apply them (setter, field assignment, or a "call" whose args may add config values
+// such as cfg.TraceSampling), and can fall back to a default expression
 // when the key is missing.
 //
 // Spec overview
@@ -94,19 +106,122 @@
 //
 //	//go:generate go run ../../cmd/di2 -spec specs/core.inject.json -out core_v4.gen.go
 //
+// For every *.inject.json in a directory (batch mode, generated concurrently; -j N workers):
+//
+//	//go:generate go run ../../cmd/di2 -specs specs -out .
+//
+// A batch fails when its specs declare the same dep name (or optional registry key)
+// with different types.
+//
 // For a graph:
 //
 //	//go:generate go run ../../cmd/di2 -graph specs/graph.json -out graph_v4.gen.go
 //
+// or, to give every root its own graph_<root>.gen.go file (-out is then a directory):
+//
+//	//go:generate go run ../../cmd/di2 -graph specs/graph.json -split -out .
+//
+// Conversely, a quoted glob merges graph fragments owned by different teams into
+// one graph (roots by name, services by var):
+//
+//	//go:generate go run ../../cmd/di2 -graph "specs/graph-*.json" -out graph_v4.gen.go
+//
 // Then:
 //
 //	go generate ./...
 //
+// To scaffold a new service (skeleton, spec, go:generate line, table-driven test and
+// the generated facade):
+//
+//	go run ../../cmd/di2 new service -name Fraud -deps TransactionGetter,DecisionWriter -optional Logger
+//
+// To see what a graph would do if services or registry keys were missing (which
+// builds fail, which defaults engage, what Explain() would print):
+//
+//	go run ../../cmd/di2 graph simulate -graph specs/graph.json -without v4.tracer,beta
+//
+// To type-check the output package right away, with compile errors attributed to
+// the spec entries (required dep, optional dep, method, graph wiring) that produced
+// the failing code, add -verify.
+//
+// Spec keys di2 does not know (typos such as "requried") are reported as warnings
+// with a suggestion; add -strict to fail on them instead.
+//
+// "-spec -" (or "-graph -") reads the spec from stdin and "-out -" prints the
+// generated file to stdout instead of writing it.
+//
+// For hermetic builds (Bazel, Please), -no-infer takes every import from the spec
+// (imports.di, config.import, imports.service and imports.packages for dep types)
+// and never scans packages, go.mod files or the existing output.
+//
+// "naming" picks how versionSuffix enters facade names: suffix (AlphaV4, the
+// default), prefix (V4Alpha) or none (Alpha); -naming sets it for specs without
+// one. Graph services linking a spec default facadeCtor/facadeType to its names.
+//
+// Registry keys of optional deps follow <package>.<depLower> by default;
+// -warn-keys reports keys that do not. "keyPattern" (or -key-pattern) enforces a
+// convention built from <package>, <service>, <dep> and <depLower>: keys that
+// do not follow it fail generation. -fix-keys rewrites them in the spec.
+//
+// String values may reference ${NAME} spec variables, set with -var NAME=value
+// (repeatable) or a JSON object of strings in -vars file.json; $${NAME} is a literal.
+//
+// For build attestation, -provenance out.json records the run: di2 version,
+// arguments, timestamp (SOURCE_DATE_EPOCH when set), and the SHA-256 of every spec,
+// graph and template read and every file written.
+//
+// To review exported API changes of the generated facade before it is overwritten
+// (removed or changed methods, types and fields are breaking):
+//
+//	go run ../../cmd/di2 -spec specs/core.inject.json -out core_v4.gen.go -api-diff prev -api-breaking=error
+//
+// To rewrite specs in the canonical layout (the generated SHA-256 headers hash that
+// form, so whitespace-only edits do not change the output):
+//
+//	go run ../../cmd/di2 fmt -w specs
+//
+// To customize the generated code, export the built-in templates and pass the
+// directory with -template-dir (files present there replace the built-in ones):
+//
+//	go run ../../cmd/di2 template export -dir di2tmpl
+//
+// To generate extra files from the same specs, add -plugin "<command>": it receives
+// the parsed spec as JSON on stdin and returns the files to write on stdout.
+//
+// To check a project for stale or orphaned generated files, broken go:generate
+// paths and outputs outside the module (each problem is printed with a fix):
+//
+//	go run ../../cmd/di2 doctor .
+//
+// To remove generated files whose spec was renamed or deleted (-n lists them), run
+// "di2 clean", or add -prune to a -specs batch:
+//
+//	go run ../../cmd/di2 clean .
+//
+// To add, fix or drop go:generate lines so every spec has exactly one (-n prints the
+// changes instead):
+//
+//	go run ../../cmd/di2 sync-directives .
+//
+// Specs without "specVersion" (version 1) are upgraded in memory; to rewrite them in
+// the current format:
+//
+//	go run ../../cmd/di2 migrate spec -w specs
+//
 // Cycle wiring note
 //
 // di2 does not solve cycles automatically. Cycles remain explicit. UnsafeImpl() exists
 // to enable composition-root wiring before Build()/BuildWith() validation completes.
 // Do not call business methods on the underlying implementation before Build().
 //
+// Cycles are also declared: the graph generator fails on a wiring cycle unless one
+// of its edges is declared by the target's linked spec ("cycles": ["Beta"], or
+// "cyclic": true for any dep) or by the wiring entry ("cycle": true), and on a
+// declared edge that closes no cycle. A cyclic spec can bound the recursion of its
+// mutually-calling methods with "recursionGuard": { "maxDepth": 16, "methods": [...] },
+// which runs them through di.DepthGuard (failing with *di.RecursionLimitError).
+// The guard only counts calls through the facade; implementations calling each
+// other directly guard that edge themselves.
+//
 // See the repository docs/service-v4.md and examples/v4 for end-to-end usage.
 package main
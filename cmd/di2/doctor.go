@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Project diagnostics (di2 doctor)
+// -------------------------
+
+// doctorOut is where "di2 doctor" prints its findings.
+var doctorOut io.Writer = os.Stdout
+
+// finding is one problem reported by di2 doctor, with a suggested fix.
+type finding struct {
+	Path    string // file (and line) the problem is in, relative to the checked dir
+	Problem string
+	Fix     string
+}
+
+// generateDirective is a //go:generate line running di2.
+type generateDirective struct {
+	File string // absolute path of the .go file
+	Line int
+	Dir  string // the directive's working directory (File's dir)
+
+	RunPath string // "go run" package argument ("" when di2 runs as a binary)
+	Spec    string
+	Specs   string
+	Graph   string
+	Out     string
+}
+
+// di2Header is the header of a file generated by di2.
+type di2Header struct {
+	Kind string // "Spec" or "Graph"
+	Path string // spec or graph path as passed to di2
+	Hash string
+}
+
+// runDoctor checks the project under the given dir (default ".") for common
+// di2 setup problems and prints one finding per problem. It fails when any
+// problem is found, so it can gate CI.
+func runDoctor(args []string) error {
+	fset := flag.NewFlagSet("di2 doctor", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() > 1 {
+		return fmt.Errorf("usage: di2 doctor [dir]")
+	}
+	root := "."
+	if fset.NArg() == 1 {
+		root = fset.Arg(0)
+	}
+	if !dirExists(root) {
+		return fmt.Errorf("doctor: %s is not a directory", root)
+	}
+
+	findings := doctor(root)
+	for _, f := range findings {
+		_, _ = fmt.Fprintf(doctorOut, "%s: %s\n\tfix: %s\n", f.Path, f.Problem, f.Fix)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("doctor: %d problem(s) found", len(findings))
+	}
+	_, _ = fmt.Fprintln(doctorOut, "doctor: no problems found")
+	return nil
+}
+
+// doctor runs every check on the project under root and returns the findings
+// sorted by path.
+func doctor(root string) []finding {
+	absRoot, err := filepath.Abs(root)
+	must(err)
+	rel := func(p string) string {
+		if r, err := filepath.Rel(absRoot, p); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return filepath.ToSlash(p)
+	}
+
+	var out []finding
+	if _, _, err := findModule(absRoot); err != nil {
+		out = append(out, finding{
+			Path:    rel(absRoot),
+			Problem: "no go.mod found in this directory or above (" + err.Error() + ")",
+			Fix:     "run `go mod init <module path>` at the project root",
+		})
+	}
+
+	goFiles, genFiles := doctorFiles(absRoot)
+	var directives []generateDirective
+	for _, f := range goFiles {
+		directives = append(directives, readGenerateDirectives(f)...)
+	}
+	for _, d := range directives {
+		out = append(out, checkDirective(d, rel)...)
+	}
+	for _, f := range genFiles {
+		out = append(out, checkGeneratedFile(f, absRoot, directives, rel)...)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// doctorFiles lists the .go files under root and, among them, the .gen.go files.
+// Hidden, "_"-prefixed, vendor and testdata directories are skipped, like go does.
+func doctorFiles(root string) (goFiles, genFiles []string) {
+	_ = filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := e.Name()
+		if e.IsDir() {
+			if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, ".go") {
+			goFiles = append(goFiles, p)
+			if strings.HasSuffix(name, ".gen.go") {
+				genFiles = append(genFiles, p)
+			}
+		}
+		return nil
+	})
+	return goFiles, genFiles
+}
+
+// readGenerateDirectives returns the di2 //go:generate directives of file.
+func readGenerateDirectives(file string) []generateDirective {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var out []generateDirective
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for line := 1; sc.Scan(); line++ {
+		text, ok := strings.CutPrefix(sc.Text(), "//go:generate ")
+		if !ok {
+			continue
+		}
+		if d, ok := parseGenerateDirective(text); ok {
+			d.File, d.Line, d.Dir = file, line, filepath.Dir(file)
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// parseGenerateDirective parses the command of a //go:generate line; ok is false
+// for commands that do not run di2 or run one of its subcommands.
+func parseGenerateDirective(cmd string) (d generateDirective, ok bool) {
+	args := strings.Fields(cmd)
+	for i := range args {
+		args[i] = strings.Trim(args[i], `"`)
+	}
+	switch {
+	case len(args) >= 3 && args[0] == "go" && args[1] == "run" && isDI2Package(args[2]):
+		d.RunPath, args = args[2], args[3:]
+	case len(args) >= 1 && filepath.Base(args[0]) == "di2":
+		args = args[1:]
+	default:
+		return d, false
+	}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return d, false // new, fmt, migrate, ...
+	}
+	for i := 0; i < len(args); i++ {
+		name, val, hasVal := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		var dst *string
+		switch name {
+		case "spec":
+			dst = &d.Spec
+		case "specs":
+			dst = &d.Specs
+		case "graph":
+			dst = &d.Graph
+		case "out":
+			dst = &d.Out
+		default:
+			continue
+		}
+		if !hasVal && i+1 < len(args) {
+			i++
+			val = args[i]
+		}
+		*dst = val
+	}
+	return d, true
+}
+
+// isDI2Package reports whether a "go run" argument is the di2 command, by
+// relative path or import path (with or without a version).
+func isDI2Package(p string) bool {
+	p, _, _ = strings.Cut(p, "@")
+	return strings.HasSuffix(strings.TrimSuffix(p, "/"), "cmd/di2")
+}
+
+// checkDirective checks that a directive's di2 command, inputs and output are
+// where it expects them.
+func checkDirective(d generateDirective, rel func(string) string) []finding {
+	var out []finding
+	at := fmt.Sprintf("%s:%d", rel(d.File), d.Line)
+	add := func(problem, fix string) { out = append(out, finding{Path: at, Problem: problem, Fix: fix}) }
+
+	if strings.HasPrefix(d.RunPath, ".") {
+		if !dirExists(filepath.Join(d.Dir, filepath.FromSlash(d.RunPath))) {
+			add(fmt.Sprintf("go:generate runs %s, which does not exist relative to %s", d.RunPath, rel(d.Dir)),
+				di2RunFix(d.Dir))
+		}
+	}
+
+	input := func(flagName, p string, exists func(string) bool) {
+		if p != "" && !exists(filepath.Join(d.Dir, filepath.FromSlash(p))) {
+			add(fmt.Sprintf("-%s %s does not exist relative to %s", flagName, p, rel(d.Dir)),
+				fmt.Sprintf("fix the path; go generate runs di2 in %s", rel(d.Dir)))
+		}
+	}
+	input("spec", d.Spec, fileExists)
+	input("specs", d.Specs, dirExists)
+	input("graph", d.Graph, func(p string) bool {
+		if isGraphGlob(p) {
+			m, _ := filepath.Glob(p)
+			return len(m) > 0
+		}
+		return fileExists(p)
+	})
+
+	modRoot, modPath, err := findModule(d.Dir)
+	if err != nil {
+		return out
+	}
+	escapes := func(p string) bool {
+		r, err := filepath.Rel(modRoot, p)
+		return err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator))
+	}
+	if d.Out != "" && d.Out != "-" && escapes(filepath.Join(d.Dir, filepath.FromSlash(d.Out))) {
+		add(fmt.Sprintf("-out %s is outside module %s", d.Out, modPath),
+			"generate into a package of the module; code outside it is not built with it")
+	}
+	if d.Spec != "" {
+		specPath := filepath.Join(d.Dir, filepath.FromSlash(d.Spec))
+		if acc := specAccessorsPath(specPath); acc != "" && escapes(acc) {
+			add(fmt.Sprintf("%s output.accessors %s is outside module %s", d.Spec, rel(acc), modPath),
+				"point output.accessors (relative to the spec file) at the service package")
+		}
+	}
+	return out
+}
+
+// specAccessorsPath returns the resolved output.accessors file of the spec at
+// specPath, or "" if it has none or cannot be read.
+func specAccessorsPath(specPath string) string {
+	raw, err := os.ReadFile(specPath)
+	if err != nil {
+		return ""
+	}
+	var spec ServiceSpec
+	if decodeSpec(raw, &spec) != nil || spec.Output.Accessors == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(specPath), filepath.FromSlash(spec.Output.Accessors))
+}
+
+// di2RunFix suggests a "go run" argument for di2 from dir: the relative path to
+// the module's cmd/di2 when it has one (the odi module or a vendored copy),
+// written so go run treats it as a directory, and the import path otherwise.
+func di2RunFix(dir string) string {
+	if modRoot, _, err := findModule(dir); err == nil {
+		local := filepath.Join(modRoot, "cmd", "di2")
+		if r, err := filepath.Rel(dir, local); err == nil && dirExists(local) {
+			r = filepath.ToSlash(r)
+			if !strings.HasPrefix(r, ".") {
+				r = "./" + r
+			}
+			return fmt.Sprintf("use `go run %s`", r)
+		}
+	}
+	return fmt.Sprintf("use `go run %s` (with a require or tool directive in go.mod)", defaultGenCmd)
+}
+
+// checkGeneratedFile checks a .gen.go file under root: it must carry a
+// generated-code header, and a di2 file's spec or graph must exist and match
+// its hash.
+func checkGeneratedFile(file, root string, directives []generateDirective, rel func(string) string) []finding {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	at := rel(file)
+	h, generated := readDI2Header(raw)
+	if !generated {
+		return []finding{{
+			Path:    at,
+			Problem: `missing the "// Code generated ... DO NOT EDIT." header`,
+			Fix:     "regenerate it with go generate, or drop the .gen.go suffix if it is hand-written",
+		}}
+	}
+	if h.Kind == "" {
+		return nil // generated by another tool
+	}
+	if h.Path == "" || h.Hash == "" {
+		return []finding{{
+			Path:    at,
+			Problem: "di2 header is missing its " + h.Kind + " path or " + h.Kind + "-SHA256 line",
+			Fix:     "regenerate it with go generate",
+		}}
+	}
+	if h.Path == "-" {
+		return nil // generated from stdin: no source to compare with
+	}
+
+	src := resolveHeaderPath(h, headerDirs(file, root, directives))
+	if src == "" {
+		return []finding{{
+			Path:    at,
+			Problem: fmt.Sprintf("orphaned: %s %s no longer exists", strings.ToLower(h.Kind), h.Path),
+			Fix:     "delete the file and its go:generate line, or restore the " + strings.ToLower(h.Kind),
+		}}
+	}
+	if hash := headerSourceHash(h.Kind, src); hash != "" && hash != h.Hash {
+		return []finding{{
+			Path:    at,
+			Problem: fmt.Sprintf("stale: %s changed since the file was generated", rel(src)),
+			Fix:     "run go generate ./...",
+		}}
+	}
+	return nil
+}
+
+// readDI2Header parses the header of a generated file. generated is false when
+// the file has no "Code generated" marker before its package clause; Kind is
+// empty for files generated by other tools.
+func readDI2Header(raw []byte) (h di2Header, generated bool) {
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(ln, "package "):
+			return h, generated
+		case strings.HasPrefix(ln, "// Code generated ") && strings.HasSuffix(ln, "DO NOT EDIT."):
+			generated = true
+			if strings.Contains(ln, "(di v2)") {
+				h.Kind = "Spec"
+			}
+		case h.Kind != "" && strings.HasPrefix(ln, "// Graph: "):
+			h.Kind, h.Path = "Graph", strings.TrimPrefix(ln, "// Graph: ")
+		case h.Kind != "" && strings.HasPrefix(ln, "// Spec: "):
+			h.Path = strings.TrimPrefix(ln, "// Spec: ")
+		case h.Kind != "" && strings.HasPrefix(ln, "// Spec-SHA256: "):
+			h.Hash = strings.TrimPrefix(ln, "// Spec-SHA256: ")
+		case h.Kind != "" && strings.HasPrefix(ln, "// Graph-SHA256: "):
+			h.Hash = strings.TrimPrefix(ln, "// Graph-SHA256: ")
+		}
+	}
+	return h, generated
+}
+
+// headerDirs are the directories a header path of file may be relative to.
+// Header paths are relative to di2's working directory: usually the directory
+// of the go:generate line (the file's own, or for accessor, batch and split
+// outputs possibly that of another directive), but a facade generated by hand
+// may name its spec relative to the checked root, the current directory or the
+// module root.
+func headerDirs(file, root string, directives []generateDirective) []string {
+	dirs := []string{filepath.Dir(file)}
+	for _, d := range directives {
+		dirs = append(dirs, d.Dir)
+	}
+	dirs = append(dirs, root, ".")
+	if modRoot, _, err := findModule(filepath.Dir(file)); err == nil {
+		dirs = append(dirs, modRoot)
+	}
+	return dirs
+}
+
+// resolveHeaderPath finds the spec or graph a header names, trying dirs in
+// order for a relative path; it returns "" when none has it.
+func resolveHeaderPath(h di2Header, dirs []string) string {
+	exists := fileExists
+	if h.Kind == "Graph" && isGraphGlob(h.Path) {
+		exists = func(p string) bool {
+			m, _ := filepath.Glob(p)
+			return len(m) > 0
+		}
+	}
+	p := filepath.FromSlash(h.Path)
+	if filepath.IsAbs(p) {
+		if exists(p) {
+			return p
+		}
+		return ""
+	}
+	for _, dir := range dirs {
+		if c := filepath.Join(dir, p); exists(c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// headerSourceHash hashes a spec or graph the way generation does, or returns
+// "" if it cannot be read.
+func headerSourceHash(kind, path string) (hash string) {
+	if kind == "Spec" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		return specSHA256(raw)
+	}
+	defer func() {
+		if recover() != nil {
+			hash = ""
+		}
+	}()
+	_, hash = readGraphSpec(path)
+	return hash
+}
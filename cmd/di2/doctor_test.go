@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// di2 doctor
+// -------------------------
+
+const doctorSpec = `{"package": "svc", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [{"name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true}]}`
+
+func doctorGenFile(spec, hash string) string {
+	return "// Code generated by (di v2); DO NOT EDIT.\n// Spec: " + spec + "\n// Spec-SHA256: " + hash + "\n\npackage svc\n"
+}
+
+// writeDoctorProject writes a module with one correctly generated service.
+func writeDoctorProject(p *pkgHarness) {
+	writeGoMod(p)
+	p.write("cmd/di2/main.go", "package main\n")
+	p.write("svc/svc.go", "package svc\n\n//go:generate go run ../cmd/di2 -spec specs/core.inject.json -out core_v4.gen.go\n")
+	p.write("svc/specs/core.inject.json", doctorSpec)
+	p.write("svc/core_v4.gen.go", doctorGenFile("specs/core.inject.json", specSHA256([]byte(doctorSpec))))
+	p.write("svc/pb.gen.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage svc\n")
+}
+
+func TestDoctor_Healthy(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDoctorProject(p)
+
+	if got := doctor(p.dir); len(got) != 0 {
+		t.Fatalf("expected no findings, got %+v", got)
+	}
+}
+
+func TestDoctor_Findings(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDoctorProject(p)
+	p.write("svc/bad.go", "package svc\n\n//go:generate go run ../../cmd/di2 -spec=specs/missing.inject.json -out ../../outside.gen.go\n")
+	p.write("svc/stale_v4.gen.go", doctorGenFile("specs/core.inject.json", "deadbeef"))
+	p.write("svc/orphan_v4.gen.go", doctorGenFile("specs/gone.inject.json", "deadbeef"))
+	p.write("svc/hand.gen.go", "package svc\n")
+	p.write("svc/nohash_v4.gen.go", "// Code generated by (di v2); DO NOT EDIT.\n// Spec: specs/core.inject.json\n\npackage svc\n")
+	p.write("svc/testdata/skipped.gen.go", "package svc\n")
+
+	var got []string
+	for _, f := range doctor(p.dir) {
+		if f.Fix == "" {
+			t.Fatalf("finding without a fix: %+v", f)
+		}
+		got = append(got, f.Path+": "+f.Problem)
+	}
+	want := []string{
+		"svc/bad.go:3: go:generate runs ../../cmd/di2, which does not exist relative to svc",
+		"svc/bad.go:3: -spec specs/missing.inject.json does not exist relative to svc",
+		"svc/bad.go:3: -out ../../outside.gen.go is outside module example.com/proj",
+		`svc/hand.gen.go: missing the "// Code generated ... DO NOT EDIT." header`,
+		"svc/nohash_v4.gen.go: di2 header is missing its Spec path or Spec-SHA256 line",
+		"svc/orphan_v4.gen.go: orphaned: spec specs/gone.inject.json no longer exists",
+		"svc/stale_v4.gen.go: stale: svc/specs/core.inject.json changed since the file was generated",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestDi2RunFix(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDoctorProject(p)
+
+	// the suggested path must resolve from the directive's directory
+	for dir, want := range map[string]string{
+		p.out("svc"):     "use `go run ../cmd/di2`",
+		p.dir:            "use `go run ./cmd/di2`",
+		p.out("cmd/di2"): "use `go run .`",
+	} {
+		if got := di2RunFix(dir); got != want {
+			t.Fatalf("%s: got %q want %q", dir, got, want)
+		}
+	}
+
+	bare := newPkg(t)
+	writeGoMod(bare)
+	if got := di2RunFix(bare.dir); !strings.Contains(got, "go run "+defaultGenCmd+"`") {
+		t.Fatalf("without a local cmd/di2 the import path should be suggested, got %q", got)
+	}
+}
+
+func TestDoctor_NoGoMod(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("svc/svc.go", "package svc\n")
+
+	got := doctor(p.dir)
+	if len(got) != 1 || !strings.Contains(got[0].Problem, "no go.mod found") || !strings.Contains(got[0].Fix, "go mod init") {
+		t.Fatalf("expected a missing go.mod finding, got %+v", got)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	// NOT parallel: swaps doctorOut
+	var buf bytes.Buffer
+	old := doctorOut
+	doctorOut = &buf
+	t.Cleanup(func() { doctorOut = old })
+
+	p := newPkg(t)
+	writeDoctorProject(p)
+	if err := runDoctor([]string{p.dir}); err != nil {
+		t.Fatalf("healthy project: %v", err)
+	}
+	if buf.String() != "doctor: no problems found\n" {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+
+	buf.Reset()
+	p.write("svc/stale_v4.gen.go", doctorGenFile("specs/core.inject.json", "deadbeef"))
+	err := runDoctor([]string{p.dir})
+	if err == nil || err.Error() != "doctor: 1 problem(s) found" {
+		t.Fatalf("expected a problem count error, got %v", err)
+	}
+	assertContainsInOrder(t, buf.String(), "svc/stale_v4.gen.go: stale:", "\tfix: run go generate ./...")
+
+	if err := runDoctor([]string{p.out("missing")}); err == nil {
+		t.Fatal("expected an error for a missing dir")
+	}
+	if err := runDoctor([]string{"a", "b"}); err == nil {
+		t.Fatal("expected a usage error")
+	}
+}
+
+func TestParseGenerateDirective(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		cmd  string
+		ok   bool
+		want generateDirective
+	}{
+		{cmd: "go run ../../cmd/di2 -spec specs/a.json -out a.gen.go", ok: true,
+			want: generateDirective{RunPath: "../../cmd/di2", Spec: "specs/a.json", Out: "a.gen.go"}},
+		{cmd: `go run github.com/sghaida/odi/cmd/di2@v1.2.0 -graph "specs/graph-*.json" -out=g.gen.go`, ok: true,
+			want: generateDirective{RunPath: "github.com/sghaida/odi/cmd/di2@v1.2.0", Graph: "specs/graph-*.json", Out: "g.gen.go"}},
+		{cmd: "di2 -specs specs -j 4 -out .", ok: true, want: generateDirective{Specs: "specs", Out: "."}},
+		{cmd: "go run ../../cmd/di2 fmt -w specs"},
+		{cmd: "stringer -type=Kind"},
+	}
+	for _, tc := range cases {
+		got, ok := parseGenerateDirective(tc.cmd)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Fatalf("%s: got %+v, %v want %+v, %v", tc.cmd, got, ok, tc.want, tc.ok)
+		}
+	}
+}
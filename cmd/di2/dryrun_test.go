@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Dry run
+// -------------------------
+
+func TestGenService_DryRun(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	var buf bytes.Buffer
+	genService(specPath, p.out("alpha_v4.gen.go"), genOptions{DryRun: &buf})
+
+	if fileExists(p.out("alpha_v4.gen.go")) {
+		t.Fatalf("dry run must not write the output file")
+	}
+	out := buf.String()
+	assertHasImport(t, out, "maps")
+	assertHasImport(t, out, "example.com/proj/di")
+	if !strings.Contains(out, "type AlphaV4 struct") {
+		t.Fatalf("expected facade in dry-run output:\n%s", out)
+	}
+}
+
+func TestGenGraph_DryRun(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{"package": "p", "roots": [{"name": "App", "services": [
+  { "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }]}]}`)
+
+	var buf bytes.Buffer
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{DryRun: &buf})
+
+	if fileExists(p.out("graph.gen.go")) {
+		t.Fatalf("dry run must not write the output file")
+	}
+	if !strings.Contains(buf.String(), "func App(reg di.Registry) (AppResult, error)") {
+		t.Fatalf("expected graph in dry-run output:\n%s", buf.String())
+	}
+}
+
+func TestEmitGenerated_DryRunFormatError(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	assertPanicContains(t, func() {
+		emitGenerated("unused.go", []byte("package p\nfunc {"), genOptions{DryRun: &buf})
+	}, "gofmt/format failed")
+	if buf.String() != "package p\nfunc {" {
+		t.Fatalf("expected raw source on format error, got %q", buf.String())
+	}
+}
+
+func TestGenServiceBatch_DryRun(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeBatchSpec(p, "bravo", "Bravo", "V4")
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	var buf bytes.Buffer
+	if _, err := genServiceBatch(p.out("specs"), p.dir, 2, genOptions{DryRun: &buf}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		"// ---- ", "alpha_v4.gen.go ----", "type AlphaV4 struct",
+		"// ---- ", "bravo_v4.gen.go ----", "type BravoV4 struct",
+	)
+	if fileExists(p.out("alpha_v4.gen.go")) || fileExists(p.out("bravo_v4.gen.go")) {
+		t.Fatalf("dry run must not write output files")
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	// NOT parallel: swaps dryRunOut
+
+	var buf bytes.Buffer
+	old := dryRunOut
+	dryRunOut = &buf
+	t.Cleanup(func() { dryRunOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	cacheFile := p.out("scan.json")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "flag", args: []string{"-spec", specPath, "-out", p.out("alpha.gen.go"), "-dry-run", "-scan-cache", cacheFile}},
+		{name: "out_dash", args: []string{"-spec", specPath, "-out", "-"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if err := run(tt.args); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !strings.Contains(buf.String(), "type AlphaV4 struct") {
+				t.Fatalf("expected output on stdout, got:\n%s", buf.String())
+			}
+		})
+	}
+
+	if fileExists(p.out("alpha.gen.go")) || fileExists(cacheFile) {
+		t.Fatalf("dry run must not touch the filesystem")
+	}
+}
+
+func TestRun_SpecFromStdin(t *testing.T) {
+	// NOT parallel: swaps specStdin and dryRunOut
+	var stdout bytes.Buffer
+	oldIn, oldOut := specStdin, dryRunOut
+	dryRunOut = &stdout
+	t.Cleanup(func() {
+		specStdin, dryRunOut = oldIn, oldOut
+		stdinSpec.raw, stdinSpec.read = nil, false
+	})
+
+	p := newPkg(t)
+	specStdin = strings.NewReader(mustReadString(t, writeBatchSpec(p, "alpha", "Alpha", "V4")))
+
+	// the spec is read more than once per run, stdin only once; -out - prints
+	if err := run([]string{"-spec", "-", "-out", "-", "-strict"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	assertContainsInOrder(t, stdout.String(), "// Spec: -\n", "package p", "type AlphaV4 struct")
+
+	// a file generated from stdin has no spec on disk, yet is not an orphan
+	out := p.write("alpha_v4.gen.go", stdout.String())
+	if isOrphanedGenFile(out, []string{p.dir}) {
+		t.Fatal("files generated from stdin must not be cleaned")
+	}
+}
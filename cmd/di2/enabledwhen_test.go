@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Feature-flag gated optional deps
+// -------------------------
+
+func TestGenService_EnabledWhen(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }],
+  "optional": [
+    { "name": "Metrics", "type": "Metrics", "registryKey": "m", "apply": { "kind": "field", "name": "metrics" } },
+    { "name": "Tracer", "type": "Tracer", "registryKey": "t", "apply": { "kind": "setter", "name": "SetTracer" },
+      "defaultExpr": "NoopTracer{}", "enabledWhen": { "flagKey": "features.tracing" } }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		`di.ResolveOptionalCtx[Metrics](ctx, reg, nil, "CoreV4", "Metrics", "m")`,
+		`if on, err := di.FlagEnabled(ctx, reg, nil, "CoreV4", "Tracer", "features.tracing"); err != nil {`,
+		"} else if !on {",
+		"b.svc.SetTracer(NoopTracer{})",
+		`b.optionalMissing["t"] = "disabled by flag features.tracing"`,
+		`} else if v, ok, err := di.ResolveOptionalCtx[Tracer](ctx, reg, nil, "CoreV4", "Tracer", "t"); err != nil {`,
+		"b.svc.SetTracer(v)",
+	)
+	if strings.Count(out, "di.FlagEnabled(") != 1 {
+		t.Fatalf("expected only the gated dep to consult flags:\n%s", out)
+	}
+}
+
+func TestValidateServiceSpec_EnabledWhen(t *testing.T) {
+	t.Parallel()
+
+	spec := ServiceSpec{
+		Package: "p", WrapperBase: "Core", VersionSuffix: "V4", ImplType: "Core", Constructor: "NewCore",
+		Required: []RequiredDep{{Name: "Alpha", Field: "alpha", Type: "*Alpha", Nilable: true}},
+		Optional: []OptionalDep{{
+			Name: "Tracer", Type: "Tracer", RegistryKey: "t",
+			Apply:       OptionalApply{Kind: "field", Name: "tracer"},
+			EnabledWhen: &EnabledWhen{FlagKey: " "},
+		}},
+	}
+	assertPanicContains(t, func() { validateServiceSpec(&spec) }, "optional dep Tracer enabledWhen.flagKey must be non-empty")
+}
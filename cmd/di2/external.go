@@ -0,0 +1,294 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OutputSpec generates the facade into a package other than the service package
+// (e.g. internal/wiring), for teams that keep generated code away from domain code.
+//
+// The facade cannot reach the impl's unexported fields from there, so di2 also
+// generates an accessor file in the service package with one exported
+// DIRef<Name>() method per field-injected dependency.
+type OutputSpec struct {
+	// Package is the package name of the -out file. Empty (or equal to the spec
+	// package) keeps the facade in the service package.
+	Package string `json:"package"`
+
+	// Accessors is the accessor file path in the service package, relative to the
+	// spec file (e.g. "../core_access.gen.go"). Required when Package is set.
+	Accessors string `json:"accessors"`
+}
+
+// external reports whether the facade is generated outside the service package.
+func (o OutputSpec) external(servicePkg string) bool {
+	return strings.TrimSpace(o.Package) != "" && o.Package != servicePkg
+}
+
+// implRefMethod is the accessor exposing the impl field behind dep name to an
+// external facade.
+func implRefMethod(name string) string { return "DIRef" + exportName(name) }
+
+// setImplTargets fills the Target expression the facade uses to read and assign
+// impl fields: the field itself in the service package, or the dereferenced
+// accessor when generating into another package.
+func setImplTargets(s *ServiceSpec, external bool) {
+	for i := range s.Required {
+		d := &s.Required[i]
+		d.Target = "b.svc." + d.Field
+		if external {
+			d.Target = "*b.svc." + implRefMethod(d.Name) + "()"
+		}
+	}
+	for i := range s.Optional {
+		o := &s.Optional[i]
+		if o.Apply.Kind != "field" {
+			continue
+		}
+		o.Target = "b.svc." + o.Apply.Name
+		if external {
+			o.Target = "*b.svc." + implRefMethod(o.Name) + "()"
+		}
+	}
+}
+
+// externalizeService rewrites spec so the facade template can be rendered in
+// spec.Output.Package: package-local identifiers of the service package are
+// qualified with its package name. It returns the import for the service package.
+func externalizeService(s *ServiceSpec, specPath string) GoImport {
+	imp := servicePackageImport(*s, specPath)
+	svcPkg := serviceQualifier(s.Package)
+
+	q := func(ctx, expr string) string {
+		out, err := qualifyGoExpr(expr, svcPkg)
+		if err != nil {
+			die("output.package: " + ctx + ": " + err.Error())
+		}
+		return out
+	}
+
+	s.ImplType = q("implType", s.ImplType)
+	s.Constructor = q("constructor", s.Constructor)
+	for i := range s.Required {
+		s.Required[i].Type = q("required "+s.Required[i].Name, s.Required[i].Type)
+	}
+	for i := range s.Optional {
+		o := &s.Optional[i]
+		o.Type = q("optional "+o.Name, o.Type)
+		if o.DefaultExpr != "" {
+			o.DefaultExpr = q("optional "+o.Name+" defaultExpr", o.DefaultExpr)
+		}
+		qualifyApplyArgs(o, svcPkg)
+		if o.Apply.Kind != "field" && !ast.IsExported(o.Apply.Name) {
+			die("output.package: optional " + o.Name + " " + o.Apply.Kind + " " + o.Apply.Name + " must be exported")
+		}
+	}
+	for i := range s.Methods {
+		m := &s.Methods[i]
+		for j := range m.Params {
+			m.Params[j].Type = q("method "+m.Name, m.Params[j].Type)
+		}
+		for j := range m.Returns {
+			m.Returns[j].Type = q("method "+m.Name, m.Returns[j].Type)
+		}
+	}
+	s.Package = s.Output.Package
+	return imp
+}
+
+// servicePackageImport returns the import of the service package of an external
+// facade: imports.service, or the package of the accessor file's directory. The
+// import is named after the spec package when the path does not end in it.
+func servicePackageImport(s ServiceSpec, specPath string) GoImport {
+	svcImport := strings.TrimSpace(s.Imports.Service)
+	if svcImport == "" {
+		dir := filepath.Dir(linkedSpecPath(specPath, s.Output.Accessors))
+		p, err := resolveImportPath(dir)
+		if err != nil {
+			die("cannot infer imports.service for output.package: " + err.Error())
+		}
+		svcImport = p
+	}
+
+	imp := GoImport{Path: svcImport}
+	if q := serviceQualifier(s.Package); path.Base(svcImport) != q {
+		imp.Name = q
+	}
+	return imp
+}
+
+// facadeLocals are identifiers the facade and graph templates declare locally;
+// a service package with one of these names would be shadowed by them.
+var facadeLocals = map[string]bool{
+	"b": true, "svc": true, "err": true, "dep": true, "reg": true, "ctx": true,
+	"v": true, "ok": true, "res": true, "cfg": true, "di": true, "fmt": true, "maps": true,
+}
+
+// serviceQualifier is the name an external facade uses for the service package:
+// the package name, or <name>pkg when that would clash with a template local.
+func serviceQualifier(pkg string) string {
+	if facadeLocals[pkg] {
+		return pkg + "pkg"
+	}
+	return pkg
+}
+
+// withoutImportPath returns imps without the imports of path (any name).
+func withoutImportPath(imps []GoImport, path string) []GoImport {
+	out := imps[:0:0]
+	for _, gi := range imps {
+		if gi.Path != path {
+			out = append(out, gi)
+		}
+	}
+	return out
+}
+
+// predeclared lists Go's predeclared identifiers, which are never qualified.
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true, "min": true, "new": true,
+	"panic": true, "print": true, "println": true, "real": true, "recover": true,
+}
+
+// qualifyGoExpr prefixes the package-level identifiers of a type or expression
+// with pkg ("*Alpha" -> "*v4.Alpha", "NoopTracer{}" -> "v4.NoopTracer{}").
+// Package-qualified names, predeclared identifiers, field/param names and
+// composite literal keys are left alone. Unexported identifiers cannot be
+// referenced from another package and are reported as errors.
+func qualifyGoExpr(expr, pkg string) (string, error) {
+	src := strings.TrimSpace(expr)
+	node, err := parser.ParseExpr(src)
+	if err != nil {
+		return "", err
+	}
+
+	skip := map[*ast.Ident]bool{}
+	var idents []*ast.Ident
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			// pkg.Name or value.Field: only the leftmost operand can be package-local
+			skip[x.Sel] = true
+			if id, ok := x.X.(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.Field:
+			for _, name := range x.Names {
+				skip[name] = true
+			}
+		case *ast.KeyValueExpr:
+			if id, ok := x.Key.(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.Ident:
+			idents = append(idents, x)
+		}
+		return true
+	})
+
+	var targets []*ast.Ident
+	for _, id := range idents {
+		if skip[id] || predeclared[id.Name] || id.Name == "_" {
+			continue
+		}
+		if !ast.IsExported(id.Name) {
+			return "", &cmdError{msg: "unexported identifier " + id.Name + " in " + src + " is not reachable from another package"}
+		}
+		targets = append(targets, id)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Pos() > targets[j].Pos() })
+
+	out := src
+	for _, id := range targets {
+		off := int(id.Pos()) - 1 // ParseExpr positions start at 1
+		out = out[:off] + pkg + "." + out[off:]
+	}
+	return out, nil
+}
+
+// typeQualifiers returns the package qualifiers used by a Go type expression.
+func typeQualifiers(expr string) []string {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				out = append(out, id.Name)
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// accessorDep is one DIRef accessor in the service package.
+type accessorDep struct {
+	Method string
+	Field  string
+	Type   string
+}
+
+// genAccessors writes the service-package accessor file used by an external facade.
+// spec must not be externalized yet (types are rendered package-local).
+func genAccessors(spec ServiceSpec, specPath, specHash string, opts genOptions) {
+	if strings.TrimSpace(spec.Output.Accessors) == "" {
+		die("spec output.accessors is required when output.package is set")
+	}
+	outPath := linkedSpecPath(specPath, spec.Output.Accessors)
+
+	var deps []accessorDep
+	quals := map[string]bool{}
+	add := func(name, field, typ string) {
+		deps = append(deps, accessorDep{Method: implRefMethod(name), Field: field, Type: typ})
+		for _, q := range typeQualifiers(typ) {
+			quals[q] = true
+		}
+	}
+	for _, d := range spec.Required {
+		add(d.Name, d.Field, d.Type)
+	}
+	for _, o := range spec.Optional {
+		if o.Apply.Kind == "field" {
+			add(o.Name, o.Apply.Name, o.Type)
+		}
+	}
+
+	// the accessor types come from the service's own field declarations, so its
+	// package imports provide every qualifier (declared packages first)
+	scanned := declaredPackageImports(spec.Imports.Packages)
+	if !opts.NoInfer {
+		scanned = append(scanned, scanPackageImports(filepath.Dir(outPath))...)
+	}
+	var imports []GoImport
+	for q := range quals {
+		if gi, ok := findImportByAliasOrSuffix(scanned, q, "/"+q); ok {
+			imports = append(imports, gi)
+		} else {
+			imports = append(imports, GoImport{Path: q})
+		}
+	}
+
+	data := map[string]any{
+		"Spec":     spec,
+		"SpecPath": filepath.ToSlash(specPath),
+		"SpecHash": specHash,
+		"Imports":  dedupeAndSortImports(imports),
+		"Deps":     deps,
+		"Preamble": codegenPreamble(spec.Codegen, "spec"),
+	}
+	emitGenerated(outPath, mustExecTemplate(opts.templates().Accessors, data), opts)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// External output package
+// -------------------------
+
+func TestQualifyGoExpr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "pointer", in: "*Alpha", want: "*v4.Alpha"},
+		{name: "already_qualified", in: "*slog.Logger", want: "*slog.Logger"},
+		{name: "predeclared", in: "map[string][]error", want: "map[string][]error"},
+		{name: "generic_and_mixed", in: "map[Key]*Box[time.Time]", want: "map[v4.Key]*v4.Box[time.Time]"},
+		{name: "func_type_param_names", in: "func(ctx context.Context, r Request) (Resp, error)", want: "func(ctx context.Context, r v4.Request) (v4.Resp, error)"},
+		{name: "composite_literal_keys", in: "&Noop{Level: Debug}", want: "&v4.Noop{Level: v4.Debug}"},
+		{name: "call_and_nil", in: "NewNoop(nil, true)", want: "v4.NewNoop(nil, true)"},
+		{name: "unexported", in: "*alpha", wantErr: "unexported identifier alpha"},
+		{name: "parse_error", in: "*[", wantErr: "expected"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := qualifyGoExpr(tt.in, "v4")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err=%v want contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceQualifierAndTypeQualifiers(t *testing.T) {
+	t.Parallel()
+
+	if got := serviceQualifier("svc"); got != "svcpkg" {
+		t.Fatalf("svc: got %q", got)
+	}
+	if got := serviceQualifier("orders"); got != "orders" {
+		t.Fatalf("orders: got %q", got)
+	}
+	if got := strings.Join(typeQualifiers("map[time.Duration]*slog.Logger"), ","); got != "time,slog" {
+		t.Fatalf("typeQualifiers: got %q", got)
+	}
+	if got := typeQualifiers("*["); got != nil {
+		t.Fatalf("expected nil for invalid type, got %v", got)
+	}
+}
+
+// writeExternalLayout writes a service package "orders" and a sibling package
+// "wiring" that receives the facade.
+func writeExternalLayout(p *pkgHarness, output string) string {
+	p.write("orders/orders.go", `package orders
+import "log/slog"
+type Core struct{ alpha *Alpha; logger *slog.Logger }
+`)
+	p.write("wiring/di.go", `package wiring
+import di "example.com/proj/di"
+var _ di.Registry
+`)
+	return p.write("orders/specs/core.inject.json", `{
+  "package": "orders", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "imports": { "service": "example.com/proj/orders" },
+  `+output+`
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }],
+  "optional": [
+    { "slog": true },
+    { "name": "Tracer", "type": "Tracer", "registryKey": "t", "apply": { "kind": "setter", "name": "SetTracer" }, "defaultExpr": "NoopTracer{}" }
+  ],
+  "methods": [{ "name": "Place", "params": [{ "name": "o", "type": "Order" }], "returns": [{ "type": "error" }], "requires": ["Alpha"] }]
+}`)
+}
+
+func TestGenService_ExternalPackage(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	specPath := writeExternalLayout(p, `"output": { "package": "wiring", "accessors": "../core_access.gen.go" },`)
+
+	genService(specPath, p.out("wiring/core_v4.gen.go"), genOptions{})
+
+	acc := p.read("orders/core_access.gen.go")
+	assertHasImport(t, acc, "log/slog")
+	assertContainsInOrder(t, acc,
+		"package orders",
+		"func (s *Core) DIRefAlpha() **Alpha { return &s.alpha }",
+		"func (s *Core) DIRefLogger() **slog.Logger { return &s.logger }",
+	)
+	if strings.Contains(acc, "DIRefTracer") {
+		t.Fatalf("setter-applied optional deps need no accessor:\n%s", acc)
+	}
+
+	out := p.read("wiring/core_v4.gen.go")
+	assertHasImport(t, out, "example.com/proj/orders")
+	assertContainsInOrder(t, out,
+		"package wiring",
+		"svc *orders.Core",
+		"svc:      orders.NewCore(),",
+		"func (b *CoreV4) TryInjectAlpha(dep *orders.Alpha) (*CoreV4, error) {",
+		"*b.svc.DIRefAlpha() = dep",
+		"*b.svc.DIRefLogger() = v",
+		"di.ResolveOptionalCtx[orders.Tracer](",
+		"b.svc.SetTracer(orders.NoopTracer{})",
+		"*b.svc.DIRefAlpha() == nil",
+		"o orders.Order,",
+	)
+}
+
+func TestGenService_ExternalPackage_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "missing_accessors", output: `"output": { "package": "wiring" },`, want: "output.accessors is required"},
+		{name: "unexported_setter", output: `"output": { "package": "wiring", "accessors": "../a.gen.go" },`, want: "setter setTracer must be exported"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			specPath := writeExternalLayout(p, tt.output)
+			if tt.name == "unexported_setter" {
+				raw := strings.Replace(p.read("orders/specs/core.inject.json"), `"SetTracer"`, `"setTracer"`, 1)
+				specPath = p.write("orders/specs/core.inject.json", raw)
+			}
+			assertPanicContains(t, func() { genService(specPath, p.out("wiring/core_v4.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
+
+func TestGenService_ExternalPackage_SamePackageIsInternal(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	specPath := writeExternalLayout(p, `"output": { "package": "orders" },`)
+
+	genService(specPath, p.out("orders/core_v4.gen.go"), genOptions{})
+	out := p.read("orders/core_v4.gen.go")
+	if !strings.Contains(out, "b.svc.alpha = dep") || strings.Contains(out, "DIRef") {
+		t.Fatalf("expected direct field access:\n%s", out)
+	}
+}
+
+func TestGenGraph_ExternalPackage(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeExternalLayout(p, `"output": { "package": "wiring", "accessors": "../core_access.gen.go" },`)
+	graphPath := p.write("wiring/graph.json", `{"package": "wiring", "roots": [{"name": "App", "services": [
+  { "var": "core", "facadeCtor": "NewCoreV4", "facadeType": "*CoreV4", "implType": "Core", "spec": "../orders/specs/core.inject.json" }]}]}`)
+
+	genGraph(graphPath, p.out("wiring/graph.gen.go"), genOptions{})
+	out := p.read("wiring/graph.gen.go")
+
+	assertHasImport(t, out, "example.com/proj/orders")
+	assertContainsInOrder(t, out, "Core *orders.Core", "coreB := NewCoreV4()")
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Graph analysis helpers
+// -------------------------
+
+// resolveGraphServiceSpecs loads the service specs linked from graph services
+// ("spec", relative to the graph file) and copies service-level declarations the
+// graph generator needs (healthCheck, consumer) unless the graph overrides them.
+// facadeCtor, facadeType and implType default to the linked spec's names, which
+// follow its naming strategy (see validateGraphServiceNames).
+func resolveGraphServiceSpecs(g *GraphSpec, graphPath string) {
+	for _, root := range g.allRoots() {
+		for j := range root.Services {
+			svc := &root.Services[j]
+			if svc.Spec == "" {
+				continue
+			}
+			specPath := linkedSpecPath(graphPath, svc.Spec)
+			spec, _ := readServiceSpec(specPath)
+			defaultGraphServiceNames(svc, spec, g.Package)
+			svc.NoUnsafeImpl = spec.UnsafeImpl.Disable
+			svc.Cyclic, svc.CycleDeps = spec.Cyclic, specCycleDeps(spec)
+			if svc.HealthCheck == "" {
+				svc.HealthCheck = spec.HealthCheck
+			}
+			svc.Consumer = svc.Consumer || spec.Consumer
+			// facade generated next to the graph, impl in the service package
+			if spec.Output.external(spec.Package) && g.Package != spec.Package {
+				if !strings.Contains(svc.ImplType, ".") {
+					impl, err := qualifyGoExpr(svc.ImplType, serviceQualifier(spec.Package))
+					if err != nil {
+						die("graph service " + svc.Var + ": " + err.Error())
+					}
+					svc.ImplType = impl
+				}
+				g.ServiceImports = append(g.ServiceImports, servicePackageImport(spec, specPath))
+			}
+		}
+	}
+}
+
+// defaultGraphServiceNames fills the facade and impl names svc leaves empty
+// from its linked spec. Facade names are only derived for facades generated
+// into the graph's package, which need no qualifier.
+func defaultGraphServiceNames(svc *GraphService, spec ServiceSpec, graphPkg string) {
+	if svc.ImplType == "" {
+		svc.ImplType = spec.ImplType
+	}
+	if spec.Package != graphPkg && !spec.Output.external(spec.Package) {
+		return
+	}
+	if svc.FacadeCtor == "" {
+		svc.FacadeCtor = spec.PublicConstructorName
+	}
+	if svc.FacadeType == "" {
+		svc.FacadeType = "*" + spec.FacadeName
+	}
+}
+
+// validateGraphServiceNames dies for a service left without facadeCtor,
+// facadeType or implType once linked specs are resolved.
+func validateGraphServiceNames(g *GraphSpec) {
+	for _, root := range g.allRoots() {
+		for _, svc := range root.Services {
+			if svc.FacadeCtor != "" && svc.FacadeType != "" && svc.ImplType != "" {
+				continue
+			}
+			msg := "graph service " + svc.Var + ": facadeCtor, facadeType and implType are required"
+			if svc.Spec == "" {
+				msg += " without a linked spec"
+			} else {
+				msg += " when the facade of " + svc.Spec + " is generated outside package " + g.Package
+			}
+			die(msg)
+		}
+	}
+}
+
+// linkedSpecPath resolves a spec path referenced from a graph file.
+func linkedSpecPath(graphPath, rel string) string {
+	if filepath.IsAbs(rel) {
+		return rel
+	}
+	return filepath.Join(filepath.Dir(graphPath), filepath.FromSlash(rel))
+}
+
+// dependencyOrder returns the root's services so that every service comes after
+// the services it is wired from (wiring "argFrom" -> "to").
+//
+// Ties are broken by var name, and cycles are broken by emitting the smallest
+// remaining var first, so the order is deterministic for any graph.
+func dependencyOrder(root GraphRoot) []GraphService {
+	byVar := make(map[string]GraphService, len(root.Services))
+	for _, s := range root.Services {
+		byVar[s.Var] = s
+	}
+
+	deps := make(map[string]map[string]bool, len(root.Services))
+	for _, w := range root.Wiring {
+		if w.To == w.ArgFrom {
+			continue
+		}
+		if _, ok := byVar[w.To]; !ok {
+			continue
+		}
+		if _, ok := byVar[w.ArgFrom]; !ok {
+			continue
+		}
+		if deps[w.To] == nil {
+			deps[w.To] = map[string]bool{}
+		}
+		deps[w.To][w.ArgFrom] = true
+	}
+
+	remaining := make([]string, 0, len(byVar))
+	for v := range byVar {
+		remaining = append(remaining, v)
+	}
+	sort.Strings(remaining)
+
+	done := make(map[string]bool, len(remaining))
+	out := make([]GraphService, 0, len(remaining))
+	ready := func(v string) bool {
+		for d := range deps[v] {
+			if !done[d] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(remaining) > 0 {
+		pick := 0 // cycle: fall back to the smallest remaining var
+		for i, v := range remaining {
+			if ready(v) {
+				pick = i
+				break
+			}
+		}
+		v := remaining[pick]
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+		done[v] = true
+		out = append(out, byVar[v])
+	}
+	return out
+}
+
+// dependencyStages groups root.BuildOrder into stages: every service lands in the
+// stage after the latest stage holding one of its dependencies, so services in
+// the same stage are independent and can be built concurrently.
+//
+// Only dependencies built earlier in BuildOrder count; edges dropped to break a
+// cycle are ignored, matching the serial build. Within a stage, services keep
+// their BuildOrder order, and Pos records their BuildOrder index.
+func dependencyStages(root GraphRoot) [][]GraphService {
+	deps := map[string][]string{}
+	for _, w := range root.Wiring {
+		deps[w.To] = append(deps[w.To], w.ArgFrom)
+	}
+
+	level := make(map[string]int, len(root.BuildOrder))
+	var stages [][]GraphService
+	for i, s := range root.BuildOrder {
+		l := 0
+		for _, d := range deps[s.Var] {
+			if dl, ok := level[d]; ok && d != s.Var && dl+1 > l {
+				l = dl + 1
+			}
+		}
+		level[s.Var] = l
+		if l == len(stages) {
+			stages = append(stages, nil)
+		}
+		s.Pos = i
+		stages[l] = append(stages[l], s)
+	}
+	return stages
+}
+
+// BuildIndex returns the index of the service var in BuildOrder, or -1.
+func (r GraphRoot) BuildIndex(v string) int {
+	for i, s := range r.BuildOrder {
+		if s.Var == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasHealthCheck reports whether any service of the root declares a health check.
+func (r GraphRoot) HasHealthCheck() bool {
+	for _, s := range r.Services {
+		if s.HealthCheck != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConsumers reports whether any service of the root is a stream consumer.
+func (r GraphRoot) HasConsumers() bool {
+	for _, s := range r.Services {
+		if s.Consumer {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConsumers reports whether any root of g has stream consumers.
+func (g GraphSpec) HasConsumers() bool {
+	for _, r := range g.Roots {
+		if r.HasConsumers() {
+			return true
+		}
+	}
+	return false
+}
+
+// streamImport is the di/stream package next to the di import path.
+func streamImport(diPath string) GoImport {
+	return GoImport{Name: "stream", Path: path.Join(diPath, "stream")}
+}
+
+// setRootParams computes the parameters shared by the generated root function
+// and its Must/Ctx variants.
+func setRootParams(r *GraphRoot, cfg ConfigSpec) {
+	var params, args []string
+	if cfg.Enabled {
+		params = append(params, cfg.ParamName+" "+cfg.Type)
+		args = append(args, cfg.ParamName)
+	}
+	params = append(params, "reg di.Registry")
+	args = append(args, "reg")
+	if len(r.SharedServices) > 0 {
+		params = append(params, "shared "+r.SharedName+"Result")
+		args = append(args, "shared")
+	}
+	r.Params = strings.Join(params, ", ")
+	r.Args = strings.Join(args, ", ")
+}
+
+func validateGraphHealth(r GraphRoot) {
+	if r.HealthHandler && !r.HasHealthCheck() {
+		die("graph root " + r.Name + ": healthHandler requires at least one service with healthCheck")
+	}
+}
+
+func validateGraphReload(r GraphRoot, cfg ConfigSpec) {
+	if r.ConfigReload && !cfg.Enabled {
+		die("graph root " + r.Name + ": configReload requires config.enabled")
+	}
+}
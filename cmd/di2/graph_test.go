@@ -0,0 +1,1055 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// dependencyOrder
+// -------------------------
+
+func TestDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	svcs := func(vars ...string) []GraphService {
+		out := make([]GraphService, 0, len(vars))
+		for _, v := range vars {
+			out = append(out, GraphService{Var: v})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		root   GraphRoot
+		expect []string
+	}{
+		{
+			name:   "no_wiring_sorted_by_var",
+			root:   GraphRoot{Services: svcs("b", "a", "c")},
+			expect: []string{"a", "b", "c"},
+		},
+		{
+			name: "deps_before_dependents",
+			root: GraphRoot{
+				Services: svcs("api", "db", "repo"),
+				Wiring: []GraphWiring{
+					{To: "api", Call: "InjectRepo", ArgFrom: "repo"},
+					{To: "repo", Call: "InjectDB", ArgFrom: "db"},
+				},
+			},
+			expect: []string{"db", "repo", "api"},
+		},
+		{
+			name: "cycle_broken_by_smallest_var",
+			root: GraphRoot{
+				Services: svcs("alpha", "beta", "core"),
+				Wiring: []GraphWiring{
+					{To: "alpha", Call: "InjectBeta", ArgFrom: "beta"},
+					{To: "beta", Call: "InjectAlpha", ArgFrom: "alpha"},
+					{To: "core", Call: "InjectAlpha", ArgFrom: "alpha"},
+					{To: "core", Call: "InjectBeta", ArgFrom: "beta"},
+				},
+			},
+			expect: []string{"alpha", "beta", "core"},
+		},
+		{
+			name: "self_and_unknown_edges_ignored",
+			root: GraphRoot{
+				Services: svcs("a", "b"),
+				Wiring: []GraphWiring{
+					{To: "a", Call: "InjectSelf", ArgFrom: "a"},
+					{To: "a", Call: "InjectGhost", ArgFrom: "ghost"},
+					{To: "ghost", Call: "InjectA", ArgFrom: "a"},
+					{To: "a", Call: "InjectB", ArgFrom: "b"},
+				},
+			},
+			expect: []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := []string{}
+			for _, s := range dependencyOrder(tt.root) {
+				got = append(got, s.Var)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("order: got %v want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+// -------------------------
+// dependencyStages
+// -------------------------
+
+func TestDependencyStages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		vars   []string
+		wiring []GraphWiring
+		expect [][]string
+	}{
+		{
+			name:   "independent_single_stage",
+			vars:   []string{"a", "b", "c"},
+			expect: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "diamond",
+			vars: []string{"api", "cache", "db", "repo"},
+			wiring: []GraphWiring{
+				{To: "repo", ArgFrom: "db"},
+				{To: "cache", ArgFrom: "db"},
+				{To: "api", ArgFrom: "repo"},
+				{To: "api", ArgFrom: "cache"},
+			},
+			expect: [][]string{{"db"}, {"cache", "repo"}, {"api"}},
+		},
+		{
+			name: "cycle_serialized",
+			vars: []string{"alpha", "beta", "core"},
+			wiring: []GraphWiring{
+				{To: "alpha", ArgFrom: "beta"},
+				{To: "beta", ArgFrom: "alpha"},
+				{To: "core", ArgFrom: "alpha"},
+				{To: "core", ArgFrom: "beta"},
+			},
+			expect: [][]string{{"alpha"}, {"beta"}, {"core"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			root := GraphRoot{Wiring: tt.wiring}
+			for _, v := range tt.vars {
+				root.Services = append(root.Services, GraphService{Var: v})
+			}
+			root.BuildOrder = dependencyOrder(root)
+
+			got := [][]string{}
+			for _, stage := range dependencyStages(root) {
+				vars := []string{}
+				for _, s := range stage {
+					if root.BuildOrder[s.Pos].Var != s.Var {
+						t.Fatalf("Pos %d of %s does not match BuildOrder", s.Pos, s.Var)
+					}
+					vars = append(vars, s.Var)
+				}
+				got = append(got, vars)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("stages: got %v want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGenGraph_Parallel(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	g := GraphSpec{
+		Package:  "p",
+		Parallel: true,
+		Roots: []GraphRoot{{
+			Name:          "App",
+			WiringHandler: true,
+			Services: []GraphService{
+				{Var: "a", FacadeCtor: "NewAV4", FacadeType: "*AV4", ImplType: "A"},
+				{Var: "b", FacadeCtor: "NewBV4", FacadeType: "*BV4", ImplType: "B"},
+				{Var: "c", FacadeCtor: "NewCV4", FacadeType: "*CV4", ImplType: "C"},
+			},
+			Wiring: []GraphWiring{
+				{To: "c", Call: "InjectA", ArgFrom: "a"},
+				{To: "c", Call: "InjectB", ArgFrom: "b"},
+			},
+		}},
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out,
+		"res.wiring = make([]di.WiringInfo, 3)",
+		"err := di.BuildStages(",
+		"svc, err := aB.Build()",
+		`res.wiring[0] = di.BuiltWiring(aB, "a", time.Now())`,
+		"svc, err := bB.Build()",
+		`res.wiring[1] = di.BuiltWiring(bB, "b", time.Now())`,
+		"},\n\t\t[]func() error{",
+		"svc, err := cB.Build()",
+		`return fmt.Errorf("App: build c failed: %w", err)`,
+		"res.C = svc",
+		`res.wiring[2] = di.BuiltWiring(cB, "c", time.Now())`,
+	)
+	if strings.Contains(out, "aSvc, err :=") {
+		t.Fatalf("did not expect serial builds in parallel mode:\n%s", out)
+	}
+}
+
+func TestGenGraph_BuildOrder(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []bool{false, true} {
+		p := newPkg(t)
+		writeDISource(p)
+		g := GraphSpec{
+			Package:  "p",
+			Parallel: parallel,
+			Roots: []GraphRoot{{
+				Name: "App",
+				Services: []GraphService{
+					{Var: "api", FacadeCtor: "NewAPIV4", FacadeType: "*APIV4", ImplType: "API"},
+					{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core"},
+					{Var: "db", FacadeCtor: "NewDBV4", FacadeType: "*DBV4", ImplType: "DB"},
+				},
+				Wiring: []GraphWiring{
+					{To: "api", Call: "InjectCore", ArgFrom: "core"},
+					{To: "core", Call: "InjectDB", ArgFrom: "db"},
+				},
+			}},
+		}
+		raw, err := json.Marshal(g)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+		out := p.read("graph.gen.go")
+
+		assertContainsInOrder(t, out,
+			"// AppBuildOrder returns the vars of the services App builds",
+			"func AppBuildOrder() []string {\n\treturn []string{\"db\", \"core\", \"api\"}\n}",
+			"func (r AppResult) BuildOrder() []string {\n\treturn AppBuildOrder()\n}",
+		)
+		if got := strings.Contains(out, "built concurrently"); got != parallel {
+			t.Fatalf("parallel=%v: concurrency note present=%v:\n%s", parallel, got, out)
+		}
+	}
+}
+
+// TestGenGraph_MountAll verifies mountAll emits <Root>Result.MountAll over every
+// service, nil-guarding conditional ones.
+func TestGenGraph_MountAll(t *testing.T) {
+	t.Parallel()
+
+	for _, mount := range []bool{false, true} {
+		p := newPkg(t)
+		writeDISource(p)
+		g := GraphSpec{
+			Package: "p",
+			Roots: []GraphRoot{{
+				Name:     "App",
+				MountAll: mount,
+				Services: []GraphService{
+					{Var: "api", FacadeCtor: "NewAPIV4", FacadeType: "*APIV4", ImplType: "API", When: "true"},
+					{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core"},
+				},
+				Wiring: []GraphWiring{{To: "api", Call: "InjectCore", ArgFrom: "core"}},
+			}},
+		}
+		raw, err := json.Marshal(g)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+		out := p.read("graph.gen.go")
+
+		if !mount {
+			if strings.Contains(out, ") MountAll(") {
+				t.Fatalf("MountAll emitted without mountAll:\n%s", out)
+			}
+			continue
+		}
+		assertContainsInOrder(t, out,
+			"func (r AppResult) MountAll(mux di.Mux) error {",
+			"if p, ok := any(r.Core).(di.RouteProvider); ok {",
+			"if p, ok := any(r.Api).(di.RouteProvider); ok && r.Api != nil {",
+			"return di.MountRoutes(mux, providers...)",
+		)
+	}
+}
+
+// TestGenGraph_ConfigReload verifies configReload emits ReloadConfig over the
+// root's services and requires config.
+func TestGenGraph_ConfigReload(t *testing.T) {
+	t.Parallel()
+
+	p := newPkg(t)
+	writeDISource(p)
+	g := GraphSpec{
+		Package: "p",
+		Config:  ConfigSpec{Enabled: true, Import: "example.com/proj/config"},
+		Roots: []GraphRoot{{
+			Name:         "App",
+			ConfigReload: true,
+			Services: []GraphService{
+				{Var: "api", FacadeCtor: "NewAPIV4", FacadeType: "*APIV4", ImplType: "API", When: "true"},
+				{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core"},
+			},
+			Wiring: []GraphWiring{{To: "api", Call: "InjectCore", ArgFrom: "core"}},
+		}},
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out,
+		"func (r AppResult) ReloadConfig(cfg config.Config) error {",
+		`rl := di.NewReloader[config.Config]("App")`,
+		"if s, ok := any(r.Core).(di.ConfigReloadable[config.Config]); ok {\n\t\trl.Register(\"core\", s.OnConfigChange)",
+		"if s, ok := any(r.Api).(di.ConfigReloadable[config.Config]); ok && r.Api != nil {",
+		"return rl.Reload(cfg)",
+	)
+
+	g.Config = ConfigSpec{}
+	raw, err = json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	graphPath := p.write("noconfig.json", string(raw))
+	assertPanicContains(t, func() { genGraph(graphPath, p.out("noconfig.gen.go"), genOptions{}) },
+		"graph root App: configReload requires config.enabled")
+}
+
+// TestGenGraph_Consumers verifies consumer services are added to the root's
+// stream group and the result gets StartAll/StopAll/Done.
+func TestGenGraph_Consumers(t *testing.T) {
+	t.Parallel()
+
+	p := newPkg(t)
+	writeDISource(p)
+	g := GraphSpec{
+		Package: "p",
+		Roots: []GraphRoot{
+			{
+				Name: "App",
+				Services: []GraphService{
+					{Var: "api", FacadeCtor: "NewAPIV4", FacadeType: "*APIV4", ImplType: "API", Consumer: true, When: "true"},
+					{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core", Consumer: true},
+					{Var: "db", FacadeCtor: "NewDBV4", FacadeType: "*DBV4", ImplType: "DB"},
+				},
+				Wiring: []GraphWiring{
+					{To: "api", Call: "InjectCore", ArgFrom: "core"},
+					{To: "core", Call: "InjectDB", ArgFrom: "db"},
+				},
+			},
+			{
+				Name:     "Tool",
+				Services: []GraphService{{Var: "db", FacadeCtor: "NewDBV4", FacadeType: "*DBV4", ImplType: "DB"}},
+			},
+		},
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertHasImport(t, out, "example.com/proj/di/stream")
+	assertContainsInOrder(t, out,
+		"consumers *stream.Group",
+		"var AppRestartPolicy = stream.DefaultRestartPolicy",
+		"res.consumers = stream.NewGroup(\"App\", AppRestartPolicy)\n\tres.consumers.Add(\"core\", res.Core)\n\tif res.Api != nil {\n\t\tres.consumers.Add(\"api\", res.Api)\n\t}",
+		"func (r AppResult) StartAll(ctx context.Context) error {",
+		"func (r AppResult) StopAll(ctx context.Context) error {",
+		"func (r AppResult) Done() <-chan struct{} {",
+	)
+	if strings.Contains(out, "ToolRestartPolicy") || strings.Contains(out, "func (r ToolResult) StartAll") {
+		t.Fatalf("root without consumers got lifecycle methods:\n%s", out)
+	}
+}
+
+// -------------------------
+// Linked service specs + health checks
+// -------------------------
+
+func writeHealthSpec(t *testing.T, p *pkgHarness, rel, healthCheck string) {
+	t.Helper()
+	spec := ServiceSpec{
+		Package:       "p",
+		WrapperBase:   "Core",
+		VersionSuffix: "V4",
+		ImplType:      "Core",
+		Constructor:   "NewCore",
+		Required:      []RequiredDep{{Name: "Alpha", Field: "alpha", Type: "*Alpha", Nilable: true}},
+		HealthCheck:   healthCheck,
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	p.write(rel, string(raw))
+}
+
+func TestResolveGraphServiceSpecs(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeHealthSpec(t, p, filepath.Join("specs", "core.inject.json"), "Ping")
+
+	g := &GraphSpec{Roots: []GraphRoot{{
+		Name: "Root",
+		Services: []GraphService{
+			{Var: "core", Spec: "core.inject.json"},
+			{Var: "override", Spec: "core.inject.json", HealthCheck: "Live"},
+			{Var: "plain"},
+		},
+	}}}
+	resolveGraphServiceSpecs(g, p.out(filepath.Join("specs", "graph.json")))
+
+	got := []string{}
+	for _, s := range g.Roots[0].Services {
+		got = append(got, s.HealthCheck)
+	}
+	if want := []string{"Ping", "Live", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("healthCheck: got %v want %v", got, want)
+	}
+
+	if got := linkedSpecPath("/x/graph.json", "/abs/core.json"); got != "/abs/core.json" {
+		t.Fatalf("absolute spec path should be kept, got %q", got)
+	}
+}
+
+func TestResolveGraphServiceSpecs_InvalidLinkedSpecPanics(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("bad.inject.json", `{"package":"p"}`)
+
+	g := &GraphSpec{Roots: []GraphRoot{{Name: "Root", Services: []GraphService{{Var: "x", Spec: "bad.inject.json"}}}}}
+	assertPanicContains(t, func() { resolveGraphServiceSpecs(g, p.out("graph.json")) }, "spec missing: wrapperBase")
+}
+
+func TestValidateGraphHealth(t *testing.T) {
+	t.Parallel()
+
+	assertPanicContains(t, func() {
+		validateGraphHealth(GraphRoot{Name: "Root", HealthHandler: true, Services: []GraphService{{Var: "a"}}})
+	}, "graph root Root: healthHandler requires at least one service with healthCheck")
+
+	// no panic when a health check exists
+	validateGraphHealth(GraphRoot{Name: "Root", HealthHandler: true, Services: []GraphService{{Var: "a", HealthCheck: "Ping"}}})
+}
+
+func TestGenService_HealthCheckWrapper(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, "core.inject.json", "Ping")
+
+	genService(p.out("core.inject.json"), p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "context")
+	assertContainsInOrder(t, out,
+		"func (b *CoreV4) HealthCheck(ctx context.Context) error {",
+		`svc, err := b.buildScoped("HealthCheck", reqCoreV4All)`,
+		"return svc.Ping(ctx)",
+	)
+}
+
+func TestGenGraph_HealthCheckAndHandler(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, filepath.Join("specs", "core.inject.json"), "Ping")
+
+	g := GraphSpec{
+		Package: "p",
+		Roots: []GraphRoot{{
+			Name:          "App",
+			HealthHandler: true,
+			Services: []GraphService{
+				{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core", Spec: "core.inject.json"},
+				{Var: "alpha", FacadeCtor: "NewAlphaV4", FacadeType: "*AlphaV4", ImplType: "Alpha", HealthCheck: "Check"},
+				{Var: "zed", FacadeCtor: "NewZedV4", FacadeType: "*ZedV4", ImplType: "Zed"},
+			},
+			Wiring: []GraphWiring{
+				{To: "alpha", Call: "InjectZed", ArgFrom: "zed"},
+				{To: "core", Call: "InjectAlpha", ArgFrom: "alpha"},
+			},
+		}},
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	graphPath := p.write(filepath.Join("specs", "graph.json"), string(raw))
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertHasImport(t, out, "context")
+	assertHasImport(t, out, "net/http")
+
+	// builds follow dependency order: zed -> alpha -> core
+	assertContainsInOrder(t, out, "zedSvc, err := zedB.Build()", "alphaSvc, err := alphaB.Build()", "coreSvc, err := coreB.Build()")
+	assertContainsInOrder(t, out,
+		"func (r AppResult) HealthCheck(ctx context.Context) map[string]error {",
+		`out["alpha"] = r.Alpha.Check(ctx)`,
+		`out["core"] = r.Core.Ping(ctx)`,
+		"func (r AppResult) HealthHandler() http.Handler {",
+		"return di.HealthHandler(r.HealthCheck)",
+	)
+	if strings.Contains(out, "r.Zed.") {
+		t.Fatalf("service without healthCheck must not be checked:\n%s", out)
+	}
+}
+
+// -------------------------
+// Wiring introspection
+// -------------------------
+
+func TestGenService_WiringInfo(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, "core.inject.json", "")
+	specPath := p.out("core.inject.json")
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+	for _, s := range []string{"WiringInfo()", "WiringManifest()", "SpecInfo()"} {
+		if strings.Contains(out, s) {
+			t.Fatalf("%s needs introspection:\n%s", s, out)
+		}
+	}
+
+	spec := strings.Replace(mustReadString(t, specPath), `"introspection":false`, `"introspection":true`, 1)
+	p.write("core.inject.json", spec)
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out = p.read("core.gen.go")
+
+	want := `return di.NewWiringInfo("CoreV4", "` + filepath.ToSlash(specPath) + `", "` + specSHA256([]byte(spec)) + `", b.injected, nil, nil).WithUnsafe(b.escapes.Consumers())`
+	if !strings.Contains(out, want) {
+		t.Fatalf("expected WiringInfo body %q in:\n%s", want, out)
+	}
+}
+
+func TestGenGraph_WiringHandler(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{false, true} {
+		enabled := enabled
+		t.Run(map[bool]string{false: "disabled", true: "enabled"}[enabled], func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+
+			g := GraphSpec{
+				Package: "p",
+				Roots: []GraphRoot{{
+					Name:          "App",
+					WiringHandler: enabled,
+					Services: []GraphService{
+						{Var: "b", FacadeCtor: "NewBV4", FacadeType: "*BV4", ImplType: "B"},
+						{Var: "a", FacadeCtor: "NewAV4", FacadeType: "*AV4", ImplType: "A"},
+					},
+				}},
+			}
+			raw, err := json.Marshal(g)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			graphPath := p.write("graph.json", string(raw))
+
+			genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+			out := p.read("graph.gen.go")
+
+			if !enabled {
+				if strings.Contains(out, ") WiringHandler() http.Handler") || strings.Contains(out, "wiring []di.WiringInfo") {
+					t.Fatalf("did not expect wiring introspection when disabled:\n%s", out)
+				}
+				assertNotHasImport(t, out, "net/http")
+				return
+			}
+
+			assertHasImport(t, out, "net/http")
+			assertHasImport(t, out, "time")
+			assertContainsInOrder(t, out,
+				"wiring []di.WiringInfo",
+				`res.wiring = append(res.wiring, di.BuiltWiring(aB, "a", time.Now()))`,
+				`res.wiring = append(res.wiring, di.BuiltWiring(bB, "b", time.Now()))`,
+				"func (r AppResult) Wiring() []di.WiringInfo {",
+				"func (r AppResult) WiringHandler() http.Handler {",
+				`Graph:     "`+filepath.ToSlash(graphPath)+`"`,
+				`GraphHash: "`+specSHA256(raw)+`"`,
+			)
+		})
+	}
+}
+
+// -------------------------
+// Build profiling (-profile)
+// -------------------------
+
+func TestGenGraph_Profile(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []bool{false, true} {
+		parallel := parallel
+		t.Run(map[bool]string{false: "serial", true: "parallel"}[parallel], func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+
+			g := GraphSpec{
+				Package:  "p",
+				Parallel: parallel,
+				Roots: []GraphRoot{{
+					Name: "App",
+					Services: []GraphService{
+						{Var: "b", FacadeCtor: "NewBV4", FacadeType: "*BV4", ImplType: "B"},
+						{Var: "a", FacadeCtor: "NewAV4", FacadeType: "*AV4", ImplType: "A"},
+					},
+					Wiring: []GraphWiring{{To: "a", Call: "InjectB", ArgFrom: "b"}},
+				}},
+			}
+			raw, err := json.Marshal(g)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			graphPath := p.write("graph.json", string(raw))
+
+			if err := run([]string{"-graph", graphPath, "-out", p.out("graph.gen.go"), "-profile"}); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			out := p.read("graph.gen.go")
+
+			assertHasImport(t, out, "time")
+			build := "res.profile.Services[0].Build = time.Since(mark)"
+			if parallel {
+				build = "res.profile.Services[0].Build = time.Since(start)"
+			}
+			// build order is b -> a, so b is index 0 even though a is constructed first
+			assertContainsInOrder(t, out,
+				"profile di.BuildProfile",
+				`res.profile = di.NewBuildProfile("App", "b", "a")`,
+				"aB := NewAV4()",
+				"res.profile.Services[1].Construct = time.Since(mark)",
+				"bB := NewBV4()",
+				"res.profile.Services[0].Construct = time.Since(mark)",
+				"aB.InjectB(bB.UnsafeImpl())",
+				"res.profile.Services[1].Inject += time.Since(mark)",
+				build,
+				"res.profile.Total = time.Since(started)",
+				"func (r AppResult) Profile() di.BuildProfile {",
+			)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		graphPath := p.write("graph.json", `{"package":"p","roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}]}]}`)
+
+		genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+		out := p.read("graph.gen.go")
+		if strings.Contains(out, "profile") {
+			t.Fatalf("did not expect profiling code without -profile:\n%s", out)
+		}
+		assertNotHasImport(t, out, "time")
+	})
+}
+
+// -------------------------
+// Builder bookkeeping maps
+// -------------------------
+
+func TestGenService_BookkeepingMaps(t *testing.T) {
+	t.Parallel()
+
+	t.Run("required_only", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		writeHealthSpec(t, p, "core.inject.json", "")
+		genService(p.out("core.inject.json"), p.out("core.gen.go"), genOptions{})
+		out := p.read("core.gen.go")
+
+		if strings.Contains(out, "optionalResolved") || strings.Contains(out, "optionalMissing") {
+			t.Fatalf("did not expect optional diagnostics maps without optional deps:\n%s", out)
+		}
+		assertContainsInOrder(t, out,
+			"injected: make(map[string]bool, 1),",
+			"injected: maps.Clone(b.injected),",
+			"clear(b.injected)",
+		)
+	})
+
+	t.Run("with_optional", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		genService(writeLoggingSpec(t, p, LoggingSpec{}), p.out("core.gen.go"), genOptions{})
+		out := p.read("core.gen.go")
+
+		if strings.Contains(out, "optionalResolved: map[string]string{}") {
+			t.Fatalf("optional maps must not be allocated eagerly:\n%s", out)
+		}
+		assertContainsInOrder(t, out,
+			"optionalResolved map[string]string",
+			"optionalResolved: maps.Clone(b.optionalResolved),",
+			"clear(b.optionalResolved)",
+			"if !di.Slim && b.optionalResolved == nil {",
+			"b.optionalResolved = make(map[string]string, 1)",
+			"b.optionalMissing = make(map[string]string, 1)",
+		)
+	})
+}
+
+// TestGenService_SpecInfo verifies the generated <FacadeName>SpecInfo.
+func TestGenService_SpecInfo(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "introspection": true,
+  "required": [
+    { "name": "DB", "field": "db", "type": "*DB", "nilable": true },
+    { "name": "Clock", "field": "clock", "type": "Clock", "nilable": true }
+  ],
+  "optional": [
+    { "name": "Tracer", "type": "Tracer", "registryKey": "p.tracer", "apply": { "kind": "field", "name": "tracer" } }
+  ],
+  "methods": [
+    { "name": "Process", "requires": ["DB", "Clock"], "returns": [{ "type": "error" }] },
+    { "name": "Name", "returns": [{ "type": "string" }] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+	assertContainsInOrder(t, out,
+		"func CoreV4SpecInfo() di.SpecInfo {",
+		`Facade:   "CoreV4",`,
+		`Impl:     "Core",`,
+		`core.inject.json",`,
+		`SpecHash: "`+specSHA256([]byte(p.read("core.inject.json")))+`",`,
+		`Required: []string{"Clock", "DB"},`,
+		`{Name: "Tracer", RegistryKey: "p.tracer"},`,
+		`{Name: "Name"},`,
+		`{Name: "Process", Requires: []string{"DB", "Clock"}},`,
+	)
+}
+
+// TestGenService_IdentityHelpers verifies SameImpl and ImplPtr are generated
+// only with identityHelpers, also when the spec disables UnsafeImpl.
+func TestGenService_IdentityHelpers(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	spec := `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "identityHelpers": true, "unsafeImpl": { "disable": %s },
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`
+	genService(p.write("plain.inject.json", strings.NewReplacer(`"identityHelpers": true, `, "", "%s", "false").Replace(spec)), p.out("plain.gen.go"), genOptions{})
+	if out := p.read("plain.gen.go"); strings.Contains(out, "SameImpl") || strings.Contains(out, "ImplPtr") {
+		t.Fatalf("identity helpers are opt-in:\n%s", out)
+	}
+	for _, disable := range []string{"false", "true"} {
+		genService(p.write("core_"+disable+".inject.json", strings.Replace(spec, "%s", disable, 1)), p.out("core_"+disable+".gen.go"), genOptions{})
+		assertContainsInOrder(t, p.read("core_"+disable+".gen.go"),
+			"func (b *CoreV4) SameImpl(other *CoreV4) bool {\n\treturn other != nil && b.svc == other.svc\n}",
+			"func (b *CoreV4) ImplPtr() uintptr { return di.ImplPtr(b.svc) }",
+		)
+	}
+}
+
+// TestGenService_Slim verifies the optional-dep diagnostics and Explain are
+// behind the di.Slim constant (odi_slim builds) while validation is not.
+func TestGenService_Slim(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	genService(writeLoggingSpec(t, p, LoggingSpec{}), p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"func (b *CoreV4) Explain() string {\n\tif di.Slim {\n\t\treturn di.SlimExplain\n\t}",
+		"if !di.Slim && b.optionalMissing == nil {",
+		"if !di.Slim {\n\t\t\t\tb.optionalResolved[\"p.tracer\"] = fmt.Sprintf(\"%T\", v)\n\t\t\t}",
+		"if !di.Slim {\n\t\t\t\tb.optionalMissing[\"p.tracer\"] = \"not provided\"\n\t\t\t}",
+	)
+}
+
+// -------------------------
+// UnsafeImpl: Reset, tracking and disabling
+// -------------------------
+
+func TestGenService_ResetTracksUnsafeConsumers(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, "core.inject.json", "")
+	genService(p.out("core.inject.json"), p.out("core.gen.go"), genOptions{})
+
+	assertContainsInOrder(t, p.read("core.gen.go"),
+		"escapes di.Escapes",
+		"escapes:  b.escapes.Clone(),",
+		"func (b *CoreV4) Reset() *CoreV4 {\n\tb.escapes.Reset()\n\tb.recreate()",
+		"func (b *CoreV4) ResetUnsafe() (*Core, []string) {\n\torphaned := b.escapes.Orphans()\n\tb.recreate()\n\treturn b.svc, orphaned\n}",
+		"func (b *CoreV4) recreate() {",
+		"clear(b.injected)",
+		"func (b *CoreV4) UnsafeImpl() *Core {\n\tb.escapes.Add(\"\")\n\treturn b.svc\n}",
+		"func (b *CoreV4) UnsafeImplFor(consumer string) *Core {\n\tb.escapes.Add(consumer)\n\treturn b.svc\n}",
+		"+ b.escapes.Explain()",
+		"func (b *CoreV4) buildScoped(",
+		`if err := b.escapes.CheckStale("CoreV4", ctx); err != nil {`,
+		"if missing := b.missingMask(need); missing != 0 {",
+	)
+}
+
+func TestGenService_UnsafeImplTrack(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	spec := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "unsafeImpl": { "track": true },
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`)
+	genService(spec, p.out("core.gen.go"), genOptions{})
+
+	assertContainsInOrder(t, p.read("core.gen.go"),
+		`b.escapes.AddTracked("")`,
+		"b.escapes.AddTracked(consumer)",
+		"+ b.escapes.Explain()",
+	)
+}
+
+func TestGenService_UnsafeImplDisable(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	spec := `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  %s
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`
+	genService(p.write("core.inject.json", strings.Replace(spec, "%s", `"unsafeImpl": { "disable": true },`, 1)), p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+	for _, s := range []string{"UnsafeImpl()", "UnsafeImplFor", "ResetUnsafe", "escapes", "CheckStale"} {
+		if strings.Contains(out, s) {
+			t.Fatalf("unsafeImpl.disable must omit %s:\n%s", s, out)
+		}
+	}
+	assertContainsInOrder(t, out, "func (b *CoreV4) Reset() *CoreV4 {\n\tb.recreate()\n\treturn b\n}")
+
+	tests := []struct{ name, fields, want string }{
+		{name: "cyclic", fields: `"cyclic": true, "unsafeImpl": { "disable": true },`, want: "spec unsafeImpl.disable cannot be combined with cyclic"},
+		{name: "track", fields: `"unsafeImpl": { "disable": true, "track": true },`, want: "spec unsafeImpl.track needs UnsafeImpl"},
+	}
+	for _, tt := range tests {
+		path := p.write(tt.name+".inject.json", strings.Replace(spec, "%s", tt.fields, 1))
+		assertPanicContains(t, func() { genService(path, p.out(tt.name+".gen.go"), genOptions{}) }, tt.want)
+	}
+
+	// a graph cannot wire a service whose UnsafeImpl is disabled into another
+	p.write("specs/core.inject.json", strings.Replace(spec, "%s", `"unsafeImpl": { "disable": true },`, 1))
+	graph := p.write("specs/graph.json", `{"package": "p", "roots": [{"name": "App",
+  "services": [
+    {"var": "core", "spec": "core.inject.json"},
+    {"var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API"}
+  ],
+  "wiring": [{"to": "api", "call": "InjectCore", "argFrom": "core"}]
+}]}`)
+	assertPanicContains(t, func() {
+		genGraph(graph, p.out("graph.gen.go"), genOptions{})
+	}, "graph root App: wiring core into api needs core.UnsafeImpl(), which its spec disables (unsafeImpl.disable)")
+}
+
+// -------------------------
+// buildScoped mask
+// -------------------------
+
+func TestRequiresMask(t *testing.T) {
+	t.Parallel()
+
+	if got := requiresMask("CoreV4", nil); got != "0" {
+		t.Fatalf("empty requires: got %q", got)
+	}
+	if got := requiresMask("CoreV4", []string{"Alpha", "Beta"}); got != "reqCoreV4Alpha | reqCoreV4Beta" {
+		t.Fatalf("mask: got %q", got)
+	}
+}
+
+func TestGenService_BuildScopedMask(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [
+    { "name": "Beta", "field": "beta", "type": "*Beta", "nilable": true },
+    { "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }
+  ],
+  "methods": [
+    { "name": "Run", "requires": ["Beta"] },
+    { "name": "Ping" }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"reqCoreV4Alpha uint64 = 1 << iota",
+		"reqCoreV4Beta",
+		"reqCoreV4All = 1<<2 - 1",
+		`var reqCoreV4Names = [...]string{`,
+		`"Alpha",`,
+		`"Beta",`,
+		"return di.MissingNames(reqCoreV4Names[:], b.missingMask(reqCoreV4All))",
+		`b.buildScoped("Build", reqCoreV4All)`,
+		"func (b *CoreV4) missingMask(need uint64) uint64 {",
+		"if need&reqCoreV4Alpha != 0 && b.svc.alpha == nil {",
+		"if need&reqCoreV4Beta != 0 && b.svc.beta == nil {",
+		"func (b *CoreV4) buildScoped(ctx string, need uint64) (*Core, error) {",
+		"if missing := b.missingMask(need); missing != 0 {",
+		`svc, err := b.buildScoped("Ping", 0)`,
+		`svc, err := b.buildScoped("Run", reqCoreV4Beta)`,
+	)
+	if strings.Contains(out, "isMissing") {
+		t.Fatalf("did not expect per-dep boolean locals:\n%s", out)
+	}
+}
+
+func TestGenService_WideRequiredMask(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	var deps []string
+	for i := 0; i < 70; i++ {
+		deps = append(deps, fmt.Sprintf(`{ "name": "D%02d", "field": "d%02d", "type": "*X", "nilable": true }`, i, i))
+	}
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [`+strings.Join(deps, ",\n")+`],
+  "methods": [{ "name": "Run", "requires": ["D65", "D01"] }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"type reqCoreV4Mask [2]uint64",
+		"reqCoreV4D00 = reqCoreV4Mask{0: 1 << 0}",
+		"reqCoreV4D65 = reqCoreV4Mask{1: 1 << 1}",
+		"reqCoreV4All = reqCoreV4Mask{^uint64(0), 1<<6 - 1}",
+		"missing := b.missingMask(reqCoreV4All)\n\treturn di.MissingNamesWide(reqCoreV4Names[:], missing[:])",
+		"func (b *CoreV4) missingMask(need reqCoreV4Mask) reqCoreV4Mask {",
+		"if need[1]&reqCoreV4D65[1] != 0 && b.svc.d65 == nil {\n\t\tmissing[1] |= reqCoreV4D65[1]",
+		"func (b *CoreV4) buildScoped(ctx string, need reqCoreV4Mask) (*Core, error) {",
+		"if missing := b.missingMask(need); missing != (reqCoreV4Mask{}) {",
+		"di.WiringIncompleteWide(",
+		`svc, err := b.buildScoped("Run", reqCoreV4Mask{0: 1 << 1, 1: 1 << 1})`,
+	)
+
+	grouped := p.write("grouped.inject.json", strings.Replace(p.read("core.inject.json"), `"nilable": true }`, `"nilable": true, "group": "g" }`, 2))
+	assertPanicContains(t, func() {
+		genService(grouped, p.out("grouped.gen.go"), genOptions{})
+	}, "spec with dep groups or oneOf sets supports at most 64 required deps (got 70)")
+}
+
+func TestGenService_ChainedMethods(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("admin.inject.json", `{
+  "package": "p", "wrapperBase": "Admin", "versionSuffix": "V4",
+  "implType": "Admin", "constructor": "NewAdmin",
+  "required": [{ "name": "Store", "field": "store", "type": "*Store", "nilable": true }],
+  "methods": [
+    { "name": "SetMode", "params": [{ "name": "mode", "type": "string" }], "requires": ["Store"], "chain": true },
+    { "name": "Reset" }
+  ]
+}`)
+
+	genService(specPath, p.out("admin.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("admin.gen.go"),
+		"func (b *AdminV4) Reset() {",
+		`svc, err := b.buildScoped("Reset", 0)`,
+		"return\n",
+		"return svc.Reset()",
+		"func (b *AdminV4) SetMode(",
+		"mode string,",
+		") *AdminV4 {",
+		`svc, err := b.buildScoped("SetMode", reqAdminV4Store)`,
+		"return b\n",
+		"svc.SetMode(",
+		"mode,",
+		"return b\n}",
+	)
+
+	bad := p.write("bad.inject.json", `{
+  "package": "p", "wrapperBase": "Admin", "versionSuffix": "V4",
+  "implType": "Admin", "constructor": "NewAdmin",
+  "required": [{ "name": "Store", "field": "store", "type": "*Store", "nilable": true }],
+  "methods": [{ "name": "Count", "returns": [{ "type": "int" }], "chain": true }]
+}`)
+	assertPanicContains(t, func() { genService(bad, p.out("bad.gen.go"), genOptions{}) },
+		"method Count chain requires a method without returns")
+}
+
+// -------------------------
+// Stale import pruning
+// -------------------------
+
+func TestDropUnusedManagedImports(t *testing.T) {
+	t.Parallel()
+
+	src := []byte(`package p
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	keep "example.com/keep/me"
+	_ "net/http"
+)
+
+var _ = fmt.Sprint(keep.X)
+`)
+	out := string(dropUnusedManagedImports(src))
+
+	assertHasImport(t, out, "fmt")
+	assertNotHasImport(t, out, "log/slog")
+	assertNotHasImport(t, out, "strings")
+	if !strings.Contains(out, `keep "example.com/keep/me"`) || !strings.Contains(out, `_ "net/http"`) {
+		t.Fatalf("non-managed and blank imports must be kept:\n%s", out)
+	}
+
+	clean := []byte("package p\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint()\n")
+	if got := dropUnusedManagedImports(clean); string(got) != string(clean) {
+		t.Fatalf("expected unchanged source, got:\n%s", got)
+	}
+	if got := dropUnusedManagedImports([]byte("not go")); string(got) != "not go" {
+		t.Fatalf("expected invalid source to be returned as-is")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Graph fragments (-graph with a glob)
+// -------------------------
+
+// isGraphGlob reports whether a -graph argument is a glob of graph fragments.
+func isGraphGlob(graphPath string) bool {
+	return strings.ContainsAny(graphPath, "*?[")
+}
+
+// readGraphSpec decodes the graph at graphPath and returns it with the hash the
+// generated file records. A glob (e.g. "specs/graph-*.json") reads every matching
+// fragment and merges them into one graph (see mergeGraphFragments); its hash
+// covers the fragments in path order.
+func readGraphSpec(graphPath string) (GraphSpec, string) {
+	if !isGraphGlob(graphPath) {
+		raw := mustRead(graphPath)
+		var g GraphSpec
+		if err := decodeSpec(renderSpecVars(graphPath, raw), &g); err != nil {
+			die(graphPath + ": " + err.Error())
+		}
+		return g, specSHA256(raw)
+	}
+
+	paths, err := filepath.Glob(graphPath)
+	must(err)
+	if len(paths) == 0 {
+		die("no graph files match " + graphPath)
+	}
+	sort.Strings(paths)
+
+	frags := make([]graphFragment, 0, len(paths))
+	var hashes strings.Builder
+	for _, p := range paths {
+		raw := mustRead(p)
+		var g GraphSpec
+		if err := decodeSpec(renderSpecVars(p, raw), &g); err != nil {
+			die(p + ": " + err.Error())
+		}
+		absolutizeSpecLinks(&g, p)
+		frags = append(frags, graphFragment{Path: p, Spec: g})
+		hashes.WriteString(specSHA256(raw) + "\n")
+	}
+	return mergeGraphFragments(frags), sha256Hex([]byte(hashes.String()))
+}
+
+// graphFragment is one file of a globbed graph.
+type graphFragment struct {
+	Path string
+	Spec GraphSpec
+}
+
+// absolutizeSpecLinks makes the linked service specs of a fragment independent
+// of its directory, since the merged graph has no single file to resolve them from.
+func absolutizeSpecLinks(g *GraphSpec, fragPath string) {
+	for _, root := range g.allRoots() {
+		for i := range root.Services {
+			if s := &root.Services[i]; s.Spec != "" {
+				abs, err := filepath.Abs(linkedSpecPath(fragPath, s.Spec))
+				must(err)
+				s.Spec = abs
+			}
+		}
+	}
+}
+
+// mergeGraphFragments merges graph fragments into one graph:
+//   - package and settings (imports, config, logging, codegen, flags) are taken
+//     from the fragments that set them and must not conflict;
+//   - profiles are merged by name;
+//   - roots (and the shared section) with the same name are merged: services by
+//     var (a var declared twice must be identical; it is then shared), wiring and
+//     hooks appended without duplicates, root flags ORed.
+//
+// Roots keep their first-seen order, so "preserveOrder" follows the fragments'
+// path order.
+func mergeGraphFragments(frags []graphFragment) GraphSpec {
+	var g GraphSpec
+	setBy := map[string]string{} // setting -> fragment that set it
+	rootIdx := map[string]int{}
+	for _, f := range frags {
+		fs := f.Spec
+		mergeGraphSetting(&g.Package, fs.Package, "package", f.Path, setBy)
+		mergeGraphSetting(&g.Imports, fs.Imports, "imports", f.Path, setBy)
+		mergeGraphSetting(&g.Config, fs.Config, "config", f.Path, setBy)
+		mergeGraphSetting(&g.Logging, fs.Logging, "logging", f.Path, setBy)
+		mergeGraphSetting(&g.Codegen, fs.Codegen, "codegen", f.Path, setBy)
+		mergeGraphSetting(&g.Manifest, fs.Manifest, "manifest", f.Path, setBy)
+		g.Parallel = g.Parallel || fs.Parallel
+		g.PreserveOrder = g.PreserveOrder || fs.PreserveOrder
+		g.ExposeBuilders = g.ExposeBuilders || fs.ExposeBuilders
+
+		for name, expr := range fs.Profiles {
+			if g.Profiles == nil {
+				g.Profiles = map[string]string{}
+			}
+			v := g.Profiles[name]
+			mergeGraphSetting(&v, expr, "profile "+name, f.Path, setBy)
+			g.Profiles[name] = v
+		}
+
+		for _, r := range fs.Roots {
+			i, ok := rootIdx[r.Name]
+			if !ok {
+				rootIdx[r.Name] = len(g.Roots)
+				g.Roots = append(g.Roots, GraphRoot{Name: r.Name})
+				i = len(g.Roots) - 1
+			}
+			mergeGraphRoot(&g.Roots[i], r, "root "+r.Name, f.Path, setBy)
+		}
+		if fs.Shared != nil {
+			if g.Shared == nil {
+				g.Shared = &GraphRoot{}
+			}
+			mergeGraphSetting(&g.Shared.Name, fs.Shared.Name, "shared name", f.Path, setBy)
+			mergeGraphRoot(g.Shared, *fs.Shared, "shared", f.Path, setBy)
+		}
+	}
+	return g
+}
+
+// mergeGraphRoot merges fragment root r into dst.
+func mergeGraphRoot(dst *GraphRoot, r GraphRoot, what, fragPath string, setBy map[string]string) {
+	dst.BuildWithRegistry = dst.BuildWithRegistry || r.BuildWithRegistry
+	dst.HealthHandler = dst.HealthHandler || r.HealthHandler
+	dst.MountAll = dst.MountAll || r.MountAll
+	dst.ConfigReload = dst.ConfigReload || r.ConfigReload
+	dst.WiringHandler = dst.WiringHandler || r.WiringHandler
+
+	for _, s := range r.Services {
+		key := what + " service " + s.Var
+		prev, ok := setBy[key]
+		if !ok {
+			setBy[key] = fragPath
+			dst.Services = append(dst.Services, s)
+			continue
+		}
+		for _, have := range dst.Services {
+			if have.Var == s.Var && !reflect.DeepEqual(have, s) {
+				die(fmt.Sprintf("graph fragments %s and %s declare %s differently", prev, fragPath, key))
+			}
+		}
+	}
+	dst.Wiring = appendMissing(dst.Wiring, r.Wiring...)
+	dst.After = appendMissing(dst.After, r.After...)
+}
+
+// mergeGraphSetting sets *dst to v when v is set; a second, different value is a
+// conflict between the fragments that set them.
+func mergeGraphSetting[T any](dst *T, v T, what, fragPath string, setBy map[string]string) {
+	var zero T
+	if reflect.DeepEqual(v, zero) {
+		return
+	}
+	if prev, ok := setBy[what]; ok && !reflect.DeepEqual(*dst, v) {
+		die(fmt.Sprintf("graph fragments %s and %s set %s differently", prev, fragPath, what))
+	}
+	if _, ok := setBy[what]; !ok {
+		setBy[what] = fragPath
+	}
+	*dst = v
+}
+
+// appendMissing appends the items of add that dst does not contain yet.
+func appendMissing[T any](dst []T, add ...T) []T {
+	for _, a := range add {
+		found := false
+		for _, d := range dst {
+			if reflect.DeepEqual(d, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst = append(dst, a)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Graph fragments (-graph with a glob)
+// -------------------------
+
+func TestRun_GraphGlobMerge(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	p.write("specs/db.inject.json", `{
+  "package": "p", "wrapperBase": "DB", "versionSuffix": "V4", "implType": "DB", "constructor": "NewDB",
+  "required": [{ "name": "Conn", "field": "conn", "type": "*Conn", "nilable": true }],
+  "healthCheck": "Ping"
+}`)
+	p.write("specs/graph-core.json", `{
+  "package": "p",
+  "profiles": { "prod": "true" },
+  "roots": [{
+    "name": "BuildApp",
+    "services": [
+      { "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB", "spec": "db.inject.json" },
+      { "var": "core", "facadeCtor": "NewCoreV4", "facadeType": "*CoreV4", "implType": "Core" }
+    ],
+    "wiring": [{ "to": "core", "call": "InjectDB", "argFrom": "db" }]
+  }]
+}`)
+	p.write("specs/graph-api.json", `{
+  "package": "p", "profiles": { "prod": "true" },
+  "roots": [
+    {
+      "name": "BuildApp", "buildWithRegistry": true,
+      "services": [
+        { "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB", "spec": "db.inject.json" },
+        { "var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API" }
+      ],
+      "wiring": [
+        { "to": "core", "call": "InjectDB", "argFrom": "db" },
+        { "to": "api", "call": "InjectCore", "argFrom": "core" }
+      ]
+    },
+    {
+      "name": "Admin",
+      "services": [{ "var": "admin", "facadeCtor": "NewAdminV4", "facadeType": "*AdminV4", "implType": "Admin" }]
+    }
+  ]
+}`)
+
+	pattern := p.out("specs/graph-*.json")
+	if err := run([]string{"-graph", pattern, "-out", p.out("graph.gen.go")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out,
+		"// Graph: "+pattern,
+		"func Admin(",
+		"func BuildApp(reg di.Registry) (BuildAppResult, error) {",
+		"dbB := NewDBV4()",
+		"apiB.InjectCore(coreB.UnsafeImpl())",
+		"coreB.InjectDB(dbB.UnsafeImpl())",
+		// linked spec of a fragment, resolved from the fragment's directory
+		"func (r BuildAppResult) HealthCheck(",
+	)
+	if n := strings.Count(out, "coreB.InjectDB("); n != 1 {
+		t.Fatalf("identical wiring in two fragments must be merged, found %d:\n%s", n, out)
+	}
+	if n := strings.Count(out, "dbB := NewDBV4()"); n != 1 {
+		t.Fatalf("a service declared in two fragments must be built once, found %d:\n%s", n, out)
+	}
+}
+
+func TestReadGraphSpec_GlobErrors(t *testing.T) {
+	t.Parallel()
+
+	const svcA = `{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }`
+	tests := []struct {
+		name  string
+		frags map[string]string
+		want  string
+	}{
+		{
+			name:  "no_match",
+			frags: map[string]string{},
+			want:  "no graph files match",
+		},
+		{
+			name: "package_conflict",
+			frags: map[string]string{
+				"graph-a.json": `{"package": "p", "roots": [{"name": "App", "services": [` + svcA + `]}]}`,
+				"graph-b.json": `{"package": "q", "roots": []}`,
+			},
+			want: "graph-b.json set package differently",
+		},
+		{
+			name: "service_conflict",
+			frags: map[string]string{
+				"graph-a.json": `{"package": "p", "roots": [{"name": "App", "services": [` + svcA + `]}]}`,
+				"graph-b.json": `{"package": "p", "roots": [{"name": "App", "services": [{ "var": "a", "facadeCtor": "NewAV5", "facadeType": "*AV5", "implType": "A" }]}]}`,
+			},
+			want: "graph-b.json declare root App service a differently",
+		},
+		{
+			name: "profile_conflict",
+			frags: map[string]string{
+				"graph-a.json": `{"package": "p", "profiles": {"prod": "cfg.Prod"}, "roots": [{"name": "App", "services": [` + svcA + `]}]}`,
+				"graph-b.json": `{"package": "p", "profiles": {"prod": "true"}}`,
+			},
+			want: "set profile prod differently",
+		},
+		{
+			name: "invalid_fragment",
+			frags: map[string]string{
+				"graph-a.json": `{"package": `,
+			},
+			want: "graph-a.json: ",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			for name, content := range tt.frags {
+				p.write(name, content)
+			}
+			assertPanicContains(t, func() { readGraphSpec(p.out("graph-*.json")) }, tt.want)
+		})
+	}
+}
+
+func TestReadGraphSpec_GlobSharedAndHash(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("graph-a.json", `{"package": "p", "shared": {"services": [{ "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB" }]}}`)
+	p.write("graph-b.json", `{"package": "p", "parallel": true, "roots": [{"name": "App", "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }]}]}`)
+
+	g, hash := readGraphSpec(p.out("graph-*.json"))
+	if g.Package != "p" || !g.Parallel || g.Shared == nil || len(g.Shared.Services) != 1 || len(g.Roots) != 1 {
+		t.Fatalf("unexpected merged graph: %+v", g)
+	}
+
+	p.write("graph-b.json", `{"package": "p", "parallel": true, "roots": [{"name": "App", "services": [{ "var": "b", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }]}]}`)
+	if _, hash2 := readGraphSpec(p.out("graph-*.json")); hash2 == hash || len(hash2) != 64 {
+		t.Fatalf("the hash must change with any fragment: %s vs %s", hash, hash2)
+	}
+}
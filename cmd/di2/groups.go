@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// -------------------------
+// All-or-nothing dep groups and exclusive deps
+// -------------------------
+
+// DepGroup is a computed all-or-nothing group or exclusive ("oneOf") set of
+// required deps.
+type DepGroup struct {
+	Name string
+	Deps []string // in Required order
+}
+
+// setDepGroups collects required deps sharing a "group" into spec.Groups and
+// deps sharing a "oneOf" set into spec.OneOfs (both sorted by name), and lists
+// them all in spec.GroupedDeps.
+//
+// Grouped deps are not required on their own: Build instead fails with a
+// di.GroupWiringError when a group is only partly wired, and with a
+// di.OneOfWiringError unless exactly one dep of each oneOf set is wired.
+func setDepGroups(s *ServiceSpec) {
+	groups := map[string]*DepGroup{}
+	oneOfs := map[string]*DepGroup{}
+	add := func(sets map[string]*DepGroup, name, dep string) {
+		if !validGroupName(name) {
+			die("required dep " + dep + ": group name must use only letters, digits, '_', '-' and '.'")
+		}
+		g := sets[name]
+		if g == nil {
+			g = &DepGroup{Name: name}
+			sets[name] = g
+		}
+		g.Deps = append(g.Deps, dep)
+	}
+
+	s.GroupedDeps = nil
+	for _, d := range s.Required {
+		switch {
+		case d.Group != "" && d.OneOf != "":
+			die("required dep " + d.Name + ": group and oneOf are mutually exclusive")
+		case d.Group != "":
+			add(groups, d.Group, d.Name)
+		case d.OneOf != "":
+			add(oneOfs, d.OneOf, d.Name)
+		default:
+			continue
+		}
+		s.GroupedDeps = append(s.GroupedDeps, d.Name)
+	}
+
+	s.Groups = sortedDepGroups(groups, "dep group")
+	s.OneOfs = sortedDepGroups(oneOfs, "oneOf set")
+	if s.GroupedDeps != nil && s.MaskWords() > 0 {
+		die(fmt.Sprintf("spec with dep groups or oneOf sets supports at most %d required deps (got %d)", maskWordBits, len(s.Required)))
+	}
+}
+
+func sortedDepGroups(sets map[string]*DepGroup, what string) []DepGroup {
+	var out []DepGroup
+	for _, g := range sets {
+		if len(g.Deps) < 2 {
+			die(what + " " + g.Name + " must have at least 2 deps (got " + g.Deps[0] + " only)")
+		}
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func validGroupName(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// All-or-nothing dep groups and exclusive deps
+// -------------------------
+
+func TestGenService_DepGroups(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore", "preserveOrder": true,
+  "required": [
+    { "name": "DB", "field": "db", "type": "*DB", "nilable": true },
+    { "name": "Producer", "field": "producer", "type": "*Producer", "nilable": true, "group": "kafka" },
+    { "name": "Consumer", "field": "consumer", "type": "*Consumer", "nilable": true, "group": "kafka" }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"reqCoreV4Grouped = reqCoreV4Producer | reqCoreV4Consumer",
+		"reqCoreV4All     = (1<<3 - 1) &^ reqCoreV4Grouped",
+		"var reqCoreV4Groups = [...]di.DepGroup{",
+		`{Name: "kafka", Mask: reqCoreV4Producer | reqCoreV4Consumer},`,
+		"func (b *CoreV4) buildScoped(ctx string, need uint64) (*Core, error) {",
+		"di.WiringIncomplete(",
+		`if err := di.CheckGroups("CoreV4", ctx, `,
+		"b.missingMask(reqCoreV4Grouped), reqCoreV4Groups[:]); err != nil {",
+	)
+}
+
+func TestGenService_OneOf(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore", "preserveOrder": true,
+  "required": [
+    { "name": "SQLStore", "field": "sql", "type": "*SQLStore", "nilable": true, "oneOf": "store" },
+    { "name": "Log", "field": "log", "type": "*Log", "nilable": true },
+    { "name": "MemoryStore", "field": "mem", "type": "*MemoryStore", "nilable": true, "oneOf": "store" }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"reqCoreV4Grouped = reqCoreV4SQLStore | reqCoreV4MemoryStore",
+		"reqCoreV4All     = (1<<3 - 1) &^ reqCoreV4Grouped",
+		"var reqCoreV4OneOfs = [...]di.DepGroup{",
+		`{Name: "store", Mask: reqCoreV4SQLStore | reqCoreV4MemoryStore},`,
+		`if err := di.CheckOneOf("CoreV4", ctx, `,
+		"b.missingMask(reqCoreV4Grouped), reqCoreV4OneOfs[:]); err != nil {",
+	)
+	if strings.Contains(out, "CheckGroups") {
+		t.Fatalf("did not expect group checks without groups:\n%s", out)
+	}
+}
+
+func TestGenService_NoDepGroups(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+	if strings.Contains(out, "Grouped") || strings.Contains(out, "CheckGroups") {
+		t.Fatalf("did not expect group code without groups:\n%s", out)
+	}
+}
+
+func TestSetDepGroups(t *testing.T) {
+	t.Parallel()
+
+	spec := ServiceSpec{Required: []RequiredDep{
+		{Name: "DB"},
+		{Name: "Producer", Group: "kafka"},
+		{Name: "Cache", Group: "cache"},
+		{Name: "Consumer", Group: "kafka"},
+		{Name: "CacheTTL", Group: "cache"},
+		{Name: "SQL", OneOf: "store"},
+		{Name: "Mem", OneOf: "store"},
+	}}
+	setDepGroups(&spec)
+	want := []DepGroup{
+		{Name: "cache", Deps: []string{"Cache", "CacheTTL"}},
+		{Name: "kafka", Deps: []string{"Producer", "Consumer"}},
+	}
+	if !reflect.DeepEqual(spec.Groups, want) {
+		t.Fatalf("got %+v want %+v", spec.Groups, want)
+	}
+	if want := []DepGroup{{Name: "store", Deps: []string{"SQL", "Mem"}}}; !reflect.DeepEqual(spec.OneOfs, want) {
+		t.Fatalf("got %+v want %+v", spec.OneOfs, want)
+	}
+	if want := []string{"Producer", "Cache", "Consumer", "CacheTTL", "SQL", "Mem"}; !reflect.DeepEqual(spec.GroupedDeps, want) {
+		t.Fatalf("got %v want %v", spec.GroupedDeps, want)
+	}
+
+	tests := []struct {
+		name string
+		deps []RequiredDep
+		want string
+	}{
+		{"single dep group", []RequiredDep{{Name: "A", Group: "kafka"}}, "dep group kafka must have at least 2 deps (got A only)"},
+		{"single dep oneOf", []RequiredDep{{Name: "A", OneOf: "store"}}, "oneOf set store must have at least 2 deps (got A only)"},
+		{"group and oneOf", []RequiredDep{{Name: "A", Group: "g", OneOf: "store"}}, "required dep A: group and oneOf are mutually exclusive"},
+		{"bad name", []RequiredDep{{Name: "A", Group: `ka"fka`}, {Name: "B", Group: `ka"fka`}}, "required dep A: group name must use only letters, digits"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := ServiceSpec{Required: tt.deps}
+			assertPanicContains(t, func() { setDepGroups(&s) }, tt.want)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// -------------------------
+// Post-build hooks
+// -------------------------
+
+// prepareGraphHooks splits each hook's call into service var and method and
+// checks that the var names a service of the root or a shared one.
+func prepareGraphHooks(root *GraphRoot) {
+	services := map[string]GraphService{}
+	for _, s := range root.SharedServices {
+		services[s.Var] = s
+	}
+	for _, s := range root.Services {
+		services[s.Var] = s
+	}
+
+	for i := range root.After {
+		h := &root.After[i]
+		ctx := fmt.Sprintf("graph root %s after[%d]", root.Name, i)
+		v, method, ok := strings.Cut(strings.TrimSpace(h.Call), ".")
+		if !ok || !token.IsIdentifier(v) || !token.IsExported(method) || !token.IsIdentifier(method) {
+			die(fmt.Sprintf("%s call %q must be <var>.<ExportedMethod>", ctx, h.Call))
+		}
+		s, ok := services[v]
+		if !ok {
+			die(fmt.Sprintf("%s call %q: unknown service %s", ctx, h.Call, v))
+		}
+		if h.TimeoutMs < 0 {
+			die(ctx + " timeoutMs must be >= 0")
+		}
+		h.Var, h.Method, h.When = v, method, s.When
+	}
+}
+
+// HasHookTimeout reports whether any post-build hook of the root has a timeout.
+func (r GraphRoot) HasHookTimeout() bool {
+	for _, h := range r.After {
+		if h.TimeoutMs > 0 {
+			return true
+		}
+	}
+	return false
+}
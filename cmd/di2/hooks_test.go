@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Post-build hooks
+// -------------------------
+
+func TestGenGraph_AfterHooks(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "logging": { "enabled": true },
+  "roots": [{
+    "name": "App",
+    "services": [
+      { "var": "core", "facadeCtor": "NewCoreV4", "facadeType": "*CoreV4", "implType": "Core" },
+      { "var": "audit", "facadeCtor": "NewAuditV4", "facadeType": "*AuditV4", "implType": "Audit", "when": "auditOn" }
+    ],
+    "after": [
+      { "call": "core.WarmCache", "timeoutMs": 5000 },
+      { "call": "audit.Migrate" }
+    ]
+  }]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertHasImport(t, out, "context")
+	assertHasImport(t, out, "time")
+	assertContainsInOrder(t, out,
+		"res.Core = coreSvc",
+		"hookCtx, cancel := context.WithTimeout(ctx, 5000*time.Millisecond)",
+		"err := res.Core.WarmCache(hookCtx)",
+		"cancel()",
+		`logger.Error("di: post-build hook failed", "root", "App", "hook", "core.WarmCache", "error", err)`,
+		`return res, fmt.Errorf("App: after core.WarmCache failed: %w", err)`,
+		"if res.Audit != nil {",
+		"err := res.Audit.Migrate(ctx)",
+		`return res, fmt.Errorf("App: after audit.Migrate failed: %w", err)`,
+		`logger.Debug("di: graph build succeeded", "root", "App")`,
+	)
+}
+
+func TestGenGraph_AfterHooksWithoutTimeout(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "shared": { "services": [{ "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB" }] },
+  "roots": [{
+    "name": "App",
+    "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }],
+    "after": [{ "call": "db.Migrate" }]
+  }]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertNotHasImport(t, out, "time")
+	if !strings.Contains(out, "err := res.Db.Migrate(ctx)") {
+		t.Fatalf("expected hook on shared service:\n%s", out)
+	}
+}
+
+func TestPrepareGraphHooks_Invalid(t *testing.T) {
+	t.Parallel()
+
+	root := func(h GraphHook) *GraphRoot {
+		return &GraphRoot{
+			Name:     "App",
+			Services: []GraphService{{Var: "core"}},
+			After:    []GraphHook{h},
+		}
+	}
+
+	tests := []struct {
+		name string
+		hook GraphHook
+		want string
+	}{
+		{name: "no_method", hook: GraphHook{Call: "core"}, want: `graph root App after[0] call "core" must be <var>.<ExportedMethod>`},
+		{name: "unexported", hook: GraphHook{Call: "core.warm"}, want: "must be <var>.<ExportedMethod>"},
+		{name: "unknown_var", hook: GraphHook{Call: "db.Warm"}, want: `graph root App after[0] call "db.Warm": unknown service db`},
+		{name: "negative_timeout", hook: GraphHook{Call: "core.Warm", TimeoutMs: -1}, want: "graph root App after[0] timeoutMs must be >= 0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertPanicContains(t, func() { prepareGraphHooks(root(tt.hook)) }, tt.want)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// -------------------------
+// JSON error locations
+// -------------------------
+
+// jsonSnippetLines is how many lines before the offending one jsonErrorAt shows.
+const jsonSnippetLines = 2
+
+// jsonErrorAt annotates a JSON decoding error of raw with the line and column
+// of the offending byte and a snippet of the surrounding input:
+//
+//	line 12, column 19: invalid character '}' looking for beginning of object key string
+//	   11 |       "type": "*sql.DB",
+//	   12 |       "field": "db",}
+//	      |                     ^
+//
+// Errors without an offset (or nil) are returned unchanged.
+func jsonErrorAt(raw []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+
+	// Offset counts the bytes read when the error occurred, so the offending
+	// byte is the one before it.
+	pos := int(min(max(offset-1, 0), int64(len(raw))))
+	if offset >= int64(len(raw)) {
+		// truncated document: point at the last non-space byte
+		for pos > 0 && (pos >= len(raw) || isJSONSpace(raw[pos])) {
+			pos--
+		}
+	}
+
+	lineStart := bytes.LastIndexByte(raw[:pos], '\n') + 1
+	line := bytes.Count(raw[:lineStart], []byte("\n")) + 1
+	col := utf8.RuneCount(raw[lineStart:pos]) + 1
+	return fmt.Errorf("line %d, column %d: %w\n%s", line, col, err, jsonSnippet(raw, lineStart, pos, line))
+}
+
+// jsonSnippet renders the line holding pos (starting at lineStart, numbered
+// line) with up to jsonSnippetLines lines before it and a caret under pos.
+func jsonSnippet(raw []byte, lineStart, pos, line int) string {
+	lines := strings.Split(string(raw[:lineStart]), "\n")
+	lines = lines[:len(lines)-1] // the part after the last newline is empty
+	first := max(len(lines)-jsonSnippetLines, 0)
+
+	var b strings.Builder
+	for i := first; i < len(lines); i++ {
+		_, _ = fmt.Fprintf(&b, "%5d | %s\n", line-len(lines)+i, strings.TrimRight(lines[i], "\r"))
+	}
+	lineEnd := bytes.IndexByte(raw[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(raw) - lineStart
+	}
+	_, _ = fmt.Fprintf(&b, "%5d | %s\n", line, strings.TrimRight(string(raw[lineStart:lineStart+lineEnd]), "\r"))
+
+	// keep tabs so the caret lines up with tab-indented specs
+	b.WriteString("      | ")
+	for _, r := range string(raw[lineStart:pos]) {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
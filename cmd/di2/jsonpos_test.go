@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// JSON error locations
+// -------------------------
+
+func TestJSONErrorAt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "syntax",
+			raw:  "{\n  \"package\": \"p\",\n  \"required\": [\n    {\"name\": \"DB\",}\n  ]\n}\n",
+			want: "line 4, column 19: invalid character '}' looking for beginning of object key string\n" +
+				"    2 |   \"package\": \"p\",\n" +
+				"    3 |   \"required\": [\n" +
+				"    4 |     {\"name\": \"DB\",}\n" +
+				"      |                   ^",
+		},
+		{
+			name: "truncated",
+			raw:  "{\"package\": \n\n",
+			want: "line 1, column 11: unexpected end of JSON input\n" +
+				"    1 | {\"package\": \n" +
+				"      |           ^",
+		},
+		{
+			name: "type",
+			raw:  "{\n\t\"package\": 3\n}",
+			want: "line 2, column 13: json: cannot unmarshal number into Go struct field ServiceSpec.package of type string\n" +
+				"    1 | {\n" +
+				"    2 | \t\"package\": 3\n" +
+				"      | \t           ^",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var spec ServiceSpec
+			err := decodeSpec([]byte(tt.raw), &spec)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("got:\n%v\nwant:\n%s", err, tt.want)
+			}
+		})
+	}
+
+	other := errors.New("boom")
+	if got := jsonErrorAt(nil, other); got != other {
+		t.Fatalf("errors without an offset must be returned unchanged, got %v", got)
+	}
+}
+
+func TestRun_SpecParseErrorHasLocation(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	spec := p.write("specs/bad.inject.json", "{\n  \"package\": \"p\",,\n}\n")
+	graph := p.write("specs/graph.json", "{\"package\": \"p\", \"roots\": [}\n")
+
+	assertPanicContains(t, func() {
+		_ = run([]string{"-spec", spec, "-out", p.out("bad.gen.go")})
+	}, spec+": line 2, column 18: invalid character ','")
+	assertPanicContains(t, func() {
+		_ = run([]string{"-graph", graph, "-out", p.out("graph.gen.go")})
+	}, graph+": line 1, column 28: invalid character '}'")
+
+	if _, err := formatSpec([]byte("{\"package\": \"p\"\n\"x\": 1}")); err == nil || !strings.HasPrefix(err.Error(), "line 2, column 1: ") {
+		t.Fatalf("fmt errors should carry a location, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// -------------------------
+// Registry key convention (keyPattern, -key-pattern, -fix-keys)
+// -------------------------
+
+// conventionalKeyPattern is the default registry key convention: "v4.tracer" for
+// dep Tracer of package v4. Keys of specs without a pattern are checked against
+// it as warnings, and -fix-keys applies it.
+const conventionalKeyPattern = "<package>.<depLower>"
+
+// defaultKeyPattern is the key pattern of specs without "keyPattern"; run sets
+// it from -key-pattern. Empty: registry keys are not enforced, and -warn-keys
+// checks them against conventionalKeyPattern (see warnRegistryKeys).
+var defaultKeyPattern string
+
+// fixKeysOut is where -fix-keys reports the keys it rewrites.
+var fixKeysOut io.Writer = os.Stderr
+
+var keyPlaceholder = regexp.MustCompile(`<[^<>]*>`)
+
+// validateKeyPattern checks that pattern only uses the known placeholders
+// (<package>, <service>, <dep>, <depLower>) and names the dep, so every
+// optional dep of a spec gets its own key.
+func validateKeyPattern(pattern, ctx string) error {
+	if pattern == "" {
+		return nil
+	}
+	for _, ph := range keyPlaceholder.FindAllString(pattern, -1) {
+		switch ph {
+		case "<package>", "<service>", "<dep>", "<depLower>":
+		default:
+			return fmt.Errorf("%s %q: unknown placeholder %s (want <package>, <service>, <dep> or <depLower>)", ctx, pattern, ph)
+		}
+	}
+	if !strings.Contains(pattern, "<dep>") && !strings.Contains(pattern, "<depLower>") {
+		return fmt.Errorf("%s %q: must contain <dep> or <depLower>", ctx, pattern)
+	}
+	return nil
+}
+
+// specKeyPattern is the key pattern s is checked against: its own, else -key-pattern.
+func specKeyPattern(s ServiceSpec) string {
+	if s.KeyPattern != "" {
+		return s.KeyPattern
+	}
+	return defaultKeyPattern
+}
+
+// conventionalKey renders pattern for the optional dep named dep of s.
+func conventionalKey(pattern string, s ServiceSpec, dep string) string {
+	return strings.NewReplacer(
+		"<package>", s.Package,
+		"<service>", strings.ToLower(s.WrapperBase),
+		"<depLower>", strings.ToLower(dep),
+		"<dep>", dep,
+	).Replace(pattern)
+}
+
+// validateRegistryKeys fails on optional deps whose registryKey does not follow
+// the spec's key pattern, suggesting the conventional key. Deps using the slog
+// shortcut keep di.SlogKey, which is shared across services by design.
+func validateRegistryKeys(s *ServiceSpec) {
+	if err := validateKeyPattern(s.KeyPattern, "spec keyPattern"); err != nil {
+		die(err.Error())
+	}
+	pattern := specKeyPattern(*s)
+	if pattern == "" {
+		return
+	}
+	if bad := registryKeyMismatches(*s, pattern); len(bad) > 0 {
+		die(strings.Join(bad, "\n") + "\n(rewrite the spec with -fix-keys)")
+	}
+}
+
+// warnRegistryKeys checks the registry keys of a spec without a key pattern
+// (its own or -key-pattern) against conventionalKeyPattern, printing each
+// mismatch to specWarnOut. It runs with -warn-keys only: the default convention
+// is advisory, so specs written before it keep generating quietly.
+func warnRegistryKeys(path string, s ServiceSpec) {
+	if specKeyPattern(s) != "" {
+		return
+	}
+	bad := registryKeyMismatches(s, conventionalKeyPattern)
+	if len(bad) == 0 {
+		return
+	}
+	specWarnMu.Lock()
+	defer specWarnMu.Unlock()
+	for _, b := range bad {
+		_, _ = fmt.Fprintf(specWarnOut, "di2: warning: %s: %s (-warn-keys; set keyPattern or -key-pattern to enforce one, or rewrite with -fix-keys)\n", path, b)
+	}
+}
+
+// registryKeyMismatches describes the optional deps of s (other than slog
+// ones) whose registryKey does not render from pattern.
+func registryKeyMismatches(s ServiceSpec, pattern string) []string {
+	var bad []string
+	for _, o := range s.Optional {
+		if o.Slog {
+			continue
+		}
+		if want := conventionalKey(pattern, s, o.Name); o.RegistryKey != want {
+			bad = append(bad, fmt.Sprintf("optional %s registryKey %q does not match key pattern %s; want %q", o.Name, o.RegistryKey, pattern, want))
+		}
+	}
+	return bad
+}
+
+// fixSpecKeys rewrites the registryKey of every optional dep in the spec file
+// at path that does not follow its key pattern (else -key-pattern, else
+// conventionalKeyPattern), reporting each change to fixKeysOut. A spec needing
+// no change is left alone; a changed one is written in canonical form (see
+// formatSpec). Keys built from spec variables are not rewritten.
+func fixSpecKeys(path string) error {
+	if path == "-" {
+		return fmt.Errorf("-fix-keys cannot rewrite a spec read from stdin")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s ServiceSpec
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("%s: %w", path, jsonErrorAt(raw, err))
+	}
+	pattern := specKeyPattern(s)
+	if pattern == "" {
+		pattern = conventionalKeyPattern
+	}
+	if err := validateKeyPattern(pattern, "key pattern"); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("%s: %w", path, jsonErrorAt(raw, err))
+	}
+	opts, _ := doc["optional"].([]any)
+	changed := false
+	for _, e := range opts {
+		o, _ := e.(map[string]any)
+		name, _ := o["name"].(string)
+		key, _ := o["registryKey"].(string)
+		if o == nil || name == "" || o["slog"] == true || strings.Contains(key, "${") {
+			continue
+		}
+		if want := conventionalKey(pattern, s, name); key != want {
+			o["registryKey"] = want
+			changed = true
+			_, _ = fmt.Fprintf(fixKeysOut, "di2: %s: optional %s registryKey %q -> %q\n", path, name, key, want)
+		}
+	}
+	if !changed {
+		return nil
+	}
+	compact, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	out, err := formatSpec(compact)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
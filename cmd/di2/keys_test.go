@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Registry key convention (keyPattern, -key-pattern, -fix-keys)
+// -------------------------
+
+func keyedSpec(keyPattern, tracerKey string) string {
+	return `{
+  "package": "p", "wrapperBase": "Store", "versionSuffix": "V4",
+  "implType": "Store", "constructor": "NewStore", "keyPattern": "` + keyPattern + `",
+  "required": [{ "name": "Dep", "field": "dep", "type": "*Dep", "nilable": true }],
+  "optional": [
+    { "name": "Tracer", "type": "*Tracer", "registryKey": "` + tracerKey + `",
+      "apply": { "kind": "setter", "name": "SetTracer" } },
+    { "name": "Logger", "slog": true }
+  ]
+}`
+}
+
+func TestValidateKeyPattern(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: ""},
+		{pattern: "<package>.<depLower>"},
+		{pattern: "svc/<service>/<dep>"},
+		{pattern: "<pkg>.<dep>", want: "unknown placeholder <pkg>"},
+		{pattern: "<package>.tracer", want: "must contain <dep> or <depLower>"},
+	}
+	for _, tt := range tests {
+		err := validateKeyPattern(tt.pattern, "-key-pattern")
+		if tt.want == "" && err != nil {
+			t.Fatalf("%q: unexpected err: %v", tt.pattern, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Fatalf("%q: expected %q, got %v", tt.pattern, tt.want, err)
+		}
+	}
+}
+
+func TestGenService_KeyPattern(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	bad := p.write("specs/bad.inject.json", keyedSpec("<package>.<depLower>", "tracer"))
+	assertPanicContains(t, func() {
+		genService(bad, p.out("bad.gen.go"), genOptions{})
+	}, `optional Tracer registryKey "tracer" does not match key pattern <package>.<depLower>; want "p.tracer"`)
+
+	svc := p.write("specs/svc.inject.json", keyedSpec("svc/<service>/<dep>", "svc/store/Tracer"))
+	genService(svc, p.out("svc.gen.go"), genOptions{}) // slog dep is exempt
+
+	unknown := p.write("specs/unknown.inject.json", keyedSpec("<pkg>.<dep>", "p.Tracer"))
+	assertPanicContains(t, func() {
+		genService(unknown, p.out("unknown.gen.go"), genOptions{})
+	}, `spec keyPattern "<pkg>.<dep>": unknown placeholder <pkg>`)
+}
+
+func TestGenService_FixKeys(t *testing.T) {
+	// NOT parallel: swaps fixKeysOut
+	var buf bytes.Buffer
+	old := fixKeysOut
+	fixKeysOut = &buf
+	t.Cleanup(func() { fixKeysOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	spec := p.write("specs/store.inject.json", keyedSpec("<package>.<depLower>", "tracer"))
+
+	genService(spec, p.out("store_v4.gen.go"), genOptions{FixKeys: true})
+	if got := p.read("specs/store.inject.json"); !strings.Contains(got, `"registryKey": "p.tracer"`) || strings.Contains(got, `"registryKey": "tracer"`) {
+		t.Fatalf("spec not rewritten:\n%s", got)
+	}
+	if !strings.Contains(p.read("store_v4.gen.go"), `"p.tracer"`) {
+		t.Fatal("generated facade should use the fixed key")
+	}
+	if !strings.Contains(buf.String(), `optional Tracer registryKey "tracer" -> "p.tracer"`) {
+		t.Fatalf("missing fix report, got %q", buf.String())
+	}
+
+	// a conforming spec is left byte-for-byte alone
+	before := p.read("specs/store.inject.json")
+	buf.Reset()
+	genService(spec, p.out("store_v4.gen.go"), genOptions{FixKeys: true})
+	if p.read("specs/store.inject.json") != before || buf.Len() != 0 {
+		t.Fatal("conforming spec should not be rewritten")
+	}
+
+	if err := fixSpecKeys("-"); err == nil || !strings.Contains(err.Error(), "stdin") {
+		t.Fatalf("expected a stdin error, got %v", err)
+	}
+}
+
+func TestRun_KeyPatternFlags(t *testing.T) {
+	// NOT parallel: swaps defaultKeyPattern, fixKeysOut and specWarnOut
+	var warn bytes.Buffer
+	oldPattern, oldOut, oldWarn := defaultKeyPattern, fixKeysOut, specWarnOut
+	fixKeysOut, specWarnOut = &bytes.Buffer{}, &warn
+	t.Cleanup(func() { defaultKeyPattern, fixKeysOut, specWarnOut = oldPattern, oldOut, oldWarn })
+
+	p := newPkg(t)
+	writeDISource(p)
+	spec := p.write("specs/store.inject.json", keyedSpec("", "tracer"))
+
+	// without a pattern the default convention is not checked, and only warned
+	// about with -warn-keys
+	if err := run([]string{"-spec", spec, "-out", p.out("store_v4.gen.go")}); err != nil || warn.Len() != 0 {
+		t.Fatalf("the default convention is opt-in: %v, %q", err, warn.String())
+	}
+	if err := run([]string{"-spec", spec, "-out", p.out("store_v4.gen.go"), "-warn-keys"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "di2: warning: " + spec + `: optional Tracer registryKey "tracer" does not match key pattern <package>.<depLower>; want "p.tracer" (-warn-keys; set keyPattern or -key-pattern to enforce one, or rewrite with -fix-keys)` + "\n"
+	if warn.String() != want {
+		t.Fatalf("warning:\n got %q\nwant %q", warn.String(), want)
+	}
+	assertPanicContains(t, func() {
+		_ = run([]string{"-spec", spec, "-out", p.out("store_v4.gen.go"), "-key-pattern", "<package>.<depLower>"})
+	}, `want "p.tracer"`)
+	if err := run([]string{"-spec", spec, "-out", p.out("store_v4.gen.go"), "-key-pattern", "<package>"}); err == nil || !strings.Contains(err.Error(), `-key-pattern "<package>": must contain <dep> or <depLower>`) {
+		t.Fatalf("expected an invalid pattern error, got %v", err)
+	}
+
+	defaultKeyPattern = ""
+	if err := run([]string{"-specs", p.out("specs"), "-out", p.dir, "-fix-keys"}); err != nil {
+		t.Fatalf("run -fix-keys: %v", err)
+	}
+	if !strings.Contains(p.read("specs/store.inject.json"), `"registryKey": "p.tracer"`) {
+		t.Fatal("-fix-keys should apply the conventional pattern")
+	}
+	warn.Reset()
+	if err := run([]string{"-spec", spec, "-out", p.out("store_v4.gen.go"), "-warn-keys"}); err != nil || warn.Len() != 0 {
+		t.Fatalf("conventional keys should not warn: %v, %q", err, warn.String())
+	}
+
+	graph := p.write("specs/graph.json", `{"package": "p", "roots": []}`)
+	if err := run([]string{"-graph", graph, "-out", p.out("graph.gen.go"), "-fix-keys"}); err == nil || !strings.Contains(err.Error(), "-fix-keys needs -spec or -specs") {
+		t.Fatalf("expected a -graph error, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Logging
+// -------------------------
+
+func TestApplyLoggingDefaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		in     LoggingSpec
+		method string
+	}{
+		{name: "disabled_ignored", in: LoggingSpec{Level: "bogus"}, method: ""},
+		{name: "default_debug", in: LoggingSpec{Enabled: true}, method: "Debug"},
+		{name: "info", in: LoggingSpec{Enabled: true, Level: "info"}, method: "Info"},
+		{name: "warn", in: LoggingSpec{Enabled: true, Level: "warn"}, method: "Warn"},
+		{name: "error", in: LoggingSpec{Enabled: true, Level: "error"}, method: "Error"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			l := tt.in
+			applyLoggingDefaults(&l, "spec")
+			if l.Method != tt.method {
+				t.Fatalf("method: got %q want %q", l.Method, tt.method)
+			}
+		})
+	}
+
+	assertPanicContains(t, func() {
+		applyLoggingDefaults(&LoggingSpec{Enabled: true, Level: "trace"}, "spec")
+	}, "spec logging.level must be one of: debug|info|warn|error")
+}
+
+func writeLoggingSpec(t *testing.T, p *pkgHarness, logging LoggingSpec) string {
+	t.Helper()
+	spec := ServiceSpec{
+		Package:       "p",
+		WrapperBase:   "Core",
+		VersionSuffix: "V4",
+		ImplType:      "Core",
+		Constructor:   "NewCore",
+		Required:      []RequiredDep{{Name: "Alpha", Field: "alpha", Type: "*Alpha", Nilable: true}},
+		Optional: []OptionalDep{{
+			Name:        "Tracer",
+			Type:        "Tracer",
+			RegistryKey: "p.tracer",
+			Apply:       OptionalApply{Kind: "field", Name: "tracer"},
+		}},
+		Logging: logging,
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return p.write("core.inject.json", string(raw))
+}
+
+func TestGenService_Logging(t *testing.T) {
+	t.Parallel()
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		genService(writeLoggingSpec(t, p, LoggingSpec{}), p.out("core.gen.go"), genOptions{})
+		out := p.read("core.gen.go")
+
+		assertNotHasImport(t, out, "log/slog")
+		if strings.Contains(out, "b.log(") || strings.Contains(out, "CoreV4Logger") {
+			t.Fatalf("did not expect logging code when disabled:\n%s", out)
+		}
+		if !strings.Contains(out, "return &CoreV4{") {
+			t.Fatalf("expected constructor to return the literal directly:\n%s", out)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		genService(writeLoggingSpec(t, p, LoggingSpec{Enabled: true, Level: "info"}), p.out("core.gen.go"), genOptions{})
+		out := p.read("core.gen.go")
+
+		assertHasImport(t, out, "log/slog")
+		assertContainsInOrder(t, out,
+			"logger *slog.Logger",
+			"var CoreV4Logger *slog.Logger",
+			"func (b *CoreV4) WithLogger(l *slog.Logger) *CoreV4 {",
+			`b.loggerOrDefault().Info(msg, append([]any{"facade", "CoreV4"}, args...)...)`,
+			`b.loggerOrDefault().Error("di: build failed", "facade", "CoreV4", "ctx", ctx, "error", err)`,
+			`b.log("di: facade constructed")`,
+			`logger:           b.logger,`,
+			`b.log("di: dependency injected", "dep", "Alpha")`,
+			`b.logBuild("Build", err)`,
+			"func (b *CoreV4) BuildWithCtx(ctx context.Context, reg di.Registry) (svc *Core, err error) {",
+			`defer func() { b.logBuild("BuildWith", err) }()`,
+			`b.log("di: optional resolved", "key", "p.tracer", "type", fmt.Sprintf("%T", v))`,
+			`b.log("di: optional missing", "key", "p.tracer", "reason", "not provided")`,
+		)
+	})
+}
+
+func TestGenGraph_Logging(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	g := GraphSpec{
+		Package: "p",
+		Logging: LoggingSpec{Enabled: true, Level: "warn"},
+		Roots: []GraphRoot{{
+			Name: "App",
+			Services: []GraphService{
+				{Var: "a", FacadeCtor: "NewAV4", FacadeType: "*AV4", ImplType: "A"},
+			},
+		}},
+	}
+	raw, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	genGraph(p.write("graph.json", string(raw)), p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertHasImport(t, out, "log/slog")
+	assertContainsInOrder(t, out,
+		"var AppLogger *slog.Logger",
+		"logger := AppLogger",
+		`logger.Warn("di: graph build started", "root", "App", "services", 1)`,
+		`logger.Error("di: graph build failed", "root", "App", "service", "a", "error", err)`,
+		`logger.Warn("di: service built", "root", "App", "service", "a")`,
+		`logger.Warn("di: graph build succeeded", "root", "App")`,
+	)
+
+	bad := GraphSpec{Package: "p", Logging: LoggingSpec{Enabled: true, Level: "loud"}, Roots: g.Roots}
+	raw, err = json.Marshal(bad)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	badPath := p.write("bad.json", string(raw))
+	assertPanicContains(t, func() { genGraph(badPath, p.out("bad.gen.go"), genOptions{}) }, "graph spec logging.level must be one of")
+}
+
+// -------------------------
+// slog optional shortcut
+// -------------------------
+
+func TestApplySlogDefaults(t *testing.T) {
+	t.Parallel()
+
+	opts := []OptionalDep{
+		{Slog: true},
+		{Slog: true, Name: "Log", RegistryKey: "app.log", Apply: OptionalApply{Kind: "setter", Name: "SetLog"}, DefaultExpr: "nil"},
+		{Name: "Tracer", Type: "Tracer", RegistryKey: "t", Apply: OptionalApply{Kind: "field", Name: "tracer"}},
+	}
+	applySlogDefaults(opts)
+
+	want := []OptionalDep{
+		{Slog: true, Name: "Logger", Type: "*slog.Logger", RegistryKey: "odi.slog", Apply: OptionalApply{Kind: "field", Name: "logger"}, DefaultExpr: "slog.Default()"},
+		{Slog: true, Name: "Log", Type: "*slog.Logger", RegistryKey: "app.log", Apply: OptionalApply{Kind: "setter", Name: "SetLog"}, DefaultExpr: "nil"},
+		{Name: "Tracer", Type: "Tracer", RegistryKey: "t", Apply: OptionalApply{Kind: "field", Name: "tracer"}},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Fatalf("got %+v\nwant %+v", opts, want)
+	}
+	if !hasSlogOptional(opts) || hasSlogOptional(opts[2:]) {
+		t.Fatalf("hasSlogOptional mismatch")
+	}
+
+	assertPanicContains(t, func() {
+		applySlogDefaults([]OptionalDep{{Slog: true, Type: "Logger"}})
+	}, "optional slog dep must have type *slog.Logger")
+}
+
+func TestGenService_SlogOptional(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }],
+  "optional": [{ "slog": true }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "log/slog")
+	assertContainsInOrder(t, out,
+		`CoreV4OptionalLoggerKey = "odi.slog"`,
+		`if v, ok, err := di.ResolveOptionalCtx[*slog.Logger](ctx, reg, nil, "CoreV4", "Logger", "odi.slog"); err != nil {`,
+		"b.svc.logger = v",
+		"b.svc.logger = slog.Default()",
+	)
+}
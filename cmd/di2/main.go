@@ -3,9 +3,11 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"go/ast"
+	"go/build/constraint"
 	"go/format"
 	"go/parser"
 	"go/token"
@@ -13,14 +15,24 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
 )
 
 type Imports struct {
 	DI     string `json:"di"`
 	Config string `json:"config"`
+
+	// Service overrides the inferred import path of the service package when the
+	// facade is generated into another package (see OutputSpec).
+	Service string `json:"service"`
+
+	// Packages maps package names used in dep and method types (e.g. "sql") to
+	// their import paths; the generated file imports the ones it references.
+	Packages map[string]string `json:"packages"`
 }
 
 // ConfigSpec makes config truly optional.
@@ -45,20 +57,107 @@ type ConfigSpec struct {
 	ParamName string `json:"paramName"`
 }
 
+// LoggingSpec enables structured lifecycle logging (log/slog) in generated code.
+// Level is one of debug|info|warn|error (default debug); build failures always
+// log at error level.
+type LoggingSpec struct {
+	Enabled bool   `json:"enabled"`
+	Level   string `json:"level"`
+
+	// Method is the slog.Logger method for Level (computed; not part of the spec).
+	Method string `json:"-"`
+}
+
+// CodegenSpec customizes the top of generated files.
+type CodegenSpec struct {
+	// BuildTags are build constraint expressions, ANDed into one //go:build line.
+	BuildTags []string `json:"buildTags"`
+
+	// Header is emitted as line comments above everything else (e.g. a license).
+	// Lines that are not already comments get a "// " prefix.
+	Header string `json:"header"`
+}
+
 type InjectPolicy struct {
 	OnOverwrite string `json:"onOverwrite"` // "error" | "overwrite" | "ignore"
 }
 
+// UnsafeImplSpec configures the facade's UnsafeImpl escape hatch.
+type UnsafeImplSpec struct {
+	// Disable omits UnsafeImpl, UnsafeImplFor and ResetUnsafe, so the raw
+	// implementation cannot escape the builder. Not allowed for cyclic specs.
+	Disable bool `json:"disable"`
+
+	// Track records the call site and time of every UnsafeImpl call; Explain
+	// lists them.
+	Track bool `json:"track"`
+}
+
+// RecursionGuardSpec bounds the calls into a cyclic service through its
+// facade: the listed methods run through di.DepthGuard(MaxDepth), outermost.
+// Calls the implementations make to each other are not counted.
+type RecursionGuardSpec struct {
+	// MaxDepth is the deepest nesting of guarded calls a call may run at.
+	MaxDepth int `json:"maxDepth"`
+
+	// Methods are the mutually-calling methods to guard; each takes a
+	// context.Context first (the depth travels in it) and returns error last.
+	Methods []string `json:"methods"`
+}
+
 type RequiredDep struct {
 	Name    string `json:"name"`
 	Field   string `json:"field"`
 	Type    string `json:"type"`
 	Nilable bool   `json:"nilable"`
+
+	// Description is appended to the Inject<Name>/TryInject<Name> doc comments.
+	Description string `json:"description"`
+
+	// Min is the number of items a slice or map dep needs before Build (default 1).
+	Min int `json:"min"`
+
+	// Plural names the bulk injector of a slice or map dep (default Name + "s").
+	Plural string `json:"plural"`
+
+	// Group makes the dep part of an all-or-nothing group: it is not required on
+	// its own, but Build fails if some, not all, deps of the group are wired.
+	Group string `json:"group"`
+
+	// OneOf makes the dep part of an exclusive set: Build requires exactly one
+	// dep of the set to be wired (e.g. SQLStore xor MemoryStore).
+	OneOf string `json:"oneOf"`
+
+	// Order positions the dep in generated output (ascending; ties by name).
+	Order int `json:"order"`
+
+	// Tags classify the dep for audits (e.g. "pii", "external", "stateful");
+	// see WiringManifest.
+	Tags []string `json:"tags"`
+
+	// Target is the facade expression for the impl field (computed; see setImplTargets).
+	Target string `json:"-"`
+
+	// Kind is "slice" or "map" for collection deps, "" otherwise; ElemType and
+	// KeyType are the item and key types (computed; see setCollectionDeps).
+	Kind     string `json:"-"`
+	ElemType string `json:"-"`
+	KeyType  string `json:"-"`
 }
 
 type OptionalApply struct {
-	Kind string `json:"kind"` // "setter" | "field"
+	Kind string `json:"kind"` // "setter" | "field" | "call"
 	Name string `json:"name"`
+
+	// Args are the arguments of a "call": Go expressions over casted (the
+	// resolved dep), cfg (the facade's config) and package-qualified names,
+	// e.g. ["casted", "cfg.TraceSampling"] for SetTracerWithSampling.
+	Args []string `json:"args"`
+
+	// Call and DefaultCall are the rendered args for the resolved dep and for
+	// defaultExpr (computed; see setApplyCalls).
+	Call        string `json:"-"`
+	DefaultCall string `json:"-"`
 }
 
 type OptionalDep struct {
@@ -67,11 +166,39 @@ type OptionalDep struct {
 	RegistryKey string        `json:"registryKey"`
 	Apply       OptionalApply `json:"apply"`
 
+	// Description is rendered on the dep's registry key constant.
+	Description string `json:"description"`
+
 	// Optional: if set, generator emits this expression when registry lookup misses (ok=false).
 	// Example: "NoopTracer{}" or "&NoopMetrics{}"
 	DefaultExpr string `json:"defaultExpr"`
+
+	// Slog is a shortcut for a *slog.Logger dep under di.SlogKey ("odi.slog")
+	// defaulting to slog.Default(); unset fields are filled by applySlogDefaults.
+	Slog bool `json:"slog"`
+
+	// EnabledWhen gates the dep behind a feature flag: BuildWith only resolves
+	// it when the di.FlagChecker under di.FlagsKey reports FlagKey on.
+	EnabledWhen *EnabledWhen `json:"enabledWhen"`
+
+	// Order positions the dep in generated output (ascending; ties by name).
+	Order int `json:"order"`
+
+	// Tags classify the dep for audits, like RequiredDep.Tags.
+	Tags []string `json:"tags"`
+
+	// Target is the facade expression for a field apply (computed; see setImplTargets).
+	Target string `json:"-"`
 }
 
+// EnabledWhen names the feature flag gating an optional dep.
+type EnabledWhen struct {
+	FlagKey string `json:"flagKey"`
+}
+
+// slogRegistryKey mirrors di.SlogKey.
+const slogRegistryKey = "odi.slog"
+
 type MethodParam struct {
 	Name string `json:"name"`
 	Type string `json:"type"`
@@ -81,19 +208,75 @@ type MethodReturn struct {
 	Type string `json:"type"`
 }
 
+// RetryPolicy makes up to Attempts calls, BackoffMs milliseconds apart.
+type RetryPolicy struct {
+	Attempts  int `json:"attempts"`
+	BackoffMs int `json:"backoffMs"`
+}
+
 type MethodSpec struct {
-	Name     string         `json:"name"`
+	Name string `json:"name"`
+
+	// Description is rendered as the wrapper's doc comment.
+	Description string `json:"description"`
+
 	Params   []MethodParam  `json:"params"`
 	Returns  []MethodReturn `json:"returns"`
 	Requires []string       `json:"requires"`
+
+	// Chain makes the wrapper of a method without returns return the facade, so
+	// configuration-style calls can be chained.
+	Chain bool `json:"chain"`
+
+	// Order positions the method in generated output (ascending; ties by name).
+	Order int `json:"order"`
+
+	// Decorators are di.Decorator expressions wrapped around the call, inside the
+	// service-level ones (first is outermost).
+	Decorators []string `json:"decorators"`
+
+	// TimeoutMs bounds each call with a context deadline (di.Timeout); the first
+	// parameter must be a context.Context.
+	TimeoutMs int `json:"timeoutMs"`
+
+	// Retry repeats a call whose error is non-nil (di.Retry).
+	Retry *RetryPolicy `json:"retry"`
+
+	// RecoverPanics converts a panic of the call into a *di.PanicError; the last
+	// return must be error.
+	RecoverPanics bool `json:"recoverPanics"`
+
+	// Decorate is the full decorator chain, Recover whether panics are recovered
+	// and CtxParam the context.Context parameter passed through the chain
+	// (computed; see setMethodDecorators).
+	Decorate []string `json:"-"`
+	Recover  bool     `json:"-"`
+	CtxParam string   `json:"-"`
 }
 
 type ServiceSpec struct {
+	// SpecVersion is the spec format version (1 when absent; see currentSpecVersion).
+	SpecVersion int `json:"specVersion,omitempty"`
+
 	Package       string `json:"package"`
 	WrapperBase   string `json:"wrapperBase"`
 	VersionSuffix string `json:"versionSuffix"`
 	ImplType      string `json:"implType"`
 
+	// Naming is how versionSuffix enters the facade and constructor names:
+	// "suffix" (AlphaV4), "prefix" (V4Alpha) or "none" (Alpha, versionSuffix
+	// optional). Empty uses -naming, which defaults to "suffix".
+	Naming string `json:"naming"`
+
+	// KeyPattern is the registry key convention of the optional deps, e.g.
+	// "<package>.<depLower>" (see validateRegistryKeys). Empty uses -key-pattern;
+	// without either, keys are not enforced (-warn-keys reports those that do
+	// not follow "<package>.<depLower>").
+	KeyPattern string `json:"keyPattern"`
+
+	// Description is rendered as the facade type's doc comment.
+	Description string `json:"description"`
+
 	// Constructor is a symbol name (in the same package) for the service constructor.
 	// It will be called as:
 	// - Constructor(cfg) if Config.Enabled=true
@@ -107,44 +290,344 @@ type ServiceSpec struct {
 	PublicConstructorName string       `json:"publicConstructorName"`
 	InjectPolicy          InjectPolicy `json:"injectPolicy"`
 
-	// if true, spec indicates cycle wiring; we still generate UnsafeImpl() always
+	// Cyclic declares that the service takes part in wiring cycles: a graph may
+	// wire any of its required deps as a cycle edge (see Cycles). UnsafeImpl is
+	// always generated for cyclic specs.
 	Cyclic bool `json:"cyclic"`
 
+	// Cycles narrows Cyclic to the required deps (by name) that close a cycle;
+	// graphs verify them against their wiring (see validateGraphCycles). Setting
+	// it implies cyclic.
+	Cycles []string `json:"cycles"`
+
+	UnsafeImpl UnsafeImplSpec `json:"unsafeImpl"`
+
+	// RecursionGuard optionally guards the methods of a cyclic service that
+	// call around the cycle (see setMethodDecorators).
+	RecursionGuard *RecursionGuardSpec `json:"recursionGuard"`
+
 	Required []RequiredDep `json:"required"`
 	Optional []OptionalDep `json:"optional"`
 	Methods  []MethodSpec  `json:"methods"`
+
+	// Decorators are di.Decorator expressions (evaluated in the facade's package)
+	// wrapped around every method whose last return is error; first is outermost.
+	Decorators []string `json:"decorators"`
+
+	// RecoverPanics makes every method whose last return is error convert a panic
+	// of the implementation (or a decorator) into a *di.PanicError.
+	RecoverPanics bool `json:"recoverPanics"`
+
+	// Recovers is set when some method recovers panics (computed; see
+	// setMethodDecorators).
+	Recovers bool `json:"-"`
+
+	// APIInterface generates <FacadeName>API, an interface of the safe method
+	// wrappers (and HealthCheck) that the facade implements.
+	APIInterface bool `json:"apiInterface"`
+
+	// APIMock also generates <FacadeName>APIMock, a func-field implementation of
+	// the API interface for tests; it requires APIInterface.
+	APIMock bool `json:"apiMock"`
+
+	// IdentityHelpers generates SameImpl and ImplPtr, which tests use to check
+	// that wiring shared one implementation instance.
+	IdentityHelpers bool `json:"identityHelpers"`
+
+	// Introspection generates WiringInfo, WiringManifest and <FacadeName>SpecInfo
+	// for debug endpoints and audits. WiringManifest is also generated when a dep
+	// has tags, since Explain lists them.
+	Introspection bool `json:"introspection"`
+
+	// HealthCheck optionally names an impl method with signature
+	// func(context.Context) error; the facade then gets HealthCheck(ctx) error.
+	HealthCheck string `json:"healthCheck"`
+
+	// Consumer marks the impl as a stream consumer (Run(context.Context) error);
+	// graph services linking the spec default to it (see GraphService.Consumer).
+	Consumer bool `json:"consumer"`
+
+	Logging LoggingSpec `json:"logging"`
+
+	Codegen CodegenSpec `json:"codegen"`
+
+	Output OutputSpec `json:"output"`
+
+	// PreserveOrder keeps spec file order for required/optional deps and methods
+	// (ties on Order) instead of sorting them by name.
+	PreserveOrder bool `json:"preserveOrder"`
+
+	// Groups are the all-or-nothing dep groups, OneOfs the exclusive dep sets and
+	// GroupedDeps the deps in either (computed; see setDepGroups).
+	Groups      []DepGroup `json:"-"`
+	OneOfs      []DepGroup `json:"-"`
+	GroupedDeps []string   `json:"-"`
 }
 
 type GraphSpec struct {
+	// SpecVersion is the spec format version (1 when absent; see currentSpecVersion).
+	SpecVersion int `json:"specVersion,omitempty"`
+
 	Package string `json:"package"`
 
 	Imports Imports    `json:"imports"`
 	Config  ConfigSpec `json:"config"`
 
-	Roots []struct {
-		Name              string `json:"name"`
-		BuildWithRegistry bool   `json:"buildWithRegistry"`
-		Services          []struct {
-			Var        string `json:"var"`
-			FacadeCtor string `json:"facadeCtor"` // symbol name, called with cfg if Config.Enabled=true
-			FacadeType string `json:"facadeType"`
-			ImplType   string `json:"implType"`
-		} `json:"services"`
-		Wiring []struct {
-			To      string `json:"to"`
-			Call    string `json:"call"`
-			ArgFrom string `json:"argFrom"`
-		} `json:"wiring"`
-	} `json:"roots"`
-}
-
-func run(args []string) error {
+	Logging LoggingSpec `json:"logging"`
+
+	Codegen CodegenSpec `json:"codegen"`
+
+	// Parallel builds independent services of each root concurrently
+	// (stage by stage, via di.BuildStages) while respecting dependency order.
+	Parallel bool `json:"parallel"`
+
+	// PreserveOrder keeps spec file order for roots and services (ties on Order)
+	// instead of sorting them by name.
+	PreserveOrder bool `json:"preserveOrder"`
+
+	// ExposeBuilders adds each root's facades to its result (<Root>Result.Builders),
+	// so tests can Clone one from the assembled graph, re-inject a dep and rebuild.
+	ExposeBuilders bool `json:"exposeBuilders"`
+
+	// Profiles names Go boolean expressions (e.g. "prod": "cfg.Env == \"prod\"")
+	// that services and wiring can reference by name in "when".
+	Profiles map[string]string `json:"profiles"`
+
+	// Manifest names a JSON file in the output directory that di2 writes a
+	// di.GraphManifest to. The generated graph embeds it and registers it at init,
+	// so the binary reports the wiring it was built with via di.Manifest().
+	Manifest string `json:"manifest"`
+
+	Roots []GraphRoot `json:"roots"`
+
+	// Shared declares services built once and shared by every root: it is generated
+	// like a root (<Name>Result, func <Name>(...), Name defaults to "Shared"), and
+	// every root then takes the built <Name>Result, exposes the shared services in
+	// its result and may wire them in via argFrom.
+	Shared *GraphRoot `json:"shared"`
+
+	// ServiceImports are the service packages of linked specs whose facades are
+	// generated outside them (computed; see OutputSpec).
+	ServiceImports []GoImport `json:"-"`
+}
+
+type GraphRoot struct {
+	Name              string         `json:"name"`
+	BuildWithRegistry bool           `json:"buildWithRegistry"`
+	Services          []GraphService `json:"services"`
+	Wiring            []GraphWiring  `json:"wiring"`
+
+	// HealthHandler emits <Name>Result.HealthHandler() (net/http) on top of HealthCheck.
+	HealthHandler bool `json:"healthHandler"`
+
+	// MountAll emits <Name>Result.MountAll(mux di.Mux) error, mounting the routes
+	// of every service implementing di.RouteProvider (Routes() []di.Route).
+	MountAll bool `json:"mountAll"`
+
+	// ConfigReload emits <Name>Result.ReloadConfig(cfg), passing a new config to
+	// every service implementing di.ConfigReloadable (OnConfigChange(cfg) error)
+	// in dependency order. It requires config.enabled.
+	ConfigReload bool `json:"configReload"`
+
+	// WiringHandler captures each facade's WiringInfo during the build and emits
+	// <Name>Result.Wiring() and <Name>Result.WiringHandler() (net/http, JSON).
+	WiringHandler bool `json:"wiringHandler"`
+
+	// After lists post-build hooks run once every service is built (see GraphHook).
+	After []GraphHook `json:"after"`
+
+	// BuildOrder is the services in dependency order (computed; not part of the spec).
+	BuildOrder []GraphService `json:"-"`
+
+	// BuildStages groups BuildOrder into stages of mutually independent services
+	// (computed; used when the graph is parallel).
+	BuildStages [][]GraphService `json:"-"`
+
+	// SharedName and SharedServices describe the graph's shared section for roots
+	// that receive it (computed; see GraphSpec.Shared).
+	SharedName     string         `json:"-"`
+	SharedServices []GraphService `json:"-"`
+
+	// Params and Args are the root function's parameter list and the matching
+	// call arguments (computed; config, registry, shared result).
+	Params string `json:"-"`
+	Args   string `json:"-"`
+}
+
+type GraphService struct {
+	Var        string `json:"var"`
+	FacadeCtor string `json:"facadeCtor"` // symbol name, called with cfg if Config.Enabled=true
+	FacadeType string `json:"facadeType"`
+	ImplType   string `json:"implType"`
+
+	// Spec optionally links the service spec (relative to the graph file) so the graph
+	// generator can pick up service-level declarations such as healthCheck.
+	Spec string `json:"spec"`
+
+	// HealthCheck names the impl method (func(context.Context) error) used by
+	// <Root>Result.HealthCheck. Defaults to the linked spec's healthCheck.
+	HealthCheck string `json:"healthCheck"`
+
+	// Consumer runs the service (Run(context.Context) error) in the root's
+	// stream.Group: <Root>Result.StartAll launches it and StopAll stops it.
+	// Defaults to the linked spec's consumer.
+	Consumer bool `json:"consumer"`
+
+	// Order positions the service in generated output (ascending; ties by var).
+	Order int `json:"order"`
+
+	// When makes the service conditional: a Go boolean expression or a profile
+	// name. Conditional services are left nil in the result when it is false.
+	When string `json:"when"`
+
+	// Pos is the service's index in BuildOrder (computed; set on BuildStages entries).
+	Pos int `json:"-"`
+
+	// NoUnsafeImpl is set when the linked spec disables UnsafeImpl, so the
+	// service cannot be wired into others (computed; see resolveGraphServiceSpecs).
+	NoUnsafeImpl bool `json:"-"`
+
+	// Cyclic and CycleDeps are the linked spec's cycle declaration (computed;
+	// see validateGraphCycles).
+	Cyclic    bool          `json:"-"`
+	CycleDeps []RequiredDep `json:"-"`
+}
+
+type GraphWiring struct {
+	To      string `json:"to"`
+	Call    string `json:"call"`
+	ArgFrom string `json:"argFrom"`
+
+	// When makes the wiring conditional (a Go boolean expression or a profile name).
+	When string `json:"when"`
+
+	// Cycle declares the wiring as the edge closing a cycle, for targets without
+	// a linked spec declaring it (see validateGraphCycles).
+	Cycle bool `json:"cycle"`
+
+	// Guard is the generated if-condition: When plus nil checks for conditional
+	// services on either end (computed; empty when the wiring is unconditional).
+	Guard string `json:"-"`
+
+	// Arg is the generated argument expression for ArgFrom (computed; a builder's
+	// UnsafeImpl() or a built shared service).
+	Arg string `json:"-"`
+}
+
+// GraphHook is a post-build call on a built service of the root (or a shared one):
+// Call is "<var>.<Method>" where Method is func(context.Context) error.
+type GraphHook struct {
+	Call string `json:"call"`
+
+	// TimeoutMs bounds the hook's context (0 = no timeout).
+	TimeoutMs int `json:"timeoutMs"`
+
+	// Var, Method and When are computed from Call (When is the service's condition).
+	Var    string `json:"-"`
+	Method string `json:"-"`
+	When   string `json:"-"`
+}
+
+// genOptions are generator switches set from CLI flags (not part of the specs).
+type genOptions struct {
+	// Profile emits per-service construct/inject/build timings into graph results.
+	Profile bool
+
+	// DryRun, when set, receives the gofmt'ed output instead of the -out file;
+	// nothing is written to disk.
+	DryRun io.Writer
+
+	// APIDiff is the baseline file whose exported API the output is compared
+	// with ("prev": the current -out file); see checkAPIDiff.
+	APIDiff string
+
+	// APIBreaking is "warn" (report only) or "error" (fail on breaking changes).
+	APIBreaking string
+
+	// Templates overrides the built-in templates (-template-dir); nil uses them.
+	Templates *genTemplates
+
+	// Plugins are command lines run after each generated file (see runPlugins).
+	Plugins []string
+
+	// Split writes each graph root to its own file in the -out directory (see
+	// splitGraphOutPath).
+	Split bool
+
+	// Strict fails on unknown spec fields instead of warning about them (see
+	// checkSpecFields).
+	Strict bool
+
+	// Provenance records the files read and written for -provenance; nil
+	// records nothing.
+	Provenance *provenanceRecorder
+
+	// FixKeys rewrites spec registry keys that do not follow the key pattern
+	// before generating (see fixSpecKeys).
+	FixKeys bool
+
+	// WarnKeys reports registry keys of specs without a key pattern that do not
+	// follow conventionalKeyPattern (see warnRegistryKeys).
+	WarnKeys bool
+
+	// NoInfer makes generation hermetic: imports come only from the spec (see
+	// requireDeclaredServiceImports), and the output package, go.mod files, di2's
+	// own sources and the existing -out file are never read.
+	NoInfer bool
+}
+
+// dryRunOut is where -dry-run (or -out -) prints generated files.
+var dryRunOut io.Writer = os.Stdout
+
+func run(args []string) (err error) {
+	switch {
+	case len(args) > 0 && args[0] == "new":
+		return runNew(args[1:])
+	case len(args) > 1 && args[0] == "graph" && args[1] == "simulate":
+		return runGraphSimulate(args[2:])
+	case len(args) > 0 && args[0] == "fmt":
+		return runFmt(args[1:])
+	case len(args) > 0 && args[0] == "migrate":
+		return runMigrate(args[1:])
+	case len(args) > 0 && args[0] == "template":
+		return runTemplate(args[1:])
+	case len(args) > 0 && args[0] == "doctor":
+		return runDoctor(args[1:])
+	case len(args) > 0 && args[0] == "clean":
+		return runClean(args[1:])
+	case len(args) > 0 && args[0] == "sync-directives":
+		return runSyncDirectives(args[1:])
+	}
+
 	fs := flag.NewFlagSet("di2", flag.ContinueOnError)
 	fs.SetOutput(io.Discard) // or os.Stderr if you want CLI output
 
-	specPath := fs.String("spec", "", "path to service.inject.json")
-	graphPath := fs.String("graph", "", "path to graph.json")
-	outPath := fs.String("out", "", "output .gen.go file path")
+	specPath := fs.String("spec", "", "path to service.inject.json (\"-\" reads stdin)")
+	specsDir := fs.String("specs", "", "batch: directory of *.inject.json specs (-out is then a directory)")
+	jobs := fs.Int("j", 0, "batch: number of specs generated concurrently (default GOMAXPROCS)")
+	graphPath := fs.String("graph", "", "path to graph.json (\"-\" reads stdin), or a glob of graph fragments merged into one graph")
+	outPath := fs.String("out", "", "output .gen.go file path (\"-\" implies -dry-run)")
+	dryRun := fs.Bool("dry-run", false, "print the gofmt'ed output to stdout instead of writing -out")
+	profile := fs.Bool("profile", false, "graph: record per-service build timings (<Root>Result.Profile())")
+	scanCachePath := fs.String("scan-cache", "", "file persisting package import scans between runs")
+	apiDiff := fs.String("api-diff", "", "report exported API changes against this .gen.go (\"prev\": the current -out file)")
+	apiBreaking := fs.String("api-breaking", "warn", "with -api-diff: \"warn\" or \"error\" (fail on removed/changed API)")
+	verify := fs.Bool("verify", false, "type-check the output package and attribute compile errors to spec fields")
+	var plugins listFlag
+	fs.Var(&plugins, "plugin", "command run after generation with the spec as JSON on stdin; may be repeated")
+	split := fs.Bool("split", false, "graph: write each root to graph_<root>.gen.go (-out is then a directory)")
+	prune := fs.Bool("prune", false, "batch: remove facades in -out whose spec no longer exists")
+	strict := fs.Bool("strict", false, "fail on unknown spec fields instead of warning about them")
+	naming := fs.String("naming", "", "facade naming for specs without \"naming\": suffix (AlphaV4, default), prefix (V4Alpha) or none (Alpha)")
+	var varAssigns listFlag
+	fs.Var(&varAssigns, "var", "NAME=value substituted for ${NAME} in spec strings; may be repeated")
+	varsFile := fs.String("vars", "", "JSON file of spec variables ({\"NAME\": \"value\"}); -var wins")
+	noInfer := fs.Bool("no-infer", false, "hermetic: take every import from the spec instead of scanning the package, go.mod and the existing output")
+	provenancePath := fs.String("provenance", "", "write a JSON provenance record of the run (generator version, inputs and outputs with SHA-256) to this file")
+	keyPattern := fs.String("key-pattern", "", "registry key convention enforced for specs without \"keyPattern\", e.g. \"<package>.<depLower>\"; unset: keys are not checked (see -warn-keys)")
+	warnKeys := fs.Bool("warn-keys", false, "warn about registry keys not following <package>.<depLower> in specs without a key pattern")
+	fixKeys := fs.Bool("fix-keys", false, "rewrite spec registry keys that do not follow the key pattern (default \"<package>.<depLower>\") before generating")
+	templateDir := fs.String("template-dir", "", "directory of <name>.go.tmpl files overriding the built-in templates (see di2 template export)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -154,15 +637,140 @@ func run(args []string) error {
 		return fmt.Errorf("missing -out")
 	}
 
+	switch *apiBreaking {
+	case "warn", "error":
+	default:
+		return fmt.Errorf("-api-breaking must be warn or error, got %q", *apiBreaking)
+	}
+	if *specsDir != "" && *apiDiff != "" && *apiDiff != apiDiffPrev {
+		return fmt.Errorf("-specs supports only -api-diff=prev")
+	}
+	if *split && *apiDiff != "" && *apiDiff != apiDiffPrev {
+		return fmt.Errorf("-split supports only -api-diff=prev")
+	}
+	if *split && *graphPath == "" {
+		return fmt.Errorf("-split needs -graph")
+	}
+	if *prune && *specsDir == "" {
+		return fmt.Errorf("-prune needs -specs")
+	}
+
+	opts := genOptions{Profile: *profile, APIDiff: *apiDiff, APIBreaking: *apiBreaking, Plugins: plugins, Split: *split, Strict: *strict, NoInfer: *noInfer, FixKeys: *fixKeys, WarnKeys: *warnKeys}
+	if *dryRun || *outPath == "-" {
+		opts.DryRun = dryRunOut
+	}
+	if *verify && opts.DryRun != nil {
+		return fmt.Errorf("-verify needs written output; drop -dry-run")
+	}
+	if *templateDir != "" {
+		if opts.Templates, err = loadTemplates(*templateDir); err != nil {
+			return err
+		}
+	}
+	if *provenancePath != "" {
+		if opts.DryRun != nil {
+			return fmt.Errorf("-provenance needs written output; drop -dry-run")
+		}
+		opts.Provenance = newProvenanceRecorder(args)
+		if *templateDir != "" {
+			files, _ := filepath.Glob(filepath.Join(*templateDir, "*.go.tmpl"))
+			for _, f := range files {
+				opts.Provenance.input("template", f)
+			}
+		}
+	}
+
+	if *naming != "" {
+		if !isNamingStrategy(*naming) {
+			return fmt.Errorf("-naming %q: want suffix, prefix or none", *naming)
+		}
+		defaultNaming = *naming
+	}
+	if err := validateKeyPattern(*keyPattern, "-key-pattern"); err != nil {
+		return err
+	}
+	if *keyPattern != "" {
+		defaultKeyPattern = *keyPattern
+	}
+	if *fixKeys && *graphPath != "" {
+		return fmt.Errorf("-fix-keys needs -spec or -specs")
+	}
+	if *fixKeys && defaultKeyPattern == "" {
+		defaultKeyPattern = conventionalKeyPattern
+	}
+	if *varsFile != "" || len(varAssigns) > 0 {
+		if specVars, err = loadSpecVars(*varsFile, varAssigns); err != nil {
+			return err
+		}
+		if opts.Provenance != nil && *varsFile != "" {
+			opts.Provenance.input("vars", *varsFile)
+		}
+	}
+	if *noInfer && *scanCachePath != "" {
+		return fmt.Errorf("-scan-cache cannot be combined with -no-infer")
+	}
+	if *scanCachePath != "" {
+		if err := importScanCache.load(*scanCachePath); err != nil {
+			return err
+		}
+		defer func() {
+			if opts.DryRun != nil {
+				return
+			}
+			if serr := importScanCache.save(*scanCachePath); err == nil {
+				err = serr
+			}
+		}()
+	}
+
+	if err := generate(*specPath, *specsDir, *graphPath, *outPath, *jobs, *prune, *verify, opts); err != nil {
+		return err
+	}
+	if opts.Provenance != nil {
+		return opts.Provenance.write(*provenancePath)
+	}
+	return nil
+}
+
+// generate runs the -spec, -specs or -graph generation of run.
+func generate(specPath, specsDir, graphPath, outPath string, jobs int, prune, verify bool, opts genOptions) error {
 	switch {
-	case *specPath != "" && *graphPath != "":
+	case specsDir != "" && (specPath != "" || graphPath != ""):
+		return fmt.Errorf("use only one of -spec, -specs or -graph")
+	case specsDir != "":
+		if jobs < 0 {
+			return fmt.Errorf("-j must be >= 0")
+		}
+		results, err := genServiceBatch(specsDir, outPath, jobs, opts)
+		if prune && opts.DryRun == nil {
+			err = errors.Join(err, pruneBatchOutput(outPath))
+		}
+		if err != nil || !verify {
+			return err
+		}
+		targets := make([]verifyTarget, 0, len(results))
+		for _, r := range results {
+			targets = append(targets, serviceVerifyTarget(r.Spec, r.Out))
+		}
+		return verifyGenerated(targets)
+	case specPath != "" && graphPath != "":
 		return fmt.Errorf("use only one of -spec or -graph")
-	case *specPath != "":
-		genService(*specPath, *outPath)
-		return nil
-	case *graphPath != "":
-		genGraph(*graphPath, *outPath)
+	case specPath != "":
+		genService(specPath, outPath, opts)
+		if verify {
+			return verifyGenerated([]verifyTarget{serviceVerifyTarget(specPath, outPath)})
+		}
 		return nil
+	case graphPath != "":
+		outs := genGraph(graphPath, outPath, opts)
+		if !verify {
+			return nil
+		}
+		targets := make([]verifyTarget, 0, len(outs))
+		for _, out := range outs {
+			targets = append(targets, graphVerifyTarget(graphPath, out))
+		}
+		return verifyGenerated(targets)
 	default:
 		return fmt.Errorf("missing -spec or -graph")
 	}
@@ -175,58 +783,113 @@ func main() {
 	}
 }
 
-func genService(specPath, outPath string) {
+// readServiceSpec loads, validates and defaults a service spec.
+// It returns the spec together with its raw bytes (used for the spec hash).
+func readServiceSpec(specPath string) (ServiceSpec, []byte) {
 	raw := mustRead(specPath)
 
 	var spec ServiceSpec
-	must(json.Unmarshal(raw, &spec))
+	if err := decodeSpec(renderSpecVars(specPath, raw), &spec); err != nil {
+		die(specPath + ": " + err.Error())
+	}
 
 	applyConfigDefaults(&spec.Config)
+	applyLoggingDefaults(&spec.Logging, "spec")
+	applySlogDefaults(spec.Optional)
+	if spec.Naming == "" {
+		spec.Naming = defaultNaming
+	}
 	validateServiceSpec(&spec)
+	spec.Cyclic = spec.Cyclic || len(spec.Cycles) > 0
 
 	if strings.TrimSpace(spec.FacadeName) == "" {
-		spec.FacadeName = spec.WrapperBase + spec.VersionSuffix
+		spec.FacadeName = facadeBaseName(spec)
 	}
 	if strings.TrimSpace(spec.PublicConstructorName) == "" {
-		spec.PublicConstructorName = "New" + spec.WrapperBase + spec.VersionSuffix
+		spec.PublicConstructorName = "New" + facadeBaseName(spec)
 	}
 	if spec.InjectPolicy.OnOverwrite == "" {
 		spec.InjectPolicy.OnOverwrite = "error"
 	}
+	return spec, raw
+}
+
+func genService(specPath, outPath string, opts genOptions) {
+	if opts.FixKeys {
+		must(fixSpecKeys(specPath))
+	}
+	// before validation: a typo'd key is often why validation fails
+	checkServiceFields(specPath, mustRead(specPath), opts.Strict)
+	opts.Provenance.input("spec", specPath)
+	spec, raw := readServiceSpec(specPath)
+	if opts.WarnKeys {
+		warnRegistryKeys(specPath, spec)
+	}
 
 	// imports are optional:
 	// - config import inferred only if spec.Config.Enabled
 	// - di import always needed (BuildWith uses di.Registry)
-	inferImportsForService(&spec, outPath)
+	if opts.NoInfer {
+		requireDeclaredServiceImports(&spec)
+	} else {
+		inferImportsForService(&spec, outPath)
+	}
 
-	specHash := sha256Hex(raw)
+	specHash := specSHA256(raw)
 
-	// deterministic ordering (hygiene)
-	sort.Slice(spec.Required, func(i, j int) bool { return spec.Required[i].Name < spec.Required[j].Name })
-	sort.Slice(spec.Optional, func(i, j int) bool { return spec.Optional[i].Name < spec.Optional[j].Name })
-	sort.Slice(spec.Methods, func(i, j int) bool { return spec.Methods[i].Name < spec.Methods[j].Name })
+	// deterministic ordering (hygiene): explicit order first, then name or spec order
+	sortSpecEntries(spec.Required, func(d RequiredDep) (int, string) { return d.Order, d.Name }, spec.PreserveOrder)
+	sortSpecEntries(spec.Optional, func(d OptionalDep) (int, string) { return d.Order, d.Name }, spec.PreserveOrder)
+	sortSpecEntries(spec.Methods, func(m MethodSpec) (int, string) { return m.Order, m.Name }, spec.PreserveOrder)
 
 	// Preserve imports from existing generated file (keeps manually added imports)
-	preserved := readImportsFromExistingOut(outPath)
+	var preserved []GoImport
+	if !opts.NoInfer {
+		preserved = readImportsFromExistingOut(outPath)
+	}
 
 	// Required imports for this template
 	required := []GoImport{
+		{Path: "context"}, // BuildWithCtx, HealthCheck; dropped when unused
 		{Path: "fmt"},
-		{Path: "strings"},
+		{Path: "maps"},
 		{Name: "di", Path: spec.Imports.DI}, // always needed because BuildWith(reg di.Registry) exists
 	}
+
+	// facade in another package: accessors next to the service, qualified types here
+	external := spec.Output.external(spec.Package)
+	if external {
+		genAccessors(spec, specPath, specHash, opts)
+		svcImp := externalizeService(&spec, specPath)
+		// the service import's name may change (e.g. aliasing); drop stale copies
+		preserved = withoutImportPath(preserved, svcImp.Path)
+		required = append(required, svcImp)
+	}
+	setImplTargets(&spec, external)
+	setApplyCalls(&spec)
+	setCollectionDeps(&spec)
+	setDepGroups(&spec)
+	setMethodDecorators(&spec)
 	if spec.Config.Enabled {
 		required = append(required, GoImport{Name: "config", Path: spec.Imports.Config})
 	}
+	if spec.Logging.Enabled || hasSlogOptional(spec.Optional) {
+		required = append(required, GoImport{Path: "log/slog"})
+	}
 
-	// auto-import stdlib packages referenced by types in method signatures
-	if methodUsesPkgQualifier(spec.Methods, "context") {
-		required = append(required, GoImport{Path: "context"})
+	if spec.APIMock {
+		required = append(required, GoImport{Path: "sync"})
 	}
+
+	// auto-import stdlib packages referenced by types in method signatures
 	if methodUsesPkgQualifier(spec.Methods, "time") {
 		required = append(required, GoImport{Path: "time"})
 	}
 
+	if opts.NoInfer {
+		requireDeclaredQualifiers(&spec, required)
+	}
+	required = append(required, declaredPackageImports(spec.Imports.Packages)...)
 	mergedImports := mergeImports(required, preserved)
 
 	data := map[string]any{
@@ -234,48 +897,182 @@ func genService(specPath, outPath string) {
 		"SpecPath": filepath.ToSlash(specPath),
 		"SpecHash": specHash,
 		"Imports":  mergedImports,
+		"Opts":     opts,
+		"Preamble": codegenPreamble(spec.Codegen, "spec"),
 	}
 
-	src := mustExecTemplate(serviceTpl, data)
-	writeFormatted(outPath, src)
+	src := dropUnusedDeclaredImports(mustExecTemplate(opts.templates().Service, data), spec.Imports.Packages)
+	checkAPIDiff(outPath, src, opts)
+	emitGenerated(outPath, src, opts)
+	runPlugins(pluginRequest{Kind: "service", SpecPath: specPath, OutPath: outPath, Service: &spec}, opts)
 }
 
-func genGraph(graphPath, outPath string) {
-	raw := mustRead(graphPath)
+// sortSpecEntries orders spec entries by their explicit order key. Ties are broken
+// by name, or keep their spec file position when preserve is set; both are deterministic.
+func sortSpecEntries[T any](entries []T, key func(T) (order int, name string), preserve bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		oi, ni := key(entries[i])
+		oj, nj := key(entries[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return !preserve && ni < nj
+	})
+}
+
+// codegenPreamble renders the configured header comment and //go:build line,
+// each followed by a blank line, for placement above the "Code generated" marker.
+func codegenPreamble(c CodegenSpec, ctx string) string {
+	var b strings.Builder
+
+	lines := strings.Split(strings.TrimRight(c.Header, " \t\r\n"), "\n")
+	if strings.TrimSpace(c.Header) != "" {
+		for _, ln := range lines {
+			ln = strings.TrimRight(ln, " \t\r")
+			switch {
+			case strings.HasPrefix(ln, "//"):
+				b.WriteString(ln)
+			case ln == "":
+				b.WriteString("//")
+			default:
+				b.WriteString("// " + ln)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	var expr constraint.Expr
+	for i, tag := range c.BuildTags {
+		e, err := constraint.Parse("//go:build " + strings.TrimSpace(tag))
+		if err != nil {
+			die(fmt.Sprintf("%s codegen.buildTags[%d] %q is not a valid build constraint: %v", ctx, i, tag, err))
+		}
+		if expr == nil {
+			expr = e
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: e}
+		}
+	}
+	if expr != nil {
+		b.WriteString("//go:build " + expr.String() + "\n\n")
+	}
+	return b.String()
+}
 
-	var g GraphSpec
-	must(json.Unmarshal(raw, &g))
+// genGraph generates the composition roots of graphPath into outPath, or with
+// opts.Split one file per root in the outPath directory, and returns the files.
+func genGraph(graphPath, outPath string, opts genOptions) []string {
+	checkGraphFields(graphPath, opts.Strict)
+	g, graphHash := readGraphSpec(graphPath)
 
 	applyConfigDefaults(&g.Config)
+	applyLoggingDefaults(&g.Logging, "graph spec")
 	validateGraphSpec(&g)
+	resolveGraphConditions(&g)
+	if opts.NoInfer {
+		requireDeclaredGraphImports(&g, graphPath)
+	}
+	resolveGraphServiceSpecs(&g, graphPath)
+	validateGraphServiceNames(&g)
+	for _, root := range g.allRoots() {
+		validateGraphCycles(*root)
+	}
+	recordGraphInputs(g, graphPath, opts.Provenance)
 
 	// imports optional:
 	// - config import inferred only if g.Config.Enabled
 	// - di import always needed (reg di.Registry)
-	inferImportsForGraph(&g, outPath)
-
-	graphHash := sha256Hex(raw)
+	pkgOut := outPath
+	if opts.Split {
+		pkgOut = splitGraphOutPath(outPath, "")
+	}
+	if !opts.NoInfer {
+		inferImportsForGraph(&g, pkgOut)
+	}
 
 	for i := range g.Roots {
-		sort.Slice(g.Roots[i].Services, func(a, b int) bool { return g.Roots[i].Services[a].Var < g.Roots[i].Services[b].Var })
+		sortSpecEntries(g.Roots[i].Services, func(s GraphService) (int, string) { return s.Order, s.Var }, g.PreserveOrder)
 		sort.Slice(g.Roots[i].Wiring, func(a, b int) bool {
 			wa := g.Roots[i].Wiring[a]
 			wb := g.Roots[i].Wiring[b]
 			return wa.To+wa.Call+wa.ArgFrom < wb.To+wb.Call+wb.ArgFrom
 		})
 	}
-	sort.Slice(g.Roots, func(i, j int) bool { return g.Roots[i].Name < g.Roots[j].Name })
+	if !g.PreserveOrder {
+		sort.Slice(g.Roots, func(i, j int) bool { return g.Roots[i].Name < g.Roots[j].Name })
+	}
 
-	preserved := readImportsFromExistingOut(outPath)
+	if g.Shared != nil {
+		// built first: roots take its result
+		g.Roots = append([]GraphRoot{prepareSharedRoot(&g)}, g.Roots...)
+	}
+
+	for i := range g.Roots {
+		g.Roots[i].BuildOrder = dependencyOrder(g.Roots[i])
+		g.Roots[i].BuildStages = dependencyStages(g.Roots[i])
+		setWiringArgs(&g.Roots[i])
+		setRootParams(&g.Roots[i], g.Config)
+		prepareGraphHooks(&g.Roots[i])
+		validateGraphHealth(g.Roots[i])
+		validateGraphReload(g.Roots[i], g.Config)
+	}
 
 	required := []GoImport{
+		{Path: "context"},
 		{Path: "fmt"},
 		{Name: "di", Path: g.Imports.DI},
 	}
+	required = append(required, g.ServiceImports...)
 	if g.Config.Enabled {
 		required = append(required, GoImport{Name: "config", Path: g.Imports.Config})
 	}
+	if g.Logging.Enabled {
+		required = append(required, GoImport{Path: "log/slog"})
+	}
+	if g.HasConsumers() {
+		required = append(required, streamImport(g.Imports.DI))
+	}
+	for _, r := range g.Roots {
+		if r.HasHookTimeout() {
+			required = append(required, GoImport{Path: "time"})
+		}
+		if r.HealthHandler || r.WiringHandler {
+			required = append(required, GoImport{Path: "net/http"})
+		}
+		if r.WiringHandler || opts.Profile {
+			required = append(required, GoImport{Path: "time"})
+		}
+	}
 
+	if g.Manifest != "" {
+		writeGraphManifest(g, graphPath, pkgOut, graphHash, opts)
+	}
+	if !opts.Split {
+		emitGraph(g, graphPath, outPath, graphHash, required, opts)
+		runPlugins(pluginRequest{Kind: "graph", SpecPath: graphPath, OutPath: outPath, Graph: &g}, opts)
+		return []string{outPath}
+	}
+	outs := emitSplitGraph(g, graphPath, outPath, graphHash, required, opts)
+	runPlugins(pluginRequest{Kind: "graph", SpecPath: graphPath, OutPath: outs[0], Graph: &g}, opts)
+	return outs
+}
+
+// emitGraph renders the roots of g into outPath. Imports of the existing file
+// are kept, except stale copies of the required ones.
+func emitGraph(g GraphSpec, graphPath, outPath, graphHash string, required []GoImport, opts genOptions) {
+	var preserved []GoImport
+	if !opts.NoInfer {
+		preserved = readImportsFromExistingOut(outPath)
+	}
+	for _, imp := range g.ServiceImports {
+		preserved = withoutImportPath(preserved, imp.Path)
+	}
+	preserved = withoutImportPath(preserved, "embed")
+	if g.Manifest != "" {
+		required = append(required[:len(required):len(required)], GoImport{Name: "_", Path: "embed"})
+	}
+	required = append(required[:len(required):len(required)], declaredPackageImports(g.Imports.Packages)...)
 	mergedImports := mergeImports(required, preserved)
 
 	data := map[string]any{
@@ -283,10 +1080,17 @@ func genGraph(graphPath, outPath string) {
 		"GraphPath": filepath.ToSlash(graphPath),
 		"GraphHash": graphHash,
 		"Imports":   mergedImports,
+		"Opts":      opts,
+		"Preamble":  codegenPreamble(g.Codegen, "graph spec"),
 	}
 
-	src := mustExecTemplate(graphTpl, data)
-	writeFormatted(outPath, src)
+	src := dropUnusedDeclaredImports(mustExecTemplate(opts.templates().Graph, data), g.Imports.Packages)
+	if opts.Split {
+		// each file gets the service imports of every root; keep its own
+		src = dropUnusedImports(src, importNames(append(g.ServiceImports, streamImport(g.Imports.DI))))
+	}
+	checkAPIDiff(outPath, src, opts)
+	emitGenerated(outPath, src, opts)
 }
 
 func applyConfigDefaults(c *ConfigSpec) {
@@ -304,6 +1108,132 @@ func applyConfigDefaults(c *ConfigSpec) {
 	}
 }
 
+// applyLoggingDefaults validates the logging level and computes the slog method.
+func applyLoggingDefaults(l *LoggingSpec, ctx string) {
+	if !l.Enabled {
+		return
+	}
+	switch l.Level {
+	case "", "debug":
+		l.Method = "Debug"
+	case "info":
+		l.Method = "Info"
+	case "warn":
+		l.Method = "Warn"
+	case "error":
+		l.Method = "Error"
+	default:
+		die(ctx + " logging.level must be one of: debug|info|warn|error")
+	}
+}
+
+// maskWordBits is the number of required deps one word of the buildScoped mask
+// holds. Facades with more use a multi-word mask (see ServiceSpec.MaskWords).
+const maskWordBits = 64
+
+// MaskWords is the number of uint64 words of the required dep mask when the deps
+// do not fit one word, and 0 for the single uint64 mask.
+func (s ServiceSpec) MaskWords() int {
+	if len(s.Required) <= maskWordBits {
+		return 0
+	}
+	return (len(s.Required) + maskWordBits - 1) / maskWordBits
+}
+
+// wideMaskBit renders the multi-word mask element of the required dep at index
+// i, e.g. "1: 1 << 3" for i = 67.
+func wideMaskBit(i int) string {
+	return fmt.Sprintf("%d: 1 << %d", i/maskWordBits, i%maskWordBits)
+}
+
+// wideMaskAll renders the elements of the multi-word mask with all n required
+// deps set.
+func wideMaskAll(n int) string {
+	var parts []string
+	for ; n >= maskWordBits; n -= maskWordBits {
+		parts = append(parts, "^uint64(0)")
+	}
+	if n > 0 {
+		parts = append(parts, fmt.Sprintf("1<<%d - 1", n))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// wideRequiresMask is requiresMask for a multi-word mask: the elements of the
+// mask with the bits of names set, e.g. "0: 1<<2 | 1<<5, 1: 1<<0".
+func wideRequiresMask(s ServiceSpec, names []string) string {
+	words := map[int][]string{}
+	var order []int
+	for _, n := range names {
+		for i, d := range s.Required {
+			if d.Name != n {
+				continue
+			}
+			w := i / maskWordBits
+			if words[w] == nil {
+				order = append(order, w)
+			}
+			words[w] = append(words[w], fmt.Sprintf("1<<%d", i%maskWordBits))
+		}
+	}
+	sort.Ints(order)
+	parts := make([]string, 0, len(order))
+	for _, w := range order {
+		parts = append(parts, fmt.Sprintf("%d: %s", w, strings.Join(words[w], " | ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// requiresMask renders the buildScoped mask for a method's requires list,
+// e.g. "reqCoreV4Alpha | reqCoreV4Beta" (or "0" when nothing is required).
+func requiresMask(facade string, names []string) string {
+	if len(names) == 0 {
+		return "0"
+	}
+	parts := make([]string, 0, len(names))
+	for _, n := range names {
+		parts = append(parts, "req"+facade+n)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// applySlogDefaults expands {"slog": true} optional deps:
+// name Logger, type *slog.Logger, key di.SlogKey, field "logger", default slog.Default().
+func applySlogDefaults(opts []OptionalDep) {
+	for i := range opts {
+		o := &opts[i]
+		if !o.Slog {
+			continue
+		}
+		if o.Type != "" && o.Type != "*slog.Logger" {
+			die("optional slog dep must have type *slog.Logger (got " + o.Type + ")")
+		}
+		o.Type = "*slog.Logger"
+		if o.Name == "" {
+			o.Name = "Logger"
+		}
+		if o.RegistryKey == "" {
+			o.RegistryKey = slogRegistryKey
+		}
+		if o.Apply.Kind == "" && o.Apply.Name == "" {
+			o.Apply = OptionalApply{Kind: "field", Name: "logger"}
+		}
+		if o.DefaultExpr == "" {
+			o.DefaultExpr = "slog.Default()"
+		}
+	}
+}
+
+// hasSlogOptional reports whether any optional dep uses the slog shortcut.
+func hasSlogOptional(opts []OptionalDep) bool {
+	for _, o := range opts {
+		if o.Slog {
+			return true
+		}
+	}
+	return false
+}
+
 func validateServiceSpec(s *ServiceSpec) {
 	req := func(name, v string) {
 		if strings.TrimSpace(v) == "" {
@@ -312,9 +1242,19 @@ func validateServiceSpec(s *ServiceSpec) {
 	}
 	req("package", s.Package)
 	req("wrapperBase", s.WrapperBase)
-	req("versionSuffix", s.VersionSuffix)
+	validateNaming(s.Naming, "spec naming")
+	if s.Naming != namingNone {
+		req("versionSuffix", s.VersionSuffix)
+	}
 	req("implType", s.ImplType)
 	req("constructor", s.Constructor)
+	validateSpecCycles(s)
+	if s.UnsafeImpl.Disable && (s.Cyclic || len(s.Cycles) > 0) {
+		die("spec unsafeImpl.disable cannot be combined with cyclic (cycles are wired with UnsafeImpl)")
+	}
+	if s.UnsafeImpl.Disable && s.UnsafeImpl.Track {
+		die("spec unsafeImpl.track needs UnsafeImpl; drop unsafeImpl.disable")
+	}
 
 	if len(s.Required) == 0 {
 		die("spec required must be non-empty")
@@ -331,14 +1271,43 @@ func validateServiceSpec(s *ServiceSpec) {
 		if o.Name == "" || o.Type == "" || o.RegistryKey == "" || o.Apply.Kind == "" || o.Apply.Name == "" {
 			die("optional dep must have name/type/registryKey/apply{kind,name}")
 		}
-		if o.Apply.Kind != "setter" && o.Apply.Kind != "field" {
-			die("optional.apply.kind must be 'setter' or 'field'")
+		switch o.Apply.Kind {
+		case "setter", "field":
+			if len(o.Apply.Args) > 0 {
+				die("optional " + o.Name + " apply.args is only valid for kind 'call'")
+			}
+		case "call":
+			validateApplyCall(o, s.Config.Enabled)
+		default:
+			die("optional.apply.kind must be 'setter', 'field' or 'call'")
+		}
+		if o.EnabledWhen != nil && strings.TrimSpace(o.EnabledWhen.FlagKey) == "" {
+			die("optional dep " + o.Name + " enabledWhen.flagKey must be non-empty")
 		}
 	}
+	validateDepTags(s)
+	validateRegistryKeys(s)
+	validateDeclaredPackages(s.Imports.Packages, "spec")
+	requiredNames := make(map[string]bool, len(s.Required))
+	for _, d := range s.Required {
+		requiredNames[d.Name] = true
+	}
 	for _, m := range s.Methods {
 		if m.Name == "" {
 			die("method must have name")
 		}
+		if m.Chain && len(m.Returns) > 0 {
+			die("method " + m.Name + " chain requires a method without returns")
+		}
+		for _, r := range m.Requires {
+			if !requiredNames[r] {
+				die("method " + m.Name + " requires unknown required dep " + r)
+			}
+		}
+	}
+
+	if s.APIMock && !s.APIInterface {
+		die("apiMock requires apiInterface")
 	}
 
 	switch s.InjectPolicy.OnOverwrite {
@@ -355,6 +1324,8 @@ func validateGraphSpec(g *GraphSpec) {
 	if len(g.Roots) == 0 {
 		die("graph spec roots must be non-empty")
 	}
+	validateGraphManifest(g.Manifest)
+	validateDeclaredPackages(g.Imports.Packages, "graph spec")
 }
 
 // inferOptionalConfigImport populates imports.Config based on cfg + scanned imports + go.mod fallback.
@@ -383,41 +1354,71 @@ func inferOptionalConfigImport(
 		return
 	}
 
-	// Prefer whatever the project already uses in source files
-	if gi, ok := findImportByAliasOrSuffix(scanned, "config", "/config"); ok {
+	// An import the sources name config explicitly is the one generated code means
+	if gi, ok := findImportByAliasOrSuffix(scanned, "config", ""); ok {
 		imports.Config = gi.Path
 		return
 	}
 
-	// Fallback: use project go.mod + ./config directory
-	modRoot, modPath, err := findModule(pkgDir)
-	if err != nil {
-		die("cannot infer " + ctx + ": config enabled but not imported in sources and cannot find project go.mod: " + err.Error())
+	// Then the ./config directory next to the package, resolved by go/packages
+	// (falling back to the project go.mod)
+	cfgImport, err := resolveConfigImport(pkgDir)
+	if err == nil {
+		imports.Config = cfgImport
+		return
 	}
-	pkgImport, perr := moduleImportPathForDir(modRoot, modPath, pkgDir)
-	if perr != nil || strings.TrimSpace(pkgImport) == "" {
-		msg := "cannot infer " + ctx + ": cannot compute project pkg import for " + filepath.ToSlash(pkgDir)
-		if perr != nil {
-			msg += ": " + perr.Error()
-		}
-		die(msg)
+
+	// Last resort: any scanned import ending in /config
+	if gi, ok := findImportByAliasOrSuffix(scanned, "", "/config"); ok {
+		imports.Config = gi.Path
+		return
+	}
+	die("cannot infer " + ctx + ": " + err.Error())
+}
+
+// resolveConfigImport returns the import path of the ./config directory next to
+// the package in pkgDir.
+func resolveConfigImport(pkgDir string) (string, error) {
+	if _, _, err := findOwningModule(pkgDir); err != nil {
+		return "", fmt.Errorf("config enabled but not imported in sources and cannot find project go.mod: %w", err)
+	}
+	cfgDir := filepath.Join(pkgDir, "config")
+	if !dirExists(cfgDir) {
+		return "", fmt.Errorf("config enabled but ./config directory not found in %s (and not imported in sources)", filepath.ToSlash(pkgDir))
+	}
+	cfgImport, err := resolveImportPath(cfgDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot compute project pkg import for %s: %w", filepath.ToSlash(pkgDir), err)
 	}
-	if !dirExists(filepath.Join(pkgDir, "config")) {
-		die("cannot infer " + ctx + ": config enabled but ./config directory not found in " + filepath.ToSlash(pkgDir) + " (and not imported in sources)")
+	if strings.TrimSpace(cfgImport) == "" {
+		return "", fmt.Errorf("cannot compute project pkg import for %s", filepath.ToSlash(pkgDir))
 	}
-	imports.Config = pkgImport + "/config"
+	return cfgImport, nil
 }
 
-// inferDIImport populates imports.DI (always needed). Prefer scanned imports, else infer from di2 module.
+// inferDIImport populates imports.DI (always needed): the import the sources
+// refer to as runtimePkgAlias (aliased so, or unaliased with that last path
+// element, as a forked or vendored runtime is), else the runtime package of the
+// di2 module (resolved by go/packages), else, as a last resort, any scanned
+// import ending in preferSuffix.
 func inferDIImport(imports *Imports, scanned []GoImport, runtimePkgAlias, preferSuffix string) {
 	if strings.TrimSpace(imports.DI) != "" {
 		return
 	}
-	if gi, ok := findImportByAliasOrSuffix(scanned, runtimePkgAlias, preferSuffix); ok {
+	if gi, ok := findImportByName(scanned, runtimePkgAlias); ok {
 		imports.DI = gi.Path
 		return
 	}
-	imports.DI = inferDIRuntimeImportFromDI2Module(runtimePkgAlias)
+	p, err := diRuntimeImportFromDI2Module(runtimePkgAlias)
+	if err == nil {
+		imports.DI = p
+		return
+	}
+	if gi, ok := findImportByAliasOrSuffix(scanned, "", preferSuffix); ok {
+		imports.DI = gi.Path
+		return
+	}
+	die(err.Error())
 }
 
 // -------------------------
@@ -433,9 +1434,12 @@ func inferDIImport(imports *Imports, scanned []GoImport, runtimePkgAlias, prefer
 //     BUT project imports are from the project go.mod (nearest go.mod above outPath dir).
 //
 // Notes:
-// - For config: prefer local-package import (since config is part of the project).
-// - For di runtime: prefer local-package import if present (lets a project override/fork),
-//   otherwise compute from di2 module via runtime.Caller + findModule.
+// - An import the sources alias explicitly (config, di) wins: generated code uses
+//   that identifier. So does an unaliased import named di (a forked or vendored
+//   runtime).
+// - Otherwise config is the ./config package next to the output and the di runtime
+//   is the di2 module's, both resolved by go/packages (go.mod fallback).
+// - Other scanned imports merely ending in /config or /di are a last resort.
 
 func inferImportsForService(s *ServiceSpec, outPath string) {
 	pkgDir := filepath.Dir(outPath)
@@ -455,28 +1459,62 @@ func inferImportsForGraph(g *GraphSpec, outPath string) {
 
 // inferDIRuntimeImportFromDI2Module computes the import path for the DI runtime package
 // based on the go.mod of the module that contains di2 (this generator).
+//
+// When the generator's source tree is not on disk (di2 installed via `go install`,
+// built with -trimpath, or run from the module proxy cache of another machine),
+// the module path is taken from the binary's build info instead.
 func inferDIRuntimeImportFromDI2Module(runtimePkgRel string) string {
-	_, thisFile, _, ok := runtime.Caller(0)
-	if !ok {
-		die("cannot infer di runtime import: runtime.Caller failed")
-	}
-	genDir := filepath.Dir(thisFile)
-
-	modRoot, modPath, err := findModule(genDir)
+	p, err := diRuntimeImportFromDI2Module(runtimePkgRel)
 	if err != nil {
-		die("cannot infer di runtime import: cannot find go.mod for generator module: " + err.Error())
+		die(err.Error())
 	}
+	return p
+}
 
+// diRuntimeImportFromDI2Module is inferDIRuntimeImportFromDI2Module returning
+// the error.
+func diRuntimeImportFromDI2Module(runtimePkgRel string) (string, error) {
 	if strings.TrimSpace(runtimePkgRel) == "" {
 		runtimePkgRel = "di"
 	}
 
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok || !fileExists(thisFile) {
+		bi, biOK := debug.ReadBuildInfo()
+		if p, ok := diRuntimeImportFromBuildInfo(bi, biOK, runtimePkgRel); ok {
+			return p, nil
+		}
+		return "", fmt.Errorf("cannot infer di runtime import: generator sources not on disk and no module build info (set imports.di in the spec)")
+	}
+	genDir := filepath.Dir(thisFile)
+
+	modRoot, modPath, err := findOwningModule(genDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot infer di runtime import: cannot find go.mod for generator module: %w", err)
+	}
+
 	runtimeAbs := filepath.Join(modRoot, filepath.FromSlash(runtimePkgRel))
 	if !dirExists(runtimeAbs) {
-		die("cannot infer di runtime import: expected runtime package dir at " + filepath.ToSlash(runtimeAbs))
+		return "", fmt.Errorf("cannot infer di runtime import: expected runtime package dir at %s", filepath.ToSlash(runtimeAbs))
+	}
+
+	if p, err := loadImportPath(runtimeAbs); err == nil {
+		return p, nil
 	}
+	return modPath + "/" + filepath.ToSlash(runtimePkgRel), nil
+}
 
-	return modPath + "/" + filepath.ToSlash(runtimePkgRel)
+// diRuntimeImportFromBuildInfo derives the DI runtime import path from the main
+// module recorded in the generator binary (runtime package relative to its root).
+func diRuntimeImportFromBuildInfo(bi *debug.BuildInfo, ok bool, runtimePkgRel string) (string, bool) {
+	if !ok || bi == nil {
+		return "", false
+	}
+	mod := strings.TrimSpace(bi.Main.Path)
+	if mod == "" || mod == "command-line-arguments" {
+		return "", false
+	}
+	return mod + "/" + strings.Trim(filepath.ToSlash(runtimePkgRel), "/"), true
 }
 
 // -------------------------
@@ -556,31 +1594,18 @@ type GoImport struct {
 // scanPackageImports reads imports from all non-generated .go files in pkgDir
 // (excluding *_test.go and *.gen.go) and returns them as GoImport entries.
 // It preserves aliases from source files (e.g. `config "..."`).
+// Results are memoized per directory in importScanCache.
 func scanPackageImports(pkgDir string) []GoImport {
-	entries, err := os.ReadDir(pkgDir)
-	if err != nil {
-		return nil
-	}
+	return importScanCache.scan(pkgDir)
+}
 
+// parsePackageImports parses the imports of the given files of pkgDir.
+// Unreadable files and files that fail to parse are skipped.
+func parsePackageImports(pkgDir string, files []string) []GoImport {
 	var out []GoImport
 	fset := token.NewFileSet()
 
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		if !strings.HasSuffix(name, ".go") {
-			continue
-		}
-		if strings.HasSuffix(name, "_test.go") {
-			continue
-		}
-		// avoid feeding generated outputs back into inference
-		if strings.HasSuffix(name, ".gen.go") || strings.Contains(name, ".gen.") || strings.HasSuffix(name, "_gen.go") {
-			continue
-		}
-
+	for _, name := range files {
 		full := filepath.Join(pkgDir, name)
 		src, rerr := os.ReadFile(full)
 		if rerr != nil {
@@ -605,6 +1630,20 @@ func scanPackageImports(pkgDir string) []GoImport {
 	return dedupeAndSortImports(out)
 }
 
+// findImportByName returns the scanned import the sources refer to as name:
+// one aliased name, or an unaliased one whose last path element is name.
+func findImportByName(imports []GoImport, name string) (GoImport, bool) {
+	if gi, ok := findImportByAliasOrSuffix(imports, name, ""); ok {
+		return gi, true
+	}
+	for _, gi := range imports {
+		if gi.Name == "" && (gi.Path == name || strings.HasSuffix(gi.Path, "/"+name)) {
+			return gi, true
+		}
+	}
+	return GoImport{}, false
+}
+
 // findImportByAliasOrSuffix picks an import from scanned imports.
 // Prefer alias match first, then suffix match.
 func findImportByAliasOrSuffix(imports []GoImport, preferAlias, preferSuffix string) (GoImport, bool) {
@@ -625,6 +1664,30 @@ func findImportByAliasOrSuffix(imports []GoImport, preferAlias, preferSuffix str
 	return GoImport{}, false
 }
 
+// isStdImport reports whether p is a standard library path: its first element
+// has no dot, which is how gofmt/goimports tell the groups apart.
+func isStdImport(p string) bool {
+	first, _, _ := strings.Cut(p, "/")
+	return !strings.Contains(first, ".")
+}
+
+// importLess orders stdlib imports before the others, then by path and name.
+func importLess(a, b GoImport) bool {
+	if sa, sb := isStdImport(a.Path), isStdImport(b.Path); sa != sb {
+		return sa
+	}
+	if a.Path == b.Path {
+		return a.Name < b.Name
+	}
+	return a.Path < b.Path
+}
+
+// importBreak reports whether a blank line goes before imps[i]: the first
+// non-stdlib import after a stdlib one.
+func importBreak(imps []GoImport, i int) bool {
+	return i > 0 && isStdImport(imps[i-1].Path) && !isStdImport(imps[i].Path)
+}
+
 func dedupeAndSortImports(imps []GoImport) []GoImport {
 	type key struct {
 		path string
@@ -640,12 +1703,7 @@ func dedupeAndSortImports(imps []GoImport) []GoImport {
 		seen[k] = true
 		out = append(out, gi)
 	}
-	sort.Slice(out, func(i, j int) bool {
-		if out[i].Path == out[j].Path {
-			return out[i].Name < out[j].Name
-		}
-		return out[i].Path < out[j].Path
-	})
+	sort.Slice(out, func(i, j int) bool { return importLess(out[i], out[j]) })
 	return out
 }
 
@@ -708,15 +1766,125 @@ func mergeImports(required []GoImport, preserved []GoImport) []GoImport {
 	for _, gi := range seen {
 		out = append(out, gi)
 	}
-	sort.Slice(out, func(i, j int) bool {
-		if out[i].Path == out[j].Path {
-			return out[i].Name < out[j].Name
+	sort.Slice(out, func(i, j int) bool { return importLess(out[i], out[j]) })
+	return out
+}
+
+// managedImports are the stdlib imports the templates add on demand, keyed by
+// path with their package name. A preserved copy is dropped once the generated
+// code stops using it (e.g. after turning logging off), so stale imports never
+// break the build.
+var managedImports = map[string]string{
+	"context":   "context",
+	"fmt":       "fmt",
+	"log/slog":  "slog",
+	"maps":      "maps",
+	"math/bits": "bits",
+	"net/http":  "http",
+	"strings":   "strings",
+	"sync":      "sync",
+	"time":      "time",
+}
+
+// dropUnusedManagedImports removes managed imports that src does not reference.
+// Other imports (including user-preserved ones) are left alone; src is returned
+// unchanged if it does not parse.
+func dropUnusedManagedImports(src []byte) []byte {
+	return dropUnusedImports(src, managedImports)
+}
+
+// dropUnusedImports removes the imports in managed (path -> package name) that
+// src does not reference; see dropUnusedManagedImports.
+func dropUnusedImports(src []byte, managed map[string]string) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
 		}
-		return out[i].Path < out[j].Path
+		return true
 	})
+
+	unused := func(spec *ast.ImportSpec) bool {
+		name, ok := managed[strings.Trim(spec.Path.Value, `"`)]
+		if !ok {
+			return false
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		return name != "_" && name != "." && !used[name]
+	}
+
+	// Unused specs are cut line by line rather than via the AST, so the blank
+	// line between the stdlib and other import groups survives and no gap is
+	// left where a spec was.
+	lines := strings.SplitAfter(string(src), "\n")
+	drop := map[int]bool{}
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		if !gd.Lparen.IsValid() {
+			if unused(gd.Specs[0].(*ast.ImportSpec)) {
+				markLines(drop, fset, gd)
+			}
+			continue
+		}
+		for _, sp := range gd.Specs {
+			if unused(sp.(*ast.ImportSpec)) {
+				markLines(drop, fset, sp)
+			}
+		}
+		// Collapse the blank lines the removal left inside the block.
+		first, last := fset.Position(gd.Lparen).Line, fset.Position(gd.Rparen).Line
+		prevBlank := true
+		for ln := first + 1; ln < last; ln++ {
+			if drop[ln] {
+				continue
+			}
+			blank := strings.TrimSpace(lines[ln-1]) == ""
+			if blank && prevBlank {
+				drop[ln] = true
+			}
+			prevBlank = blank
+		}
+		for ln := last - 1; ln > first && (drop[ln] || strings.TrimSpace(lines[ln-1]) == ""); ln-- {
+			drop[ln] = true
+		}
+	}
+	if len(drop) == 0 {
+		return src
+	}
+
+	var buf strings.Builder
+	for i, l := range lines {
+		if !drop[i+1] {
+			buf.WriteString(l)
+		}
+	}
+	out, err := format.Source([]byte(buf.String()))
+	if err != nil {
+		return src
+	}
 	return out
 }
 
+// markLines marks the source lines n spans in drop.
+func markLines(drop map[int]bool, fset *token.FileSet, n ast.Node) {
+	for ln := fset.Position(n.Pos()).Line; ln <= fset.Position(n.End()).Line; ln++ {
+		drop[ln] = true
+	}
+}
+
 // -------------------------
 // Misc helpers
 // -------------------------
@@ -726,7 +1894,28 @@ func sha256Hex(b []byte) string {
 	return hex.EncodeToString(sum[:])
 }
 
+// specStdin supplies the spec for "-spec -" (or the graph for "-graph -").
+var specStdin io.Reader = os.Stdin
+
+// stdinSpec holds specStdin once read: a run reads its spec more than once.
+var stdinSpec struct {
+	sync.Mutex
+	raw  []byte
+	read bool
+}
+
+// mustRead reads a spec or graph file; "-" reads specStdin.
 func mustRead(path string) []byte {
+	if path == "-" {
+		stdinSpec.Lock()
+		defer stdinSpec.Unlock()
+		if !stdinSpec.read {
+			raw, err := io.ReadAll(specStdin)
+			must(err)
+			stdinSpec.raw, stdinSpec.read = raw, true
+		}
+		return stdinSpec.raw
+	}
 	b, err := os.ReadFile(path)
 	must(err)
 	return b
@@ -738,8 +1927,24 @@ func mustExecTemplate(tpl *template.Template, data any) []byte {
 	return []byte(sb.String())
 }
 
+// emitGenerated writes the generated file to out, or prints it to opts.DryRun.
+func emitGenerated(out string, src []byte, opts genOptions) {
+	if opts.DryRun == nil {
+		writeFormatted(out, src)
+		opts.Provenance.output(out)
+		return
+	}
+	fmtSrc, err := format.Source(dropUnusedManagedImports(src))
+	if err != nil {
+		_, _ = opts.DryRun.Write(src)
+		die("gofmt/format failed: " + err.Error())
+	}
+	_, err = opts.DryRun.Write(fmtSrc)
+	must(err)
+}
+
 func writeFormatted(out string, src []byte) {
-	fmtSrc, err := format.Source(src)
+	fmtSrc, err := format.Source(dropUnusedManagedImports(src))
 	if err != nil {
 		_ = os.WriteFile(out, src, 0o644)
 		die("gofmt/format failed: " + err.Error())
@@ -765,6 +1970,21 @@ func exportName(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
 
+// docLines renders a spec description as Go comment lines ("// ..."), one per
+// line of text, without a trailing newline. Blank lines become "//".
+func docLines(desc string) string {
+	lines := strings.Split(strings.TrimSpace(desc), "\n")
+	for i, l := range lines {
+		l = strings.TrimRight(l, " \t\r")
+		if l == "" {
+			lines[i] = "//"
+			continue
+		}
+		lines[i] = "// " + l
+	}
+	return strings.Join(lines, "\n")
+}
+
 // methodUsesPkgQualifier returns true if any method param/return contains "pkg."
 func methodUsesPkgQualifier(methods []MethodSpec, pkg string) bool {
 	needle := pkg + "."
@@ -779,409 +1999,11 @@ func methodUsesPkgQualifier(methods []MethodSpec, pkg string) bool {
 				return true
 			}
 		}
-	}
-	return false
-}
-
-// -------------------------
-// Templates
-// -------------------------
-
-var serviceTpl = template.Must(
-	template.New("service").
-		Funcs(template.FuncMap{
-			"isError": func(t string) bool { return t == "error" },
-			"minus1":  func(n int) int { return n - 1 },
-		}).
-		Parse(`// Code generated by (di v2); DO NOT EDIT.
-// Spec: {{.SpecPath}}
-// Spec-SHA256: {{.SpecHash}}
-
-package {{.Spec.Package}}
-
-import (
-{{- range .Imports }}
-	{{- if .Name }}
-	{{ .Name }} "{{ .Path }}"
-	{{- else }}
-	"{{ .Path }}"
-	{{- end }}
-{{- end }}
-)
-
-// {{.Spec.FacadeName}}InjectPolicyOnOverwrite controls behavior when a required dep is injected twice.
-// NOTE: generated as a var to allow unit tests to cover all branches.
-var {{.Spec.FacadeName}}InjectPolicyOnOverwrite = "{{.Spec.InjectPolicy.OnOverwrite}}"
-
-{{- if gt (len .Spec.Optional) 0 }}
-
-// Optional registry keys for {{.Spec.FacadeName}}.
-const (
-{{- range .Spec.Optional }}
-	{{ $.Spec.FacadeName }}Optional{{ .Name }}Key = "{{ .RegistryKey }}"
-{{- end }}
-)
-
-{{- end }}
-
-type {{.Spec.FacadeName}} struct {
-{{- if .Spec.Config.Enabled }}
-	{{ .Spec.Config.FieldName }} {{ .Spec.Config.Type }}
-{{- end }}
-	svc *{{.Spec.ImplType}}
-
-	injected map[string]bool
-
-	// Optional wiring diagnostics (best-effort)
-	optionalResolved map[string]string
-	optionalMissing  map[string]string
-}
-
-// {{.Spec.PublicConstructorName}} creates a new builder/facade.
-// You must call Build()/BuildWith()/MustBuild() before calling business methods.
-{{- if .Spec.Config.Enabled }}
-func {{.Spec.PublicConstructorName}}({{ .Spec.Config.ParamName }} {{ .Spec.Config.Type }}) *{{.Spec.FacadeName}} {
-	return &{{.Spec.FacadeName}}{
-		{{ .Spec.Config.FieldName }}: {{ .Spec.Config.ParamName }},
-		svc:              {{.Spec.Constructor}}({{ .Spec.Config.ParamName }}),
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-}
-{{- else }}
-func {{.Spec.PublicConstructorName}}() *{{.Spec.FacadeName}} {
-	return &{{.Spec.FacadeName}}{
-		svc:              {{.Spec.Constructor}}(),
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-}
-{{- end }}
-
-// Clone copies the builder with the current injected state.
-// Useful for tests and branching wiring paths.
-func (b *{{.Spec.FacadeName}}) Clone() *{{.Spec.FacadeName}} {
-	nb := &{{.Spec.FacadeName}}{
-{{- if .Spec.Config.Enabled }}
-		{{ .Spec.Config.FieldName }}: b.{{ .Spec.Config.FieldName }},
-{{- end }}
-		svc:              b.svc,
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-	for k, v := range b.injected {
-		nb.injected[k] = v
-	}
-	for k, v := range b.optionalResolved {
-		nb.optionalResolved[k] = v
-	}
-	for k, v := range b.optionalMissing {
-		nb.optionalMissing[k] = v
-	}
-	return nb
-}
-
-// Reset discards injected bookkeeping and recreates the underlying implementation.
-func (b *{{.Spec.FacadeName}}) Reset() *{{.Spec.FacadeName}} {
-{{- if .Spec.Config.Enabled }}
-	b.svc = {{.Spec.Constructor}}(b.{{ .Spec.Config.FieldName }})
-{{- else }}
-	b.svc = {{.Spec.Constructor}}()
-{{- end }}
-	b.injected = map[string]bool{}
-	b.optionalResolved = map[string]string{}
-	b.optionalMissing = map[string]string{}
-	return b
-}
-
-// UnsafeImpl returns the underlying implementation pointer for composition root wiring.
-// It must NOT be used to call business methods before Build()/MustBuild().
-func (b *{{.Spec.FacadeName}}) UnsafeImpl() *{{.Spec.ImplType}} { return b.svc }
-
-// Inject allows custom wiring for advanced usage.
-// Prefer InjectX methods for required deps.
-func (b *{{.Spec.FacadeName}}) Inject(fn func(*{{.Spec.ImplType}})) *{{.Spec.FacadeName}} {
-	if fn != nil {
-		fn(b.svc)
-	}
-	return b
-}
-
-{{ range .Spec.Required }}
-
-// TryInject{{ .Name }} injects the required dependency {{ .Name }}.
-// Unlike Inject{{ .Name }}, it returns an error instead of panicking.
-func (b *{{ $.Spec.FacadeName }}) TryInject{{ .Name }}(dep {{ .Type }}) (*{{ $.Spec.FacadeName }}, error) {
-	switch {{ $.Spec.FacadeName }}InjectPolicyOnOverwrite {
-	case "error":
-		if b.injected["{{ .Name }}"] {
-			return nil, fmt.Errorf("{{ $.Spec.FacadeName }}: duplicate inject {{ .Name }}")
-		}
-	case "ignore":
-		if b.injected["{{ .Name }}"] {
-			return b, nil
-		}
-	case "overwrite":
-		// allow overwriting
-	default:
-		return nil, fmt.Errorf("{{ $.Spec.FacadeName }}: invalid injectPolicy.onOverwrite=%s", {{ $.Spec.FacadeName }}InjectPolicyOnOverwrite)
-	}
-	b.svc.{{ .Field }} = dep
-	b.injected["{{ .Name }}"] = true
-	return b, nil
-}
-
-// Inject{{ .Name }} injects the required dependency {{ .Name }} and panics on policy violations.
-// Prefer TryInject{{ .Name }} for safer wiring in tests.
-func (b *{{ $.Spec.FacadeName }}) Inject{{ .Name }}(dep {{ .Type }}) *{{ $.Spec.FacadeName }} {
-	nb, err := b.TryInject{{ .Name }}(dep)
-	if err != nil {
-		panic(err)
-	}
-	return nb
-}
-{{ end }}
-
-// Missing returns the list of missing required dependency names at this moment.
-// This is useful for debug UX before calling Build().
-func (b *{{.Spec.FacadeName}}) Missing() []string {
-	missing := []string{}
-{{- range .Spec.Required }}
-	if b.svc.{{ .Field }} == nil {
-		missing = append(missing, "{{ .Name }}")
-	}
-{{- end }}
-	return missing
-}
-
-// Explain returns a human-friendly summary of the wiring state.
-func (b *{{.Spec.FacadeName}}) Explain() string {
-	var sb strings.Builder
-	m := b.Missing()
-	if len(m) == 0 {
-		sb.WriteString("required: complete\n")
-	} else {
-		sb.WriteString(fmt.Sprintf("required: missing=%v\n", m))
-	}
-{{- if gt (len .Spec.Optional) 0 }}
-	if len(b.optionalResolved) > 0 {
-		sb.WriteString("optional: resolved\n")
-		for k, v := range b.optionalResolved {
-			sb.WriteString(fmt.Sprintf("  - %s => %s\n", k, v))
-		}
-	}
-	if len(b.optionalMissing) > 0 {
-		sb.WriteString("optional: missing\n")
-		for k, v := range b.optionalMissing {
-			sb.WriteString(fmt.Sprintf("  - %s => %s\n", k, v))
-		}
-	}
-{{- end }}
-	return sb.String()
-}
-
-func (b *{{.Spec.FacadeName}}) Build() (*{{.Spec.ImplType}}, error) {
-	return b.buildScoped("Build", nil)
-}
-
-// NOTE: Registry.Resolve must be (val any, ok bool, err error)
-func (b *{{.Spec.FacadeName}}) BuildWith(reg di.Registry) (*{{.Spec.ImplType}}, error) {
-{{ if gt (len .Spec.Optional) 0 }}
-	if reg != nil {
-		// IMPORTANT: declare once; reuse for each optional dep to avoid ":=" redeclare errors.
-		var (
-			v   any
-			ok  bool
-			err error
-		)
-
-{{ range .Spec.Optional }}
-		v, ok, err = reg.Resolve({{ if $.Spec.Config.Enabled }}b.{{ $.Spec.Config.FieldName }}{{ else }}nil{{ end }}, "{{ .RegistryKey }}")
-		if err != nil {
-			return nil, fmt.Errorf("{{ $.Spec.FacadeName }}: optional dep {{ .Name }} resolve failed: %w", err)
-		}
-		if ok {
-			casted, ok := v.({{ .Type }})
-			if !ok {
-				return nil, fmt.Errorf("{{ $.Spec.FacadeName }}: optional dep {{ .Name }} key={{ .RegistryKey }}: want {{ .Type }}, got %T", v)
-			}
-{{ if eq .Apply.Kind "setter" }}
-			b.svc.{{ .Apply.Name }}(casted)
-{{ else }}
-			b.svc.{{ .Apply.Name }} = casted
-{{ end }}
-			b.optionalResolved["{{ .RegistryKey }}"] = fmt.Sprintf("%T", v)
-		} else {
-{{- if ne (print .DefaultExpr) "" }}
-			def := {{ .DefaultExpr }}
-{{- if eq .Apply.Kind "setter" }}
-			b.svc.{{ .Apply.Name }}(def)
-{{- else }}
-			b.svc.{{ .Apply.Name }} = def
-{{- end }}
-			b.optionalMissing["{{ .RegistryKey }}"] = "used defaultExpr"
-{{- else }}
-			b.optionalMissing["{{ .RegistryKey }}"] = "not provided"
-{{- end }}
-		}
-{{ end }}
-	}
-{{ end }}
-	return b.buildScoped("BuildWith", nil)
-}
-
-func (b *{{.Spec.FacadeName}}) MustBuild() *{{.Spec.ImplType}} {
-	svc, err := b.Build()
-	if err != nil {
-		panic(err)
-	}
-	return svc
-}
-
-func (b *{{.Spec.FacadeName}}) buildScoped(ctx string, reqNames []string) (*{{.Spec.ImplType}}, error) {
-	missing := []string{}
-
-{{ range .Spec.Required }}
-	isMissing{{ .Name }} := b.svc.{{ .Field }} == nil
-{{ end }}
-
-	check := func(name string, isMissing bool) {
-		if isMissing {
-			missing = append(missing, name)
-		}
-	}
-
-	if reqNames == nil {
-{{ range .Spec.Required }}
-		check("{{ .Name }}", isMissing{{ .Name }})
-{{ end }}
-	} else {
-		for _, n := range reqNames {
-			switch n {
-{{ range .Spec.Required }}
-			case "{{ .Name }}":
-				check("{{ .Name }}", isMissing{{ .Name }})
-{{ end }}
+		for _, d := range m.Decorate {
+			if strings.Contains(d, needle) {
+				return true
 			}
 		}
 	}
-
-	if len(missing) > 0 {
-		return nil, fmt.Errorf("%s: wiring incomplete (ctx=%s, missing=%v, spec=%s)",
-			"{{ .Spec.FacadeName }}", ctx, missing, "{{ .SpecHash }}")
-	}
-	return b.svc, nil
-}
-
-{{ range .Spec.Methods }}
-func (b *{{ $.Spec.FacadeName }}) {{ .Name }}(
-{{- range .Params }}
-	{{ .Name }} {{ .Type }},
-{{- end }}
-){{ if eq (len .Returns) 0 }}{{ else if eq (len .Returns) 1 }} {{ (index .Returns 0).Type }}{{ else }} ({{ range $i, $r := .Returns }}{{ if gt $i 0 }}, {{ end }}{{ $r.Type }}{{ end }}){{ end }} {
-	{{- $m := . }}
-	svc, err := b.buildScoped("{{ $m.Name }}", []string{
-{{- range $m.Requires }}
-		"{{ . }}",
-{{- end }}
-	})
-	if err != nil {
-{{- if eq (len $m.Returns) 0 }}
-		return
-{{- else if eq (len $m.Returns) 1 }}
-{{- if isError (index $m.Returns 0).Type }}
-		return err
-{{- else }}
-		var zero {{ (index $m.Returns 0).Type }}
-		return zero
-{{- end }}
-{{- else }}
-		{{- $last := index $m.Returns (minus1 (len $m.Returns)) }}
-		{{- if not (isError $last.Type) }}
-		panic(fmt.Errorf("di2: method {{ $m.Name }} last return must be error for safe codegen"))
-		{{- end }}
-
-{{- range $i, $r := $m.Returns }}
-{{- if lt $i (minus1 (len $m.Returns)) }}
-		var zero{{ $i }} {{ $r.Type }}
-{{- end }}
-{{- end }}
-
-		return {{ range $i, $r := $m.Returns }}{{ if lt $i (minus1 (len $m.Returns)) }}zero{{ $i }}, {{ end }}{{ end }}err
-{{- end }}
-	}
-
-	return svc.{{ $m.Name }}(
-{{- range $m.Params }}
-		{{ .Name }},
-{{- end }}
-	)
-}
-{{ end }}
-`),
-)
-
-var graphTpl = template.Must(
-	template.New("graph").
-		Funcs(template.FuncMap{"export": exportName}).
-		Parse(`// Code generated by (di v2); DO NOT EDIT.
-// Graph: {{.GraphPath}}
-// Graph-SHA256: {{.GraphHash}}
-
-package {{.G.Package}}
-
-import (
-{{- range .Imports }}
-	{{- if .Name }}
-	{{ .Name }} "{{ .Path }}"
-	{{- else }}
-	"{{ .Path }}"
-	{{- end }}
-{{- end }}
-)
-
-{{- range .G.Roots}}
-{{- $root := . }}
-
-type {{.Name}}Result struct {
-	{{- range .Services}}
-	{{ export .Var }} *{{.ImplType}}
-	{{- end}}
-}
-
-{{- if $.G.Config.Enabled }}
-func {{.Name}}({{ $.G.Config.ParamName }} {{ $.G.Config.Type }}, reg di.Registry) ({{.Name}}Result, error) {
-{{- else }}
-func {{.Name}}(reg di.Registry) ({{.Name}}Result, error) {
-{{- end }}
-	var res {{.Name}}Result
-
-	{{- range .Services}}
-	{{.Var}}B := {{.FacadeCtor}}({{ if $.G.Config.Enabled }}{{ $.G.Config.ParamName }}{{ end }})
-	{{- end}}
-
-	{{- range .Wiring}}
-	{{.To}}B.{{.Call}}({{.ArgFrom}}B.UnsafeImpl())
-	{{- end}}
-
-	{{- range .Services}}
-	{{- if $root.BuildWithRegistry}}
-	{{.Var}}Svc, err := {{.Var}}B.BuildWith(reg)
-	{{- else}}
-	{{.Var}}Svc, err := {{.Var}}B.Build()
-	{{- end}}
-	if err != nil {
-		return res, fmt.Errorf("{{ $root.Name }}: build {{.Var}} failed: %w", err)
-	}
-	res.{{ export .Var }} = {{.Var}}Svc
-	{{- end}}
-
-	return res, nil
+	return false
 }
-
-{{- end}}
-`),
-)
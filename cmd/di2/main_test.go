@@ -4,6 +4,8 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"io"
+	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -11,6 +13,13 @@ import (
 	"testing"
 )
 
+// TestMain keeps advisory spec warnings out of the test output; tests that check
+// them swap specWarnOut for a buffer.
+func TestMain(m *testing.M) {
+	specWarnOut = io.Discard
+	os.Exit(m.Run())
+}
+
 // -------------------------
 // applyConfigDefaults
 // -------------------------
@@ -141,13 +150,18 @@ func TestValidateServiceSpec(t *testing.T) {
 					Apply:       OptionalApply{Kind: "wat", Name: "opt"},
 				}}
 			},
-			wantPanic: "optional.apply.kind must be 'setter' or 'field'",
+			wantPanic: "optional.apply.kind must be 'setter', 'field' or 'call'",
 		},
 		{
 			name:      "method_missing_name",
 			mutate:    func(s *ServiceSpec) { s.Methods = []MethodSpec{{Name: ""}} },
 			wantPanic: "method must have name",
 		},
+		{
+			name:      "method_requires_unknown_dep",
+			mutate:    func(s *ServiceSpec) { s.Methods = []MethodSpec{{Name: "Do", Requires: []string{"A", "Ghost"}}} },
+			wantPanic: "method Do requires unknown required dep Ghost",
+		},
 		{
 			name:      "inject_policy_invalid",
 			mutate:    func(s *ServiceSpec) { s.InjectPolicy.OnOverwrite = "nope" },
@@ -186,21 +200,7 @@ func TestValidateGraphSpec(t *testing.T) {
 			name: "valid_ok",
 			g: GraphSpec{
 				Package: "p",
-				Roots: []struct {
-					Name              string `json:"name"`
-					BuildWithRegistry bool   `json:"buildWithRegistry"`
-					Services          []struct {
-						Var        string `json:"var"`
-						FacadeCtor string `json:"facadeCtor"`
-						FacadeType string `json:"facadeType"`
-						ImplType   string `json:"implType"`
-					} `json:"services"`
-					Wiring []struct {
-						To      string `json:"to"`
-						Call    string `json:"call"`
-						ArgFrom string `json:"argFrom"`
-					} `json:"wiring"`
-				}{
+				Roots: []GraphRoot{
 					{Name: "Root"},
 				},
 			},
@@ -210,21 +210,7 @@ func TestValidateGraphSpec(t *testing.T) {
 			name: "missing_package",
 			g: GraphSpec{
 				Package: " ",
-				Roots: []struct {
-					Name              string `json:"name"`
-					BuildWithRegistry bool   `json:"buildWithRegistry"`
-					Services          []struct {
-						Var        string `json:"var"`
-						FacadeCtor string `json:"facadeCtor"`
-						FacadeType string `json:"facadeType"`
-						ImplType   string `json:"implType"`
-					} `json:"services"`
-					Wiring []struct {
-						To      string `json:"to"`
-						Call    string `json:"call"`
-						ArgFrom string `json:"argFrom"`
-					} `json:"wiring"`
-				}{
+				Roots: []GraphRoot{
 					{Name: "Root"},
 				},
 			},
@@ -431,10 +417,10 @@ import (
 	}
 
 	want := []GoImport{
-		{Name: "config", Path: "example.com/proj/config"},
-		{Name: "di", Path: "example.com/proj/di"},
 		{Name: "", Path: "fmt"},
 		{Name: "strings", Path: "strings"},
+		{Name: "config", Path: "example.com/proj/config"},
+		{Name: "di", Path: "example.com/proj/di"},
 	}
 	if !reflect.DeepEqual(imps, want) {
 		t.Fatalf("got %#v\nwant %#v", imps, want)
@@ -559,6 +545,31 @@ func TestDedupeAndSortImports(t *testing.T) {
 	}
 }
 
+func TestImportLessAndBreak(t *testing.T) {
+	t.Parallel()
+
+	imps := dedupeAndSortImports([]GoImport{
+		{Name: "di", Path: "github.com/acme/di"},
+		{Path: "maps"},
+		{Path: "log/slog"},
+		{Name: "config", Path: "example.com/proj/config"},
+	})
+	want := []GoImport{
+		{Path: "log/slog"},
+		{Path: "maps"},
+		{Name: "config", Path: "example.com/proj/config"},
+		{Name: "di", Path: "github.com/acme/di"},
+	}
+	if !reflect.DeepEqual(imps, want) {
+		t.Fatalf("got %#v want %#v", imps, want)
+	}
+	for i, wantBreak := range []bool{false, false, true, false} {
+		if got := importBreak(imps, i); got != wantBreak {
+			t.Fatalf("importBreak(%d) = %v, want %v", i, got, wantBreak)
+		}
+	}
+}
+
 func TestReadImportsFromExistingOut(t *testing.T) {
 	t.Parallel()
 
@@ -625,11 +636,11 @@ func TestMergeImports_DedupesAndSorts(t *testing.T) {
 
 	got := mergeImports(required, preserved)
 	want := []GoImport{
+		{Name: "", Path: "fmt"},
+		{Name: "", Path: "strings"},
 		{Name: "config", Path: "example.com/proj/config"},
 		{Name: "di", Path: "example.com/proj/di"},
 		{Name: "di2", Path: "example.com/proj/di"},
-		{Name: "", Path: "fmt"},
-		{Name: "", Path: "strings"},
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("got %#v want %#v", got, want)
@@ -829,21 +840,7 @@ func _() { _ = di.Registry(nil) }`)
 					Package: "p",
 					Imports: Imports{Config: "should_be_cleared"},
 					Config:  ConfigSpec{Enabled: false},
-					Roots: []struct {
-						Name              string `json:"name"`
-						BuildWithRegistry bool   `json:"buildWithRegistry"`
-						Services          []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						} `json:"services"`
-						Wiring []struct {
-							To      string `json:"to"`
-							Call    string `json:"call"`
-							ArgFrom string `json:"argFrom"`
-						} `json:"wiring"`
-					}{
+					Roots: []GraphRoot{
 						{Name: "Root"},
 					},
 				}
@@ -865,21 +862,7 @@ func _() { _ = di.Registry(nil) }`)
 				g := &GraphSpec{
 					Package: "p",
 					Config:  ConfigSpec{Enabled: true},
-					Roots: []struct {
-						Name              string `json:"name"`
-						BuildWithRegistry bool   `json:"buildWithRegistry"`
-						Services          []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						} `json:"services"`
-						Wiring []struct {
-							To      string `json:"to"`
-							Call    string `json:"call"`
-							ArgFrom string `json:"argFrom"`
-						} `json:"wiring"`
-					}{
+					Roots: []GraphRoot{
 						{Name: "Root"},
 					},
 				}
@@ -894,21 +877,7 @@ func _() { _ = di.Registry(nil) }`)
 				g := &GraphSpec{
 					Package: "p",
 					Config:  ConfigSpec{Enabled: false},
-					Roots: []struct {
-						Name              string `json:"name"`
-						BuildWithRegistry bool   `json:"buildWithRegistry"`
-						Services          []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						} `json:"services"`
-						Wiring []struct {
-							To      string `json:"to"`
-							Call    string `json:"call"`
-							ArgFrom string `json:"argFrom"`
-						} `json:"wiring"`
-					}{
+					Roots: []GraphRoot{
 						{Name: "Root"},
 					},
 				}
@@ -1093,21 +1062,7 @@ func TestRun_Routing_SpecAndGraphHappyPaths(t *testing.T) {
 		g := GraphSpec{
 			Package: "p",
 			Config:  ConfigSpec{Enabled: false},
-			Roots: []struct {
-				Name              string `json:"name"`
-				BuildWithRegistry bool   `json:"buildWithRegistry"`
-				Services          []struct {
-					Var        string `json:"var"`
-					FacadeCtor string `json:"facadeCtor"`
-					FacadeType string `json:"facadeType"`
-					ImplType   string `json:"implType"`
-				} `json:"services"`
-				Wiring []struct {
-					To      string `json:"to"`
-					Call    string `json:"call"`
-					ArgFrom string `json:"argFrom"`
-				} `json:"wiring"`
-			}{
+			Roots: []GraphRoot{
 				{Name: "Root"},
 			},
 		}
@@ -1213,13 +1168,13 @@ import keep "example.com/keep/me"`)
 			}
 			mustWriteFile(t, specPath, string(raw))
 
-			genService(specPath, outPath)
+			genService(specPath, outPath, genOptions{})
 			out := p.read("svc.gen.go")
 
 			if !strings.Contains(out, "Spec: "+filepath.ToSlash(specPath)) {
 				t.Fatalf("expected Spec path in header")
 			}
-			if !strings.Contains(out, "Spec-SHA256: "+sha256Hex(raw)) {
+			if !strings.Contains(out, "Spec-SHA256: "+specSHA256(raw)) {
 				t.Fatalf("expected Spec hash in header")
 			}
 
@@ -1228,9 +1183,12 @@ import keep "example.com/keep/me"`)
 			}
 
 			assertHasImport(t, out, "fmt")
-			assertHasImport(t, out, "strings")
+			assertNotHasImport(t, out, "strings") // Explain lives in the di runtime
 			assertHasImport(t, out, "context")
 			assertHasImport(t, out, "time")
+			if !strings.Contains(out, "\t\"time\"\n\n\t") {
+				t.Fatalf("expected stdlib imports in their own group:\n%s", out)
+			}
 			if !strings.Contains(out, `di "example.com/proj/di"`) {
 				t.Fatalf("expected di import inferred from sources")
 			}
@@ -1242,6 +1200,10 @@ import keep "example.com/keep/me"`)
 				if !strings.Contains(out, "func NewFooV2(cfg config.Config) *FooV2") {
 					t.Fatalf("expected ctor signature with cfg when enabled")
 				}
+				assertContainsInOrder(t, out,
+					`cfgErr:   di.ValidateConfig("FooV2", &cfg),`,
+					"func (b *FooV2) buildScoped(ctx string, need uint64) (*FooImpl, error) {\n\tif b.cfgErr != nil {\n\t\treturn nil, b.cfgErr\n\t}",
+				)
 			} else {
 				if strings.Contains(out, `config "example.com/proj/config"`) {
 					t.Fatalf("did not expect config import when disabled")
@@ -1249,6 +1211,9 @@ import keep "example.com/keep/me"`)
 				if !strings.Contains(out, "func NewFooV2() *FooV2") {
 					t.Fatalf("expected ctor signature without cfg when disabled")
 				}
+				if strings.Contains(out, "cfgErr") {
+					t.Fatalf("did not expect config validation when disabled")
+				}
 			}
 
 			if !strings.Contains(out, `var FooV2InjectPolicyOnOverwrite = "error"`) {
@@ -1317,51 +1282,23 @@ import keep "example.com/keep/me"`)
 			g := GraphSpec{
 				Package: "p",
 				Config:  ConfigSpec{Enabled: tc.configEnabled},
-				Roots: []struct {
-					Name              string `json:"name"`
-					BuildWithRegistry bool   `json:"buildWithRegistry"`
-					Services          []struct {
-						Var        string `json:"var"`
-						FacadeCtor string `json:"facadeCtor"`
-						FacadeType string `json:"facadeType"`
-						ImplType   string `json:"implType"`
-					} `json:"services"`
-					Wiring []struct {
-						To      string `json:"to"`
-						Call    string `json:"call"`
-						ArgFrom string `json:"argFrom"`
-					} `json:"wiring"`
-				}{
+				Roots: []GraphRoot{
 					{
 						Name:              "ZRoot",
 						BuildWithRegistry: false,
-						Services: []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						}{
+						Services: []GraphService{
 							{Var: "b", FacadeCtor: "NewB", FacadeType: "B", ImplType: "BImpl"},
 							{Var: "a", FacadeCtor: "NewA", FacadeType: "A", ImplType: "AImpl"},
 						},
-						Wiring: []struct {
-							To      string `json:"to"`
-							Call    string `json:"call"`
-							ArgFrom string `json:"argFrom"`
-						}{
+						Wiring: []GraphWiring{
 							{To: "b", Call: "InjectX", ArgFrom: "a"},
-							{To: "a", Call: "InjectY", ArgFrom: "b"},
+							{To: "a", Call: "InjectY", ArgFrom: "b", Cycle: true},
 						},
 					},
 					{
 						Name:              "ARoot",
 						BuildWithRegistry: true,
-						Services: []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						}{
+						Services: []GraphService{
 							{Var: "x", FacadeCtor: "NewX", FacadeType: "X", ImplType: "XImpl"},
 						},
 					},
@@ -1374,13 +1311,13 @@ import keep "example.com/keep/me"`)
 			}
 			mustWriteFile(t, graphPath, string(raw))
 
-			genGraph(graphPath, outPath)
+			genGraph(graphPath, outPath, genOptions{})
 			out := p.read("graph.gen.go")
 
 			if !strings.Contains(out, "Graph: "+filepath.ToSlash(graphPath)) {
 				t.Fatalf("expected Graph path in header")
 			}
-			if !strings.Contains(out, "Graph-SHA256: "+sha256Hex(raw)) {
+			if !strings.Contains(out, "Graph-SHA256: "+specSHA256(raw)) {
 				t.Fatalf("expected Graph hash in header")
 			}
 
@@ -1414,3 +1351,89 @@ import keep "example.com/keep/me"`)
 		})
 	}
 }
+
+// -------------------------
+// Spec ordering
+// -------------------------
+
+func TestSortSpecEntries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		in       []RequiredDep
+		preserve bool
+		want     string
+	}{
+		{name: "alphabetical_default", in: []RequiredDep{{Name: "C"}, {Name: "A"}, {Name: "B"}}, want: "A,B,C"},
+		{name: "preserve_spec_order", in: []RequiredDep{{Name: "C"}, {Name: "A"}, {Name: "B"}}, preserve: true, want: "C,A,B"},
+		{name: "order_then_name", in: []RequiredDep{{Name: "A", Order: 2}, {Name: "C", Order: 1}, {Name: "B", Order: 1}, {Name: "Z", Order: -1}}, want: "Z,B,C,A"},
+		{name: "order_then_spec_order", in: []RequiredDep{{Name: "A", Order: 2}, {Name: "C", Order: 1}, {Name: "B", Order: 1}}, preserve: true, want: "C,B,A"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sortSpecEntries(tt.in, func(d RequiredDep) (int, string) { return d.Order, d.Name }, tt.preserve)
+			var names []string
+			for _, d := range tt.in {
+				names = append(names, d.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Fatalf("got %s want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenService_PreserveOrder(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore", "preserveOrder": true,
+  "required": [
+    { "name": "Zeta", "field": "zeta", "type": "*Zeta", "nilable": true },
+    { "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true },
+    { "name": "Last", "field": "last", "type": "*Last", "nilable": true, "order": 1 }
+  ],
+  "methods": [
+    { "name": "Write", "requires": ["Zeta"] },
+    { "name": "Read", "requires": ["Alpha"] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		`"Zeta",`, `"Alpha",`, `"Last",`,
+		"func (b *CoreV4) InjectZeta(", "func (b *CoreV4) InjectAlpha(", "func (b *CoreV4) InjectLast(",
+		"func (b *CoreV4) Write(", "func (b *CoreV4) Read(",
+	)
+}
+
+func TestGenGraph_PreserveOrder(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p", "preserveOrder": true,
+  "roots": [
+    { "name": "Zed", "services": [
+      { "var": "z", "facadeCtor": "NewZV4", "facadeType": "*ZV4", "implType": "Z" },
+      { "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }
+    ] },
+    { "name": "App", "services": [
+      { "var": "m", "facadeCtor": "NewMV4", "facadeType": "*MV4", "implType": "M" }
+    ] }
+  ]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out, "type ZedResult struct", "Z *Z", "A *A", "type AppResult struct")
+}
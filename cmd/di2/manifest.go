@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/sghaida/odi/di"
+)
+
+// -------------------------
+// Embedded wiring manifest (graph "manifest")
+// -------------------------
+
+// validateGraphManifest checks the graph's manifest file name: go:embed only
+// reaches files in the package directory, so it must be a plain .json name.
+func validateGraphManifest(name string) {
+	if name == "" {
+		return
+	}
+	if filepath.Base(name) != name || filepath.Ext(name) != ".json" || name[0] == '.' || name[0] == '_' {
+		die(fmt.Sprintf("graph manifest must be a .json file name in the output directory (got %q)", name))
+	}
+}
+
+// generatorVersion is the di2 module version, "(devel)" when run from a checkout.
+func generatorVersion() string {
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		return bi.Main.Version
+	}
+	return "(devel)"
+}
+
+// graphManifest describes the prepared graph g. Linked service specs are read
+// for their hash and deps; their paths are made relative to pkgDir.
+func graphManifest(g GraphSpec, graphPath, pkgDir, graphHash string) di.GraphManifest {
+	m := di.GraphManifest{
+		Generator: generatorVersion(),
+		Package:   g.Package,
+		Graph:     filepath.ToSlash(graphPath),
+		GraphHash: graphHash,
+	}
+	for _, r := range g.Roots {
+		mr := di.ManifestRoot{Name: r.Name, Services: []di.ManifestService{}, Wiring: []di.ManifestWiring{}}
+		for _, s := range r.BuildOrder {
+			mr.Services = append(mr.Services, manifestService(s, graphPath, pkgDir))
+		}
+		for _, w := range r.Wiring {
+			mr.Wiring = append(mr.Wiring, di.ManifestWiring{To: w.To, Call: w.Call, ArgFrom: w.ArgFrom, When: w.When})
+		}
+		m.Roots = append(m.Roots, mr)
+	}
+	return m
+}
+
+func manifestService(s GraphService, graphPath, pkgDir string) di.ManifestService {
+	ms := di.ManifestService{
+		Var:        s.Var,
+		FacadeType: s.FacadeType,
+		ImplType:   s.ImplType,
+		When:       s.When,
+		Consumer:   s.Consumer,
+	}
+	if s.Spec == "" {
+		return ms
+	}
+	specPath := linkedSpecPath(graphPath, s.Spec)
+	spec, raw := readServiceSpec(specPath)
+	ms.Spec = filepath.ToSlash(specPath)
+	if rel, err := filepath.Rel(pkgDir, specPath); err == nil {
+		ms.Spec = filepath.ToSlash(rel)
+	}
+	ms.SpecHash = specSHA256(raw)
+
+	// same order as the generated facade's WiringManifest
+	sortSpecEntries(spec.Required, func(d RequiredDep) (int, string) { return d.Order, d.Name }, spec.PreserveOrder)
+	sortSpecEntries(spec.Optional, func(d OptionalDep) (int, string) { return d.Order, d.Name }, spec.PreserveOrder)
+	for _, d := range spec.Required {
+		ms.Deps = append(ms.Deps, di.ManifestDep{Name: d.Name, Type: d.Type, Tags: d.Tags})
+	}
+	for _, o := range spec.Optional {
+		ms.Deps = append(ms.Deps, di.ManifestDep{Name: o.Name, Type: o.Type, Optional: true, RegistryKey: o.RegistryKey, Tags: o.Tags})
+	}
+	return ms
+}
+
+// writeGraphManifest writes the manifest of g next to pkgOut. Dry runs print
+// only Go code, so they skip it.
+func writeGraphManifest(g GraphSpec, graphPath, pkgOut, graphHash string, opts genOptions) {
+	if opts.DryRun != nil {
+		return
+	}
+	pkgDir := filepath.Dir(pkgOut)
+	raw, err := json.MarshalIndent(graphManifest(g, graphPath, pkgDir, graphHash), "", "  ")
+	must(err)
+	manifestPath := filepath.Join(pkgDir, g.Manifest)
+	must(os.WriteFile(manifestPath, append(raw, '\n'), 0o644))
+	opts.Provenance.output(manifestPath)
+}
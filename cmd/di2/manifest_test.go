@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sghaida/odi/di"
+)
+
+// -------------------------
+// Embedded wiring manifest
+// -------------------------
+
+func manifestGraph() GraphSpec {
+	return GraphSpec{
+		Package:  "p",
+		Manifest: "wiring.json",
+		Roots: []GraphRoot{
+			{
+				Name: "App",
+				Services: []GraphService{
+					{Var: "core", FacadeCtor: "NewCoreV4", FacadeType: "*CoreV4", ImplType: "Core", Spec: "core.inject.json"},
+					{Var: "alpha", FacadeCtor: "NewAlphaV4", FacadeType: "*AlphaV4", ImplType: "Alpha", When: "true"},
+				},
+				Wiring: []GraphWiring{{To: "core", Call: "InjectAlpha", ArgFrom: "alpha"}},
+			},
+			{
+				Name:     "Tool",
+				Services: []GraphService{{Var: "alpha", FacadeCtor: "NewAlphaV4", FacadeType: "*AlphaV4", ImplType: "Alpha"}},
+			},
+		},
+	}
+}
+
+// TestGenGraph_Manifest verifies the manifest file describes the graph and the
+// generated graph embeds and registers it.
+func TestGenGraph_Manifest(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, filepath.Join("specs", "core.inject.json"), "")
+
+	raw, err := json.Marshal(manifestGraph())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	graphPath := p.write(filepath.Join("specs", "graph.json"), string(raw))
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertHasImport(t, out, "embed")
+	assertContainsInOrder(t, out,
+		"//go:embed wiring.json\nvar wiringManifest []byte",
+		"func init() { di.RegisterManifest(wiringManifest) }",
+	)
+
+	var m di.GraphManifest
+	if err := json.Unmarshal([]byte(p.read("wiring.json")), &m); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if m.Package != "p" || m.Generator == "" || m.GraphHash == "" || len(m.Roots) != 2 {
+		t.Fatalf("unexpected manifest header: %+v", m)
+	}
+	app := m.Roots[0]
+	if app.Name != "App" || len(app.Services) != 2 || len(app.Wiring) != 1 {
+		t.Fatalf("unexpected root: %+v", app)
+	}
+	alpha, core := app.Services[0], app.Services[1] // build order
+	if alpha.Var != "alpha" || alpha.When != "true" || alpha.Spec != "" || alpha.Deps != nil {
+		t.Fatalf("unexpected alpha: %+v", alpha)
+	}
+	if core.Spec != "specs/core.inject.json" || len(core.SpecHash) != 64 {
+		t.Fatalf("linked spec should be relative to the package with its hash: %+v", core)
+	}
+	if len(core.Deps) != 1 || !reflect.DeepEqual(core.Deps[0], di.ManifestDep{Name: "Alpha", Type: "*Alpha"}) {
+		t.Fatalf("unexpected core deps: %+v", core.Deps)
+	}
+	if w := app.Wiring[0]; w != (di.ManifestWiring{To: "core", Call: "InjectAlpha", ArgFrom: "alpha"}) {
+		t.Fatalf("unexpected wiring: %+v", w)
+	}
+}
+
+// TestGenGraph_ManifestSplit verifies split output embeds the manifest once.
+func TestGenGraph_ManifestSplit(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeHealthSpec(t, p, filepath.Join("specs", "core.inject.json"), "")
+
+	raw, err := json.Marshal(manifestGraph())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	genGraph(p.write(filepath.Join("specs", "graph.json"), string(raw)), p.out(""), genOptions{Split: true})
+
+	app, tool := p.read("graph_app.gen.go"), p.read("graph_tool.gen.go")
+	assertHasImport(t, app, "embed")
+	if !strings.Contains(app, "//go:embed wiring.json") {
+		t.Fatalf("first file should embed the manifest:\n%s", app)
+	}
+	assertNotHasImport(t, tool, "embed")
+	if strings.Contains(tool, "go:embed") {
+		t.Fatalf("manifest must be embedded once:\n%s", tool)
+	}
+	if !strings.Contains(p.read("wiring.json"), `"name": "Tool"`) {
+		t.Fatal("manifest should list every root")
+	}
+}
+
+func TestValidateGraphManifest(t *testing.T) {
+	t.Parallel()
+
+	validateGraphManifest("")
+	validateGraphManifest("wiring.manifest.json")
+
+	for _, name := range []string{"out/wiring.json", "../wiring.json", "wiring.yaml", ".wiring.json", "_wiring.json"} {
+		assertPanicContains(t, func() { validateGraphManifest(name) },
+			"graph manifest must be a .json file name in the output directory")
+	}
+}
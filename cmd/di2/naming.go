@@ -0,0 +1,42 @@
+package main
+
+import "fmt"
+
+// -------------------------
+// Facade naming strategies (naming, -naming)
+// -------------------------
+
+const (
+	namingSuffix = "suffix" // <wrapperBase><versionSuffix>: AlphaV4
+	namingPrefix = "prefix" // <versionSuffix><wrapperBase>: V4Alpha
+	namingNone   = "none"   // <wrapperBase>: Alpha
+)
+
+// defaultNaming is the strategy of specs without "naming". run sets it from
+// -naming; graphs read their linked specs with it too, so the facade names a
+// graph derives match the ones generated for the specs.
+var defaultNaming = namingSuffix
+
+func isNamingStrategy(s string) bool {
+	return s == namingSuffix || s == namingPrefix || s == namingNone
+}
+
+// validateNaming dies on an unknown strategy; empty means the default.
+func validateNaming(s, ctx string) {
+	if s != "" && !isNamingStrategy(s) {
+		die(fmt.Sprintf("%s %q: want suffix, prefix or none", ctx, s))
+	}
+}
+
+// facadeBaseName is the facade type name of s under its naming strategy; the
+// public constructor is "New" + it.
+func facadeBaseName(s ServiceSpec) string {
+	switch s.Naming {
+	case namingPrefix:
+		return s.VersionSuffix + s.WrapperBase
+	case namingNone:
+		return s.WrapperBase
+	default:
+		return s.WrapperBase + s.VersionSuffix
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Facade naming strategies (naming, -naming)
+// -------------------------
+
+func writeNamingSpec(p *pkgHarness, name, naming, suffix string) string {
+	return p.write(filepath.Join("specs", strings.ToLower(name)+".inject.json"), `{
+  "package": "p", "wrapperBase": "`+name+`", "versionSuffix": "`+suffix+`", "naming": "`+naming+`",
+  "implType": "`+name+`", "constructor": "New`+name+`",
+  "required": [{ "name": "Dep", "field": "dep", "type": "*Dep", "nilable": true }]
+}`)
+}
+
+func TestFacadeBaseName(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ naming, suffix, want string }{
+		{naming: "", suffix: "V4", want: "AlphaV4"},
+		{naming: "suffix", suffix: "V4", want: "AlphaV4"},
+		{naming: "prefix", suffix: "V4", want: "V4Alpha"},
+		{naming: "none", suffix: "V4", want: "Alpha"},
+		{naming: "none", suffix: "", want: "Alpha"},
+	}
+	for _, tt := range tests {
+		if got := facadeBaseName(ServiceSpec{WrapperBase: "Alpha", VersionSuffix: tt.suffix, Naming: tt.naming}); got != tt.want {
+			t.Fatalf("%s/%s: got %q want %q", tt.naming, tt.suffix, got, tt.want)
+		}
+	}
+}
+
+func TestGenService_Naming(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	genService(writeNamingSpec(p, "Alpha", "prefix", "V4"), p.out("alpha.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("alpha.gen.go"), "type V4Alpha struct", "func NewV4Alpha(")
+
+	genService(writeNamingSpec(p, "Beta", "none", ""), p.out("beta.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("beta.gen.go"), "type Beta struct", "func NewBeta(")
+
+	assertPanicContains(t, func() {
+		genService(writeNamingSpec(p, "Gamma", "suffix", ""), p.out("gamma.gen.go"), genOptions{})
+	}, "spec missing: versionSuffix")
+	assertPanicContains(t, func() {
+		genService(writeNamingSpec(p, "Delta", "infix", "V4"), p.out("delta.gen.go"), genOptions{})
+	}, `spec naming "infix": want suffix, prefix or none`)
+}
+
+func TestRun_NamingFlag(t *testing.T) {
+	// NOT parallel: swaps defaultNaming
+	old := defaultNaming
+	t.Cleanup(func() { defaultNaming = old })
+	p := newPkg(t)
+	writeDISource(p)
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+	writeNamingSpec(p, "Beta", "prefix", "V4")
+
+	if err := run([]string{"-specs", p.out("specs"), "-out", p.dir, "-naming", "none"}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// the flag only applies to specs without naming; "none" also drops the file suffix
+	assertContainsInOrder(t, p.read("alpha.gen.go"), "type Alpha struct", "func NewAlpha(")
+	assertContainsInOrder(t, p.read("beta_v4.gen.go"), "type V4Beta struct")
+
+	if err := run([]string{"-specs", p.out("specs"), "-out", p.dir, "-naming", "Suffix"}); err == nil || !strings.Contains(err.Error(), `-naming "Suffix": want suffix, prefix or none`) {
+		t.Fatalf("expected a -naming error, got %v", err)
+	}
+}
+
+func TestGenGraph_NamesFromLinkedSpecs(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeNamingSpec(p, "Alpha", "none", "")
+	writeNamingSpec(p, "Beta", "prefix", "V4")
+	graph := p.write("specs/graph.json", `{"package": "p", "roots": [{"name": "App", "services": [
+  {"var": "alpha", "spec": "alpha.inject.json"},
+  {"var": "beta", "spec": "beta.inject.json", "facadeCtor": "NewBetaCustom"}
+]}]}`)
+
+	g, _ := readGraphSpec(graph)
+	resolveGraphServiceSpecs(&g, graph)
+	var got []string
+	for _, s := range g.Roots[0].Services {
+		got = append(got, s.FacadeCtor+" "+s.FacadeType+" "+s.ImplType)
+	}
+	// the graph's own names win over the linked spec's
+	if want := "NewAlpha *Alpha Alpha,NewBetaCustom *V4Beta Beta"; strings.Join(got, ",") != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	genGraph(graph, p.out("graph.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("graph.gen.go"), "NewAlpha(", "NewBetaCustom(")
+
+	plain := p.write("specs/plain.json", `{"package": "p", "roots": [{"name": "App", "services": [{"var": "a", "implType": "A"}]}]}`)
+	assertPanicContains(t, func() {
+		genGraph(plain, p.out("plain.gen.go"), genOptions{})
+	}, "graph service a: facadeCtor, facadeType and implType are required without a linked spec")
+}
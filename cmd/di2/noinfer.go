@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Declared imports and hermetic generation (-no-infer)
+// -------------------------
+
+// declaredPackageImports returns the imports of imports.packages (package name
+// -> import path), stdlib first and then by path. The name is kept only when it differs from
+// the last path element.
+func declaredPackageImports(pkgs map[string]string) []GoImport {
+	out := make([]GoImport, 0, len(pkgs))
+	for name, p := range pkgs {
+		gi := GoImport{Path: p}
+		if path.Base(p) != name {
+			gi.Name = name
+		}
+		out = append(out, gi)
+	}
+	sort.Slice(out, func(i, j int) bool { return importLess(out[i], out[j]) })
+	return out
+}
+
+// dropUnusedDeclaredImports removes the imports.packages entries src does not
+// reference, so packages can be declared once for all deps and methods.
+func dropUnusedDeclaredImports(src []byte, pkgs map[string]string) []byte {
+	if len(pkgs) == 0 {
+		return src
+	}
+	managed := make(map[string]string, len(pkgs))
+	for name, p := range pkgs {
+		managed[p] = name
+	}
+	return dropUnusedImports(src, managed)
+}
+
+// validateDeclaredPackages checks imports.packages entries: identifiers mapped
+// to non-empty import paths.
+func validateDeclaredPackages(pkgs map[string]string, ctx string) {
+	for name, p := range pkgs {
+		if !token.IsIdentifier(name) || name == "_" {
+			die(fmt.Sprintf("%s imports.packages: %q is not a package name", ctx, name))
+		}
+		if strings.TrimSpace(p) == "" {
+			die(fmt.Sprintf("%s imports.packages.%s: import path is required", ctx, name))
+		}
+	}
+}
+
+// requireDeclaredServiceImports is the -no-infer replacement of
+// inferImportsForService: every import di2 would otherwise find by scanning the
+// output package, walking go.mod files or locating its own sources must be in
+// the spec.
+func requireDeclaredServiceImports(s *ServiceSpec) {
+	var missing []string
+	if strings.TrimSpace(s.Imports.DI) == "" {
+		missing = append(missing, "imports.di")
+	}
+	if s.Config.Enabled && strings.TrimSpace(s.Config.Import) == "" && strings.TrimSpace(s.Imports.Config) == "" {
+		missing = append(missing, "config.import (or imports.config)")
+	}
+	if s.Output.external(s.Package) && strings.TrimSpace(s.Imports.Service) == "" {
+		missing = append(missing, "imports.service")
+	}
+	if len(missing) > 0 {
+		die("-no-infer: spec must declare " + strings.Join(missing, ", "))
+	}
+	// both return before touching the filesystem now that the imports are set
+	inferOptionalConfigImport(&s.Config, &s.Imports, nil, "", "imports.config (service)")
+	inferDIImport(&s.Imports, nil, "di", "/di")
+}
+
+// requireDeclaredGraphImports is requireDeclaredServiceImports for a graph. A
+// linked spec whose facade lives in another package must declare
+// imports.service, which the graph imports.
+func requireDeclaredGraphImports(g *GraphSpec, graphPath string) {
+	var missing []string
+	if strings.TrimSpace(g.Imports.DI) == "" {
+		missing = append(missing, "imports.di")
+	}
+	if g.Config.Enabled && strings.TrimSpace(g.Config.Import) == "" && strings.TrimSpace(g.Imports.Config) == "" {
+		missing = append(missing, "config.import (or imports.config)")
+	}
+	for _, root := range g.allRoots() {
+		for _, svc := range root.Services {
+			if svc.Spec == "" {
+				continue
+			}
+			spec, _ := readServiceSpec(linkedSpecPath(graphPath, svc.Spec))
+			if spec.Output.external(spec.Package) && g.Package != spec.Package && strings.TrimSpace(spec.Imports.Service) == "" {
+				missing = append(missing, "imports.service in "+svc.Spec)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		die("-no-infer: graph must declare " + strings.Join(missing, ", "))
+	}
+	inferOptionalConfigImport(&g.Config, &g.Imports, nil, "", "graph imports.config")
+	inferDIImport(&g.Imports, nil, "di", "/di")
+}
+
+// requireDeclaredQualifiers fails when a dep or method type of the service spec
+// uses a package qualifier that is neither generated (imports) nor declared
+// in imports.packages: without -no-infer it would come from the existing output
+// file, which a hermetic build does not declare as an input.
+func requireDeclaredQualifiers(s *ServiceSpec, imports []GoImport) {
+	known := map[string]bool{}
+	for _, gi := range imports {
+		name := gi.Name
+		if name == "" {
+			name = path.Base(gi.Path)
+		}
+		known[name] = true
+	}
+	for name := range s.Imports.Packages {
+		known[name] = true
+	}
+
+	check := func(ctx, expr string) {
+		for _, q := range typeQualifiers(expr) {
+			if !known[q] {
+				die(fmt.Sprintf("-no-infer: %s uses package %s; declare it in imports.packages", ctx, q))
+			}
+		}
+	}
+	for _, d := range s.Required {
+		check("required "+d.Name, d.Type)
+	}
+	for _, o := range s.Optional {
+		check("optional "+o.Name, o.Type)
+		for _, q := range applyArgQualifiers(o) {
+			if !known[q] {
+				die(fmt.Sprintf("-no-infer: optional %s apply.args uses package %s; declare it in imports.packages", o.Name, q))
+			}
+		}
+	}
+	for _, m := range s.Methods {
+		for _, p := range m.Params {
+			check("method "+m.Name, p.Type)
+		}
+		for _, r := range m.Returns {
+			check("method "+m.Name, r.Type)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Declared imports and hermetic generation (-no-infer)
+// -------------------------
+
+func hermeticSpec(imports string) string {
+	return `{
+  "package": "p", "wrapperBase": "Store", "versionSuffix": "V4",
+  "implType": "Store", "constructor": "NewStore",
+  "imports": ` + imports + `,
+  "required": [{ "name": "DB", "field": "db", "type": "*sql.DB", "nilable": true }]
+}`
+}
+
+func TestGenService_NoInfer(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	// no di.go to scan, no go.mod: nothing to infer from
+	spec := p.write("specs/store.inject.json", hermeticSpec(`{
+    "di": "example.com/proj/di",
+    "packages": { "sql": "database/sql", "http": "net/http", "xlog": "example.com/proj/log" }
+  }`))
+	out := p.write("store_v4.gen.go", "package p\n\nimport stale \"example.com/stale\"\n")
+
+	genService(spec, out, genOptions{NoInfer: true})
+	got := p.read("store_v4.gen.go")
+
+	assertHasImport(t, got, "example.com/proj/di")
+	assertHasImport(t, got, "database/sql")
+	assertNotHasImport(t, got, "net/http")          // declared but unused
+	assertNotHasImport(t, got, "example.com/stale") // -out is not an input
+	if strings.Contains(got, "xlog") {
+		t.Fatal("unused aliased package should be dropped")
+	}
+}
+
+func TestGenService_NoInferMissingImports(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	tests := []struct {
+		name    string
+		imports string
+		want    string
+	}{
+		{name: "di", imports: `{}`, want: "-no-infer: spec must declare imports.di"},
+		{name: "dep_package", imports: `{ "di": "example.com/proj/di" }`, want: "-no-infer: required DB uses package sql; declare it in imports.packages"},
+		{name: "bad_package_name", imports: `{ "di": "example.com/proj/di", "packages": { "my-sql": "database/sql" } }`, want: `spec imports.packages: "my-sql" is not a package name`},
+	}
+	for _, tt := range tests {
+		spec := p.write("specs/"+tt.name+".inject.json", hermeticSpec(tt.imports))
+		assertPanicContains(t, func() {
+			genService(spec, p.out(tt.name+".gen.go"), genOptions{NoInfer: true})
+		}, tt.want)
+	}
+}
+
+func TestGenService_DeclaredPackagesWithoutNoInfer(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	spec := p.write("specs/store.inject.json", hermeticSpec(`{ "packages": { "sql": "database/sql" } }`))
+
+	genService(spec, p.out("store_v4.gen.go"), genOptions{})
+	got := p.read("store_v4.gen.go")
+	assertHasImport(t, got, "example.com/proj/di") // still inferred
+	assertHasImport(t, got, "database/sql")
+}
+
+func TestGenGraph_NoInfer(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	graph := `{"package": "p", "imports": %s, "roots": [{"name": "App", "services": [
+  {"var": "alpha", "facadeCtor": "NewAlphaV4", "facadeType": "*AlphaV4", "implType": "Alpha"}
+]}]}`
+
+	missing := p.write("specs/missing.json", strings.Replace(graph, "%s", `{}`, 1))
+	assertPanicContains(t, func() {
+		genGraph(missing, p.out("graph.gen.go"), genOptions{NoInfer: true})
+	}, "-no-infer: graph must declare imports.di")
+
+	ok := p.write("specs/graph.json", strings.Replace(graph, "%s", `{"di": "example.com/proj/di"}`, 1))
+	genGraph(ok, p.out("graph.gen.go"), genOptions{NoInfer: true})
+	assertHasImport(t, p.read("graph.gen.go"), "example.com/proj/di")
+
+	if err := run([]string{"-graph", ok, "-out", p.out("graph.gen.go"), "-no-infer", "-scan-cache", p.out("cache.json")}); err == nil {
+		t.Fatal("expected -scan-cache to be rejected with -no-infer")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// -------------------------
+// Generator plugins (-plugin)
+// -------------------------
+
+// pluginProtocolVersion is sent with every request; plugins should reject
+// versions they do not know.
+const pluginProtocolVersion = 1
+
+// pluginRequest is written as JSON to a plugin's stdin after di2 generated a
+// file. Exactly one of Service and Graph is set, holding the spec as di2 used it
+// (defaults applied, imports inferred, entries sorted).
+type pluginRequest struct {
+	ProtocolVersion int          `json:"protocolVersion"`
+	Kind            string       `json:"kind"` // "service" | "graph"
+	SpecPath        string       `json:"specPath"`
+	OutPath         string       `json:"outPath"`
+	Service         *ServiceSpec `json:"service,omitempty"`
+	Graph           *GraphSpec   `json:"graph,omitempty"`
+}
+
+// pluginFile is a file a plugin asks di2 to write. Path is relative to the
+// directory of the generated file; .go files are gofmt'ed.
+type pluginFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// pluginResponse is the JSON a plugin writes to stdout. A non-empty Error
+// fails generation.
+type pluginResponse struct {
+	Files []pluginFile `json:"files"`
+	Error string       `json:"error"`
+}
+
+// listFlag is a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ", ") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// execPlugin runs a plugin command line with stdin and returns its stdout; the
+// plugin's stderr is passed through. Tests replace it.
+var execPlugin = func(cmdline string, stdin []byte) ([]byte, error) {
+	argv := strings.Fields(cmdline)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// runPlugins hands req to every -plugin in order and writes the files they
+// return next to req.OutPath (or prints them in dry-run mode).
+func runPlugins(req pluginRequest, opts genOptions) {
+	if len(opts.Plugins) == 0 {
+		return
+	}
+	req.ProtocolVersion = pluginProtocolVersion
+	in, err := json.Marshal(req)
+	must(err)
+
+	outDir := filepath.Dir(req.OutPath)
+	for _, plugin := range opts.Plugins {
+		files, err := callPlugin(plugin, in)
+		if err != nil {
+			die(fmt.Sprintf("plugin %q (%s): %v", plugin, req.SpecPath, err))
+		}
+		for _, f := range files {
+			path := filepath.Join(outDir, filepath.FromSlash(f.Path))
+			if opts.DryRun == nil {
+				must(os.MkdirAll(filepath.Dir(path), 0o755))
+			}
+			if !strings.HasSuffix(f.Path, ".go") {
+				if opts.DryRun != nil {
+					_, _ = fmt.Fprintf(opts.DryRun, "// ---- %s ----\n%s", filepath.ToSlash(path), f.Content)
+					continue
+				}
+				must(os.WriteFile(path, []byte(f.Content), 0o644))
+				opts.Provenance.output(path)
+				continue
+			}
+			if opts.DryRun != nil {
+				_, _ = fmt.Fprintf(opts.DryRun, "// ---- %s ----\n", filepath.ToSlash(path))
+			}
+			emitGenerated(path, []byte(f.Content), opts)
+		}
+	}
+}
+
+// callPlugin runs one plugin and validates its response.
+func callPlugin(plugin string, in []byte) ([]pluginFile, error) {
+	if strings.TrimSpace(plugin) == "" {
+		return nil, fmt.Errorf("empty command")
+	}
+	out, err := execPlugin(plugin, in)
+	if err != nil {
+		return nil, err
+	}
+	var resp pluginResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	seen := map[string]bool{}
+	for _, f := range resp.Files {
+		clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(f.Path)))
+		switch {
+		case f.Path == "" || filepath.IsAbs(f.Path) || clean == ".." || strings.HasPrefix(clean, "../"):
+			return nil, fmt.Errorf("file path %q must be relative to the output directory", f.Path)
+		case seen[clean]:
+			return nil, fmt.Errorf("file %s returned twice", clean)
+		}
+		seen[clean] = true
+	}
+	return resp.Files, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Generator plugins (-plugin)
+// -------------------------
+
+// stubPlugin replaces execPlugin with respond, recording the requests per
+// command line.
+func stubPlugin(t *testing.T, respond func(cmdline string, req pluginRequest) string) map[string][]pluginRequest {
+	t.Helper()
+	got := map[string][]pluginRequest{}
+	old := execPlugin
+	execPlugin = func(cmdline string, stdin []byte) ([]byte, error) {
+		var req pluginRequest
+		if err := json.Unmarshal(stdin, &req); err != nil {
+			t.Fatalf("plugin request is not JSON: %v", err)
+		}
+		got[cmdline] = append(got[cmdline], req)
+		return []byte(respond(cmdline, req)), nil
+	}
+	t.Cleanup(func() { execPlugin = old })
+	return got
+}
+
+func TestRun_Plugins(t *testing.T) {
+	// NOT parallel: swaps execPlugin
+
+	calls := stubPlugin(t, func(cmdline string, req pluginRequest) string {
+		if cmdline == "noop" {
+			return `{}`
+		}
+		name := "graph"
+		if req.Service != nil {
+			name = strings.ToLower(req.Service.FacadeName)
+		}
+		src := fmt.Sprintf("package p\n\n// %sMetrics counts builds.\nvar %sMetrics    int\n", name, name)
+		resp, _ := json.Marshal(pluginResponse{Files: []pluginFile{
+			{Path: name + "_metrics.gen.go", Content: src},
+			{Path: "plugin/" + name + ".txt", Content: req.Kind + "\n"},
+		}})
+		return string(resp)
+	})
+
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := writeSimulateGraph(p, false)
+	specPath := p.out("specs/db.inject.json")
+
+	if err := run([]string{"-spec", specPath, "-out", p.out("db_v4.gen.go"), "-plugin", "metrics --flag", "-plugin", "noop"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := run([]string{"-graph", graphPath, "-out", p.out("graph_v4.gen.go"), "-plugin", "metrics --flag"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	reqs := calls["metrics --flag"]
+	if len(reqs) != 2 || len(calls["noop"]) != 1 {
+		t.Fatalf("unexpected plugin calls: %+v", calls)
+	}
+	svc, graph := reqs[0], reqs[1]
+	if svc.ProtocolVersion != pluginProtocolVersion || svc.Kind != "service" || svc.SpecPath != specPath ||
+		svc.OutPath != p.out("db_v4.gen.go") || svc.Service == nil || svc.Graph != nil {
+		t.Fatalf("unexpected service request: %+v", svc)
+	}
+	if svc.Service.FacadeName != "DBV4" || svc.Service.Imports.DI != "example.com/proj/di" {
+		t.Fatalf("plugins must get the spec with defaults and inferred imports: %+v", svc.Service)
+	}
+	if graph.Kind != "graph" || graph.Graph == nil || len(graph.Graph.Roots) != 1 || graph.Service != nil {
+		t.Fatalf("unexpected graph request: %+v", graph)
+	}
+
+	if got, want := p.read("dbv4_metrics.gen.go"), "package p\n\n// dbv4Metrics counts builds.\nvar dbv4Metrics int\n"; got != want {
+		t.Fatalf("plugin .go files are gofmt'ed: got %q want %q", got, want)
+	}
+	if got := p.read("plugin/graph.txt"); got != "graph\n" {
+		t.Fatalf("plugin files are written as is, got %q", got)
+	}
+}
+
+func TestRun_PluginsDryRun(t *testing.T) {
+	// NOT parallel: swaps execPlugin and dryRunOut
+
+	stubPlugin(t, func(string, pluginRequest) string {
+		return `{"files": [{"path": "extra.gen.go", "content": "package p"}, {"path": "extra.txt", "content": "hi\n"}]}`
+	})
+	var buf bytes.Buffer
+	old := dryRunOut
+	dryRunOut = &buf
+	t.Cleanup(func() { dryRunOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	if err := run([]string{"-spec", specPath, "-out", p.out("a.gen.go"), "-dry-run", "-plugin", "x"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		"type AlphaV4 struct",
+		"// ---- "+p.out("extra.gen.go")+" ----\npackage p\n",
+		"// ---- "+p.out("extra.txt")+" ----\nhi\n",
+	)
+	if _, err := os.Stat(p.out("extra.gen.go")); !os.IsNotExist(err) {
+		t.Fatalf("dry-run must not write plugin files (err=%v)", err)
+	}
+}
+
+func TestRun_PluginErrors(t *testing.T) {
+	// NOT parallel: swaps execPlugin
+
+	tests := []struct {
+		name string
+		resp string
+		want string
+	}{
+		{name: "plugin_error", resp: `{"error": "unsupported spec"}`, want: `plugin "x" (` + "%s" + `): unsupported spec`},
+		{name: "invalid_json", resp: `files:`, want: "invalid response"},
+		{name: "absolute", resp: `{"files": [{"path": "/etc/x.go"}]}`, want: `file path "/etc/x.go" must be relative to the output directory`},
+		{name: "escape", resp: `{"files": [{"path": "a/../../x.go"}]}`, want: `file path "a/../../x.go" must be relative`},
+		{name: "empty_path", resp: `{"files": [{"content": "x"}]}`, want: `file path "" must be relative`},
+		{name: "duplicate", resp: `{"files": [{"path": "a.go"}, {"path": "./a.go"}]}`, want: "file a.go returned twice"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			stubPlugin(t, func(string, pluginRequest) string { return tt.resp })
+			p := newPkg(t)
+			writeDISource(p)
+			specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+			want := tt.want
+			if strings.Contains(want, "%s") {
+				want = fmt.Sprintf(want, specPath)
+			}
+			assertPanicContains(t, func() {
+				genService(specPath, p.out("a.gen.go"), genOptions{Plugins: []string{"x"}})
+			}, want)
+		})
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	t.Parallel()
+
+	// cat echoes the request, which decodes as a response without files
+	if files, err := callPlugin("cat", []byte(`{"protocolVersion": 1}`)); err != nil || len(files) != 0 {
+		t.Fatalf("cat: files=%v err=%v", files, err)
+	}
+	if _, err := callPlugin("false", nil); err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Fatalf("expected exit status error, got %v", err)
+	}
+	if _, err := callPlugin("  ", nil); err == nil || !strings.Contains(err.Error(), "empty command") {
+		t.Fatalf("expected empty command error, got %v", err)
+	}
+}
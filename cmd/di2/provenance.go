@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// -------------------------
+// Provenance record (-provenance)
+// -------------------------
+
+// provenance is the record -provenance writes for a generation run, for build
+// attestation and supply-chain tooling: which di2 ran, with what arguments,
+// which files it read and which it wrote, each with its SHA-256.
+type provenance struct {
+	Generator provenanceGenerator `json:"generator"`
+
+	// Timestamp is RFC 3339 UTC; SOURCE_DATE_EPOCH overrides it for
+	// reproducible builds.
+	Timestamp string           `json:"timestamp"`
+	Args      []string         `json:"args"`
+	Inputs    []provenanceFile `json:"inputs"`
+	Outputs   []provenanceFile `json:"outputs"`
+}
+
+type provenanceGenerator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// provenanceFile is one input (kind spec, graph, template or vars) or output
+// file. SpecHash is the canonical hash generated headers record for specs and
+// graphs (Spec-SHA256), so a record can be matched with the files it produced.
+type provenanceFile struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind,omitempty"`
+	SHA256   string `json:"sha256"`
+	SpecHash string `json:"specHash,omitempty"`
+}
+
+// provenanceRecorder collects the files of a run; -specs batches record from
+// several goroutines. A nil recorder records nothing.
+type provenanceRecorder struct {
+	mu      sync.Mutex
+	args    []string
+	inputs  map[string]string // path -> kind
+	outputs map[string]bool
+}
+
+func newProvenanceRecorder(args []string) *provenanceRecorder {
+	return &provenanceRecorder{args: args, inputs: map[string]string{}, outputs: map[string]bool{}}
+}
+
+// input records a file read by the run.
+func (r *provenanceRecorder) input(kind, path string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inputs[filepath.Clean(path)] = kind
+}
+
+// output records a file written by the run.
+func (r *provenanceRecorder) output(path string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outputs[filepath.Clean(path)] = true
+}
+
+// write hashes the recorded files as they are now on disk and writes the
+// record to path.
+func (r *provenanceRecorder) write(path string) error {
+	ts, err := provenanceTime()
+	if err != nil {
+		return err
+	}
+	p := provenance{
+		Generator: provenanceGenerator{Name: "di2", Version: generatorVersion()},
+		Timestamp: ts.UTC().Format(time.RFC3339),
+		Args:      r.args,
+		Inputs:    []provenanceFile{},
+		Outputs:   []provenanceFile{},
+	}
+	for f, kind := range r.inputs {
+		pf, err := provenanceFileOf(f)
+		if err != nil {
+			return err
+		}
+		pf.Kind = kind
+		if kind == "spec" || kind == "graph" {
+			pf.SpecHash = specSHA256(mustRead(f))
+		}
+		p.Inputs = append(p.Inputs, pf)
+	}
+	for f := range r.outputs {
+		pf, err := provenanceFileOf(f)
+		if err != nil {
+			return err
+		}
+		p.Outputs = append(p.Outputs, pf)
+	}
+	sort.Slice(p.Inputs, func(i, j int) bool { return p.Inputs[i].Path < p.Inputs[j].Path })
+	sort.Slice(p.Outputs, func(i, j int) bool { return p.Outputs[i].Path < p.Outputs[j].Path })
+
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); !dirExists(dir) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// recordGraphInputs records the graph file (or each fragment of a graph glob)
+// and the service specs its services link.
+func recordGraphInputs(g GraphSpec, graphPath string, r *provenanceRecorder) {
+	if r == nil {
+		return
+	}
+	paths := []string{graphPath}
+	if isGraphGlob(graphPath) {
+		paths, _ = filepath.Glob(graphPath)
+	}
+	for _, p := range paths {
+		r.input("graph", p)
+	}
+	for _, root := range g.allRoots() {
+		for _, svc := range root.Services {
+			if svc.Spec != "" {
+				r.input("spec", linkedSpecPath(graphPath, svc.Spec))
+			}
+		}
+	}
+}
+
+func provenanceFileOf(path string) (provenanceFile, error) {
+	if path == "-" {
+		return provenanceFile{Path: path, SHA256: sha256Hex(mustRead(path))}, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return provenanceFile{}, fmt.Errorf("provenance: %w", err)
+	}
+	return provenanceFile{Path: filepath.ToSlash(path), SHA256: sha256Hex(raw)}, nil
+}
+
+// provenanceTime is the run's timestamp: SOURCE_DATE_EPOCH (seconds) when set,
+// otherwise now.
+func provenanceTime() (time.Time, error) {
+	s := os.Getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		return time.Now(), nil
+	}
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("provenance: invalid SOURCE_DATE_EPOCH %q", s)
+	}
+	return time.Unix(sec, 0), nil
+}
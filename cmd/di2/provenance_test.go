@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Provenance record (-provenance)
+// -------------------------
+
+func readProvenance(t *testing.T, p *pkgHarness, rel string) provenance {
+	t.Helper()
+	var got provenance
+	if err := json.Unmarshal([]byte(p.read(rel)), &got); err != nil {
+		t.Fatalf("decode provenance: %v", err)
+	}
+	return got
+}
+
+func TestRun_ProvenanceBatch(t *testing.T) {
+	// NOT parallel: sets SOURCE_DATE_EPOCH
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	p := newPkg(t)
+	writeDISource(p)
+	alpha := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	writeBatchSpec(p, "beta", "Beta", "V4")
+
+	args := []string{"-out", p.dir, "-specs", p.out("specs"), "-provenance", p.out("attest/di2.json")}
+	if err := run(args); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	got := readProvenance(t, p, "attest/di2.json")
+	if got.Generator.Name != "di2" || got.Generator.Version == "" {
+		t.Fatalf("generator = %+v", got.Generator)
+	}
+	if got.Timestamp != "2023-11-14T22:13:20Z" {
+		t.Fatalf("timestamp = %q, want SOURCE_DATE_EPOCH", got.Timestamp)
+	}
+	if strings.Join(got.Args, " ") != strings.Join(args, " ") {
+		t.Fatalf("args = %q", got.Args)
+	}
+	if len(got.Inputs) != 2 || len(got.Outputs) != 2 {
+		t.Fatalf("want 2 inputs and 2 outputs, got %+v", got)
+	}
+	in := got.Inputs[0]
+	raw := []byte(p.read("specs/alpha.inject.json"))
+	if in.Path != strings.ReplaceAll(alpha, `\`, "/") || in.Kind != "spec" || in.SHA256 != sha256Hex(raw) || in.SpecHash != specSHA256(raw) {
+		t.Fatalf("input = %+v", in)
+	}
+	if out := got.Outputs[0]; !strings.HasSuffix(out.Path, "/alpha_v4.gen.go") || out.SHA256 != sha256Hex([]byte(p.read("alpha_v4.gen.go"))) || out.Kind != "" {
+		t.Fatalf("output = %+v", out)
+	}
+}
+
+func TestRun_ProvenanceGraph(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	writeBatchSpec(p, "alpha", "Alpha", "V4")
+	graph := p.write("specs/graph.json", `{
+  "package": "p", "manifest": "wiring.manifest.json",
+  "roots": [{ "name": "App", "services": [
+    { "var": "alpha", "facadeCtor": "NewAlphaV4", "facadeType": "*AlphaV4", "implType": "Alpha", "spec": "alpha.inject.json" }
+  ] }]
+}`)
+
+	if err := run([]string{"-graph", graph, "-out", p.out("graph.gen.go"), "-provenance", p.out("prov.json")}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	got := readProvenance(t, p, "prov.json")
+	var kinds, outs []string
+	for _, in := range got.Inputs {
+		kinds = append(kinds, in.Kind)
+	}
+	for _, out := range got.Outputs {
+		outs = append(outs, out.Path[strings.LastIndex(out.Path, "/")+1:])
+	}
+	if strings.Join(kinds, ",") != "spec,graph" || strings.Join(outs, ",") != "graph.gen.go,wiring.manifest.json" {
+		t.Fatalf("inputs %q, outputs %q", kinds, outs)
+	}
+
+	if err := run([]string{"-graph", graph, "-out", "-", "-provenance", p.out("prov.json")}); err == nil || !strings.Contains(err.Error(), "-provenance needs written output") {
+		t.Fatalf("expected a dry-run error, got %v", err)
+	}
+}
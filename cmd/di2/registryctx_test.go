@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Context-aware registry resolution
+// -------------------------
+
+func TestGenService_BuildWithCtx(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }],
+  "optional": [{ "name": "Tracer", "type": "Tracer", "registryKey": "t", "apply": { "kind": "field", "name": "tracer" } }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertHasImport(t, out, "context")
+	assertContainsInOrder(t, out,
+		"func (b *CoreV4) BuildWith(reg di.Registry) (*Core, error) {",
+		"return b.BuildWithCtx(context.Background(), reg)",
+		"func (b *CoreV4) BuildWithCtx(ctx context.Context, reg di.Registry) (*Core, error) {",
+		`di.ResolveOptionalCtx[Tracer](ctx, reg, nil, "CoreV4", "Tracer", "t")`,
+		`return b.buildScoped("BuildWith", reqCoreV4All)`,
+	)
+}
+
+// TestGenService_BuildWithNoOptional verifies a facade without optional deps has
+// no BuildWithCtx: BuildWith builds directly and needs no context import.
+func TestGenService_BuildWithNoOptional(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [{ "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertNotHasImport(t, out, "context")
+	assertContainsInOrder(t, out,
+		"func (b *CoreV4) BuildWith(reg di.Registry) (*Core, error) {\n\treturn b.buildScoped(\"BuildWith\", reqCoreV4All)\n}",
+	)
+	if strings.Contains(out, "BuildWithCtx") {
+		t.Fatalf("BuildWithCtx needs optional deps:\n%s", out)
+	}
+}
+
+func TestGenGraph_BuildWithCtx(t *testing.T) {
+	t.Parallel()
+
+	for _, parallel := range []bool{false, true} {
+		parallel := parallel
+		name := "serial"
+		if parallel {
+			name = "parallel"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			par := "false"
+			if parallel {
+				par = "true"
+			}
+			genGraph(p.write("graph.json", `{
+  "package": "p",
+  "parallel": `+par+`,
+  "roots": [{
+    "name": "App",
+    "buildWithRegistry": true,
+    "services": [
+      { "var": "alpha", "facadeCtor": "NewAlphaV4", "facadeType": "*AlphaV4", "implType": "Alpha" }
+    ]
+  }]
+}`), p.out("graph.gen.go"), genOptions{})
+			assertContainsInOrder(t, p.read("graph.gen.go"),
+				"func AppCtx(ctx context.Context, reg di.Registry) (AppResult, error) {",
+				"di.BuildCtx(ctx, alphaB, reg)",
+			)
+		})
+	}
+}
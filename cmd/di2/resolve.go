@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// loadImportPath asks the go command (via go/packages) for the import path of the
+// package in dir. Unlike the textual go.mod lookup it honours go.work workspaces,
+// vendoring and nested modules. It is a variable so tests can stub it.
+var loadImportPath = func(dir string) (string, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName, Dir: dir}, ".")
+	if err != nil {
+		return "", err
+	}
+	if len(pkgs) != 1 {
+		return "", fmt.Errorf("go/packages: expected 1 package in %s, got %d", dir, len(pkgs))
+	}
+	p := pkgs[0]
+	if len(p.Errors) > 0 {
+		return "", p.Errors[0]
+	}
+	if strings.TrimSpace(p.PkgPath) == "" {
+		return "", fmt.Errorf("go/packages: empty import path for %s", dir)
+	}
+	return p.PkgPath, nil
+}
+
+// resolveImportPath returns the import path of the package directory dir.
+//
+// go/packages is authoritative; the textual findOwningModule/moduleImportPathForDir
+// lookup (go.work aware) is the fallback for when the go command cannot answer (no toolchain on
+// PATH, a directory without Go files, a tree that is not a module yet).
+func resolveImportPath(dir string) (string, error) {
+	if p, err := loadImportPath(dir); err == nil {
+		return p, nil
+	}
+	modRoot, modPath, err := findOwningModule(dir)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return moduleImportPathForDir(modRoot, modPath, abs)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// go/packages import resolution
+// -------------------------
+
+func TestResolveImportPath(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	// quoted module path with a trailing comment: the go command understands it,
+	// the textual go.mod reader alone would not
+	p.write("go.mod", "module \"example.com/quoted\" // legacy\n\ngo 1.25\n")
+	p.write("svc/svc.go", "package svc\n")
+	p.write("plain/go.mod", "module example.com/plain\n\ngo 1.25\n")
+	p.write("plain/empty/README.md", "no go files here\n")
+
+	tests := []struct {
+		name    string
+		dir     string
+		want    string
+		wantErr bool
+	}{
+		{name: "go_packages", dir: p.out("svc"), want: "example.com/quoted/svc"},
+		{name: "fallback_without_go_files", dir: p.out("plain/empty"), want: "example.com/plain/empty"},
+		{name: "outside_module", dir: t.TempDir(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := resolveImportPath(tt.dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferOptionalConfigImport_UsesGoPackages(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("go.mod", "module example.com/ws/app\n\ngo 1.25\n")
+	p.write("go.work", "go 1.25\n\nuse .\n")
+	p.write("svc/svc.go", "package svc\n")
+	p.write("svc/config/config.go", "package config\n")
+
+	cfg := ConfigSpec{Enabled: true}
+	var imps Imports
+	inferOptionalConfigImport(&cfg, &imps, nil, p.out("svc"), "imports.config (service)")
+	if imps.Config != "example.com/ws/app/svc/config" {
+		t.Fatalf("got %q", imps.Config)
+	}
+}
+
+func TestInferImports_SuffixMatchIsLastResort(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("go.mod", "module example.com/app\n\ngo 1.25\n")
+	p.write("svc/svc.go", "package svc\n")
+	p.write("svc/config/config.go", "package config\n")
+	scanned := []GoImport{{Path: "example.com/vendor/config"}, {Name: "rt", Path: "example.com/vendor/di"}}
+	cfg := ConfigSpec{Enabled: true}
+
+	// a scanned import merely ending in /config, or in /di under another name,
+	// loses to the resolved packages
+	var imps Imports
+	inferOptionalConfigImport(&cfg, &imps, scanned, p.out("svc"), "imports.config (service)")
+	inferDIImport(&imps, scanned, "di", "/di")
+	if imps.Config != "example.com/app/svc/config" || imps.DI != "github.com/sghaida/odi/di" {
+		t.Fatalf("got config %q, di %q", imps.Config, imps.DI)
+	}
+
+	// an explicit alias is what generated code refers to
+	aliased := []GoImport{{Name: "config", Path: "example.com/vendor/config"}, {Name: "di", Path: "example.com/vendor/di"}}
+	imps = Imports{}
+	inferOptionalConfigImport(&cfg, &imps, aliased, p.out("svc"), "imports.config (service)")
+	inferDIImport(&imps, aliased, "di", "/di")
+	if imps.Config != "example.com/vendor/config" || imps.DI != "example.com/vendor/di" {
+		t.Fatalf("got config %q, di %q", imps.Config, imps.DI)
+	}
+
+	// without a ./config package the suffix match still applies
+	p.write("other/other.go", "package other\n")
+	imps = Imports{}
+	inferOptionalConfigImport(&cfg, &imps, scanned, p.out("other"), "imports.config (service)")
+	if imps.Config != "example.com/vendor/config" {
+		t.Fatalf("got config %q", imps.Config)
+	}
+	assertPanicContains(t, func() {
+		inferOptionalConfigImport(&cfg, &Imports{}, nil, p.out("other"), "imports.config (service)")
+	}, "cannot infer imports.config (service): config enabled but ./config directory not found")
+}
+
+func TestGenService_ForkedDIRuntime(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	// a forked runtime imported without an alias is still the one the sources call di
+	p.write("di.go", `package p
+import "example.com/proj/di"
+func _() { _ = di.Registry(nil) }`)
+	spec := p.write("specs/svc.inject.json", `{
+  "package": "p", "wrapperBase": "Svc", "versionSuffix": "V4",
+  "implType": "Svc", "constructor": "NewSvc",
+  "required": [{ "name": "Dep", "field": "dep", "type": "*Dep", "nilable": true }]
+}`)
+
+	genService(spec, p.out("svc_v4.gen.go"), genOptions{})
+	got := p.read("svc_v4.gen.go")
+	if !strings.Contains(got, `di "example.com/proj/di"`) || strings.Contains(got, "github.com/sghaida/odi/di") {
+		t.Fatalf("expected the forked runtime import, got:\n%s", got)
+	}
+
+	var imps Imports
+	inferDIImport(&imps, []GoImport{{Path: "example.com/proj/di"}}, "di", "/di")
+	if imps.DI != "example.com/proj/di" {
+		t.Fatalf("got di %q", imps.DI)
+	}
+}
+
+func TestDIRuntimeImportFromBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		bi     *debug.BuildInfo
+		ok     bool
+		rel    string
+		want   string
+		wantOK bool
+	}{
+		{name: "installed_module", bi: &debug.BuildInfo{Main: debug.Module{Path: "github.com/sghaida/odi", Version: "v1.2.3"}}, ok: true, rel: "di", want: "github.com/sghaida/odi/di", wantOK: true},
+		{name: "trims_slashes", bi: &debug.BuildInfo{Main: debug.Module{Path: "example.com/m"}}, ok: true, rel: "/pkg/di/", want: "example.com/m/pkg/di", wantOK: true},
+		{name: "no_build_info", ok: false, rel: "di"},
+		{name: "nil_build_info", ok: true, rel: "di"},
+		{name: "command_line_arguments", bi: &debug.BuildInfo{Main: debug.Module{Path: "command-line-arguments"}}, ok: true, rel: "di"},
+		{name: "empty_main_path", bi: &debug.BuildInfo{}, ok: true, rel: "di"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := diRuntimeImportFromBuildInfo(tt.bi, tt.ok, tt.rel)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("got (%q, %v) want (%q, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
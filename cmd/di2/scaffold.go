@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// -------------------------
+// di2 new service (scaffolder)
+// -------------------------
+
+// defaultGenCmd is the package run by the scaffolded go:generate line.
+const defaultGenCmd = "github.com/sghaida/odi/cmd/di2"
+
+// scaffoldOut is where "di2 new" lists the files it created.
+var scaffoldOut io.Writer = os.Stdout
+
+// scaffoldDep is a required or optional dep of a scaffolded service.
+type scaffoldDep struct {
+	Name  string // exported dep name, e.g. "TransactionGetter"
+	Field string // impl field, e.g. "transactionGetter"
+	Type  string // Go type in the spec, e.g. "TransactionGetter" or "*Store"
+
+	// Declare is true when the package has no such type yet: the skeleton then
+	// declares an empty interface for it. Fake is the test's non-nil value; for
+	// interfaces (FakeType) it is a struct embedding the interface.
+	Declare  bool
+	FakeType bool
+	Fake     string
+}
+
+type scaffoldData struct {
+	Package  string
+	Name     string // impl type, e.g. "Fraud"
+	Facade   string // e.g. "FraudV4"
+	Suffix   string
+	File     string // snake-case base, e.g. "fraud"
+	SpecRel  string // spec path relative to the package dir
+	GenCmd   string
+	DIImport string
+	Required []scaffoldDep
+	Optional []scaffoldDep
+
+	SpecVersion int
+}
+
+// runNew implements "di2 new service": it writes a service skeleton with its
+// go:generate line, the service spec and a table-driven test, then generates the
+// facade so the package builds and the test passes right away.
+func runNew(args []string) error {
+	if len(args) == 0 || args[0] != "service" {
+		return fmt.Errorf("usage: di2 new service -name Name [-deps A,B] [-optional C] [-dir .]")
+	}
+
+	fs := flag.NewFlagSet("di2 new service", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	name := fs.String("name", "", "service (impl type) name, e.g. Fraud")
+	deps := fs.String("deps", "", "comma-separated required deps, e.g. TransactionGetter,DecisionWriter")
+	optional := fs.String("optional", "", "comma-separated optional deps resolved from the registry, e.g. Logger")
+	dir := fs.String("dir", ".", "package directory")
+	pkgName := fs.String("package", "", "package name (default: the directory's package, or its base name)")
+	suffix := fs.String("suffix", "V4", "facade version suffix")
+	genCmd := fs.String("gen-cmd", defaultGenCmd, "di2 package (or relative path) used in the go:generate line")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if !token.IsIdentifier(*name) || !token.IsExported(*name) {
+		return fmt.Errorf("-name must be an exported Go identifier, got %q", *name)
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	pkg, types := scanScaffoldPackage(*dir)
+	if *pkgName != "" {
+		pkg = *pkgName
+	}
+	if pkg == "" {
+		pkg = scaffoldPackageName(*dir)
+	}
+	if _, ok := types[*name]; ok && !*force {
+		return fmt.Errorf("type %s already exists in %s", *name, *dir)
+	}
+
+	d := scaffoldData{
+		Package: pkg,
+		Name:    *name,
+		Facade:  *name + *suffix,
+		Suffix:  *suffix,
+		File:    snakeCase(*name),
+		GenCmd:  *genCmd,
+
+		SpecVersion: currentSpecVersion,
+	}
+	d.SpecRel = "specs/" + d.File + ".inject.json"
+
+	seen := map[string]bool{}
+	parse := func(list string) ([]scaffoldDep, error) {
+		var out []scaffoldDep
+		for _, n := range strings.Split(list, ",") {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			if !token.IsIdentifier(n) || !token.IsExported(n) {
+				return nil, fmt.Errorf("dep %q must be an exported Go identifier", n)
+			}
+			if seen[n] {
+				return nil, fmt.Errorf("dep %s listed twice", n)
+			}
+			seen[n] = true
+			out = append(out, newScaffoldDep(n, types))
+		}
+		return out, nil
+	}
+	var err error
+	if d.Required, err = parse(*deps); err != nil {
+		return err
+	}
+	if d.Optional, err = parse(*optional); err != nil {
+		return err
+	}
+
+	svcPath := filepath.Join(*dir, d.File+"_service.go")
+	specPath := filepath.Join(*dir, filepath.FromSlash(d.SpecRel))
+	testPath := filepath.Join(*dir, d.File+"_service_test.go")
+	outPath := filepath.Join(*dir, d.File+"_"+strings.ToLower(*suffix)+".gen.go")
+	if !*force {
+		for _, p := range []string{svcPath, specPath, testPath} {
+			if fileExists(p) {
+				return fmt.Errorf("%s already exists (use -force to overwrite)", p)
+			}
+		}
+	}
+
+	if err := writeScaffold(svcPath, scaffoldServiceTpl, d, true); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(specPath), 0o755); err != nil {
+		return err
+	}
+	if err := writeScaffold(specPath, scaffoldSpecTpl, d, false); err != nil {
+		return err
+	}
+
+	genService(specPath, outPath, genOptions{})
+	for _, imp := range readImportsFromExistingOut(outPath) {
+		if imp.Name == "di" || (imp.Name == "" && strings.HasSuffix(imp.Path, "/di")) {
+			d.DIImport = imp.Path
+		}
+	}
+	if err := writeScaffold(testPath, scaffoldTestTpl, d, true); err != nil {
+		return err
+	}
+
+	for _, p := range []string{svcPath, specPath, outPath, testPath} {
+		_, _ = fmt.Fprintf(scaffoldOut, "created %s\n", filepath.ToSlash(p))
+	}
+	return nil
+}
+
+func newScaffoldDep(name string, types map[string]bool) scaffoldDep {
+	d := scaffoldDep{Name: name, Field: unexportName(name), Type: name}
+	iface, exists := types[name]
+	switch {
+	case !exists, iface:
+		d.Declare = !exists
+		d.FakeType = true
+		d.Fake = "fake" + name + "{}"
+	default:
+		d.Type = "*" + name
+		d.Fake = "&" + name + "{}"
+	}
+	return d
+}
+
+// scanScaffoldPackage returns the package name of the non-test .go files in dir
+// and their type declarations (true for interfaces).
+func scanScaffoldPackage(dir string) (string, map[string]bool) {
+	types := map[string]bool{}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	sort.Strings(files)
+	pkg := ""
+	fset := token.NewFileSet()
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		if pkg == "" {
+			pkg = f.Name.Name
+		}
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				ts := s.(*ast.TypeSpec)
+				_, iface := ts.Type.(*ast.InterfaceType)
+				types[ts.Name.Name] = iface
+			}
+		}
+	}
+	return pkg, types
+}
+
+// scaffoldPackageName derives a package name from dir's base name
+// ("fraud-check" -> "fraudcheck").
+func scaffoldPackageName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	var sb strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(abs)) {
+		if r == '_' || unicode.IsLetter(r) || (unicode.IsDigit(r) && sb.Len() > 0) {
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 || token.IsKeyword(sb.String()) {
+		return "service"
+	}
+	return sb.String()
+}
+
+// snakeCase converts an exported name to a file name base
+// ("FraudCheck" -> "fraud_check", "HTTPClient" -> "http_client").
+func snakeCase(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// unexportName returns the impl field name for a dep ("DB" -> "db",
+// "HTTPClient" -> "httpClient", "Type" -> "typ").
+func unexportName(s string) string {
+	rs := []rune(s)
+	n := 0
+	for n < len(rs) && unicode.IsUpper(rs[n]) {
+		n++
+	}
+	if n > 1 && n < len(rs) {
+		n-- // keep the start of the next word: HTTPClient -> httpClient
+	}
+	out := strings.ToLower(string(rs[:n])) + string(rs[n:])
+	if token.IsKeyword(out) {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func writeScaffold(path string, tpl *template.Template, d scaffoldData, goSource bool) error {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, d); err != nil {
+		return err
+	}
+	if goSource {
+		writeFormatted(path, buf.Bytes())
+		return nil
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+var scaffoldServiceTpl = template.Must(template.New("scaffold-service").Funcs(template.FuncMap{
+	"lower": strings.ToLower,
+}).Parse(`package {{.Package}}
+
+//go:generate go run {{.GenCmd}} -spec {{.SpecRel}} -out {{.File}}_{{ lower .Suffix }}.gen.go
+{{ range .Required }}{{ if .Declare }}
+// {{.Name}} is a required dependency of {{$.Name}}.
+type {{.Name}} interface {
+	// TODO: add the methods {{$.Name}} needs.
+}
+{{ end }}{{ end }}
+{{- range .Optional }}{{ if .Declare }}
+// {{.Name}} is an optional dependency of {{$.Name}}, resolved from the registry.
+type {{.Name}} interface {
+	// TODO: add the methods {{$.Name}} needs.
+}
+{{ end }}{{ end }}
+// {{.Name}} is wired by the generated facade ({{.Facade}}).
+type {{.Name}} struct {
+	{{- range .Required }}
+	{{.Field}} {{.Type}} // required
+	{{- end }}
+	{{- range .Optional }}
+	{{.Field}} {{.Type}} // optional (nil when not in the registry)
+	{{- end }}
+}
+
+// New{{.Name}} is the constructor used by the generated facade ({{.Facade}}).
+func New{{.Name}}() *{{.Name}} { return &{{.Name}}{} }
+`))
+
+var scaffoldSpecTpl = template.Must(template.New("scaffold-spec").Parse(`{
+  "specVersion": {{.SpecVersion}},
+  "package": "{{.Package}}",
+  "wrapperBase": "{{.Name}}",
+  "versionSuffix": "{{.Suffix}}",
+  "implType": "{{.Name}}",
+  "constructor": "New{{.Name}}",
+  "injectPolicy": { "onOverwrite": "error" },
+
+  "required": [
+    {{- range $i, $d := .Required }}{{ if $i }},{{ end }}
+    { "name": "{{.Name}}", "field": "{{.Field}}", "type": "{{.Type}}", "nilable": true }
+    {{- end }}
+  ],
+
+  "optional": [
+    {{- range $i, $d := .Optional }}{{ if $i }},{{ end }}
+    {
+      "name": "{{.Name}}",
+      "type": "{{.Type}}",
+      "registryKey": "{{ $.Package }}.{{.Field}}",
+      "apply": { "kind": "field", "name": "{{.Field}}" }
+    }
+    {{- end }}
+  ],
+
+  "methods": []
+}
+`))
+
+var scaffoldTestTpl = template.Must(template.New("scaffold-test").Parse(`package {{.Package}}
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	di "{{.DIImport}}"
+)
+{{ range .Required }}{{ if .FakeType }}
+type fake{{.Name}} struct{ {{.Name}} }
+{{ end }}{{ end }}
+{{- range .Optional }}{{ if .FakeType }}
+type fake{{.Name}} struct{ {{.Name}} }
+{{ end }}{{ end }}
+func Test{{.Facade}}_Build(t *testing.T) {
+	tests := []struct {
+		name        string
+		wire        func(b *{{.Facade}})
+		wantMissing []string
+	}{
+		{
+			name: "all required deps",
+			wire: func(b *{{.Facade}}) {
+				{{- range .Required }}
+				b.Inject{{.Name}}({{.Fake}})
+				{{- end }}
+			},
+		},
+		{{- range $m := .Required }}
+		{
+			name: "missing {{$m.Name}}",
+			wire: func(b *{{$.Facade}}) {
+				{{- range $.Required }}{{ if ne .Name $m.Name }}
+				b.Inject{{.Name}}({{.Fake}})
+				{{- end }}{{ end }}
+			},
+			wantMissing: []string{"{{$m.Name}}"},
+		},
+		{{- end }}
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			b := New{{.Facade}}()
+			tt.wire(b)
+
+			if got := b.Missing(); !slices.Equal(got, tt.wantMissing) {
+				t.Fatalf("Missing() = %v, want %v", got, tt.wantMissing)
+			}
+			_, err := b.Build()
+			if len(tt.wantMissing) == 0 && err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if len(tt.wantMissing) > 0 && !errors.Is(err, di.ErrWiringIncomplete) {
+				t.Fatalf("expected di.ErrWiringIncomplete, got %v", err)
+			}
+		})
+	}
+}
+{{- if .Optional }}
+
+func Test{{.Facade}}_BuildWith_Optional(t *testing.T) {
+	reg := di.NewMapRegistry()
+	{{- range .Optional }}
+	reg.Provide("{{ $.Package }}.{{.Field}}", {{.Fake}})
+	{{- end }}
+
+	b := New{{.Facade}}()
+	{{- range .Required }}
+	b.Inject{{.Name}}({{.Fake}})
+	{{- end }}
+	svc, err := b.BuildWith(reg)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	{{- range .Optional }}
+	if svc.{{.Field}} == nil {
+		t.Fatalf("expected optional {{.Name}} to be resolved")
+	}
+	{{- end }}
+}
+{{- end }}
+`))
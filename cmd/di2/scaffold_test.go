@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// di2 new service
+// -------------------------
+
+func TestRun_NewService(t *testing.T) {
+	// NOT parallel: swaps scaffoldOut
+
+	var buf bytes.Buffer
+	old := scaffoldOut
+	scaffoldOut = &buf
+	t.Cleanup(func() { scaffoldOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	p.write("store.go", `package p
+
+type Store struct{}
+
+type Clock interface{ Now() int64 }
+`)
+
+	err := run([]string{"new", "service", "--name", "FraudCheck", "--deps", "TransactionGetter, Store", "--optional", "Clock", "--dir", p.dir})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		"fraud_check_service.go", "specs/fraud_check.inject.json", "fraud_check_v4.gen.go", "fraud_check_service_test.go",
+	)
+
+	svc := p.read("fraud_check_service.go")
+	assertContainsInOrder(t, svc,
+		"package p",
+		"//go:generate go run github.com/sghaida/odi/cmd/di2 -spec specs/fraud_check.inject.json -out fraud_check_v4.gen.go",
+		"type TransactionGetter interface {",
+		"type FraudCheck struct {",
+		"transactionGetter TransactionGetter // required",
+		"store             *Store            // required",
+		"clock             Clock             // optional",
+		"func NewFraudCheck() *FraudCheck { return &FraudCheck{} }",
+	)
+	if strings.Contains(svc, "type Store") || strings.Contains(svc, "type Clock") {
+		t.Fatalf("existing types must not be redeclared:\n%s", svc)
+	}
+
+	assertContainsInOrder(t, p.read("specs/fraud_check.inject.json"),
+		`"specVersion": 2,`,
+		`"wrapperBase": "FraudCheck"`,
+		`{ "name": "TransactionGetter", "field": "transactionGetter", "type": "TransactionGetter", "nilable": true },`,
+		`{ "name": "Store", "field": "store", "type": "*Store", "nilable": true }`,
+		`"registryKey": "p.clock"`,
+		`"apply": { "kind": "field", "name": "clock" }`,
+	)
+
+	assertContainsInOrder(t, p.read("fraud_check_v4.gen.go"),
+		"type FraudCheckV4 struct",
+		"func (b *FraudCheckV4) InjectStore(dep *Store) *FraudCheckV4",
+		"func (b *FraudCheckV4) InjectTransactionGetter(dep TransactionGetter) *FraudCheckV4",
+	)
+
+	test := p.read("fraud_check_service_test.go")
+	assertHasImport(t, test, "example.com/proj/di")
+	assertContainsInOrder(t, test,
+		"type fakeTransactionGetter struct{ TransactionGetter }",
+		"type fakeClock struct{ Clock }",
+		"func TestFraudCheckV4_Build(t *testing.T) {",
+		`name: "all required deps"`,
+		"b.InjectTransactionGetter(fakeTransactionGetter{})",
+		"b.InjectStore(&Store{})",
+		`name: "missing TransactionGetter"`,
+		`wantMissing: []string{"TransactionGetter"}`,
+		`name: "missing Store"`,
+		"func TestFraudCheckV4_BuildWith_Optional(t *testing.T) {",
+		`reg.Provide("p.clock", fakeClock{})`,
+		"if svc.clock == nil {",
+	)
+	if strings.Contains(test, "type fakeStore") {
+		t.Fatalf("struct deps need no fake type:\n%s", test)
+	}
+
+	// a second run refuses to overwrite
+	err = run([]string{"new", "service", "-name", "FraudCheck", "-dir", p.dir})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+}
+
+func TestRun_NewServiceErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no_kind", args: []string{"new"}, want: "usage: di2 new service"},
+		{name: "unknown_kind", args: []string{"new", "graph"}, want: "usage: di2 new service"},
+		{name: "missing_name", args: []string{"new", "service"}, want: "-name must be an exported Go identifier"},
+		{name: "unexported_name", args: []string{"new", "service", "-name", "fraud"}, want: "-name must be an exported Go identifier"},
+		{name: "bad_dep", args: []string{"new", "service", "-name", "Fraud", "-deps", "a-b"}, want: `dep "a-b" must be an exported Go identifier`},
+		{name: "dup_dep", args: []string{"new", "service", "-name", "Fraud", "-deps", "A", "-optional", "A"}, want: "dep A listed twice"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			args := tt.args
+			if len(args) > 2 {
+				args = append(args, "-dir", t.TempDir())
+			}
+			err := run(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestScaffoldNames(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string][2]string{
+		"Fraud":          {"fraud", "fraud"},
+		"FraudCheck":     {"fraud_check", "fraudCheck"},
+		"HTTPClient":     {"http_client", "httpClient"},
+		"DB":             {"db", "db"},
+		"Type":           {"type", "typ"},
+		"DecisionWriter": {"decision_writer", "decisionWriter"},
+	} {
+		if got := snakeCase(in); got != want[0] {
+			t.Fatalf("snakeCase(%q) = %q, want %q", in, got, want[0])
+		}
+		if got := unexportName(in); got != want[1] {
+			t.Fatalf("unexportName(%q) = %q, want %q", in, got, want[1])
+		}
+	}
+
+	for in, want := range map[string]string{
+		"/x/fraud-check": "fraudcheck",
+		"/x/9lives":      "lives",
+		"/x/func":        "service",
+	} {
+		if got := scaffoldPackageName(in); got != want {
+			t.Fatalf("scaffoldPackageName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// scanCacheVersion is bumped whenever the on-disk format or the scan rules change.
+const scanCacheVersion = 1
+
+// scanCacheEntry is the cached import scan of one package directory.
+type scanCacheEntry struct {
+	// Fingerprint covers name, size and mtime of every scanned source file.
+	Fingerprint string     `json:"fingerprint"`
+	Imports     []GoImport `json:"imports"`
+}
+
+// scanCache memoizes scanPackageImports per directory. Generating several specs
+// of one package (or a whole -specs batch) otherwise re-parses every .go file of
+// the package once per spec. Entries are validated by fingerprint on each lookup,
+// so edits to the package invalidate them. It is safe for concurrent use.
+type scanCache struct {
+	mu      sync.Mutex
+	entries map[string]scanCacheEntry
+	hits    int
+	misses  int
+}
+
+func newScanCache() *scanCache {
+	return &scanCache{entries: make(map[string]scanCacheEntry)}
+}
+
+// importScanCache is the process-wide cache used by scanPackageImports.
+var importScanCache = newScanCache()
+
+// scanSourceFiles lists the package files that take part in import inference,
+// together with the fingerprint of their current state.
+func scanSourceFiles(pkgDir string) ([]string, string, error) {
+	entries, err := os.ReadDir(pkgDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	var fp strings.Builder
+	for _, e := range entries {
+		if e.IsDir() || !isScannedSourceFile(e.Name()) {
+			continue
+		}
+		files = append(files, e.Name())
+
+		fp.WriteString(e.Name())
+		if info, ierr := e.Info(); ierr == nil {
+			fp.WriteString("\x00" + strconv.FormatInt(info.Size(), 10))
+			fp.WriteString("\x00" + strconv.FormatInt(info.ModTime().UnixNano(), 10))
+		}
+		fp.WriteString("\n")
+	}
+	return files, sha256Hex([]byte(fp.String())), nil
+}
+
+// isScannedSourceFile reports whether a file contributes to import inference.
+func isScannedSourceFile(name string) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	// avoid feeding generated outputs back into inference
+	return !strings.HasSuffix(name, ".gen.go") && !strings.Contains(name, ".gen.") && !strings.HasSuffix(name, "_gen.go")
+}
+
+// scan returns the imports of pkgDir, parsing the package only when it changed
+// since the cached scan. The returned slice is owned by the caller.
+func (c *scanCache) scan(pkgDir string) []GoImport {
+	files, fingerprint, err := scanSourceFiles(pkgDir)
+	if err != nil {
+		return nil
+	}
+	key := scanCacheKey(pkgDir)
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && e.Fingerprint == fingerprint {
+		c.hits++
+		c.mu.Unlock()
+		return slices.Clone(e.Imports)
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	imports := parsePackageImports(pkgDir, files)
+
+	c.mu.Lock()
+	c.entries[key] = scanCacheEntry{Fingerprint: fingerprint, Imports: imports}
+	c.mu.Unlock()
+	return slices.Clone(imports)
+}
+
+// stats returns the number of cache hits and misses so far.
+func (c *scanCache) stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+func scanCacheKey(pkgDir string) string {
+	if abs, err := filepath.Abs(pkgDir); err == nil {
+		return abs
+	}
+	return filepath.Clean(pkgDir)
+}
+
+// scanCacheFile is the on-disk representation used by -scan-cache.
+type scanCacheFile struct {
+	Version int                       `json:"version"`
+	Dirs    map[string]scanCacheEntry `json:"dirs"`
+}
+
+// load merges a cache file written by save into c. A missing file is not an
+// error; an unreadable or outdated one is ignored, the cache is only an optimization.
+func (c *scanCache) load(path string) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var f scanCacheFile
+	if json.Unmarshal(raw, &f) != nil || f.Version != scanCacheVersion {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for dir, e := range f.Dirs {
+		if _, ok := c.entries[dir]; !ok {
+			c.entries[dir] = e
+		}
+	}
+	return nil
+}
+
+// save writes all entries whose directory still exists to path (atomically).
+func (c *scanCache) save(path string) error {
+	c.mu.Lock()
+	f := scanCacheFile{Version: scanCacheVersion, Dirs: make(map[string]scanCacheEntry, len(c.entries))}
+	dirs := make([]string, 0, len(c.entries))
+	for dir := range c.entries {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if dirExists(dir) {
+			f.Dirs[dir] = c.entries[dir]
+		}
+	}
+	c.mu.Unlock()
+
+	raw, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Import scan cache
+// -------------------------
+
+func TestScanCache_HitsUntilPackageChanges(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	p.write("a.go", "package p\nimport cfg \"example.com/proj/config\"\n")
+	p.write("a.gen.go", "package p\nimport \"example.com/ignored\"\n")
+
+	c := newScanCache()
+	first := c.scan(p.dir)
+	if len(first) != 1 || first[0] != (GoImport{Name: "cfg", Path: "example.com/proj/config"}) {
+		t.Fatalf("unexpected scan: %+v", first)
+	}
+
+	first[0].Path = "mutated"
+	second := c.scan(p.dir)
+	if second[0].Path != "example.com/proj/config" {
+		t.Fatalf("caller mutation leaked into cache: %+v", second)
+	}
+	if h, m := c.stats(); h != 1 || m != 1 {
+		t.Fatalf("hits/misses: got %d/%d want 1/1", h, m)
+	}
+
+	// a generated file changing does not invalidate; a source file does
+	p.write("a.gen.go", "package p\nimport \"example.com/other\"\n")
+	c.scan(p.dir)
+	p.write("b.go", "package p\nimport \"context\"\n")
+	third := c.scan(p.dir)
+	if len(third) != 2 {
+		t.Fatalf("expected rescan to pick up b.go: %+v", third)
+	}
+	if h, m := c.stats(); h != 2 || m != 2 {
+		t.Fatalf("hits/misses: got %d/%d want 2/2", h, m)
+	}
+
+	if got := c.scan(filepath.Join(p.dir, "missing")); got != nil {
+		t.Fatalf("expected nil for missing dir, got %+v", got)
+	}
+}
+
+func TestScanCache_SaveLoad(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	pkgDir := filepath.Join(p.dir, "pkg")
+	p.write("pkg/a.go", "package p\nimport \"context\"\n")
+	cacheFile := p.out("scan.json")
+
+	c := newScanCache()
+	if err := c.load(cacheFile); err != nil {
+		t.Fatalf("missing cache file must not be an error: %v", err)
+	}
+	c.scan(pkgDir)
+	c.entries[filepath.Join(p.dir, "gone")] = scanCacheEntry{Fingerprint: "x"}
+	if err := c.save(cacheFile); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var f scanCacheFile
+	if err := json.Unmarshal([]byte(p.read("scan.json")), &f); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if f.Version != scanCacheVersion || len(f.Dirs) != 1 {
+		t.Fatalf("expected one live dir in cache file: %+v", f)
+	}
+
+	warm := newScanCache()
+	if err := warm.load(cacheFile); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	warm.scan(pkgDir)
+	if h, m := warm.stats(); h != 1 || m != 0 {
+		t.Fatalf("expected warm hit, got hits/misses %d/%d", h, m)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "corrupt", content: "{"},
+		{name: "old_version", content: `{"version": 0, "dirs": {"/x": {"fingerprint": "f"}}}`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := p.write(tt.name+".json", tt.content)
+			c := newScanCache()
+			if err := c.load(path); err != nil || len(c.entries) != 0 {
+				t.Fatalf("expected ignored cache file, err=%v entries=%d", err, len(c.entries))
+			}
+		})
+	}
+
+	if err := newScanCache().load(p.dir); err == nil {
+		t.Fatalf("expected read error for directory path")
+	}
+	if err := c.save(filepath.Join(p.dir, "nope", "scan.json")); err == nil {
+		t.Fatalf("expected write error")
+	}
+}
+
+func TestRun_ScanCacheFlag(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("svc.inject.json", `{
+  "package": "p", "wrapperBase": "Foo", "versionSuffix": "V2",
+  "implType": "Foo", "constructor": "NewFoo",
+  "required": [{ "name": "A", "field": "a", "type": "*A", "nilable": true }]
+}`)
+	cacheFile := p.out("scan-cache.json")
+
+	if err := run([]string{"-spec", specPath, "-out", p.out("svc.gen.go"), "-scan-cache", cacheFile}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.Contains(p.read("scan-cache.json"), `"example.com/proj/di"`) {
+		t.Fatalf("expected scanned imports in cache file:\n%s", p.read("scan-cache.json"))
+	}
+
+	if err := run([]string{"-spec", specPath, "-out", p.out("svc.gen.go"), "-scan-cache", p.dir}); err == nil {
+		t.Fatalf("expected error for unreadable cache path")
+	}
+}
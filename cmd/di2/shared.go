@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// allRoots returns the graph roots followed by the shared section, if any.
+func (g *GraphSpec) allRoots() []*GraphRoot {
+	roots := make([]*GraphRoot, 0, len(g.Roots)+1)
+	for i := range g.Roots {
+		roots = append(roots, &g.Roots[i])
+	}
+	if g.Shared != nil {
+		roots = append(roots, g.Shared)
+	}
+	return roots
+}
+
+// prepareSharedRoot validates the graph's shared section, hands its services to
+// every root and returns it as a root to generate ahead of the others.
+func prepareSharedRoot(g *GraphSpec) GraphRoot {
+	shared := *g.Shared
+	if strings.TrimSpace(shared.Name) == "" {
+		shared.Name = "Shared"
+	}
+	if len(shared.Services) == 0 {
+		die("graph shared services must be non-empty")
+	}
+
+	sortSpecEntries(shared.Services, func(s GraphService) (int, string) { return s.Order, s.Var }, g.PreserveOrder)
+	sort.Slice(shared.Wiring, func(a, b int) bool {
+		wa := shared.Wiring[a]
+		wb := shared.Wiring[b]
+		return wa.To+wa.Call+wa.ArgFrom < wb.To+wb.Call+wb.ArgFrom
+	})
+
+	sharedVars := map[string]bool{}
+	for _, s := range shared.Services {
+		sharedVars[s.Var] = true
+	}
+
+	for i := range g.Roots {
+		root := &g.Roots[i]
+		if root.Name == shared.Name {
+			die("graph root " + root.Name + " has the same name as the shared section")
+		}
+		for _, s := range root.Services {
+			if sharedVars[s.Var] {
+				die("graph root " + root.Name + " service " + s.Var + " is already declared as shared")
+			}
+		}
+		for _, w := range root.Wiring {
+			if sharedVars[w.To] {
+				die("graph root " + root.Name + " wires into shared service " + w.To + "; wire it in the shared section")
+			}
+		}
+		root.SharedName = shared.Name
+		root.SharedServices = shared.Services
+	}
+	return shared
+}
+
+// setWiringArgs computes the argument expression of each wiring entry (the
+// source builder's UnsafeImpl(), or the built instance for shared services)
+// and its guard (see wiringGuard).
+func setWiringArgs(root *GraphRoot) {
+	sharedVars := map[string]bool{}
+	for _, s := range root.SharedServices {
+		sharedVars[s.Var] = true
+	}
+	noUnsafe := map[string]bool{}
+	for _, s := range root.Services {
+		noUnsafe[s.Var] = s.NoUnsafeImpl
+	}
+	for i := range root.Wiring {
+		w := &root.Wiring[i]
+		w.Guard = wiringGuard(*root, *w)
+		if sharedVars[w.ArgFrom] {
+			w.Arg = "shared." + exportName(w.ArgFrom)
+			continue
+		}
+		if noUnsafe[w.ArgFrom] {
+			die("graph root " + root.Name + ": wiring " + w.ArgFrom + " into " + w.To + " needs " + w.ArgFrom + ".UnsafeImpl(), which its spec disables (unsafeImpl.disable)")
+		}
+		w.Arg = w.ArgFrom + "B.UnsafeImpl()"
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Shared graph services
+// -------------------------
+
+func TestGenGraph_SharedServices(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "shared": {
+    "services": [
+      { "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB" }
+    ]
+  },
+  "roots": [
+    {
+      "name": "API",
+      "services": [
+        { "var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API" }
+      ],
+      "wiring": [{ "to": "api", "call": "InjectDB", "argFrom": "db" }]
+    },
+    {
+      "name": "Worker",
+      "services": [
+        { "var": "worker", "facadeCtor": "NewWorkerV4", "facadeType": "*WorkerV4", "implType": "Worker" }
+      ],
+      "wiring": [{ "to": "worker", "call": "InjectDB", "argFrom": "db" }]
+    }
+  ]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out,
+		"type SharedResult struct {",
+		"Db *DB",
+		"func Shared(reg di.Registry) (SharedResult, error) {",
+		"type APIResult struct {",
+		"Api *API",
+		"// shared with other roots (built by Shared)",
+		"Db *DB",
+		"func API(reg di.Registry, shared SharedResult) (APIResult, error) {",
+		"if shared.Db == nil {",
+		`return res, fmt.Errorf("API: shared db %w", di.ErrNotBuilt)`,
+		"res.Db = shared.Db",
+		"apiB.InjectDB(shared.Db)",
+		"func Worker(reg di.Registry, shared SharedResult) (WorkerResult, error) {",
+		"workerB.InjectDB(shared.Db)",
+	)
+	if strings.Contains(out, "dbB.UnsafeImpl()") {
+		t.Fatalf("shared services must be passed as built instances:\n%s", out)
+	}
+}
+
+func TestGenGraph_SharedServicesValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		graph string
+		want  string
+	}{
+		{
+			name:  "empty_shared",
+			graph: `{"package":"p","shared":{"services":[]},"roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}]}]}`,
+			want:  "graph shared services must be non-empty",
+		},
+		{
+			name:  "root_name_collision",
+			graph: `{"package":"p","shared":{"services":[{"var":"db","facadeCtor":"NewDBV4","facadeType":"*DBV4","implType":"DB"}]},"roots":[{"name":"Shared","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}]}]}`,
+			want:  "graph root Shared has the same name as the shared section",
+		},
+		{
+			name:  "var_collision",
+			graph: `{"package":"p","shared":{"services":[{"var":"db","facadeCtor":"NewDBV4","facadeType":"*DBV4","implType":"DB"}]},"roots":[{"name":"App","services":[{"var":"db","facadeCtor":"NewDBV4","facadeType":"*DBV4","implType":"DB"}]}]}`,
+			want:  "graph root App service db is already declared as shared",
+		},
+		{
+			name:  "wiring_into_shared",
+			graph: `{"package":"p","shared":{"services":[{"var":"db","facadeCtor":"NewDBV4","facadeType":"*DBV4","implType":"DB"}]},"roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}],"wiring":[{"to":"db","call":"InjectA","argFrom":"a"}]}]}`,
+			want:  "graph root App wires into shared service db; wire it in the shared section",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			graphPath := p.write("graph.json", tt.graph)
+			assertPanicContains(t, func() { genGraph(graphPath, p.out("graph.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sghaida/odi/di"
+)
+
+// -------------------------
+// di2 graph simulate (what-if mode)
+// -------------------------
+
+// simulateOut is where "di2 graph simulate" prints its report.
+var simulateOut io.Writer = os.Stdout
+
+// Service states reported by simulateGraph.
+const (
+	simBuilt   = "built"
+	simRemoved = "removed"
+	simFails   = "fails"
+	simSkipped = "not built"
+)
+
+// simService is the simulated outcome for one service of a root.
+type simService struct {
+	Var    string
+	Status string
+
+	// Missing are the required deps left unwired (or the group/oneOf error) that
+	// make the build fail; Defaults the registry keys whose defaultExpr engages.
+	Missing  []string
+	Defaults []string
+
+	// Explain is what the facade's Explain() would print after the build.
+	Explain string
+}
+
+// simRoot is the simulated outcome of one generated root function.
+type simRoot struct {
+	Name     string
+	FailedAt string // var of the first failing build ("" if the root builds)
+	Services []simService
+}
+
+// runGraphSimulate implements "di2 graph simulate -graph graph.json -without a,b".
+func runGraphSimulate(args []string) error {
+	fs := flag.NewFlagSet("di2 graph simulate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	graphPath := fs.String("graph", "", "path to graph.json, or a glob of graph fragments")
+	without := fs.String("without", "", "comma-separated service vars and registry keys to treat as missing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*graphPath) == "" {
+		return fmt.Errorf("missing -graph")
+	}
+
+	var missing []string
+	for _, w := range strings.Split(*without, ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			missing = append(missing, w)
+		}
+	}
+	roots, err := simulateGraph(*graphPath, missing)
+	if err != nil {
+		return err
+	}
+	writeSimulation(simulateOut, *graphPath, missing, roots)
+	return nil
+}
+
+// simSpecInfo is what the simulation needs from a linked service spec.
+type simSpecInfo struct {
+	Required []RequiredDep
+	Optional []OptionalDep
+}
+
+// simulateGraph predicts the outcome of every generated root function when the
+// services and registry keys in without are missing.
+//
+// It is static: "when" conditions are assumed true, registry keys not listed are
+// assumed provided, and required deps the graph never wires are assumed set by the
+// service constructor. Services without a linked spec treat each wiring call as a
+// required dep.
+func simulateGraph(graphPath string, without []string) ([]simRoot, error) {
+	g, _ := readGraphSpec(graphPath)
+	applyConfigDefaults(&g.Config)
+	validateGraphSpec(&g)
+	resolveGraphConditions(&g)
+
+	specs := map[string]simSpecInfo{}
+	known := map[string]bool{}
+	for _, root := range g.allRoots() {
+		for _, s := range root.Services {
+			known[s.Var] = true
+			if s.Spec == "" {
+				continue
+			}
+			spec, _ := readServiceSpec(linkedSpecPath(graphPath, s.Spec))
+			specs[s.Var] = simSpecInfo{Required: spec.Required, Optional: spec.Optional}
+			for _, o := range spec.Optional {
+				known[o.RegistryKey] = true
+			}
+		}
+	}
+	known[di.FlagsKey] = true
+
+	gone := map[string]bool{}
+	for _, w := range without {
+		if !known[w] {
+			return nil, fmt.Errorf("simulate: -without %q is neither a service var nor a registry key of a linked spec", w)
+		}
+		gone[w] = true
+	}
+
+	if !g.PreserveOrder {
+		sort.Slice(g.Roots, func(i, j int) bool { return g.Roots[i].Name < g.Roots[j].Name })
+	}
+	if g.Shared != nil {
+		// built first: roots take its result
+		g.Roots = append([]GraphRoot{prepareSharedRoot(&g)}, g.Roots...)
+	}
+
+	out := make([]simRoot, 0, len(g.Roots))
+	for _, root := range g.Roots {
+		root.BuildOrder = dependencyOrder(root)
+		out = append(out, simulateRoot(root, g.Parallel, specs, gone))
+	}
+	return out, nil
+}
+
+func simulateRoot(root GraphRoot, parallel bool, specs map[string]simSpecInfo, gone map[string]bool) simRoot {
+	res := simRoot{Name: root.Name}
+
+	// wired[to][dep] counts the injections the graph makes into each dep, before
+	// (all) and after (live) removing services.
+	all := map[string]map[string]int{}
+	live := map[string]map[string]int{}
+	for _, w := range root.Wiring {
+		dep := wiringDepName(w.Call, specs[w.To])
+		for _, m := range []map[string]map[string]int{all, live} {
+			if m[w.To] == nil {
+				m[w.To] = map[string]int{}
+			}
+		}
+		all[w.To][dep]++
+		if !gone[w.ArgFrom] {
+			live[w.To][dep]++
+		}
+	}
+
+	stageOf := map[string]int{}
+	if parallel {
+		for i, stage := range dependencyStages(root) {
+			for _, s := range stage {
+				stageOf[s.Var] = i
+			}
+		}
+	}
+
+	failedStage := -1
+	for i, s := range root.BuildOrder {
+		stage := i
+		if parallel {
+			stage = stageOf[s.Var]
+		}
+		ss := simService{Var: s.Var}
+		switch {
+		case gone[s.Var]:
+			ss.Status = simRemoved
+			res.Services = append(res.Services, ss)
+			continue
+		case failedStage >= 0 && stage > failedStage:
+			ss.Status = simSkipped
+			res.Services = append(res.Services, ss)
+			continue
+		}
+
+		spec, linked := specs[s.Var]
+		if !linked {
+			for dep := range all[s.Var] {
+				spec.Required = append(spec.Required, RequiredDep{Name: dep})
+			}
+			sort.Slice(spec.Required, func(a, b int) bool { return spec.Required[a].Name < spec.Required[b].Name })
+		}
+		ss.Missing = simMissing(spec.Required, all[s.Var], live[s.Var])
+
+		var resolved, optMissing map[string]string
+		if root.BuildWithRegistry {
+			resolved, optMissing = map[string]string{}, map[string]string{}
+			for _, o := range spec.Optional {
+				switch {
+				case o.EnabledWhen != nil && gone[di.FlagsKey]:
+					optMissing[o.RegistryKey] = "disabled by flag " + o.EnabledWhen.FlagKey
+				case gone[o.RegistryKey] && o.DefaultExpr != "":
+					optMissing[o.RegistryKey] = "used defaultExpr"
+					ss.Defaults = append(ss.Defaults, o.RegistryKey)
+				case gone[o.RegistryKey]:
+					optMissing[o.RegistryKey] = "not provided"
+				default:
+					resolved[o.RegistryKey] = o.Type
+				}
+			}
+		}
+
+		var unwired []string
+		for _, m := range ss.Missing {
+			if !strings.Contains(m, " ") {
+				unwired = append(unwired, m)
+			}
+		}
+		ss.Explain = di.ExplainWiring(unwired, resolved, optMissing)
+
+		ss.Status = simBuilt
+		if len(ss.Missing) > 0 {
+			ss.Status = simFails
+			if failedStage < 0 {
+				failedStage = stage
+				res.FailedAt = s.Var
+			}
+		}
+		res.Services = append(res.Services, ss)
+	}
+	return res
+}
+
+// wiringDepName maps a wiring call ("InjectDB", "TryInjectTopic", "InjectTopics")
+// to the required dep it wires.
+func wiringDepName(call string, spec simSpecInfo) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(call, "Try"), "Inject")
+	for _, d := range spec.Required {
+		if d.Name == name || (d.Plural != "" && d.Plural == name) || d.Name+"s" == name {
+			return d.Name
+		}
+	}
+	return name
+}
+
+// simMissing applies Build's checks (required deps, all-or-nothing groups and
+// oneOf sets) to the simulated wiring. Deps the graph never wires are assumed
+// set by the constructor.
+func simMissing(required []RequiredDep, all, live map[string]int) []string {
+	wired := func(d RequiredDep) bool {
+		if all[d.Name] == 0 {
+			return true
+		}
+		return live[d.Name] >= max(d.Min, 1)
+	}
+
+	var missing []string
+	groups := map[string][]RequiredDep{}
+	oneOfs := map[string][]RequiredDep{}
+	for _, d := range required {
+		switch {
+		case d.Group != "":
+			groups[d.Group] = append(groups[d.Group], d)
+		case d.OneOf != "":
+			oneOfs[d.OneOf] = append(oneOfs[d.OneOf], d)
+		case !wired(d):
+			missing = append(missing, d.Name)
+		}
+	}
+
+	for _, name := range sortedKeys(groups) {
+		var have, lack []string
+		for _, d := range groups[name] {
+			// a group the graph never wires stays off
+			if all[d.Name] > 0 && live[d.Name] > 0 {
+				have = append(have, d.Name)
+			} else {
+				lack = append(lack, d.Name)
+			}
+		}
+		if len(have) > 0 && len(lack) > 0 {
+			missing = append(missing, fmt.Sprintf("group %s missing %s", name, strings.Join(lack, ", ")))
+		}
+	}
+	for _, name := range sortedKeys(oneOfs) {
+		var have []string
+		for _, d := range oneOfs[name] {
+			if live[d.Name] > 0 {
+				have = append(have, d.Name)
+			}
+		}
+		if len(have) != 1 {
+			missing = append(missing, fmt.Sprintf("oneOf %s has %d wired", name, len(have)))
+		}
+	}
+	return missing
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeSimulation prints the simulated roots, with the Explain() output of every
+// service whose wiring differs from a complete build.
+func writeSimulation(w io.Writer, graphPath string, without []string, roots []simRoot) {
+	_, _ = fmt.Fprintf(w, "simulate %s without %s\n", graphPath, strings.Join(without, ", "))
+	for _, r := range roots {
+		verdict := "builds"
+		if r.FailedAt != "" {
+			verdict = "FAILS at " + r.FailedAt
+		}
+		_, _ = fmt.Fprintf(w, "\nroot %s: %s\n", r.Name, verdict)
+
+		width := 0
+		for _, s := range r.Services {
+			width = max(width, len(s.Var))
+		}
+		for _, s := range r.Services {
+			line := fmt.Sprintf("  %-*s  %s", width, s.Var, s.Status)
+			if len(s.Missing) > 0 {
+				line += ": missing " + strings.Join(s.Missing, "; ")
+			}
+			if len(s.Defaults) > 0 {
+				line += " (defaults: " + strings.Join(s.Defaults, ", ") + ")"
+			}
+			_, _ = fmt.Fprintln(w, line)
+		}
+		for _, s := range r.Services {
+			if len(s.Missing) == 0 && !strings.Contains(s.Explain, "optional: missing") {
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "\n  %s.Explain():\n", s.Var)
+			for _, l := range strings.Split(strings.TrimSuffix(s.Explain, "\n"), "\n") {
+				_, _ = fmt.Fprintln(w, "    "+l)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// di2 graph simulate
+// -------------------------
+
+// writeSimulateGraph writes a graph where core needs db (required), a oneOf
+// cache (redis or mem) and resolves tracer/metrics/audit from the registry. db's
+// Conn is never wired by the graph, so it counts as set by the constructor.
+func writeSimulateGraph(p *pkgHarness, parallel bool) string {
+	p.write("specs/db.inject.json", `{
+  "package": "p", "wrapperBase": "DB", "versionSuffix": "V4", "implType": "DB", "constructor": "NewDB",
+  "required": [{ "name": "Conn", "field": "conn", "type": "*Conn", "nilable": true }]
+}`)
+	p.write("specs/core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [
+    { "name": "DB", "field": "db", "type": "*DB", "nilable": true },
+    { "name": "Redis", "field": "redis", "type": "*DB", "nilable": true, "oneOf": "cache" },
+    { "name": "Mem", "field": "mem", "type": "*DB", "nilable": true, "oneOf": "cache" }
+  ],
+  "optional": [
+    { "name": "Tracer", "type": "Tracer", "registryKey": "v4.tracer", "apply": { "kind": "field", "name": "tracer" }, "defaultExpr": "NoopTracer{}" },
+    { "name": "Metrics", "type": "Metrics", "registryKey": "v4.metrics", "apply": { "kind": "field", "name": "metrics" } },
+    { "name": "Audit", "type": "Audit", "registryKey": "v4.audit", "apply": { "kind": "field", "name": "audit" },
+      "enabledWhen": { "flagKey": "audit" } }
+  ]
+}`)
+	par := "false"
+	if parallel {
+		par = "true"
+	}
+	return p.write("specs/graph.json", `{
+  "package": "p", "parallel": `+par+`,
+  "roots": [{
+    "name": "BuildApp", "buildWithRegistry": true,
+    "services": [
+      { "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB", "spec": "db.inject.json" },
+      { "var": "redis", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB", "spec": "db.inject.json" },
+      { "var": "core", "facadeCtor": "NewCoreV4", "facadeType": "*CoreV4", "implType": "Core", "spec": "core.inject.json" },
+      { "var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API" },
+      { "var": "edge", "facadeCtor": "NewEdgeV4", "facadeType": "*EdgeV4", "implType": "Edge" }
+    ],
+    "wiring": [
+      { "to": "core", "call": "InjectDB", "argFrom": "db" },
+      { "to": "core", "call": "InjectRedis", "argFrom": "redis" },
+      { "to": "api", "call": "InjectCore", "argFrom": "core" },
+      { "to": "edge", "call": "InjectAPI", "argFrom": "api" }
+    ]
+  }]
+}`)
+}
+
+func TestRun_GraphSimulate(t *testing.T) {
+	// NOT parallel: swaps simulateOut
+
+	var buf bytes.Buffer
+	old := simulateOut
+	simulateOut = &buf
+	t.Cleanup(func() { simulateOut = old })
+
+	tests := []struct {
+		name     string
+		parallel bool
+		without  string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "nothing_missing",
+			want:    []string{"root BuildApp: builds", "  core   built", "  edge   built"},
+			notWant: []string{"Explain()"},
+		},
+		{
+			name:    "default_engages",
+			without: "v4.tracer, v4.metrics",
+			want: []string{
+				"root BuildApp: builds",
+				"core   built (defaults: v4.tracer)",
+				"core.Explain():",
+				"required: complete",
+				"optional: resolved", "- v4.audit => Audit",
+				"optional: missing", "- v4.metrics => not provided", "- v4.tracer => used defaultExpr",
+			},
+		},
+		{
+			name:    "flags_missing",
+			without: "odi.flags",
+			want:    []string{"root BuildApp: builds", "- v4.audit => disabled by flag audit"},
+		},
+		{
+			name:    "required_service_removed",
+			without: "db",
+			want: []string{
+				"root BuildApp: FAILS at core",
+				"db     removed",
+				"core   fails: missing DB",
+				"api    not built",
+				"edge   not built",
+				"core.Explain():", "required: missing=[DB]",
+			},
+		},
+		{
+			name:    "oneOf_left_empty",
+			without: "redis",
+			want:    []string{"root BuildApp: FAILS at core", "core   fails: missing oneOf cache has 0 wired"},
+		},
+		{
+			name:    "unlinked_service",
+			without: "core",
+			want:    []string{"root BuildApp: FAILS at api", "core   removed", "api    fails: missing Core", "edge   not built"},
+		},
+		{
+			name:     "parallel_later_stages_skipped",
+			parallel: true,
+			without:  "redis",
+			want:     []string{"root BuildApp: FAILS at core", "db     built", "core   fails", "api    not built"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			p := newPkg(t)
+			graphPath := writeSimulateGraph(p, tt.parallel)
+
+			if err := run([]string{"graph", "simulate", "-graph", graphPath, "-without", tt.without}); err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			out := buf.String()
+			assertContainsInOrder(t, out, tt.want...)
+			for _, nw := range tt.notWant {
+				if strings.Contains(out, nw) {
+					t.Fatalf("did not expect %q in:\n%s", nw, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_GraphSimulateErrors(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	graphPath := writeSimulateGraph(p, false)
+
+	err := run([]string{"graph", "simulate", "-without", "db"})
+	if err == nil || !strings.Contains(err.Error(), "missing -graph") {
+		t.Fatalf("expected missing -graph, got %v", err)
+	}
+	err = run([]string{"graph", "simulate", "-graph", graphPath, "-without", "v4.tracr"})
+	if err == nil || !strings.Contains(err.Error(), `-without "v4.tracr" is neither a service var nor a registry key`) {
+		t.Fatalf("expected unknown name error, got %v", err)
+	}
+}
+
+func TestSimMissing_Groups(t *testing.T) {
+	t.Parallel()
+
+	req := []RequiredDep{
+		{Name: "Consumer", Group: "kafka"},
+		{Name: "Producer", Group: "kafka"},
+		{Name: "Topic", Min: 2},
+	}
+	all := map[string]int{"Consumer": 1, "Producer": 1, "Topic": 2}
+
+	tests := []struct {
+		name string
+		live map[string]int
+		want string
+	}{
+		{name: "complete", live: all, want: ""},
+		{name: "group_partial", live: map[string]int{"Consumer": 1, "Topic": 2}, want: "group kafka missing Producer"},
+		{name: "group_off", live: map[string]int{"Topic": 2}, want: ""},
+		{name: "below_min", live: map[string]int{"Consumer": 1, "Producer": 1, "Topic": 1}, want: "Topic"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := strings.Join(simMissing(req, all, tt.live), "; "); got != tt.want {
+				t.Fatalf("simMissing = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
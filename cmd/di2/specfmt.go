@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Canonical spec formatting (di2 fmt)
+// -------------------------
+
+// specLineWidth is the width up to which small objects and arrays of scalars are
+// kept on one line, e.g. { "name": "Alpha", "field": "alpha", "type": "*Alpha" }.
+const specLineWidth = 120
+
+// fmtOut is where "di2 fmt" prints formatted specs and -l file names.
+var fmtOut io.Writer = os.Stdout
+
+// jsonNode is a JSON value that keeps object keys in input order.
+type jsonNode struct {
+	kind   byte // '{', '[' or 0 for scalars
+	keys   []string
+	fields map[string]*jsonNode
+	elems  []*jsonNode
+	lit    string // encoded scalar
+}
+
+// formatSpec returns the canonical form of a service or graph spec: keys in
+// schema order (fields of ServiceSpec/GraphSpec as declared, then unknown keys
+// sorted), two-space indentation, short objects on one line and a trailing
+// newline. It is idempotent, and specs that differ only in whitespace or key
+// order format identically.
+func formatSpec(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	root, err := parseJSONNode(dec)
+	if err != nil {
+		return nil, jsonErrorAt(raw, err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	if root.kind != '{' {
+		return nil, fmt.Errorf("spec must be a JSON object")
+	}
+
+	typ := reflect.TypeFor[ServiceSpec]()
+	if _, ok := root.fields["roots"]; ok {
+		typ = reflect.TypeFor[GraphSpec]()
+	} else if _, ok := root.fields["shared"]; ok {
+		typ = reflect.TypeFor[GraphSpec]()
+	}
+
+	var buf bytes.Buffer
+	writeJSONBlock(&buf, root, typ, "")
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// specSHA256 is the hash generated files record for a spec: the SHA-256 of its
+// canonical form, so whitespace-only edits keep the Spec-SHA256 header stable.
+// Specs that cannot be formatted fall back to hashing the raw bytes.
+func specSHA256(raw []byte) string {
+	if canon, err := formatSpec(raw); err == nil {
+		return sha256Hex(canon)
+	}
+	return sha256Hex(raw)
+}
+
+func parseJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &jsonNode{kind: byte(t)}
+		if t == '{' {
+			n.fields = map[string]*jsonNode{}
+		}
+		for dec.More() {
+			if t == '[' {
+				v, err := parseJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.elems = append(n.elems, v)
+				continue
+			}
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k := kt.(string)
+			v, err := parseJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := n.fields[k]; !dup {
+				n.keys = append(n.keys, k)
+			}
+			n.fields[k] = v // last one wins, like encoding/json
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case json.Number:
+		return &jsonNode{lit: t.String()}, nil
+	case nil:
+		return &jsonNode{lit: "null"}, nil
+	default:
+		return &jsonNode{lit: encodeJSONScalar(t)}, nil
+	}
+}
+
+func encodeJSONScalar(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// orderedKeys returns n's keys in the order of typ's json fields, then the
+// remaining keys sorted. It also returns each key's value type (nil if unknown).
+func orderedKeys(n *jsonNode, typ reflect.Type) ([]string, map[string]reflect.Type) {
+	types := map[string]reflect.Type{}
+	var keys []string
+	switch {
+	case typ != nil && typ.Kind() == reflect.Struct:
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if _, ok := n.fields[name]; ok {
+				keys = append(keys, name)
+				types[name] = f.Type
+			}
+		}
+	case typ != nil && typ.Kind() == reflect.Map:
+		for _, k := range n.keys {
+			types[k] = typ.Elem()
+		}
+	}
+
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k] = true
+	}
+	var rest []string
+	for _, k := range n.keys {
+		if !known[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...), types
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	t = derefType(t)
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return derefType(t.Elem())
+	}
+	return nil
+}
+
+// inlineJSON renders n on one line.
+func inlineJSON(n *jsonNode, typ reflect.Type) string {
+	typ = derefType(typ)
+	switch n.kind {
+	case '{':
+		if len(n.keys) == 0 {
+			return "{}"
+		}
+		keys, types := orderedKeys(n, typ)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = encodeJSONScalar(k) + ": " + inlineJSON(n.fields[k], types[k])
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case '[':
+		parts := make([]string, len(n.elems))
+		for i, e := range n.elems {
+			parts[i] = inlineJSON(e, elemType(typ))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return n.lit
+	}
+}
+
+// writeJSONValue writes n, on one line when it fits after the already written
+// prefix of width used, otherwise as a block.
+func writeJSONValue(buf *bytes.Buffer, n *jsonNode, typ reflect.Type, indent string, used int) {
+	if n.kind == 0 || (n.kind == '{' && len(n.keys) == 0) || (n.kind == '[' && len(n.elems) == 0) {
+		buf.WriteString(inlineJSON(n, typ))
+		return
+	}
+	// arrays of scalars and objects nested at most one level stay on one line
+	maxDepth := 2
+	if n.kind == '[' {
+		maxDepth = 1
+	}
+	if jsonDepth(n) <= maxDepth {
+		if s := inlineJSON(n, typ); used+len(s)+1 <= specLineWidth {
+			buf.WriteString(s)
+			return
+		}
+	}
+	writeJSONBlock(buf, n, typ, indent)
+}
+
+// jsonDepth is 0 for scalars and 1 + the deepest child for objects and arrays.
+func jsonDepth(n *jsonNode) int {
+	if n.kind == 0 {
+		return 0
+	}
+	d := 0
+	for _, e := range n.elems {
+		d = max(d, jsonDepth(e))
+	}
+	for _, v := range n.fields {
+		d = max(d, jsonDepth(v))
+	}
+	return d + 1
+}
+
+func writeJSONBlock(buf *bytes.Buffer, n *jsonNode, typ reflect.Type, indent string) {
+	typ = derefType(typ)
+	inner := indent + "  "
+	if n.kind == '[' {
+		buf.WriteString("[\n")
+		for i, e := range n.elems {
+			buf.WriteString(inner)
+			writeJSONValue(buf, e, elemType(typ), inner, len(inner))
+			if i < len(n.elems)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+		return
+	}
+
+	keys, types := orderedKeys(n, typ)
+	buf.WriteString("{\n")
+	for i, k := range keys {
+		prefix := inner + encodeJSONScalar(k) + ": "
+		buf.WriteString(prefix)
+		writeJSONValue(buf, n.fields[k], types[k], inner, len(prefix))
+		if i < len(keys)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(indent + "}")
+}
+
+// runFmt implements "di2 fmt [-w] [-l] path...": paths are spec files, or
+// directories whose *.inject.json, graph.json and *.graph.json files are formatted.
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("di2 fmt", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	list := fs.Bool("l", false, "list files whose formatting differs from canonical")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: di2 fmt [-w] [-l] spec.json|dir ...")
+	}
+
+	var errs []error
+	for _, f := range specFiles(fs.Args()) {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		out, err := formatSpec(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+			continue
+		}
+		changed := !bytes.Equal(raw, out)
+		if *list && changed {
+			_, _ = fmt.Fprintln(fmtOut, f)
+		}
+		switch {
+		case *write && changed:
+			if err := os.WriteFile(f, out, 0o644); err != nil {
+				errs = append(errs, err)
+			}
+		case !*write && !*list:
+			_, _ = fmtOut.Write(out)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// specFiles expands paths for the spec subcommands: files are kept, directories
+// contribute their *.inject.json, graph.json and *.graph.json files.
+func specFiles(paths []string) []string {
+	var files []string
+	for _, p := range paths {
+		if !dirExists(p) {
+			files = append(files, p)
+			continue
+		}
+		for _, pattern := range []string{"*.inject.json", "graph.json", "*.graph.json"} {
+			m, _ := filepath.Glob(filepath.Join(p, pattern))
+			files = append(files, m...)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
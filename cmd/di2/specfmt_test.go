@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Canonical spec formatting (di2 fmt)
+// -------------------------
+
+func TestFormatSpec_Service(t *testing.T) {
+	t.Parallel()
+
+	raw := `{"required":[{"nilable":true,"type":"*Alpha","field":"alpha","name":"Alpha"}],
+	"zUnknown": 1, "package":"p",  "wrapperBase":"Core","versionSuffix":"V4","implType":"Core","constructor":"NewCore",
+	"aUnknown": {"b": 2, "a": 1.50},
+	"optional":[{"apply":{"name":"tracer","kind":"field"},"name":"Tracer","registryKey":"t","type":"Tracer","defaultExpr":"NoopTracer{} <x> & y"}],
+	"methods":[{"name":"Process","params":[{"name":"ctx","type":"context.Context"},{"name":"req","type":"Request"}],"returns":[{"type":"error"}],"requires":["Alpha"]}],
+	"imports": {}, "codegen": {"buildTags": null}}`
+
+	want := `{
+  "package": "p",
+  "wrapperBase": "Core",
+  "versionSuffix": "V4",
+  "implType": "Core",
+  "constructor": "NewCore",
+  "imports": {},
+  "required": [
+    { "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true }
+  ],
+  "optional": [
+    {
+      "name": "Tracer",
+      "type": "Tracer",
+      "registryKey": "t",
+      "apply": { "kind": "field", "name": "tracer" },
+      "defaultExpr": "NoopTracer{} <x> & y"
+    }
+  ],
+  "methods": [
+    {
+      "name": "Process",
+      "params": [
+        { "name": "ctx", "type": "context.Context" },
+        { "name": "req", "type": "Request" }
+      ],
+      "returns": [
+        { "type": "error" }
+      ],
+      "requires": ["Alpha"]
+    }
+  ],
+  "codegen": { "buildTags": null },
+  "aUnknown": { "a": 1.50, "b": 2 },
+  "zUnknown": 1
+}
+`
+	got, err := formatSpec([]byte(raw))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("formatSpec mismatch\n got:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := formatSpec(got)
+	if err != nil || !bytes.Equal(again, got) {
+		t.Fatalf("formatSpec must be idempotent, got:\n%s (err=%v)", again, err)
+	}
+}
+
+func TestFormatSpec_GraphKeyOrder(t *testing.T) {
+	t.Parallel()
+
+	got, err := formatSpec([]byte(`{"roots":[{"wiring":[],"services":[{"implType":"A","var":"a","facadeType":"*AV4","facadeCtor":"NewAV4"}],"name":"App"}],"package":"p","parallel":true}`))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, string(got),
+		`"package": "p",`,
+		`"parallel": true,`,
+		`"roots": [`,
+		`"name": "App",`,
+		`{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }`,
+		`"wiring": []`,
+	)
+}
+
+func TestFormatSpec_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "not_object", raw: `[1]`, want: "spec must be a JSON object"},
+		{name: "trailing", raw: `{} {}`, want: "unexpected data after the top-level value"},
+		{name: "invalid", raw: `{"a": }`, want: "missing value after object key"},
+		{name: "empty", raw: ``, want: "EOF"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := formatSpec([]byte(tt.raw))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSpecSHA256_IgnoresWhitespaceAndKeyOrder(t *testing.T) {
+	t.Parallel()
+
+	a := specSHA256([]byte(`{"package":"p","wrapperBase":"Core"}`))
+	b := specSHA256([]byte("{\n\t\"wrapperBase\" : \"Core\",\n\n\t\"package\": \"p\"\n}\n"))
+	if a != b {
+		t.Fatalf("whitespace/key order changed the hash: %s != %s", a, b)
+	}
+	if c := specSHA256([]byte(`{"package":"q","wrapperBase":"Core"}`)); c == a {
+		t.Fatalf("different specs must hash differently")
+	}
+	if got, want := specSHA256([]byte("not json")), sha256Hex([]byte("not json")); got != want {
+		t.Fatalf("unformattable specs hash their raw bytes: got %s want %s", got, want)
+	}
+}
+
+func TestGenService_SpecHashStableAcrossReformat(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	genService(specPath, p.out("a.gen.go"), genOptions{})
+	before := p.read("a.gen.go")
+
+	formatted, err := formatSpec([]byte(p.read("specs/alpha.inject.json")))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	p.write("specs/alpha.inject.json", string(formatted))
+	genService(specPath, p.out("a.gen.go"), genOptions{})
+	if after := p.read("a.gen.go"); after != before {
+		t.Fatalf("reformatting the spec changed the output:\n%s\nvs\n%s", before, after)
+	}
+}
+
+func TestRun_Fmt(t *testing.T) {
+	// NOT parallel: swaps fmtOut
+
+	var buf bytes.Buffer
+	old := fmtOut
+	fmtOut = &buf
+	t.Cleanup(func() { fmtOut = old })
+
+	p := newPkg(t)
+	messy := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	clean := p.write("specs/beta.inject.json", "{\n  \"package\": \"p\"\n}\n")
+	graph := p.write("specs/graph.json", `{"package":"p","roots":[]}`)
+	p.write("specs/notes.json", `not a spec`)
+
+	// stdout
+	if err := run([]string{"fmt", messy}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "{\n  \"package\": \"p\",\n") {
+		t.Fatalf("expected formatted spec on stdout, got:\n%s", buf.String())
+	}
+	if p.read("specs/alpha.inject.json") == buf.String() {
+		t.Fatalf("printing must not rewrite the file")
+	}
+
+	// -l over a directory lists only files that differ (notes.json is not a spec)
+	buf.Reset()
+	if err := run([]string{"fmt", "-l", p.out("specs")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, want := buf.String(), messy+"\n"+graph+"\n"; got != want {
+		t.Fatalf("-l got %q want %q", got, want)
+	}
+
+	// -w rewrites them; a second -l lists nothing
+	if err := run([]string{"fmt", "-w", p.out("specs")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	buf.Reset()
+	if err := run([]string{"fmt", "-l", p.out("specs")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no files to differ after -w, got %q", buf.String())
+	}
+	if p.read("specs/beta.inject.json") != "{\n  \"package\": \"p\"\n}\n" {
+		t.Fatalf("canonical file %s must be untouched", clean)
+	}
+
+	if err := run([]string{"fmt"}); err == nil || !strings.Contains(err.Error(), "usage: di2 fmt") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+	if err := run([]string{"fmt", p.out("specs/notes.json")}); err == nil || !strings.Contains(err.Error(), "notes.json") {
+		t.Fatalf("expected format error naming the file, got %v", err)
+	}
+}
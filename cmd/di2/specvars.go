@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// Spec variables (${NAME}, -var, -vars)
+// -------------------------
+
+// specVars are substituted for ${NAME} in the string values of specs and
+// graphs. run sets them from -vars and -var before any spec is read; without
+// those flags they are nil and every reference is undefined.
+var specVars map[string]string
+
+var (
+	// specVarRef matches ${NAME} and $${NAME}, an escaped (literal) ${NAME}.
+	specVarRef  = regexp.MustCompile(`\$?\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+	specVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// loadSpecVars merges the JSON object of string values in file (if any) with
+// the -var assignments (NAME=value), which win.
+func loadSpecVars(file string, assigns []string) (map[string]string, error) {
+	vars := map[string]string{}
+	if file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &vars); err != nil {
+			return nil, fmt.Errorf("-vars %s: want a JSON object of strings: %w", file, jsonErrorAt(raw, err))
+		}
+	}
+	for _, a := range assigns {
+		name, value, ok := strings.Cut(a, "=")
+		if !ok || !specVarName.MatchString(name) {
+			return nil, fmt.Errorf("-var %q: want NAME=value", a)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// renderSpecVars returns raw with specVars substituted, dying on a reference
+// to an undefined variable. Raw without references, or that does not parse,
+// is returned unchanged (decoding reports parse errors with their location).
+func renderSpecVars(path string, raw []byte) []byte {
+	out, err := expandSpecVars(raw)
+	if err != nil {
+		die(path + ": " + err.Error())
+	}
+	return out
+}
+
+// expandSpecVars is renderSpecVars returning the error.
+func expandSpecVars(raw []byte) ([]byte, error) {
+	if !bytes.Contains(raw, []byte("${")) {
+		return raw, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if dec.Decode(&doc) != nil {
+		return raw, nil
+	}
+
+	var undefined []string
+	doc = expandSpecValue(doc, "", &undefined)
+	if len(undefined) > 0 {
+		sort.Strings(undefined)
+		return nil, fmt.Errorf("undefined spec variable(s): %s (set them with -var NAME=value or -vars file.json)", strings.Join(undefined, ", "))
+	}
+	return json.Marshal(doc)
+}
+
+// expandSpecValue substitutes the references in every string of v, collecting
+// "<pointer>: ${NAME}" for undefined ones.
+func expandSpecValue(v any, pointer string, undefined *[]string) any {
+	switch t := v.(type) {
+	case string:
+		return specVarRef.ReplaceAllStringFunc(t, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			name := ref[2 : len(ref)-1]
+			value, ok := specVars[name]
+			if !ok {
+				*undefined = append(*undefined, pointer+": "+ref)
+			}
+			return value
+		})
+	case []any:
+		for i, e := range t {
+			t[i] = expandSpecValue(e, fmt.Sprintf("%s/%d", pointer, i), undefined)
+		}
+	case map[string]any:
+		for k, e := range t {
+			t[k] = expandSpecValue(e, pointer+"/"+jsonPointerEscape(k), undefined)
+		}
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Spec variables (${NAME}, -var, -vars)
+// -------------------------
+
+func setSpecVars(t *testing.T, vars map[string]string) {
+	t.Helper()
+	old := specVars
+	specVars = vars
+	t.Cleanup(func() { specVars = old })
+}
+
+func TestExpandSpecVars(t *testing.T) {
+	// NOT parallel: swaps specVars
+	setSpecVars(t, map[string]string{"SUFFIX": "V5", "MOD": "example.com/proj"})
+
+	got, err := expandSpecVars([]byte(`{"versionSuffix": "${SUFFIX}", "imports": {"di": "${MOD}/di"}, "n": 1, "doc": "$${SUFFIX} is ${SUFFIX}"}`))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if want := `{"doc":"${SUFFIX} is V5","imports":{"di":"example.com/proj/di"},"n":1,"versionSuffix":"V5"}`; string(got) != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+
+	raw := []byte(`{"versionSuffix": "V4"}`)
+	if got, _ := expandSpecVars(raw); string(got) != string(raw) {
+		t.Fatalf("specs without references must be returned unchanged, got %s", got)
+	}
+
+	_, err = expandSpecVars([]byte(`{"required": [{"type": "${DB}"}], "package": "${PKG}"}`))
+	if err == nil || !strings.HasPrefix(err.Error(), "undefined spec variable(s): /package: ${PKG}, /required/0/type: ${DB} (") {
+		t.Fatalf("expected undefined variables, got %v", err)
+	}
+}
+
+func TestLoadSpecVars(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	file := p.write("vars.json", `{"SUFFIX": "V4", "PKG": "p"}`)
+
+	vars, err := loadSpecVars(file, []string{"SUFFIX=V5", "EMPTY="})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if vars["SUFFIX"] != "V5" || vars["PKG"] != "p" || len(vars) != 3 {
+		t.Fatalf("-var must win over -vars, got %v", vars)
+	}
+
+	for _, bad := range []string{"SUFFIX", "1X=a", "A-B=c"} {
+		if _, err := loadSpecVars("", []string{bad}); err == nil || !strings.Contains(err.Error(), "want NAME=value") {
+			t.Fatalf("%s: expected a usage error, got %v", bad, err)
+		}
+	}
+	if _, err := loadSpecVars(p.write("bad.json", `{"N": 1}`), nil); err == nil || !strings.Contains(err.Error(), "want a JSON object of strings") {
+		t.Fatalf("expected a vars file error, got %v", err)
+	}
+}
+
+func TestRun_SpecVars(t *testing.T) {
+	// NOT parallel: run sets specVars
+	setSpecVars(t, nil)
+	p := newPkg(t)
+	writeDISource(p)
+	spec := writeBatchSpec(p, "alpha", "Alpha", "${SUFFIX}")
+
+	// one spec, two facade versions
+	for _, suffix := range []string{"V4", "V5"} {
+		out := p.out("alpha_" + strings.ToLower(suffix) + ".gen.go")
+		if err := run([]string{"-spec", spec, "-var", "SUFFIX=" + suffix, "-out", out}); err != nil {
+			t.Fatalf("run %s: %v", suffix, err)
+		}
+		if got := mustReadString(t, out); !strings.Contains(got, "type Alpha"+suffix+" struct") {
+			t.Fatalf("%s: facade not renamed:\n%s", suffix, got)
+		}
+	}
+
+	// batch output names use the rendered versionSuffix
+	vars := p.write("vars.json", `{"SUFFIX": "V6"}`)
+	if err := run([]string{"-specs", p.out("specs"), "-vars", vars, "-out", p.dir}); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if !fileExists(p.out("alpha_v6.gen.go")) {
+		t.Fatal("expected alpha_v6.gen.go")
+	}
+
+	specVars = nil
+	assertPanicContains(t, func() {
+		_ = run([]string{"-spec", spec, "-out", p.out("x.gen.go")})
+	}, spec+": undefined spec variable(s): /versionSuffix: ${SUFFIX}")
+	if err := run([]string{"-spec", spec, "-var", "oops", "-out", p.out("x.gen.go")}); err == nil {
+		t.Fatal("expected a -var usage error")
+	}
+}
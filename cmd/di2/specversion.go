@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// -------------------------
+// Spec versioning and migration (di2 migrate spec)
+// -------------------------
+
+// currentSpecVersion is the spec format di2 generates from. Specs without
+// "specVersion" are version 1; older versions are upgraded in memory before
+// generation and rewritten on disk by "di2 migrate spec".
+const currentSpecVersion = 2
+
+// migrateOut is where "di2 migrate spec" prints migrated specs and -l file names.
+var migrateOut io.Writer = os.Stdout
+
+// specMigration upgrades a decoded spec from version From to From+1. Service
+// and Graph are applied to service and graph specs; a nil func leaves that kind
+// unchanged.
+type specMigration struct {
+	From    int
+	Summary string
+	Service func(spec map[string]any)
+	Graph   func(spec map[string]any)
+}
+
+// specMigrations lists every upgrade step in version order. A format change
+// (renamed field, new required block) bumps currentSpecVersion and appends a step
+// here, so existing specs keep generating unchanged.
+var specMigrations = []specMigration{
+	{
+		From:    1,
+		Summary: "add specVersion; make injectPolicy.onOverwrite explicit (v1 defaulted it to error)",
+		Service: func(spec map[string]any) {
+			policy, _ := spec["injectPolicy"].(map[string]any)
+			if policy == nil {
+				policy = map[string]any{}
+				spec["injectPolicy"] = policy
+			}
+			if s, _ := policy["onOverwrite"].(string); s == "" {
+				policy["onOverwrite"] = "error"
+			}
+		},
+	},
+}
+
+// specVersionOf returns the "specVersion" of a decoded spec (1 when absent).
+func specVersionOf(spec map[string]any) (int, error) {
+	v, ok := spec["specVersion"]
+	if !ok {
+		return 1, nil
+	}
+	n, isNum := v.(json.Number)
+	ver, err := strconv.Atoi(string(n))
+	if !isNum || err != nil || ver < 1 {
+		return 0, fmt.Errorf("specVersion must be a positive integer, got %v", v)
+	}
+	if ver > currentSpecVersion {
+		return 0, fmt.Errorf("specVersion %d is newer than this di2 supports (%d); upgrade di2", ver, currentSpecVersion)
+	}
+	return ver, nil
+}
+
+// migrateSpec upgrades a raw spec to currentSpecVersion. It returns the migrated
+// JSON (compact, keys sorted) and the summaries of the applied steps; a spec
+// that is already current is returned unchanged with no steps.
+func migrateSpec(raw []byte, graph bool) ([]byte, []string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var spec map[string]any
+	if err := dec.Decode(&spec); err != nil {
+		return nil, nil, jsonErrorAt(raw, err)
+	}
+	ver, err := specVersionOf(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ver == currentSpecVersion {
+		return raw, nil, nil
+	}
+
+	var applied []string
+	for _, m := range specMigrations {
+		if m.From < ver {
+			continue
+		}
+		step := m.Service
+		if graph {
+			step = m.Graph
+		}
+		if step != nil {
+			step(spec)
+		}
+		applied = append(applied, fmt.Sprintf("v%d -> v%d: %s", m.From, m.From+1, m.Summary))
+	}
+	spec["specVersion"] = currentSpecVersion
+
+	out, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, applied, nil
+}
+
+// isGraphSpec reports whether raw looks like a graph spec (has roots or shared).
+func isGraphSpec(raw []byte) bool {
+	var head struct {
+		Roots  json.RawMessage `json:"roots"`
+		Shared json.RawMessage `json:"shared"`
+	}
+	_ = json.Unmarshal(raw, &head)
+	return head.Roots != nil || head.Shared != nil
+}
+
+// decodeSpec decodes raw into v, a *ServiceSpec or *GraphSpec, upgrading it to
+// currentSpecVersion first when it is older. Decoding errors carry the line and
+// column of the offending input (see jsonErrorAt).
+func decodeSpec(raw []byte, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return jsonErrorAt(raw, err)
+	}
+	_, graph := v.(*GraphSpec)
+	upgraded, steps, err := migrateSpec(raw, graph)
+	if err != nil || len(steps) == 0 {
+		return err
+	}
+	return json.Unmarshal(upgraded, v)
+}
+
+// runMigrate implements "di2 migrate spec [-w] [-l] path...": specs are upgraded
+// to currentSpecVersion and printed in canonical form (see formatSpec).
+func runMigrate(args []string) error {
+	if len(args) == 0 || args[0] != "spec" {
+		return fmt.Errorf("usage: di2 migrate spec [-w] [-l] spec.json|dir ...")
+	}
+	fs := flag.NewFlagSet("di2 migrate spec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	list := fs.Bool("l", false, "list files that need migration, with the steps that apply")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: di2 migrate spec [-w] [-l] spec.json|dir ...")
+	}
+
+	var errs []error
+	for _, f := range specFiles(fs.Args()) {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		migrated, steps, err := migrateSpec(raw, isGraphSpec(raw))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+			continue
+		}
+		if *list {
+			if len(steps) > 0 {
+				_, _ = fmt.Fprintln(migrateOut, f)
+				for _, s := range steps {
+					_, _ = fmt.Fprintln(migrateOut, "\t"+s)
+				}
+			}
+			continue
+		}
+		if *write && len(steps) == 0 {
+			continue // already current: leave the file (and its formatting) alone
+		}
+		out, err := formatSpec(migrated)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+			continue
+		}
+		if !*write {
+			_, _ = migrateOut.Write(out)
+			continue
+		}
+		if err := os.WriteFile(f, out, 0o644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
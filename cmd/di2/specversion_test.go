@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Spec versioning and migration (di2 migrate spec)
+// -------------------------
+
+func TestMigrateSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		raw       string
+		graph     bool
+		want      string
+		wantSteps int
+	}{
+		{
+			name:      "v1_service",
+			raw:       `{"package":"p","wrapperBase":"A","min":1.50}`,
+			want:      `{"injectPolicy":{"onOverwrite":"error"},"min":1.50,"package":"p","specVersion":2,"wrapperBase":"A"}`,
+			wantSteps: 1,
+		},
+		{
+			name:      "v1_service_keeps_policy",
+			raw:       `{"specVersion":1,"injectPolicy":{"onOverwrite":"ignore"}}`,
+			want:      `{"injectPolicy":{"onOverwrite":"ignore"},"specVersion":2}`,
+			wantSteps: 1,
+		},
+		{
+			name:      "v1_graph",
+			raw:       `{"package":"p","roots":[]}`,
+			graph:     true,
+			want:      `{"package":"p","roots":[],"specVersion":2}`,
+			wantSteps: 1,
+		},
+		{
+			name: "current_untouched",
+			raw:  `{ "specVersion": 2, "package": "p" }`,
+			want: `{ "specVersion": 2, "package": "p" }`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, steps, err := migrateSpec([]byte(tt.raw), tt.graph)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("migrateSpec = %s, want %s", got, tt.want)
+			}
+			if len(steps) != tt.wantSteps {
+				t.Fatalf("steps = %q, want %d", steps, tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestMigrateSpec_Errors(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]string{
+		`{"specVersion": 3}`:   "specVersion 3 is newer than this di2 supports (2); upgrade di2",
+		`{"specVersion": 0}`:   "specVersion must be a positive integer, got 0",
+		`{"specVersion": 1.5}`: "specVersion must be a positive integer, got 1.5",
+		`{"specVersion": "2"}`: "specVersion must be a positive integer, got 2",
+		`[]`:                   "cannot unmarshal array",
+	} {
+		if _, _, err := migrateSpec([]byte(raw), false); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected %q, got %v", raw, want, err)
+		}
+	}
+}
+
+func TestGenService_OldSpecVersionUpgradedInMemory(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+
+	v1 := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	genService(v1, p.out("v1.gen.go"), genOptions{})
+
+	migrated, _, err := migrateSpec([]byte(p.read("specs/alpha.inject.json")), false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	v2 := p.write("specs/alpha2.inject.json", string(migrated))
+	genService(v2, p.out("v2.gen.go"), genOptions{})
+
+	// only the spec path and hash differ
+	specRef := regexp.MustCompile(`alpha2?\.inject\.json|[0-9a-f]{64}`)
+	if a, b := specRef.ReplaceAllString(p.read("v1.gen.go"), ""), specRef.ReplaceAllString(p.read("v2.gen.go"), ""); a != b {
+		t.Fatalf("v1 and migrated spec generate differently:\n%s\nvs\n%s", a, b)
+	}
+
+	p.write("specs/alpha.inject.json", `{"specVersion": 9, "package": "p"}`)
+	assertPanicContains(t, func() { genService(v1, p.out("v1.gen.go"), genOptions{}) }, "specVersion 9 is newer")
+}
+
+func TestRun_MigrateSpec(t *testing.T) {
+	// NOT parallel: swaps migrateOut
+
+	var buf bytes.Buffer
+	old := migrateOut
+	migrateOut = &buf
+	t.Cleanup(func() { migrateOut = old })
+
+	p := newPkg(t)
+	svc := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	current := "{\"specVersion\":2,\n\"package\":\"p\"}"
+	p.write("specs/beta.inject.json", current)
+	graph := p.write("specs/graph.json", `{"package":"p","roots":[]}`)
+
+	if err := run([]string{"migrate", "spec", "-l", p.out("specs")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		svc+"\n\tv1 -> v2: add specVersion; make injectPolicy.onOverwrite explicit",
+		graph+"\n\tv1 -> v2:",
+	)
+	if strings.Contains(buf.String(), "beta") {
+		t.Fatalf("current specs must not be listed:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := run([]string{"migrate", "spec", graph}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, want := buf.String(), "{\n  \"specVersion\": 2,\n  \"package\": \"p\",\n  \"roots\": []\n}\n"; got != want {
+		t.Fatalf("stdout got %q want %q", got, want)
+	}
+
+	if err := run([]string{"migrate", "spec", "-w", p.out("specs")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, p.read("specs/alpha.inject.json"),
+		`"specVersion": 2,`, `"injectPolicy": { "onOverwrite": "error" },`, `"required": [`)
+	if p.read("specs/beta.inject.json") != current {
+		t.Fatalf("current spec must be left untouched")
+	}
+	buf.Reset()
+	if err := run([]string{"migrate", "spec", "-l", p.out("specs")}); err != nil || buf.Len() != 0 {
+		t.Fatalf("expected nothing left to migrate, got %q (err=%v)", buf.String(), err)
+	}
+
+	for _, args := range [][]string{{"migrate"}, {"migrate", "graph"}, {"migrate", "spec"}} {
+		if err := run(args); err == nil || !strings.Contains(err.Error(), "usage: di2 migrate spec") {
+			t.Fatalf("%v: expected usage error, got %v", args, err)
+		}
+	}
+}
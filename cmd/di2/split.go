@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// -------------------------
+// Graph output splitting (-split)
+// -------------------------
+
+// splitGraphOutPath is the file of root in the -split output directory:
+// <outDir>/graph_<snake(root)>.gen.go ("Admin" -> graph_admin.gen.go).
+func splitGraphOutPath(outDir, root string) string {
+	return filepath.Join(outDir, "graph_"+snakeCase(root)+".gen.go")
+}
+
+// emitSplitGraph renders every root of g (the shared section included) into its
+// own file in outDir and returns the files in root order. Roots of one graph
+// share a package, so the files compile together like the single-file output;
+// in dry-run mode each is printed under a "// ---- path ----" header.
+func emitSplitGraph(g GraphSpec, graphPath, outDir, graphHash string, required []GoImport, opts genOptions) []string {
+	outs := make([]string, 0, len(g.Roots))
+	seen := map[string]string{}
+	for _, r := range g.Roots {
+		out := splitGraphOutPath(outDir, r.Name)
+		if prev, ok := seen[out]; ok {
+			die(fmt.Sprintf("-split: graph roots %s and %s both map to %s", prev, r.Name, filepath.Base(out)))
+		}
+		seen[out] = r.Name
+		outs = append(outs, out)
+	}
+
+	for i, r := range g.Roots {
+		part := g
+		part.Roots = []GraphRoot{r}
+		if i > 0 {
+			part.Manifest = "" // embedded once, by the first file
+		}
+		if opts.DryRun != nil {
+			_, _ = fmt.Fprintf(opts.DryRun, "// ---- %s ----\n", filepath.ToSlash(outs[i]))
+		}
+		emitGraph(part, graphPath, outs[i], graphHash, required, opts)
+	}
+	return outs
+}
+
+// importNames maps the paths of imps to the package names they are used by.
+func importNames(imps []GoImport) map[string]string {
+	names := make(map[string]string, len(imps))
+	for _, imp := range imps {
+		name := imp.Name
+		if strings.TrimSpace(name) == "" {
+			name = path.Base(imp.Path)
+		}
+		names[imp.Path] = name
+	}
+	return names
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Graph output splitting (-split)
+// -------------------------
+
+const splitTestGraph = `{
+  "package": "p",
+  "shared": {
+    "services": [
+      { "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB" }
+    ]
+  },
+  "roots": [
+    {
+      "name": "WorkerApp",
+      "services": [
+        { "var": "worker", "facadeCtor": "NewWorkerV4", "facadeType": "*WorkerV4", "implType": "Worker" }
+      ],
+      "wiring": [{ "to": "worker", "call": "InjectDB", "argFrom": "db" }]
+    },
+    {
+      "name": "API",
+      "services": [
+        { "var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API" }
+      ],
+      "wiring": [{ "to": "api", "call": "InjectDB", "argFrom": "db" }]
+    }
+  ]
+}`
+
+func TestRun_GraphSplit(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", splitTestGraph)
+
+	if err := run([]string{"-graph", graphPath, "-split", "-out", p.out(".")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	files := map[string]struct{ want, notWant string }{
+		"graph_shared.gen.go":     {want: "func Shared(reg di.Registry) (SharedResult, error) {", notWant: "func API("},
+		"graph_api.gen.go":        {want: "func API(reg di.Registry, shared SharedResult) (APIResult, error) {", notWant: "func Shared("},
+		"graph_worker_app.gen.go": {want: "func WorkerApp(reg di.Registry, shared SharedResult) (WorkerAppResult, error) {", notWant: "func API("},
+	}
+	for name, tc := range files {
+		out := p.read(name)
+		assertContainsInOrder(t, out,
+			"// Code generated by (di v2); DO NOT EDIT.",
+			"// Graph: "+graphPath,
+			"package p",
+			tc.want,
+		)
+		if strings.Contains(out, tc.notWant) {
+			t.Fatalf("%s must hold only its root, found %q:\n%s", name, tc.notWant, out)
+		}
+	}
+}
+
+func TestRun_GraphSplitDryRun(t *testing.T) {
+	// NOT parallel: swaps dryRunOut
+
+	var buf bytes.Buffer
+	old := dryRunOut
+	dryRunOut = &buf
+	t.Cleanup(func() { dryRunOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", splitTestGraph)
+
+	if err := run([]string{"-graph", graphPath, "-split", "-dry-run", "-out", p.out(".")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		"// ---- "+p.out("graph_shared.gen.go")+" ----\n",
+		"func Shared(",
+		"// ---- "+p.out("graph_api.gen.go")+" ----\n",
+		"func API(",
+		"// ---- "+p.out("graph_worker_app.gen.go")+" ----\n",
+		"func WorkerApp(",
+	)
+	if _, err := os.Stat(p.out("graph_api.gen.go")); !os.IsNotExist(err) {
+		t.Fatalf("dry-run must not write split files (err=%v)", err)
+	}
+}
+
+func TestRun_GraphSplitErrors(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", splitTestGraph)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "needs_graph", args: []string{"-spec", specPath, "-split", "-out", p.out(".")}, want: "-split needs -graph"},
+		{name: "api_diff_file", args: []string{"-graph", graphPath, "-split", "-api-diff", "old.go", "-out", p.out(".")}, want: "-split supports only -api-diff=prev"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := run(tt.args); err == nil || err.Error() != tt.want {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	collide := p.write("collide.json", `{
+  "package": "p",
+  "roots": [
+    { "name": "APIApp", "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }] },
+    { "name": "ApiApp", "services": [{ "var": "b", "facadeCtor": "NewBV4", "facadeType": "*BV4", "implType": "B" }] }
+  ]
+}`)
+	assertPanicContains(t, func() {
+		genGraph(collide, p.out("."), genOptions{Split: true})
+	}, "-split: graph roots APIApp and ApiApp both map to graph_api_app.gen.go")
+}
+
+func TestDropUnusedImports(t *testing.T) {
+	t.Parallel()
+
+	src := []byte(`package p
+
+import (
+	"fmt"
+	alpha "example.com/svc/alpha"
+	"example.com/svc/beta"
+)
+
+var _ = fmt.Sprint(alpha.X)
+`)
+	out := string(dropUnusedImports(src, importNames([]GoImport{
+		{Name: "alpha", Path: "example.com/svc/alpha"},
+		{Path: "example.com/svc/beta"},
+	})))
+
+	assertHasImport(t, out, "fmt")
+	if !strings.Contains(out, `alpha "example.com/svc/alpha"`) {
+		t.Fatalf("used service import must be kept:\n%s", out)
+	}
+	assertNotHasImport(t, out, "example.com/svc/beta")
+}
+
+func TestDropUnusedImports_KeepsGroupsWithoutGaps(t *testing.T) {
+	t.Parallel()
+
+	src := []byte(`package p
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	alpha "example.com/svc/alpha"
+	"example.com/svc/beta"
+)
+
+var _ = maps.Keys(alpha.X)
+`)
+	out := string(dropUnusedImports(src, managedImports))
+
+	want := "import (\n\t\"maps\"\n\n\talpha \"example.com/svc/alpha\"\n\t\"example.com/svc/beta\"\n)\n"
+	if !strings.Contains(out, want) {
+		t.Fatalf("expected grouped imports without gaps:\n%s", out)
+	}
+
+	// Dropping the whole stdlib group must not leave a leading blank line.
+	src = []byte("package p\n\nimport (\n\t\"fmt\"\n\n\talpha \"example.com/svc/alpha\"\n)\n\nvar _ = alpha.X\n")
+	out = string(dropUnusedImports(src, managedImports))
+	if !strings.Contains(out, "import (\n\talpha \"example.com/svc/alpha\"\n)\n") {
+		t.Fatalf("expected the emptied stdlib group to disappear:\n%s", out)
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// -------------------------
+// go:generate directive sync (di2 sync-directives)
+// -------------------------
+
+// syncOut is where "di2 sync-directives" reports changed directives.
+var syncOut io.Writer = os.Stdout
+
+// directiveLine is a //go:generate line of a package file.
+type directiveLine struct {
+	File  string
+	Index int // 0-based line index
+	Text  string
+	DI2   bool // runs di2 (as opposed to another generator)
+
+	Input string   // resolved -spec/-graph file, "" if none
+	Specs string   // resolved -specs dir
+	Glob  string   // resolved -graph glob
+	Out   string   // -out as written
+	Extra []string // other arguments, in order
+}
+
+// ownedSpec is a service or graph spec of a package and the directive it needs.
+type ownedSpec struct {
+	Path  string // absolute
+	Graph bool
+	Owner string // file declaring the impl type ("" for graphs)
+	Out   string // default -out for a new directive
+}
+
+// fileEdit collects the line changes of one file.
+type fileEdit struct {
+	replace map[int]string
+	remove  map[int]bool
+	insert  []string // new directives
+}
+
+// runSyncDirectives makes the di2 go:generate lines of every package under the
+// given dirs (default ".") match the specs they hold: one directive per spec,
+// in the file declaring its impl type, with a consistent "go run" command.
+// Directives of missing specs and duplicates are removed. With -n it only
+// prints the changes.
+func runSyncDirectives(args []string) error {
+	fset := flag.NewFlagSet("di2 sync-directives", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	dryRun := fset.Bool("n", false, "print the changes without writing them")
+	genCmd := fset.String("gen-cmd", "", "di2 package (or relative path) to run (default: the one the package already uses)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	roots := fset.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	for _, root := range roots {
+		if !dirExists(root) {
+			return fmt.Errorf("sync-directives: %s is not a directory", root)
+		}
+		for _, dir := range syncPackageDirs(root) {
+			if err := syncPackageDirectives(dir, *genCmd, *dryRun); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncPackageDirs lists the directories under root holding Go files or a specs
+// directory, skipping the directories go skips.
+func syncPackageDirs(root string) []string {
+	var dirs []string
+	_ = filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || !e.IsDir() {
+			return nil
+		}
+		name := e.Name()
+		if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+		goFiles, _ := filepath.Glob(filepath.Join(p, "*.go"))
+		if len(goFiles) > 0 || dirExists(filepath.Join(p, "specs")) {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// syncPackageDirectives syncs the directives of the package in dir.
+func syncPackageDirectives(dir, genCmd string, dryRun bool) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	files := syncSourceFiles(absDir)
+	specs := packageSpecs(absDir, files)
+	lines := readDirectiveLines(absDir, files)
+	if len(specs) == 0 && len(lines) == 0 {
+		return nil
+	}
+	if genCmd == "" {
+		genCmd = packageGenCmd(absDir, lines)
+	}
+
+	covered := map[string]bool{} // specs handled by a batch, glob or foreign directive
+	for _, l := range lines {
+		switch {
+		case !l.DI2 && l.Input != "":
+			covered[l.Input] = true
+		case l.Specs != "":
+			for _, s := range specs {
+				if !s.Graph && filepath.Dir(s.Path) == l.Specs {
+					covered[s.Path] = true
+				}
+			}
+		case l.Glob != "":
+			m, _ := filepath.Glob(l.Glob)
+			for _, p := range m {
+				covered[p] = true
+			}
+		}
+	}
+
+	edits := map[string]*fileEdit{}
+	edit := func(file string) *fileEdit {
+		if edits[file] == nil {
+			edits[file] = &fileEdit{replace: map[int]string{}, remove: map[int]bool{}}
+		}
+		return edits[file]
+	}
+
+	seen := map[string]bool{}
+	for _, l := range lines {
+		if !l.DI2 || l.Input == "" {
+			continue
+		}
+		e := edit(l.File)
+		if !fileExists(l.Input) || seen[l.Input] {
+			e.remove[l.Index] = true // spec gone, or a duplicate directive
+			continue
+		}
+		seen[l.Input] = true
+		if want := directiveText(absDir, genCmd, l.Input, isGraphSpecFile(l.Input), l.Out, l.Extra); want != l.Text {
+			e.replace[l.Index] = want
+		}
+	}
+
+	for _, s := range specs {
+		if seen[s.Path] || covered[s.Path] {
+			continue
+		}
+		owner := s.Owner
+		if owner == "" {
+			owner = filepath.Join(absDir, "generate.go")
+		}
+		edit(owner).insert = append(edit(owner).insert, directiveText(absDir, genCmd, s.Path, s.Graph, s.Out, nil))
+	}
+
+	names := make([]string, 0, len(edits))
+	for f, e := range edits {
+		if len(e.replace)+len(e.remove)+len(e.insert) > 0 {
+			names = append(names, f)
+		}
+	}
+	sort.Strings(names)
+	for _, f := range names {
+		if err := applyDirectiveEdit(f, edits[f], packageName(absDir, specs), dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncSourceFiles lists the hand-written .go files of dir.
+func syncSourceFiles(dir string) []string {
+	all, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var out []string
+	for _, f := range all {
+		if !strings.HasSuffix(f, ".gen.go") {
+			out = append(out, f)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// packageSpecs finds the specs of the package in dir: *.inject.json, graph.json
+// and *.graph.json files in dir (if it has Go files) or in dir/specs. Service
+// specs generated into another package (output.package) or that do not decode
+// as di2 specs are left alone.
+func packageSpecs(dir string, files []string) []ownedSpec {
+	var specDirs []string
+	if len(files) > 0 {
+		specDirs = append(specDirs, dir)
+	}
+	specDirs = append(specDirs, filepath.Join(dir, "specs"))
+
+	owners := typeOwners(files)
+	var out []ownedSpec
+	for _, sd := range specDirs {
+		for _, p := range specFiles([]string{sd}) {
+			if isGraphSpecFile(p) {
+				base := strings.TrimSuffix(filepath.Base(p), ".json")
+				out = append(out, ownedSpec{Path: p, Graph: true, Out: strings.ReplaceAll(base, ".", "_") + ".gen.go"})
+				continue
+			}
+			raw, err := os.ReadFile(p)
+			if err != nil {
+				continue
+			}
+			var spec ServiceSpec
+			if decodeSpec(raw, &spec) != nil || spec.WrapperBase == "" || spec.ImplType == "" || spec.Output.external(spec.Package) {
+				continue
+			}
+			out = append(out, ownedSpec{Path: p, Owner: owners[spec.ImplType], Out: filepath.Base(batchOutPath(p, dir))})
+		}
+	}
+	return out
+}
+
+func isGraphSpecFile(p string) bool {
+	base := filepath.Base(p)
+	return base == "graph.json" || strings.HasSuffix(base, ".graph.json")
+}
+
+// typeOwners maps the type names declared in files to their file.
+func typeOwners(files []string) map[string]string {
+	owners := map[string]string{}
+	fset := token.NewFileSet()
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		af, err := parser.ParseFile(fset, f, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range af.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, s := range gd.Specs {
+					owners[s.(*ast.TypeSpec).Name.Name] = f
+				}
+			}
+		}
+	}
+	return owners
+}
+
+// readDirectiveLines returns the //go:generate lines of files that take a
+// -spec, -specs or -graph argument (di2 or another generator such as di1).
+func readDirectiveLines(dir string, files []string) []directiveLine {
+	var out []directiveLine
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		for i, text := range strings.Split(string(raw), "\n") {
+			cmd, ok := strings.CutPrefix(text, "//go:generate ")
+			if !ok {
+				continue
+			}
+			l := directiveLine{File: f, Index: i, Text: text}
+			d, isDI2 := parseGenerateDirective(cmd)
+			l.DI2 = isDI2
+			if !isDI2 {
+				d = foreignDirective(cmd)
+			}
+			resolve := func(p string) string { return filepath.Join(dir, filepath.FromSlash(p)) }
+			switch {
+			case d.Spec != "":
+				l.Input = resolve(d.Spec)
+			case d.Specs != "":
+				l.Specs = resolve(d.Specs)
+			case d.Graph != "" && isGraphGlob(d.Graph):
+				l.Glob = resolve(d.Graph)
+			case d.Graph != "":
+				l.Input = resolve(d.Graph)
+			default:
+				continue
+			}
+			l.Out = d.Out
+			l.Extra = directiveExtraArgs(cmd)
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// foreignDirective reads the -spec/-specs/-graph/-out arguments of a directive
+// that does not run di2.
+func foreignDirective(cmd string) generateDirective {
+	fields := strings.Fields(cmd)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "-") {
+			d, _ := parseGenerateDirective("di2 " + strings.Join(fields[i:], " "))
+			return d
+		}
+	}
+	return generateDirective{}
+}
+
+// directiveExtraArgs returns the arguments of a di2 directive other than the
+// command and its -spec/-specs/-graph/-out flags, in order.
+func directiveExtraArgs(cmd string) []string {
+	args := strings.Fields(cmd)
+	switch {
+	case len(args) >= 3 && args[0] == "go" && args[1] == "run":
+		args = args[3:]
+	case len(args) >= 1:
+		args = args[1:]
+	}
+	var extra []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasVal := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		switch name {
+		case "spec", "specs", "graph", "out":
+			if !hasVal {
+				i++
+			}
+		default:
+			extra = append(extra, args[i])
+		}
+	}
+	return extra
+}
+
+// packageGenCmd is the di2 "go run" argument directives in dir should use: the
+// one existing directives use when it resolves, else the di2RunFix suggestion.
+func packageGenCmd(dir string, lines []directiveLine) string {
+	for _, l := range lines {
+		cmd, _ := strings.CutPrefix(l.Text, "//go:generate ")
+		d, ok := parseGenerateDirective(cmd)
+		if !ok || d.RunPath == "" {
+			continue
+		}
+		if !strings.HasPrefix(d.RunPath, ".") || dirExists(filepath.Join(dir, filepath.FromSlash(d.RunPath))) {
+			return d.RunPath
+		}
+	}
+	if modRoot, modPath, err := findModule(dir); err == nil && modPath == "github.com/sghaida/odi" {
+		if r, err := filepath.Rel(dir, filepath.Join(modRoot, "cmd", "di2")); err == nil {
+			return filepath.ToSlash(r)
+		}
+	}
+	return defaultGenCmd
+}
+
+// directiveText renders the canonical directive for a spec: the input flag,
+// any other flags in their original order, then -out.
+func directiveText(dir, genCmd, input string, graph bool, out string, extra []string) string {
+	rel, err := filepath.Rel(dir, input)
+	if err != nil {
+		rel = input
+	}
+	flagName := "-spec"
+	if graph {
+		flagName = "-graph"
+	}
+	parts := []string{"//go:generate go run", genCmd, flagName, filepath.ToSlash(rel)}
+	parts = append(parts, extra...)
+	if out != "" {
+		parts = append(parts, "-out", out)
+	}
+	return strings.Join(parts, " ")
+}
+
+// packageName is the Go package of dir, or the package of its first spec when
+// dir has no Go files yet.
+func packageName(dir string, specs []ownedSpec) string {
+	if pkg, _ := scanScaffoldPackage(dir); pkg != "" {
+		return pkg
+	}
+	for _, s := range specs {
+		raw, err := os.ReadFile(s.Path)
+		if err != nil {
+			continue
+		}
+		var head struct {
+			Package string `json:"package"`
+		}
+		if decodeSpec(raw, &head) == nil && head.Package != "" {
+			return head.Package
+		}
+	}
+	return scaffoldPackageName(dir)
+}
+
+// applyDirectiveEdit rewrites file with e and prints the changed lines. New
+// directives go after the file's last go:generate line, or after its package
+// clause; a missing file is created.
+func applyDirectiveEdit(file string, e *fileEdit, pkg string, dryRun bool) error {
+	var lines []string
+	if raw, err := os.ReadFile(file); err == nil {
+		lines = strings.Split(string(raw), "\n")
+	} else if os.IsNotExist(err) {
+		lines = []string{"package " + pkg, ""}
+	} else {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(syncOut, "%s:\n", filepath.ToSlash(file))
+	at := -1 // insert after this line
+	for i, ln := range lines {
+		if strings.HasPrefix(ln, "//go:generate ") {
+			at = i
+		}
+	}
+	if at < 0 {
+		for i, ln := range lines {
+			if strings.HasPrefix(ln, "package ") {
+				at = i
+				e.insert = append([]string{""}, e.insert...)
+				break
+			}
+		}
+	}
+
+	var out []string
+	for i, ln := range lines {
+		switch {
+		case e.remove[i]:
+			_, _ = fmt.Fprintf(syncOut, "-\t%s\n", ln)
+		case e.replace[i] != "":
+			_, _ = fmt.Fprintf(syncOut, "-\t%s\n+\t%s\n", ln, e.replace[i])
+			out = append(out, e.replace[i])
+		default:
+			out = append(out, ln)
+		}
+		if i == at {
+			for _, ins := range e.insert {
+				if ins != "" {
+					_, _ = fmt.Fprintf(syncOut, "+\t%s\n", ins)
+				}
+			}
+			out = append(out, e.insert...)
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	return os.WriteFile(file, []byte(strings.Join(out, "\n")), 0o644)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// di2 sync-directives
+// -------------------------
+
+func swapSyncOut(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := syncOut
+	syncOut = &buf
+	t.Cleanup(func() { syncOut = old })
+	return &buf
+}
+
+func TestSyncDirectives_InSync(t *testing.T) {
+	// NOT parallel: swaps syncOut
+	buf := swapSyncOut(t)
+
+	p := newPkg(t)
+	writeDoctorProject(p)
+	p.write("svc/core.go", "package svc\n\ntype Core struct{}\n")
+	before := p.read("svc/svc.go")
+
+	if err := runSyncDirectives([]string{p.dir}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if buf.Len() != 0 || p.read("svc/svc.go") != before {
+		t.Fatalf("in-sync package should be left alone, got %q", buf.String())
+	}
+}
+
+func TestSyncDirectives_Fixes(t *testing.T) {
+	// NOT parallel: swaps syncOut
+	buf := swapSyncOut(t)
+
+	p := newPkg(t)
+	writeGoMod(p)
+	p.write("cmd/di2/main.go", "package main\n")
+	p.write("svc/specs/core.inject.json", doctorSpec)
+	p.write("svc/specs/alpha.inject.json", strings.NewReplacer(`"Core"`, `"Alpha"`, "NewCore", "NewAlpha").Replace(doctorSpec))
+	p.write("svc/specs/legacy.inject.json", `{"package": "svc"}`)
+	p.write("svc/specs/graph.json", `{"package": "svc", "roots": []}`)
+	p.write("svc/core.go", `package svc
+
+//go:generate go run ../../cmd/di2 -spec ./specs/core.inject.json -verify -out core_v4.gen.go
+//go:generate go run ../cmd/di2 -spec specs/core.inject.json -out core_v4.gen.go
+//go:generate go run ../../cmd/di2 -spec specs/gone.inject.json -out gone_v4.gen.go
+
+type Core struct{}
+`)
+	alpha := "package svc\n\nimport \"fmt\"\n\ntype Alpha struct{}\n\nvar _ = fmt.Sprint\n"
+	p.write("svc/alpha.go", alpha)
+	p.write("svc/legacy.go", "package svc\n\n//go:generate go run ../cmd/di1 -spec ./specs/legacy.inject.json -out ./legacy_di.gen.go\n")
+
+	if err := runSyncDirectives([]string{"-n", p.dir}); err != nil {
+		t.Fatalf("sync -n: %v", err)
+	}
+	if p.read("svc/alpha.go") != alpha || fileExists(p.out("svc/generate.go")) {
+		t.Fatal("-n must not write")
+	}
+	dry := buf.String()
+
+	buf.Reset()
+	if err := runSyncDirectives([]string{p.dir}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if buf.String() != dry {
+		t.Fatalf("-n should print the same changes:\n%s\nvs\n%s", dry, buf.String())
+	}
+
+	const cmd = "//go:generate go run ../cmd/di2 "
+	if got, want := p.read("svc/core.go"), "package svc\n\n"+cmd+"-spec specs/core.inject.json -verify -out core_v4.gen.go\n\ntype Core struct{}\n"; got != want {
+		t.Fatalf("core.go:\n%s\nwant\n%s", got, want)
+	}
+	if got, want := p.read("svc/alpha.go"), "package svc\n\n"+cmd+"-spec specs/alpha.inject.json -out alpha_v4.gen.go\n\nimport \"fmt\"\n"; !strings.HasPrefix(got, want) {
+		t.Fatalf("alpha.go:\n%s", got)
+	}
+	if got, want := p.read("svc/generate.go"), "package svc\n\n"+cmd+"-graph specs/graph.json -out graph.gen.go\n"; got != want {
+		t.Fatalf("generate.go:\n%q\nwant\n%q", got, want)
+	}
+	if !strings.Contains(p.read("svc/legacy.go"), "cmd/di1") {
+		t.Fatal("directives of other generators must be kept")
+	}
+
+	buf.Reset()
+	if err := runSyncDirectives([]string{p.dir}); err != nil || buf.Len() != 0 {
+		t.Fatalf("second sync should change nothing, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestSyncDirectives_GenCmdAndBatch(t *testing.T) {
+	// NOT parallel: swaps syncOut
+	swapSyncOut(t)
+
+	p := newPkg(t)
+	p.write("svc/specs/core.inject.json", doctorSpec)
+	p.write("svc/core.go", "package svc\n\n//go:generate di2 -specs specs -out .\n\ntype Core struct{}\n")
+	p.write("other/specs/core.inject.json", doctorSpec)
+	p.write("other/core.go", "package other\n\ntype Core struct{}\n")
+
+	if err := runSyncDirectives([]string{"-gen-cmd", "example.com/tools/di2", p.dir}); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if got := p.read("svc/core.go"); strings.Count(got, "go:generate") != 1 {
+		t.Fatalf("specs covered by a -specs batch need no directive:\n%s", got)
+	}
+	if got := p.read("other/core.go"); !strings.Contains(got, "//go:generate go run example.com/tools/di2 -spec specs/core.inject.json -out core_v4.gen.go") {
+		t.Fatalf("-gen-cmd should be used:\n%s", got)
+	}
+
+	if err := runSyncDirectives([]string{p.out("missing")}); err == nil {
+		t.Fatal("expected an error for a missing dir")
+	}
+}
+
+func TestDirectiveExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	// quoted arguments survive the round trip through directiveText
+	got := directiveExtraArgs(`go run ../../cmd/di2 -spec=a.json -profile -out x.gen.go -plugin "tool -x"`)
+	if s := strings.Join(got, " "); s != `-profile -plugin "tool -x"` {
+		t.Fatalf("got %q", s)
+	}
+}
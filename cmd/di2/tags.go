@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// -------------------------
+// Dependency tags
+// -------------------------
+
+// depTagPattern is the shape of a dep tag: lowercase words such as "pii" or
+// "external.payments", so tags stay greppable across specs.
+var depTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// validateDepTags checks the tags of every required and optional dep.
+func validateDepTags(s *ServiceSpec) {
+	check := func(dep string, tags []string) {
+		seen := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			if !depTagPattern.MatchString(t) {
+				die(fmt.Sprintf("dep %s: tag %q must be lowercase letters, digits, '.', '_' or '-'", dep, t))
+			}
+			if seen[t] {
+				die(fmt.Sprintf("dep %s: duplicate tag %q", dep, t))
+			}
+			seen[t] = true
+		}
+	}
+	for _, d := range s.Required {
+		check(d.Name, d.Tags)
+	}
+	for _, o := range s.Optional {
+		check(o.Name, o.Tags)
+	}
+}
+
+// HasTags reports whether any dep of the spec is tagged.
+func (s ServiceSpec) HasTags() bool {
+	for _, d := range s.Required {
+		if len(d.Tags) > 0 {
+			return true
+		}
+	}
+	for _, o := range s.Optional {
+		if len(o.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Doc is the dep's GoDoc text: its description plus a "Tags:" line.
+func (d RequiredDep) Doc() string { return depDoc(d.Description, d.Tags) }
+
+// Doc is the dep's GoDoc text: its description plus a "Tags:" line.
+func (o OptionalDep) Doc() string { return depDoc(o.Description, o.Tags) }
+
+func depDoc(description string, tags []string) string {
+	if len(tags) == 0 {
+		return description
+	}
+	line := "Tags: " + strings.Join(tags, ", ") + "."
+	if strings.TrimSpace(description) == "" {
+		return line
+	}
+	return strings.TrimRight(description, "\n") + "\n\n" + line
+}
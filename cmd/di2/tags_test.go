@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// -------------------------
+// Dependency tags
+// -------------------------
+
+func TestGenService_DepTags(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "required": [
+    { "name": "DB", "field": "db", "type": "*DB", "nilable": true, "description": "DB stores orders.", "tags": ["pii", "stateful"] },
+    { "name": "Clock", "field": "clock", "type": "Clock", "nilable": true }
+  ],
+  "optional": [
+    { "name": "Payments", "type": "PaymentsClient", "registryKey": "payments", "apply": { "kind": "field", "name": "payments" }, "tags": ["external"] }
+  ]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"\t// Tags: external.\n\tCoreV4OptionalPaymentsKey = \"payments\"",
+		"// Unlike InjectDB, it returns an error instead of panicking.\n//\n// DB stores orders.\n//\n// Tags: pii, stateful.\nfunc (b *CoreV4) TryInjectDB(",
+		"return di.ExplainWiring(b.Missing(), b.optionalResolved, b.optionalMissing) + di.ExplainTags(b.WiringManifest())",
+		"func (b *CoreV4) WiringManifest() di.WiringManifest {",
+		`{Name: "Clock", Type: "Clock"},`,
+		`{Name: "DB", Type: "*DB", Tags: []string{"pii", "stateful"}},`,
+		`{Name: "Payments", Type: "PaymentsClient", Optional: true, RegistryKey: "payments", Tags: []string{"external"}},`,
+	)
+}
+
+func TestGenService_DepTagsUntagged(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4", "implType": "Core", "constructor": "NewCore",
+  "introspection": true,
+  "required": [{ "name": "DB", "field": "db", "type": "*DB", "nilable": true }]
+}`)
+
+	genService(specPath, p.out("core.gen.go"), genOptions{})
+	out := p.read("core.gen.go")
+
+	assertContainsInOrder(t, out,
+		"return di.ExplainWiring(b.Missing(), nil, nil) + b.escapes.Explain()\n}",
+		`{Name: "DB", Type: "*DB"},`,
+	)
+}
+
+func TestValidateDepTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{name: "upper", tags: []string{"PII"}, want: `dep DB: tag "PII" must be lowercase letters`},
+		{name: "space", tags: []string{"third party"}, want: `dep DB: tag "third party" must be lowercase`},
+		{name: "empty", tags: []string{""}, want: `dep DB: tag "" must be lowercase`},
+		{name: "duplicate", tags: []string{"pii", "pii"}, want: `dep DB: duplicate tag "pii"`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			spec := ServiceSpec{
+				Package: "p", WrapperBase: "Core", VersionSuffix: "V4", ImplType: "Core", Constructor: "NewCore",
+				Required: []RequiredDep{{Name: "DB", Field: "db", Type: "*DB", Nilable: true, Tags: tt.tags}},
+			}
+			raw, err := json.Marshal(spec)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			specPath := p.write("core.inject.json", string(raw))
+			assertPanicContains(t, func() { genService(specPath, p.out("core.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
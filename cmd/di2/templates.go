@@ -0,0 +1,136 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// -------------------------
+// Templates
+// -------------------------
+
+// builtinTemplateFS holds the built-in generator templates, one file per
+// template (see genTemplateNames).
+//
+//go:embed templates/*.go.tmpl
+var builtinTemplateFS embed.FS
+
+// genTemplateNames are the templates di2 renders; <name>.go.tmpl in a
+// -template-dir replaces the built-in one.
+var genTemplateNames = []string{"service", "graph", "accessors"}
+
+// templateFuncs are available to built-in and overriding templates alike.
+var templateFuncs = template.FuncMap{
+	"isError":  func(t string) bool { return t == "error" },
+	"minus1":   func(n int) int { return n - 1 },
+	"reqMask":  requiresMask,
+	"reqWord":  func(i int) int { return i / maskWordBits },
+	"wideBit":  wideMaskBit,
+	"wideAll":  wideMaskAll,
+	"wideReq":  wideRequiresMask,
+	"doc":      docLines,
+	"export":   exportName,
+	"impBreak": importBreak,
+}
+
+// genTemplates is the set of templates a generation run renders with.
+type genTemplates struct {
+	Service   *template.Template
+	Graph     *template.Template
+	Accessors *template.Template
+}
+
+var builtinTemplates = func() *genTemplates {
+	t, err := loadTemplates("")
+	must(err)
+	return t
+}()
+
+// templateOut is where "di2 template export" reports the files it writes.
+var templateOut io.Writer = os.Stdout
+
+// loadTemplates parses the generator templates, taking <name>.go.tmpl from dir
+// when present and the built-in template otherwise. Other *.tmpl files in dir
+// are rejected so a misspelled override does not go unnoticed.
+func loadTemplates(dir string) (*genTemplates, error) {
+	if dir != "" {
+		if !dirExists(dir) {
+			return nil, fmt.Errorf("-template-dir %s: not a directory", dir)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		for _, f := range files {
+			if name := strings.TrimSuffix(filepath.Base(f), ".go.tmpl"); !slices.Contains(genTemplateNames, name) {
+				return nil, fmt.Errorf("-template-dir: unknown template %s (want one of %s.go.tmpl)", f, strings.Join(genTemplateNames, ".go.tmpl, "))
+			}
+		}
+	}
+
+	parsed := map[string]*template.Template{}
+	for _, name := range genTemplateNames {
+		path := "templates/" + name + ".go.tmpl"
+		text, err := builtinTemplateFS.ReadFile(path)
+		if override := filepath.Join(dir, name+".go.tmpl"); dir != "" && fileExists(override) {
+			path = override
+			text, err = os.ReadFile(override)
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", path, err)
+		}
+		parsed[name] = t
+	}
+	return &genTemplates{Service: parsed["service"], Graph: parsed["graph"], Accessors: parsed["accessors"]}, nil
+}
+
+// templates returns the templates to render with: opts.Templates, or the
+// built-in ones.
+func (o genOptions) templates() *genTemplates {
+	if o.Templates != nil {
+		return o.Templates
+	}
+	return builtinTemplates
+}
+
+// runTemplate implements "di2 template export [-dir templates] [-force]": it
+// writes the built-in templates as a starting point for -template-dir.
+func runTemplate(args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		return fmt.Errorf("usage: di2 template export [-dir templates] [-force]")
+	}
+	fs := flag.NewFlagSet("di2 template export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dir := fs.String("dir", "templates", "directory to write <name>.go.tmpl files to")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	for _, name := range genTemplateNames {
+		path := filepath.Join(*dir, name+".go.tmpl")
+		if fileExists(path) && !*force {
+			return fmt.Errorf("%s already exists (use -force to overwrite)", path)
+		}
+		text, err := builtinTemplateFS.ReadFile("templates/" + name + ".go.tmpl")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, text, 0o644); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(templateOut, "wrote %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// Templates (-template-dir, di2 template export)
+// -------------------------
+
+func TestRun_TemplateExportAndOverride(t *testing.T) {
+	// NOT parallel: swaps templateOut
+
+	var buf bytes.Buffer
+	old := templateOut
+	templateOut = &buf
+	t.Cleanup(func() { templateOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+	tplDir := p.out("tpl")
+
+	if err := run([]string{"template", "export", "-dir", tplDir}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(), "wrote "+tplDir+"/service.go.tmpl", "graph.go.tmpl", "accessors.go.tmpl")
+
+	// the exported templates render exactly like the built-in ones
+	if err := run([]string{"-spec", specPath, "-out", p.out("builtin.gen.go")}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := run([]string{"-spec", specPath, "-out", p.out("builtin.gen.go"), "-template-dir", tplDir}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	builtin := p.read("builtin.gen.go")
+
+	// a small deviation: the org's error wrapping in every Build failure
+	svc := p.read("tpl/service.go.tmpl")
+	if !strings.Contains(svc, "return nil, di.WiringIncomplete(") {
+		t.Fatalf("unexpected exported template:\n%s", svc)
+	}
+	p.write("tpl/service.go.tmpl", strings.ReplaceAll(svc,
+		"return nil, di.WiringIncomplete(", "return nil, /* org */ di.WiringIncomplete("))
+	if err := os.Remove(p.out("tpl/graph.go.tmpl")); err != nil { // missing overrides fall back to the built-in
+		t.Fatalf("remove: %v", err)
+	}
+
+	if err := run([]string{"-spec", specPath, "-out", p.out("custom.gen.go"), "-template-dir", tplDir}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	custom := p.read("custom.gen.go")
+	if !strings.Contains(custom, "/* org */, di.WiringIncomplete(") {
+		t.Fatalf("override not applied:\n%s", custom)
+	}
+	if builtin == custom || strings.Contains(builtin, "/* org */") {
+		t.Fatalf("built-in output must not change")
+	}
+
+	err := run([]string{"template", "export", "-dir", tplDir})
+	if err == nil || !strings.Contains(err.Error(), "service.go.tmpl already exists (use -force to overwrite)") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+	if err := run([]string{"template", "export", "-dir", tplDir, "-force"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if p.read("tpl/service.go.tmpl") != svc {
+		t.Fatalf("-force must restore the built-in template")
+	}
+}
+
+func TestLoadTemplates_Errors(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		dir   string
+		want  string
+	}{
+		{name: "not_a_dir", dir: "missing", want: "-template-dir " + p.out("missing") + ": not a directory"},
+		{name: "unknown", dir: "unknown", files: map[string]string{"servcie.go.tmpl": ""},
+			want: "unknown template " + p.out("unknown/servcie.go.tmpl") + " (want one of service.go.tmpl, graph.go.tmpl, accessors.go.tmpl)"},
+		{name: "syntax", dir: "syntax", files: map[string]string{"graph.go.tmpl": "{{ .G.Package "},
+			want: "template " + p.out("syntax/graph.go.tmpl") + ":"},
+	}
+	for _, tt := range tests {
+		for name, text := range tt.files {
+			p.write(tt.dir+"/"+name, text)
+		}
+		if _, err := loadTemplates(p.out(tt.dir)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if err := run([]string{"template"}); err == nil || !strings.Contains(err.Error(), "usage: di2 template export") {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
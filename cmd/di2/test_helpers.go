@@ -263,21 +263,7 @@ func addGraphConfigMatrixCases(cases []inferCase[GraphSpec], matrix []cfgMatrixR
 					Package: "p",
 					Imports: Imports{DI: "", Config: row.initial},
 					Config:  ConfigSpec{Enabled: true, Import: row.force},
-					Roots: []struct {
-						Name              string `json:"name"`
-						BuildWithRegistry bool   `json:"buildWithRegistry"`
-						Services          []struct {
-							Var        string `json:"var"`
-							FacadeCtor string `json:"facadeCtor"`
-							FacadeType string `json:"facadeType"`
-							ImplType   string `json:"implType"`
-						} `json:"services"`
-						Wiring []struct {
-							To      string `json:"to"`
-							Call    string `json:"call"`
-							ArgFrom string `json:"argFrom"`
-						} `json:"wiring"`
-					}{
+					Roots: []GraphRoot{
 						{Name: "Root"},
 					},
 				}
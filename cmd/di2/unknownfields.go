@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// -------------------------
+// Unknown spec fields (-strict)
+// -------------------------
+
+// specWarnOut is where unknown spec fields are reported without -strict.
+var specWarnOut io.Writer = os.Stderr
+
+// specWarnMu serializes warnings of concurrent -specs batch workers.
+var specWarnMu sync.Mutex
+
+// unknownField is a key of a spec that no spec field decodes. Pointer locates it
+// (RFC 6901, e.g. "/required/0/nilabel"); Suggest is the closest known key of
+// the same object, if any is close enough to be a typo.
+type unknownField struct {
+	Pointer string
+	Suggest string
+}
+
+func (u unknownField) String() string {
+	if u.Suggest == "" {
+		return "unknown field " + u.Pointer
+	}
+	return fmt.Sprintf("unknown field %s (did you mean %q?)", u.Pointer, u.Suggest)
+}
+
+// checkSpecFields reports the keys of the spec file path (contents raw) that
+// encoding/json silently ignores when decoding into typ: with strict it fails
+// on them, like a decoder with DisallowUnknownFields but listing all of them,
+// otherwise each is printed to specWarnOut as a warning.
+func checkSpecFields(path string, raw []byte, typ reflect.Type, strict bool) {
+	unknown := unknownSpecFields(raw, typ)
+	if len(unknown) == 0 {
+		return
+	}
+	if strict {
+		msgs := make([]string, len(unknown))
+		for i, u := range unknown {
+			msgs[i] = "\t" + u.String()
+		}
+		die(fmt.Sprintf("%s: %d unknown field(s) (-strict):\n%s", path, len(unknown), strings.Join(msgs, "\n")))
+	}
+
+	specWarnMu.Lock()
+	defer specWarnMu.Unlock()
+	for _, u := range unknown {
+		_, _ = fmt.Fprintf(specWarnOut, "di2: warning: %s: %s\n", path, u)
+	}
+}
+
+// checkServiceFields is checkSpecFields for a service spec.
+func checkServiceFields(specPath string, raw []byte, strict bool) {
+	checkSpecFields(specPath, raw, reflect.TypeFor[ServiceSpec](), strict)
+}
+
+// checkGraphFields is checkSpecFields for the graph at graphPath, or every
+// fragment matched by a graph glob.
+func checkGraphFields(graphPath string, strict bool) {
+	paths := []string{graphPath}
+	if isGraphGlob(graphPath) {
+		paths, _ = filepath.Glob(graphPath)
+		sort.Strings(paths)
+	}
+	for _, p := range paths {
+		checkSpecFields(p, mustRead(p), reflect.TypeFor[GraphSpec](), strict)
+	}
+}
+
+// unknownSpecFields returns the unknown keys of raw in document order. Raw that
+// does not parse yields none (decoding reports that).
+func unknownSpecFields(raw []byte, typ reflect.Type) []unknownField {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	root, err := parseJSONNode(dec)
+	if err != nil {
+		return nil
+	}
+	var out []unknownField
+	collectUnknownFields(root, typ, "", &out)
+	return out
+}
+
+func collectUnknownFields(n *jsonNode, typ reflect.Type, pointer string, out *[]unknownField) {
+	typ = derefType(typ)
+	if typ == nil {
+		return
+	}
+	switch n.kind {
+	case '[':
+		for i, e := range n.elems {
+			collectUnknownFields(e, elemType(typ), pointer+"/"+strconv.Itoa(i), out)
+		}
+	case '{':
+		switch typ.Kind() {
+		case reflect.Map:
+			for _, k := range n.keys {
+				collectUnknownFields(n.fields[k], typ.Elem(), pointer+"/"+jsonPointerEscape(k), out)
+			}
+		case reflect.Struct:
+			fields := jsonFieldTypes(typ)
+			for _, k := range n.keys {
+				ft, ok := lookupJSONField(fields, k)
+				if !ok {
+					*out = append(*out, unknownField{
+						Pointer: pointer + "/" + jsonPointerEscape(k),
+						Suggest: closestJSONField(fields, k),
+					})
+					continue
+				}
+				collectUnknownFields(n.fields[k], ft, pointer+"/"+jsonPointerEscape(k), out)
+			}
+		}
+	}
+}
+
+// jsonFieldTypes maps the json names of typ's exported fields to their types.
+func jsonFieldTypes(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, typ.NumField())
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupJSONField matches key like encoding/json does: exactly, else ignoring case.
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// closestJSONField returns the field name nearest to key by edit distance, or ""
+// when none is within a third of the key's length.
+func closestJSONField(fields map[string]reflect.Type, key string) string {
+	best, bestDist := "", len(key)/3+1
+	for name := range fields {
+		d := editDistance(strings.ToLower(name), strings.ToLower(key))
+		if d < bestDist || (d == bestDist && best != "" && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b (bytes).
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// jsonPointerEscape escapes a key for use as a JSON pointer segment (RFC 6901).
+func jsonPointerEscape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// -------------------------
+// Unknown spec fields (-strict)
+// -------------------------
+
+const typoSpec = `{
+  "package": "p", "wrapperBase": "Alpha", "versionSuffix": "V4",
+  "implType": "Alpha", "constructor": "NewAlpha",
+  "Required": [{ "name": "Dep", "field": "dep", "type": "*Dep", "nilable": true, "desciption": "x" }],
+  "requried": [],
+  "x/y": 1
+}`
+
+func TestUnknownSpecFields(t *testing.T) {
+	t.Parallel()
+
+	got := unknownSpecFields([]byte(typoSpec), reflect.TypeFor[ServiceSpec]())
+	want := []unknownField{
+		{Pointer: "/Required/0/desciption", Suggest: "description"},
+		{Pointer: "/requried", Suggest: "required"},
+		{Pointer: "/x~1y"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if got := unknownSpecFields([]byte(`{"package": `), reflect.TypeFor[ServiceSpec]()); got != nil {
+		t.Fatalf("unparsable specs are left to the decoder, got %+v", got)
+	}
+	if got := unknownSpecFields([]byte(`{"roots": [{"name": "App", "servces": []}], "profiles": {"prod": "x"}}`), reflect.TypeFor[GraphSpec]()); len(got) != 1 || got[0].Pointer != "/roots/0/servces" || got[0].Suggest != "services" {
+		t.Fatalf("graph: got %+v", got)
+	}
+}
+
+func TestRun_UnknownFieldsWarnOrStrict(t *testing.T) {
+	// NOT parallel: swaps specWarnOut
+	var buf bytes.Buffer
+	old := specWarnOut
+	specWarnOut = &buf
+	t.Cleanup(func() { specWarnOut = old })
+
+	p := newPkg(t)
+	writeDISource(p)
+	spec := p.write("specs/alpha.inject.json", typoSpec)
+
+	if err := run([]string{"-spec", spec, "-out", p.out("alpha_v4.gen.go")}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	assertContainsInOrder(t, buf.String(),
+		"di2: warning: "+spec+": unknown field /Required/0/desciption (did you mean \"description\"?)\n",
+		"di2: warning: "+spec+": unknown field /requried (did you mean \"required\"?)\n",
+		"di2: warning: "+spec+": unknown field /x~1y\n",
+	)
+
+	buf.Reset()
+	assertPanicContains(t, func() {
+		_ = run([]string{"-spec", spec, "-out", p.out("alpha_v4.gen.go"), "-strict"})
+	}, spec+": 3 unknown field(s) (-strict):\n\tunknown field /Required/0/desciption")
+	if buf.Len() != 0 {
+		t.Fatalf("-strict should not warn, got %q", buf.String())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+)
+
+// -------------------------
+// Must / Ctx graph variants
+// -------------------------
+
+func TestGenGraph_MustAndCtxVariants(t *testing.T) {
+	t.Parallel()
+
+	t.Run("serial", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		graphPath := p.write("graph.json", `{
+  "package": "p",
+  "config": { "enabled": true, "import": "example.com/proj/config", "type": "config.Config", "paramName": "cfg" },
+  "shared": { "services": [{ "var": "db", "facadeCtor": "NewDBV4", "facadeType": "*DBV4", "implType": "DB" }] },
+  "roots": [{
+    "name": "App",
+    "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }],
+    "after": [{ "call": "a.Warm" }]
+  }]
+}`)
+		genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+		out := p.read("graph.gen.go")
+
+		assertHasImport(t, out, "context")
+		assertContainsInOrder(t, out,
+			"func App(cfg config.Config, reg di.Registry, shared SharedResult) (AppResult, error) {",
+			"return AppCtx(context.Background(), cfg, reg, shared)",
+			"func MustApp(cfg config.Config, reg di.Registry, shared SharedResult) AppResult {",
+			"res, err := AppCtx(context.Background(), cfg, reg, shared)",
+			"panic(err)",
+			"func AppCtx(ctx context.Context, cfg config.Config, reg di.Registry, shared SharedResult) (AppResult, error) {",
+			"if err := ctx.Err(); err != nil {",
+			`return res, fmt.Errorf("App: build a canceled: %w", err)`,
+			"aSvc, err := aB.Build()",
+			"err := res.A.Warm(ctx)",
+		)
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		writeDISource(p)
+		graphPath := p.write("graph.json", `{
+  "package": "p",
+  "parallel": true,
+  "roots": [{
+    "name": "App",
+    "services": [{ "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A" }]
+  }]
+}`)
+		genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+		assertContainsInOrder(t, p.read("graph.gen.go"),
+			"func App(reg di.Registry) (AppResult, error) {",
+			"func MustApp(reg di.Registry) AppResult {",
+			"func AppCtx(ctx context.Context, reg di.Registry) (AppResult, error) {",
+			"func() error {",
+			"if err := ctx.Err(); err != nil {",
+			`return fmt.Errorf("App: build a canceled: %w", err)`,
+			"svc, err := aB.Build()",
+		)
+	})
+}
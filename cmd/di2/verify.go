@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// -------------------------
+// -verify: type-check generated output
+// -------------------------
+
+// verifyTarget is a generated file to type-check and the spec it came from.
+// Origin maps an error to the spec entry that produced the code: decl is the
+// enclosing top-level declaration and line the offending source line.
+type verifyTarget struct {
+	Out    string
+	Spec   string
+	Origin func(decl, line string) string
+}
+
+// verifyIssue is one compile error found by -verify.
+type verifyIssue struct {
+	Pos    string // file:line:col as reported by the go command
+	Msg    string
+	Spec   string // originating spec file (empty outside generated files)
+	Origin string // spec entry, e.g. `required[0] "Alpha" (field "alpha")`
+}
+
+func (i verifyIssue) String() string {
+	s := i.Pos + ": " + i.Msg
+	switch {
+	case i.Origin != "":
+		s += "\n\tfrom " + i.Spec + ": " + i.Origin
+	case i.Spec != "":
+		s += "\n\tfrom " + i.Spec
+	}
+	return s
+}
+
+// verifyError lists the compile errors of the packages holding generated files.
+type verifyError struct {
+	Issues []verifyIssue
+}
+
+func (e *verifyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "verify: %d compile error(s):", len(e.Issues))
+	for _, is := range e.Issues {
+		sb.WriteString("\n" + is.String())
+	}
+	return sb.String()
+}
+
+// loadPackageErrors type-checks the package in dir (go/packages with NeedTypes)
+// and returns its errors. It is a variable so tests can stub it.
+var loadPackageErrors = func(dir string) ([]packages.Error, error) {
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes
+	pkgs, err := packages.Load(&packages.Config{Mode: mode, Dir: dir}, ".")
+	if err != nil {
+		return nil, err
+	}
+	var errs []packages.Error
+	for _, p := range pkgs {
+		errs = append(errs, p.Errors...)
+	}
+	return errs, nil
+}
+
+// verifyGenerated type-checks the package of every target once and returns a
+// *verifyError attributing errors inside generated files to their spec entries.
+// Errors elsewhere in those packages are reported as-is.
+func verifyGenerated(targets []verifyTarget) error {
+	byOut := make(map[string]verifyTarget, len(targets))
+	var dirs []string
+	seen := map[string]bool{}
+	for _, t := range targets {
+		abs, err := filepath.Abs(t.Out)
+		if err != nil {
+			return err
+		}
+		byOut[abs] = t
+		if dir := filepath.Dir(abs); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	var issues []verifyIssue
+	for _, dir := range dirs {
+		errs, err := loadPackageErrors(dir)
+		if err != nil {
+			return fmt.Errorf("verify %s: %w", dir, err)
+		}
+		for _, e := range errs {
+			is := verifyIssue{Pos: e.Pos, Msg: e.Msg}
+			file, line := splitErrorPos(e.Pos)
+			if abs, err := filepath.Abs(file); err == nil && file != "" {
+				if t, ok := byOut[abs]; ok {
+					is.Spec = t.Spec
+					is.Origin = originAt(t, line)
+				}
+			}
+			issues = append(issues, is)
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return &verifyError{Issues: issues}
+}
+
+// splitErrorPos splits "file:line[:col]" into the file and line (0 if absent).
+func splitErrorPos(pos string) (string, int) {
+	file, line := pos, 0
+	for range 2 {
+		i := strings.LastIndexByte(file, ':')
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(file[i+1:])
+		if err != nil {
+			break
+		}
+		file, line = file[:i], n
+	}
+	return file, line
+}
+
+// originAt finds the declaration and source text at line of t.Out and asks
+// t.Origin which spec entry produced them.
+func originAt(t verifyTarget, line int) string {
+	if t.Origin == nil || line <= 0 {
+		return ""
+	}
+	src, err := os.ReadFile(t.Out)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(src), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, t.Out, src, parser.SkipObjectResolution)
+	decl := ""
+	if f != nil {
+		for _, d := range f.Decls {
+			if fset.Position(d.Pos()).Line <= line && line <= fset.Position(d.End()).Line {
+				decl = declName(d)
+				break
+			}
+		}
+	}
+	return t.Origin(decl, lines[line-1])
+}
+
+// declName returns a func's name or the first name a GenDecl declares.
+func declName(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		return d.Name.Name
+	case *ast.GenDecl:
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				return s.Name.Name
+			case *ast.ValueSpec:
+				return s.Names[0].Name
+			}
+		}
+	}
+	return ""
+}
+
+// serviceVerifyTarget attributes errors in a service facade to the required dep,
+// optional dep or method wrapper of the spec that generated it.
+func serviceVerifyTarget(specPath, outPath string) verifyTarget {
+	s, _ := readServiceSpec(specPath)
+	return verifyTarget{Out: outPath, Spec: specPath, Origin: func(decl, line string) string {
+		// the source line names a field or setter first: it is the most precise hint
+		for i, d := range s.Required {
+			if d.Field != "" && containsIdent(line, ".svc."+d.Field) {
+				return fmt.Sprintf("required[%d] %q (field %q)", i, d.Name, d.Field)
+			}
+		}
+		for i, d := range s.Optional {
+			if d.Apply.Name != "" && containsIdent(line, ".svc."+d.Apply.Name) {
+				return fmt.Sprintf("optional[%d] %q (apply %s %q)", i, d.Name, d.Apply.Kind, d.Apply.Name)
+			}
+		}
+
+		best, origin := 0, ""
+		for i, d := range s.Required {
+			for _, prefix := range []string{"TryInject" + d.Name, "Inject" + d.Name} {
+				if strings.HasPrefix(decl, prefix) && len(prefix) > best {
+					best, origin = len(prefix), fmt.Sprintf("required[%d] %q (type %q)", i, d.Name, d.Type)
+				}
+			}
+		}
+		if origin != "" {
+			return origin
+		}
+		for i, m := range s.Methods {
+			if decl == m.Name {
+				return fmt.Sprintf("methods[%d] %q", i, m.Name)
+			}
+		}
+		switch decl {
+		case s.PublicConstructorName:
+			return fmt.Sprintf("constructor %q", s.Constructor)
+		case s.FacadeName:
+			return fmt.Sprintf("implType %q", s.ImplType)
+		}
+		if s.HealthCheck != "" && decl == "HealthCheck" {
+			return fmt.Sprintf("healthCheck %q", s.HealthCheck)
+		}
+		return ""
+	}}
+}
+
+// containsIdent reports whether line contains sel not followed by more
+// identifier characters (".svc.alpha" does not match ".svc.alphaB").
+func containsIdent(line, sel string) bool {
+	for i := 0; ; {
+		j := strings.Index(line[i:], sel)
+		if j < 0 {
+			return false
+		}
+		end := i + j + len(sel)
+		if end == len(line) || !isIdentByte(line[end]) {
+			return true
+		}
+		i = end
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// graphVerifyTarget attributes errors in a graph file to the root service or
+// wiring entry of the graph spec that generated them.
+func graphVerifyTarget(graphPath, outPath string) verifyTarget {
+	g, _ := readGraphSpec(graphPath)
+
+	type namedRoot struct {
+		path string
+		root GraphRoot
+	}
+	var roots []namedRoot
+	for i, r := range g.Roots {
+		roots = append(roots, namedRoot{fmt.Sprintf("roots[%d]", i), r})
+	}
+	if g.Shared != nil {
+		r := *g.Shared
+		if r.Name == "" {
+			r.Name = "Shared"
+		}
+		roots = append(roots, namedRoot{"shared", r})
+	}
+
+	return verifyTarget{Out: outPath, Spec: graphPath, Origin: func(decl, line string) string {
+		for _, nr := range roots {
+			r := nr.root
+			if decl != r.Name && decl != r.Name+"Ctx" && decl != r.Name+"Result" && decl != "Must"+r.Name {
+				continue
+			}
+			for j, w := range r.Wiring {
+				if strings.Contains(line, w.To+"B."+w.Call+"(") {
+					return fmt.Sprintf("%s.wiring[%d] %q", nr.path, j, w.To+"."+w.Call)
+				}
+			}
+			for j, s := range r.Services {
+				if strings.Contains(line, s.FacadeCtor+"(") || strings.Contains(line, s.FacadeType) ||
+					(s.ImplType != "" && strings.Contains(line, "*"+s.ImplType)) {
+					return fmt.Sprintf("%s.services[%d] %q", nr.path, j, s.Var)
+				}
+			}
+			return fmt.Sprintf("%s %q", nr.path, r.Name)
+		}
+		return ""
+	}}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// -------------------------
+// -verify
+// -------------------------
+
+// stubPackageErrors replaces loadPackageErrors for one test (NOT parallel-safe).
+func stubPackageErrors(t *testing.T, fn func(dir string) ([]packages.Error, error)) {
+	t.Helper()
+	old := loadPackageErrors
+	loadPackageErrors = fn
+	t.Cleanup(func() { loadPackageErrors = old })
+}
+
+// lineOf returns the 1-based line of the first line of out containing substr.
+func lineOf(t *testing.T, out, substr string) int {
+	t.Helper()
+	for i, l := range strings.Split(out, "\n") {
+		if strings.Contains(l, substr) {
+			return i + 1
+		}
+	}
+	t.Fatalf("no line containing %q", substr)
+	return 0
+}
+
+func TestRun_VerifyService(t *testing.T) {
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := p.write("core.inject.json", `{
+  "package": "p", "wrapperBase": "Core", "versionSuffix": "V4",
+  "implType": "Core", "constructor": "NewCore",
+  "required": [
+    { "name": "Alpha", "field": "alpha", "type": "*Alpha", "nilable": true },
+    { "name": "AlphaB", "field": "alphaB", "type": "*AlphaB", "nilable": true }
+  ],
+  "optional": [
+    { "name": "Tracer", "type": "Tracer", "registryKey": "t", "apply": { "kind": "field", "name": "tracer" } }
+  ],
+  "methods": [{ "name": "Process", "returns": [{ "type": "error" }] }]
+}`)
+	out := p.out("core.gen.go")
+
+	var loaded []string
+	var errs []packages.Error
+	stubPackageErrors(t, func(dir string) ([]packages.Error, error) {
+		loaded = append(loaded, dir)
+		return errs, nil
+	})
+
+	// clean package
+	if err := run([]string{"-spec", specPath, "-out", out, "-verify"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0] != p.dir {
+		t.Fatalf("expected one load of %s, got %v", p.dir, loaded)
+	}
+
+	src := p.read("core.gen.go")
+	at := func(substr string) string { return fmt.Sprintf("%s:%d:2", out, lineOf(t, src, substr)) }
+	errs = []packages.Error{
+		{Pos: at("b.svc.alpha = dep"), Msg: "b.svc.alpha undefined"},
+		{Pos: at("func (b *CoreV4) TryInjectAlphaB("), Msg: "undefined: AlphaB"},
+		{Pos: at("b.svc.alphaB = dep"), Msg: "b.svc.alphaB undefined"},
+		{Pos: at("b.svc.tracer = v"), Msg: "cannot use v"},
+		{Pos: at("func (b *CoreV4) Process("), Msg: "svc.Process undefined"},
+		{Pos: at("return &CoreV4{"), Msg: "undefined: NewCore"},
+		{Pos: p.out("other.go") + ":3:1", Msg: "hand-written error"},
+	}
+	err := run([]string{"-spec", specPath, "-out", out, "-verify"})
+	var verr *verifyError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *verifyError, got %v", err)
+	}
+	assertContainsInOrder(t, err.Error(),
+		"verify: 7 compile error(s):",
+		"b.svc.alpha undefined\n\tfrom "+specPath+`: required[0] "Alpha" (field "alpha")`,
+		"undefined: AlphaB\n\tfrom "+specPath+`: required[1] "AlphaB" (type "*AlphaB")`,
+		"b.svc.alphaB undefined\n\tfrom "+specPath+`: required[1] "AlphaB" (field "alphaB")`,
+		"cannot use v\n\tfrom "+specPath+`: optional[0] "Tracer" (apply field "tracer")`,
+		"svc.Process undefined\n\tfrom "+specPath+`: methods[0] "Process"`,
+		"undefined: NewCore\n\tfrom "+specPath+`: constructor "NewCore"`,
+		"other.go:3:1: hand-written error",
+	)
+	if strings.Contains(err.Error(), "hand-written error\n\tfrom") {
+		t.Fatalf("errors outside generated files must not be attributed:\n%v", err)
+	}
+}
+
+func TestRun_VerifyGraph(t *testing.T) {
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "roots": [{
+    "name": "BuildApp",
+    "services": [
+      { "var": "alpha", "facadeCtor": "NewAlphaV4", "facadeType": "*AlphaV4", "implType": "Alpha" },
+      { "var": "core",  "facadeCtor": "NewCoreV4",  "facadeType": "*CoreV4",  "implType": "Core" }
+    ],
+    "wiring": [{ "to": "core", "call": "InjectAlpha", "argFrom": "alpha" }]
+  }]
+}`)
+	out := p.out("graph.gen.go")
+
+	var errs []packages.Error
+	stubPackageErrors(t, func(string) ([]packages.Error, error) { return errs, nil })
+	if err := run([]string{"-graph", graphPath, "-out", out, "-verify"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	src := p.read("graph.gen.go")
+	errs = []packages.Error{
+		{Pos: fmt.Sprintf("%s:%d:2", out, lineOf(t, src, "coreB.InjectAlpha(")), Msg: "coreB.InjectAlpha undefined"},
+		{Pos: fmt.Sprintf("%s:%d:2", out, lineOf(t, src, "NewAlphaV4(")), Msg: "undefined: NewAlphaV4"},
+	}
+	err := run([]string{"-graph", graphPath, "-out", out, "-verify"})
+	if err == nil {
+		t.Fatalf("expected verify error")
+	}
+	assertContainsInOrder(t, err.Error(),
+		"from "+graphPath+`: roots[0].wiring[0] "core.InjectAlpha"`,
+		"from "+graphPath+`: roots[0].services[0] "alpha"`,
+	)
+}
+
+func TestRun_VerifyLoadError(t *testing.T) {
+	p := newPkg(t)
+	writeDISource(p)
+	specPath := writeBatchSpec(p, "alpha", "Alpha", "V4")
+
+	stubPackageErrors(t, func(string) ([]packages.Error, error) { return nil, errors.New("no go command") })
+	err := run([]string{"-spec", specPath, "-out", p.out("alpha.gen.go"), "-verify"})
+	if err == nil || !strings.Contains(err.Error(), "no go command") {
+		t.Fatalf("expected load error, got %v", err)
+	}
+
+	err = run([]string{"-spec", specPath, "-out", "-", "-verify"})
+	if err == nil || !strings.Contains(err.Error(), "-verify needs written output") {
+		t.Fatalf("expected dry-run conflict, got %v", err)
+	}
+}
+
+func TestSplitErrorPos(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pos, file string
+		line      int
+	}{
+		{"a/b.go:12:3", "a/b.go", 12},
+		{"a/b.go:12", "a/b.go", 12},
+		{"C:/x/b.go:7:1", "C:/x/b.go", 7},
+		{"-", "-", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.pos, func(t *testing.T) {
+			t.Parallel()
+			file, line := splitErrorPos(tt.pos)
+			if file != tt.file || line != tt.line {
+				t.Fatalf("splitErrorPos(%q) = %q, %d; want %q, %d", tt.pos, file, line, tt.file, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"strings"
+)
+
+// -------------------------
+// Conditional wiring
+// -------------------------
+
+// resolveGraphConditions replaces profile names in services' and wiring's "when"
+// with their expressions and checks that every condition is a Go expression.
+func resolveGraphConditions(g *GraphSpec) {
+	for name, expr := range g.Profiles {
+		if _, err := parser.ParseExpr(expr); err != nil {
+			die(fmt.Sprintf("graph profiles.%s %q is not a valid Go expression: %v", name, expr, err))
+		}
+	}
+	resolve := func(ctx, when string) string {
+		when = strings.TrimSpace(when)
+		if when == "" {
+			return ""
+		}
+		if expr, ok := g.Profiles[when]; ok {
+			return expr
+		}
+		if _, err := parser.ParseExpr(when); err != nil {
+			die(fmt.Sprintf("%s when %q is neither a profile nor a valid Go expression: %v", ctx, when, err))
+		}
+		return when
+	}
+	for _, root := range g.allRoots() {
+		for j := range root.Services {
+			s := &root.Services[j]
+			s.When = resolve("graph service "+s.Var, s.When)
+		}
+		for j := range root.Wiring {
+			w := &root.Wiring[j]
+			w.When = resolve("graph wiring "+w.To+"."+w.Call, w.When)
+		}
+	}
+}
+
+// wiringGuard returns the if-condition of a wiring entry: its own condition plus
+// nil checks for conditional services on either end, or "" when unconditional.
+func wiringGuard(root GraphRoot, w GraphWiring) string {
+	conditional := func(services []GraphService, v string) bool {
+		for _, s := range services {
+			if s.Var == v {
+				return s.When != ""
+			}
+		}
+		return false
+	}
+
+	var parts []string
+	if w.When != "" {
+		parts = append(parts, w.When)
+	}
+	if conditional(root.Services, w.To) {
+		parts = append(parts, w.To+"B != nil")
+	}
+	switch {
+	case conditional(root.Services, w.ArgFrom):
+		parts = append(parts, w.ArgFrom+"B != nil")
+	case conditional(root.SharedServices, w.ArgFrom):
+		parts = append(parts, "shared."+exportName(w.ArgFrom)+" != nil")
+	}
+	if len(parts) > 1 && w.When != "" {
+		parts[0] = "(" + parts[0] + ")"
+	}
+	return strings.Join(parts, " && ")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+)
+
+// -------------------------
+// Conditional wiring
+// -------------------------
+
+func TestWiringGuard(t *testing.T) {
+	t.Parallel()
+
+	root := GraphRoot{
+		Services: []GraphService{
+			{Var: "a"},
+			{Var: "b", When: "prod"},
+		},
+		SharedServices: []GraphService{
+			{Var: "db", When: "useDB"},
+			{Var: "cache"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		w    GraphWiring
+		want string
+	}{
+		{name: "unconditional", w: GraphWiring{To: "a", ArgFrom: "cache"}, want: ""},
+		{name: "when_only", w: GraphWiring{To: "a", ArgFrom: "a", When: "x || y"}, want: "x || y"},
+		{name: "conditional_from", w: GraphWiring{To: "a", ArgFrom: "b"}, want: "bB != nil"},
+		{name: "conditional_to", w: GraphWiring{To: "b", ArgFrom: "a"}, want: "bB != nil"},
+		{name: "conditional_shared", w: GraphWiring{To: "a", ArgFrom: "db"}, want: "shared.Db != nil"},
+		{name: "combined", w: GraphWiring{To: "b", ArgFrom: "db", When: "x || y"}, want: "(x || y) && bB != nil && shared.Db != nil"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := wiringGuard(root, tt.w); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenGraph_Conditions(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "config": { "enabled": true, "import": "example.com/proj/config", "type": "config.Config", "paramName": "cfg" },
+  "profiles": { "prod": "cfg.Env == \"prod\"" },
+  "roots": [{
+    "name": "App",
+    "services": [
+      { "var": "api", "facadeCtor": "NewAPIV4", "facadeType": "*APIV4", "implType": "API" },
+      { "var": "audit", "facadeCtor": "NewAuditV4", "facadeType": "*AuditV4", "implType": "Audit", "when": "prod", "healthCheck": "Ping" }
+    ],
+    "wiring": [
+      { "to": "api", "call": "InjectAudit", "argFrom": "audit" },
+      { "to": "api", "call": "InjectDebug", "argFrom": "api", "when": "cfg.Debug", "cycle": true }
+    ]
+  }]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	out := p.read("graph.gen.go")
+
+	assertContainsInOrder(t, out,
+		"apiB := NewAPIV4(cfg)",
+		"var auditB *AuditV4",
+		`if cfg.Env == "prod" {`,
+		"auditB = NewAuditV4(cfg)",
+		"if auditB != nil {",
+		"apiB.InjectAudit(auditB.UnsafeImpl())",
+		"if cfg.Debug {",
+		"apiB.InjectDebug(apiB.UnsafeImpl())",
+		"if auditB != nil {",
+		"auditSvc, err := auditB.Build()",
+		"res.Audit = auditSvc",
+		"apiSvc, err := apiB.Build()",
+		"func (r AppResult) HealthCheck(ctx context.Context) map[string]error {",
+		"if r.Audit != nil {",
+		`out["audit"] = r.Audit.Ping(ctx)`,
+	)
+}
+
+func TestGenGraph_ConditionsParallel(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeDISource(p)
+	graphPath := p.write("graph.json", `{
+  "package": "p",
+  "parallel": true,
+  "roots": [{
+    "name": "App",
+    "services": [
+      { "var": "a", "facadeCtor": "NewAV4", "facadeType": "*AV4", "implType": "A", "when": "enabled" }
+    ]
+  }]
+}`)
+
+	genGraph(graphPath, p.out("graph.gen.go"), genOptions{})
+	assertContainsInOrder(t, p.read("graph.gen.go"),
+		"var aB *AV4",
+		"if enabled {",
+		"func() error {",
+		"if aB == nil {",
+		"return nil",
+		"svc, err := aB.Build()",
+	)
+}
+
+func TestGenGraph_ConditionsInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		graph string
+		want  string
+	}{
+		{
+			name:  "bad_profile",
+			graph: `{"package":"p","profiles":{"prod":"cfg.Env =="},"roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}]}]}`,
+			want:  `graph profiles.prod "cfg.Env ==" is not a valid Go expression`,
+		},
+		{
+			name:  "bad_service_when",
+			graph: `{"package":"p","roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A","when":"staging only"}]}]}`,
+			want:  `graph service a when "staging only" is neither a profile nor a valid Go expression`,
+		},
+		{
+			name:  "bad_wiring_when",
+			graph: `{"package":"p","roots":[{"name":"App","services":[{"var":"a","facadeCtor":"NewAV4","facadeType":"*AV4","implType":"A"}],"wiring":[{"to":"a","call":"InjectA","argFrom":"a","when":"if x"}]}]}`,
+			want:  `graph wiring a.InjectA when "if x" is neither a profile nor a valid Go expression`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := newPkg(t)
+			writeDISource(p)
+			graphPath := p.write("graph.json", tt.graph)
+			assertPanicContains(t, func() { genGraph(graphPath, p.out("graph.gen.go"), genOptions{}) }, tt.want)
+		})
+	}
+}
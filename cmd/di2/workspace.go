@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// findWorkspace locates the go.work file governing startDir the way the go
+// command does: GOWORK=off disables workspace mode, an explicit GOWORK path is
+// used as-is, otherwise the nearest go.work walking up from startDir.
+func findWorkspace(startDir string) (string, bool) {
+	switch gowork := strings.TrimSpace(os.Getenv("GOWORK")); gowork {
+	case "off":
+		return "", false
+	case "":
+	default:
+		return gowork, fileExists(gowork)
+	}
+
+	dir := startDir
+	for {
+		work := filepath.Join(dir, "go.work")
+		if fileExists(work) {
+			return work, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// workspaceModules returns the modules listed by the use directives of a go.work
+// file, keyed by absolute module root. Used directories without a readable
+// go.mod module path are skipped.
+func workspaceModules(workFile string) (map[string]string, error) {
+	raw, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := modfile.ParseWork(workFile, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Dir(workFile)
+	mods := make(map[string]string, len(wf.Use))
+	for _, u := range wf.Use {
+		root := filepath.FromSlash(u.Path)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(base, root)
+		}
+		b, rerr := os.ReadFile(filepath.Join(root, "go.mod"))
+		if rerr != nil {
+			continue
+		}
+		if mp := modfile.ModulePath(b); mp != "" {
+			mods[filepath.Clean(root)] = mp
+		}
+	}
+	return mods, nil
+}
+
+// findOwningModule returns the module that owns dir. Inside a go.work workspace
+// that is the deepest used module whose root contains dir (which is not
+// necessarily the nearest go.mod: nested modules that are not used belong to
+// their parent); outside a workspace, or when no used module contains dir, it is
+// the nearest go.mod (findModule).
+func findOwningModule(dir string) (modRoot string, modPath string, err error) {
+	abs, aerr := filepath.Abs(dir)
+	if aerr != nil {
+		return findModule(dir)
+	}
+	work, ok := findWorkspace(abs)
+	if !ok {
+		return findModule(abs)
+	}
+	mods, werr := workspaceModules(work)
+	if werr != nil {
+		return "", "", &cmdError{msg: "invalid go.work at " + filepath.ToSlash(work) + ": " + werr.Error()}
+	}
+
+	for root, path := range mods {
+		if len(root) <= len(modRoot) {
+			continue
+		}
+		if rel, rerr := filepath.Rel(root, abs); rerr == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			modRoot, modPath = root, path
+		}
+	}
+	if modRoot == "" {
+		return findModule(abs)
+	}
+	return modRoot, modPath, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// -------------------------
+// go.work workspaces
+// -------------------------
+
+// writeWorkspace lays out a workspace with a used app module, a used lib module
+// nested inside it, and a nested tools module that is not used.
+func writeWorkspace(p *pkgHarness) {
+	p.write("go.work", "go 1.25\n\nuse (\n\t./app\n\t./app/lib\n\t./missing\n)\n")
+	p.write("app/go.mod", "module example.com/app\n\ngo 1.25\n")
+	p.write("app/svc/svc.go", "package svc\n")
+	p.write("app/lib/go.mod", "module \"example.com/lib\" // quoted\n\ngo 1.25\n")
+	p.write("app/lib/x/x.txt", "x")
+	p.write("app/tools/go.mod", "module example.com/tools\n\ngo 1.25\n")
+	p.write("app/tools/gen/g.txt", "x")
+}
+
+func TestFindOwningModule_Workspace(t *testing.T) {
+	t.Parallel()
+	p := newPkg(t)
+	writeWorkspace(p)
+
+	tests := []struct {
+		name     string
+		dir      string
+		wantRoot string
+		wantPath string
+	}{
+		{name: "used_module", dir: p.out("app/svc"), wantRoot: p.out("app"), wantPath: "example.com/app"},
+		{name: "deepest_used_module_wins", dir: p.out("app/lib/x"), wantRoot: p.out("app/lib"), wantPath: "example.com/lib"},
+		{name: "unused_nested_module_belongs_to_parent", dir: p.out("app/tools/gen"), wantRoot: p.out("app"), wantPath: "example.com/app"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			root, path, err := findOwningModule(tt.dir)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if root != tt.wantRoot || path != tt.wantPath {
+				t.Fatalf("got (%q, %q) want (%q, %q)", root, path, tt.wantRoot, tt.wantPath)
+			}
+		})
+	}
+
+	got, err := resolveImportPath(p.out("app/tools/gen"))
+	if err != nil || got != "example.com/app/tools/gen" {
+		t.Fatalf("resolveImportPath: got %q err=%v", got, err)
+	}
+}
+
+func TestFindOwningModule_Fallbacks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no_workspace_uses_nearest_go_mod", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		p.write("go.mod", "module example.com/root\n\ngo 1.25\n")
+		p.write("inner/go.mod", "module example.com/inner\n\ngo 1.25\n")
+		p.write("inner/a/x.txt", "x")
+
+		_, path, err := findOwningModule(p.out("inner/a"))
+		if err != nil || path != "example.com/inner" {
+			t.Fatalf("got %q err=%v", path, err)
+		}
+	})
+
+	t.Run("dir_outside_used_modules_uses_nearest_go_mod", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		p.write("go.work", "go 1.25\n\nuse ./a\n")
+		p.write("a/go.mod", "module example.com/a\n\ngo 1.25\n")
+		p.write("b/go.mod", "module example.com/b\n\ngo 1.25\n")
+
+		_, path, err := findOwningModule(p.out("b"))
+		if err != nil || path != "example.com/b" {
+			t.Fatalf("got %q err=%v", path, err)
+		}
+	})
+
+	t.Run("invalid_go_work_errors", func(t *testing.T) {
+		t.Parallel()
+		p := newPkg(t)
+		p.write("go.work", "use (\n")
+		p.write("a/x.txt", "x")
+
+		_, _, err := findOwningModule(p.out("a"))
+		if err == nil || !strings.Contains(err.Error(), "invalid go.work") {
+			t.Fatalf("expected invalid go.work error, got %v", err)
+		}
+	})
+}
+
+func TestFindWorkspace(t *testing.T) {
+	p := newPkg(t)
+	p.write("go.work", "go 1.25\n")
+	p.write("a/b/x.txt", "x")
+	start := filepath.Join(p.dir, "a", "b")
+
+	if work, ok := findWorkspace(start); !ok || work != p.out("go.work") {
+		t.Fatalf("got %q ok=%v", work, ok)
+	}
+
+	t.Setenv("GOWORK", "off")
+	if _, ok := findWorkspace(start); ok {
+		t.Fatalf("GOWORK=off must disable workspace mode")
+	}
+
+	explicit := p.write("other.work", "go 1.25\n")
+	t.Setenv("GOWORK", explicit)
+	if work, ok := findWorkspace(start); !ok || work != explicit {
+		t.Fatalf("got %q ok=%v want explicit GOWORK", work, ok)
+	}
+
+	if _, err := workspaceModules(p.out("nope.work")); err == nil {
+		t.Fatalf("expected read error")
+	}
+}
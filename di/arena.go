@@ -0,0 +1,47 @@
+package di
+
+// InitAll constructs one Service per ctor, allocating all Service shells from a
+// single backing slice.
+//
+// Deps bags are not preallocated: Injecting creates them on first use, and the
+// accessors treat a nil bag as empty. Compared with calling Init per service this
+// saves one shell and one map allocation per service.
+func InitAll[T any](ctors ...func() *T) []*Service[T] {
+	shells := make([]Service[T], len(ctors))
+	out := make([]*Service[T], len(ctors))
+	for i, ctor := range ctors {
+		shells[i].Val = ctor()
+		out[i] = &shells[i]
+	}
+	return out
+}
+
+// Arena hands out Service[T] shells from preallocated chunks.
+//
+// It is meant for large composition roots and tests that create hundreds of
+// services of the same type. Like InitAll, shells start without a Deps bag.
+// An Arena is not safe for concurrent use.
+type Arena[T any] struct {
+	chunk  []Service[T]
+	chunkN int
+}
+
+// NewArena returns an Arena that allocates shells n at a time (n < 1 means 1).
+func NewArena[T any](n int) *Arena[T] {
+	if n < 1 {
+		n = 1
+	}
+	return &Arena[T]{chunkN: n}
+}
+
+// Init constructs a Service from the next free shell, allocating a new chunk
+// when the current one is exhausted. Earlier services stay valid.
+func (a *Arena[T]) Init(ctor func() *T) *Service[T] {
+	if len(a.chunk) == 0 {
+		a.chunk = make([]Service[T], a.chunkN)
+	}
+	s := &a.chunk[0]
+	a.chunk = a.chunk[1:]
+	s.Val = ctor()
+	return s
+}
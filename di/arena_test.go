@@ -0,0 +1,78 @@
+package di_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestInitAll verifies one service per ctor, in order, with lazily created Deps.
+func TestInitAll(t *testing.T) {
+	t.Parallel()
+
+	svcs := di.InitAll(
+		func() *di.DB { return &di.DB{DSN: "a"} },
+		func() *di.DB { return &di.DB{DSN: "b"} },
+	)
+	require.Len(t, svcs, 2)
+	assert.Equal(t, "a", svcs[0].Val.DSN)
+	assert.Equal(t, "b", svcs[1].Val.DSN)
+	assert.Nil(t, svcs[0].Deps)
+	assert.False(t, svcs[0].Has(di.Key("x")))
+
+	assert.Empty(t, di.InitAll[di.DB]())
+}
+
+// TestInitAll_ServicesAreIndependent verifies injecting into one shell does not affect its neighbours.
+func TestInitAll_ServicesAreIndependent(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	users := di.InitAll(
+		func() *di.UserService { return &di.UserService{} },
+		func() *di.UserService { return &di.UserService{} },
+	)
+
+	_, err := users[0].With(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }))
+	require.NoError(t, err)
+
+	assert.True(t, users[0].Has(di.Key("db")))
+	assert.False(t, users[1].Has(di.Key("db")))
+	assert.Nil(t, users[1].Val.DB)
+}
+
+// TestArena verifies chunking, pointer stability across chunks and the n<1 clamp.
+func TestArena(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		chunk int
+	}{
+		{name: "chunk_2", chunk: 2},
+		{name: "chunk_clamped", chunk: 0},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := di.NewArena[di.DB](tc.chunk)
+			var svcs []*di.Service[di.DB]
+			for i := 0; i < 5; i++ {
+				dsn := string(rune('a' + i))
+				svcs = append(svcs, a.Init(func() *di.DB { return &di.DB{DSN: dsn} }))
+			}
+			for i, s := range svcs {
+				assert.Equal(t, string(rune('a'+i)), s.Val.DSN)
+				for j := 0; j < i; j++ {
+					assert.NotSame(t, svcs[j], s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package bindings provides ready-made v1 services for the infrastructure most
+// composition roots start with: a *sql.DB, an *http.Client and a Redis client.
+//
+// Each Bind function returns a Handle: the dependency as a *di.Service, the key
+// it is injected under, and health/close hooks, so
+//
+//	db, err := bindings.BindSQLDB("db", "postgres", dsn)
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//	users, err := di.Init(NewUserService).With(bindings.Into(db, (*UserService).SetDB))
+//	http.Handle("/healthz", di.HealthHandler(bindings.HealthCheck(db)))
+//
+// The package has no third-party dependencies: BindRedis takes the client
+// constructor and ping of the Redis library in use.
+package bindings
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/sghaida/odi/di"
+)
+
+// Handle is an infrastructure dependency bound under Key.
+type Handle[D any] struct {
+	// Key is the dependency key Into injects the service under.
+	Key di.DependencyKey
+
+	// Service holds the dependency (Service.Val).
+	Service *di.Service[D]
+
+	ping  func(ctx context.Context) error
+	close func() error
+}
+
+// Name returns the key as a string; HealthCheck reports the handle under it.
+func (h *Handle[D]) Name() string { return string(h.Key) }
+
+// Value returns the dependency.
+func (h *Handle[D]) Value() *D { return h.Service.Val }
+
+// Ping checks that the dependency is reachable. Handles without a health probe
+// (see BindHTTPClient) always succeed.
+func (h *Handle[D]) Ping(ctx context.Context) error {
+	if h.ping == nil {
+		return nil
+	}
+	return h.ping(ctx)
+}
+
+// Close releases the dependency (connection pools, idle connections).
+func (h *Handle[D]) Close() error {
+	if h.close == nil {
+		return nil
+	}
+	return h.close()
+}
+
+// Into returns an injector that binds h's dependency into a target service
+// under h.Key (see di.Injecting).
+func Into[T any, D any](h *Handle[D], bind func(target *T, dependency *D)) di.Injector[T] {
+	return di.Injecting(h.Key, h.Service, bind)
+}
+
+// Pinger is a named health probe; every Handle is one.
+type Pinger interface {
+	Name() string
+	Ping(ctx context.Context) error
+}
+
+// HealthCheck combines handles into a di.HealthCheckFunc keyed by their names,
+// for di.HealthHandler.
+func HealthCheck(ps ...Pinger) di.HealthCheckFunc {
+	return func(ctx context.Context) map[string]error {
+		out := make(map[string]error, len(ps))
+		for _, p := range ps {
+			out[p.Name()] = p.Ping(ctx)
+		}
+		return out
+	}
+}
+
+// BindSQLDB opens a *sql.DB for driverName (which the caller's imports must
+// register) and dsn. Like sql.Open it does not connect; Ping does.
+func BindSQLDB(key di.DependencyKey, driverName, dsn string) (*Handle[sql.DB], error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &Handle[sql.DB]{
+		Key:     key,
+		Service: di.Init(func() *sql.DB { return db }),
+		ping:    db.PingContext,
+		close:   db.Close,
+	}, nil
+}
+
+// BindHTTPClient returns an *http.Client with the given overall request timeout
+// (0: none). Its Ping always succeeds; Close drops idle connections.
+func BindHTTPClient(key di.DependencyKey, timeout time.Duration) *Handle[http.Client] {
+	c := &http.Client{Timeout: timeout}
+	return &Handle[http.Client]{
+		Key:     key,
+		Service: di.Init(func() *http.Client { return c }),
+		close: func() error {
+			c.CloseIdleConnections()
+			return nil
+		},
+	}
+}
+
+// RedisOptions are the connection settings BindRedis passes to the client
+// constructor.
+type RedisOptions struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+
+	// DialTimeout bounds connecting (0: the client's default).
+	DialTimeout time.Duration
+}
+
+// RedisClient is what BindRedis needs from a constructed client besides ping.
+type RedisClient interface {
+	Close() error
+}
+
+// BindRedis constructs a Redis client of the library in use from opts. ping
+// adapts the client's ping command to an error, e.g. for go-redis:
+//
+//	rdb, err := bindings.BindRedis("redis", opts,
+//		func(o bindings.RedisOptions) (*redis.Client, error) {
+//			return redis.NewClient(&redis.Options{Addr: o.Addr, Password: o.Password, DB: o.DB}), nil
+//		},
+//		func(ctx context.Context, c *redis.Client) error { return c.Ping(ctx).Err() },
+//	)
+func BindRedis[C any, PC interface {
+	*C
+	RedisClient
+}](key di.DependencyKey, opts RedisOptions, dial func(RedisOptions) (PC, error), ping func(context.Context, PC) error) (*Handle[C], error) {
+	c, err := dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, di.ErrNilDep
+	}
+	h := &Handle[C]{
+		Key:     key,
+		Service: di.Init(func() *C { return c }),
+		close:   c.Close,
+	}
+	if ping != nil {
+		h.ping = func(ctx context.Context) error { return ping(ctx, c) }
+	}
+	return h, nil
+}
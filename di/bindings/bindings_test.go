@@ -0,0 +1,123 @@
+package bindings_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+	"github.com/sghaida/odi/di/bindings"
+)
+
+var errDown = errors.New("down")
+
+// fakeDriver opens connections unless the DSN is "down".
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn == "down" {
+		return nil, errDown
+	}
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func init() { sql.Register("bindings-fake", fakeDriver{}) }
+
+type repo struct {
+	db   *sql.DB
+	http *http.Client
+}
+
+// TestBindSQLDB verifies injection, health checks and close of a *sql.DB handle.
+func TestBindSQLDB(t *testing.T) {
+	t.Parallel()
+
+	db, err := bindings.BindSQLDB("db", "bindings-fake", "ok")
+	require.NoError(t, err)
+	assert.Equal(t, "db", db.Name())
+
+	r, err := di.Init(func() *repo { return &repo{} }).With(bindings.Into(db, func(r *repo, d *sql.DB) { r.db = d }))
+	require.NoError(t, err)
+	assert.Same(t, db.Value(), r.Val.db)
+	assert.True(t, r.Has("db"))
+
+	down, err := bindings.BindSQLDB("replica", "bindings-fake", "down")
+	require.NoError(t, err, "sql.Open does not connect")
+
+	checks := bindings.HealthCheck(db, down)(context.Background())
+	require.NoError(t, checks["db"])
+	require.ErrorIs(t, checks["replica"], errDown)
+
+	require.NoError(t, db.Close())
+	require.Error(t, db.Ping(context.Background()), "closed pools fail ping")
+
+	_, err = bindings.BindSQLDB("db", "no-such-driver", "")
+	require.Error(t, err)
+}
+
+// TestBindHTTPClient verifies the client's timeout and no-op health check.
+func TestBindHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	c := bindings.BindHTTPClient("http", 3*time.Second)
+	assert.Equal(t, 3*time.Second, c.Value().Timeout)
+	require.NoError(t, c.Ping(context.Background()))
+	require.NoError(t, c.Close())
+
+	r, err := di.Init(func() *repo { return &repo{} }).With(bindings.Into(c, func(r *repo, h *http.Client) { r.http = h }))
+	require.NoError(t, err)
+	assert.Same(t, c.Value(), r.Val.http)
+}
+
+// fakeRedis stands in for a Redis library client.
+type fakeRedis struct {
+	addr   string
+	closed bool
+}
+
+func (c *fakeRedis) Close() error {
+	c.closed = true
+	return nil
+}
+
+// TestBindRedis verifies the client constructor, ping adapter and error paths.
+func TestBindRedis(t *testing.T) {
+	t.Parallel()
+
+	dial := func(o bindings.RedisOptions) (*fakeRedis, error) { return &fakeRedis{addr: o.Addr}, nil }
+	ping := func(_ context.Context, c *fakeRedis) error {
+		if c.closed {
+			return errDown
+		}
+		return nil
+	}
+
+	rdb, err := bindings.BindRedis("redis", bindings.RedisOptions{Addr: "localhost:6379"}, dial, ping)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:6379", rdb.Value().addr)
+	require.NoError(t, rdb.Ping(context.Background()))
+	require.NoError(t, rdb.Close())
+	require.ErrorIs(t, rdb.Ping(context.Background()), errDown)
+
+	noPing, err := bindings.BindRedis("redis", bindings.RedisOptions{}, dial, nil)
+	require.NoError(t, err)
+	require.NoError(t, noPing.Ping(context.Background()))
+
+	_, err = bindings.BindRedis("redis", bindings.RedisOptions{}, func(bindings.RedisOptions) (*fakeRedis, error) { return nil, errDown }, ping)
+	require.ErrorIs(t, err, errDown)
+	_, err = bindings.BindRedis("redis", bindings.RedisOptions{}, func(bindings.RedisOptions) (*fakeRedis, error) { return nil, nil }, ping)
+	require.ErrorIs(t, err, di.ErrNilDep)
+}
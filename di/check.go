@@ -0,0 +1,60 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NilFieldsError is returned by CheckWiring when required fields are still nil.
+type NilFieldsError struct {
+	Type   string   // e.g. "*app.UserService"
+	Fields []string // in the order they were requested
+}
+
+func (e NilFieldsError) Error() string {
+	// Example: di: *app.UserService has nil fields: db, logger
+	return "di: " + e.Type + " has nil fields: " + strings.Join(e.Fields, ", ")
+}
+
+// CheckWiring reports whether the listed fields of v (a struct or a pointer to
+// one) are non-nil. It is the v2 guardrail: call it once at startup or in a test
+// after wiring by hand.
+//
+// Fields may be unexported and must be of a nilable kind (pointer, interface,
+// map, slice, func or chan). Unknown or non-nilable fields are reported as
+// errors, since they mean the check itself is wrong. CheckWiring uses
+// reflection, so keep it out of hot paths.
+func CheckWiring(v any, requiredFields ...string) error {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return ErrNilTarget
+	}
+	typeName := rv.Type().String()
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("di: CheckWiring: %s is not a struct", typeName)
+	}
+
+	var missing []string
+	for _, name := range requiredFields {
+		f := rv.FieldByName(name)
+		if !f.IsValid() {
+			return fmt.Errorf("di: CheckWiring: %s has no field %q", typeName, name)
+		}
+		switch f.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		default:
+			return fmt.Errorf("di: CheckWiring: field %q of %s is a %s, not a nilable kind", name, typeName, f.Kind())
+		}
+		if f.IsNil() {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return NilFieldsError{Type: typeName, Fields: missing}
+	}
+	return nil
+}
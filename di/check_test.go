@@ -0,0 +1,72 @@
+package di_test
+
+import (
+	"testing"
+
+	"github.com/sghaida/odi/di"
+	"github.com/stretchr/testify/assert"
+)
+
+type checked struct {
+	db     *di.DB
+	log    interface{ Print(...any) }
+	hooks  []func()
+	name   string
+	Basket *di.BasketService
+}
+
+// TestCheckWiring covers nil/non-nil fields, unexported fields and misuse.
+func TestCheckWiring(t *testing.T) {
+	t.Parallel()
+
+	wired := &checked{db: &di.DB{}, Basket: &di.BasketService{}, hooks: []func(){}}
+
+	tests := []struct {
+		name    string
+		v       any
+		fields  []string
+		wantErr string
+	}{
+		{name: "all wired", v: wired, fields: []string{"db", "Basket", "hooks"}},
+		{name: "struct value", v: *wired, fields: []string{"db"}},
+		{name: "no fields", v: &checked{}},
+		{
+			name:    "nil fields in request order",
+			v:       &checked{db: &di.DB{}},
+			fields:  []string{"log", "db", "Basket"},
+			wantErr: "di: *di_test.checked has nil fields: log, Basket",
+		},
+		{name: "nil pointer", v: (*checked)(nil), wantErr: di.ErrNilTarget.Error()},
+		{name: "nil", v: nil, wantErr: di.ErrNilTarget.Error()},
+		{name: "not a struct", v: &[]int{}, wantErr: "di: CheckWiring: *[]int is not a struct"},
+		{
+			name:    "unknown field",
+			v:       wired,
+			fields:  []string{"cache"},
+			wantErr: `di: CheckWiring: *di_test.checked has no field "cache"`,
+		},
+		{
+			name:    "non-nilable field",
+			v:       wired,
+			fields:  []string{"name"},
+			wantErr: `di: CheckWiring: field "name" of *di_test.checked is a string, not a nilable kind`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := di.CheckWiring(tc.v, tc.fields...)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+
+	var nf di.NilFieldsError
+	assert.ErrorAs(t, di.CheckWiring(&checked{}, "db"), &nf)
+	assert.Equal(t, []string{"db"}, nf.Fields)
+}
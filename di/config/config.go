@@ -0,0 +1,303 @@
+// Package config loads application config structs from layered sources, so
+// composition roots stop hand-writing LoadFromEnv.
+//
+// Layers are applied in order, each overriding the previous one:
+//
+//  1. defaults: the `default:"..."` struct tag
+//  2. file: a JSON file (field names per `json` tags), see WithFile
+//  3. env: the `env:"NAME"` struct tag, see WithEnvPrefix
+//  4. flags: the `flag:"name"` struct tag, see WithFlags
+//
+// Fields tagged `required:"true"` must be non-zero afterwards, and a config with
+// a Validate() error method (di.ConfigValidator) is validated last:
+//
+//	type Config struct {
+//		Env     string        `json:"env" env:"ENV" flag:"env" default:"local"`
+//		Timeout time.Duration `json:"timeout" env:"TIMEOUT" default:"10s"`
+//		DSN     string        `json:"dsn" env:"DSN" required:"true"`
+//	}
+//
+//	cfg, err := config.Load[Config](config.WithEnvPrefix("ODI_"), config.WithFlags(flag.CommandLine, os.Args[1:]))
+//
+// Unlike ad-hoc env parsing, a malformed value is an error naming the field and
+// its source instead of a silent fallback to the default. Supported field types
+// are strings, bools, integers, floats, time.Duration, encoding.TextUnmarshaler
+// implementations, slices of those (comma-separated) and nested structs.
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sghaida/odi/di"
+)
+
+// Option configures Load.
+type Option func(*loader)
+
+type loader struct {
+	file         string
+	fileOptional bool
+	envPrefix    string
+	lookupEnv    func(string) (string, bool)
+	flags        *flag.FlagSet
+	args         []string
+}
+
+// WithFile decodes the JSON file at path over the defaults, with encoding/json
+// rules (a time.Duration is a number of nanoseconds there). A missing file is an
+// error; see WithOptionalFile.
+func WithFile(path string) Option {
+	return func(l *loader) { l.file, l.fileOptional = path, false }
+}
+
+// WithOptionalFile is WithFile, except that a missing file is skipped.
+func WithOptionalFile(path string) Option {
+	return func(l *loader) { l.file, l.fileOptional = path, true }
+}
+
+// WithEnvPrefix prepends prefix to every `env` tag name (e.g. "ODI_").
+func WithEnvPrefix(prefix string) Option {
+	return func(l *loader) { l.envPrefix = prefix }
+}
+
+// WithLookupEnv replaces os.LookupEnv, e.g. for tests.
+func WithLookupEnv(lookup func(string) (string, bool)) Option {
+	return func(l *loader) { l.lookupEnv = lookup }
+}
+
+// WithFlags defines a flag on fs for every `flag` tag (usage from the `usage`
+// tag) and parses args with it; only flags given in args override a field.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *loader) { l.flags, l.args = fs, args }
+}
+
+// Load builds a T from the configured layers and validates it.
+func Load[T any](opts ...Option) (T, error) {
+	var cfg T
+	err := LoadInto(&cfg, opts...)
+	return cfg, err
+}
+
+// LoadInto is Load for an existing struct; dst must be a non-nil struct pointer.
+// Its current field values are the base the default tags override.
+func LoadInto(dst any, opts ...Option) error {
+	l := loader{lookupEnv: os.LookupEnv}
+	for _, o := range opts {
+		o(&l)
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: LoadInto needs a non-nil struct pointer, got %T", dst)
+	}
+	root := rv.Elem()
+	fields := collect(root, "")
+
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := f.set(def); err != nil {
+				return f.errorf("default", "", err)
+			}
+		}
+	}
+	if err := l.applyFile(dst); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		name := f.tag.Get("env")
+		if name == "" {
+			continue
+		}
+		name = l.envPrefix + name
+		if v, ok := l.lookupEnv(name); ok {
+			if err := f.set(v); err != nil {
+				return f.errorf("env", name, err)
+			}
+		}
+	}
+	if err := l.applyFlags(fields); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if f.tag.Get("required") == "true" && f.v.IsZero() {
+			missing = append(missing, f.path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("config: required fields not set: %s", strings.Join(missing, ", "))
+	}
+	if v, ok := dst.(di.ConfigValidator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("config: %w: %w", di.ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+func (l *loader) applyFile(dst any) error {
+	if l.file == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(l.file)
+	if err != nil {
+		if l.fileOptional && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("config: %w", err)
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("config: %s: %w", l.file, err)
+	}
+	return nil
+}
+
+func (l *loader) applyFlags(fields []field) error {
+	if l.flags == nil {
+		return nil
+	}
+	var setErr error
+	for _, f := range fields {
+		name := f.tag.Get("flag")
+		if name == "" {
+			continue
+		}
+		fv := &flagValue{f: f, name: name, err: &setErr}
+		l.flags.Var(fv, name, f.tag.Get("usage"))
+	}
+	if err := l.flags.Parse(l.args); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return setErr
+}
+
+// flagValue sets a field when its flag is parsed. Errors are recorded rather
+// than returned, so they name the field instead of flag's generic message.
+type flagValue struct {
+	f    field
+	name string
+	err  *error
+}
+
+func (v *flagValue) String() string {
+	if v == nil || !v.f.v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.f.v.Interface())
+}
+
+func (v *flagValue) Set(s string) error {
+	if err := v.f.set(s); err != nil && *v.err == nil {
+		*v.err = v.f.errorf("flag", "-"+v.name, err)
+	}
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.f.v.Kind() == reflect.Bool }
+
+// field is a settable leaf field of the config struct.
+type field struct {
+	path string // e.g. "DB.Timeout"
+	v    reflect.Value
+	tag  reflect.StructTag
+}
+
+func (f field) errorf(source, name string, err error) error {
+	if name != "" {
+		source += " " + name
+	}
+	return fmt.Errorf("config: %s: %s: %w", f.path, source, err)
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// collect returns the exported leaf fields of v, descending into nested structs
+// that do not implement encoding.TextUnmarshaler.
+func collect(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		path := prefix + sf.Name
+		if fv.Kind() == reflect.Struct && !reflect.PointerTo(fv.Type()).Implements(textUnmarshaler) {
+			out = append(out, collect(fv, path+".")...)
+			continue
+		}
+		out = append(out, field{path: path, v: fv, tag: sf.Tag})
+	}
+	return out
+}
+
+func (f field) set(s string) error { return setValue(f.v, s) }
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
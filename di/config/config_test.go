@@ -0,0 +1,159 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+	"github.com/sghaida/odi/di/config"
+)
+
+type dbConfig struct {
+	DSN      string        `json:"dsn" env:"DB_DSN" required:"true"`
+	MaxConns int           `json:"maxConns" env:"DB_MAX_CONNS" default:"4"`
+	Timeout  time.Duration `json:"timeout" env:"DB_TIMEOUT" default:"2s"`
+}
+
+type appConfig struct {
+	Env     string     `json:"env" env:"ENV" flag:"env" usage:"environment" default:"local"`
+	Debug   bool       `json:"debug" env:"DEBUG" flag:"debug"`
+	Ratio   float64    `json:"ratio" default:"0.5"`
+	Brokers []string   `json:"brokers" env:"BROKERS"`
+	Addr    netip.Addr `json:"addr" env:"ADDR" default:"127.0.0.1"`
+	DB      dbConfig   `json:"db"`
+
+	internal string
+}
+
+func (c appConfig) Validate() error {
+	if c.DB.MaxConns <= 0 {
+		return errors.New("db.maxConns must be > 0")
+	}
+	return nil
+}
+
+func env(vars map[string]string) config.Option {
+	return config.WithLookupEnv(func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	})
+}
+
+func flags(args ...string) config.Option {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return config.WithFlags(fs, args)
+}
+
+func writeFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+	return path
+}
+
+// TestLoad_Layers verifies defaults < file < env < flags precedence.
+func TestLoad_Layers(t *testing.T) {
+	t.Parallel()
+
+	file := writeFile(t, `{"env": "file", "ratio": 0.9, "db": {"dsn": "file-dsn", "maxConns": 8}}`)
+	cfg, err := config.Load[appConfig](
+		config.WithFile(file),
+		config.WithEnvPrefix("ODI_"),
+		env(map[string]string{"ODI_ENV": "env", "ODI_BROKERS": "a:9092, b:9092", "ODI_DB_TIMEOUT": "5s", "ENV": "unprefixed"}),
+		flags("-env", "flag", "-debug"),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "flag", cfg.Env)
+	assert.True(t, cfg.Debug)
+	assert.InDelta(t, 0.9, cfg.Ratio, 1e-9)
+	assert.Equal(t, []string{"a:9092", "b:9092"}, cfg.Brokers)
+	assert.Equal(t, netip.MustParseAddr("127.0.0.1"), cfg.Addr)
+	assert.Equal(t, dbConfig{DSN: "file-dsn", MaxConns: 8, Timeout: 5 * time.Second}, cfg.DB)
+}
+
+// TestLoad_Defaults verifies defaults apply when no other layer sets a field and
+// that an optional file may be missing.
+func TestLoad_Defaults(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Load[appConfig](
+		config.WithOptionalFile(filepath.Join(t.TempDir(), "missing.json")),
+		env(map[string]string{"DB_DSN": "dsn"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "local", cfg.Env)
+	assert.Equal(t, 4, cfg.DB.MaxConns)
+	assert.Equal(t, 2*time.Second, cfg.DB.Timeout)
+}
+
+// TestLoad_Errors verifies malformed values, missing required fields, Validate
+// failures and bad inputs are reported with their field and source.
+func TestLoad_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    []config.Option
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:    "bad_env",
+			opts:    []config.Option{env(map[string]string{"DB_DSN": "dsn", "DB_MAX_CONNS": "many"})},
+			wantErr: `config: DB.MaxConns: env DB_MAX_CONNS: strconv.ParseInt: parsing "many": invalid syntax`,
+		},
+		{
+			name:    "bad_flag",
+			opts:    []config.Option{env(map[string]string{"DB_DSN": "dsn"}), flags("-debug=maybe")},
+			wantErr: `config: Debug: flag -debug: strconv.ParseBool: parsing "maybe": invalid syntax`,
+		},
+		{
+			name:    "unknown_flag",
+			opts:    []config.Option{env(map[string]string{"DB_DSN": "dsn"}), flags("-nope")},
+			wantErr: "config: flag provided but not defined: -nope",
+		},
+		{
+			name:    "required",
+			opts:    []config.Option{env(nil)},
+			wantErr: "config: required fields not set: DB.DSN",
+		},
+		{
+			name:    "validate",
+			opts:    []config.Option{env(map[string]string{"DB_DSN": "dsn", "DB_MAX_CONNS": "0"})},
+			wantErr: "config: invalid config: db.maxConns must be > 0",
+			wantIs:  di.ErrInvalidConfig,
+		},
+		{
+			name:    "missing_file",
+			opts:    []config.Option{config.WithFile("/nonexistent/config.json")},
+			wantIs:  os.ErrNotExist,
+			wantErr: "config: open /nonexistent/config.json",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := config.Load[appConfig](tc.opts...)
+			require.ErrorContains(t, err, tc.wantErr)
+			if tc.wantIs != nil {
+				require.ErrorIs(t, err, tc.wantIs)
+			}
+		})
+	}
+
+	var notStruct int
+	require.ErrorContains(t, config.LoadInto(&notStruct), "non-nil struct pointer")
+}
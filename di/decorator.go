@@ -0,0 +1,191 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// MethodCall identifies the facade method a Decorator wraps.
+type MethodCall struct {
+	Facade string // e.g. "CoreV4"
+	Method string // e.g. "Process"
+}
+
+// Decorator wraps a call to a generated facade method (retry, timeout, panic
+// recovery, logging, metrics). It calls next, which invokes the implementation,
+// zero or more times and returns next's error or one of its own.
+//
+// next runs with the context it is given: for methods whose first parameter is a
+// context.Context that context replaces the caller's, so a decorator can add a
+// deadline or values. Other methods get context.Background().
+//
+// Facades declare decorators per service or per method in the spec
+// ("decorators"); the generated wrapper of a method returning an error runs the
+// call through Chain of them.
+type Decorator func(ctx context.Context, call MethodCall, next func(context.Context) error) error
+
+// Chain composes decorators into one; the first is the outermost. nil entries
+// are skipped and an empty chain calls next directly.
+func Chain(ds ...Decorator) Decorator {
+	var live []Decorator
+	for _, d := range ds {
+		if d != nil {
+			live = append(live, d)
+		}
+	}
+	return func(ctx context.Context, call MethodCall, next func(context.Context) error) error {
+		return runChain(ctx, call, live, next)
+	}
+}
+
+func runChain(ctx context.Context, call MethodCall, ds []Decorator, next func(context.Context) error) error {
+	if len(ds) == 0 {
+		return next(ctx)
+	}
+	return ds[0](ctx, call, func(ctx context.Context) error {
+		return runChain(ctx, call, ds[1:], next)
+	})
+}
+
+// Timeout is a Decorator that runs the call with a context that expires after d.
+// It only bounds methods that honor their context.Context parameter.
+func Timeout(d time.Duration) Decorator {
+	return func(ctx context.Context, _ MethodCall, next func(context.Context) error) error {
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return next(ctx)
+	}
+}
+
+// Retry is a Decorator that makes up to attempts calls, waiting backoff between
+// them, until one returns a nil error. It stops early when ctx is done and then
+// returns the last error of the call.
+func Retry(attempts int, backoff time.Duration) Decorator {
+	return func(ctx context.Context, _ MethodCall, next func(context.Context) error) error {
+		err := next(ctx)
+		for i := 1; i < attempts && err != nil; i++ {
+			t := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+			err = next(ctx)
+		}
+		return err
+	}
+}
+
+// RecursionLimitError is returned by a DepthGuard call that would nest deeper
+// than its limit. It unwraps to ErrRecursionLimit.
+type RecursionLimitError struct {
+	// FacadeName and Method identify the guarded call that was refused.
+	FacadeName string
+	Method     string
+	// Depth is the nesting the call would have had, Max the guard's limit.
+	Depth int
+	Max   int
+}
+
+func (e *RecursionLimitError) Error() string {
+	return fmt.Sprintf("%s: %v (method=%s, depth=%d, max=%d)",
+		e.FacadeName, ErrRecursionLimit, e.Method, e.Depth, e.Max)
+}
+
+// Unwrap returns ErrRecursionLimit.
+func (e *RecursionLimitError) Unwrap() error { return ErrRecursionLimit }
+
+type recursionDepthKey struct{}
+
+// RecursionDepth is the number of DepthGuard calls ctx is nested in (0 outside
+// of any).
+func RecursionDepth(ctx context.Context) int {
+	d, _ := ctx.Value(recursionDepthKey{}).(int)
+	return d
+}
+
+// DepthGuard is a Decorator that bounds nested guarded calls: every guarded
+// call runs next one level deeper (see RecursionDepth), and a call that would
+// nest more than max levels returns a *RecursionLimitError without calling
+// next. All guards share the depth, so guards on every edge of a cycle bound
+// it by the smallest max on the path.
+//
+// The depth travels in the context and only counts calls that pass through a
+// guard. Graph wiring injects implementations, not facades, so a guard on a
+// facade method counts the call into the cycle but not the calls the
+// implementations make to each other; guard those where the cycle edge is
+// crossed:
+//
+//	err := guard(ctx, di.MethodCall{Facade: "Alpha", Method: "DoBeta"}, func(ctx context.Context) error {
+//		resp, err = a.beta.DoBeta(ctx, req)
+//		return err
+//	})
+//
+// Specs generate it for the facade methods in "recursionGuard".
+func DepthGuard(max int) Decorator {
+	return func(ctx context.Context, call MethodCall, next func(context.Context) error) error {
+		depth := RecursionDepth(ctx) + 1
+		if depth > max {
+			return &RecursionLimitError{FacadeName: call.Facade, Method: call.Method, Depth: depth, Max: max}
+		}
+		return next(context.WithValue(ctx, recursionDepthKey{}, depth))
+	}
+}
+
+// PanicError reports a panic recovered from a facade method generated with
+// "recoverPanics". It unwraps to ErrPanicked and, when the panic value is an
+// error, to that error as well.
+type PanicError struct {
+	// FacadeName is the generated facade type (e.g. "CoreV4").
+	FacadeName string
+	// Method is the wrapped method that panicked.
+	Method string
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the goroutine stack at the time of the panic.
+	Stack []byte
+	// SpecHash is the SHA-256 of the spec the facade was generated from.
+	SpecHash string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %v (method=%s, value=%v, spec=%s)",
+		e.FacadeName, ErrPanicked, e.Method, e.Value, e.SpecHash)
+}
+
+// Unwrap returns ErrPanicked and the panic value if it is an error.
+func (e *PanicError) Unwrap() []error {
+	if err, ok := e.Value.(error); ok {
+		return []error{ErrPanicked, err}
+	}
+	return []error{ErrPanicked}
+}
+
+// PanicHandler is told about every panic a facade recovers (logging, metrics,
+// alerting) before the method returns the *PanicError.
+type PanicHandler func(ctx context.Context, err *PanicError)
+
+// CallRecovered runs next through chain (nil: directly) like a decorated facade
+// method, and converts a panic in either into a *PanicError that is passed to h
+// (if non-nil) and returned. Generated wrappers of "recoverPanics" methods call
+// it; the panic of a handler is not recovered.
+func CallRecovered(ctx context.Context, call MethodCall, specHash string, h PanicHandler, chain Decorator, next func(context.Context) error) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		pe := &PanicError{FacadeName: call.Facade, Method: call.Method, Value: v, Stack: debug.Stack(), SpecHash: specHash}
+		if h != nil {
+			h(ctx, pe)
+		}
+		err = pe
+	}()
+	if chain == nil {
+		return next(ctx)
+	}
+	return chain(ctx, call, next)
+}
@@ -0,0 +1,252 @@
+package di_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+type ctxKey string
+
+// trace records entry and exit of a decorator and tags the context.
+func trace(name string, log *[]string) di.Decorator {
+	return func(ctx context.Context, call di.MethodCall, next func(context.Context) error) error {
+		*log = append(*log, fmt.Sprintf("%s>%s.%s", name, call.Facade, call.Method))
+		err := next(context.WithValue(ctx, ctxKey(name), true))
+		*log = append(*log, name+"<")
+		return err
+	}
+}
+
+// TestChain verifies decorator order, context propagation and error flow.
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var log []string
+	call := di.MethodCall{Facade: "CoreV4", Method: "Process"}
+	boom := errors.New("boom")
+
+	err := di.Chain(trace("outer", &log), nil, trace("inner", &log))(context.Background(), call, func(ctx context.Context) error {
+		assert.Equal(t, true, ctx.Value(ctxKey("outer")))
+		assert.Equal(t, true, ctx.Value(ctxKey("inner")))
+		log = append(log, "call")
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+	assert.Equal(t, []string{"outer>CoreV4.Process", "inner>CoreV4.Process", "call", "inner<", "outer<"}, log)
+}
+
+// TestChain_Empty verifies an empty chain calls next directly.
+func TestChain_Empty(t *testing.T) {
+	t.Parallel()
+
+	called := 0
+	require.NoError(t, di.Chain()(context.Background(), di.MethodCall{}, func(context.Context) error {
+		called++
+		return nil
+	}))
+	assert.Equal(t, 1, called)
+}
+
+// TestChain_ShortCircuitAndRetry verifies decorators may skip or repeat next.
+func TestChain_ShortCircuitAndRetry(t *testing.T) {
+	t.Parallel()
+
+	denied := errors.New("denied")
+	deny := func(context.Context, di.MethodCall, func(context.Context) error) error { return denied }
+	twice := func(ctx context.Context, _ di.MethodCall, next func(context.Context) error) error {
+		if err := next(ctx); err == nil {
+			return nil
+		}
+		return next(ctx)
+	}
+
+	calls := 0
+	next := func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("flaky")
+		}
+		return nil
+	}
+	require.NoError(t, di.Chain(twice)(context.Background(), di.MethodCall{}, next))
+	assert.Equal(t, 2, calls)
+
+	require.ErrorIs(t, di.Chain(deny, twice)(context.Background(), di.MethodCall{}, next), denied)
+	assert.Equal(t, 2, calls)
+}
+
+// TestTimeout verifies the call runs with a deadline.
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	err := di.Timeout(time.Millisecond)(context.Background(), di.MethodCall{}, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestRetry verifies attempts, success short-circuit and cancellation.
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	flaky := func(failures int, calls *int) func(context.Context) error {
+		return func(context.Context) error {
+			*calls++
+			if *calls <= failures {
+				return fmt.Errorf("attempt %d", *calls)
+			}
+			return nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  int
+		wantCalls int
+		wantErr   string
+	}{
+		{name: "first_succeeds", attempts: 3, failures: 0, wantCalls: 1},
+		{name: "third_succeeds", attempts: 3, failures: 2, wantCalls: 3},
+		{name: "exhausted", attempts: 3, failures: 5, wantCalls: 3, wantErr: "attempt 3"},
+		{name: "zero_attempts_calls_once", attempts: 0, failures: 5, wantCalls: 1, wantErr: "attempt 1"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			err := di.Retry(tt.attempts, time.Microsecond)(context.Background(), di.MethodCall{}, flaky(tt.failures, &calls))
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("canceled_stops_retrying", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := di.Retry(5, time.Hour)(ctx, di.MethodCall{}, func(context.Context) error {
+			calls++
+			cancel()
+			return errors.New("down")
+		})
+		require.EqualError(t, err, "down")
+		assert.Equal(t, 1, calls)
+	})
+}
+
+// TestDepthGuard verifies guarded mutual calls nest one level each and fail
+// past the limit with a *RecursionLimitError.
+func TestDepthGuard(t *testing.T) {
+	t.Parallel()
+
+	alpha := di.MethodCall{Facade: "AlphaV4", Method: "DoAlpha"}
+	beta := di.MethodCall{Facade: "BetaV4", Method: "DoBeta"}
+	var calls []int
+	var ping func(ctx context.Context, call di.MethodCall) error
+	ping = func(ctx context.Context, call di.MethodCall) error {
+		return di.DepthGuard(3)(ctx, call, func(ctx context.Context) error {
+			calls = append(calls, di.RecursionDepth(ctx))
+			if call == alpha {
+				return ping(ctx, beta)
+			}
+			return ping(ctx, alpha)
+		})
+	}
+
+	err := ping(context.Background(), alpha)
+	var re *di.RecursionLimitError
+	require.ErrorAs(t, err, &re)
+	require.ErrorIs(t, err, di.ErrRecursionLimit)
+	assert.Equal(t, []int{1, 2, 3}, calls)
+	assert.Equal(t, di.RecursionLimitError{FacadeName: "BetaV4", Method: "DoBeta", Depth: 4, Max: 3}, *re)
+	assert.Equal(t, "BetaV4: recursion limit exceeded (method=DoBeta, depth=4, max=3)", err.Error())
+	assert.Equal(t, 0, di.RecursionDepth(context.Background()))
+
+	// the smallest limit on the path wins
+	err = di.DepthGuard(8)(context.Background(), alpha, func(ctx context.Context) error {
+		return di.DepthGuard(1)(ctx, beta, func(context.Context) error { return nil })
+	})
+	require.ErrorIs(t, err, di.ErrRecursionLimit)
+}
+
+// TestCallRecovered verifies panics in the call or the chain become *PanicError.
+func TestCallRecovered(t *testing.T) {
+	t.Parallel()
+
+	call := di.MethodCall{Facade: "CoreV4", Method: "Process"}
+	cause := errors.New("nil map")
+	boom := errors.New("boom")
+	panicking := func(v any) func(context.Context) error {
+		return func(context.Context) error { panic(v) }
+	}
+
+	tests := []struct {
+		name      string
+		chain     di.Decorator
+		next      func(context.Context) error
+		wantErr   error
+		wantValue any
+	}{
+		{name: "no_panic", next: func(context.Context) error { return boom }, wantErr: boom},
+		{name: "panic_string", next: panicking("bad state"), wantErr: di.ErrPanicked, wantValue: "bad state"},
+		{name: "panic_error", next: panicking(cause), wantErr: cause, wantValue: cause},
+		{
+			name:      "panic_in_chain",
+			chain:     di.Chain(func(context.Context, di.MethodCall, func(context.Context) error) error { panic("decorator") }),
+			next:      func(context.Context) error { return nil },
+			wantErr:   di.ErrPanicked,
+			wantValue: "decorator",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.WithValue(context.Background(), ctxKey("req"), "42")
+			var handled *di.PanicError
+			h := func(hctx context.Context, err *di.PanicError) {
+				assert.Equal(t, "42", hctx.Value(ctxKey("req")))
+				handled = err
+			}
+
+			err := di.CallRecovered(ctx, call, "abc123", h, tt.chain, tt.next)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantValue == nil {
+				assert.Nil(t, handled)
+				return
+			}
+
+			var pe *di.PanicError
+			require.ErrorAs(t, err, &pe)
+			require.ErrorIs(t, err, di.ErrPanicked)
+			assert.Same(t, pe, handled)
+			assert.Equal(t, "CoreV4", pe.FacadeName)
+			assert.Equal(t, "Process", pe.Method)
+			assert.Equal(t, tt.wantValue, pe.Value)
+			assert.Contains(t, string(pe.Stack), "TestCallRecovered")
+			assert.Equal(t, fmt.Sprintf("CoreV4: panicked (method=Process, value=%v, spec=abc123)", tt.wantValue), err.Error())
+		})
+	}
+
+	// without a handler the panic is still returned
+	err := di.CallRecovered(context.Background(), call, "h", nil, nil, panicking("x"))
+	require.ErrorIs(t, err, di.ErrPanicked)
+}
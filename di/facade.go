@@ -0,0 +1,498 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runtime helpers shared by di2-generated (v4) facades. They keep the repeated
+// wiring logic out of every generated file; they are not meant to be called
+// directly by application code.
+
+// Sentinel errors wrapped by di2-generated code, so callers can match them with
+// errors.Is regardless of the facade or graph that produced the error. They are
+// phrased as fragments of the wrapping messages (e.g. "CoreV4: duplicate inject Alpha").
+var (
+	// ErrWiringIncomplete matches every *WiringError.
+	ErrWiringIncomplete = errors.New("wiring incomplete")
+
+	// ErrDuplicateInject is returned when a required dep is injected twice under
+	// InjectPolicyError.
+	ErrDuplicateInject = errors.New("duplicate inject")
+
+	// ErrNotBuilt is returned by generated graphs when a service they need (a shared
+	// service, or a service asked for its health) was not built.
+	ErrNotBuilt = errors.New("not built")
+
+	// ErrPanicked matches every *PanicError.
+	ErrPanicked = errors.New("panicked")
+
+	// ErrInvalidConfig is wrapped by ValidateConfig failures.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	// ErrStaleImpl matches every *StaleImplError.
+	ErrStaleImpl = errors.New("reset after UnsafeImpl")
+
+	// ErrRecursionLimit matches every *RecursionLimitError.
+	ErrRecursionLimit = errors.New("recursion limit exceeded")
+)
+
+// ConfigValidator is implemented by config types that can check themselves.
+// Generated facades with config enabled call it on construction (see
+// ValidateConfig), and di/config calls it after loading.
+type ConfigValidator interface {
+	Validate() error
+}
+
+// ValidateConfig runs cfg's Validate method, if it has one, wrapping a failure as
+// "<facade>: invalid config: <err>". cfg may be a config value or a pointer to
+// one; for a pointer, methods of both the pointer and the value are considered.
+func ValidateConfig(facade string, cfg any) error {
+	v, ok := cfg.(ConfigValidator)
+	if !ok {
+		rv := reflect.ValueOf(cfg)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return nil
+		}
+		if v, ok = rv.Elem().Interface().(ConfigValidator); !ok {
+			return nil
+		}
+	}
+	if err := v.Validate(); err != nil {
+		return fmt.Errorf("%s: %w: %w", facade, ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+// Inject policies for a required dep that is injected twice
+// (spec "injectPolicy.onOverwrite").
+const (
+	InjectPolicyError     = "error"
+	InjectPolicyIgnore    = "ignore"
+	InjectPolicyOverwrite = "overwrite"
+)
+
+// CheckInject applies an inject policy to injecting dep into facade.
+//
+// It reports whether the injection should proceed. It returns an error for a
+// duplicate injection under InjectPolicyError and for an unknown policy; a
+// duplicate under InjectPolicyIgnore returns (false, nil).
+func CheckInject(facade, policy, dep string, alreadyInjected bool) (bool, error) {
+	switch policy {
+	case InjectPolicyError:
+		if alreadyInjected {
+			return false, fmt.Errorf("%s: %w %s", facade, ErrDuplicateInject, dep)
+		}
+	case InjectPolicyIgnore:
+		if alreadyInjected {
+			return false, nil
+		}
+	case InjectPolicyOverwrite:
+		// allow overwriting
+	default:
+		return false, fmt.Errorf("%s: invalid injectPolicy.onOverwrite=%s", facade, policy)
+	}
+	return true, nil
+}
+
+// MissingNames returns the names whose bit is set in missing (bit i is names[i]).
+// The result is never nil.
+func MissingNames(names []string, missing uint64) []string {
+	out := make([]string, 0, bits.OnesCount64(missing))
+	for i, name := range names {
+		if missing&(1<<i) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// MissingNamesWide is MissingNames for facades with more than 64 required deps,
+// whose mask spans several words: bit i of missing[w] is names[64*w+i].
+func MissingNamesWide(names []string, missing []uint64) []string {
+	n := 0
+	for _, w := range missing {
+		n += bits.OnesCount64(w)
+	}
+	out := make([]string, 0, n)
+	for i, name := range names {
+		if w := i / 64; w < len(missing) && missing[w]&(1<<(i%64)) != 0 {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// WiringError is the error a facade reports when required deps are missing.
+// Use errors.As to inspect which deps are missing, e.g. for metrics.
+type WiringError struct {
+	// FacadeName is the generated facade type (e.g. "CoreV4").
+	FacadeName string
+	// Ctx is Build, BuildWith or the name of the wrapped method.
+	Ctx string
+	// Missing lists the missing required deps in spec order.
+	Missing []string
+	// SpecHash is the SHA-256 of the spec the facade was generated from.
+	SpecHash string
+}
+
+func (e *WiringError) Error() string {
+	return fmt.Sprintf("%s: %v (ctx=%s, missing=%v, spec=%s)",
+		e.FacadeName, ErrWiringIncomplete, e.Ctx, e.Missing, e.SpecHash)
+}
+
+// Unwrap returns ErrWiringIncomplete.
+func (e *WiringError) Unwrap() error { return ErrWiringIncomplete }
+
+// WiringIncomplete returns the *WiringError a facade reports when required deps
+// are missing for ctx (Build, BuildWith or a wrapped method name).
+func WiringIncomplete(facade, ctx, specHash string, names []string, missing uint64) error {
+	return &WiringError{
+		FacadeName: facade,
+		Ctx:        ctx,
+		Missing:    MissingNames(names, missing),
+		SpecHash:   specHash,
+	}
+}
+
+// WiringIncompleteWide is WiringIncomplete for a multi-word mask (see
+// MissingNamesWide).
+func WiringIncompleteWide(facade, ctx, specHash string, names []string, missing []uint64) error {
+	return &WiringError{
+		FacadeName: facade,
+		Ctx:        ctx,
+		Missing:    MissingNamesWide(names, missing),
+		SpecHash:   specHash,
+	}
+}
+
+// DepGroup is an all-or-nothing set of facade deps: Mask holds their required
+// dep bits.
+type DepGroup struct {
+	Name string
+	Mask uint64
+}
+
+// GroupWiringError reports a dep group that is only partly wired. It unwraps to
+// ErrWiringIncomplete.
+type GroupWiringError struct {
+	FacadeName string
+	Ctx        string
+	Group      string
+	Wired      []string
+	Missing    []string
+	SpecHash   string
+}
+
+func (e *GroupWiringError) Error() string {
+	return fmt.Sprintf("%s: %v: group %s is partially wired (ctx=%s, wired=%v, missing=%v, spec=%s)",
+		e.FacadeName, ErrWiringIncomplete, e.Group, e.Ctx, e.Wired, e.Missing, e.SpecHash)
+}
+
+// Unwrap returns ErrWiringIncomplete.
+func (e *GroupWiringError) Unwrap() error { return ErrWiringIncomplete }
+
+// CheckGroups returns a *GroupWiringError for the first group with some, but
+// not all, of its deps in missing (a mask over names).
+func CheckGroups(facade, ctx, specHash string, names []string, missing uint64, groups []DepGroup) error {
+	for _, g := range groups {
+		m := missing & g.Mask
+		if m == 0 || m == g.Mask {
+			continue
+		}
+		return &GroupWiringError{
+			FacadeName: facade,
+			Ctx:        ctx,
+			Group:      g.Name,
+			Wired:      MissingNames(names, g.Mask&^m),
+			Missing:    MissingNames(names, m),
+			SpecHash:   specHash,
+		}
+	}
+	return nil
+}
+
+// OneOfWiringError reports an exclusive dep set with no dep, or more than one,
+// wired. With none wired it unwraps to ErrWiringIncomplete.
+type OneOfWiringError struct {
+	FacadeName string
+	Ctx        string
+	Set        string
+	Options    []string
+	Wired      []string
+	SpecHash   string
+}
+
+func (e *OneOfWiringError) Error() string {
+	return fmt.Sprintf("%s: exactly one of %s %v must be wired, got %v (ctx=%s, spec=%s)",
+		e.FacadeName, e.Set, e.Options, e.Wired, e.Ctx, e.SpecHash)
+}
+
+// Unwrap returns ErrWiringIncomplete when no dep of the set is wired.
+func (e *OneOfWiringError) Unwrap() error {
+	if len(e.Wired) == 0 {
+		return ErrWiringIncomplete
+	}
+	return nil
+}
+
+// CheckOneOf returns a *OneOfWiringError for the first set (in sets) that does
+// not have exactly one dep wired; missing is a mask over names.
+func CheckOneOf(facade, ctx, specHash string, names []string, missing uint64, sets []DepGroup) error {
+	for _, set := range sets {
+		wired := set.Mask &^ missing
+		if bits.OnesCount64(wired) == 1 {
+			continue
+		}
+		return &OneOfWiringError{
+			FacadeName: facade,
+			Ctx:        ctx,
+			Set:        set.Name,
+			Options:    MissingNames(names, set.Mask),
+			Wired:      MissingNames(names, wired),
+			SpecHash:   specHash,
+		}
+	}
+	return nil
+}
+
+// StaleImplError is the error a facade reports after Reset recreated an
+// implementation that UnsafeImpl had handed out: its consumers still hold the
+// old pointer, so building would split the wiring. ResetUnsafe clears it.
+type StaleImplError struct {
+	FacadeName string
+	Ctx        string
+	// Consumers are the holders of the old pointer named with UnsafeImplFor;
+	// UnsafeImpl callers are listed as UnnamedConsumer.
+	Consumers []string
+}
+
+func (e *StaleImplError) Error() string {
+	return fmt.Sprintf("%s: %v (ctx=%s, consumers=%v); use ResetUnsafe and re-wire them",
+		e.FacadeName, ErrStaleImpl, e.Ctx, e.Consumers)
+}
+
+// Unwrap returns ErrStaleImpl.
+func (e *StaleImplError) Unwrap() error { return ErrStaleImpl }
+
+// UnnamedConsumer stands for an UnsafeImpl caller in StaleImplError.Consumers.
+const UnnamedConsumer = "(unnamed)"
+
+// StaleImpl returns the *StaleImplError a facade reports for ctx while
+// consumers hold an implementation Reset replaced.
+func StaleImpl(facade, ctx string, consumers []string) error {
+	return &StaleImplError{FacadeName: facade, Ctx: ctx, Consumers: append([]string(nil), consumers...)}
+}
+
+// AddConsumer appends consumer (UnnamedConsumer when empty) to consumers
+// unless it is already listed.
+func AddConsumer(consumers []string, consumer string) []string {
+	if consumer == "" {
+		consumer = UnnamedConsumer
+	}
+	for _, c := range consumers {
+		if c == consumer {
+			return consumers
+		}
+	}
+	return append(consumers, consumer)
+}
+
+// UnsafeEscape records an UnsafeImpl call of a facade generated with
+// unsafeImpl.track: who received the pointer, from where and when.
+type UnsafeEscape struct {
+	Consumer string
+	Caller   string // file:line of the call, "unknown" if unavailable
+	At       time.Time
+}
+
+// NewUnsafeEscape records consumer (UnnamedConsumer when empty) with the call
+// site skip frames above NewUnsafeEscape's caller (0 is that caller itself).
+func NewUnsafeEscape(consumer string, skip int) UnsafeEscape {
+	if consumer == "" {
+		consumer = UnnamedConsumer
+	}
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	return UnsafeEscape{Consumer: consumer, Caller: caller, At: time.Now()}
+}
+
+// ExplainUnsafe renders the UnsafeImpl part of a facade's Explain: the consumers
+// of the implementation, those still holding one Reset replaced, and the tracked
+// escapes in call order. It is empty when the pointer never escaped.
+func ExplainUnsafe(consumers, stale []string, escapes []UnsafeEscape) string {
+	var sb strings.Builder
+	if len(consumers) > 0 {
+		fmt.Fprintf(&sb, "unsafe: escaped to %v\n", consumers)
+	}
+	if len(stale) > 0 {
+		fmt.Fprintf(&sb, "unsafe: stale (reset) for %v\n", stale)
+	}
+	if len(escapes) > 0 {
+		sb.WriteString("unsafe: escapes\n")
+		for _, e := range escapes {
+			fmt.Fprintf(&sb, "  - %s at %s (%s)\n", e.Consumer, e.Caller, e.At.UTC().Format(time.RFC3339))
+		}
+	}
+	return sb.String()
+}
+
+// Escapes is the UnsafeImpl bookkeeping of a di2-generated facade: the consumers
+// that received the implementation pointer, those still holding one Reset
+// replaced, and (for unsafeImpl.track) where each escape happened. The zero
+// value is ready to use.
+type Escapes struct {
+	to, stale []string
+	calls     []UnsafeEscape
+}
+
+// Add records consumer as a holder of the pointer. Facades call it directly from
+// UnsafeImpl/UnsafeImplFor.
+func (e *Escapes) Add(consumer string) {
+	e.to = AddConsumer(e.to, consumer)
+}
+
+// AddTracked is Add that also records the call site of the UnsafeImpl call.
+func (e *Escapes) AddTracked(consumer string) {
+	e.to = AddConsumer(e.to, consumer)
+	e.calls = append(e.calls, NewUnsafeEscape(consumer, 2))
+}
+
+// Reset marks every consumer as holding a replaced implementation.
+func (e *Escapes) Reset() {
+	for _, c := range e.to {
+		e.stale = AddConsumer(e.stale, c)
+	}
+	e.to = nil
+}
+
+// Orphans returns the consumers to re-wire after ResetUnsafe (stale holders and
+// current ones) and clears both lists.
+func (e *Escapes) Orphans() []string {
+	orphaned := e.stale
+	for _, c := range e.to {
+		orphaned = AddConsumer(orphaned, c)
+	}
+	e.stale, e.to = nil, nil
+	return orphaned
+}
+
+// CheckStale returns the *StaleImplError facade reports for ctx while some
+// consumer holds a replaced implementation, and nil otherwise.
+func (e *Escapes) CheckStale(facade, ctx string) error {
+	if e.stale == nil {
+		return nil
+	}
+	return StaleImpl(facade, ctx, e.stale)
+}
+
+// Consumers returns the consumers of the current implementation.
+func (e *Escapes) Consumers() []string {
+	return append([]string(nil), e.to...)
+}
+
+// Explain renders the UnsafeImpl part of the facade's Explain (see ExplainUnsafe).
+func (e *Escapes) Explain() string {
+	return ExplainUnsafe(e.to, e.stale, e.calls)
+}
+
+// Clone returns a copy that does not share its lists with e.
+func (e *Escapes) Clone() Escapes {
+	return Escapes{
+		to:    append([]string(nil), e.to...),
+		stale: append([]string(nil), e.stale...),
+		calls: append([]UnsafeEscape(nil), e.calls...),
+	}
+}
+
+// ImplPtr is the identity of the instance v points to, as the ImplPtr method of
+// generated facades reports it: tests compare it with the deps a consumer
+// received to check that wiring shared one instance. v may be a pointer or an
+// interface holding one; anything else (including nil) is 0. The result only
+// identifies the instance and cannot be turned back into a pointer.
+func ImplPtr(v any) uintptr {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return 0
+	}
+	return rv.Pointer()
+}
+
+// ResolveOptional resolves an optional dep from reg and asserts it to T.
+//
+// ok is false when the key is absent. Registry errors and values of the wrong
+// type are returned as errors naming the facade, dep and key.
+func ResolveOptional[T any](reg Registry, cfg any, facade, dep, key string) (val T, ok bool, err error) {
+	return ResolveOptionalCtx[T](context.Background(), reg, cfg, facade, dep, key)
+}
+
+// ResolveOptionalCtx is ResolveOptional under ctx (see ResolveCtx); a done ctx
+// is reported as a resolve failure.
+func ResolveOptionalCtx[T any](ctx context.Context, reg Registry, cfg any, facade, dep, key string) (val T, ok bool, err error) {
+	v, ok, err := ResolveCtx(ctx, reg, cfg, key)
+	if err != nil {
+		return val, false, fmt.Errorf("%s: optional dep %s resolve failed: %w", facade, dep, err)
+	}
+	if !ok {
+		return val, false, nil
+	}
+	val, ok = v.(T)
+	if !ok {
+		return val, false, fmt.Errorf("%s: optional dep %s key=%s: want %s, got %T",
+			facade, dep, key, reflect.TypeFor[T](), v)
+	}
+	return val, true, nil
+}
+
+// BuildCtx builds a facade with reg under ctx, as generated graph roots do: through
+// BuildWithCtx when the facade has one (its spec declares optional deps) and
+// through BuildWith otherwise.
+func BuildCtx[T any](ctx context.Context, b interface{ BuildWith(Registry) (T, error) }, reg Registry) (T, error) {
+	if bc, ok := b.(interface {
+		BuildWithCtx(context.Context, Registry) (T, error)
+	}); ok {
+		return bc.BuildWithCtx(ctx, reg)
+	}
+	return b.BuildWith(reg)
+}
+
+// SlimExplain is what Explain of a generated facade returns in odi_slim builds,
+// where its diagnostics are compiled out (see Slim).
+const SlimExplain = "explain: compiled out (odi_slim)\n"
+
+// ExplainWiring renders a facade's wiring state: missing required deps, then the
+// resolved and missing optional deps (sorted by registry key).
+func ExplainWiring(missing []string, resolved, optionalMissing map[string]string) string {
+	var sb strings.Builder
+	if len(missing) == 0 {
+		sb.WriteString("required: complete\n")
+	} else {
+		fmt.Fprintf(&sb, "required: missing=%v\n", missing)
+	}
+	writeSorted := func(title string, m map[string]string) {
+		if len(m) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString(title)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "  - %s => %s\n", k, m[k])
+		}
+	}
+	writeSorted("optional: resolved\n", resolved)
+	writeSorted("optional: missing\n", optionalMissing)
+	return sb.String()
+}
@@ -0,0 +1,392 @@
+package di_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+type facadeTracer interface{ Name() string }
+
+type facadeTracerImpl struct{}
+
+func (facadeTracerImpl) Name() string { return "impl" }
+
+type failingRegistry struct{ err error }
+
+func (r failingRegistry) Resolve(any, string) (any, bool, error) { return nil, false, r.err }
+
+// TestCheckInject verifies every inject policy with and without a previous injection.
+func TestCheckInject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   string
+		injected bool
+		proceed  bool
+		wantErr  string
+	}{
+		{name: "error_first", policy: di.InjectPolicyError, proceed: true},
+		{name: "error_duplicate", policy: di.InjectPolicyError, injected: true, wantErr: "CoreV4: duplicate inject Alpha"},
+		{name: "ignore_first", policy: di.InjectPolicyIgnore, proceed: true},
+		{name: "ignore_duplicate", policy: di.InjectPolicyIgnore, injected: true, proceed: false},
+		{name: "overwrite_duplicate", policy: di.InjectPolicyOverwrite, injected: true, proceed: true},
+		{name: "invalid", policy: "nope", wantErr: "CoreV4: invalid injectPolicy.onOverwrite=nope"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			proceed, err := di.CheckInject("CoreV4", tc.policy, "Alpha", tc.injected)
+			if tc.wantErr != "" {
+				require.EqualError(t, err, tc.wantErr)
+				assert.Equal(t, tc.injected, errors.Is(err, di.ErrDuplicateInject))
+				assert.False(t, proceed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.proceed, proceed)
+		})
+	}
+}
+
+// TestMissingNames_AndWiringIncomplete verifies mask decoding and the wiring error text.
+func TestMissingNames_AndWiringIncomplete(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"Alpha", "Beta", "Gamma"}
+	assert.Equal(t, []string{"Alpha", "Gamma"}, di.MissingNames(names, 0b101))
+	assert.Equal(t, []string{}, di.MissingNames(names, 0))
+
+	err := di.WiringIncomplete("CoreV4", "Build", "abc", names, 0b010)
+	require.EqualError(t, err, "CoreV4: wiring incomplete (ctx=Build, missing=[Beta], spec=abc)")
+
+	var we *di.WiringError
+	require.ErrorIs(t, err, di.ErrWiringIncomplete)
+	require.ErrorAs(t, fmt.Errorf("app: %w", err), &we)
+	assert.Equal(t, &di.WiringError{FacadeName: "CoreV4", Ctx: "Build", Missing: []string{"Beta"}, SpecHash: "abc"}, we)
+}
+
+// TestMissingNamesWide verifies masks spanning several words.
+func TestMissingNamesWide(t *testing.T) {
+	t.Parallel()
+
+	names := make([]string, 70)
+	for i := range names {
+		names[i] = fmt.Sprint("D", i)
+	}
+	assert.Equal(t, []string{"D0", "D63", "D64", "D69"}, di.MissingNamesWide(names, []uint64{1 | 1<<63, 1 | 1<<5}))
+	assert.Equal(t, []string{}, di.MissingNamesWide(names, []uint64{0, 0}))
+
+	err := di.WiringIncompleteWide("BigV4", "Build", "abc", names, []uint64{0, 1 << 1})
+	require.EqualError(t, err, "BigV4: wiring incomplete (ctx=Build, missing=[D65], spec=abc)")
+	require.ErrorIs(t, err, di.ErrWiringIncomplete)
+}
+
+// TestCheckGroups verifies all-or-nothing groups and the partial group error.
+func TestCheckGroups(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"DB", "Producer", "Consumer", "Topics", "Cache", "CacheTTL"}
+	groups := []di.DepGroup{
+		{Name: "cache", Mask: 0b110000},
+		{Name: "kafka", Mask: 0b001110},
+	}
+
+	require.NoError(t, di.CheckGroups("CoreV4", "Build", "abc", names, 0b111110, groups), "no group wired")
+	require.NoError(t, di.CheckGroups("CoreV4", "Build", "abc", names, 0, groups), "all wired")
+	require.NoError(t, di.CheckGroups("CoreV4", "Build", "abc", names, 0b110000, groups), "kafka wired, cache absent")
+
+	err := di.CheckGroups("CoreV4", "Build", "abc", names, 0b111010, groups)
+	require.ErrorIs(t, err, di.ErrWiringIncomplete)
+	assert.EqualError(t, err, "CoreV4: wiring incomplete: group kafka is partially wired (ctx=Build, wired=[Consumer], missing=[Producer Topics], spec=abc)")
+
+	var ge *di.GroupWiringError
+	require.ErrorAs(t, err, &ge)
+	assert.Equal(t, "kafka", ge.Group)
+}
+
+// TestCheckOneOf verifies exclusive sets accept exactly one wired dep.
+func TestCheckOneOf(t *testing.T) {
+	t.Parallel()
+
+	names := []string{"DB", "SQLStore", "MemoryStore"}
+	sets := []di.DepGroup{{Name: "store", Mask: 0b110}}
+
+	require.NoError(t, di.CheckOneOf("CoreV4", "Build", "abc", names, 0b100, sets))
+	require.NoError(t, di.CheckOneOf("CoreV4", "Build", "abc", names, 0b010, sets))
+
+	err := di.CheckOneOf("CoreV4", "Build", "abc", names, 0b110, sets)
+	require.ErrorIs(t, err, di.ErrWiringIncomplete)
+	assert.EqualError(t, err, "CoreV4: exactly one of store [SQLStore MemoryStore] must be wired, got [] (ctx=Build, spec=abc)")
+
+	err = di.CheckOneOf("CoreV4", "Build", "abc", names, 0, sets)
+	assert.NotErrorIs(t, err, di.ErrWiringIncomplete)
+	assert.EqualError(t, err, "CoreV4: exactly one of store [SQLStore MemoryStore] must be wired, got [SQLStore MemoryStore] (ctx=Build, spec=abc)")
+
+	var oe *di.OneOfWiringError
+	require.ErrorAs(t, err, &oe)
+	assert.Equal(t, []string{"SQLStore", "MemoryStore"}, oe.Wired)
+}
+
+// TestStaleImpl verifies consumer tracking and the stale implementation error.
+func TestStaleImpl(t *testing.T) {
+	t.Parallel()
+
+	var consumers []string
+	for _, c := range []string{"core", "", "core", "beta", ""} {
+		consumers = di.AddConsumer(consumers, c)
+	}
+	assert.Equal(t, []string{"core", di.UnnamedConsumer, "beta"}, consumers)
+
+	err := di.StaleImpl("AlphaV4", "Build", consumers)
+	require.ErrorIs(t, err, di.ErrStaleImpl)
+	assert.EqualError(t, err, "AlphaV4: reset after UnsafeImpl (ctx=Build, consumers=[core (unnamed) beta]); use ResetUnsafe and re-wire them")
+
+	var se *di.StaleImplError
+	require.ErrorAs(t, fmt.Errorf("app: %w", err), &se)
+	consumers[0] = "changed"
+	assert.Equal(t, "core", se.Consumers[0], "consumers are copied")
+}
+
+// escapeVia stands for a generated UnsafeImpl: the escape is recorded two
+// frames below the call site.
+func escapeVia(consumer string) di.UnsafeEscape {
+	return func() di.UnsafeEscape { return di.NewUnsafeEscape(consumer, 2) }()
+}
+
+// TestExplainUnsafe verifies call site capture and the Explain section.
+func TestExplainUnsafe(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, di.ExplainUnsafe(nil, nil, nil))
+
+	_, file, line, _ := runtime.Caller(0)
+	e := escapeVia("")
+	assert.Equal(t, di.UnnamedConsumer, e.Consumer)
+	assert.Equal(t, fmt.Sprintf("%s:%d", file, line+1), e.Caller)
+	assert.WithinDuration(t, time.Now(), e.At, time.Minute)
+
+	e = di.UnsafeEscape{Consumer: "alpha", Caller: "wire.go:12", At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	got := di.ExplainUnsafe([]string{"alpha"}, []string{"core"}, []di.UnsafeEscape{e})
+	assert.Equal(t, "unsafe: escaped to [alpha]\nunsafe: stale (reset) for [core]\nunsafe: escapes\n  - alpha at wire.go:12 (2026-01-02T03:04:05Z)\n", got)
+}
+
+// escapesFacade stands for a generated facade calling Escapes from UnsafeImpl.
+type escapesFacade struct{ escapes di.Escapes }
+
+func (f *escapesFacade) UnsafeImpl() { f.escapes.AddTracked("") }
+
+// TestEscapes verifies the UnsafeImpl bookkeeping generated facades delegate to.
+func TestEscapes(t *testing.T) {
+	t.Parallel()
+
+	var e di.Escapes
+	require.NoError(t, e.CheckStale("AlphaV4", "Build"))
+	assert.Empty(t, e.Explain())
+
+	e.Add("core")
+	e.Add("")
+	e.Add("core")
+	assert.Equal(t, []string{"core", di.UnnamedConsumer}, e.Consumers())
+	assert.Equal(t, "unsafe: escaped to [core (unnamed)]\n", e.Explain())
+
+	clone := e.Clone()
+	e.Reset()
+	assert.Empty(t, e.Consumers())
+	err := e.CheckStale("AlphaV4", "Build")
+	require.ErrorIs(t, err, di.ErrStaleImpl)
+	assert.EqualError(t, err, "AlphaV4: reset after UnsafeImpl (ctx=Build, consumers=[core (unnamed)]); use ResetUnsafe and re-wire them")
+	require.NoError(t, clone.CheckStale("AlphaV4", "Build"), "clones do not share state")
+
+	e.Add("beta")
+	assert.Equal(t, []string{"core", di.UnnamedConsumer, "beta"}, e.Orphans())
+	require.NoError(t, e.CheckStale("AlphaV4", "Build"))
+	assert.Empty(t, e.Orphans())
+
+	var f escapesFacade
+	_, file, line, _ := runtime.Caller(0)
+	f.UnsafeImpl()
+	assert.Contains(t, f.escapes.Explain(), fmt.Sprintf("  - %s at %s:%d (", di.UnnamedConsumer, file, line+1))
+}
+
+// TestImplPtr verifies instance identity through pointers and interfaces.
+func TestImplPtr(t *testing.T) {
+	t.Parallel()
+
+	type impl struct{ n int }
+	a, b := &impl{}, &impl{}
+	var viaIface any = a
+
+	assert.NotZero(t, di.ImplPtr(a))
+	assert.Equal(t, di.ImplPtr(a), di.ImplPtr(viaIface))
+	assert.NotEqual(t, di.ImplPtr(a), di.ImplPtr(b))
+
+	var nilImpl *impl
+	assert.Zero(t, di.ImplPtr(nilImpl))
+	assert.Zero(t, di.ImplPtr(nil))
+	assert.Zero(t, di.ImplPtr(impl{}))
+}
+
+// TestResolveOptional verifies found, absent, registry error and wrong type outcomes.
+func TestResolveOptional(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	reg := di.NewMapRegistry().
+		Provide("tracer", facadeTracerImpl{}).
+		Provide("wrong", 42)
+
+	v, ok, err := di.ResolveOptional[facadeTracer](reg, nil, "CoreV4", "Tracer", "tracer")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "impl", v.Name())
+
+	v, ok, err = di.ResolveOptional[facadeTracer](reg, nil, "CoreV4", "Tracer", "absent")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, v)
+
+	_, ok, err = di.ResolveOptional[facadeTracer](failingRegistry{err: boom}, nil, "CoreV4", "Tracer", "tracer")
+	require.ErrorIs(t, err, boom)
+	assert.False(t, ok)
+	assert.EqualError(t, err, "CoreV4: optional dep Tracer resolve failed: boom")
+
+	_, ok, err = di.ResolveOptional[facadeTracer](reg, nil, "CoreV4", "Tracer", "wrong")
+	assert.False(t, ok)
+	assert.EqualError(t, err, "CoreV4: optional dep Tracer key=wrong: want di_test.facadeTracer, got int")
+}
+
+// TestResolveOptionalCtx verifies a done ctx is reported as a resolve failure.
+func TestResolveOptionalCtx(t *testing.T) {
+	t.Parallel()
+
+	reg := di.NewMapRegistry().Provide("tracer", facadeTracerImpl{})
+
+	v, ok, err := di.ResolveOptionalCtx[facadeTracer](context.Background(), reg, nil, "CoreV4", "Tracer", "tracer")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "impl", v.Name())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok, err = di.ResolveOptionalCtx[facadeTracer](ctx, reg, nil, "CoreV4", "Tracer", "tracer")
+	assert.False(t, ok)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "CoreV4: optional dep Tracer resolve failed: context canceled")
+}
+
+// TestExplainWiring verifies the summary text with sorted optional keys.
+func TestExplainWiring(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "required: complete\n", di.ExplainWiring(nil, nil, nil))
+
+	got := di.ExplainWiring(
+		[]string{"Beta"},
+		map[string]string{"z.key": "*Z", "a.key": "*A"},
+		map[string]string{"m.key": "not provided"},
+	)
+	assert.Equal(t, "required: missing=[Beta]\n"+
+		"optional: resolved\n"+
+		"  - a.key => *A\n"+
+		"  - z.key => *Z\n"+
+		"optional: missing\n"+
+		"  - m.key => not provided\n", got)
+}
+
+type valueCfg struct{ ok bool }
+
+func (c valueCfg) Validate() error {
+	if !c.ok {
+		return errors.New("timeout must be > 0")
+	}
+	return nil
+}
+
+type ptrCfg struct{ ok bool }
+
+func (c *ptrCfg) Validate() error { return valueCfg(*c).Validate() }
+
+// TestValidateConfig verifies value, pointer and non-validating configs.
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	bad := &ptrCfg{}
+	tests := []struct {
+		name    string
+		cfg     any
+		wantErr bool
+	}{
+		{name: "value_ok", cfg: valueCfg{ok: true}},
+		{name: "value_bad", cfg: valueCfg{}, wantErr: true},
+		{name: "ptr_to_value_method", cfg: &valueCfg{}, wantErr: true},
+		{name: "ptr_method", cfg: bad, wantErr: true},
+		{name: "ptr_to_ptr", cfg: &bad, wantErr: true},
+		{name: "nil_ptr", cfg: (*struct{})(nil)},
+		{name: "no_validate", cfg: &struct{}{}},
+		{name: "nil", cfg: nil},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := di.ValidateConfig("CoreV4", tc.cfg)
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, di.ErrInvalidConfig)
+			assert.Equal(t, "CoreV4: invalid config: timeout must be > 0", err.Error())
+		})
+	}
+}
+
+type plainBuilder struct{ built string }
+
+func (b *plainBuilder) BuildWith(di.Registry) (string, error) {
+	b.built = "BuildWith"
+	return "svc", nil
+}
+
+type ctxBuilder struct {
+	plainBuilder
+	ctx context.Context
+}
+
+func (b *ctxBuilder) BuildWithCtx(ctx context.Context, _ di.Registry) (string, error) {
+	b.built, b.ctx = "BuildWithCtx", ctx
+	return "svc", nil
+}
+
+// TestBuildCtx verifies graph builds use BuildWithCtx only when the facade has it.
+func TestBuildCtx(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+
+	var plain plainBuilder
+	svc, err := di.BuildCtx(ctx, &plain, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", svc)
+	assert.Equal(t, "BuildWith", plain.built)
+
+	var withCtx ctxBuilder
+	_, err = di.BuildCtx(ctx, &withCtx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "BuildWithCtx", withCtx.built)
+	assert.Equal(t, "v", withCtx.ctx.Value(key{}))
+}
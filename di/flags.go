@@ -0,0 +1,58 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// FlagsKey is the well-known registry key for a FlagChecker.
+//
+// Optional deps declaring "enabledWhen": {"flagKey": "..."} in their spec are
+// only resolved when the checker provided under this key reports the flag on.
+const FlagsKey = "odi.flags"
+
+// FlagChecker reports whether a feature flag is on. Implementations backed by a
+// remote flag service should honor ctx.
+type FlagChecker interface {
+	Enabled(ctx context.Context, flag string) (bool, error)
+}
+
+// StaticFlags is a FlagChecker over a fixed set of flags; unknown flags are off.
+type StaticFlags map[string]bool
+
+// Enabled implements FlagChecker.
+func (f StaticFlags) Enabled(_ context.Context, flag string) (bool, error) {
+	return f[flag], nil
+}
+
+// ProvideFlags stores f under FlagsKey and returns the registry for chaining.
+func ProvideFlags(reg *MapRegistry, f FlagChecker) *MapRegistry {
+	return reg.Provide(FlagsKey, f)
+}
+
+// FlagEnabled reports whether flag gates in the optional dep of facade.
+//
+// Without a FlagChecker under FlagsKey every gated dep is off, so expensive
+// integrations stay disabled unless an environment opts in. Registry and
+// checker errors, and a FlagsKey value that is not a FlagChecker, are returned
+// as errors naming the facade, dep and flag.
+func FlagEnabled(ctx context.Context, reg Registry, cfg any, facade, dep, flag string) (bool, error) {
+	v, ok, err := ResolveCtx(ctx, reg, cfg, FlagsKey)
+	if err != nil {
+		return false, fmt.Errorf("%s: optional dep %s flag %s: %w", facade, dep, flag, err)
+	}
+	if !ok {
+		return false, nil
+	}
+	fc, ok := v.(FlagChecker)
+	if !ok {
+		return false, fmt.Errorf("%s: optional dep %s flag %s: key=%s: want %s, got %T",
+			facade, dep, flag, FlagsKey, reflect.TypeFor[FlagChecker](), v)
+	}
+	on, err := fc.Enabled(ctx, flag)
+	if err != nil {
+		return false, fmt.Errorf("%s: optional dep %s flag %s: %w", facade, dep, flag, err)
+	}
+	return on, nil
+}
@@ -0,0 +1,47 @@
+package di_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+type failingFlags struct{ err error }
+
+func (f failingFlags) Enabled(context.Context, string) (bool, error) { return false, f.err }
+
+// TestFlagEnabled verifies on/off flags, the missing checker default and error outcomes.
+func TestFlagEnabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	reg := di.ProvideFlags(di.NewMapRegistry(), di.StaticFlags{"features.tracing": true})
+
+	on, err := di.FlagEnabled(ctx, reg, nil, "CoreV4", "Tracer", "features.tracing")
+	require.NoError(t, err)
+	assert.True(t, on)
+
+	on, err = di.FlagEnabled(ctx, reg, nil, "CoreV4", "Tracer", "features.unknown")
+	require.NoError(t, err)
+	assert.False(t, on)
+
+	on, err = di.FlagEnabled(ctx, di.NewMapRegistry(), nil, "CoreV4", "Tracer", "features.tracing")
+	require.NoError(t, err)
+	assert.False(t, on, "no checker means gated deps are off")
+
+	boom := errors.New("flag service down")
+	_, err = di.FlagEnabled(ctx, di.ProvideFlags(di.NewMapRegistry(), failingFlags{err: boom}), nil, "CoreV4", "Tracer", "f")
+	require.ErrorIs(t, err, boom)
+	assert.EqualError(t, err, "CoreV4: optional dep Tracer flag f: flag service down")
+
+	_, err = di.FlagEnabled(ctx, failingRegistry{err: boom}, nil, "CoreV4", "Tracer", "f")
+	require.ErrorIs(t, err, boom)
+
+	_, err = di.FlagEnabled(ctx, di.NewMapRegistry().Provide(di.FlagsKey, 42), nil, "CoreV4", "Tracer", "f")
+	assert.EqualError(t, err, "CoreV4: optional dep Tracer flag f: key=odi.flags: want di.FlagChecker, got int")
+}
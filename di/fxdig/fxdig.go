@@ -0,0 +1,223 @@
+// Package fxdig adapts an existing go.uber.org/fx or go.uber.org/dig container
+// into a di.Registry.
+//
+// It exists for migrations: services generated by odi can source their
+// optional dependencies from a legacy container while the rest of the
+// application is still wired by fx/dig.
+//
+// Only applications still wired by fx/dig need it; once the migration is done,
+// dropping the module drops go.uber.org/fx and go.uber.org/dig with it.
+//
+// Registry keys are mapped to container types explicitly:
+//
+//	b := fxdig.NewBindings()
+//	fxdig.Bind[Tracer](b, "v4.tracer")
+//	fxdig.BindNamed[Metrics](b, "v4.metrics", "prom")
+//
+//	reg := fxdig.FromDig(container, b)
+//	// or, for fx applications:
+//	reg, opt := fxdig.FromFx(b)
+//	app := fx.New(legacyModule, opt)
+//
+// Container entries are requested as optional dependencies: a bound type the
+// container cannot provide resolves as "not found" (ok=false) rather than as an
+// error, matching how generated builders treat missing registry keys.
+package fxdig
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go.uber.org/dig"
+	"go.uber.org/fx"
+
+	"github.com/sghaida/odi/di"
+)
+
+// ErrNotPopulated is returned by an fx-backed registry that is resolved before
+// fx.New has run the populate option returned by FromFx.
+var ErrNotPopulated = errors.New("fxdig: registry not populated (pass the option returned by FromFx to fx.New)")
+
+// binding describes how one registry key maps to a container entry.
+type binding struct {
+	typ  reflect.Type
+	name string
+}
+
+// Bindings maps registry keys to container types (and optional dig names).
+//
+// Bindings is not safe for concurrent mutation; build it once before creating
+// a registry.
+type Bindings struct {
+	items map[string]binding
+}
+
+// NewBindings returns an empty binding set.
+func NewBindings() *Bindings {
+	return &Bindings{items: map[string]binding{}}
+}
+
+// Bind maps key to the container type T and returns b for chaining.
+//
+// T is usually an interface (e.g. Tracer) or a pointer type (e.g. *sql.DB),
+// exactly as it was registered with fx.Provide / dig.Provide.
+func Bind[T any](b *Bindings, key string) *Bindings {
+	return BindNamed[T](b, key, "")
+}
+
+// BindNamed maps key to the named container value of type T
+// (dig `name:"..."` / fx.ResultTags(`name:"..."`)) and returns b for chaining.
+func BindNamed[T any](b *Bindings, key, name string) *Bindings {
+	b.items[key] = binding{typ: reflect.TypeOf((*T)(nil)).Elem(), name: name}
+	return b
+}
+
+// Keys returns the bound registry keys in sorted order.
+func (b *Bindings) Keys() []string {
+	keys := make([]string, 0, len(b.items))
+	for k := range b.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Registry implements di.Registry on top of an fx/dig container.
+type Registry struct {
+	bindings *Bindings
+
+	// container is set for dig-backed registries and resolved lazily.
+	container *dig.Container
+
+	// values is filled by the fx populate option for fx-backed registries.
+	mu        sync.RWMutex
+	populated bool
+	values    map[string]any
+}
+
+var _ di.Registry = (*Registry)(nil)
+
+// FromDig returns a registry that resolves bound keys from c on demand.
+//
+// Each Resolve performs a dig Invoke; dig caches constructed values, so
+// repeated resolutions return the same instance.
+func FromDig(c *dig.Container, b *Bindings) *Registry {
+	return &Registry{bindings: b, container: c}
+}
+
+// FromFx returns a registry together with the fx.Option that populates it.
+//
+// fx.App does not expose its underlying container, so the registry cannot be
+// derived from an *fx.App after the fact. Instead, pass the returned option to
+// fx.New alongside the legacy modules; once fx.New returns, the registry serves
+// every bound key from the values fx resolved.
+func FromFx(b *Bindings) (*Registry, fx.Option) {
+	r := &Registry{bindings: b}
+	keys := b.Keys()
+	in := paramStruct(b, keys)
+
+	fn := reflect.MakeFunc(
+		reflect.FuncOf([]reflect.Type{in}, nil, false),
+		func(args []reflect.Value) []reflect.Value {
+			r.store(keys, args[0])
+			return nil
+		},
+	)
+	return r, fx.Invoke(fn.Interface())
+}
+
+// Resolve implements di.Registry.
+//
+// Keys without a binding, and bound types the container cannot provide, return
+// ok=false with a nil error.
+func (r *Registry) Resolve(_ any, key string) (val any, ok bool, err error) {
+	bnd, bound := r.bindings.items[key]
+	if !bound {
+		return nil, false, nil
+	}
+
+	if r.container != nil {
+		return r.resolveDig(key, bnd)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.populated {
+		return nil, false, ErrNotPopulated
+	}
+	v, ok := r.values[key]
+	return v, ok, nil
+}
+
+func (r *Registry) resolveDig(key string, bnd binding) (any, bool, error) {
+	single := &Bindings{items: map[string]binding{key: bnd}}
+	keys := []string{key}
+	in := paramStruct(single, keys)
+
+	var (
+		out   any
+		found bool
+	)
+	fn := reflect.MakeFunc(
+		reflect.FuncOf([]reflect.Type{in}, nil, false),
+		func(args []reflect.Value) []reflect.Value {
+			out, found = fieldValue(args[0], 0)
+			return nil
+		},
+	)
+	if err := r.container.Invoke(fn.Interface()); err != nil {
+		return nil, false, fmt.Errorf("fxdig: resolve %q: %w", key, err)
+	}
+	return out, found, nil
+}
+
+func (r *Registry) store(keys []string, in reflect.Value) {
+	values := make(map[string]any, len(keys))
+	for i, k := range keys {
+		if v, ok := fieldValue(in, i); ok {
+			values[k] = v
+		}
+	}
+
+	r.mu.Lock()
+	r.values = values
+	r.populated = true
+	r.mu.Unlock()
+}
+
+// paramStruct builds a dig.In parameter struct with one optional field per key
+// (in keys order).
+func paramStruct(b *Bindings, keys []string) reflect.Type {
+	fields := make([]reflect.StructField, 0, len(keys)+1)
+	fields = append(fields, reflect.StructField{
+		Name:      "In",
+		Type:      reflect.TypeOf(dig.In{}),
+		Anonymous: true,
+	})
+	for i, k := range keys {
+		bnd := b.items[k]
+		tag := `optional:"true"`
+		if bnd.name != "" {
+			tag += fmt.Sprintf(" name:%q", bnd.name)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("V%d", i),
+			Type: bnd.typ,
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	return reflect.StructOf(fields)
+}
+
+// fieldValue returns the i-th bound value of a paramStruct instance.
+// Zero values are reported as absent (dig leaves optional fields zeroed).
+func fieldValue(in reflect.Value, i int) (any, bool) {
+	f := in.Field(i + 1) // field 0 is the embedded dig.In
+	if f.IsZero() {
+		return nil, false
+	}
+	return f.Interface(), true
+}
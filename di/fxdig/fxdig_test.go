@@ -0,0 +1,138 @@
+package fxdig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/dig"
+	"go.uber.org/fx"
+)
+
+type tracer interface{ Name() string }
+
+type namedTracer struct{ name string }
+
+func (t namedTracer) Name() string { return t.name }
+
+type metrics struct{ prefix string }
+
+//
+// -----------------------------------------------------------------------------
+// Bindings
+// -----------------------------------------------------------------------------
+
+// TestBindings_KeysSorted verifies Bind/BindNamed chain and Keys is deterministic.
+func TestBindings_KeysSorted(t *testing.T) {
+	t.Parallel()
+
+	b := NewBindings()
+	ret := BindNamed[*metrics](Bind[tracer](b, "z.tracer"), "a.metrics", "prom")
+	require.Same(t, b, ret)
+	assert.Equal(t, []string{"a.metrics", "z.tracer"}, b.Keys())
+}
+
+//
+// -----------------------------------------------------------------------------
+// FromDig
+// -----------------------------------------------------------------------------
+
+// TestFromDig_ResolvesBoundTypes verifies bound interface, pointer and named values resolve from dig.
+func TestFromDig_ResolvesBoundTypes(t *testing.T) {
+	t.Parallel()
+
+	c := dig.New()
+	require.NoError(t, c.Provide(func() tracer { return namedTracer{name: "legacy"} }))
+	require.NoError(t, c.Provide(func() *metrics { return &metrics{prefix: "p"} }, dig.Name("prom")))
+
+	b := NewBindings()
+	Bind[tracer](b, "v4.tracer")
+	BindNamed[*metrics](b, "v4.metrics", "prom")
+	reg := FromDig(c, b)
+
+	v, ok, err := reg.Resolve(nil, "v4.tracer")
+	require.NoError(t, err)
+	require.True(t, ok)
+	tr, isTracer := v.(tracer)
+	require.True(t, isTracer)
+	assert.Equal(t, "legacy", tr.Name())
+
+	v, ok, err = reg.Resolve(nil, "v4.metrics")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "p", v.(*metrics).prefix)
+}
+
+// TestFromDig_MissingIsNotFound verifies unbound keys and types absent from the container resolve as ok=false.
+func TestFromDig_MissingIsNotFound(t *testing.T) {
+	t.Parallel()
+
+	b := NewBindings()
+	Bind[tracer](b, "v4.tracer")
+	reg := FromDig(dig.New(), b)
+
+	for _, key := range []string{"v4.tracer", "unbound"} {
+		v, ok, err := reg.Resolve(nil, key)
+		require.NoError(t, err, key)
+		assert.False(t, ok, key)
+		assert.Nil(t, v, key)
+	}
+}
+
+// TestFromDig_ConstructorErrorPropagates verifies container construction failures surface as errors.
+func TestFromDig_ConstructorErrorPropagates(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	c := dig.New()
+	require.NoError(t, c.Provide(func() (tracer, error) { return nil, boom }))
+
+	b := NewBindings()
+	Bind[tracer](b, "v4.tracer")
+
+	_, ok, err := FromDig(c, b).Resolve(nil, "v4.tracer")
+	require.Error(t, err)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, boom)
+	assert.Contains(t, err.Error(), `fxdig: resolve "v4.tracer"`)
+}
+
+//
+// -----------------------------------------------------------------------------
+// FromFx
+// -----------------------------------------------------------------------------
+
+// TestFromFx_PopulatedByApp verifies the fx option fills the registry during fx.New.
+func TestFromFx_PopulatedByApp(t *testing.T) {
+	t.Parallel()
+
+	b := NewBindings()
+	Bind[tracer](b, "v4.tracer")
+	Bind[*metrics](b, "v4.metrics")
+	reg, opt := FromFx(b)
+
+	_, _, err := reg.Resolve(nil, "v4.tracer")
+	require.ErrorIs(t, err, ErrNotPopulated)
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(func() tracer { return namedTracer{name: "fx"} }),
+		opt,
+	)
+	require.NoError(t, app.Err())
+
+	v, ok, err := reg.Resolve(nil, "v4.tracer")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "fx", v.(tracer).Name())
+
+	v, ok, err = reg.Resolve(nil, "v4.metrics")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, v)
+
+	_, ok, err = reg.Resolve(nil, "unbound")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
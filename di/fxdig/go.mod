@@ -0,0 +1,17 @@
+module github.com/sghaida/odi/di/fxdig
+
+go 1.25.3
+
+require (
+	github.com/sghaida/odi v0.0.0-20261015040519-0d9cfca13c78
+	github.com/stretchr/testify v1.12.1
+	go.uber.org/dig v1.19.0
+	go.uber.org/fx v1.24.0
+)
+
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.26.0 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/sys v0.0.0-20220412211240-33da011f77ad // indirect
+)
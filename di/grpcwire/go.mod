@@ -0,0 +1,18 @@
+module github.com/sghaida/odi/di/grpcwire
+
+go 1.25.3
+
+require (
+	github.com/sghaida/odi v0.0.0-20261015040519-0d9cfca13c78
+	github.com/stretchr/testify v1.12.1
+	google.golang.org/grpc v1.84.0
+)
+
+require (
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
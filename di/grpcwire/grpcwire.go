@@ -0,0 +1,200 @@
+// Package grpcwire provides gRPC glue for odi-wired services.
+//
+// It contains:
+//   - Service / RegisterAll / NewServer: register built services implementing
+//     generated gRPC server interfaces on a grpc.Server, with nil and duplicate
+//     registrations reported as errors instead of panics
+//   - ClientConfig / Dial: a client connection from config-shaped settings
+//     (target, TLS or plaintext, authority, message sizes, keepalive)
+//   - NewClient / ClientInjector: typed clients from a connection, as v1
+//     services or injectors binding them into a target service
+//
+// It needs nothing from protoc output beyond the Register<Name>Server and
+// New<Name>Client funcs, which are passed in as values.
+package grpcwire
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/sghaida/odi/di"
+)
+
+// ErrDuplicateService is returned by RegisterAll when two registrations name the
+// same gRPC service.
+var ErrDuplicateService = errors.New("grpcwire: duplicate service")
+
+// Registration is a built service paired with the generated Register<Name>Server
+// function that registers it; see Service.
+type Registration struct {
+	name     string
+	nilImpl  bool
+	register func(grpc.ServiceRegistrar)
+}
+
+// Service pairs impl with its generated registration function:
+//
+//	grpcwire.Service(pb.RegisterGreeterServer, app.Greeter)
+//
+// impl is typically a service built by a facade or graph root.
+func Service[S any](register func(grpc.ServiceRegistrar, S), impl S) Registration {
+	if isNil(impl) {
+		// Generated Register functions panic on nil; report it from RegisterAll.
+		return Registration{name: reflect.TypeFor[S]().String(), nilImpl: true}
+	}
+	var rec recorder
+	register(&rec, impl)
+	return Registration{
+		name:     rec.name,
+		register: func(r grpc.ServiceRegistrar) { register(r, impl) },
+	}
+}
+
+// Name returns the full gRPC service name (e.g. "helloworld.Greeter"), or the
+// server interface type for a nil implementation.
+func (r Registration) Name() string { return r.name }
+
+// RegisterAll registers every registration on s. It fails, before registering
+// anything, if an implementation is nil or two registrations name the same
+// service.
+func RegisterAll(s grpc.ServiceRegistrar, regs ...Registration) error {
+	seen := make(map[string]bool, len(regs))
+	for _, r := range regs {
+		switch {
+		case r.nilImpl:
+			return fmt.Errorf("grpcwire: %s: %w", r.name, di.ErrNilDep)
+		case seen[r.name]:
+			return fmt.Errorf("%w: %s", ErrDuplicateService, r.name)
+		}
+		seen[r.name] = true
+	}
+	for _, r := range regs {
+		r.register(s)
+	}
+	return nil
+}
+
+// NewServer creates a grpc.Server with opts and registers regs on it.
+func NewServer(opts []grpc.ServerOption, regs ...Registration) (*grpc.Server, error) {
+	s := grpc.NewServer(opts...)
+	if err := RegisterAll(s, regs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// recorder captures the service name a registration function registers.
+type recorder struct{ name string }
+
+func (r *recorder) RegisterService(desc *grpc.ServiceDesc, _ any) { r.name = desc.ServiceName }
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// ClientConfig describes a client connection. Its fields are plain values, so it
+// can be embedded in an application config and decoded from JSON or YAML.
+type ClientConfig struct {
+	// Target is the gRPC target, e.g. "dns:///payments:443".
+	Target string `json:"target" yaml:"target"`
+
+	// Insecure uses plaintext instead of TLS with the system roots.
+	Insecure bool `json:"insecure" yaml:"insecure"`
+
+	// Authority overrides the :authority header, which TLS also verifies against.
+	Authority string `json:"authority" yaml:"authority"`
+
+	// UserAgent is prepended to the gRPC user agent.
+	UserAgent string `json:"userAgent" yaml:"userAgent"`
+
+	// MaxRecvMsgBytes and MaxSendMsgBytes override gRPC's message size limits (0: default).
+	MaxRecvMsgBytes int `json:"maxRecvMsgBytes" yaml:"maxRecvMsgBytes"`
+	MaxSendMsgBytes int `json:"maxSendMsgBytes" yaml:"maxSendMsgBytes"`
+
+	// KeepaliveTime pings an idle connection after this long (0: no keepalive).
+	KeepaliveTime time.Duration `json:"keepaliveTime" yaml:"keepaliveTime"`
+}
+
+// DialOptions returns the grpc.DialOptions cfg describes.
+func (cfg ClientConfig) DialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if cfg.Insecure {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})))
+	}
+	if cfg.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(cfg.Authority))
+	}
+	if cfg.UserAgent != "" {
+		opts = append(opts, grpc.WithUserAgent(cfg.UserAgent))
+	}
+	var call []grpc.CallOption
+	if cfg.MaxRecvMsgBytes > 0 {
+		call = append(call, grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgBytes))
+	}
+	if cfg.MaxSendMsgBytes > 0 {
+		call = append(call, grpc.MaxCallSendMsgSize(cfg.MaxSendMsgBytes))
+	}
+	if len(call) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(call...))
+	}
+	if cfg.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: cfg.KeepaliveTime}))
+	}
+	return opts
+}
+
+// Dial creates a client connection for cfg (grpc.NewClient: it connects lazily).
+// extra options are applied after cfg's, so they win.
+func Dial(cfg ClientConfig, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if cfg.Target == "" {
+		return nil, errors.New("grpcwire: client config has no target")
+	}
+	return grpc.NewClient(cfg.Target, append(cfg.DialOptions(), extra...)...)
+}
+
+// NewClient wraps the generated New<Name>Client(conn) result in a v1 service:
+//
+//	greeter := grpcwire.NewClient(conn, pb.NewGreeterClient)
+func NewClient[C any](conn grpc.ClientConnInterface, newClient func(grpc.ClientConnInterface) C) *di.Service[C] {
+	return di.Init(func() *C {
+		c := newClient(conn)
+		return &c
+	})
+}
+
+// ClientInjector builds the client for conn and binds it into a target service
+// under key, recording it like di.Injecting:
+//
+//	svc.With(grpcwire.ClientInjector("payments", conn, pb.NewPaymentsClient, (*Checkout).SetPayments))
+func ClientInjector[T any, C any](
+	key di.DependencyKey,
+	conn grpc.ClientConnInterface,
+	newClient func(grpc.ClientConnInterface) C,
+	bind func(target *T, client C),
+) di.Injector[T] {
+	if conn == nil || newClient == nil {
+		return func(*di.Service[T]) error { return di.NilDependencyServiceError{Key: key} }
+	}
+	if bind == nil {
+		return func(*di.Service[T]) error { return di.NilBindError{Key: key} }
+	}
+	return di.Injecting(key, NewClient(conn, newClient), func(t *T, c *C) { bind(t, *c) })
+}
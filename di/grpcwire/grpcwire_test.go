@@ -0,0 +1,109 @@
+package grpcwire_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sghaida/odi/di"
+	"github.com/sghaida/odi/di/grpcwire"
+)
+
+type checker struct {
+	health healthpb.HealthClient
+}
+
+// TestServerAndClient verifies a registered service is reachable through a
+// client injected from a ClientConfig connection.
+func TestServerAndClient(t *testing.T) {
+	t.Parallel()
+
+	reg := grpcwire.Service(healthpb.RegisterHealthServer, healthpb.HealthServer(health.NewServer()))
+	assert.Equal(t, "grpc.health.v1.Health", reg.Name())
+
+	srv, err := grpcwire.NewServer(nil, reg)
+	require.NoError(t, err)
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpcwire.Dial(
+		grpcwire.ClientConfig{Target: "passthrough:///bufnet", Insecure: true, UserAgent: "odi-test", MaxRecvMsgBytes: 1 << 20},
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	c, err := di.Init(func() *checker { return &checker{} }).
+		With(grpcwire.ClientInjector("health", conn, healthpb.NewHealthClient, func(c *checker, h healthpb.HealthClient) { c.health = h }))
+	require.NoError(t, err)
+	assert.True(t, c.Has("health"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := c.Val.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+// TestRegisterAll_Errors verifies nil and duplicate registrations fail before
+// anything is registered.
+func TestRegisterAll_Errors(t *testing.T) {
+	t.Parallel()
+
+	ok := grpcwire.Service(healthpb.RegisterHealthServer, healthpb.HealthServer(health.NewServer()))
+	var nilImpl *health.Server
+	missing := grpcwire.Service(healthpb.RegisterHealthServer, healthpb.HealthServer(nilImpl))
+
+	s := grpc.NewServer()
+	require.ErrorIs(t, grpcwire.RegisterAll(s, missing), di.ErrNilDep)
+	require.ErrorIs(t, grpcwire.RegisterAll(s, ok, ok), grpcwire.ErrDuplicateService)
+	assert.Empty(t, s.GetServiceInfo())
+
+	_, err := grpcwire.NewServer(nil, ok, ok)
+	require.ErrorIs(t, err, grpcwire.ErrDuplicateService)
+}
+
+// TestClientConfig verifies config validation and the options it produces.
+func TestClientConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := grpcwire.Dial(grpcwire.ClientConfig{})
+	require.Error(t, err)
+
+	assert.Len(t, grpcwire.ClientConfig{}.DialOptions(), 1, "TLS credentials only")
+	full := grpcwire.ClientConfig{
+		Authority: "api.example.com", UserAgent: "ua",
+		MaxRecvMsgBytes: 1, MaxSendMsgBytes: 1, KeepaliveTime: time.Minute,
+	}
+	assert.Len(t, full.DialOptions(), 5)
+
+	conn, err := grpcwire.Dial(grpcwire.ClientConfig{Target: "dns:///example.com:443"})
+	require.NoError(t, err, "NewClient connects lazily")
+	require.NoError(t, conn.Close())
+}
+
+// TestClientInjector_Nil verifies nil inputs surface as injector errors.
+func TestClientInjector_Nil(t *testing.T) {
+	t.Parallel()
+
+	bind := func(c *checker, h healthpb.HealthClient) { c.health = h }
+	_, err := di.Init(func() *checker { return &checker{} }).
+		With(grpcwire.ClientInjector[checker, healthpb.HealthClient]("health", nil, healthpb.NewHealthClient, bind))
+	require.ErrorIs(t, err, di.NilDependencyServiceError{Key: "health"})
+
+	conn, err := grpcwire.Dial(grpcwire.ClientConfig{Target: "passthrough:///x", Insecure: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = di.Init(func() *checker { return &checker{} }).
+		With(grpcwire.ClientInjector[checker]("health", conn, healthpb.NewHealthClient, nil))
+	require.ErrorIs(t, err, di.NilBindError{Key: "health"})
+}
@@ -0,0 +1,91 @@
+package di
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeoutError is returned by a WithTimeout injector whose wrapped injector did
+// not complete within After.
+type TimeoutError struct{ After time.Duration }
+
+// Error implements the error interface.
+func (e TimeoutError) Error() string {
+	// Example: di: injector timed out after 5s
+	return "di: injector timed out after " + e.After.String()
+}
+
+// WithTimeout wraps inj so that it fails with TimeoutError when it does not
+// complete within d (d <= 0 disables the guard).
+//
+// inj runs in its own goroutine. On timeout the goroutine is left running and may
+// still mutate the service when it finishes, so treat a service whose wiring
+// timed out as unusable; the guard exists to fail startup instead of hanging it.
+func WithTimeout[T any](d time.Duration, inj Injector[T]) Injector[T] {
+	if inj == nil || d <= 0 {
+		return inj
+	}
+	return func(s *Service[T]) error {
+		done := make(chan error, 1)
+		go func() { done <- inj(s) }()
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case err := <-done:
+			return err
+		case <-timer.C:
+			return TimeoutError{After: d}
+		}
+	}
+}
+
+// BindPanicError is returned by a Recovering injector whose wrapped injector
+// panicked. Key is the dependency whose bind function panicked, or "" when the
+// panic happened outside the bind of Injecting / InjectingTransient.
+type BindPanicError struct {
+	Key   DependencyKey
+	Value any
+}
+
+// Error implements the error interface.
+func (e BindPanicError) Error() string {
+	// Example: di: bind panicked for key "db": boom
+	return "di: bind panicked for key " + strconv.Quote(string(e.Key)) + ": " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the recovered value if it is an error.
+func (e BindPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Recovering wraps inj so that a panic inside it (typically in a bind function)
+// is returned as BindPanicError instead of crashing the process, like
+// MapRegistry.Resolve does for registries.
+//
+// The dependency recorded for the panicking key is removed from Deps, so the
+// bag does not claim a dependency whose bind never completed. To combine with
+// WithTimeout, wrap the recovering injector: WithTimeout(d, Recovering(inj)).
+func Recovering[T any](inj Injector[T]) Injector[T] {
+	if inj == nil {
+		return nil
+	}
+	return func(s *Service[T]) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				var key DependencyKey
+				if s != nil {
+					key = s.binding
+					s.binding = ""
+					if key != "" {
+						delete(s.Deps, key)
+					}
+				}
+				err = BindPanicError{Key: key, Value: rec}
+			}
+		}()
+		return inj(s)
+	}
+}
@@ -0,0 +1,111 @@
+package di_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sghaida/odi/di"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithTimeout verifies pass-through results, the timeout error and the
+// disabled guard.
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	bind := di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d })
+
+	t.Run("completes", func(t *testing.T) {
+		t.Parallel()
+		svc := di.Init(func() *di.UserService { return &di.UserService{} })
+		_, err := svc.With(di.WithTimeout(time.Second, bind))
+		require.NoError(t, err)
+		assert.Same(t, db.Val, svc.Val.DB)
+	})
+
+	t.Run("error_passes_through", func(t *testing.T) {
+		t.Parallel()
+		boom := errors.New("boom")
+		_, err := di.Init(func() *di.UserService { return &di.UserService{} }).
+			With(di.WithTimeout(time.Second, func(*di.Service[di.UserService]) error { return boom }))
+		assert.ErrorIs(t, err, boom)
+	})
+
+	t.Run("times_out", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		hang := func(*di.Service[di.UserService]) error {
+			<-release
+			return nil
+		}
+
+		_, err := di.Init(func() *di.UserService { return &di.UserService{} }).
+			With(di.WithTimeout(10*time.Millisecond, hang))
+		assert.Equal(t, di.TimeoutError{After: 10 * time.Millisecond}, err)
+		assert.EqualError(t, err, "di: injector timed out after 10ms")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, di.WithTimeout[di.UserService](time.Second, nil))
+		svc := di.Init(func() *di.UserService { return &di.UserService{} })
+		_, err := svc.With(di.WithTimeout(0, bind))
+		require.NoError(t, err)
+	})
+}
+
+// TestRecovering verifies that bind panics become BindPanicError with key context
+// and that the half-wired dependency is dropped from Deps.
+func TestRecovering(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	boom := errors.New("boom")
+	panicking := di.Injecting(di.Key("db"), db, func(*di.UserService, *di.DB) { panic(boom) })
+
+	t.Run("tracked", func(t *testing.T) {
+		t.Parallel()
+		svc := di.Init(func() *di.UserService { return &di.UserService{} })
+		_, err := svc.With(di.Recovering(panicking))
+
+		var bpe di.BindPanicError
+		require.ErrorAs(t, err, &bpe)
+		assert.Equal(t, di.DependencyKey("db"), bpe.Key)
+		assert.ErrorIs(t, err, boom)
+		assert.EqualError(t, err, `di: bind panicked for key "db": boom`)
+		assert.False(t, svc.Has("db"))
+
+		// the key is free again once bind succeeds
+		_, err = svc.With(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }))
+		require.NoError(t, err)
+	})
+
+	t.Run("transient_untracked", func(t *testing.T) {
+		t.Parallel()
+		svc := di.InitUntracked(func() *di.UserService { return &di.UserService{} })
+		inj := di.InjectingTransient(di.Key("req"), func() *di.DB { return &di.DB{} },
+			func(*di.UserService, func() *di.DB) { panic("nope") })
+		err := di.Recovering(inj)(svc)
+		assert.Equal(t, di.BindPanicError{Key: "req", Value: "nope"}, err)
+		assert.NoError(t, errors.Unwrap(err))
+	})
+
+	t.Run("outside_bind", func(t *testing.T) {
+		t.Parallel()
+		err := di.Recovering(func(*di.Service[di.UserService]) error { panic("early") })(nil)
+		assert.Equal(t, di.BindPanicError{Value: "early"}, err)
+	})
+
+	t.Run("passes_through", func(t *testing.T) {
+		t.Parallel()
+		assert.Nil(t, di.Recovering[di.UserService](nil))
+		svc := di.Init(func() *di.UserService { return &di.UserService{} })
+		_, err := svc.With(di.Recovering(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d })))
+		require.NoError(t, err)
+		assert.Same(t, db.Val, svc.Val.DB)
+	})
+}
@@ -0,0 +1,48 @@
+package di
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// HealthCheckFunc runs a set of named health checks.
+// A nil error for a name means that check passed.
+//
+// Generated graph results expose one as <Root>Result.HealthCheck.
+type HealthCheckFunc func(ctx context.Context) map[string]error
+
+// HealthReport is the JSON body written by HealthHandler.
+type HealthReport struct {
+	Status string            `json:"status"` // "ok" | "fail"
+	Checks map[string]string `json:"checks"` // name -> "ok" | error message
+}
+
+// HealthHandler serves check as JSON.
+//
+// It responds 200 with status "ok" when every check passes and 503 with status
+// "fail" otherwise. The request context is passed to check, so server timeouts
+// and client cancellation propagate into the health checks.
+func HealthHandler(check HealthCheckFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := HealthReport{Status: "ok", Checks: map[string]string{}}
+		if check != nil {
+			for name, err := range check(r.Context()) {
+				if err != nil {
+					rep.Status = "fail"
+					rep.Checks[name] = err.Error()
+					continue
+				}
+				rep.Checks[name] = "ok"
+			}
+		}
+
+		code := http.StatusOK
+		if rep.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
@@ -0,0 +1,72 @@
+package di_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestHealthHandler verifies status codes and the JSON report for passing, failing and nil checks.
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		check      di.HealthCheckFunc
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name: "all_ok",
+			check: func(context.Context) map[string]error {
+				return map[string]error{"core": nil, "alpha": nil}
+			},
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+			wantChecks: map[string]string{"core": "ok", "alpha": "ok"},
+		},
+		{
+			name: "one_failing",
+			check: func(context.Context) map[string]error {
+				return map[string]error{"core": errors.New("db down"), "alpha": nil}
+			},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "fail",
+			wantChecks: map[string]string{"core": "db down", "alpha": "ok"},
+		},
+		{
+			name:       "nil_check",
+			check:      nil,
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+			wantChecks: map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			di.HealthHandler(tc.check).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			require.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var rep di.HealthReport
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rep))
+			assert.Equal(t, tc.wantStatus, rep.Status)
+			assert.Equal(t, tc.wantChecks, rep.Checks)
+		})
+	}
+}
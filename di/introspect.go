@@ -0,0 +1,281 @@
+package di
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Injection records one successful injection into a tracked Service.
+type Injection struct {
+	Key DependencyKey
+	At  time.Time
+}
+
+// DefaultHistoryLimit is the number of injections WithHistory keeps for a limit
+// below 1.
+const DefaultHistoryLimit = 256
+
+// WithHistory turns on InjectionHistory for later injections and returns s. It
+// keeps the most recent limit injections (DefaultHistoryLimit for limit < 1), so
+// services rewired for their whole lifetime stay bounded.
+//
+// History is off by default: each recorded injection costs a time.Now call and
+// an append on the success path.
+func (s *Service[T]) WithHistory(limit int) *Service[T] {
+	if limit < 1 {
+		limit = DefaultHistoryLimit
+	}
+	s.historyLimit = limit
+	if n := len(s.history) - limit; n > 0 {
+		s.history = slices.Delete(s.history, 0, n)
+	}
+	return s
+}
+
+// added notes key, newly recorded by Injecting or InjectingTransient, for Keys
+// and Stats, and records the injection.
+func (s *Service[T]) added(key DependencyKey) {
+	s.order = append(s.order, key)
+	s.peak = max(s.peak, len(s.Deps))
+	s.record(key)
+}
+
+// record appends a successful injection of key to the history when WithHistory
+// is on. The history holds up to twice the limit before the oldest entries are
+// dropped, so trimming is amortized over the injections.
+func (s *Service[T]) record(key DependencyKey) {
+	if s.historyLimit == 0 {
+		return
+	}
+	if len(s.history) >= 2*s.historyLimit {
+		n := copy(s.history, s.history[len(s.history)-s.historyLimit+1:])
+		s.history = s.history[:n]
+	}
+	s.history = append(s.history, Injection{Key: key, At: time.Now()})
+}
+
+// recentHistory is the part of the history within the WithHistory limit.
+func (s *Service[T]) recentHistory() []Injection {
+	if n := len(s.history) - s.historyLimit; s.historyLimit > 0 && n > 0 {
+		return s.history[n:]
+	}
+	return s.history
+}
+
+// InjectionHistory returns the successful injections recorded by Injecting,
+// InjectingTransient and Rewire since WithHistory, oldest first (at most its
+// limit). Untracked injections are not recorded.
+func (s *Service[T]) InjectionHistory() []Injection {
+	if s == nil {
+		return nil
+	}
+	return slices.Clone(s.recentHistory())
+}
+
+// Keys returns the keys currently in Deps in injection order. Keys written to
+// Deps directly (not via an injector) follow, sorted.
+func (s *Service[T]) Keys() []DependencyKey {
+	if s == nil || len(s.Deps) == 0 {
+		return nil
+	}
+	keys := make([]DependencyKey, 0, len(s.Deps))
+	seen := make(map[DependencyKey]bool, len(s.Deps))
+	for _, k := range s.order {
+		if _, ok := s.Deps[k]; ok && !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	var rest []DependencyKey
+	for k := range s.Deps {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	slices.Sort(rest)
+	return append(keys, rest...)
+}
+
+// Range calls fn for each recorded dependency in sorted key order, with the raw
+// value GetAny returns, until fn returns false. Unlike Keys (injection order),
+// the order does not depend on how the service was wired, so logs, dumps and
+// tests are stable without sorting.
+func (s *Service[T]) Range(fn func(key DependencyKey, val any) bool) {
+	if s == nil || len(s.Deps) == 0 {
+		return
+	}
+	keys := make([]DependencyKey, 0, len(s.Deps))
+	for k := range s.Deps {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if !fn(k, s.Deps[k]) {
+			return
+		}
+	}
+}
+
+// FindByInterface returns the recorded dependencies implementing I (all
+// io.Closers, all health checkers, ...) in Keys order, so shutdown or health
+// fan-out can cover whatever is wired without knowing its keys. A value recorded
+// under several keys is returned once. Transient keys are skipped: their
+// factories are not called.
+func FindByInterface[I any, T any](s *Service[T]) []I {
+	var out []I
+	seen := map[any]bool{}
+	for _, k := range s.Keys() {
+		raw := s.Deps[k]
+		if _, ok := raw.(lifetimed); ok {
+			continue
+		}
+		v, ok := raw.(I)
+		if !ok {
+			continue
+		}
+		if reflect.TypeOf(raw).Comparable() {
+			if seen[raw] {
+				continue
+			}
+			seen[raw] = true
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// DepInfo describes one recorded dependency without its value.
+type DepInfo struct {
+	Key DependencyKey `json:"key"`
+	// Type is the concrete type name of the recorded value (for Transient keys,
+	// the type the factory produces).
+	Type     string `json:"type"`
+	Lifetime string `json:"lifetime"`
+}
+
+// DepInfos describes the recorded dependencies in Keys order. Values are
+// never included, only their concrete type names.
+func (s *Service[T]) DepInfos() []DepInfo {
+	keys := s.Keys()
+	out := make([]DepInfo, 0, len(keys))
+	for _, k := range keys {
+		raw := s.Deps[k]
+		info := DepInfo{Key: k, Type: "<nil>", Lifetime: Singleton.String()}
+		if lt, ok := raw.(lifetimed); ok {
+			info.Type = lt.producedType().String()
+			info.Lifetime = lt.lifetime().String()
+		} else if raw != nil {
+			info.Type = reflect.TypeOf(raw).String()
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// DepStats are the Stats of a Service, for diagnosing bloated Deps bags in
+// long-lived processes that clone services heavily.
+type DepStats struct {
+	// Deps is the number of recorded dependencies.
+	Deps int `json:"deps"`
+	// Capacity estimates the slots the Deps map holds. Maps do not shrink, so it
+	// is sized for the most entries the bag has held via injectors, or for the
+	// current keys when there are more.
+	Capacity int `json:"capacity"`
+	// Keys describe the recorded dependencies in Keys order.
+	Keys []KeyStats `json:"keys"`
+	// Size is the sum of the Keys sizes.
+	Size uintptr `json:"size"`
+}
+
+// KeyStats describes the size of one recorded dependency.
+type KeyStats struct {
+	Key  DependencyKey `json:"key"`
+	Type string        `json:"type"`
+	// Size is the shallow size in bytes of the value (for pointers, of the value
+	// pointed to; for Transient keys, of a produced value). Memory the value
+	// references through further pointers, slices or maps is not included.
+	Size uintptr `json:"size"`
+}
+
+// Stats reports the number of recorded dependencies, an estimate of the Deps
+// map capacity and the shallow size of each dependency (via reflect).
+func (s *Service[T]) Stats() DepStats {
+	infos := s.DepInfos()
+	st := DepStats{Deps: len(infos), Keys: make([]KeyStats, 0, len(infos))}
+	if s == nil {
+		return st
+	}
+	for _, info := range infos {
+		raw := s.Deps[info.Key]
+		var typ reflect.Type
+		if lt, ok := raw.(lifetimed); ok {
+			typ = lt.producedType()
+		} else if raw != nil {
+			typ = reflect.TypeOf(raw)
+		}
+		ks := KeyStats{Key: info.Key, Type: info.Type, Size: shallowSize(typ)}
+		st.Keys = append(st.Keys, ks)
+		st.Size += ks.Size
+	}
+	if s.Deps != nil {
+		st.Capacity = mapCapacity(max(len(s.Deps), s.peak))
+	}
+	return st
+}
+
+// shallowSize is the size of a value of t, or of the value it points to.
+func shallowSize(t reflect.Type) uintptr {
+	switch {
+	case t == nil:
+		return 0
+	case t.Kind() == reflect.Pointer:
+		return t.Elem().Size()
+	default:
+		return t.Size()
+	}
+}
+
+// mapCapacity estimates the slots of a map that has held n entries: groups of
+// 8 slots, a power of two of them, filled at most 7/8.
+func mapCapacity(n int) int {
+	c := 8
+	for n > c*7/8 {
+		c *= 2
+	}
+	return c
+}
+
+// MarshalJSON renders the service type and its recorded dependencies (keys,
+// type names and lifetimes; never values), for structured startup logs and
+// golden tests of wiring.
+func (s *Service[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string    `json:"type"`
+		Deps []DepInfo `json:"deps"`
+	}{Type: reflect.TypeFor[*T]().String(), Deps: s.DepInfos()})
+}
+
+// String renders the service type and its recorded dependencies (keys and type
+// names, never values), e.g. `di.Service[*app.User]{db: *app.DB, logger: *app.Logger}`.
+func (s *Service[T]) String() string {
+	var sb strings.Builder
+	sb.WriteString("di.Service[")
+	sb.WriteString(reflect.TypeFor[*T]().String())
+	sb.WriteString("]{")
+	for i, d := range s.DepInfos() {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(string(d.Key))
+		sb.WriteString(": ")
+		sb.WriteString(d.Type)
+		if d.Lifetime != Singleton.String() {
+			sb.WriteString(" (" + d.Lifetime + ")")
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
@@ -0,0 +1,246 @@
+package di_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/sghaida/odi/di"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeysAndInjectionHistory verifies injection order, timestamps, direct Deps
+// writes, failed injections and Clone.
+func TestKeysAndInjectionHistory(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	logger := di.Init(func() *di.Logger { return &di.Logger{} })
+
+	svc := di.Init(func() *di.UserService { return &di.UserService{} }).WithHistory(0)
+	assert.Nil(t, svc.Keys())
+	assert.Empty(t, svc.InjectionHistory())
+
+	before := time.Now()
+	injLogger := di.Injecting(di.Key("logger"), logger, func(u *di.UserService, l *di.Logger) { u.Logger = l })
+	_, err := svc.WithAll(
+		injLogger,
+		di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }),
+		di.InjectingTransient(di.Key("req"), func() *di.DB { return &di.DB{} }, func(*di.UserService, func() *di.DB) {}),
+	)
+	require.NoError(t, err)
+	_, err = svc.With(injLogger)
+	require.Error(t, err, "duplicate must not be recorded")
+
+	svc.Deps["b-direct"] = 1
+	svc.Deps["a-direct"] = 2
+	assert.Equal(t, []di.DependencyKey{"logger", "db", "req", "a-direct", "b-direct"}, svc.Keys())
+
+	hist := svc.InjectionHistory()
+	require.Len(t, hist, 3)
+	assert.Equal(t, di.DependencyKey("logger"), hist[0].Key)
+	assert.Equal(t, di.DependencyKey("req"), hist[2].Key)
+	assert.False(t, hist[0].At.Before(before))
+	assert.False(t, hist[2].At.Before(hist[0].At))
+
+	hist[0].Key = "mutated"
+	assert.Equal(t, di.DependencyKey("logger"), svc.InjectionHistory()[0].Key, "history must be copied")
+
+	cp := svc.Clone()
+	delete(svc.Deps, "db")
+	assert.Equal(t, []di.DependencyKey{"logger", "req", "a-direct", "b-direct"}, svc.Keys())
+	assert.Equal(t, svc.InjectionHistory(), cp.InjectionHistory())
+	assert.Equal(t, []di.DependencyKey{"logger", "db", "req", "a-direct", "b-direct"}, cp.Keys())
+
+	var nilSvc *di.Service[di.UserService]
+	assert.Nil(t, nilSvc.Keys())
+	assert.Nil(t, nilSvc.InjectionHistory())
+
+	untracked := di.InitUntracked(func() *di.UserService { return &di.UserService{} })
+	_, err = untracked.With(injLogger)
+	require.NoError(t, err)
+	assert.Empty(t, untracked.InjectionHistory())
+}
+
+// TestInjectionHistory_OptInAndLimit verifies history is off by default (Keys
+// still keep injection order), bounded by the WithHistory limit, and that
+// Remove drops the key from Keys.
+func TestInjectionHistory_OptInAndLimit(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	bind := func(u *di.UserService, d *di.DB) { u.DB = d }
+
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	_, err := svc.WithAll(
+		di.Injecting(di.Key("z"), db, bind),
+		di.Injecting(di.Key("a"), db, bind),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, svc.InjectionHistory())
+	assert.Equal(t, []di.DependencyKey{"z", "a"}, svc.Keys())
+
+	require.NoError(t, svc.Remove("z"))
+	_, err = svc.With(di.Injecting(di.Key("z"), db, bind))
+	require.NoError(t, err)
+	assert.Equal(t, []di.DependencyKey{"a", "z"}, svc.Keys())
+
+	svc.WithHistory(3)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, di.Rewire(svc, di.DependencyKey([]string{"a", "z"}[i%2]), db, bind))
+	}
+	hist := svc.InjectionHistory()
+	require.Len(t, hist, 3)
+	assert.Equal(t, []di.DependencyKey{"z", "a", "z"}, []di.DependencyKey{hist[0].Key, hist[1].Key, hist[2].Key})
+	assert.Len(t, svc.Clone().InjectionHistory(), 3)
+
+	svc.WithHistory(1)
+	require.Len(t, svc.InjectionHistory(), 1)
+	assert.Equal(t, di.DependencyKey("z"), svc.InjectionHistory()[0].Key)
+}
+
+// TestService_Range verifies sorted iteration and early stop.
+func TestService_Range(t *testing.T) {
+	t.Parallel()
+
+	db := &di.DB{}
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	_, err := svc.With(di.Injecting(di.Key("db"), di.Init(func() *di.DB { return db }), func(*di.UserService, *di.DB) {}))
+	require.NoError(t, err)
+	svc.Deps["cache"] = 1
+	svc.Deps["zone"] = "eu"
+
+	var keys []di.DependencyKey
+	var vals []any
+	svc.Range(func(k di.DependencyKey, v any) bool {
+		keys, vals = append(keys, k), append(vals, v)
+		return true
+	})
+	assert.Equal(t, []di.DependencyKey{"cache", "db", "zone"}, keys)
+	assert.Equal(t, []any{1, db, "eu"}, vals)
+
+	keys = nil
+	svc.Range(func(k di.DependencyKey, _ any) bool {
+		keys = append(keys, k)
+		return k != "db"
+	})
+	assert.Equal(t, []di.DependencyKey{"cache", "db"}, keys)
+
+	var nilSvc *di.Service[di.UserService]
+	nilSvc.Range(func(di.DependencyKey, any) bool {
+		t.Fatal("nil service has no deps")
+		return false
+	})
+}
+
+type closer struct {
+	name   string
+	closed bool
+}
+
+func (c *closer) Close() error { c.closed = true; return nil }
+
+// TestFindByInterface verifies matching deps are found in Keys order, once per
+// value, without calling Transient factories.
+func TestFindByInterface(t *testing.T) {
+	t.Parallel()
+
+	cache, queue := &closer{name: "cache"}, &closer{name: "queue"}
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	_, err := svc.WithAll(
+		di.Injecting(di.Key("queue"), di.Init(func() *closer { return queue }), func(*di.UserService, *closer) {}),
+		di.Injecting(di.Key("db"), di.Init(func() *di.DB { return &di.DB{} }), func(*di.UserService, *di.DB) {}),
+		di.InjectingTransient(di.Key("conn"), func() *closer {
+			t.Fatal("transient factory must not be called")
+			return nil
+		}, func(*di.UserService, func() *closer) {}),
+	)
+	require.NoError(t, err)
+	svc.Deps["cache"] = cache
+	svc.Deps["queue-alias"] = queue
+
+	closers := di.FindByInterface[io.Closer](svc)
+	assert.Equal(t, []io.Closer{queue, cache}, closers)
+	for _, c := range closers {
+		require.NoError(t, c.Close())
+	}
+	assert.True(t, cache.closed && queue.closed)
+
+	assert.Empty(t, di.FindByInterface[fmt.Stringer](svc))
+	var nilSvc *di.Service[di.UserService]
+	assert.Nil(t, di.FindByInterface[io.Closer](nilSvc))
+}
+
+// TestService_Stats verifies counts, shallow sizes and the capacity estimate.
+func TestService_Stats(t *testing.T) {
+	t.Parallel()
+
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	assert.Equal(t, di.DepStats{Keys: []di.KeyStats{}, Capacity: 8}, svc.Stats())
+
+	_, err := svc.WithAll(
+		di.Injecting(di.Key("closer"), di.Init(func() *closer { return &closer{} }), func(*di.UserService, *closer) {}),
+		di.InjectingTransient(di.Key("req"), func() *closer { return &closer{} }, func(*di.UserService, func() *closer) {}),
+	)
+	require.NoError(t, err)
+	svc.Deps["n"] = int64(1)
+
+	size := unsafe.Sizeof(closer{})
+	st := svc.Stats()
+	assert.Equal(t, 3, st.Deps)
+	assert.Equal(t, 8, st.Capacity)
+	assert.Equal(t, []di.KeyStats{
+		{Key: "closer", Type: "*di_test.closer", Size: size},
+		{Key: "req", Type: "*di_test.closer", Size: size},
+		{Key: "n", Type: "int64", Size: 8},
+	}, st.Keys)
+	assert.Equal(t, 2*size+8, st.Size)
+
+	for i := 0; i < 20; i++ {
+		svc.Deps[di.DependencyKey(fmt.Sprint("k", i))] = i
+	}
+	assert.Equal(t, 32, svc.Stats().Capacity)
+
+	var nilSvc *di.Service[di.UserService]
+	assert.Equal(t, 0, nilSvc.Stats().Deps)
+}
+
+// TestService_MarshalJSONAndString verifies that keys and type names are rendered
+// in injection order and values are never included.
+func TestService_MarshalJSONAndString(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{DSN: "postgres://secret"} })
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	_, err := svc.WithAll(
+		di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }),
+		di.InjectingTransient(di.Key("req"), func() *di.Logger { return &di.Logger{} }, func(*di.UserService, func() *di.Logger) {}),
+	)
+	require.NoError(t, err)
+	svc.Deps["raw"] = nil
+
+	raw, err := json.Marshal(svc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "type": "*di.UserService",
+  "deps": [
+    {"key": "db", "type": "*di.DB", "lifetime": "singleton"},
+    {"key": "req", "type": "*di.Logger", "lifetime": "transient"},
+    {"key": "raw", "type": "<nil>", "lifetime": "singleton"}
+  ]
+}`, string(raw))
+	assert.NotContains(t, string(raw), "secret")
+
+	assert.Equal(t, "di.Service[*di.UserService]{db: *di.DB, req: *di.Logger (transient), raw: <nil>}", svc.String())
+	assert.Equal(t, svc.String(), fmt.Sprint(svc))
+
+	empty := di.Init(func() *di.DB { return &di.DB{} })
+	assert.Equal(t, "di.Service[*di.DB]{}", empty.String())
+	raw, err = json.Marshal(empty)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "*di.DB", "deps": []}`, string(raw))
+}
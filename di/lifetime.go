@@ -0,0 +1,111 @@
+package di
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Lifetime describes what a recorded dependency yields on retrieval.
+type Lifetime int
+
+const (
+	// Singleton dependencies are recorded once and every retrieval returns the
+	// same pointer. It is the lifetime of everything recorded via Injecting.
+	Singleton Lifetime = iota
+
+	// Transient dependencies record a Factory; GetAs, TryGetAs and MustGetAs call
+	// it on every retrieval (e.g. per-request objects). See InjectingTransient.
+	Transient
+)
+
+// String returns "singleton" or "transient".
+func (l Lifetime) String() string {
+	switch l {
+	case Singleton:
+		return "singleton"
+	case Transient:
+		return "transient"
+	}
+	return "Lifetime(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Factory produces a new *D per call. It is what Deps holds for Transient keys.
+type Factory[D any] func() *D
+
+func (Factory[D]) lifetime() Lifetime { return Transient }
+
+func (Factory[D]) producedType() reflect.Type { return reflect.TypeFor[*D]() }
+
+// lifetimed is implemented by recorded values with a non-Singleton lifetime.
+type lifetimed interface {
+	lifetime() Lifetime
+	producedType() reflect.Type
+}
+
+// NilFactoryError indicates a nil factory for a specific transient key.
+type NilFactoryError struct{ Key DependencyKey }
+
+// Error implements the error interface.
+func (e NilFactoryError) Error() string {
+	// Example: di: nil factory for key "request"
+	return "di: nil factory for key " + strconv.Quote(string(e.Key))
+}
+
+// InjectingTransient builds an Injector that records factory under key with the
+// Transient lifetime and passes it to bind, so the target can create a fresh
+// dependency whenever it needs one.
+//
+// Typed retrieval (GetAs, TryGetAs, MustGetAs) of a transient key calls the
+// factory per call; GetAny returns the Factory itself.
+//
+// The returned injector fails like Injecting: ErrNilTarget, NilFactoryError,
+// NilBindError or DuplicateKeyError. Untracked services only bind.
+func InjectingTransient[T any, D any](
+	key DependencyKey,
+	factory func() *D,
+	bind func(target *T, factory func() *D),
+) Injector[T] {
+	return func(s *Service[T]) error {
+		if s == nil || s.Val == nil {
+			return ErrNilTarget
+		}
+		if factory == nil {
+			return NilFactoryError{Key: key}
+		}
+		if bind == nil {
+			return NilBindError{Key: key}
+		}
+		if s.untracked {
+			s.binding = key
+			bind(s.Val, factory)
+			s.binding = ""
+			return nil
+		}
+		if s.Deps == nil {
+			s.Deps = make(map[DependencyKey]any)
+		}
+		if _, exists := s.Deps[key]; exists {
+			return DuplicateKeyError{Key: key}
+		}
+
+		s.Deps[key] = Factory[D](factory)
+		s.binding = key
+		bind(s.Val, factory)
+		s.binding = ""
+		s.added(key)
+		return nil
+	}
+}
+
+// Lifetime returns the lifetime of the dependency recorded under key.
+// ok is false if the key is not recorded.
+func (s *Service[T]) Lifetime(key DependencyKey) (l Lifetime, ok bool) {
+	raw, ok := s.GetAny(key)
+	if !ok {
+		return Singleton, false
+	}
+	if lt, isLifetimed := raw.(lifetimed); isLifetimed {
+		return lt.lifetime(), true
+	}
+	return Singleton, true
+}
@@ -0,0 +1,112 @@
+package di_test
+
+import (
+	"testing"
+
+	"github.com/sghaida/odi/di"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type requestScoped struct{ ID int }
+
+type handler struct {
+	newRequest func() *requestScoped
+}
+
+// TestInjectingTransient_FactoryPerRetrieval verifies that transient keys call the
+// factory on every typed retrieval while singleton keys return the same pointer.
+func TestInjectingTransient_FactoryPerRetrieval(t *testing.T) {
+	t.Parallel()
+
+	n := 0
+	factory := func() *requestScoped {
+		n++
+		return &requestScoped{ID: n}
+	}
+	db := di.Init(func() *di.DB { return &di.DB{} })
+
+	h := di.Init(func() *handler { return &handler{} })
+	_, err := h.WithAll(
+		di.InjectingTransient(di.Key("request"), factory, func(h *handler, f func() *requestScoped) { h.newRequest = f }),
+		di.Injecting(di.Key("db"), db, func(*handler, *di.DB) {}),
+	)
+	require.NoError(t, err)
+
+	require.NotNil(t, h.Val.newRequest)
+	assert.Equal(t, 1, h.Val.newRequest().ID)
+
+	r, ok := di.GetAs[handler, requestScoped](h, "request")
+	require.True(t, ok)
+	assert.Equal(t, 2, r.ID)
+
+	r, err = di.TryGetAs[handler, requestScoped](h, "request")
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.ID)
+	assert.Equal(t, 4, di.MustGetAs[handler, requestScoped](h, "request").ID)
+
+	_, ok = di.GetAs[handler, di.DB](h, "request")
+	assert.False(t, ok, "factory of another type must not match")
+
+	raw, ok := h.GetAny("request")
+	require.True(t, ok)
+	assert.IsType(t, di.Factory[requestScoped](nil), raw)
+
+	lt, ok := h.Lifetime("request")
+	assert.True(t, ok)
+	assert.Equal(t, di.Transient, lt)
+	lt, ok = h.Lifetime("db")
+	assert.True(t, ok)
+	assert.Equal(t, di.Singleton, lt)
+	_, ok = h.Lifetime("missing")
+	assert.False(t, ok)
+}
+
+// TestInjectingTransient_Errors verifies the injector's failure modes.
+func TestInjectingTransient_Errors(t *testing.T) {
+	t.Parallel()
+
+	factory := func() *requestScoped { return &requestScoped{} }
+	bind := func(*handler, func() *requestScoped) {}
+
+	tests := []struct {
+		name    string
+		target  *di.Service[handler]
+		factory func() *requestScoped
+		bind    func(*handler, func() *requestScoped)
+		wantErr error
+	}{
+		{name: "nil_target", target: nil, factory: factory, bind: bind, wantErr: di.ErrNilTarget},
+		{name: "nil_factory", target: di.Init(func() *handler { return &handler{} }), bind: bind, wantErr: di.NilFactoryError{Key: "request"}},
+		{name: "nil_bind", target: di.Init(func() *handler { return &handler{} }), factory: factory, wantErr: di.NilBindError{Key: "request"}},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := di.InjectingTransient("request", tc.factory, tc.bind)(tc.target)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+
+	h := di.Init(func() *handler { return &handler{} })
+	inj := di.InjectingTransient("request", factory, bind)
+	require.NoError(t, inj(h))
+	assert.Equal(t, di.DuplicateKeyError{Key: "request"}, inj(h))
+	assert.EqualError(t, di.NilFactoryError{Key: "request"}, `di: nil factory for key "request"`)
+
+	untracked := di.InitUntracked(func() *handler { return &handler{} })
+	require.NoError(t, di.InjectingTransient("request", factory, func(h *handler, f func() *requestScoped) { h.newRequest = f })(untracked))
+	assert.NotNil(t, untracked.Val.newRequest)
+	assert.False(t, untracked.Has("request"))
+}
+
+// TestLifetime_String verifies lifetime names.
+func TestLifetime_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "singleton", di.Singleton.String())
+	assert.Equal(t, "transient", di.Transient.String())
+	assert.Equal(t, "Lifetime(7)", di.Lifetime(7).String())
+}
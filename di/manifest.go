@@ -0,0 +1,102 @@
+package di
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// GraphManifest describes the wiring a graph was generated with. di2 writes it
+// next to the generated graph when the graph spec sets "manifest", embeds it in
+// the package and registers it at init, so a running binary can report exactly
+// what it was wired from (see Manifest).
+type GraphManifest struct {
+	Generator string         `json:"generator"` // di2 module version, "(devel)" for local builds
+	Package   string         `json:"package"`
+	Graph     string         `json:"graph"`
+	GraphHash string         `json:"graphHash"`
+	Roots     []ManifestRoot `json:"roots"`
+}
+
+// ManifestRoot is one root of a GraphManifest.
+type ManifestRoot struct {
+	Name     string            `json:"name"`
+	Services []ManifestService `json:"services"` // build order
+	Wiring   []ManifestWiring  `json:"wiring"`
+}
+
+// ManifestService is one service of a ManifestRoot. Spec, SpecHash and Deps are
+// set for services linking their spec (Spec is relative to the generated package).
+type ManifestService struct {
+	Var        string        `json:"var"`
+	FacadeType string        `json:"facadeType"`
+	ImplType   string        `json:"implType"`
+	When       string        `json:"when,omitempty"`
+	Consumer   bool          `json:"consumer,omitempty"`
+	Spec       string        `json:"spec,omitempty"`
+	SpecHash   string        `json:"specHash,omitempty"`
+	Deps       []ManifestDep `json:"deps,omitempty"`
+}
+
+// ManifestWiring is one wiring entry of a ManifestRoot.
+type ManifestWiring struct {
+	To      string `json:"to"`
+	Call    string `json:"call"`
+	ArgFrom string `json:"argFrom"`
+	When    string `json:"when,omitempty"`
+}
+
+var manifests struct {
+	mu   sync.Mutex
+	list []GraphManifest
+}
+
+// RegisterManifest adds the JSON-encoded manifest raw to those returned by
+// Manifest. Generated graphs call it from init; it panics on malformed JSON.
+func RegisterManifest(raw []byte) {
+	var m GraphManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		panic(fmt.Sprintf("di: invalid wiring manifest: %v", err))
+	}
+	manifests.mu.Lock()
+	defer manifests.mu.Unlock()
+	manifests.list = append(manifests.list, m)
+}
+
+// Manifest returns the manifests registered by the generated graphs linked into
+// the binary, sorted by package and graph path.
+func Manifest() []GraphManifest {
+	manifests.mu.Lock()
+	out := slices.Clone(manifests.list)
+	manifests.mu.Unlock()
+	slices.SortStableFunc(out, func(a, b GraphManifest) int {
+		return cmp.Or(cmp.Compare(a.Package, b.Package), cmp.Compare(a.Graph, b.Graph))
+	})
+	return out
+}
+
+// WriteManifest writes Manifest as indented JSON, e.g. for a --print-wiring flag:
+//
+//	if *printWiring {
+//		_ = di.WriteManifest(os.Stdout)
+//		return
+//	}
+func WriteManifest(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Manifest())
+}
+
+// ManifestHandler serves Manifest as indented JSON, e.g. on /debug/wiring.
+//
+// Like WiringHandler, mount it on an internal/admin listener only.
+func ManifestHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = WriteManifest(w)
+	})
+}
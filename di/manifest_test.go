@@ -0,0 +1,72 @@
+package di_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestManifest_RegisterAndServe verifies registered manifests are returned sorted
+// and served as JSON. Other tests may register manifests too, so it looks only
+// at its own packages.
+func TestManifest_RegisterAndServe(t *testing.T) {
+	t.Parallel()
+
+	b := di.GraphManifest{
+		Generator: "(devel)",
+		Package:   "manifesttest_b",
+		Graph:     "specs/graph.json",
+		GraphHash: "h",
+		Roots: []di.ManifestRoot{{
+			Name: "BuildApp",
+			Services: []di.ManifestService{
+				{Var: "core", FacadeType: "*CoreV4", ImplType: "Core", Spec: "core.inject.json", SpecHash: "c",
+					Deps: []di.ManifestDep{{Name: "DB", Type: "*DB", Tags: []string{"pii"}}}},
+			},
+			Wiring: []di.ManifestWiring{{To: "core", Call: "InjectDB", ArgFrom: "db"}},
+		}},
+	}
+	a := di.GraphManifest{Package: "manifesttest_a", Graph: "graph.json"}
+	for _, m := range []di.GraphManifest{b, a} {
+		raw, err := json.Marshal(m)
+		require.NoError(t, err)
+		di.RegisterManifest(raw)
+	}
+
+	var own []di.GraphManifest
+	for _, m := range di.Manifest() {
+		if m.Package == a.Package || m.Package == b.Package {
+			own = append(own, m)
+		}
+	}
+	require.Len(t, own, 2)
+	assert.Equal(t, a.Package, own[0].Package)
+	assert.Equal(t, b, own[1])
+
+	rec := httptest.NewRecorder()
+	di.ManifestHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/wiring", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"package": "manifesttest_b"`)
+
+	var buf bytes.Buffer
+	require.NoError(t, di.WriteManifest(&buf))
+	var got []di.GraphManifest
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Contains(t, got, b)
+}
+
+// TestRegisterManifest_Malformed verifies malformed manifests panic at registration.
+func TestRegisterManifest_Malformed(t *testing.T) {
+	t.Parallel()
+
+	assert.PanicsWithValue(t, "di: invalid wiring manifest: unexpected end of JSON input", func() {
+		di.RegisterManifest([]byte(`{"package":`))
+	})
+}
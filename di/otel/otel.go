@@ -0,0 +1,115 @@
+// Package otel provides OpenTelemetry glue for odi-wired services.
+//
+// It contains:
+//   - Tracer: an implementation of the StartSpan-style tracer interface used by
+//     the examples (StartSpan(ctx, name) (context.Context, func(err error))),
+//     backed by an OpenTelemetry TracerProvider
+//   - Provide: registers a Tracer under the well-known "otel.tracer" registry key
+//   - span naming helpers (BuildSpanName, ResolveSpanName) and TraceBuild /
+//     TraceResolve hooks, so builders emit one span per Build and one per
+//     optional dependency resolution with consistent names and attributes
+//
+// NewTracer(nil) uses the global TracerProvider, so services pick up whatever
+// exporter the application registered with otel.SetTracerProvider.
+package otel
+
+import (
+	"context"
+
+	otelglobal "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sghaida/odi/di"
+)
+
+// RegistryKey is the well-known registry key a Tracer is provided under.
+const RegistryKey = "otel.tracer"
+
+// ScopeName is the instrumentation scope used when obtaining tracers.
+const ScopeName = "github.com/sghaida/odi/di/otel"
+
+// Span attribute keys set by TraceBuild / TraceResolve.
+const (
+	AttrFacade      = attribute.Key("odi.facade")
+	AttrRegistryKey = attribute.Key("odi.registry_key")
+	AttrFound       = attribute.Key("odi.found")
+)
+
+// Tracer adapts an OpenTelemetry tracer to the StartSpan-style interface.
+// It is safe for concurrent use.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a Tracer backed by tp.
+// A nil tp uses the global provider (otel.GetTracerProvider()).
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otelglobal.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(ScopeName)}
+}
+
+// StartSpan starts a span named name and returns the derived context and a
+// finish function. Passing a non-nil error to finish records it on the span and
+// sets the span status to Error.
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, func(err error) { end(span, err) }
+}
+
+// Provide registers t under RegistryKey and returns reg for chaining.
+func Provide(reg *di.MapRegistry, t *Tracer) *di.MapRegistry {
+	return reg.Provide(RegistryKey, t)
+}
+
+// BuildSpanName returns the span name used for a facade Build/BuildWith call.
+func BuildSpanName(facade string) string {
+	return "odi.build " + facade
+}
+
+// ResolveSpanName returns the span name used for resolving one optional
+// dependency of a facade from the registry.
+func ResolveSpanName(facade, key string) string {
+	return "odi.resolve " + facade + " " + key
+}
+
+// TraceBuild runs build inside a span named BuildSpanName(facade).
+// The error returned by build is recorded on the span and returned unchanged.
+//
+// Typical use from a composition root:
+//
+//	err := tr.TraceBuild(ctx, "CoreV4", func() error {
+//		_, err := coreB.BuildWith(reg)
+//		return err
+//	})
+func (t *Tracer) TraceBuild(ctx context.Context, facade string, build func() error) error {
+	_, span := t.tracer.Start(ctx, BuildSpanName(facade),
+		trace.WithAttributes(AttrFacade.String(facade)),
+	)
+	err := build()
+	end(span, err)
+	return err
+}
+
+// TraceResolve starts a span for resolving one optional dependency and returns
+// the derived context and a finish function reporting whether the key was found.
+func (t *Tracer) TraceResolve(ctx context.Context, facade, key string) (context.Context, func(found bool, err error)) {
+	ctx, span := t.tracer.Start(ctx, ResolveSpanName(facade, key),
+		trace.WithAttributes(AttrFacade.String(facade), AttrRegistryKey.String(key)),
+	)
+	return ctx, func(found bool, err error) {
+		span.SetAttributes(AttrFound.Bool(found))
+		end(span, err)
+	}
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
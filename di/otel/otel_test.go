@@ -0,0 +1,121 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/sghaida/odi/di"
+)
+
+func newRecorded(t *testing.T) (*Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return NewTracer(tp), rec
+}
+
+//
+// -----------------------------------------------------------------------------
+// Tracer
+// -----------------------------------------------------------------------------
+
+// TestStartSpan_RecordsNameAndError verifies StartSpan ends spans and records errors as status.
+func TestStartSpan_RecordsNameAndError(t *testing.T) {
+	t.Parallel()
+
+	tr, rec := newRecorded(t)
+
+	_, finish := tr.StartSpan(context.Background(), "core.process")
+	finish(nil)
+	_, finish = tr.StartSpan(context.Background(), "core.fail")
+	finish(errors.New("boom"))
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "core.process", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, "core.fail", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+}
+
+// TestNewTracer_NilUsesGlobal verifies a nil provider falls back to the global (no-op by default) provider.
+func TestNewTracer_NilUsesGlobal(t *testing.T) {
+	t.Parallel()
+
+	tr := NewTracer(nil)
+	ctx, finish := tr.StartSpan(context.Background(), "x")
+	require.NotNil(t, ctx)
+	finish(nil)
+}
+
+// TestProvide_RegistersUnderWellKnownKey verifies Provide stores the tracer under "otel.tracer".
+func TestProvide_RegistersUnderWellKnownKey(t *testing.T) {
+	t.Parallel()
+
+	tr, _ := newRecorded(t)
+	reg := di.NewMapRegistry()
+	require.Same(t, reg, Provide(reg, tr))
+
+	v, ok, err := reg.Resolve(nil, "otel.tracer")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Same(t, tr, v)
+}
+
+//
+// -----------------------------------------------------------------------------
+// Span naming + builder hooks
+// -----------------------------------------------------------------------------
+
+// TestSpanNames verifies the naming helpers are stable.
+func TestSpanNames(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "odi.build CoreV4", BuildSpanName("CoreV4"))
+	assert.Equal(t, "odi.resolve CoreV4 v4.tracer", ResolveSpanName("CoreV4", "v4.tracer"))
+}
+
+// TestTraceBuild_SpanPerBuild verifies TraceBuild emits one span per call and returns build's error.
+func TestTraceBuild_SpanPerBuild(t *testing.T) {
+	t.Parallel()
+
+	tr, rec := newRecorded(t)
+	boom := errors.New("wiring incomplete")
+
+	require.NoError(t, tr.TraceBuild(context.Background(), "AlphaV4", func() error { return nil }))
+	require.ErrorIs(t, tr.TraceBuild(context.Background(), "CoreV4", func() error { return boom }), boom)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "odi.build AlphaV4", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), AttrFacade.String("AlphaV4"))
+	assert.Equal(t, "odi.build CoreV4", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+// TestTraceResolve_RecordsFound verifies TraceResolve tags the span with key and found flag.
+func TestTraceResolve_RecordsFound(t *testing.T) {
+	t.Parallel()
+
+	tr, rec := newRecorded(t)
+
+	_, finish := tr.TraceResolve(context.Background(), "CoreV4", "v4.metrics")
+	finish(false, nil)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "odi.resolve CoreV4 v4.metrics", spans[0].Name())
+	attrs := spans[0].Attributes()
+	assert.Contains(t, attrs, AttrFacade.String("CoreV4"))
+	assert.Contains(t, attrs, AttrRegistryKey.String("v4.metrics"))
+	assert.Contains(t, attrs, AttrFound.Bool(false))
+}
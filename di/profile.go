@@ -0,0 +1,63 @@
+package di
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServiceTiming is how long one graph service took to construct, to receive
+// its wiring and to build.
+type ServiceTiming struct {
+	Service   string        `json:"service"`
+	Construct time.Duration `json:"construct"`
+	Inject    time.Duration `json:"inject"`
+	Build     time.Duration `json:"build"`
+}
+
+// Total is the sum of the construction, injection and build durations.
+func (s ServiceTiming) Total() time.Duration {
+	return s.Construct + s.Inject + s.Build
+}
+
+// BuildProfile records per-service timings of one graph root build.
+//
+// Graphs generated with di2 -profile fill one in and expose it as
+// <Root>Result.Profile().
+type BuildProfile struct {
+	Root     string          `json:"root"`
+	Services []ServiceTiming `json:"services"` // build order
+	Total    time.Duration   `json:"total"`    // wall clock; less than the sum for parallel graphs
+}
+
+// NewBuildProfile returns a profile with one zero timing per service, in order.
+func NewBuildProfile(root string, services ...string) BuildProfile {
+	p := BuildProfile{Root: root, Services: make([]ServiceTiming, len(services))}
+	for i, s := range services {
+		p.Services[i].Service = s
+	}
+	return p
+}
+
+// Slowest returns up to n service timings ordered by descending Total.
+// n <= 0 returns all of them.
+func (p BuildProfile) Slowest(n int) []ServiceTiming {
+	out := append([]ServiceTiming(nil), p.Services...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Total() > out[j].Total() })
+	if n > 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// String renders the profile as a small table, slowest service first.
+func (p BuildProfile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d services in %s\n", p.Root, len(p.Services), p.Total)
+	for _, s := range p.Slowest(0) {
+		fmt.Fprintf(&b, "  %-20s total=%s construct=%s inject=%s build=%s\n",
+			s.Service, s.Total(), s.Construct, s.Inject, s.Build)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package di_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestNewBuildProfile verifies services are preallocated in order.
+func TestNewBuildProfile(t *testing.T) {
+	t.Parallel()
+
+	p := di.NewBuildProfile("App", "db", "repo")
+	assert.Equal(t, "App", p.Root)
+	assert.Equal(t, []di.ServiceTiming{{Service: "db"}, {Service: "repo"}}, p.Services)
+}
+
+// TestBuildProfile_Slowest verifies ordering by total duration and the n limit.
+func TestBuildProfile_Slowest(t *testing.T) {
+	t.Parallel()
+
+	p := di.NewBuildProfile("App", "a", "b", "c")
+	p.Services[0].Build = 1 * time.Millisecond
+	p.Services[1].Construct = 2 * time.Millisecond
+	p.Services[1].Inject = 2 * time.Millisecond
+	p.Services[2].Build = 3 * time.Millisecond
+
+	names := func(ts []di.ServiceTiming) []string {
+		out := []string{}
+		for _, s := range ts {
+			out = append(out, s.Service)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"b", "c", "a"}, names(p.Slowest(0)))
+	assert.Equal(t, []string{"b"}, names(p.Slowest(1)))
+	assert.Equal(t, []string{"b", "c", "a"}, names(p.Slowest(10)))
+	assert.Equal(t, 4*time.Millisecond, p.Services[1].Total())
+	assert.Equal(t, "a", p.Services[0].Service, "Slowest must not reorder the profile")
+}
+
+// TestBuildProfile_String verifies the table header and slowest-first rows.
+func TestBuildProfile_String(t *testing.T) {
+	t.Parallel()
+
+	p := di.NewBuildProfile("App", "fast", "slow")
+	p.Services[1].Build = time.Second
+	p.Total = 2 * time.Second
+
+	s := p.String()
+	assert.Contains(t, s, "App: 2 services in 2s\n")
+	assert.Less(t, strings.Index(s, "slow"), strings.Index(s, "fast"))
+}
@@ -0,0 +1,151 @@
+// Package prometheus provides Prometheus glue for odi-wired services.
+//
+// It contains:
+//   - Metrics: an implementation of the Inc(name)-style metrics interface used
+//     by the examples, backed by a Prometheus counter vector
+//   - Provide: registers Metrics under the well-known "prometheus.metrics" key
+//   - BuildMetricsCollector: a prometheus.Collector exposing composition-root
+//     health (services built, build failures, build durations, missing optional
+//     dependencies) for scraping
+//
+// NewMetrics registers its counter vector on the given Registerer and returns
+// the registration error, so a duplicate namespace fails instead of panicking.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sghaida/odi/di"
+)
+
+// RegistryKey is the well-known registry key Metrics is provided under.
+const RegistryKey = "prometheus.metrics"
+
+// Metrics adapts a Prometheus counter vector to the Inc(name) interface.
+// Each distinct name becomes one label value of the "<namespace>_events_total"
+// counter. It is safe for concurrent use.
+type Metrics struct {
+	events *prometheus.CounterVec
+}
+
+// NewMetrics creates Metrics and registers its counter with r.
+// A nil r registers with prometheus.DefaultRegisterer.
+func NewMetrics(r prometheus.Registerer, namespace string) (*Metrics, error) {
+	if r == nil {
+		r = prometheus.DefaultRegisterer
+	}
+	events := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_total",
+		Help:      "Events counted by services through the Inc(name) metrics interface.",
+	}, []string{"name"})
+	if err := r.Register(events); err != nil {
+		return nil, err
+	}
+	return &Metrics{events: events}, nil
+}
+
+// Inc increments the counter for name.
+func (m *Metrics) Inc(name string) {
+	m.events.WithLabelValues(name).Inc()
+}
+
+// Provide registers m under RegistryKey and returns reg for chaining.
+func Provide(reg *di.MapRegistry, m *Metrics) *di.MapRegistry {
+	return reg.Provide(RegistryKey, m)
+}
+
+// BuildMetricsCollector exposes wiring metrics for composition roots.
+//
+// Exported series (all labelled by facade):
+//   - <ns>_services_built_total: successful Build/BuildWith calls
+//   - <ns>_build_failures_total: failed Build/BuildWith calls
+//   - <ns>_build_duration_seconds: Build/BuildWith latency histogram
+//   - <ns>_optional_missing: optional deps missing at the facade's last build
+//
+// Register it once (prometheus.MustRegister(c)) and feed it from the
+// composition root via Track / ObserveBuild / SetOptionalMissing.
+type BuildMetricsCollector struct {
+	built    *prometheus.CounterVec
+	failures *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	missing  *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*BuildMetricsCollector)(nil)
+
+// NewBuildMetricsCollector returns an unregistered collector using namespace
+// as the metric name prefix.
+func NewBuildMetricsCollector(namespace string) *BuildMetricsCollector {
+	labels := []string{"facade"}
+	return &BuildMetricsCollector{
+		built: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "services_built_total",
+			Help:      "Successful facade builds.",
+		}, labels),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "build_failures_total",
+			Help:      "Failed facade builds (wiring incomplete or optional resolution errors).",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "build_duration_seconds",
+			Help:      "Facade Build/BuildWith latency.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, labels),
+		missing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "optional_missing",
+			Help:      "Optional dependencies missing from the registry at the facade's last build.",
+		}, labels),
+	}
+}
+
+// ObserveBuild records one Build/BuildWith outcome for facade.
+func (c *BuildMetricsCollector) ObserveBuild(facade string, d time.Duration, err error) {
+	c.duration.WithLabelValues(facade).Observe(d.Seconds())
+	if err != nil {
+		c.failures.WithLabelValues(facade).Inc()
+		return
+	}
+	c.built.WithLabelValues(facade).Inc()
+}
+
+// SetOptionalMissing records how many optional deps were missing when facade
+// was last built.
+func (c *BuildMetricsCollector) SetOptionalMissing(facade string, n int) {
+	c.missing.WithLabelValues(facade).Set(float64(n))
+}
+
+// Track times build, records its outcome for facade, and returns its error.
+//
+//	err := c.Track("CoreV4", func() error {
+//		_, err := coreB.BuildWith(reg)
+//		return err
+//	})
+func (c *BuildMetricsCollector) Track(facade string, build func() error) error {
+	start := time.Now()
+	err := build()
+	c.ObserveBuild(facade, time.Since(start), err)
+	return err
+}
+
+// Describe implements prometheus.Collector.
+func (c *BuildMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.built.Describe(ch)
+	c.failures.Describe(ch)
+	c.duration.Describe(ch)
+	c.missing.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *BuildMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.built.Collect(ch)
+	c.failures.Collect(ch)
+	c.duration.Collect(ch)
+	c.missing.Collect(ch)
+}
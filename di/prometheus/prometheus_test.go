@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+//
+// -----------------------------------------------------------------------------
+// Metrics
+// -----------------------------------------------------------------------------
+
+// TestMetrics_IncCountsPerName verifies Inc increments one labelled series per name.
+func TestMetrics_IncCountsPerName(t *testing.T) {
+	t.Parallel()
+
+	r := prometheus.NewRegistry()
+	m, err := NewMetrics(r, "app")
+	require.NoError(t, err)
+
+	m.Inc("core.process.calls")
+	m.Inc("core.process.calls")
+	m.Inc("core.process.errors")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.events.WithLabelValues("core.process.calls")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.events.WithLabelValues("core.process.errors")))
+}
+
+// TestNewMetrics_DuplicateRegistrationFails verifies registration errors are returned, not panicked.
+func TestNewMetrics_DuplicateRegistrationFails(t *testing.T) {
+	t.Parallel()
+
+	r := prometheus.NewRegistry()
+	_, err := NewMetrics(r, "app")
+	require.NoError(t, err)
+
+	m, err := NewMetrics(r, "app")
+	require.Error(t, err)
+	assert.Nil(t, m)
+}
+
+// TestProvide_RegistersUnderWellKnownKey verifies Provide stores metrics under "prometheus.metrics".
+func TestProvide_RegistersUnderWellKnownKey(t *testing.T) {
+	t.Parallel()
+
+	m, err := NewMetrics(prometheus.NewRegistry(), "app")
+	require.NoError(t, err)
+
+	reg := di.NewMapRegistry()
+	require.Same(t, reg, Provide(reg, m))
+
+	v, ok, err := reg.Resolve(nil, "prometheus.metrics")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Same(t, m, v)
+}
+
+//
+// -----------------------------------------------------------------------------
+// BuildMetricsCollector
+// -----------------------------------------------------------------------------
+
+// TestBuildMetricsCollector_Exposition verifies built/failure/missing series are scraped as expected.
+func TestBuildMetricsCollector_Exposition(t *testing.T) {
+	t.Parallel()
+
+	c := NewBuildMetricsCollector("odi")
+	r := prometheus.NewRegistry()
+	require.NoError(t, r.Register(c))
+
+	c.ObserveBuild("AlphaV4", time.Millisecond, nil)
+	c.ObserveBuild("CoreV4", time.Millisecond, errors.New("wiring incomplete"))
+	c.SetOptionalMissing("CoreV4", 2)
+
+	want := `
+# HELP odi_build_failures_total Failed facade builds (wiring incomplete or optional resolution errors).
+# TYPE odi_build_failures_total counter
+odi_build_failures_total{facade="CoreV4"} 1
+# HELP odi_optional_missing Optional dependencies missing from the registry at the facade's last build.
+# TYPE odi_optional_missing gauge
+odi_optional_missing{facade="CoreV4"} 2
+# HELP odi_services_built_total Successful facade builds.
+# TYPE odi_services_built_total counter
+odi_services_built_total{facade="AlphaV4"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(r, strings.NewReader(want),
+		"odi_build_failures_total", "odi_optional_missing", "odi_services_built_total"))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(c, "odi_build_duration_seconds"))
+}
+
+// TestBuildMetricsCollector_Track verifies Track returns the build error and records the outcome.
+func TestBuildMetricsCollector_Track(t *testing.T) {
+	t.Parallel()
+
+	c := NewBuildMetricsCollector("odi")
+	boom := errors.New("boom")
+
+	require.NoError(t, c.Track("AlphaV4", func() error { return nil }))
+	require.ErrorIs(t, c.Track("BetaV4", func() error { return boom }), boom)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.built.WithLabelValues("AlphaV4")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.failures.WithLabelValues("BetaV4")))
+}
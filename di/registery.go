@@ -1,8 +1,12 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 )
 
 // Registry provides optional dependencies at build time.
@@ -19,9 +23,76 @@ type Registry interface {
 	Resolve(cfg any, key string) (val any, ok bool, err error)
 }
 
+// RegistryCtx is a Registry that can honor a context, for registries backed by
+// remote config systems (Vault, Consul) that should time out cleanly during a
+// build. Generated BuildWithCtx resolves through ResolveCtx, which uses it when
+// the registry implements it.
+type RegistryCtx interface {
+	Registry
+	ResolveCtx(ctx context.Context, cfg any, key string) (val any, ok bool, err error)
+}
+
+// ResolveCtx resolves key from reg under ctx. It returns ctx.Err() if ctx is
+// already done, calls reg.ResolveCtx when reg implements RegistryCtx, and falls
+// back to the context-free Resolve otherwise.
+func ResolveCtx(ctx context.Context, reg Registry, cfg any, key string) (val any, ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if rc, isCtx := reg.(RegistryCtx); isCtx {
+		return rc.ResolveCtx(ctx, cfg, key)
+	}
+	return reg.Resolve(cfg, key)
+}
+
 // ErrRegistryPanic is returned if a registry implementation panics internally.
 var ErrRegistryPanic = errors.New("registry: panic during Resolve")
 
+// ErrRegistryMissing is returned by RegistryGet when the key is not provided.
+var ErrRegistryMissing = errors.New("registry: missing key")
+
+// RegistryTypeError is returned by RegistryGet when the value under Key is not a Want.
+type RegistryTypeError struct {
+	Key  string
+	Want string // e.g. "*v4.CounterMetrics"
+	Got  string // dynamic type of the value, "<nil>" for nil
+}
+
+// Error implements the error interface.
+func (e RegistryTypeError) Error() string {
+	// Example: registry: key "v4.metrics" has type *v4.NoopMetrics, want *v4.CounterMetrics
+	return "registry: key " + strconv.Quote(e.Key) + " has type " + e.Got + ", want " + e.Want
+}
+
+// RegistryGet resolves key from reg and asserts the value to D, replacing raw
+// `reg.MustGet(key).(T)` assertions in composition roots and tests.
+//
+// A missing key (or nil reg) wraps ErrRegistryMissing, a Resolve error is
+// returned wrapped with the key, and a value of the wrong type yields a
+// RegistryTypeError.
+func RegistryGet[D any](reg Registry, cfg any, key string) (D, error) {
+	var zero D
+	if reg == nil {
+		return zero, fmt.Errorf("%w %q", ErrRegistryMissing, key)
+	}
+	v, ok, err := reg.Resolve(cfg, key)
+	if err != nil {
+		return zero, fmt.Errorf("registry: key %q: %w", key, err)
+	}
+	if !ok {
+		return zero, fmt.Errorf("%w %q", ErrRegistryMissing, key)
+	}
+	d, ok := v.(D)
+	if !ok {
+		return zero, RegistryTypeError{
+			Key:  key,
+			Want: reflect.TypeFor[D]().String(),
+			Got:  fmt.Sprintf("%T", v),
+		}
+	}
+	return d, nil
+}
+
 // MapRegistry is a simple in-memory registry.
 // It ignores cfg (but keeps it in the signature so future registries can use it).
 type MapRegistry struct {
@@ -38,6 +109,28 @@ func (r *MapRegistry) Provide(key string, val any) *MapRegistry {
 	return r
 }
 
+// Remove deletes the value under key (a no-op if absent) and returns the
+// registry for chaining.
+func (r *MapRegistry) Remove(key string) *MapRegistry {
+	delete(r.items, key)
+	return r
+}
+
+// Keys returns the provided keys, sorted.
+func (r *MapRegistry) Keys() []string {
+	keys := make([]string, 0, len(r.items))
+	for k := range r.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns the number of provided keys.
+func (r *MapRegistry) Len() int {
+	return len(r.items)
+}
+
 // Resolve implements Registry and defensively converts panics into errors.
 func (r *MapRegistry) Resolve(_ any, key string) (val any, ok bool, err error) {
 	defer func() {
@@ -52,6 +145,15 @@ func (r *MapRegistry) Resolve(_ any, key string) (val any, ok bool, err error) {
 	return v, ok, nil
 }
 
+// ResolveCtx implements RegistryCtx; lookups are in-memory, so ctx is only
+// checked up front.
+func (r *MapRegistry) ResolveCtx(ctx context.Context, cfg any, key string) (val any, ok bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return r.Resolve(cfg, key)
+}
+
 // Get returns the value if present (no panic).
 func (r *MapRegistry) Get(key string) (any, bool) {
 	v, ok := r.items[key]
@@ -63,7 +165,7 @@ func (r *MapRegistry) Get(key string) (any, bool) {
 func (r *MapRegistry) MustGet(key string) any {
 	v, ok := r.items[key]
 	if !ok {
-		panic(fmt.Errorf("di: registry missing key %q", key))
+		panic(fmt.Errorf("%w %q", ErrRegistryMissing, key))
 	}
 	return v
 }
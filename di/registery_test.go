@@ -1,6 +1,7 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -148,7 +149,122 @@ func TestMustGet_Missing(t *testing.T) {
 
 	r := NewMapRegistry()
 
-	require.PanicsWithError(t, `di: registry missing key "missing"`, func() {
+	require.PanicsWithError(t, `registry: missing key "missing"`, func() {
 		_ = r.MustGet("missing")
 	})
 }
+
+//
+// -----------------------------------------------------------------------------
+// ResolveCtx
+// -----------------------------------------------------------------------------
+
+// ctxRegistry records the ctx it was resolved under.
+type ctxRegistry struct {
+	MapRegistry
+	got context.Context
+}
+
+func (r *ctxRegistry) ResolveCtx(ctx context.Context, cfg any, key string) (any, bool, error) {
+	r.got = ctx
+	return r.MapRegistry.Resolve(cfg, key)
+}
+
+// plainRegistry implements only Registry.
+type plainRegistry struct{ *MapRegistry }
+
+// TestResolveCtx verifies the RegistryCtx path, the Resolve fallback and done contexts.
+func TestResolveCtx(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "v")
+
+	cr := &ctxRegistry{MapRegistry: *NewMapRegistry().Provide("k", 1)}
+	v, ok, err := ResolveCtx(ctx, cr, nil, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, ctx, cr.got)
+
+	v, ok, err = ResolveCtx(ctx, plainRegistry{NewMapRegistry().Provide("k", 2)}, nil, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, v)
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, reg := range []Registry{cr, plainRegistry{NewMapRegistry()}} {
+		_, ok, err = ResolveCtx(done, reg, nil, "k")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, ok)
+	}
+
+	m := NewMapRegistry().Provide("k", 3)
+	v, ok, err = m.ResolveCtx(context.Background(), nil, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+	_, _, err = m.ResolveCtx(done, nil, "k")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+//
+// -----------------------------------------------------------------------------
+// Keys / Remove / Len
+// -----------------------------------------------------------------------------
+
+// TestKeysRemoveLen verifies key listing is sorted and Remove prunes entries.
+func TestKeysRemoveLen(t *testing.T) {
+	t.Parallel()
+
+	r := NewMapRegistry()
+	assert.Empty(t, r.Keys())
+	assert.Equal(t, 0, r.Len())
+
+	r.Provide("v4.tracer", 1).Provide("v4.metrics", 2).Provide("a", nil)
+	assert.Equal(t, []string{"a", "v4.metrics", "v4.tracer"}, r.Keys())
+	assert.Equal(t, 3, r.Len())
+
+	assert.Same(t, r, r.Remove("a").Remove("missing"))
+	assert.Equal(t, []string{"v4.metrics", "v4.tracer"}, r.Keys())
+	assert.Equal(t, 2, r.Len())
+
+	_, ok := r.Get("a")
+	assert.False(t, ok)
+}
+
+//
+// -----------------------------------------------------------------------------
+// RegistryGet
+// -----------------------------------------------------------------------------
+
+// TestRegistryGet covers the typed accessor's success and error paths.
+func TestRegistryGet(t *testing.T) {
+	t.Parallel()
+
+	db := &DB{DSN: "x"}
+	reg := NewMapRegistry().Provide("db", db).Provide("nil", nil)
+
+	got, err := RegistryGet[*DB](reg, nil, "db")
+	require.NoError(t, err)
+	assert.Same(t, db, got)
+
+	_, err = RegistryGet[*DB](reg, nil, "missing")
+	assert.ErrorIs(t, err, ErrRegistryMissing)
+	assert.EqualError(t, err, `registry: missing key "missing"`)
+
+	_, err = RegistryGet[*DB](nil, nil, "db")
+	assert.ErrorIs(t, err, ErrRegistryMissing)
+
+	_, err = RegistryGet[*Logger](reg, nil, "db")
+	assert.Equal(t, RegistryTypeError{Key: "db", Want: "*di.Logger", Got: "*di.DB"}, err)
+	assert.EqualError(t, err, `registry: key "db" has type *di.DB, want *di.Logger`)
+
+	_, err = RegistryGet[*DB](reg, nil, "nil")
+	assert.Equal(t, RegistryTypeError{Key: "nil", Want: "*di.DB", Got: "<nil>"}, err)
+
+	_, err = RegistryGet[*DB]((*MapRegistry)(nil), nil, "db")
+	assert.ErrorIs(t, err, ErrRegistryPanic)
+	assert.Contains(t, err.Error(), `registry: key "db": registry: panic during Resolve`)
+}
@@ -0,0 +1,96 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ConfigReloadable is implemented by services that apply a new config without a
+// restart. Generated <Root>Result.ReloadConfig calls it on every service of the
+// root that has it.
+type ConfigReloadable[C any] interface {
+	OnConfigChange(cfg C) error
+}
+
+// Reloader fans a new config out to registered callbacks, in registration order
+// (generated graphs register services in dependency order).
+type Reloader[C any] struct {
+	name string
+
+	mu   sync.Mutex
+	subs []reloadSub[C]
+}
+
+type reloadSub[C any] struct {
+	name string
+	fn   func(C) error
+}
+
+// NewReloader returns an empty Reloader; name prefixes its errors.
+func NewReloader[C any](name string) *Reloader[C] {
+	return &Reloader[C]{name: name}
+}
+
+// Register adds fn, called by Reload under name. A nil fn is ignored.
+func (r *Reloader[C]) Register(name string, fn func(C) error) *Reloader[C] {
+	if fn != nil {
+		r.mu.Lock()
+		r.subs = append(r.subs, reloadSub[C]{name: name, fn: fn})
+		r.mu.Unlock()
+	}
+	return r
+}
+
+// Reload validates cfg (see ValidateConfig) and passes it to every callback.
+//
+// It stops at the first failing callback, so services after it (its dependents)
+// keep the old config; the error names the callback. Concurrent Reloads are
+// serialized.
+func (r *Reloader[C]) Reload(cfg C) error {
+	if err := ValidateConfig(r.name, &cfg); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.subs {
+		if err := s.fn(cfg); err != nil {
+			return fmt.Errorf("%s: reload %s: %w", r.name, s.name, err)
+		}
+	}
+	return nil
+}
+
+// ReloadOnSignal calls load and then reload every time the process receives one
+// of sigs (default SIGHUP), until ctx is done. Failures go to onErr (if set) and
+// do not stop the loop, so a bad config file can be fixed and signaled again:
+//
+//	go di.ReloadOnSignal(ctx, config.LoadFromEnv, res.ReloadConfig, func(err error) {
+//		slog.Error("config reload failed", "error", err)
+//	})
+func ReloadOnSignal[C any](ctx context.Context, load func() (C, error), reload func(C) error, onErr func(error), sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			cfg, err := load()
+			if err == nil {
+				err = reload(cfg)
+			}
+			if err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package di_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+type reloadCfg struct{ Level string }
+
+func (c reloadCfg) Validate() error {
+	if c.Level == "" {
+		return errors.New("level is required")
+	}
+	return nil
+}
+
+// TestReloader verifies callbacks run in order and a failure stops the fan-out.
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	sub := func(name string, err error) func(reloadCfg) error {
+		return func(c reloadCfg) error {
+			calls = append(calls, name+"="+c.Level)
+			return err
+		}
+	}
+	boom := errors.New("boom")
+
+	r := di.NewReloader[reloadCfg]("App").
+		Register("db", sub("db", nil)).
+		Register("nil", nil).
+		Register("core", sub("core", nil))
+	require.NoError(t, r.Reload(reloadCfg{Level: "debug"}))
+	assert.Equal(t, []string{"db=debug", "core=debug"}, calls)
+
+	calls = nil
+	r.Register("api", sub("api", boom)).Register("web", sub("web", nil))
+	err := r.Reload(reloadCfg{Level: "info"})
+	require.ErrorIs(t, err, boom)
+	assert.EqualError(t, err, "App: reload api: boom")
+	assert.Equal(t, []string{"db=info", "core=info", "api=info"}, calls)
+
+	calls = nil
+	require.ErrorIs(t, r.Reload(reloadCfg{}), di.ErrInvalidConfig)
+	assert.Empty(t, calls, "invalid configs are not fanned out")
+}
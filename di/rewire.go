@@ -0,0 +1,58 @@
+package di
+
+import "slices"
+
+// Remove deletes the dependency recorded under key from Deps.
+//
+// It returns MissingDependencyError if the key is not recorded. Remove only
+// updates the bag: unbinding the dependency from Val is up to the caller (see
+// Rewire to replace it instead).
+func (s *Service[T]) Remove(key DependencyKey) error {
+	if s == nil || s.Val == nil {
+		return ErrNilTarget
+	}
+	if _, ok := s.Deps[key]; !ok {
+		return MissingDependencyError{Key: key}
+	}
+	delete(s.Deps, key)
+	s.order = slices.DeleteFunc(s.order, func(k DependencyKey) bool { return k == key })
+	return nil
+}
+
+// Rewire replaces the dependency recorded under key with dep and binds it onto
+// Val, for intentional runtime swaps (e.g. rotating a credentials provider).
+//
+// Unlike Injecting, the key must already be recorded: Rewire returns
+// MissingDependencyError otherwise, and ErrNilTarget, NilDependencyServiceError
+// or NilBindError for nil inputs. With WithHistory on, the rewire is recorded in
+// InjectionHistory.
+//
+// Rewire is not synchronized: callers swapping deps while Val is in use must
+// provide their own locking.
+func Rewire[T any, D any](
+	s *Service[T],
+	key DependencyKey,
+	dep *Service[D],
+	bind func(target *T, dependency *D),
+) error {
+	if s == nil || s.Val == nil {
+		return ErrNilTarget
+	}
+	if dep == nil || dep.Val == nil {
+		return NilDependencyServiceError{Key: key}
+	}
+	if bind == nil {
+		return NilBindError{Key: key}
+	}
+	if _, ok := s.Deps[key]; !ok {
+		return MissingDependencyError{Key: key}
+	}
+
+	d := dep.Val
+	s.Deps[key] = d
+	s.binding = key
+	bind(s.Val, d)
+	s.binding = ""
+	s.record(key)
+	return nil
+}
@@ -0,0 +1,67 @@
+package di_test
+
+import (
+	"testing"
+
+	"github.com/sghaida/odi/di"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRemove verifies removal of recorded deps and its errors.
+func TestRemove(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	svc := di.Init(func() *di.UserService { return &di.UserService{} })
+	_, err := svc.With(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }))
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Remove("db"))
+	assert.False(t, svc.Has("db"))
+	assert.Equal(t, di.MissingDependencyError{Key: "db"}, svc.Remove("db"))
+
+	var nilSvc *di.Service[di.UserService]
+	assert.ErrorIs(t, nilSvc.Remove("db"), di.ErrNilTarget)
+
+	// the key can be injected again
+	_, err = svc.With(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }))
+	require.NoError(t, err)
+}
+
+// TestRewire verifies swapping a recorded dep and the typed errors.
+func TestRewire(t *testing.T) {
+	t.Parallel()
+
+	bind := func(u *di.UserService, d *di.DB) { u.DB = d }
+	oldDB := di.Init(func() *di.DB { return &di.DB{DSN: "old"} })
+	newDB := di.Init(func() *di.DB { return &di.DB{DSN: "new"} })
+
+	svc := di.Init(func() *di.UserService { return &di.UserService{} }).WithHistory(0)
+	_, err := svc.With(di.Injecting(di.Key("db"), oldDB, bind))
+	require.NoError(t, err)
+
+	require.NoError(t, di.Rewire(svc, "db", newDB, bind))
+	assert.Same(t, newDB.Val, svc.Val.DB)
+	got, ok := di.GetAs[di.UserService, di.DB](svc, "db")
+	require.True(t, ok)
+	assert.Same(t, newDB.Val, got)
+	assert.Len(t, svc.InjectionHistory(), 2)
+
+	tests := []struct {
+		name    string
+		target  *di.Service[di.UserService]
+		key     di.DependencyKey
+		dep     *di.Service[di.DB]
+		bind    func(*di.UserService, *di.DB)
+		wantErr error
+	}{
+		{name: "nil_target", target: nil, key: "db", dep: newDB, bind: bind, wantErr: di.ErrNilTarget},
+		{name: "nil_dep", target: svc, key: "db", dep: nil, bind: bind, wantErr: di.NilDependencyServiceError{Key: "db"}},
+		{name: "nil_bind", target: svc, key: "db", dep: newDB, bind: nil, wantErr: di.NilBindError{Key: "db"}},
+		{name: "missing_key", target: svc, key: "cache", dep: newDB, bind: bind, wantErr: di.MissingDependencyError{Key: "cache"}},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.wantErr, di.Rewire(tc.target, tc.key, tc.dep, tc.bind), tc.name)
+	}
+}
@@ -0,0 +1,55 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Route is an HTTP handler and the pattern it is mounted under, in the syntax of
+// the Mux it is mounted on (e.g. "GET /users/{id}" for http.ServeMux).
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// RouteProvider is implemented by services that serve HTTP routes. Generated
+// <Root>Result.MountAll mounts the routes of every service implementing it, so a
+// service's routes are declared next to its handlers rather than in main.
+type RouteProvider interface {
+	Routes() []Route
+}
+
+// Mux is what routes are mounted on; *http.ServeMux and chi.Router satisfy it.
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// ErrDuplicateRoute is returned by MountRoutes when two routes share a pattern.
+var ErrDuplicateRoute = errors.New("di: duplicate route")
+
+// MountRoutes mounts the routes of providers on mux, in order. It validates every
+// route before mounting any, failing on an empty pattern, a nil handler or a
+// pattern repeated across providers (which http.ServeMux would panic on).
+func MountRoutes(mux Mux, providers ...RouteProvider) error {
+	var routes []Route
+	seen := map[string]bool{}
+	for _, p := range providers {
+		for _, rt := range p.Routes() {
+			switch {
+			case rt.Pattern == "":
+				return fmt.Errorf("di: %T: route with empty pattern", p)
+			case rt.Handler == nil:
+				return fmt.Errorf("di: %T: route %q has a nil handler", p, rt.Pattern)
+			case seen[rt.Pattern]:
+				return fmt.Errorf("%w: %q", ErrDuplicateRoute, rt.Pattern)
+			}
+			seen[rt.Pattern] = true
+			routes = append(routes, rt)
+		}
+	}
+	for _, rt := range routes {
+		mux.Handle(rt.Pattern, rt.Handler)
+	}
+	return nil
+}
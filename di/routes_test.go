@@ -0,0 +1,77 @@
+package di_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// routes is a RouteProvider serving its patterns with a handler echoing the pattern.
+type routes []string
+
+func (rs routes) Routes() []di.Route {
+	out := make([]di.Route, 0, len(rs))
+	for _, p := range rs {
+		p := p
+		out = append(out, di.Route{Pattern: p, Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(p))
+		})})
+	}
+	return out
+}
+
+type nilHandler struct{}
+
+func (nilHandler) Routes() []di.Route { return []di.Route{{Pattern: "/nil"}} }
+
+// TestMountRoutes verifies routes of every provider are served by the mux.
+func TestMountRoutes(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	require.NoError(t, di.MountRoutes(mux, routes{"GET /users/{id}"}, routes{"/healthz", "POST /orders"}))
+
+	for _, tc := range []struct{ method, path, want string }{
+		{http.MethodGet, "/users/7", "GET /users/{id}"},
+		{http.MethodGet, "/healthz", "/healthz"},
+		{http.MethodPost, "/orders", "POST /orders"},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.want, rec.Body.String(), tc.path)
+	}
+}
+
+// TestMountRoutes_Errors verifies invalid routes fail before anything is mounted.
+func TestMountRoutes_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		providers []di.RouteProvider
+		wantErr   string
+	}{
+		{name: "duplicate", providers: []di.RouteProvider{routes{"/a"}, routes{"/b", "/a"}}, wantErr: `di: duplicate route: "/a"`},
+		{name: "empty_pattern", providers: []di.RouteProvider{routes{""}}, wantErr: "empty pattern"},
+		{name: "nil_handler", providers: []di.RouteProvider{routes{"/ok"}, nilHandler{}}, wantErr: `route "/nil" has a nil handler`},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			err := di.MountRoutes(mux, tc.providers...)
+			require.ErrorContains(t, err, tc.wantErr)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+			assert.Equal(t, http.StatusNotFound, rec.Code, "nothing mounted")
+		})
+	}
+}
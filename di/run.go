@@ -0,0 +1,93 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Runnable is an application RunUntilSignal can start and stop, such as a type
+// wrapping a graph result.
+type Runnable interface {
+	StartAll(ctx context.Context) error
+	StopAll(ctx context.Context) error
+}
+
+// DefaultStopTimeout bounds StopAll unless WithStopTimeout sets another limit.
+const DefaultStopTimeout = 30 * time.Second
+
+// RunOption configures RunUntilSignal.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	stopTimeout time.Duration
+	signals     []os.Signal
+}
+
+// WithStopTimeout bounds StopAll by d (d <= 0: DefaultStopTimeout).
+func WithStopTimeout(d time.Duration) RunOption {
+	return func(c *runConfig) {
+		if d > 0 {
+			c.stopTimeout = d
+		}
+	}
+}
+
+// WithSignals replaces the signals RunUntilSignal stops on (default SIGINT and
+// SIGTERM).
+func WithSignals(sigs ...os.Signal) RunOption {
+	return func(c *runConfig) {
+		if len(sigs) > 0 {
+			c.signals = sigs
+		}
+	}
+}
+
+// RunUntilSignal starts app, blocks until the process receives SIGINT or SIGTERM
+// (or ctx is done) and then stops app, so a main shrinks to building the graph
+// and calling it:
+//
+//	app := NewServer(MustBuildAppV4(cfg, reg)) // StartAll/StopAll
+//	if err := di.RunUntilSignal(context.Background(), app); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// StartAll runs under a context canceled by the first signal. StopAll runs under
+// a context that keeps ctx's values but not its cancellation, bounded by the stop
+// timeout; it also runs when StartAll fails, so whatever started is stopped.
+// After the first signal the default handling is restored, so a second one
+// terminates the process without waiting for StopAll.
+//
+// If app also has Done() <-chan struct{} (graph results with consumers do; see
+// package stream), RunUntilSignal stops app once Done is closed as well, and
+// StopAll reports the failure.
+//
+// The returned error joins the StartAll and StopAll errors; a signal or a done
+// ctx is not an error.
+func RunUntilSignal(ctx context.Context, app Runnable, opts ...RunOption) error {
+	cfg := runConfig{stopTimeout: DefaultStopTimeout, signals: []os.Signal{os.Interrupt, syscall.SIGTERM}}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	sigCtx, stopSignals := signal.NotifyContext(ctx, cfg.signals...)
+	startErr := app.StartAll(sigCtx)
+	if startErr == nil {
+		var failed <-chan struct{}
+		if d, ok := app.(interface{ Done() <-chan struct{} }); ok {
+			failed = d.Done()
+		}
+		select {
+		case <-sigCtx.Done():
+		case <-failed:
+		}
+	}
+	stopSignals()
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.stopTimeout)
+	defer cancel()
+	return errors.Join(startErr, app.StopAll(stopCtx))
+}
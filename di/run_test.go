@@ -0,0 +1,104 @@
+package di_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+type ctxKeyRun struct{}
+
+// fakeApp records lifecycle calls and the contexts they ran under.
+type fakeApp struct {
+	startErr, stopErr error
+	started           chan struct{}
+	startCtx, stopCtx context.Context
+	stopCtxErr        error // stopCtx.Err() during StopAll
+	calls             []string
+}
+
+func newFakeApp() *fakeApp { return &fakeApp{started: make(chan struct{})} }
+
+func (a *fakeApp) StartAll(ctx context.Context) error {
+	a.calls = append(a.calls, "start")
+	a.startCtx = ctx
+	close(a.started)
+	return a.startErr
+}
+
+func (a *fakeApp) StopAll(ctx context.Context) error {
+	a.calls = append(a.calls, "stop")
+	a.stopCtx, a.stopCtxErr = ctx, ctx.Err()
+	return a.stopErr
+}
+
+// TestRunUntilSignal_ContextDone verifies a done ctx stops the app with a fresh,
+// bounded context that keeps ctx's values.
+func TestRunUntilSignal_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKeyRun{}, "v"))
+	app := newFakeApp()
+	go func() {
+		<-app.started
+		cancel()
+	}()
+
+	require.NoError(t, di.RunUntilSignal(ctx, app, di.WithStopTimeout(time.Minute)))
+	assert.Equal(t, []string{"start", "stop"}, app.calls)
+	require.Error(t, app.startCtx.Err())
+	require.NoError(t, app.stopCtxErr)
+	assert.Equal(t, "v", app.stopCtx.Value(ctxKeyRun{}))
+	deadline, ok := app.stopCtx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+// TestRunUntilSignal_Errors verifies start and stop errors are joined and a failed
+// start still stops the app.
+func TestRunUntilSignal_Errors(t *testing.T) {
+	t.Parallel()
+
+	startErr, stopErr := errors.New("start"), errors.New("stop")
+	app := newFakeApp()
+	app.startErr, app.stopErr = startErr, stopErr
+
+	err := di.RunUntilSignal(context.Background(), app)
+	require.ErrorIs(t, err, startErr)
+	require.ErrorIs(t, err, stopErr)
+	assert.Equal(t, []string{"start", "stop"}, app.calls)
+
+	deadline, ok := app.stopCtx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(di.DefaultStopTimeout), deadline, 5*time.Second)
+}
+
+// failingApp is a fakeApp whose Done channel closes when it fails.
+type failingApp struct {
+	*fakeApp
+	done chan struct{}
+}
+
+func (a failingApp) Done() <-chan struct{} { return a.done }
+
+// TestRunUntilSignal_Done verifies an app closing Done is stopped without a signal.
+func TestRunUntilSignal_Done(t *testing.T) {
+	t.Parallel()
+
+	failed := errors.New("consumer failed")
+	app := failingApp{fakeApp: newFakeApp(), done: make(chan struct{})}
+	app.stopErr = failed
+	go func() {
+		<-app.started
+		close(app.done)
+	}()
+
+	require.ErrorIs(t, di.RunUntilSignal(context.Background(), app), failed)
+	assert.Equal(t, []string{"start", "stop"}, app.calls)
+}
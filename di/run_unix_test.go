@@ -0,0 +1,77 @@
+//go:build unix
+
+package di_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestRunUntilSignal_Signal verifies a configured signal stops the app.
+func TestRunUntilSignal_Signal(t *testing.T) {
+	t.Parallel()
+
+	app := newFakeApp()
+	go func() {
+		<-app.started
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+
+	require.NoError(t, di.RunUntilSignal(context.Background(), app, di.WithSignals(syscall.SIGUSR1), di.WithStopTimeout(0)))
+	assert.Equal(t, []string{"start", "stop"}, app.calls)
+}
+
+// TestReloadOnSignal verifies each signal loads and reloads, and failures go to
+// onErr without stopping the loop.
+func TestReloadOnSignal(t *testing.T) {
+	t.Parallel()
+
+	// keep SIGUSR2 caught even before ReloadOnSignal subscribes
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGUSR2)
+	defer signal.Stop(guard)
+
+	loads := 0
+	load := func() (reloadCfg, error) {
+		loads++
+		if loads == 1 {
+			return reloadCfg{}, errors.New("bad file")
+		}
+		return reloadCfg{Level: "debug"}, nil
+	}
+	events := make(chan string, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		di.ReloadOnSignal(ctx, load,
+			func(c reloadCfg) error { events <- "reload " + c.Level; return nil },
+			func(err error) { events <- "error " + err.Error() },
+			syscall.SIGUSR2)
+	}()
+
+	for _, want := range []string{"error bad file", "reload debug"} {
+		got := ""
+		for i := 0; i < 200 && got == ""; i++ {
+			_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+			select {
+			case got = <-events:
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		require.Equal(t, want, got)
+	}
+
+	cancel()
+	<-done
+}
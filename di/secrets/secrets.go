@@ -0,0 +1,159 @@
+// Package secrets provides a di.Registry that resolves optional deps from a
+// secrets backend (Vault, AWS Secrets Manager, GCP Secret Manager, ...).
+//
+// It contains:
+//   - Backend: the minimal lookup interface a secret manager client is adapted to
+//   - Registry: serves keys under a configurable prefix ("secrets.") from a
+//     Backend, as Secret values, honoring the build context (di.RegistryCtx)
+//   - Fake: an in-memory Backend for tests and examples
+//
+// The package has no third-party dependencies; adapters for concrete secret
+// managers are a few lines implementing Backend.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sghaida/odi/di"
+)
+
+// DefaultPrefix is the registry key prefix used when New is given "".
+const DefaultPrefix = "secrets."
+
+// Backend looks up a secret by path (the registry key without the prefix).
+//
+// ok is false when the secret does not exist; err is reserved for backend
+// failures (network, permissions), which fail the build.
+type Backend interface {
+	GetSecret(ctx context.Context, path string) (value string, ok bool, err error)
+}
+
+// Secret is a resolved secret value. It redacts itself when formatted or
+// logged; call Reveal to get the plaintext.
+type Secret string
+
+// Reveal returns the plaintext value.
+func (s Secret) Reveal() string { return string(s) }
+
+// String implements fmt.Stringer and never returns the value.
+func (s Secret) String() string { return "[redacted]" }
+
+// GoString implements fmt.GoStringer (%#v) and never returns the value.
+func (s Secret) GoString() string { return "secrets.Secret([redacted])" }
+
+// Registry resolves keys under Prefix from a Backend. It ignores cfg and is
+// safe for concurrent use if the Backend is.
+type Registry struct {
+	backend Backend
+	prefix  string
+}
+
+var _ di.RegistryCtx = (*Registry)(nil)
+
+// New returns a Registry serving keys under prefix (DefaultPrefix if empty)
+// from b.
+func New(b Backend, prefix string) *Registry {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Registry{backend: b, prefix: prefix}
+}
+
+// Prefix returns the key prefix the registry serves.
+func (r *Registry) Prefix() string { return r.prefix }
+
+// Key returns the registry key for a secret path ("api/token" -> "secrets.api/token").
+func (r *Registry) Key(path string) string { return r.prefix + path }
+
+// Resolve implements di.Registry using context.Background().
+func (r *Registry) Resolve(cfg any, key string) (val any, ok bool, err error) {
+	return r.ResolveCtx(context.Background(), cfg, key)
+}
+
+// ResolveCtx implements di.RegistryCtx. Keys outside the prefix and missing
+// secrets return ok=false with a nil error; backend errors name the key.
+func (r *Registry) ResolveCtx(ctx context.Context, _ any, key string) (val any, ok bool, err error) {
+	path, found := strings.CutPrefix(key, r.prefix)
+	if !found || path == "" {
+		return nil, false, nil
+	}
+	v, ok, err := r.backend.GetSecret(ctx, path)
+	if err != nil {
+		return nil, false, fmt.Errorf("secrets: get %q: %w", path, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	return Secret(v), true, nil
+}
+
+// Fake is an in-memory Backend for tests. The zero value is empty and ready
+// to use; it is safe for concurrent use.
+type Fake struct {
+	mu      sync.RWMutex
+	secrets map[string]string
+	err     error
+	calls   int
+}
+
+var _ Backend = (*Fake)(nil)
+
+// NewFake returns a Fake holding a copy of secrets.
+func NewFake(secrets map[string]string) *Fake {
+	f := &Fake{}
+	for path, v := range secrets {
+		f.Set(path, v)
+	}
+	return f
+}
+
+// Set stores a secret and returns the fake for chaining.
+func (f *Fake) Set(path, value string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.secrets == nil {
+		f.secrets = map[string]string{}
+	}
+	f.secrets[path] = value
+	return f
+}
+
+// Delete removes a secret.
+func (f *Fake) Delete(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.secrets, path)
+}
+
+// FailWith makes every subsequent lookup return err (nil restores lookups).
+func (f *Fake) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Calls returns the number of GetSecret calls so far.
+func (f *Fake) Calls() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.calls
+}
+
+// GetSecret implements Backend. A done ctx is returned as an error, like a
+// real client would.
+func (f *Fake) GetSecret(ctx context.Context, path string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if f.err != nil {
+		return "", false, f.err
+	}
+	v, ok := f.secrets[path]
+	return v, ok, nil
+}
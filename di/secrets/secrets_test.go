@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+//
+// -----------------------------------------------------------------------------
+// Registry
+// -----------------------------------------------------------------------------
+
+// TestRegistry_Resolve verifies prefix handling, missing secrets and backend errors.
+func TestRegistry_Resolve(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFake(map[string]string{"api/token": "s3cr3t"})
+	reg := New(fake, "")
+	assert.Equal(t, DefaultPrefix, reg.Prefix())
+	assert.Equal(t, "secrets.api/token", reg.Key("api/token"))
+
+	v, ok, err := reg.Resolve(nil, "secrets.api/token")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, Secret("s3cr3t"), v)
+
+	for _, key := range []string{"secrets.missing", "other.api/token", "secrets."} {
+		v, ok, err = reg.Resolve(nil, key)
+		require.NoError(t, err, key)
+		assert.False(t, ok, key)
+		assert.Nil(t, v, key)
+	}
+	assert.Equal(t, 2, fake.Calls(), "keys outside the prefix must not reach the backend")
+
+	boom := errors.New("permission denied")
+	fake.FailWith(boom)
+	_, ok, err = reg.Resolve(nil, "secrets.api/token")
+	assert.False(t, ok)
+	require.ErrorIs(t, err, boom)
+	assert.EqualError(t, err, `secrets: get "api/token": permission denied`)
+}
+
+// TestRegistry_ResolveCtx verifies the build context reaches the backend.
+func TestRegistry_ResolveCtx(t *testing.T) {
+	t.Parallel()
+
+	reg := New(NewFake(map[string]string{"db/password": "pw"}), "vault:")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, ok, err := reg.ResolveCtx(ctx, nil, "vault:db/password")
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	v, ok, err := di.ResolveCtx(context.Background(), reg, nil, "vault:db/password")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "pw", v.(Secret).Reveal())
+}
+
+// TestRegistry_ResolveOptional verifies secrets wire as optional deps of generated facades.
+func TestRegistry_ResolveOptional(t *testing.T) {
+	t.Parallel()
+
+	reg := New(NewFake(map[string]string{"api/token": "t"}), "")
+
+	tok, ok, err := di.ResolveOptional[Secret](reg, nil, "ClientV4", "Token", "secrets.api/token")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "t", tok.Reveal())
+
+	_, ok, err = di.ResolveOptional[Secret](reg, nil, "ClientV4", "Token", "secrets.absent")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+//
+// -----------------------------------------------------------------------------
+// Secret / Fake
+// -----------------------------------------------------------------------------
+
+// TestSecret_Redacts verifies formatting never leaks the value.
+func TestSecret_Redacts(t *testing.T) {
+	t.Parallel()
+
+	s := Secret("s3cr3t")
+	assert.Equal(t, "s3cr3t", s.Reveal())
+	for _, format := range []string{"%s", "%v", "%#v", "%+v"} {
+		assert.NotContains(t, fmt.Sprintf(format, s), "s3cr3t", format)
+	}
+	assert.Equal(t, "[redacted]", s.String())
+	assert.Equal(t, "secrets.Secret([redacted])", s.GoString())
+}
+
+// TestFake_SetDelete verifies the zero value, chaining and deletion.
+func TestFake_SetDelete(t *testing.T) {
+	t.Parallel()
+
+	var f Fake
+	_, ok, err := f.GetSecret(context.Background(), "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	f.Set("a", "1").Set("b", "2")
+	v, ok, err := f.GetSecret(context.Background(), "b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", v)
+
+	f.Delete("b")
+	_, ok, _ = f.GetSecret(context.Background(), "b")
+	assert.False(t, ok)
+
+	f.FailWith(errors.New("down"))
+	f.FailWith(nil)
+	_, ok, err = f.GetSecret(context.Background(), "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 4, f.Calls())
+}
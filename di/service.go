@@ -11,7 +11,10 @@
 //   - Test-friendly: works well in unit tests and supports introspection via Deps.
 //
 // Notes on performance:
-//   - The success path is dominated by a map write and a function call.
+//   - The success path is dominated by a map write and a function call, plus an
+//     amortized append of the key for Keys (only the function call for untracked
+//     services; see WithoutTracking). Timestamped InjectionHistory is opt-in
+//     (see WithHistory).
 //   - Error paths avoid fmt.Errorf to keep failure handling inexpensive when used
 //     in benchmarks or for control flow (e.g., TryGetAs missing checks).
 package di
@@ -19,7 +22,9 @@ package di
 import (
 	"errors"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -83,14 +88,38 @@ type WrongTypeDependencyError struct {
 	// Key is the dependency key requested.
 	Key DependencyKey
 
-	// GotType is reflect.TypeOf(raw).String() for the stored value.
+	// GotType is reflect.TypeOf(raw).String() for the stored value. Type names
+	// are static strings, so setting it neither allocates nor formats.
 	GotType string
 }
 
-// Error implements the error interface.
+// Error implements the error interface. It renders the message with a single
+// allocation, since callers using TryGetAs for control flow may log it.
 func (e WrongTypeDependencyError) Error() string {
 	// Example: di: dependency "db" has wrong type (*mypkg.Logger)
-	return "di: dependency " + strconv.Quote(string(e.Key)) + " has wrong type (" + e.GotType + ")"
+	const prefix, middle = "di: dependency ", " has wrong type ("
+	var b strings.Builder
+	b.Grow(len(prefix) + len(e.Key) + 2 + len(middle) + len(e.GotType) + 1)
+	b.WriteString(prefix)
+	writeQuoted(&b, string(e.Key))
+	b.WriteString(middle)
+	b.WriteString(e.GotType)
+	b.WriteByte(')')
+	return b.String()
+}
+
+// writeQuoted writes strconv.Quote(s) to b, without the intermediate string
+// when s needs no escaping.
+func writeQuoted(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' || c > '~' || c == '"' || c == '\\' {
+			b.WriteString(strconv.Quote(s))
+			return
+		}
+	}
+	b.WriteByte('"')
+	b.WriteString(s)
+	b.WriteByte('"')
 }
 
 // NilDependencyServiceError indicates a nil dependency service for a specific key.
@@ -132,9 +161,26 @@ func (e NilBindError) Error() string {
 // any pointer type without restricting user code.
 //
 // Typed retrieval is available via GetAs / TryGetAs / MustGetAs.
+//
+// Tracking (recording into Deps) is on by default; see WithoutTracking.
 type Service[T any] struct {
 	Val  *T
 	Deps map[DependencyKey]any
+
+	untracked bool
+
+	// binding is the key whose bind function is running (see Recovering).
+	binding DependencyKey
+
+	// order lists the keys recorded by injectors, in injection order (see Keys);
+	// peak is the most entries Deps held after one of them (see Stats).
+	order []DependencyKey
+	peak  int
+
+	// history records successful injections in order when historyLimit > 0
+	// (see WithHistory).
+	history      []Injection
+	historyLimit int
 }
 
 // Init constructs a Service by calling ctor and initializing the dependency bag.
@@ -142,6 +188,51 @@ func Init[T any](ctor func() *T) *Service[T] {
 	return &Service[T]{Val: ctor(), Deps: make(map[DependencyKey]any)}
 }
 
+// InitUntracked constructs a Service without a dependency bag, in untracked mode
+// (see WithoutTracking). It is the allocation-light variant of Init for hot paths
+// that construct services per request.
+func InitUntracked[T any](ctor func() *T) *Service[T] {
+	return &Service[T]{Val: ctor(), untracked: true}
+}
+
+// InitE constructs a Service from a constructor that can fail (parsing config,
+// opening files). It returns ctor's error unchanged, and ErrNilTarget if ctor
+// returns a nil value without an error.
+func InitE[T any](ctor func() (*T, error)) (*Service[T], error) {
+	v, err := ctor()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, ErrNilTarget
+	}
+	return &Service[T]{Val: v, Deps: make(map[DependencyKey]any)}, nil
+}
+
+// MustInit is like InitE but panics on error. It is meant for composition roots
+// and tests where a failing constructor is fatal anyway.
+func MustInit[T any](ctor func() (*T, error)) *Service[T] {
+	s, err := InitE(ctor)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// WithoutTracking switches the Service to untracked mode and returns it.
+//
+// In untracked mode Injecting only validates and binds: it does not record the
+// dependency in Deps, so it allocates nothing on success but also cannot detect
+// duplicate keys, and Has/GetAs/TryGetAs do not see later injections.
+// Already recorded dependencies are kept.
+func (s *Service[T]) WithoutTracking() *Service[T] {
+	s.untracked = true
+	return s
+}
+
+// Tracking reports whether injections are recorded in Deps (the default).
+func (s *Service[T]) Tracking() bool { return s != nil && !s.untracked }
+
 // Value returns the constructed value pointer.
 func (s *Service[T]) Value() *T { return s.Val }
 
@@ -179,7 +270,8 @@ func (s *Service[T]) WithAll(deps ...Injector[T]) (*Service[T], error) {
 // Injecting builds an Injector that binds a dependency into a target.
 //
 // It records the dependency pointer in s.Deps[key], then calls bind to attach
-// the dependency to the target service implementation.
+// the dependency to the target service implementation. For untracked services
+// (see WithoutTracking) the Deps bookkeeping, including the duplicate check, is skipped.
 //
 // The returned injector fails if:
 //   - the target service (or its Val) is nil (ErrNilTarget)
@@ -201,6 +293,12 @@ func Injecting[T any, D any](
 		if bind == nil {
 			return NilBindError{Key: key}
 		}
+		if s.untracked {
+			s.binding = key
+			bind(s.Val, dep.Val)
+			s.binding = ""
+			return nil
+		}
 		if s.Deps == nil {
 			s.Deps = make(map[DependencyKey]any)
 		}
@@ -210,7 +308,10 @@ func Injecting[T any, D any](
 
 		d := dep.Val
 		s.Deps[key] = d
+		s.binding = key
 		bind(s.Val, d)
+		s.binding = ""
+		s.added(key)
 		return nil
 	}
 }
@@ -236,6 +337,7 @@ func (s *Service[T]) GetAny(key DependencyKey) (any, bool) {
 // GetAs returns the dependency typed as *D.
 //
 // ok is false if the key is missing or the stored value is not a *D.
+// For Transient keys (see InjectingTransient) it calls the recorded Factory[D].
 func GetAs[T any, D any](s *Service[T], key DependencyKey) (*D, bool) {
 	if s == nil || s.Deps == nil {
 		return nil, false
@@ -244,11 +346,15 @@ func GetAs[T any, D any](s *Service[T], key DependencyKey) (*D, bool) {
 	if !ok || raw == nil {
 		return nil, false
 	}
+	if factory, isFactory := raw.(Factory[D]); isFactory {
+		return factory(), true
+	}
 	d, ok := raw.(*D)
 	return d, ok
 }
 
-// TryGetAs returns the dependency typed as *D.
+// TryGetAs returns the dependency typed as *D, calling the recorded Factory[D]
+// for Transient keys.
 //
 // It returns:
 //   - MissingDependencyError if the key is not present
@@ -264,6 +370,9 @@ func TryGetAs[T any, D any](s *Service[T], key DependencyKey) (*D, error) {
 	if !ok || raw == nil {
 		return nil, MissingDependencyError{Key: key}
 	}
+	if factory, isFactory := raw.(Factory[D]); isFactory {
+		return factory(), nil
+	}
 	d, ok := raw.(*D)
 	if !ok {
 		return nil, WrongTypeDependencyError{
@@ -289,18 +398,26 @@ func MustGetAs[T any, D any](s *Service[T], key DependencyKey) *D {
 //
 // The constructed value pointer (Val) is shared.
 // The dependency bag (Deps) is copied into a new map so further wiring does not
-// mutate the original Service's Deps.
+// mutate the original Service's Deps. The tracking mode is preserved; untracked
+// clones without recorded deps get no bag.
 func (s *Service[T]) Clone() *Service[T] {
 	if s == nil {
 		return nil
 	}
-	cp := &Service[T]{Val: s.Val}
+	cp := &Service[T]{
+		Val:          s.Val,
+		untracked:    s.untracked,
+		order:        slices.Clone(s.order),
+		peak:         s.peak,
+		history:      slices.Clone(s.recentHistory()),
+		historyLimit: s.historyLimit,
+	}
 	if len(s.Deps) > 0 {
 		cp.Deps = make(map[DependencyKey]any, len(s.Deps))
 		for k, v := range s.Deps {
 			cp.Deps[k] = v
 		}
-	} else {
+	} else if !s.untracked {
 		cp.Deps = make(map[DependencyKey]any)
 	}
 	return cp
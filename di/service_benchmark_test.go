@@ -84,6 +84,32 @@ func BenchmarkWithAll_TwoDependencies(b *testing.B) {
 	})
 }
 
+func BenchmarkWithAll_TwoDependencies_Untracked(b *testing.B) {
+	db := newBenchDB()
+	logger := newBenchLogger()
+
+	injDB := benchInjDB(db)
+	injLogger := benchInjLogger(logger)
+	user := di.InitUntracked(func() *di.UserService { return &di.UserService{} })
+
+	b.ReportAllocs()
+	benchLoop(b, func() { _, _ = user.WithAll(injDB, injLogger) }) // bind-only: 0 allocs/op
+}
+
+func BenchmarkInitUntracked_TwoDependencies(b *testing.B) {
+	db := newBenchDB()
+	logger := newBenchLogger()
+
+	injDB := benchInjDB(db)
+	injLogger := benchInjLogger(logger)
+
+	b.ReportAllocs()
+	benchLoop(b, func() {
+		user := di.InitUntracked(func() *di.UserService { return &di.UserService{} })
+		_, _ = user.WithAll(injDB, injLogger)
+	})
+}
+
 func BenchmarkHas(b *testing.B) {
 	user, _ := benchUserWithDB()
 	benchLoop(b, func() { _ = user.Has(dbKey) })
@@ -109,6 +135,21 @@ func BenchmarkTryGetAs_Missing(b *testing.B) {
 	benchLoop(b, func() { _, _ = di.TryGetAs[di.UserService, di.DB](user, dbKey) })
 }
 
+func BenchmarkTryGetAs_WrongType(b *testing.B) {
+	user, _ := benchUserWithDB()
+	benchLoop(b, func() { _, _ = di.TryGetAs[di.UserService, di.Logger](user, dbKey) })
+}
+
+// BenchmarkTryGetAs_WrongTypeMessage also renders the error, as a caller
+// logging it would.
+func BenchmarkTryGetAs_WrongTypeMessage(b *testing.B) {
+	user, _ := benchUserWithDB()
+	benchLoop(b, func() {
+		_, err := di.TryGetAs[di.UserService, di.Logger](user, dbKey)
+		_ = err.Error()
+	})
+}
+
 func BenchmarkClone(b *testing.B) {
 	db := newBenchDB()
 	logger := newBenchLogger()
@@ -127,6 +168,32 @@ func BenchmarkMustGetAs_Success(b *testing.B) {
 	benchLoop(b, func() { _ = di.MustGetAs[di.UserService, di.DB](user, dbKey) })
 }
 
+func BenchmarkInjecting_Success(b *testing.B) {
+	db := newBenchDB()
+	inj := benchInjDB(db)
+	user := newBenchUser()
+
+	b.ReportAllocs()
+	benchLoop(b, func() {
+		_ = inj(user)
+		_ = user.Remove(dbKey) // reuse the bag: measure the injection, not the map
+	})
+}
+
+// BenchmarkInjecting_SuccessWithHistory is the success path with WithHistory on
+// (a time.Now and a bounded append per injection).
+func BenchmarkInjecting_SuccessWithHistory(b *testing.B) {
+	db := newBenchDB()
+	inj := benchInjDB(db)
+	user := newBenchUser().WithHistory(0)
+
+	b.ReportAllocs()
+	benchLoop(b, func() {
+		_ = inj(user)
+		_ = user.Remove(dbKey)
+	})
+}
+
 func BenchmarkInjecting_DuplicateKey(b *testing.B) {
 	db := newBenchDB()
 	user := newBenchUser()
@@ -159,3 +226,40 @@ func BenchmarkInjecting_NilBind(b *testing.B) {
 
 	benchLoop(b, func() { _ = inj(user) }) // ErrNilBind path
 }
+
+var benchUsersSink []*di.Service[di.UserService]
+
+func BenchmarkInit_Hundred(b *testing.B) {
+	users := make([]*di.Service[di.UserService], 100)
+
+	b.ReportAllocs()
+	benchLoop(b, func() {
+		for j := range users {
+			users[j] = newBenchUser()
+		}
+		benchUsersSink = users
+	})
+}
+
+func BenchmarkInitAll_Hundred(b *testing.B) {
+	ctors := make([]func() *di.UserService, 100)
+	for j := range ctors {
+		ctors[j] = func() *di.UserService { return &di.UserService{} }
+	}
+
+	b.ReportAllocs()
+	benchLoop(b, func() { benchUsersSink = di.InitAll(ctors...) })
+}
+
+func BenchmarkArena_Hundred(b *testing.B) {
+	users := make([]*di.Service[di.UserService], 100)
+
+	b.ReportAllocs()
+	benchLoop(b, func() {
+		a := di.NewArena[di.UserService](100)
+		for j := range users {
+			users[j] = a.Init(func() *di.UserService { return &di.UserService{} })
+		}
+		benchUsersSink = users
+	})
+}
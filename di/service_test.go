@@ -21,6 +21,34 @@ func TestInitAndValue(t *testing.T) {
 	assert.Empty(t, svc.Deps)
 }
 
+// InitE / MustInit
+func TestInitE_AndMustInit(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+
+	svc, err := di.InitE(func() (*di.DB, error) { return &di.DB{DSN: "postgres://"}, nil })
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://", svc.Value().DSN)
+	assert.NotNil(t, svc.Deps)
+	assert.True(t, svc.Tracking())
+
+	svc, err = di.InitE(func() (*di.DB, error) { return nil, boom })
+	assert.Nil(t, svc)
+	assert.ErrorIs(t, err, boom)
+
+	svc, err = di.InitE(func() (*di.DB, error) { return nil, nil })
+	assert.Nil(t, svc)
+	assert.ErrorIs(t, err, di.ErrNilTarget)
+
+	assert.NotPanics(t, func() {
+		di.MustInit(func() (*di.DB, error) { return &di.DB{}, nil })
+	})
+	assert.PanicsWithError(t, "boom", func() {
+		di.MustInit(func() (*di.DB, error) { return nil, boom })
+	})
+}
+
 // DependencyKey helper
 func TestKey(t *testing.T) {
 	t.Parallel()
@@ -414,6 +442,85 @@ func TestClone_BranchesAndCopyBehavior(t *testing.T) {
 	assert.False(t, ok)
 }
 
+// WithoutTracking – bind-only injection, no Deps bookkeeping, mode preserved by Clone
+func TestWithoutTracking(t *testing.T) {
+	t.Parallel()
+
+	key := di.Key("db")
+	db := di.Init(func() *di.DB { return &di.DB{DSN: "untracked"} })
+	inj := di.Injecting(key, db, func(u *di.UserService, d *di.DB) { u.DB = d })
+
+	tests := []struct {
+		name string
+		svc  func() *di.Service[di.UserService]
+	}{
+		{
+			name: "InitUntracked",
+			svc: func() *di.Service[di.UserService] {
+				return di.InitUntracked(func() *di.UserService { return &di.UserService{} })
+			},
+		},
+		{
+			name: "WithoutTracking",
+			svc: func() *di.Service[di.UserService] {
+				return di.Init(func() *di.UserService { return &di.UserService{} }).WithoutTracking()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			user := tc.svc()
+			assert.False(t, user.Tracking())
+
+			_, err := user.With(inj)
+			require.NoError(t, err)
+			assert.Same(t, db.Value(), user.Val.DB)
+			assert.False(t, user.Has(key), "untracked injections are not recorded")
+
+			// no duplicate detection without bookkeeping
+			_, err = user.With(inj)
+			require.NoError(t, err)
+
+			cp := user.Clone()
+			assert.False(t, cp.Tracking())
+			assert.Empty(t, cp.Deps)
+
+			// guards still apply
+			var nilDep di.NilDependencyServiceError
+			require.ErrorAs(t, di.Injecting[di.UserService, di.DB](key, nil, nil)(user), &nilDep)
+		})
+	}
+
+	assert.True(t, di.Init(func() *di.UserService { return &di.UserService{} }).Tracking())
+	var nilSvc *di.Service[di.UserService]
+	assert.False(t, nilSvc.Tracking())
+}
+
+// WithoutTracking – already recorded deps are kept
+func TestWithoutTracking_KeepsRecordedDeps(t *testing.T) {
+	t.Parallel()
+
+	db := di.Init(func() *di.DB { return &di.DB{} })
+	logger := di.Init(func() *di.Logger { return &di.Logger{} })
+	user := di.Init(func() *di.UserService { return &di.UserService{} })
+
+	_, err := user.With(di.Injecting(di.Key("db"), db, func(u *di.UserService, d *di.DB) { u.DB = d }))
+	require.NoError(t, err)
+
+	user.WithoutTracking()
+	_, err = user.With(di.Injecting(di.Key("logger"), logger, func(u *di.UserService, l *di.Logger) { u.Logger = l }))
+	require.NoError(t, err)
+
+	assert.True(t, user.Has(di.Key("db")))
+	assert.False(t, user.Has(di.Key("logger")))
+	assert.Same(t, logger.Value(), user.Val.Logger)
+	assert.True(t, user.Clone().Has(di.Key("db")))
+}
+
 // Errors – ensure Error() strings are covered in one place
 func TestErrors_StringAndTyping(t *testing.T) {
 	t.Parallel()
@@ -438,6 +545,11 @@ func TestErrors_StringAndTyping(t *testing.T) {
 			err:  di.WrongTypeDependencyError{Key: di.Key("logger"), GotType: "*di.Logger"},
 			want: `di: dependency "logger" has wrong type (*di.Logger)`,
 		},
+		{
+			name: "WrongTypeDependencyError_escaped_key",
+			err:  di.WrongTypeDependencyError{Key: di.Key("a\"b\n"), GotType: "int"},
+			want: `di: dependency "a\"b\n" has wrong type (int)`,
+		},
 		{
 			name: "NilDependencyServiceError",
 			err:  di.NilDependencyServiceError{Key: di.Key("db")},
@@ -26,3 +26,22 @@ type ServiceV2[T any] struct {
 func New[T any](ctor func() *T) ServiceV2[T] {
 	return ServiceV2[T]{ Val: ctor()}
 }
+
+// NewWith is New followed by the given option funcs, applied in order. It lets a
+// composition root list its manual wiring next to the constructor:
+//
+//	user := di.NewWith(NewUserService,
+//		func(u *UserService) { u.DB = db.Val },
+//		func(u *UserService) { u.Logger = logger.Val },
+//	)
+//
+// Nil options are skipped.
+func NewWith[T any](ctor func() *T, opts ...func(*T)) ServiceV2[T] {
+	s := New(ctor)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s.Val)
+		}
+	}
+	return s
+}
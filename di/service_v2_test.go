@@ -87,6 +87,28 @@ func TestNew_ServiceV2_Table(t *testing.T) {
 				require.Equal(t, "sqlite://changed", db2.Val.DSN)
 			},
 		},
+		{
+			name: "NewWith applies options in order and skips nil",
+			run: func(t *testing.T) {
+				t.Parallel()
+
+				db := di.New(func() *di.DB { return &di.DB{DSN: "postgres://prod"} })
+				var order []string
+
+				basket := di.NewWith(func() *di.BasketService { return &di.BasketService{} },
+					func(b *di.BasketService) { b.DB = db.Val; order = append(order, "db") },
+					nil,
+					func(b *di.BasketService) { b.Logger = &di.Logger{}; order = append(order, "logger") },
+				)
+
+				require.Same(t, db.Val, basket.Val.DB)
+				require.NotNil(t, basket.Val.Logger)
+				require.Equal(t, []string{"db", "logger"}, order)
+
+				plain := di.NewWith(func() *di.DB { return &di.DB{} })
+				require.NotNil(t, plain.Val)
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,9 @@
+//go:build odi_slim
+
+package di
+
+// Slim is true in builds tagged odi_slim. Generated (v4) facades then skip the
+// optional-dep diagnostics (the resolved/missing maps behind Explain and
+// WiringInfo) and Explain returns SlimExplain; validation is unaffected. The
+// checks are constant, so the compiler drops the diagnostics code.
+const Slim = true
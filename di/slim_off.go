@@ -0,0 +1,6 @@
+//go:build !odi_slim
+
+package di
+
+// Slim is false unless the build is tagged odi_slim (see slim.go).
+const Slim = false
@@ -0,0 +1,14 @@
+package di
+
+import "log/slog"
+
+// SlogKey is the well-known registry key for a *slog.Logger.
+//
+// Specs declare it with the generator shortcut {"slog": true} in "optional";
+// generated builders fall back to slog.Default() when the key is absent.
+const SlogKey = "odi.slog"
+
+// ProvideSlog stores l under SlogKey and returns the registry for chaining.
+func ProvideSlog(reg *MapRegistry, l *slog.Logger) *MapRegistry {
+	return reg.Provide(SlogKey, l)
+}
@@ -0,0 +1,25 @@
+package di_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestProvideSlog verifies the logger is stored under the well-known key.
+func TestProvideSlog(t *testing.T) {
+	t.Parallel()
+
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := di.ProvideSlog(di.NewMapRegistry(), l)
+
+	v, ok, err := reg.Resolve(nil, "odi.slog")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Same(t, l, v)
+}
@@ -0,0 +1,57 @@
+package di
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BuildStages runs stages in order. The funcs of one stage run concurrently and
+// the whole stage finishes before the next one starts, so a stage may depend on
+// everything built by earlier stages.
+//
+// It returns the first error (in func order) of the first failing stage; later
+// stages are not run. A panic in a func is returned as an error instead of
+// crashing the process from a background goroutine.
+//
+// Generated graph roots use it when the graph spec sets "parallel": true.
+func BuildStages(stages ...[]func() error) error {
+	for _, stage := range stages {
+		if err := runStage(stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runStage(stage []func() error) error {
+	if len(stage) == 1 {
+		return callRecovering(stage[0])
+	}
+
+	errs := make([]error, len(stage))
+	var wg sync.WaitGroup
+	wg.Add(len(stage))
+	for i, fn := range stage {
+		go func() {
+			defer wg.Done()
+			errs[i] = callRecovering(fn)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func callRecovering(fn func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("di: build panicked: %v", rec)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,112 @@
+package di_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestBuildStages_RunsStagesInOrder verifies every func runs and stages are sequential.
+func TestBuildStages_RunsStagesInOrder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		trace []string
+		first atomic.Int32
+	)
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		trace = append(trace, s)
+	}
+
+	err := di.BuildStages(
+		[]func() error{
+			func() error { first.Add(1); record("a"); return nil },
+			func() error { first.Add(1); record("b"); return nil },
+		},
+		[]func() error{
+			func() error {
+				assert.Equal(t, int32(2), first.Load(), "stage 0 must finish before stage 1")
+				record("c")
+				return nil
+			},
+		},
+	)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, trace[:2])
+	assert.Equal(t, "c", trace[2])
+}
+
+// TestBuildStages_Errors verifies error precedence, early stop and panic recovery.
+func TestBuildStages_Errors(t *testing.T) {
+	t.Parallel()
+
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+
+	tests := []struct {
+		name    string
+		stages  [][]func() error
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "no_stages",
+			stages: nil,
+		},
+		{
+			name: "first_error_in_func_order",
+			stages: [][]func() error{{
+				func() error { return nil },
+				func() error { return errA },
+				func() error { return errB },
+			}},
+			wantIs: errA,
+		},
+		{
+			name: "later_stages_skipped",
+			stages: [][]func() error{
+				{func() error { return errB }},
+				{func() error { panic("must not run") }},
+			},
+			wantIs: errB,
+		},
+		{
+			name: "panic_becomes_error",
+			stages: [][]func() error{
+				{func() error { return nil }, func() error { panic("boom") }},
+			},
+			wantErr: "di: build panicked: boom",
+		},
+		{
+			name:    "single_func_panic_becomes_error",
+			stages:  [][]func() error{{func() error { panic("solo") }}},
+			wantErr: "di: build panicked: solo",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := di.BuildStages(tc.stages...)
+			switch {
+			case tc.wantIs != nil:
+				require.ErrorIs(t, err, tc.wantIs)
+			case tc.wantErr != "":
+				require.EqualError(t, err, tc.wantErr)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
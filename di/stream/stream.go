@@ -0,0 +1,230 @@
+// Package stream runs long-lived consumers (Kafka readers, queue workers) built
+// by a dependency graph.
+//
+// A consumer is any service with Run(ctx) error that blocks until ctx is done.
+// A Group runs consumers in goroutines, restarts failed ones per a RestartPolicy
+// and, like an errgroup, stops the others once one fails for good:
+//
+//	g := stream.NewGroup("App", stream.DefaultRestartPolicy)
+//	g.Add("orders", res.Orders)
+//	if err := g.Start(ctx); err != nil {
+//		return err
+//	}
+//	<-g.Done()             // a consumer failed for good
+//	err := g.Stop(stopCtx) // cancels the consumers and waits for them
+//
+// Graph roots with consumer services generate this wiring as
+// <Root>Result.StartAll / StopAll, which di.RunUntilSignal drives.
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Consumer is a service that consumes until ctx is done. Returning nil after ctx
+// is done is a clean stop; returning early with an error triggers a restart.
+type Consumer interface {
+	Run(ctx context.Context) error
+}
+
+// RestartPolicy decides how often a failing consumer is restarted.
+type RestartPolicy struct {
+	// MaxRestarts is how many times a consumer is restarted after failing
+	// (0: never, negative: without limit).
+	MaxRestarts int
+
+	// Backoff is the wait before the first restart; it doubles per restart up
+	// to MaxBackoff (0: no cap).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRestartPolicy restarts a consumer up to 5 times, backing off from 1s to 30s.
+var DefaultRestartPolicy = RestartPolicy{MaxRestarts: 5, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// delay returns the backoff before restart number n (1-based).
+func (p RestartPolicy) delay(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n && d > 0; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// Errors returned by Group.
+var (
+	ErrStarted    = errors.New("stream: group already started")
+	ErrNotStarted = errors.New("stream: group not started")
+)
+
+// PanicError is a consumer panic, recovered and treated as a failure.
+type PanicError struct {
+	Consumer string
+	Value    any
+}
+
+func (e PanicError) Error() string {
+	return fmt.Sprintf("stream: consumer %s panicked: %v", e.Consumer, e.Value)
+}
+
+type member struct {
+	name string
+	c    Consumer
+}
+
+// Group runs consumers; see the package documentation. A Group is started once.
+type Group struct {
+	name   string
+	policy RestartPolicy
+
+	// OnRestart, if set, is called before a failed consumer is restarted.
+	OnRestart func(consumer string, restart int, err error)
+
+	mu       sync.Mutex
+	members  []member
+	started  bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	done     chan struct{}
+	doneOnce sync.Once
+	err      error
+}
+
+// NewGroup returns an empty group; name prefixes its errors.
+func NewGroup(name string, policy RestartPolicy) *Group {
+	return &Group{name: name, policy: policy, done: make(chan struct{})}
+}
+
+// Add registers a consumer under name. Nil consumers (e.g. conditional services
+// that were not built) are skipped. Add panics after Start.
+func (g *Group) Add(name string, c Consumer) {
+	if c == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.started {
+		panic("stream: Add after Start")
+	}
+	g.members = append(g.members, member{name: name, c: c})
+}
+
+// Len returns the number of consumers added.
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.members)
+}
+
+// Start launches every consumer and returns. Consumers run under a context that
+// keeps ctx's values but not its cancellation: they stop on Stop or when one of
+// them fails for good.
+func (g *Group) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.started {
+		return ErrStarted
+	}
+	g.started = true
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	g.cancel = cancel
+	for _, m := range g.members {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			g.supervise(runCtx, m)
+		}()
+	}
+	return nil
+}
+
+// supervise runs m until runCtx is done, m stops cleanly or it fails for good.
+func (g *Group) supervise(ctx context.Context, m member) {
+	for restarts := 0; ; restarts++ {
+		err := run(ctx, m)
+		if ctx.Err() != nil || err == nil {
+			return
+		}
+		if g.policy.MaxRestarts >= 0 && restarts >= g.policy.MaxRestarts {
+			g.fail(fmt.Errorf("%s: consumer %s failed after %d restarts: %w", g.name, m.name, restarts, err))
+			return
+		}
+		if g.OnRestart != nil {
+			g.OnRestart(m.name, restarts+1, err)
+		}
+		t := time.NewTimer(g.policy.delay(restarts + 1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func run(ctx context.Context, m member) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = PanicError{Consumer: m.name, Value: v}
+		}
+	}()
+	return m.c.Run(ctx)
+}
+
+// fail records the group's first permanent failure and stops the other consumers.
+func (g *Group) fail(err error) {
+	g.doneOnce.Do(func() {
+		g.mu.Lock()
+		g.err = err
+		cancel := g.cancel
+		g.mu.Unlock()
+		cancel()
+		close(g.done)
+	})
+}
+
+// Done is closed once a consumer fails for good.
+func (g *Group) Done() <-chan struct{} { return g.done }
+
+// Err returns the first permanent failure, or nil.
+func (g *Group) Err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
+
+// Stop cancels the consumers and waits for them to return or ctx to be done. It
+// returns the group's failure (see Err) joined with ctx's error on timeout.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.started {
+		g.mu.Unlock()
+		return ErrNotStarted
+	}
+	cancel := g.cancel
+	g.mu.Unlock()
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return g.Err()
+	case <-ctx.Done():
+		return errors.Join(g.Err(), fmt.Errorf("%s: consumers did not stop: %w", g.name, ctx.Err()))
+	}
+}
@@ -0,0 +1,141 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di/stream"
+)
+
+// blocking consumes until ctx is done.
+type blocking struct{ runs atomic.Int32 }
+
+func (c *blocking) Run(ctx context.Context) error {
+	c.runs.Add(1)
+	<-ctx.Done()
+	return nil
+}
+
+// flaky fails its first fails runs, then blocks.
+type flaky struct {
+	fails int32
+	runs  atomic.Int32
+}
+
+func (c *flaky) Run(ctx context.Context) error {
+	if c.runs.Add(1) <= c.fails {
+		return errors.New("broker unavailable")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+type panicking struct{}
+
+func (panicking) Run(context.Context) error { panic("boom") }
+
+var fast = stream.RestartPolicy{MaxRestarts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+// TestGroup_StartStop verifies consumers run until Stop and stop cleanly.
+func TestGroup_StartStop(t *testing.T) {
+	t.Parallel()
+
+	a, b := &blocking{}, &blocking{}
+	g := stream.NewGroup("App", fast)
+	g.Add("a", a)
+	g.Add("b", b)
+	g.Add("nil", nil)
+	assert.Equal(t, 2, g.Len())
+
+	require.ErrorIs(t, g.Stop(context.Background()), stream.ErrNotStarted)
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, g.Start(ctx))
+	require.ErrorIs(t, g.Start(ctx), stream.ErrStarted)
+	cancel() // consumers outlive the start context
+
+	require.Eventually(t, func() bool { return a.runs.Load() == 1 && b.runs.Load() == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, g.Stop(context.Background()))
+	require.NoError(t, g.Err())
+	assert.Panics(t, func() { g.Add("late", a) })
+}
+
+// TestGroup_Restart verifies failed consumers are restarted within the policy.
+func TestGroup_Restart(t *testing.T) {
+	t.Parallel()
+
+	c := &flaky{fails: 2}
+	var restarts atomic.Int32
+	g := stream.NewGroup("App", fast)
+	g.OnRestart = func(name string, n int, err error) {
+		assert.Equal(t, "orders", name)
+		restarts.Store(int32(n))
+	}
+	g.Add("orders", c)
+	require.NoError(t, g.Start(context.Background()))
+
+	require.Eventually(t, func() bool { return c.runs.Load() == 3 }, time.Second, time.Millisecond)
+	require.NoError(t, g.Stop(context.Background()))
+	assert.Equal(t, int32(2), restarts.Load())
+}
+
+// TestGroup_Failure verifies a consumer exhausting its restarts closes Done and
+// stops the others, errgroup-style.
+func TestGroup_Failure(t *testing.T) {
+	t.Parallel()
+
+	other := &blocking{}
+	g := stream.NewGroup("App", fast)
+	g.Add("orders", &flaky{fails: 100})
+	g.Add("other", other)
+	require.NoError(t, g.Start(context.Background()))
+
+	select {
+	case <-g.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("group did not fail")
+	}
+	require.ErrorContains(t, g.Err(), "App: consumer orders failed after 3 restarts: broker unavailable")
+	require.ErrorIs(t, g.Stop(context.Background()), g.Err())
+}
+
+// TestGroup_Panic verifies panics are recovered as PanicError failures.
+func TestGroup_Panic(t *testing.T) {
+	t.Parallel()
+
+	g := stream.NewGroup("App", stream.RestartPolicy{})
+	g.Add("p", panicking{})
+	require.NoError(t, g.Start(context.Background()))
+	<-g.Done()
+
+	var pe stream.PanicError
+	require.ErrorAs(t, g.Err(), &pe)
+	assert.Equal(t, "p", pe.Consumer)
+	assert.Equal(t, "boom", pe.Value)
+}
+
+// stubborn ignores cancellation for a while.
+type stubborn struct{}
+
+func (stubborn) Run(context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+// TestGroup_StopTimeout verifies Stop gives up when ctx is done first.
+func TestGroup_StopTimeout(t *testing.T) {
+	t.Parallel()
+
+	g := stream.NewGroup("App", fast)
+	g.Add("s", stubborn{})
+	require.NoError(t, g.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, g.Stop(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,234 @@
+package di
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WiringInfo is a snapshot of one generated facade's wiring state.
+//
+// Generated facades return it from WiringInfo(); generated graph roots collect
+// one per service (stamped with the service var and build time) for WiringHandler.
+type WiringInfo struct {
+	Service  string `json:"service,omitempty"` // graph var (set by the graph root)
+	Facade   string `json:"facade"`
+	Spec     string `json:"spec"`
+	SpecHash string `json:"specHash"`
+
+	Injected         []string          `json:"injected"`
+	OptionalResolved map[string]string `json:"optionalResolved,omitempty"` // registry key -> dynamic type
+	OptionalMissing  map[string]string `json:"optionalMissing,omitempty"`  // registry key -> reason
+
+	// UnsafeConsumers received the implementation pointer from UnsafeImpl (see
+	// WithUnsafe); audits use it to find services wired via the unsafe path.
+	UnsafeConsumers []string `json:"unsafeConsumers,omitempty"`
+
+	BuiltAt time.Time `json:"builtAt,omitzero"`
+}
+
+// NewWiringInfo builds a WiringInfo from a facade's bookkeeping maps.
+// The maps are copied and the injected names sorted, so the snapshot does not
+// alias (or race with) the builder.
+func NewWiringInfo(facade, spec, specHash string, injected map[string]bool, resolved, missing map[string]string) WiringInfo {
+	info := WiringInfo{
+		Facade:   facade,
+		Spec:     spec,
+		SpecHash: specHash,
+		Injected: make([]string, 0, len(injected)),
+	}
+	for name, ok := range injected {
+		if ok {
+			info.Injected = append(info.Injected, name)
+		}
+	}
+	sort.Strings(info.Injected)
+
+	if len(resolved) > 0 {
+		info.OptionalResolved = make(map[string]string, len(resolved))
+		for k, v := range resolved {
+			info.OptionalResolved[k] = v
+		}
+	}
+	if len(missing) > 0 {
+		info.OptionalMissing = make(map[string]string, len(missing))
+		for k, v := range missing {
+			info.OptionalMissing[k] = v
+		}
+	}
+	return info
+}
+
+// Built returns a copy of w stamped with the graph service var and build time.
+func (w WiringInfo) Built(service string, at time.Time) WiringInfo {
+	w.Service = service
+	w.BuiltAt = at
+	return w
+}
+
+// BuiltWiring is the WiringInfo a generated graph root records for service: the
+// facade's own WiringInfo (spec "introspection") stamped with service and at, or
+// just the stamp for a facade generated without it.
+func BuiltWiring(facade any, service string, at time.Time) WiringInfo {
+	if f, ok := facade.(interface{ WiringInfo() WiringInfo }); ok {
+		return f.WiringInfo().Built(service, at)
+	}
+	return WiringInfo{}.Built(service, at)
+}
+
+// WithUnsafe returns a copy of w listing the consumers of the facade's
+// UnsafeImpl pointer.
+func (w WiringInfo) WithUnsafe(consumers []string) WiringInfo {
+	if len(consumers) > 0 {
+		w.UnsafeConsumers = append([]string(nil), consumers...)
+	}
+	return w
+}
+
+// WiringReport describes a built graph root. It is the JSON body served by
+// WiringHandler.
+type WiringReport struct {
+	Root      string       `json:"root"`
+	Graph     string       `json:"graph"`
+	GraphHash string       `json:"graphHash"`
+	Services  []WiringInfo `json:"services"` // build order
+}
+
+// WiringHandler serves report as indented JSON.
+//
+// Mount it on an internal/admin listener only: it exposes service names,
+// spec paths and registry keys.
+func WiringHandler(report WiringReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	})
+}
+
+// WiringManifest is the static dependency inventory of one generated facade, as
+// declared in its spec. Generated facades return it from WiringManifest(), so
+// audits can list, for example, every service with an "external" dependency.
+type WiringManifest struct {
+	Facade   string        `json:"facade"`
+	Impl     string        `json:"impl"`
+	Spec     string        `json:"spec"`
+	SpecHash string        `json:"specHash"`
+	Deps     []ManifestDep `json:"deps"` // required deps, then optional ones
+}
+
+// ManifestDep is one dependency of a WiringManifest.
+type ManifestDep struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Optional    bool     `json:"optional,omitempty"`
+	RegistryKey string   `json:"registryKey,omitempty"` // optional deps only
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// HasTag reports whether the dep carries tag.
+func (d ManifestDep) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Tagged returns the deps carrying tag, in manifest order.
+func (m WiringManifest) Tagged(tag string) []ManifestDep {
+	var out []ManifestDep
+	for _, d := range m.Deps {
+		if d.HasTag(tag) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Tags returns the distinct tags of all deps, sorted.
+func (m WiringManifest) Tags() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, d := range m.Deps {
+		for _, t := range d.Tags {
+			if !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ExplainTags renders the tagged deps of m for Explain output; it is empty when
+// no dep is tagged.
+func ExplainTags(m WiringManifest) string {
+	var sb strings.Builder
+	for _, d := range m.Deps {
+		if len(d.Tags) == 0 {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("tags:\n")
+		}
+		name := d.Name
+		if d.Optional {
+			name += " (optional)"
+		}
+		fmt.Fprintf(&sb, "  - %s => %s\n", name, strings.Join(d.Tags, ", "))
+	}
+	return sb.String()
+}
+
+// SpecInfo is the spec metadata of one generated facade: where it was generated
+// from and what it requires, as structured data. Generated facades return it
+// from <FacadeName>SpecInfo(), so runtime tooling (debug endpoints, doctors) can
+// reason about wiring without reading spec files.
+type SpecInfo struct {
+	Facade   string         `json:"facade"`
+	Impl     string         `json:"impl"`
+	Spec     string         `json:"spec"`
+	SpecHash string         `json:"specHash"`
+	Required []string       `json:"required"`
+	Optional []SpecOptional `json:"optional"`
+	Methods  []SpecMethod   `json:"methods"`
+}
+
+// SpecOptional is one optional dep of a SpecInfo.
+type SpecOptional struct {
+	Name        string `json:"name"`
+	RegistryKey string `json:"registryKey"`
+}
+
+// SpecMethod is one method of a SpecInfo with the required deps it needs built
+// (its spec "requires"; none when empty).
+type SpecMethod struct {
+	Name     string   `json:"name"`
+	Requires []string `json:"requires,omitempty"`
+}
+
+// Method returns the method named name.
+func (s SpecInfo) Method(name string) (SpecMethod, bool) {
+	for _, m := range s.Methods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return SpecMethod{}, false
+}
+
+// OptionalKeys returns the registry keys of the optional deps, in spec order.
+func (s SpecInfo) OptionalKeys() []string {
+	out := make([]string, 0, len(s.Optional))
+	for _, o := range s.Optional {
+		out = append(out, o.RegistryKey)
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package di_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sghaida/odi/di"
+)
+
+// TestNewWiringInfo_CopiesAndSorts verifies injected names are sorted, false entries dropped and maps copied.
+func TestNewWiringInfo_CopiesAndSorts(t *testing.T) {
+	t.Parallel()
+
+	injected := map[string]bool{"Beta": true, "Alpha": true, "Gone": false}
+	resolved := map[string]string{"v4.tracer": "*v4.PrintTracer"}
+	missing := map[string]string{"v4.metrics": "used defaultExpr"}
+
+	info := di.NewWiringInfo("CoreV4", "specs/core.inject.json", "abc", injected, resolved, missing)
+
+	assert.Equal(t, "CoreV4", info.Facade)
+	assert.Equal(t, "specs/core.inject.json", info.Spec)
+	assert.Equal(t, "abc", info.SpecHash)
+	assert.Equal(t, []string{"Alpha", "Beta"}, info.Injected)
+	assert.Equal(t, resolved, info.OptionalResolved)
+	assert.Equal(t, missing, info.OptionalMissing)
+
+	resolved["v4.other"] = "x"
+	assert.NotContains(t, info.OptionalResolved, "v4.other", "snapshot must not alias builder maps")
+}
+
+// TestNewWiringInfo_EmptyMapsOmitted verifies empty optional maps stay nil.
+func TestNewWiringInfo_EmptyMapsOmitted(t *testing.T) {
+	t.Parallel()
+
+	info := di.NewWiringInfo("AlphaV4", "a.json", "h", nil, map[string]string{}, nil)
+	assert.Empty(t, info.Injected)
+	assert.NotNil(t, info.Injected)
+	assert.Nil(t, info.OptionalResolved)
+	assert.Nil(t, info.OptionalMissing)
+}
+
+// TestWiringInfo_Built verifies Built stamps service and time on a copy.
+func TestWiringInfo_Built(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	base := di.WiringInfo{Facade: "CoreV4"}
+	got := base.Built("core", at)
+
+	assert.Equal(t, "core", got.Service)
+	assert.Equal(t, at, got.BuiltAt)
+	assert.Empty(t, base.Service)
+}
+
+// TestBuiltWiring verifies facades without WiringInfo still get a stamped entry.
+func TestBuiltWiring(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := di.BuiltWiring(struct{}{}, "alpha", at)
+	assert.Equal(t, di.WiringInfo{Service: "alpha", BuiltAt: at}, got)
+
+	got = di.BuiltWiring(introspected{}, "core", at)
+	assert.Equal(t, "CoreV4", got.Facade)
+	assert.Equal(t, "core", got.Service)
+	assert.Equal(t, at, got.BuiltAt)
+}
+
+type introspected struct{}
+
+func (introspected) WiringInfo() di.WiringInfo { return di.WiringInfo{Facade: "CoreV4"} }
+
+// TestWiringInfo_WithUnsafe verifies the consumers are copied and omitted when empty.
+func TestWiringInfo_WithUnsafe(t *testing.T) {
+	t.Parallel()
+
+	consumers := []string{"alpha", di.UnnamedConsumer}
+	got := di.WiringInfo{Facade: "BetaV4"}.WithUnsafe(consumers)
+	consumers[0] = "changed"
+	assert.Equal(t, []string{"alpha", di.UnnamedConsumer}, got.UnsafeConsumers)
+	assert.Nil(t, di.WiringInfo{}.WithUnsafe(nil).UnsafeConsumers)
+}
+
+// TestWiringHandler_ServesReport verifies the handler round-trips the report as JSON.
+func TestWiringHandler_ServesReport(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := di.WiringReport{
+		Root:      "BuildAppV4",
+		Graph:     "specs/graph.json",
+		GraphHash: "g",
+		Services: []di.WiringInfo{
+			di.NewWiringInfo("CoreV4", "specs/core.inject.json", "c", map[string]bool{"Alpha": true}, nil, nil).Built("core", at),
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	di.WiringHandler(report).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/wiring", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got di.WiringReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, report, got)
+	assert.NotContains(t, rec.Body.String(), "optionalResolved")
+}
+
+// TestWiringManifest verifies tag queries and the Explain rendering of tags.
+func TestWiringManifest(t *testing.T) {
+	t.Parallel()
+
+	m := di.WiringManifest{
+		Facade: "CoreV4",
+		Deps: []di.ManifestDep{
+			{Name: "DB", Type: "*DB", Tags: []string{"stateful", "pii"}},
+			{Name: "Clock", Type: "Clock"},
+			{Name: "Payments", Type: "PaymentsClient", Optional: true, RegistryKey: "payments", Tags: []string{"external", "pii"}},
+		},
+	}
+
+	assert.Equal(t, []string{"external", "pii", "stateful"}, m.Tags())
+	pii := m.Tagged("pii")
+	require.Len(t, pii, 2)
+	assert.Equal(t, "DB", pii[0].Name)
+	assert.Equal(t, "Payments", pii[1].Name)
+	assert.Empty(t, m.Tagged("none"))
+
+	assert.Equal(t, "tags:\n  - DB => stateful, pii\n  - Payments (optional) => external, pii\n", di.ExplainTags(m))
+	assert.Empty(t, di.ExplainTags(di.WiringManifest{Deps: []di.ManifestDep{{Name: "Clock"}}}))
+
+	raw, err := json.Marshal(m.Deps[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Clock", "type": "Clock"}`, string(raw))
+}
+
+// TestSpecInfo verifies method lookup and optional keys.
+func TestSpecInfo(t *testing.T) {
+	t.Parallel()
+
+	info := di.SpecInfo{
+		Facade:   "CoreV4",
+		Required: []string{"Alpha", "Beta"},
+		Optional: []di.SpecOptional{{Name: "Tracer", RegistryKey: "v4.tracer"}, {Name: "Logger", RegistryKey: "odi.slog"}},
+		Methods:  []di.SpecMethod{{Name: "Process", Requires: []string{"Alpha"}}, {Name: "Ping"}},
+	}
+
+	m, ok := info.Method("Process")
+	require.True(t, ok)
+	assert.Equal(t, []string{"Alpha"}, m.Requires)
+	_, ok = info.Method("Missing")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"v4.tracer", "odi.slog"}, info.OptionalKeys())
+	assert.Empty(t, di.SpecInfo{}.OptionalKeys())
+
+	raw, err := json.Marshal(info.Methods)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"Process","requires":["Alpha"]},{"name":"Ping"}]`, string(raw))
+}
@@ -4,32 +4,45 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/sghaida/odi/di"
 	"github.com/sghaida/odi/examples/v4/config"
 )
 
 //go:generate go run ../../cmd/di2 -spec specs/alpha.inject.json -out alpha_v4.gen.go
 // Alpha depends on Beta (required) and participates in a cycle Alpha <-> Beta.
 //
-// The cycle is safe because business calls reduce Depth until reaching 0.
+// The cycle is safe because business calls reduce Depth until reaching 0. A
+// runaway Depth is stopped by cycleGuard on the cycle edges; the spec's
+// recursionGuard only counts calls made through the facades.
 type Alpha struct {
 	cfg  config.Config
 	beta *Beta // required (cycle edge)
 }
 
+// cycleGuard bounds Alpha <-> Beta recursion where the cycle edge is crossed.
+// Graph wiring injects the implementations into each other, so their calls never
+// pass through the facades' guarded methods and must be guarded here.
+var cycleGuard = di.DepthGuard(16)
+
 // NewAlpha is the constructor used by the generated facade (AlphaV4).
 func NewAlpha(cfg config.Config) *Alpha { return &Alpha{cfg: cfg} }
 
 // DoAlpha demonstrates:
 // - Alpha needs Beta (cycle edge)
-// - it calls Beta with Depth-1 to avoid infinite recursion
+// - it calls Beta with Depth-1 to avoid infinite recursion, through cycleGuard
 func (a *Alpha) DoAlpha(ctx context.Context, req AlphaRequest) (AlphaResponse, error) {
 	if req.Depth <= 0 {
 		return AlphaResponse{Value: req.X + 10}, nil
 	}
 
-	out, err := a.beta.DoBeta(ctx, BetaRequest{
-		Input: fmt.Sprintf("alpha:%d", req.X),
-		Depth: req.Depth - 1,
+	var out BetaResponse
+	err := cycleGuard(ctx, di.MethodCall{Facade: "Alpha", Method: "DoBeta"}, func(ctx context.Context) error {
+		var err error
+		out, err = a.beta.DoBeta(ctx, BetaRequest{
+			Input: fmt.Sprintf("alpha:%d", req.X),
+			Depth: req.Depth - 1,
+		})
+		return err
 	})
 	if err != nil {
 		return AlphaResponse{}, err
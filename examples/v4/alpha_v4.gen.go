@@ -1,41 +1,54 @@
 // Code generated by (di v2); DO NOT EDIT.
 // Spec: specs/alpha.inject.json
-// Spec-SHA256: afd262a9627a67551a443862be272716c420f807fa22888c4b36cbe77bd6af93
+// Spec-SHA256: bf586097827d90fbde523b82ec2ed6a23f8adc2e6161759d3bc6c96e505faa0c
 
 package v4
 
 import (
 	"context"
-	"fmt"
+	"maps"
+
 	di "github.com/sghaida/odi/di"
 	config "github.com/sghaida/odi/examples/v4/config"
-	"strings"
 )
 
 // AlphaV4InjectPolicyOnOverwrite controls behavior when a required dep is injected twice.
 // NOTE: generated as a var to allow unit tests to cover all branches.
 var AlphaV4InjectPolicyOnOverwrite = "error"
 
+// Required dep bits for buildScoped (fixed order: reqAlphaV4Names).
+const (
+	reqAlphaV4Beta uint64 = 1 << iota
+
+	reqAlphaV4All = 1<<1 - 1
+)
+
+var reqAlphaV4Names = [...]string{
+	"Beta",
+}
+
 type AlphaV4 struct {
-	cfg config.Config
-	svc *Alpha
+	cfg    config.Config
+	cfgErr error // config Validate() failure, reported by Build
+	svc    *Alpha
 
 	injected map[string]bool
 
-	// Optional wiring diagnostics (best-effort)
-	optionalResolved map[string]string
-	optionalMissing  map[string]string
+	// escapes lists who received svc from UnsafeImpl/UnsafeImplFor and who still
+	// hold an implementation Reset replaced (Build fails until ResetUnsafe).
+	escapes di.Escapes
 }
 
 // NewAlphaV4 creates a new builder/facade.
 // You must call Build()/BuildWith()/MustBuild() before calling business methods.
+// If the config has a Validate() error method, it runs here and a failure is
+// returned by Build (see di.ValidateConfig).
 func NewAlphaV4(cfg config.Config) *AlphaV4 {
 	return &AlphaV4{
-		cfg:              cfg,
-		svc:              NewAlpha(cfg),
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
+		cfg:      cfg,
+		cfgErr:   di.ValidateConfig("AlphaV4", &cfg),
+		svc:      NewAlpha(cfg),
+		injected: make(map[string]bool, 1),
 	}
 }
 
@@ -43,36 +56,51 @@ func NewAlphaV4(cfg config.Config) *AlphaV4 {
 // Useful for tests and branching wiring paths.
 func (b *AlphaV4) Clone() *AlphaV4 {
 	nb := &AlphaV4{
-		cfg:              b.cfg,
-		svc:              b.svc,
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-	for k, v := range b.injected {
-		nb.injected[k] = v
-	}
-	for k, v := range b.optionalResolved {
-		nb.optionalResolved[k] = v
-	}
-	for k, v := range b.optionalMissing {
-		nb.optionalMissing[k] = v
+		cfg:      b.cfg,
+		cfgErr:   b.cfgErr,
+		svc:      b.svc,
+		injected: maps.Clone(b.injected),
+		escapes:  b.escapes.Clone(),
 	}
 	return nb
 }
 
 // Reset discards injected bookkeeping and recreates the underlying implementation.
+// If UnsafeImpl handed out the previous implementation, its holders keep the old
+// pointer: Build then fails with di.ErrStaleImpl until ResetUnsafe is used instead.
 func (b *AlphaV4) Reset() *AlphaV4 {
-	b.svc = NewAlpha(b.cfg)
-	b.injected = map[string]bool{}
-	b.optionalResolved = map[string]string{}
-	b.optionalMissing = map[string]string{}
+	b.escapes.Reset()
+	b.recreate()
 	return b
 }
 
+// ResetUnsafe is Reset for an implementation handed out by UnsafeImpl: it returns
+// the new implementation and the consumers holding the previous one (named with
+// UnsafeImplFor), which the caller must re-wire, and clears the stale state.
+func (b *AlphaV4) ResetUnsafe() (*Alpha, []string) {
+	orphaned := b.escapes.Orphans()
+	b.recreate()
+	return b.svc, orphaned
+}
+
+func (b *AlphaV4) recreate() {
+	b.svc = NewAlpha(b.cfg)
+	clear(b.injected)
+}
+
 // UnsafeImpl returns the underlying implementation pointer for composition root wiring.
 // It must NOT be used to call business methods before Build()/MustBuild().
-func (b *AlphaV4) UnsafeImpl() *Alpha { return b.svc }
+func (b *AlphaV4) UnsafeImpl() *Alpha {
+	b.escapes.Add("")
+	return b.svc
+}
+
+// UnsafeImplFor is UnsafeImpl recording consumer as a holder of the pointer, so
+// ResetUnsafe, Explain and di.StaleImplError can name it.
+func (b *AlphaV4) UnsafeImplFor(consumer string) *Alpha {
+	b.escapes.Add(consumer)
+	return b.svc
+}
 
 // Inject allows custom wiring for advanced usage.
 // Prefer InjectX methods for required deps.
@@ -86,19 +114,12 @@ func (b *AlphaV4) Inject(fn func(*Alpha)) *AlphaV4 {
 // TryInjectBeta injects the required dependency Beta.
 // Unlike InjectBeta, it returns an error instead of panicking.
 func (b *AlphaV4) TryInjectBeta(dep *Beta) (*AlphaV4, error) {
-	switch AlphaV4InjectPolicyOnOverwrite {
-	case "error":
-		if b.injected["Beta"] {
-			return nil, fmt.Errorf("AlphaV4: duplicate inject Beta")
-		}
-	case "ignore":
-		if b.injected["Beta"] {
-			return b, nil
-		}
-	case "overwrite":
-		// allow overwriting
-	default:
-		return nil, fmt.Errorf("AlphaV4: invalid injectPolicy.onOverwrite=%s", AlphaV4InjectPolicyOnOverwrite)
+	ok, err := di.CheckInject("AlphaV4", AlphaV4InjectPolicyOnOverwrite, "Beta", b.injected["Beta"])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b, nil
 	}
 	b.svc.beta = dep
 	b.injected["Beta"] = true
@@ -118,33 +139,25 @@ func (b *AlphaV4) InjectBeta(dep *Beta) *AlphaV4 {
 // Missing returns the list of missing required dependency names at this moment.
 // This is useful for debug UX before calling Build().
 func (b *AlphaV4) Missing() []string {
-	missing := []string{}
-	if b.svc.beta == nil {
-		missing = append(missing, "Beta")
-	}
-	return missing
+	return di.MissingNames(reqAlphaV4Names[:], b.missingMask(reqAlphaV4All))
 }
 
-// Explain returns a human-friendly summary of the wiring state.
+// Explain returns a human-friendly summary of the wiring state (di.SlimExplain
+// in odi_slim builds).
 func (b *AlphaV4) Explain() string {
-	var sb strings.Builder
-	m := b.Missing()
-	if len(m) == 0 {
-		sb.WriteString("required: complete\n")
-	} else {
-		sb.WriteString(fmt.Sprintf("required: missing=%v\n", m))
+	if di.Slim {
+		return di.SlimExplain
 	}
-	return sb.String()
+	return di.ExplainWiring(b.Missing(), nil, nil) + b.escapes.Explain()
 }
 
 func (b *AlphaV4) Build() (*Alpha, error) {
-	return b.buildScoped("Build", nil)
+	return b.buildScoped("Build", reqAlphaV4All)
 }
 
-// NOTE: Registry.Resolve must be (val any, ok bool, err error)
+// BuildWith is Build: without optional deps there is nothing to resolve from reg.
 func (b *AlphaV4) BuildWith(reg di.Registry) (*Alpha, error) {
-
-	return b.buildScoped("BuildWith", nil)
+	return b.buildScoped("BuildWith", reqAlphaV4All)
 }
 
 func (b *AlphaV4) MustBuild() *Alpha {
@@ -155,54 +168,54 @@ func (b *AlphaV4) MustBuild() *Alpha {
 	return svc
 }
 
-func (b *AlphaV4) buildScoped(ctx string, reqNames []string) (*Alpha, error) {
-	missing := []string{}
-
-	isMissingBeta := b.svc.beta == nil
-
-	check := func(name string, isMissing bool) {
-		if isMissing {
-			missing = append(missing, name)
-		}
+// missingMask returns the reqAlphaV4* bits in need whose dep is not wired.
+func (b *AlphaV4) missingMask(need uint64) uint64 {
+	var missing uint64
+	if need&reqAlphaV4Beta != 0 && b.svc.beta == nil {
+		missing |= reqAlphaV4Beta
 	}
+	return missing
+}
 
-	if reqNames == nil {
-
-		check("Beta", isMissingBeta)
-
-	} else {
-		for _, n := range reqNames {
-			switch n {
-
-			case "Beta":
-				check("Beta", isMissingBeta)
-
-			}
-		}
+// buildScoped returns the implementation if every required dep in need (a mask of
+// reqAlphaV4* bits) is wired.
+func (b *AlphaV4) buildScoped(ctx string, need uint64) (*Alpha, error) {
+	if b.cfgErr != nil {
+		return nil, b.cfgErr
 	}
-
-	if len(missing) > 0 {
-		return nil, fmt.Errorf("%s: wiring incomplete (ctx=%s, missing=%v, spec=%s)",
-			"AlphaV4", ctx, missing, "afd262a9627a67551a443862be272716c420f807fa22888c4b36cbe77bd6af93")
+	if err := b.escapes.CheckStale("AlphaV4", ctx); err != nil {
+		return nil, err
+	}
+	if missing := b.missingMask(need); missing != 0 {
+		return nil, di.WiringIncomplete("AlphaV4", ctx, "bf586097827d90fbde523b82ec2ed6a23f8adc2e6161759d3bc6c96e505faa0c", reqAlphaV4Names[:], missing)
 	}
 	return b.svc, nil
 }
 
+// decorateAlphaV4DoAlpha is the decorator chain of AlphaV4.DoAlpha.
+var decorateAlphaV4DoAlpha = di.Chain(
+	di.DepthGuard(16),
+)
+
 func (b *AlphaV4) DoAlpha(
 	ctx context.Context,
 	req AlphaRequest,
 ) (AlphaResponse, error) {
-	svc, err := b.buildScoped("DoAlpha", []string{
-		"Beta",
-	})
+	svc, err := b.buildScoped("DoAlpha", reqAlphaV4Beta)
 	if err != nil {
 		var zero0 AlphaResponse
 
 		return zero0, err
 	}
 
-	return svc.DoAlpha(
-		ctx,
-		req,
-	)
+	var out0 AlphaResponse
+	err = decorateAlphaV4DoAlpha(ctx, di.MethodCall{Facade: "AlphaV4", Method: "DoAlpha"}, func(ctx context.Context) error {
+		var err error
+		out0, err = svc.DoAlpha(
+			ctx,
+			req,
+		)
+		return err
+	})
+	return out0, err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/sghaida/odi/di"
 	"github.com/sghaida/odi/examples/v4/config"
 )
 
@@ -20,15 +21,20 @@ func NewBeta(cfg config.Config) *Beta { return &Beta{cfg: cfg} }
 
 // DoBeta demonstrates:
 // - Beta needs Alpha (cycle edge)
-// - it calls Alpha with Depth-1 to avoid infinite recursion
+// - it calls Alpha with Depth-1 to avoid infinite recursion, through cycleGuard
 func (b *Beta) DoBeta(ctx context.Context, req BetaRequest) (BetaResponse, error) {
 	if req.Depth <= 0 {
 		return BetaResponse{Output: "beta:base:" + req.Input}, nil
 	}
 
-	a, err := b.alpha.DoAlpha(ctx, AlphaRequest{
-		X:     len(req.Input),
-		Depth: req.Depth - 1,
+	var a AlphaResponse
+	err := cycleGuard(ctx, di.MethodCall{Facade: "Beta", Method: "DoAlpha"}, func(ctx context.Context) error {
+		var err error
+		a, err = b.alpha.DoAlpha(ctx, AlphaRequest{
+			X:     len(req.Input),
+			Depth: req.Depth - 1,
+		})
+		return err
 	})
 	if err != nil {
 		return BetaResponse{}, err
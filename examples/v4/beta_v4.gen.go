@@ -1,41 +1,54 @@
 // Code generated by (di v2); DO NOT EDIT.
 // Spec: specs/beta.inject.json
-// Spec-SHA256: 8147bf8aca6e83ef858e201740e050e146b4df41a3081ac4daf0983e038c6962
+// Spec-SHA256: efb12a1e2746be10bc9a7157a8de345d0273c3ca83b9b9ea5472bcecea7958f6
 
 package v4
 
 import (
 	"context"
-	"fmt"
+	"maps"
+
 	di "github.com/sghaida/odi/di"
 	config "github.com/sghaida/odi/examples/v4/config"
-	"strings"
 )
 
 // BetaV4InjectPolicyOnOverwrite controls behavior when a required dep is injected twice.
 // NOTE: generated as a var to allow unit tests to cover all branches.
 var BetaV4InjectPolicyOnOverwrite = "error"
 
+// Required dep bits for buildScoped (fixed order: reqBetaV4Names).
+const (
+	reqBetaV4Alpha uint64 = 1 << iota
+
+	reqBetaV4All = 1<<1 - 1
+)
+
+var reqBetaV4Names = [...]string{
+	"Alpha",
+}
+
 type BetaV4 struct {
-	cfg config.Config
-	svc *Beta
+	cfg    config.Config
+	cfgErr error // config Validate() failure, reported by Build
+	svc    *Beta
 
 	injected map[string]bool
 
-	// Optional wiring diagnostics (best-effort)
-	optionalResolved map[string]string
-	optionalMissing  map[string]string
+	// escapes lists who received svc from UnsafeImpl/UnsafeImplFor and who still
+	// hold an implementation Reset replaced (Build fails until ResetUnsafe).
+	escapes di.Escapes
 }
 
 // NewBetaV4 creates a new builder/facade.
 // You must call Build()/BuildWith()/MustBuild() before calling business methods.
+// If the config has a Validate() error method, it runs here and a failure is
+// returned by Build (see di.ValidateConfig).
 func NewBetaV4(cfg config.Config) *BetaV4 {
 	return &BetaV4{
-		cfg:              cfg,
-		svc:              NewBeta(cfg),
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
+		cfg:      cfg,
+		cfgErr:   di.ValidateConfig("BetaV4", &cfg),
+		svc:      NewBeta(cfg),
+		injected: make(map[string]bool, 1),
 	}
 }
 
@@ -43,36 +56,51 @@ func NewBetaV4(cfg config.Config) *BetaV4 {
 // Useful for tests and branching wiring paths.
 func (b *BetaV4) Clone() *BetaV4 {
 	nb := &BetaV4{
-		cfg:              b.cfg,
-		svc:              b.svc,
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-	for k, v := range b.injected {
-		nb.injected[k] = v
-	}
-	for k, v := range b.optionalResolved {
-		nb.optionalResolved[k] = v
-	}
-	for k, v := range b.optionalMissing {
-		nb.optionalMissing[k] = v
+		cfg:      b.cfg,
+		cfgErr:   b.cfgErr,
+		svc:      b.svc,
+		injected: maps.Clone(b.injected),
+		escapes:  b.escapes.Clone(),
 	}
 	return nb
 }
 
 // Reset discards injected bookkeeping and recreates the underlying implementation.
+// If UnsafeImpl handed out the previous implementation, its holders keep the old
+// pointer: Build then fails with di.ErrStaleImpl until ResetUnsafe is used instead.
 func (b *BetaV4) Reset() *BetaV4 {
-	b.svc = NewBeta(b.cfg)
-	b.injected = map[string]bool{}
-	b.optionalResolved = map[string]string{}
-	b.optionalMissing = map[string]string{}
+	b.escapes.Reset()
+	b.recreate()
 	return b
 }
 
+// ResetUnsafe is Reset for an implementation handed out by UnsafeImpl: it returns
+// the new implementation and the consumers holding the previous one (named with
+// UnsafeImplFor), which the caller must re-wire, and clears the stale state.
+func (b *BetaV4) ResetUnsafe() (*Beta, []string) {
+	orphaned := b.escapes.Orphans()
+	b.recreate()
+	return b.svc, orphaned
+}
+
+func (b *BetaV4) recreate() {
+	b.svc = NewBeta(b.cfg)
+	clear(b.injected)
+}
+
 // UnsafeImpl returns the underlying implementation pointer for composition root wiring.
 // It must NOT be used to call business methods before Build()/MustBuild().
-func (b *BetaV4) UnsafeImpl() *Beta { return b.svc }
+func (b *BetaV4) UnsafeImpl() *Beta {
+	b.escapes.Add("")
+	return b.svc
+}
+
+// UnsafeImplFor is UnsafeImpl recording consumer as a holder of the pointer, so
+// ResetUnsafe, Explain and di.StaleImplError can name it.
+func (b *BetaV4) UnsafeImplFor(consumer string) *Beta {
+	b.escapes.Add(consumer)
+	return b.svc
+}
 
 // Inject allows custom wiring for advanced usage.
 // Prefer InjectX methods for required deps.
@@ -86,19 +114,12 @@ func (b *BetaV4) Inject(fn func(*Beta)) *BetaV4 {
 // TryInjectAlpha injects the required dependency Alpha.
 // Unlike InjectAlpha, it returns an error instead of panicking.
 func (b *BetaV4) TryInjectAlpha(dep *Alpha) (*BetaV4, error) {
-	switch BetaV4InjectPolicyOnOverwrite {
-	case "error":
-		if b.injected["Alpha"] {
-			return nil, fmt.Errorf("BetaV4: duplicate inject Alpha")
-		}
-	case "ignore":
-		if b.injected["Alpha"] {
-			return b, nil
-		}
-	case "overwrite":
-		// allow overwriting
-	default:
-		return nil, fmt.Errorf("BetaV4: invalid injectPolicy.onOverwrite=%s", BetaV4InjectPolicyOnOverwrite)
+	ok, err := di.CheckInject("BetaV4", BetaV4InjectPolicyOnOverwrite, "Alpha", b.injected["Alpha"])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b, nil
 	}
 	b.svc.alpha = dep
 	b.injected["Alpha"] = true
@@ -118,33 +139,25 @@ func (b *BetaV4) InjectAlpha(dep *Alpha) *BetaV4 {
 // Missing returns the list of missing required dependency names at this moment.
 // This is useful for debug UX before calling Build().
 func (b *BetaV4) Missing() []string {
-	missing := []string{}
-	if b.svc.alpha == nil {
-		missing = append(missing, "Alpha")
-	}
-	return missing
+	return di.MissingNames(reqBetaV4Names[:], b.missingMask(reqBetaV4All))
 }
 
-// Explain returns a human-friendly summary of the wiring state.
+// Explain returns a human-friendly summary of the wiring state (di.SlimExplain
+// in odi_slim builds).
 func (b *BetaV4) Explain() string {
-	var sb strings.Builder
-	m := b.Missing()
-	if len(m) == 0 {
-		sb.WriteString("required: complete\n")
-	} else {
-		sb.WriteString(fmt.Sprintf("required: missing=%v\n", m))
+	if di.Slim {
+		return di.SlimExplain
 	}
-	return sb.String()
+	return di.ExplainWiring(b.Missing(), nil, nil) + b.escapes.Explain()
 }
 
 func (b *BetaV4) Build() (*Beta, error) {
-	return b.buildScoped("Build", nil)
+	return b.buildScoped("Build", reqBetaV4All)
 }
 
-// NOTE: Registry.Resolve must be (val any, ok bool, err error)
+// BuildWith is Build: without optional deps there is nothing to resolve from reg.
 func (b *BetaV4) BuildWith(reg di.Registry) (*Beta, error) {
-
-	return b.buildScoped("BuildWith", nil)
+	return b.buildScoped("BuildWith", reqBetaV4All)
 }
 
 func (b *BetaV4) MustBuild() *Beta {
@@ -155,54 +168,54 @@ func (b *BetaV4) MustBuild() *Beta {
 	return svc
 }
 
-func (b *BetaV4) buildScoped(ctx string, reqNames []string) (*Beta, error) {
-	missing := []string{}
-
-	isMissingAlpha := b.svc.alpha == nil
-
-	check := func(name string, isMissing bool) {
-		if isMissing {
-			missing = append(missing, name)
-		}
+// missingMask returns the reqBetaV4* bits in need whose dep is not wired.
+func (b *BetaV4) missingMask(need uint64) uint64 {
+	var missing uint64
+	if need&reqBetaV4Alpha != 0 && b.svc.alpha == nil {
+		missing |= reqBetaV4Alpha
 	}
+	return missing
+}
 
-	if reqNames == nil {
-
-		check("Alpha", isMissingAlpha)
-
-	} else {
-		for _, n := range reqNames {
-			switch n {
-
-			case "Alpha":
-				check("Alpha", isMissingAlpha)
-
-			}
-		}
+// buildScoped returns the implementation if every required dep in need (a mask of
+// reqBetaV4* bits) is wired.
+func (b *BetaV4) buildScoped(ctx string, need uint64) (*Beta, error) {
+	if b.cfgErr != nil {
+		return nil, b.cfgErr
 	}
-
-	if len(missing) > 0 {
-		return nil, fmt.Errorf("%s: wiring incomplete (ctx=%s, missing=%v, spec=%s)",
-			"BetaV4", ctx, missing, "8147bf8aca6e83ef858e201740e050e146b4df41a3081ac4daf0983e038c6962")
+	if err := b.escapes.CheckStale("BetaV4", ctx); err != nil {
+		return nil, err
+	}
+	if missing := b.missingMask(need); missing != 0 {
+		return nil, di.WiringIncomplete("BetaV4", ctx, "efb12a1e2746be10bc9a7157a8de345d0273c3ca83b9b9ea5472bcecea7958f6", reqBetaV4Names[:], missing)
 	}
 	return b.svc, nil
 }
 
+// decorateBetaV4DoBeta is the decorator chain of BetaV4.DoBeta.
+var decorateBetaV4DoBeta = di.Chain(
+	di.DepthGuard(16),
+)
+
 func (b *BetaV4) DoBeta(
 	ctx context.Context,
 	DoBeta BetaRequest,
 ) (BetaResponse, error) {
-	svc, err := b.buildScoped("DoBeta", []string{
-		"Alpha",
-	})
+	svc, err := b.buildScoped("DoBeta", reqBetaV4Alpha)
 	if err != nil {
 		var zero0 BetaResponse
 
 		return zero0, err
 	}
 
-	return svc.DoBeta(
-		ctx,
-		DoBeta,
-	)
+	var out0 BetaResponse
+	err = decorateBetaV4DoBeta(ctx, di.MethodCall{Facade: "BetaV4", Method: "DoBeta"}, func(ctx context.Context) error {
+		var err error
+		out0, err = svc.DoBeta(
+			ctx,
+			DoBeta,
+		)
+		return err
+	})
+	return out0, err
 }
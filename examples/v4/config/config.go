@@ -1,46 +1,29 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"strconv"
+	"errors"
+
+	diconfig "github.com/sghaida/odi/di/config"
 )
 
+// Config is loaded by di/config: tag defaults, then ODI_-prefixed env vars.
+// Generated facades call Validate when they are constructed.
 type Config struct {
-	Env       string
-	LogPrefix string
-	TimeoutMs int
+	Env       string `env:"ENV" default:"local"`
+	LogPrefix string `env:"LOG_PREFIX" default:"v4"`
+	TimeoutMs int    `env:"TIMEOUT_MS" default:"10000"`
 }
 
-// LoadFromEnv is intentionally simple for examples.
-// You can expand this later (flags, files, etc.).
-func LoadFromEnv() (Config, error) {
-	cfg := Config{
-		Env:       getenv("ODI_ENV", "local"),
-		LogPrefix: getenv("ODI_LOG_PREFIX", "v4"),
-		TimeoutMs: getenvInt("ODI_TIMEOUT_MS", 10_000),
-	}
-	if cfg.TimeoutMs <= 0 {
-		return Config{}, fmt.Errorf("ODI_TIMEOUT_MS must be > 0")
+// Validate reports config values the services cannot run with.
+func (c Config) Validate() error {
+	if c.TimeoutMs <= 0 {
+		return errors.New("ODI_TIMEOUT_MS must be > 0")
 	}
-	return cfg, nil
+	return nil
 }
 
-func getenv(k, def string) string {
-	if v := os.Getenv(k); v != "" {
-		return v
-	}
-	return def
-}
-
-func getenvInt(k string, def int) int {
-	v := os.Getenv(k)
-	if v == "" {
-		return def
-	}
-	n, err := strconv.Atoi(v)
-	if err != nil {
-		return def
-	}
-	return n
+// LoadFromEnv loads Config from the environment (ODI_ENV, ODI_LOG_PREFIX,
+// ODI_TIMEOUT_MS). Malformed values are errors rather than silent defaults.
+func LoadFromEnv() (Config, error) {
+	return diconfig.Load[Config](diconfig.WithEnvPrefix("ODI_"))
 }
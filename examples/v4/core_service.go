@@ -3,12 +3,13 @@ package v4
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/sghaida/odi/examples/v4/config"
 )
 
-// Core depends on Alpha + Beta (required) and optionally uses Tracer + Metrics.
+// Core depends on Alpha + Beta (required) and optionally uses Tracer + Metrics + Logger.
 //
 // Required deps are injected via generated facade methods:
 //
@@ -29,6 +30,7 @@ type Core struct {
 	// Optional deps (applied during BuildWith(reg) if available):
 	tracer  Tracer
 	metrics Metrics
+	logger  *slog.Logger
 }
 
 // NewCore is the constructor used by the generated facade (CoreV4).
@@ -64,6 +66,9 @@ func (c *Core) SetMetrics(m Metrics) {
 // so calling Core.Process through CoreV4.Process is safe (it checks Alpha+Beta exist).
 func (c *Core) Process(ctx context.Context, req ProcessRequest) (ProcessResponse, error) {
 	fmt.Println("[core] Process: start")
+	if c.logger != nil {
+		c.logger.Debug("core: process", "orderID", req.OrderID)
+	}
 
 	// Defensive defaults (in case Build() was used instead of BuildWith()).
 	if c.tracer == nil {
@@ -126,3 +131,15 @@ func (c *Core) Process(ctx context.Context, req ProcessRequest) (ProcessResponse
 	fmt.Printf("[core] Process: completed → %+v\n", resp)
 	return resp, nil
 }
+
+// Ping is Core's health check (spec: "healthCheck": "Ping").
+// It reports unhealthy until the required deps are wired.
+func (c *Core) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.alpha == nil || c.beta == nil {
+		return fmt.Errorf("core wiring incomplete: alpha or beta is nil")
+	}
+	return nil
+}
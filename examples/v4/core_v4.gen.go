@@ -1,48 +1,107 @@
 // Code generated by (di v2); DO NOT EDIT.
 // Spec: specs/core.inject.json
-// Spec-SHA256: 136de190e5d1532fa5e88ecdd68639728f8339845f44eada2b47cf6d89f9fb0f
+// Spec-SHA256: 027f76bc34d7da88c8e0cd6a4c706f5c89315867a8b68feadaf0784a5ad891d4
 
 package v4
 
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"maps"
+
 	di "github.com/sghaida/odi/di"
 	config "github.com/sghaida/odi/examples/v4/config"
-	"strings"
 )
 
 // CoreV4InjectPolicyOnOverwrite controls behavior when a required dep is injected twice.
 // NOTE: generated as a var to allow unit tests to cover all branches.
 var CoreV4InjectPolicyOnOverwrite = "error"
 
+// Required dep bits for buildScoped (fixed order: reqCoreV4Names).
+const (
+	reqCoreV4Alpha uint64 = 1 << iota
+	reqCoreV4Beta
+
+	reqCoreV4All = 1<<2 - 1
+)
+
+var reqCoreV4Names = [...]string{
+	"Alpha",
+	"Beta",
+}
+
 // Optional registry keys for CoreV4.
 const (
+	CoreV4OptionalLoggerKey  = "odi.slog"
 	CoreV4OptionalMetricsKey = "v4.metrics"
 	CoreV4OptionalTracerKey  = "v4.tracer"
 )
 
 type CoreV4 struct {
-	cfg config.Config
-	svc *Core
+	cfg    config.Config
+	cfgErr error // config Validate() failure, reported by Build
+	svc    *Core
 
 	injected map[string]bool
 
-	// Optional wiring diagnostics (best-effort)
+	// escapes lists who received svc from UnsafeImpl/UnsafeImplFor and who still
+	// hold an implementation Reset replaced (Build fails until ResetUnsafe).
+	escapes di.Escapes
+
+	// Optional wiring diagnostics (best-effort; allocated on first BuildWith)
 	optionalResolved map[string]string
 	optionalMissing  map[string]string
+
+	logger *slog.Logger
+}
+
+// CoreV4Logger receives CoreV4 lifecycle events (construction, injection,
+// optional resolution, build). nil uses slog.Default(); WithLogger overrides it per builder.
+var CoreV4Logger *slog.Logger
+
+// WithLogger sets the logger used for this builder's lifecycle events.
+func (b *CoreV4) WithLogger(l *slog.Logger) *CoreV4 {
+	b.logger = l
+	return b
+}
+
+func (b *CoreV4) log(msg string, args ...any) {
+	b.loggerOrDefault().Debug(msg, append([]any{"facade", "CoreV4"}, args...)...)
+}
+
+func (b *CoreV4) logBuild(ctx string, err error) {
+	if err != nil {
+		b.loggerOrDefault().Error("di: build failed", "facade", "CoreV4", "ctx", ctx, "error", err)
+		return
+	}
+	b.log("di: build succeeded", "ctx", ctx)
+}
+
+func (b *CoreV4) loggerOrDefault() *slog.Logger {
+	switch {
+	case b.logger != nil:
+		return b.logger
+	case CoreV4Logger != nil:
+		return CoreV4Logger
+	default:
+		return slog.Default()
+	}
 }
 
 // NewCoreV4 creates a new builder/facade.
 // You must call Build()/BuildWith()/MustBuild() before calling business methods.
+// If the config has a Validate() error method, it runs here and a failure is
+// returned by Build (see di.ValidateConfig).
 func NewCoreV4(cfg config.Config) *CoreV4 {
-	return &CoreV4{
-		cfg:              cfg,
-		svc:              NewCore(cfg),
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
+	b := &CoreV4{
+		cfg:      cfg,
+		cfgErr:   di.ValidateConfig("CoreV4", &cfg),
+		svc:      NewCore(cfg),
+		injected: make(map[string]bool, 2),
 	}
+	b.log("di: facade constructed")
+	return b
 }
 
 // Clone copies the builder with the current injected state.
@@ -50,35 +109,55 @@ func NewCoreV4(cfg config.Config) *CoreV4 {
 func (b *CoreV4) Clone() *CoreV4 {
 	nb := &CoreV4{
 		cfg:              b.cfg,
+		cfgErr:           b.cfgErr,
 		svc:              b.svc,
-		injected:         map[string]bool{},
-		optionalResolved: map[string]string{},
-		optionalMissing:  map[string]string{},
-	}
-	for k, v := range b.injected {
-		nb.injected[k] = v
-	}
-	for k, v := range b.optionalResolved {
-		nb.optionalResolved[k] = v
-	}
-	for k, v := range b.optionalMissing {
-		nb.optionalMissing[k] = v
+		injected:         maps.Clone(b.injected),
+		escapes:          b.escapes.Clone(),
+		optionalResolved: maps.Clone(b.optionalResolved),
+		optionalMissing:  maps.Clone(b.optionalMissing),
+		logger:           b.logger,
 	}
 	return nb
 }
 
 // Reset discards injected bookkeeping and recreates the underlying implementation.
+// If UnsafeImpl handed out the previous implementation, its holders keep the old
+// pointer: Build then fails with di.ErrStaleImpl until ResetUnsafe is used instead.
 func (b *CoreV4) Reset() *CoreV4 {
-	b.svc = NewCore(b.cfg)
-	b.injected = map[string]bool{}
-	b.optionalResolved = map[string]string{}
-	b.optionalMissing = map[string]string{}
+	b.escapes.Reset()
+	b.recreate()
 	return b
 }
 
+// ResetUnsafe is Reset for an implementation handed out by UnsafeImpl: it returns
+// the new implementation and the consumers holding the previous one (named with
+// UnsafeImplFor), which the caller must re-wire, and clears the stale state.
+func (b *CoreV4) ResetUnsafe() (*Core, []string) {
+	orphaned := b.escapes.Orphans()
+	b.recreate()
+	return b.svc, orphaned
+}
+
+func (b *CoreV4) recreate() {
+	b.svc = NewCore(b.cfg)
+	clear(b.injected)
+	clear(b.optionalResolved)
+	clear(b.optionalMissing)
+}
+
 // UnsafeImpl returns the underlying implementation pointer for composition root wiring.
 // It must NOT be used to call business methods before Build()/MustBuild().
-func (b *CoreV4) UnsafeImpl() *Core { return b.svc }
+func (b *CoreV4) UnsafeImpl() *Core {
+	b.escapes.Add("")
+	return b.svc
+}
+
+// UnsafeImplFor is UnsafeImpl recording consumer as a holder of the pointer, so
+// ResetUnsafe, Explain and di.StaleImplError can name it.
+func (b *CoreV4) UnsafeImplFor(consumer string) *Core {
+	b.escapes.Add(consumer)
+	return b.svc
+}
 
 // Inject allows custom wiring for advanced usage.
 // Prefer InjectX methods for required deps.
@@ -92,22 +171,16 @@ func (b *CoreV4) Inject(fn func(*Core)) *CoreV4 {
 // TryInjectAlpha injects the required dependency Alpha.
 // Unlike InjectAlpha, it returns an error instead of panicking.
 func (b *CoreV4) TryInjectAlpha(dep *Alpha) (*CoreV4, error) {
-	switch CoreV4InjectPolicyOnOverwrite {
-	case "error":
-		if b.injected["Alpha"] {
-			return nil, fmt.Errorf("CoreV4: duplicate inject Alpha")
-		}
-	case "ignore":
-		if b.injected["Alpha"] {
-			return b, nil
-		}
-	case "overwrite":
-		// allow overwriting
-	default:
-		return nil, fmt.Errorf("CoreV4: invalid injectPolicy.onOverwrite=%s", CoreV4InjectPolicyOnOverwrite)
+	ok, err := di.CheckInject("CoreV4", CoreV4InjectPolicyOnOverwrite, "Alpha", b.injected["Alpha"])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b, nil
 	}
 	b.svc.alpha = dep
 	b.injected["Alpha"] = true
+	b.log("di: dependency injected", "dep", "Alpha")
 	return b, nil
 }
 
@@ -124,22 +197,16 @@ func (b *CoreV4) InjectAlpha(dep *Alpha) *CoreV4 {
 // TryInjectBeta injects the required dependency Beta.
 // Unlike InjectBeta, it returns an error instead of panicking.
 func (b *CoreV4) TryInjectBeta(dep *Beta) (*CoreV4, error) {
-	switch CoreV4InjectPolicyOnOverwrite {
-	case "error":
-		if b.injected["Beta"] {
-			return nil, fmt.Errorf("CoreV4: duplicate inject Beta")
-		}
-	case "ignore":
-		if b.injected["Beta"] {
-			return b, nil
-		}
-	case "overwrite":
-		// allow overwriting
-	default:
-		return nil, fmt.Errorf("CoreV4: invalid injectPolicy.onOverwrite=%s", CoreV4InjectPolicyOnOverwrite)
+	ok, err := di.CheckInject("CoreV4", CoreV4InjectPolicyOnOverwrite, "Beta", b.injected["Beta"])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return b, nil
 	}
 	b.svc.beta = dep
 	b.injected["Beta"] = true
+	b.log("di: dependency injected", "dep", "Beta")
 	return b, nil
 }
 
@@ -156,96 +223,136 @@ func (b *CoreV4) InjectBeta(dep *Beta) *CoreV4 {
 // Missing returns the list of missing required dependency names at this moment.
 // This is useful for debug UX before calling Build().
 func (b *CoreV4) Missing() []string {
-	missing := []string{}
-	if b.svc.alpha == nil {
-		missing = append(missing, "Alpha")
-	}
-	if b.svc.beta == nil {
-		missing = append(missing, "Beta")
-	}
-	return missing
+	return di.MissingNames(reqCoreV4Names[:], b.missingMask(reqCoreV4All))
 }
 
-// Explain returns a human-friendly summary of the wiring state.
+// Explain returns a human-friendly summary of the wiring state (di.SlimExplain
+// in odi_slim builds).
 func (b *CoreV4) Explain() string {
-	var sb strings.Builder
-	m := b.Missing()
-	if len(m) == 0 {
-		sb.WriteString("required: complete\n")
-	} else {
-		sb.WriteString(fmt.Sprintf("required: missing=%v\n", m))
+	if di.Slim {
+		return di.SlimExplain
 	}
-	if len(b.optionalResolved) > 0 {
-		sb.WriteString("optional: resolved\n")
-		for k, v := range b.optionalResolved {
-			sb.WriteString(fmt.Sprintf("  - %s => %s\n", k, v))
-		}
+	return di.ExplainWiring(b.Missing(), b.optionalResolved, b.optionalMissing) + b.escapes.Explain()
+}
+
+// WiringInfo returns a snapshot of the wiring state (injected deps, optional
+// resolutions, UnsafeImpl consumers, spec hash) for introspection endpoints.
+func (b *CoreV4) WiringInfo() di.WiringInfo {
+	return di.NewWiringInfo("CoreV4", "specs/core.inject.json", "027f76bc34d7da88c8e0cd6a4c706f5c89315867a8b68feadaf0784a5ad891d4", b.injected, b.optionalResolved, b.optionalMissing).WithUnsafe(b.escapes.Consumers())
+}
+
+// WiringManifest returns the deps CoreV4 declares in its spec, with their types,
+// registry keys and tags, for audits and compliance reports.
+func (b *CoreV4) WiringManifest() di.WiringManifest {
+	return di.WiringManifest{
+		Facade:   "CoreV4",
+		Impl:     "Core",
+		Spec:     "specs/core.inject.json",
+		SpecHash: "027f76bc34d7da88c8e0cd6a4c706f5c89315867a8b68feadaf0784a5ad891d4",
+		Deps: []di.ManifestDep{
+			{Name: "Alpha", Type: "*Alpha"},
+			{Name: "Beta", Type: "*Beta"},
+			{Name: "Logger", Type: "*slog.Logger", Optional: true, RegistryKey: "odi.slog"},
+			{Name: "Metrics", Type: "Metrics", Optional: true, RegistryKey: "v4.metrics"},
+			{Name: "Tracer", Type: "Tracer", Optional: true, RegistryKey: "v4.tracer"},
+		},
 	}
-	if len(b.optionalMissing) > 0 {
-		sb.WriteString("optional: missing\n")
-		for k, v := range b.optionalMissing {
-			sb.WriteString(fmt.Sprintf("  - %s => %s\n", k, v))
-		}
+}
+
+// CoreV4SpecInfo returns the spec CoreV4 was generated from: path,
+// hash, required deps, optional registry keys and what each method requires.
+func CoreV4SpecInfo() di.SpecInfo {
+	return di.SpecInfo{
+		Facade:   "CoreV4",
+		Impl:     "Core",
+		Spec:     "specs/core.inject.json",
+		SpecHash: "027f76bc34d7da88c8e0cd6a4c706f5c89315867a8b68feadaf0784a5ad891d4",
+		Required: []string{"Alpha", "Beta"},
+		Optional: []di.SpecOptional{
+			{Name: "Logger", RegistryKey: "odi.slog"},
+			{Name: "Metrics", RegistryKey: "v4.metrics"},
+			{Name: "Tracer", RegistryKey: "v4.tracer"},
+		},
+		Methods: []di.SpecMethod{
+			{Name: "Process", Requires: []string{"Alpha", "Beta"}},
+		},
 	}
-	return sb.String()
 }
 
 func (b *CoreV4) Build() (*Core, error) {
-	return b.buildScoped("Build", nil)
+	svc, err := b.buildScoped("Build", reqCoreV4All)
+	b.logBuild("Build", err)
+	return svc, err
 }
 
 // NOTE: Registry.Resolve must be (val any, ok bool, err error)
 func (b *CoreV4) BuildWith(reg di.Registry) (*Core, error) {
+	return b.BuildWithCtx(context.Background(), reg)
+}
 
+// BuildWithCtx is BuildWith with optional deps resolved under ctx, so registries
+// implementing di.RegistryCtx can time out or be canceled.
+func (b *CoreV4) BuildWithCtx(ctx context.Context, reg di.Registry) (svc *Core, err error) {
+	defer func() { b.logBuild("BuildWith", err) }()
 	if reg != nil {
-		// IMPORTANT: declare once; reuse for each optional dep to avoid ":=" redeclare errors.
-		var (
-			v   any
-			ok  bool
-			err error
-		)
-
-		v, ok, err = reg.Resolve(b.cfg, "v4.metrics")
-		if err != nil {
-			return nil, fmt.Errorf("CoreV4: optional dep Metrics resolve failed: %w", err)
+		if !di.Slim && b.optionalResolved == nil {
+			b.optionalResolved = make(map[string]string, 3)
+		}
+		if !di.Slim && b.optionalMissing == nil {
+			b.optionalMissing = make(map[string]string, 3)
 		}
-		if ok {
-			casted, ok := v.(Metrics)
-			if !ok {
-				return nil, fmt.Errorf("CoreV4: optional dep Metrics key=v4.metrics: want Metrics, got %T", v)
-			}
-
-			b.svc.metrics = casted
 
-			b.optionalResolved["v4.metrics"] = fmt.Sprintf("%T", v)
+		if v, ok, err := di.ResolveOptionalCtx[*slog.Logger](ctx, reg, b.cfg, "CoreV4", "Logger", "odi.slog"); err != nil {
+			return nil, err
+		} else if ok {
+			b.svc.logger = v
+			if !di.Slim {
+				b.optionalResolved["odi.slog"] = fmt.Sprintf("%T", v)
+			}
+			b.log("di: optional resolved", "key", "odi.slog", "type", fmt.Sprintf("%T", v))
 		} else {
-			def := NoopMetrics{}
-			b.svc.metrics = def
-			b.optionalMissing["v4.metrics"] = "used defaultExpr"
+			b.svc.logger = slog.Default()
+			if !di.Slim {
+				b.optionalMissing["odi.slog"] = "used defaultExpr"
+			}
+			b.log("di: optional missing", "key", "odi.slog", "reason", "used defaultExpr")
 		}
 
-		v, ok, err = reg.Resolve(b.cfg, "v4.tracer")
-		if err != nil {
-			return nil, fmt.Errorf("CoreV4: optional dep Tracer resolve failed: %w", err)
-		}
-		if ok {
-			casted, ok := v.(Tracer)
-			if !ok {
-				return nil, fmt.Errorf("CoreV4: optional dep Tracer key=v4.tracer: want Tracer, got %T", v)
+		if v, ok, err := di.ResolveOptionalCtx[Metrics](ctx, reg, b.cfg, "CoreV4", "Metrics", "v4.metrics"); err != nil {
+			return nil, err
+		} else if ok {
+			b.svc.metrics = v
+			if !di.Slim {
+				b.optionalResolved["v4.metrics"] = fmt.Sprintf("%T", v)
 			}
+			b.log("di: optional resolved", "key", "v4.metrics", "type", fmt.Sprintf("%T", v))
+		} else {
+			b.svc.metrics = NoopMetrics{}
+			if !di.Slim {
+				b.optionalMissing["v4.metrics"] = "used defaultExpr"
+			}
+			b.log("di: optional missing", "key", "v4.metrics", "reason", "used defaultExpr")
+		}
 
-			b.svc.SetTracer(casted)
-
-			b.optionalResolved["v4.tracer"] = fmt.Sprintf("%T", v)
+		if v, ok, err := di.ResolveOptionalCtx[Tracer](ctx, reg, b.cfg, "CoreV4", "Tracer", "v4.tracer"); err != nil {
+			return nil, err
+		} else if ok {
+			b.svc.SetTracer(v)
+			if !di.Slim {
+				b.optionalResolved["v4.tracer"] = fmt.Sprintf("%T", v)
+			}
+			b.log("di: optional resolved", "key", "v4.tracer", "type", fmt.Sprintf("%T", v))
 		} else {
-			def := NoopTracer{}
-			b.svc.SetTracer(def)
-			b.optionalMissing["v4.tracer"] = "used defaultExpr"
+			b.svc.SetTracer(NoopTracer{})
+			if !di.Slim {
+				b.optionalMissing["v4.tracer"] = "used defaultExpr"
+			}
+			b.log("di: optional missing", "key", "v4.tracer", "reason", "used defaultExpr")
 		}
 
 	}
 
-	return b.buildScoped("BuildWith", nil)
+	return b.buildScoped("BuildWith", reqCoreV4All)
 }
 
 func (b *CoreV4) MustBuild() *Core {
@@ -256,42 +363,39 @@ func (b *CoreV4) MustBuild() *Core {
 	return svc
 }
 
-func (b *CoreV4) buildScoped(ctx string, reqNames []string) (*Core, error) {
-	missing := []string{}
-
-	isMissingAlpha := b.svc.alpha == nil
-
-	isMissingBeta := b.svc.beta == nil
-
-	check := func(name string, isMissing bool) {
-		if isMissing {
-			missing = append(missing, name)
-		}
+// HealthCheck reports whether CoreV4 is healthy by calling Core.Ping.
+// It fails with a wiring error if any required dependency is missing.
+func (b *CoreV4) HealthCheck(ctx context.Context) error {
+	svc, err := b.buildScoped("HealthCheck", reqCoreV4All)
+	if err != nil {
+		return err
 	}
+	return svc.Ping(ctx)
+}
 
-	if reqNames == nil {
-
-		check("Alpha", isMissingAlpha)
-
-		check("Beta", isMissingBeta)
-
-	} else {
-		for _, n := range reqNames {
-			switch n {
-
-			case "Alpha":
-				check("Alpha", isMissingAlpha)
-
-			case "Beta":
-				check("Beta", isMissingBeta)
-
-			}
-		}
+// missingMask returns the reqCoreV4* bits in need whose dep is not wired.
+func (b *CoreV4) missingMask(need uint64) uint64 {
+	var missing uint64
+	if need&reqCoreV4Alpha != 0 && b.svc.alpha == nil {
+		missing |= reqCoreV4Alpha
 	}
+	if need&reqCoreV4Beta != 0 && b.svc.beta == nil {
+		missing |= reqCoreV4Beta
+	}
+	return missing
+}
 
-	if len(missing) > 0 {
-		return nil, fmt.Errorf("%s: wiring incomplete (ctx=%s, missing=%v, spec=%s)",
-			"CoreV4", ctx, missing, "136de190e5d1532fa5e88ecdd68639728f8339845f44eada2b47cf6d89f9fb0f")
+// buildScoped returns the implementation if every required dep in need (a mask of
+// reqCoreV4* bits) is wired.
+func (b *CoreV4) buildScoped(ctx string, need uint64) (*Core, error) {
+	if b.cfgErr != nil {
+		return nil, b.cfgErr
+	}
+	if err := b.escapes.CheckStale("CoreV4", ctx); err != nil {
+		return nil, err
+	}
+	if missing := b.missingMask(need); missing != 0 {
+		return nil, di.WiringIncomplete("CoreV4", ctx, "027f76bc34d7da88c8e0cd6a4c706f5c89315867a8b68feadaf0784a5ad891d4", reqCoreV4Names[:], missing)
 	}
 	return b.svc, nil
 }
@@ -300,10 +404,7 @@ func (b *CoreV4) Process(
 	ctx context.Context,
 	req ProcessRequest,
 ) (ProcessResponse, error) {
-	svc, err := b.buildScoped("Process", []string{
-		"Alpha",
-		"Beta",
-	})
+	svc, err := b.buildScoped("Process", reqCoreV4Alpha|reqCoreV4Beta)
 	if err != nil {
 		var zero0 ProcessResponse
 
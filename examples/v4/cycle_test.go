@@ -0,0 +1,31 @@
+package v4
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sghaida/odi/di"
+	"github.com/sghaida/odi/examples/v4/config"
+)
+
+// TestCycleGuard verifies the Alpha <-> Beta edge guard stops a runaway Depth
+// when the implementations are wired into each other, as graph wiring does.
+func TestCycleGuard(t *testing.T) {
+	t.Parallel()
+	cfg := config.Config{TimeoutMs: 1000}
+	alphaB, betaB := NewAlphaV4(cfg), NewBetaV4(cfg)
+	alphaB.InjectBeta(betaB.UnsafeImpl())
+	betaB.InjectAlpha(alphaB.UnsafeImpl())
+	ctx := context.Background()
+
+	if _, err := alphaB.DoAlpha(ctx, AlphaRequest{X: 1, Depth: 4}); err != nil {
+		t.Fatalf("bounded Depth: %v", err)
+	}
+
+	_, err := alphaB.DoAlpha(ctx, AlphaRequest{X: 1, Depth: 1000})
+	var rle *di.RecursionLimitError
+	if !errors.Is(err, di.ErrRecursionLimit) || !errors.As(err, &rle) || rle.Max != 16 {
+		t.Fatalf("expected the cycle guard to trip, got %v", err)
+	}
+}
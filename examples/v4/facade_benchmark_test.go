@@ -0,0 +1,61 @@
+package v4
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sghaida/odi/examples/v4/config"
+)
+
+// Benchmarks for the generated safe-wrapper overhead (buildScoped mask check).
+
+func BenchmarkAlphaV4_DoAlpha_Wrapper(b *testing.B) {
+	cfg := config.Config{}
+	alphaB := NewAlphaV4(cfg)
+	betaB := NewBetaV4(cfg)
+	alphaB.InjectBeta(betaB.UnsafeImpl())
+
+	ctx := context.Background()
+	req := AlphaRequest{X: 1} // Depth 0: returns without calling Beta
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = alphaB.DoAlpha(ctx, req)
+	}
+}
+
+func BenchmarkAlphaV4_DoAlpha_Direct(b *testing.B) {
+	alpha := NewAlpha(config.Config{})
+	alpha.beta = NewBeta(config.Config{})
+
+	ctx := context.Background()
+	req := AlphaRequest{X: 1}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = alpha.DoAlpha(ctx, req)
+	}
+}
+
+func BenchmarkAlphaV4_Build(b *testing.B) {
+	alphaB := NewAlphaV4(config.Config{})
+	alphaB.InjectBeta(NewBeta(config.Config{}))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = alphaB.Build()
+	}
+}
+
+func BenchmarkAlphaV4_Build_Missing(b *testing.B) {
+	alphaB := NewAlphaV4(config.Config{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = alphaB.Build()
+	}
+}
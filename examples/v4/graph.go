@@ -1,4 +1,4 @@
 package v4
 
-//go:generate go run ../../cmd/di2 -graph specs/graph.json       -out graph_v4.gen.go
+//go:generate go run ../../cmd/di2 -graph specs/graph.json -profile -out graph_v4.gen.go
 
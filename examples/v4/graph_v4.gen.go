@@ -1,45 +1,206 @@
 // Code generated by (di v2); DO NOT EDIT.
 // Graph: specs/graph.json
-// Graph-SHA256: 5826399a6614c2bbbc8d63b9f15d5588af5a88491d61bfeac3dc2b1dda5afc38
+// Graph-SHA256: 48a05c29cf07768ba013ab8f887b9bc92533765d5911435a80bb802ac2731d22
 
 package v4
 
 import (
+	"context"
+	_ "embed"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
 	di "github.com/sghaida/odi/di"
 	config "github.com/sghaida/odi/examples/v4/config"
 )
 
+// wiringManifest is the di.GraphManifest of specs/graph.json, registered for di.Manifest().
+//
+//go:embed wiring.manifest.json
+var wiringManifest []byte
+
+func init() { di.RegisterManifest(wiringManifest) }
+
 type BuildAppV4Result struct {
 	Alpha *Alpha
 	Beta  *Beta
 	Core  *Core
+
+	wiring []di.WiringInfo
+
+	profile di.BuildProfile
+}
+
+// BuildAppV4Logger receives BuildAppV4 build events. nil uses slog.Default().
+var BuildAppV4Logger *slog.Logger
+
+// BuildAppV4BuildOrder returns the vars of the services BuildAppV4 builds, in the order
+// the generator chose (dependencies first, cycles broken by var name).
+// Services of one dependency stage are built concurrently.
+func BuildAppV4BuildOrder() []string {
+	return []string{"alpha", "beta", "core"}
+}
+
+// BuildOrder returns the order BuildAppV4 built the result's services in; see BuildAppV4BuildOrder.
+func (r BuildAppV4Result) BuildOrder() []string {
+	return BuildAppV4BuildOrder()
 }
 
 func BuildAppV4(cfg config.Config, reg di.Registry) (BuildAppV4Result, error) {
+	return BuildAppV4Ctx(context.Background(), cfg, reg)
+}
+
+// MustBuildAppV4 is like BuildAppV4 but panics on error.
+func MustBuildAppV4(cfg config.Config, reg di.Registry) BuildAppV4Result {
+	res, err := BuildAppV4Ctx(context.Background(), cfg, reg)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// BuildAppV4Ctx is like BuildAppV4 but stops before the next service build once ctx
+// is done and passes ctx to post-build hooks.
+func BuildAppV4Ctx(ctx context.Context, cfg config.Config, reg di.Registry) (BuildAppV4Result, error) {
 	var res BuildAppV4Result
+
+	logger := BuildAppV4Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Debug("di: graph build started", "root", "BuildAppV4", "services", 3)
+
+	started := time.Now()
+	res.profile = di.NewBuildProfile("BuildAppV4", "alpha", "beta", "core")
+	var mark time.Time
+	mark = time.Now()
 	alphaB := NewAlphaV4(cfg)
+	res.profile.Services[0].Construct = time.Since(mark)
+	mark = time.Now()
 	betaB := NewBetaV4(cfg)
+	res.profile.Services[1].Construct = time.Since(mark)
+	mark = time.Now()
 	coreB := NewCoreV4(cfg)
+	res.profile.Services[2].Construct = time.Since(mark)
+	mark = time.Now()
 	alphaB.InjectBeta(betaB.UnsafeImpl())
+	res.profile.Services[0].Inject += time.Since(mark)
+	mark = time.Now()
 	betaB.InjectAlpha(alphaB.UnsafeImpl())
+	res.profile.Services[1].Inject += time.Since(mark)
+	mark = time.Now()
 	coreB.InjectAlpha(alphaB.UnsafeImpl())
+	res.profile.Services[2].Inject += time.Since(mark)
+	mark = time.Now()
 	coreB.InjectBeta(betaB.UnsafeImpl())
-	alphaSvc, err := alphaB.BuildWith(reg)
-	if err != nil {
-		return res, fmt.Errorf("BuildAppV4: build alpha failed: %w", err)
-	}
-	res.Alpha = alphaSvc
-	betaSvc, err := betaB.BuildWith(reg)
-	if err != nil {
-		return res, fmt.Errorf("BuildAppV4: build beta failed: %w", err)
-	}
-	res.Beta = betaSvc
-	coreSvc, err := coreB.BuildWith(reg)
+	res.profile.Services[2].Inject += time.Since(mark)
+	res.wiring = make([]di.WiringInfo, 3)
+
+	// Services within a stage are independent and built concurrently.
+	err := di.BuildStages(
+		[]func() error{
+			func() error {
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("BuildAppV4: build alpha canceled: %w", err)
+				}
+				start := time.Now()
+				svc, err := di.BuildCtx(ctx, alphaB, reg)
+				res.profile.Services[0].Build = time.Since(start)
+				if err != nil {
+					logger.Error("di: graph build failed", "root", "BuildAppV4", "service", "alpha", "error", err)
+					return fmt.Errorf("BuildAppV4: build alpha failed: %w", err)
+				}
+				res.Alpha = svc
+				res.wiring[0] = di.BuiltWiring(alphaB, "alpha", time.Now())
+				logger.Debug("di: service built", "root", "BuildAppV4", "service", "alpha")
+				return nil
+			},
+		},
+		[]func() error{
+			func() error {
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("BuildAppV4: build beta canceled: %w", err)
+				}
+				start := time.Now()
+				svc, err := di.BuildCtx(ctx, betaB, reg)
+				res.profile.Services[1].Build = time.Since(start)
+				if err != nil {
+					logger.Error("di: graph build failed", "root", "BuildAppV4", "service", "beta", "error", err)
+					return fmt.Errorf("BuildAppV4: build beta failed: %w", err)
+				}
+				res.Beta = svc
+				res.wiring[1] = di.BuiltWiring(betaB, "beta", time.Now())
+				logger.Debug("di: service built", "root", "BuildAppV4", "service", "beta")
+				return nil
+			},
+		},
+		[]func() error{
+			func() error {
+				if err := ctx.Err(); err != nil {
+					return fmt.Errorf("BuildAppV4: build core canceled: %w", err)
+				}
+				start := time.Now()
+				svc, err := di.BuildCtx(ctx, coreB, reg)
+				res.profile.Services[2].Build = time.Since(start)
+				if err != nil {
+					logger.Error("di: graph build failed", "root", "BuildAppV4", "service", "core", "error", err)
+					return fmt.Errorf("BuildAppV4: build core failed: %w", err)
+				}
+				res.Core = svc
+				res.wiring[2] = di.BuiltWiring(coreB, "core", time.Now())
+				logger.Debug("di: service built", "root", "BuildAppV4", "service", "core")
+				return nil
+			},
+		},
+	)
 	if err != nil {
-		return res, fmt.Errorf("BuildAppV4: build core failed: %w", err)
+		return res, err
 	}
-	res.Core = coreSvc
+	res.profile.Total = time.Since(started)
+	logger.Debug("di: graph build succeeded", "root", "BuildAppV4")
 
 	return res, nil
 }
+
+// Profile returns per-service construct/inject/build timings of the BuildAppV4 run
+// (generated with -profile). Services are in build order; see di.BuildProfile.Slowest.
+func (r BuildAppV4Result) Profile() di.BuildProfile {
+	p := r.profile
+	p.Services = append([]di.ServiceTiming(nil), r.profile.Services...)
+	return p
+}
+
+// HealthCheck runs the health checks of BuildAppV4's services in dependency order.
+// The map is keyed by service var; a nil error means healthy.
+func (r BuildAppV4Result) HealthCheck(ctx context.Context) map[string]error {
+	out := map[string]error{}
+	if r.Core == nil {
+		out["core"] = fmt.Errorf("BuildAppV4: core %w", di.ErrNotBuilt)
+	} else {
+		out["core"] = r.Core.Ping(ctx)
+	}
+	return out
+}
+
+// HealthHandler serves HealthCheck as JSON (200 when all checks pass, 503 otherwise).
+func (r BuildAppV4Result) HealthHandler() http.Handler {
+	return di.HealthHandler(r.HealthCheck)
+}
+
+// Wiring returns the wiring snapshots captured while BuildAppV4 ran, in build order.
+func (r BuildAppV4Result) Wiring() []di.WiringInfo {
+	return append([]di.WiringInfo(nil), r.wiring...)
+}
+
+// WiringHandler serves the captured wiring as JSON (services, injected deps,
+// optional resolutions, spec hashes, build timestamps) for debug endpoints.
+func (r BuildAppV4Result) WiringHandler() http.Handler {
+	return di.WiringHandler(di.WiringReport{
+		Root:      "BuildAppV4",
+		Graph:     "specs/graph.json",
+		GraphHash: "48a05c29cf07768ba013ab8f887b9bc92533765d5911435a80bb802ac2731d22",
+		Services:  r.Wiring(),
+	})
+}
@@ -113,10 +113,27 @@ func main() {
 	fmt.Println("core :", coreOut)
 
 	// Show optional dep metrics snapshot (only works if your Core increments metrics).
-	if m, ok := reg.MustGet("v4.metrics").(*v4.CounterMetrics); ok {
+	if m, err := di.RegistryGet[*v4.CounterMetrics](reg, cfg, "v4.metrics"); err == nil {
 		fmt.Println("metrics:", v4.FormatSnapshot(m.Snapshot()))
 	}
 
+	// Health checks declared in specs ("healthCheck") are aggregated by the graph.
+	// app.HealthHandler() serves the same report over HTTP (e.g. mux.Handle("/healthz", ...)).
+	for name, herr := range app.HealthCheck(ctx) {
+		fmt.Println("health:", name, "err=", herr)
+	}
+
+	// Wiring introspection ("wiringHandler": true): what was injected/resolved per service.
+	// app.WiringHandler() serves the same data as JSON for an admin/debug listener.
+	for _, w := range app.Wiring() {
+		fmt.Println("wiring:", w.Service, "injected=", w.Injected, "optional=", w.OptionalResolved)
+	}
+
+	// Build profiling (di2 -profile): per-service construct/inject/build timings.
+	if slowest := app.Profile().Slowest(1); len(slowest) > 0 {
+		fmt.Println("profile: slowest service =", slowest[0].Service)
+	}
+
 	// -------------------------------------------------------------------------
 	// Step 6: Manual wiring (individual injections usage)
 	// -------------------------------------------------------------------------